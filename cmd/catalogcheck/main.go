@@ -0,0 +1,77 @@
+// catalogcheck 严格校验模型目录（MODELS_DIR）中的提供商和模型配置
+//
+// 用法:
+//
+//	go run ./cmd/catalogcheck [-dir ./models] [-format text|json] [-strict]
+//
+// 存在错误时以状态码 1 退出（-strict 时警告也视为失败），无法读取模型目录时以状态码 2 退出，
+// 可以在 CI 中运行以避免有问题的 YAML 进入生产环境
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"genkit-ai-service/internal/loader"
+)
+
+// 退出状态码
+const (
+	exitOK      = 0 // 校验通过
+	exitInvalid = 1 // 存在错误（或 -strict 时存在警告）
+	exitFailure = 2 // 参数错误或无法读取模型目录
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run 解析参数并校验模型目录，返回退出状态码
+func run(args []string, stdout, stderr io.Writer) int {
+	defaultDir := os.Getenv("MODELS_DIR")
+	if defaultDir == "" {
+		defaultDir = "./models"
+	}
+
+	flags := flag.NewFlagSet("catalogcheck", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	dir := flags.String("dir", defaultDir, "模型目录，默认取环境变量 MODELS_DIR")
+	format := flags.String("format", "text", "输出格式：text 或 json")
+	strict := flags.Bool("strict", false, "警告也视为失败")
+	if err := flags.Parse(args); err != nil {
+		return exitFailure
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(stderr, "不支持的输出格式: %s\n", *format)
+		return exitFailure
+	}
+
+	report, err := loader.Validate(*dir)
+	if err != nil {
+		fmt.Fprintf(stderr, "校验模型目录失败: %v\n", err)
+		return exitFailure
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(stderr, "输出校验报告失败: %v\n", err)
+			return exitFailure
+		}
+	} else {
+		for _, issue := range report.Issues {
+			fmt.Fprintln(stdout, issue.String())
+		}
+		fmt.Fprintf(stdout, "校验完成: %d 个提供商, %d 个模型, %d 个错误, %d 个警告\n",
+			report.Providers, report.Models, report.Errors, report.Warnings)
+	}
+
+	if report.HasErrors() || (*strict && report.Warnings > 0) {
+		return exitInvalid
+	}
+	return exitOK
+}
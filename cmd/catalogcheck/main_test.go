@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"genkit-ai-service/internal/loader"
+)
+
+// writeCatalog 写入只有一个提供商和一个模型的模型目录
+func writeCatalog(t *testing.T, modelYAML string) string {
+	t.Helper()
+	modelsDir := t.TempDir()
+	files := map[string]string{
+		"acme/provider/acme.yaml":        "provider: acme\nlabel:\n  en_US: Acme\nconfigurate_methods:\n  - predefined-model\nsupported_model_types:\n  - llm\nmodels:\n  llm:\n    predefined:\n      - models/llm/*.yaml\n",
+		"acme/models/llm/acme-chat.yaml": modelYAML,
+	}
+	for name, content := range files {
+		path := filepath.Join(modelsDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("创建目录失败: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("写入文件失败: %v", err)
+		}
+	}
+	return modelsDir
+}
+
+func TestRun(t *testing.T) {
+	validModel := "model: acme-chat\nmodel_properties:\n  mode: chat\n  context_size: 8192\n"
+	tests := []struct {
+		name     string
+		model    string
+		args     []string
+		wantCode int
+	}{
+		{name: "校验通过", model: validModel, wantCode: exitOK},
+		{name: "存在错误", model: validModel + "features:\n  - telepathy\n", wantCode: exitInvalid},
+		{name: "警告不影响结果", model: validModel + "precision: 2\n", wantCode: exitOK},
+		{name: "严格模式下警告视为失败", model: validModel + "precision: 2\n", args: []string{"-strict"}, wantCode: exitInvalid},
+		{name: "不支持的输出格式", model: validModel, args: []string{"-format", "xml"}, wantCode: exitFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modelsDir := writeCatalog(t, tt.model)
+			var stdout, stderr bytes.Buffer
+
+			code := run(append([]string{"-dir", modelsDir}, tt.args...), &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Errorf("期望状态码 %d, 得到 %d\n%s%s", tt.wantCode, code, stdout.String(), stderr.String())
+			}
+		})
+	}
+}
+
+func TestRun_JSON(t *testing.T) {
+	modelsDir := writeCatalog(t, "model: acme-chat\nmodel_properties:\n  mode: chat\n  context_size: 0\n")
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"-dir", modelsDir, "-format", "json"}, &stdout, &stderr); code != exitInvalid {
+		t.Fatalf("期望状态码 %d, 得到 %d: %s", exitInvalid, code, stderr.String())
+	}
+
+	var report loader.ValidationReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("解析校验报告失败: %v", err)
+	}
+	if report.Providers != 1 || report.Models != 1 || report.Errors != 1 {
+		t.Errorf("校验报告不正确: %+v", report)
+	}
+	if !strings.Contains(report.Issues[0].Message, "context_size") {
+		t.Errorf("应报告上下文大小错误: %+v", report.Issues)
+	}
+}
+
+func TestRun_MissingDir(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-dir", filepath.Join(t.TempDir(), "missing")}, &stdout, &stderr); code != exitFailure {
+		t.Errorf("期望状态码 %d, 得到 %d", exitFailure, code)
+	}
+}
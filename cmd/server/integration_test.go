@@ -6,6 +6,7 @@ import (
 
 	"genkit-ai-service/internal/config"
 	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/pkg/errors"
 )
 
 // TestProviderServiceIntegration 测试模型提供商服务的完整集成
@@ -24,7 +25,7 @@ func TestProviderServiceIntegration(t *testing.T) {
 			},
 		}
 
-		service, err := initProviderService(cfg, log)
+		service, _, err := initProviderService(cfg, log)
 		if err != nil {
 			t.Fatalf("模型提供商服务初始化失败: %v", err)
 		}
@@ -94,7 +95,7 @@ func TestProviderServiceIntegration(t *testing.T) {
 			},
 		}
 
-		service, err := initProviderService(cfg, log)
+		service, _, err := initProviderService(cfg, log)
 		if err != nil {
 			t.Fatalf("模型提供商服务初始化失败: %v", err)
 		}
@@ -119,7 +120,7 @@ func TestProviderServiceIntegration(t *testing.T) {
 			},
 		}
 
-		service, err := initProviderService(cfg, log)
+		service, _, err := initProviderService(cfg, log)
 		if err != nil {
 			t.Fatalf("模型提供商服务初始化失败: %v", err)
 		}
@@ -144,4 +145,45 @@ func TestProviderServiceIntegration(t *testing.T) {
 			t.Error("期望返回错误，但得到 nil")
 		}
 	})
+
+	t.Run("测试模型解析", func(t *testing.T) {
+		cfg := &config.Config{
+			Models: config.ModelsConfig{
+				Dir: "../../models",
+			},
+		}
+
+		service, _, err := initProviderService(cfg, log)
+		if err != nil {
+			t.Fatalf("模型提供商服务初始化失败: %v", err)
+		}
+
+		tests := []struct {
+			name         string
+			modelName    string
+			wantProvider string
+			wantModel    string
+		}{
+			{name: "按模型名称解析", modelName: "qwen-plus", wantProvider: "tongyi", wantModel: "qwen-plus"},
+			{name: "按提供商/模型解析", modelName: "azure_openai/gpt-4", wantProvider: "azure_openai", wantModel: "gpt-4"},
+		}
+
+		for _, tt := range tests {
+			providerID, m, err := service.ResolveModel(tt.modelName)
+			if err != nil {
+				t.Errorf("%s: 解析模型 %s 失败: %v", tt.name, tt.modelName, err)
+				continue
+			}
+			if providerID != tt.wantProvider || m.Model != tt.wantModel {
+				t.Errorf("%s: 期望 %s/%s, 得到 %s/%s", tt.name, tt.wantProvider, tt.wantModel, providerID, m.Model)
+			}
+		}
+
+		// 测试解析不存在的模型
+		_, _, err = service.ResolveModel("nonexistent-model")
+		appErr, ok := err.(*errors.AppError)
+		if !ok || appErr.Code != errors.CodeModelNotFound {
+			t.Errorf("期望模型不存在错误, 得到 %v", err)
+		}
+	})
 }
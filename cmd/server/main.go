@@ -17,11 +17,18 @@ import (
 	"genkit-ai-service/internal/genkit"
 	"genkit-ai-service/internal/loader"
 	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
 	"genkit-ai-service/internal/repository"
 	"genkit-ai-service/internal/service"
 	"genkit-ai-service/internal/service/ai"
+	"genkit-ai-service/internal/service/audio"
+	"genkit-ai-service/internal/service/credential"
+	"genkit-ai-service/internal/service/custommodel"
+	"genkit-ai-service/internal/service/file"
 	"genkit-ai-service/internal/service/health"
+	"genkit-ai-service/internal/service/knowledge"
 	"genkit-ai-service/internal/service/session"
+	"genkit-ai-service/internal/service/tool"
 	"genkit-ai-service/internal/storage"
 
 	_ "genkit-ai-service/docs" // Swagger 文档
@@ -96,27 +103,52 @@ func main() {
 	// 4. 初始化 Genkit 客户端（可选）
 	genkitClient, err := initGenkit(cfg, log)
 	if err != nil {
-		log.Warn("初始化 Genkit 客户端失败，AI服务将不可用", logger.Fields{"error": err})
+		log.Warn("初始化 Genkit 客户端失败，Gemini 模型将不可用", logger.Fields{"error": err})
 		genkitClient = nil
 	}
 
 	// 5. 初始化模型提供商数据
-	providerService, err := initProviderService(cfg, log)
+	providerService, catalogReloader, err := initProviderService(cfg, log)
 	if err != nil {
 		log.Error("初始化模型提供商服务失败", logger.Fields{"error": err})
 		os.Exit(1)
 	}
 
+	// 5.1 初始化模型路由，按模型目录将请求分发到各提供商后端
+	modelRouter := initModelRouter(genkitClient, providerService, cfg, log)
+
+	// 5.2 初始化凭证加密和自定义模型服务（如果数据库可用且配置了凭证主密钥）
+	// 自定义模型在初始化 AI 服务之前合并到模型目录并注册后端
+	var credentialCipher credential.Cipher
+	var customModelService custommodel.CustomModelService
+	if db != nil && cfg.Credentials.MasterKey != "" {
+		credentialCipher = initCredentialCipher(cfg, log)
+	}
+	if credentialCipher != nil {
+		customModelService = initCustomModelService(db, providerService, modelRouter, credentialCipher, cfg, log)
+	}
+
+	// 5.3 监听模型目录变化（如果配置了轮询间隔），重新加载时保留已注册的自定义模型
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	if cfg.Models.WatchInterval > 0 {
+		go catalogReloader.Watch(watchCtx, cfg.Models.WatchInterval)
+	}
+
 	// 6. 初始化服务
 	var aiService ai.AIService
+	var embeddingService ai.EmbeddingService
+	var rerankService ai.RerankService
 	var healthService health.Service
 	
-	// AI 服务只需要 Genkit 客户端
-	if genkitClient != nil {
-		aiService = initAIService(genkitClient, cfg, log)
+	// AI 服务、文本向量化服务和重排序服务需要至少一个可用的模型后端
+	if modelRouter.HasBackends() {
+		aiService = initAIService(modelRouter, providerService, cfg, log)
+		embeddingService = ai.NewEmbeddingService(modelRouter, providerService, log)
+		rerankService = ai.NewRerankService(modelRouter, providerService, log)
 		log.Info("AI服务已启用", nil)
 	} else {
-		log.Warn("AI服务未启用（Genkit 客户端初始化失败）", nil)
+		log.Warn("AI服务未启用（没有可用的模型后端）", nil)
 	}
 	
 	// 健康检查服务需要 Genkit 客户端和数据库
@@ -135,15 +167,54 @@ func main() {
 	routes.RegisterProviderRoutes(serveMux, providerHandler)
 	log.Info("模型提供商API路由已注册", nil)
 
-	// 8.1 注册会话管理路由（如果数据库可用）
+	// 8.1 注册上传文件路由（如果数据库可用）
+	var fileService file.FileService
+	if db != nil {
+		fileService = initFileService(db, cfg, log)
+	}
+	if fileService != nil {
+		fileHandler := handler.NewFileHandler(fileService, log)
+		routes.RegisterFileRoutes(serveMux, fileHandler)
+		log.Info("上传文件路由已注册", logger.Fields{
+			"routes": []string{"/api/v1/files", "/api/v1/files/{id}", "/api/v1/files/{id}/content"},
+		})
+	} else {
+		log.Warn("上传文件路由未注册（数据库或文件存储不可用）", nil)
+	}
+
+	// 8.2 注册知识库路由（如果数据库和文本向量化服务可用）
+	var knowledgeService knowledge.KnowledgeService
+	if db != nil && embeddingService != nil {
+		knowledgeService = initKnowledgeService(db, embeddingService, rerankService, providerService, fileService, cfg, log)
+		knowledgeHandler := handler.NewKnowledgeHandler(knowledgeService, log)
+		routes.RegisterKnowledgeRoutes(serveMux, knowledgeHandler)
+		log.Info("知识库路由已注册", logger.Fields{
+			"routes": []string{
+				"/api/v1/knowledge-bases",
+				"/api/v1/knowledge-bases/{id}",
+				"/api/v1/knowledge-bases/{id}/documents",
+				"/api/v1/knowledge-bases/{id}/search",
+			},
+		})
+	} else {
+		log.Warn("知识库路由未注册（数据库或文本向量化服务不可用）", nil)
+	}
+
+	// 8.3 注册会话管理路由（如果数据库可用）
+	var summaryScheduler session.SummaryScheduler
+	var messageService session.MessageService
 	if db != nil && aiService != nil {
-		sessionHandler, messageHandler := initSessionHandlers(db, aiService, cfg, log)
-		routes.RegisterSessionRoutes(serveMux, sessionHandler, messageHandler)
+		components := initSessionHandlers(db, aiService, providerService, fileService, knowledgeService, cfg, log)
+		summaryScheduler = components.summaryScheduler
+		messageService = components.messageService
+		routes.RegisterSessionRoutes(serveMux, components.sessionHandler, components.messageHandler)
+		routes.RegisterSummaryRoutes(serveMux, components.summaryHandler)
 		log.Info("会话管理路由已注册", logger.Fields{
 			"routes": []string{
 				"/api/v1/chat/sessions",
 				"/api/v1/chat/sessions/{id}",
 				"/api/v1/chat/sessions/{id}/messages",
+				"/api/v1/chat/sessions/{id}/summaries",
 				"/api/v1/chat/messages/{id}",
 			},
 		})
@@ -151,6 +222,29 @@ func main() {
 		log.Warn("会话管理路由未注册（数据库或AI服务不可用）", nil)
 	}
 
+	// 8.4 注册模型提供商凭证路由（如果数据库可用且配置了凭证主密钥）
+	if credentialCipher != nil {
+		credentialService := initCredentialService(db, providerService, credentialCipher, log)
+		credentialHandler := handler.NewCredentialHandler(credentialService, log)
+		routes.RegisterCredentialRoutes(serveMux, credentialHandler)
+		log.Info("模型提供商凭证路由已注册", logger.Fields{
+			"routes": []string{"/api/v1/credentials", "/api/v1/credentials/{id}"},
+		})
+	} else {
+		log.Warn("模型提供商凭证路由未注册（数据库不可用或未配置凭证主密钥）", nil)
+	}
+
+	// 8.5 注册自定义模型路由（如果自定义模型服务可用）
+	if customModelService != nil {
+		customModelHandler := handler.NewCustomModelHandler(customModelService, log)
+		routes.RegisterCustomModelRoutes(serveMux, customModelHandler)
+		log.Info("自定义模型路由已注册", logger.Fields{
+			"routes": []string{"/api/v1/providers/{providerId}/custom-models", "/api/v1/providers/{providerId}/custom-models/{modelId}"},
+		})
+	} else {
+		log.Warn("自定义模型路由未注册（数据库不可用或未配置凭证主密钥）", nil)
+	}
+
 	// 9. 注册 AI 服务路由（如果可用）
 	if aiService != nil {
 		chatHandler := handler.NewChatHandler(aiService, log)
@@ -166,7 +260,53 @@ func main() {
 	} else {
 		log.Warn("AI对话路由未注册（AI服务不可用）", nil)
 	}
+
+	// 9.1 注册文本向量化路由（如果存在可用的模型后端）
+	if embeddingService != nil {
+		embeddingHandler := handler.NewEmbeddingHandler(embeddingService, log)
+		routes.RegisterEmbeddingRoutes(serveMux, embeddingHandler)
+		log.Info("文本向量化路由已注册", logger.Fields{
+			"routes": []string{"/api/v1/embeddings"},
+		})
+	} else {
+		log.Warn("文本向量化路由未注册（没有可用的模型后端）", nil)
+	}
+
+	// 9.2 注册文档重排序路由（如果存在可用的模型后端）
+	if rerankService != nil {
+		rerankHandler := handler.NewRerankHandler(rerankService, log)
+		routes.RegisterRerankRoutes(serveMux, rerankHandler)
+		log.Info("文档重排序路由已注册", logger.Fields{
+			"routes": []string{"/api/v1/rerank"},
+		})
+	} else {
+		log.Warn("文档重排序路由未注册（没有可用的模型后端）", nil)
+	}
+
+	// 9.3 注册语音合成和语音识别路由（如果存在可用的模型后端），会话服务不可用时不能合成消息内容或将识别结果追加到会话
+	if modelRouter.HasBackends() {
+		speechService := audio.NewSpeechService(modelRouter, providerService, messageService, log)
+		transcriptionService := audio.NewTranscriptionService(modelRouter, providerService, messageService, log)
+		audioHandler := handler.NewAudioHandler(speechService, transcriptionService, log)
+		routes.RegisterAudioRoutes(serveMux, audioHandler)
+		log.Info("语音路由已注册", logger.Fields{
+			"routes": []string{"/api/v1/audio/speech", "/api/v1/audio/transcriptions"},
+		})
+	} else {
+		log.Warn("语音路由未注册（没有可用的模型后端）", nil)
+	}
 	
+	// 9.4 注册模型目录管理路由（如果配置了管理接口令牌）
+	if cfg.Admin.Token != "" {
+		catalogHandler := handler.NewCatalogHandler(catalogReloader, cfg.Admin.Token, log)
+		routes.RegisterAdminRoutes(serveMux, catalogHandler)
+		log.Info("模型目录管理路由已注册", logger.Fields{
+			"routes": []string{"/api/v1/admin/models/reload"},
+		})
+	} else {
+		log.Warn("模型目录管理路由未注册（未配置管理接口令牌）", nil)
+	}
+
 	// 10. 注册健康检查路由（如果可用）
 	if healthService != nil {
 		healthHandler := handler.NewHealthHandler(healthService, log)
@@ -236,6 +376,14 @@ func main() {
 			}
 		}
 
+		// 停止监听模型目录
+		stopWatch()
+
+		// 停止后台摘要任务
+		if summaryScheduler != nil {
+			summaryScheduler.Stop()
+		}
+
 		log.Info("服务已成功关闭", logger.Fields{
 			"version": Version,
 		})
@@ -308,6 +456,13 @@ func runDatabaseMigrations(db database.Database, log logger.Logger) error {
 			"chat_sessions",
 			"chat_messages",
 			"chat_summaries",
+			"chat_attachments",
+			"files",
+			"knowledge_bases",
+			"knowledge_documents",
+			"knowledge_chunks",
+			"provider_credentials",
+			"custom_models",
 		},
 	})
 
@@ -349,8 +504,67 @@ func initGenkit(cfg *config.Config, log logger.Logger) (genkit.Client, error) {
 	return client, nil
 }
 
+// initModelRouter 初始化模型路由
+// Gemini 使用 Genkit 客户端，通义千问使用 DashScope 客户端，Azure OpenAI 使用 OpenAI 协议客户端，
+// 未配置 API 密钥或初始化失败的提供商不会注册
+func initModelRouter(genkitClient genkit.Client, providerService service.ProviderService, cfg *config.Config, log logger.Logger) *genkit.Router {
+	router := genkit.NewRouter(providerService, genkitClient)
+	if genkitClient != nil {
+		router.Register(genkit.ProviderGemini, genkitClient)
+	}
+
+	backends := []struct {
+		providerID string
+		client     genkit.Client
+		config     *genkit.Config
+	}{
+		{
+			providerID: genkit.ProviderTongyi,
+			client:     genkit.NewDashScopeClient(),
+			config: &genkit.Config{
+				APIKey:  cfg.Providers.DashScopeAPIKey,
+				BaseURL: cfg.Providers.DashScopeBaseURL,
+			},
+		},
+		{
+			providerID: genkit.ProviderAzureOpenAI,
+			client:     genkit.NewAzureOpenAIClient(),
+			config: &genkit.Config{
+				APIKey:     cfg.Providers.AzureOpenAIAPIKey,
+				BaseURL:    cfg.Providers.AzureOpenAIEndpoint,
+				APIVersion: cfg.Providers.AzureOpenAIAPIVersion,
+			},
+		},
+	}
+
+	ctx := context.Background()
+	for _, backend := range backends {
+		if backend.config.APIKey == "" {
+			continue
+		}
+
+		backend.config.DefaultTemperature = cfg.Genkit.DefaultTemperature
+		backend.config.DefaultMaxTokens = cfg.Genkit.DefaultMaxTokens
+
+		if err := backend.client.Initialize(ctx, backend.config); err != nil {
+			log.Warn("初始化模型提供商后端失败", logger.Fields{
+				"provider": backend.providerID,
+				"error":    err,
+			})
+			continue
+		}
+
+		router.Register(backend.providerID, backend.client)
+		log.Info("模型提供商后端已注册", logger.Fields{
+			"provider": backend.providerID,
+		})
+	}
+
+	return router
+}
+
 // initAIService 初始化 AI 服务
-func initAIService(genkitClient genkit.Client, cfg *config.Config, log logger.Logger) ai.AIService {
+func initAIService(genkitClient genkit.Client, providerService service.ProviderService, cfg *config.Config, log logger.Logger) ai.AIService {
 	log.Info("初始化 AI 服务...", logger.Fields{
 		"sessionTimeout":        cfg.Session.Timeout,
 		"sessionCleanupInterval": cfg.Session.CleanupInterval,
@@ -365,16 +579,19 @@ func initAIService(genkitClient genkit.Client, cfg *config.Config, log logger.Lo
 	// 启动上下文管理器的自动清理
 	contextManager.Start()
 
+	// 创建模型参数规则引擎，未指定模型的请求按默认的 Gemini 模型校验
+	parameterRules := service.NewParameterRuleEngine(providerService, genkit.ProviderGemini+"/"+cfg.Genkit.Model)
+
 	// 创建 AI 服务
-	aiService := ai.NewGenkitService(genkitClient, contextManager, log)
+	aiService := ai.NewGenkitService(genkitClient, contextManager, parameterRules, log)
 
 	log.Info("AI 服务初始化成功", nil)
 
 	return aiService
 }
 
-// initProviderService 初始化模型提供商服务
-func initProviderService(cfg *config.Config, log logger.Logger) (service.ProviderService, error) {
+// initProviderService 初始化模型提供商服务，同时返回用于重新加载模型目录的加载器
+func initProviderService(cfg *config.Config, log logger.Logger) (service.ProviderService, loader.Reloader, error) {
 	log.Info("初始化模型提供商服务...", nil)
 
 	// 1. 创建内存存储实例
@@ -386,19 +603,119 @@ func initProviderService(cfg *config.Config, log logger.Logger) (service.Provide
 	// 3. 执行数据加载
 	// 使用配置中的模型目录路径（已包含默认值）
 	if err := modelLoader.LoadAll(cfg.Models.Dir); err != nil {
-		return nil, fmt.Errorf("加载模型数据失败: %w", err)
+		return nil, nil, fmt.Errorf("加载模型数据失败: %w", err)
 	}
 
 	// 4. 创建服务层实例
 	providerService := service.NewProviderService(store)
+	catalogReloader := loader.NewReloader(modelLoader, store, cfg.Models.Dir, log)
 
 	log.Info("模型提供商服务初始化成功", nil)
 
-	return providerService, nil
+	return providerService, catalogReloader, nil
+}
+
+// initFileService 初始化上传文件服务，文件内容保存在本地文件存储目录
+// 存储目录不可用时返回 nil
+func initFileService(db database.Database, cfg *config.Config, log logger.Logger) file.FileService {
+	blobStore, err := storage.NewLocalBlobStore(cfg.Files.Dir)
+	if err != nil {
+		log.Warn("初始化文件存储失败", logger.Fields{"error": err, "dir": cfg.Files.Dir})
+		return nil
+	}
+
+	fileRepo := repository.NewFileRepository(db.GetDB())
+	fileService := file.NewFileService(fileRepo, blobStore, int64(cfg.Files.MaxSizeMB)<<20, log)
+
+	log.Info("上传文件服务初始化成功", logger.Fields{
+		"dir":       cfg.Files.Dir,
+		"maxSizeMB": cfg.Files.MaxSizeMB,
+	})
+
+	return fileService
+}
+
+// initKnowledgeService 初始化知识库服务
+// fileService 为 nil 时不能从上传文件添加文档；配置的重排序模型不可用时检索结果不重排序
+func initKnowledgeService(db database.Database, embeddingService ai.EmbeddingService, rerankService ai.RerankService, providerService service.ProviderService, fileService file.FileService, cfg *config.Config, log logger.Logger) knowledge.KnowledgeService {
+	rerankModel := cfg.Knowledge.RerankModel
+	if rerankModel != "" {
+		if _, mdl, err := providerService.ResolveModel(rerankModel); err != nil {
+			log.Warn("知识库重排序模型不可用，检索结果不重排序", logger.Fields{"model": rerankModel, "error": err.Error()})
+			rerankModel = ""
+		} else if mdl.ModelType != model.ModelTypeRerank {
+			log.Warn("知识库重排序模型不是 rerank 类型，检索结果不重排序", logger.Fields{"model": rerankModel})
+			rerankModel = ""
+		}
+	}
+
+	knowledgeRepo := repository.NewKnowledgeRepository(db.GetDB())
+	knowledgeService := knowledge.NewKnowledgeService(knowledgeRepo, embeddingService, providerService, fileService, cfg.Knowledge.TopK, rerankService, rerankModel, log)
+
+	log.Info("知识库服务初始化成功", logger.Fields{
+		"topK":        cfg.Knowledge.TopK,
+		"rerankModel": rerankModel,
+	})
+
+	return knowledgeService
+}
+
+// initCredentialCipher 使用配置的主密钥初始化凭证加密，主密钥无效时返回 nil
+func initCredentialCipher(cfg *config.Config, log logger.Logger) credential.Cipher {
+	cipher, err := credential.NewAESCipher(cfg.Credentials.MasterKey)
+	if err != nil {
+		log.Warn("初始化凭证加密失败", logger.Fields{"error": err})
+		return nil
+	}
+	return cipher
+}
+
+// initCredentialService 初始化模型提供商凭证服务，凭证使用配置的主密钥加密保存
+func initCredentialService(db database.Database, providerService service.ProviderService, cipher credential.Cipher, log logger.Logger) credential.CredentialService {
+	credentialRepo := repository.NewCredentialRepository(db.GetDB())
+	credentialService := credential.NewCredentialService(credentialRepo, providerService, cipher, log)
+
+	log.Info("模型提供商凭证服务初始化成功", nil)
+
+	return credentialService
+}
+
+// initCustomModelService 初始化自定义模型服务，并将数据库中的自定义模型合并到模型目录
+// 自定义模型使用自身凭证创建后端客户端并注册到模型路由
+func initCustomModelService(db database.Database, providerService service.ProviderService, modelRouter *genkit.Router, cipher credential.Cipher, cfg *config.Config, log logger.Logger) custommodel.CustomModelService {
+	defaults := &genkit.Config{
+		DefaultTemperature: cfg.Genkit.DefaultTemperature,
+		DefaultMaxTokens:   cfg.Genkit.DefaultMaxTokens,
+	}
+	newBackend := func(ctx context.Context, providerID string, credentials map[string]string) (genkit.Client, error) {
+		return genkit.NewClientFromCredentials(ctx, providerID, credentials, defaults)
+	}
+
+	customModelRepo := repository.NewCustomModelRepository(db.GetDB())
+	customModelService := custommodel.NewCustomModelService(customModelRepo, providerService, modelRouter, newBackend, cipher, log)
+
+	loaded, err := customModelService.LoadModels(context.Background())
+	if err != nil {
+		log.Warn("加载自定义模型失败", logger.Fields{"error": err})
+	}
+
+	log.Info("自定义模型服务初始化成功", logger.Fields{"models": loaded})
+
+	return customModelService
+}
+
+// sessionComponents 会话管理相关组件
+type sessionComponents struct {
+	sessionHandler   *handler.SessionHandler
+	messageHandler   *handler.MessageHandler
+	summaryHandler   *handler.SummaryHandler
+	summaryScheduler session.SummaryScheduler
+	messageService   session.MessageService
 }
 
 // initSessionHandlers 初始化会话管理相关的处理器
-func initSessionHandlers(db database.Database, aiService ai.AIService, cfg *config.Config, log logger.Logger) (*handler.SessionHandler, *handler.MessageHandler) {
+// fileService 为 nil 时消息片段不能引用上传文件；knowledgeService 为 nil 时会话不能挂载知识库
+func initSessionHandlers(db database.Database, aiService ai.AIService, providerService service.ProviderService, fileService file.FileService, knowledgeService knowledge.KnowledgeService, cfg *config.Config, log logger.Logger) *sessionComponents {
 	log.Info("初始化会话管理服务...", nil)
 
 	// 1. 获取 GORM 数据库实例
@@ -408,30 +725,42 @@ func initSessionHandlers(db database.Database, aiService ai.AIService, cfg *conf
 	sessionRepo := repository.NewSessionRepository(gormDB)
 	messageRepo := repository.NewMessageRepository(gormDB)
 	summaryRepo := repository.NewSummaryRepository(gormDB)
+	attachmentRepo := repository.NewAttachmentRepository(gormDB)
 
 	// 3. 创建 Service 层实例
-	// 3.1 创建 SessionService
-	sessionService := session.NewSessionService(sessionRepo, messageRepo)
+	// 3.1 创建工具注册表，会话只能启用已注册的工具
+	toolRegistry := tool.NewRegistry()
+	if err := toolRegistry.Register(tool.NewCurrentTimeTool()); err != nil {
+		log.Warn("注册工具失败", logger.Fields{"error": err})
+	}
+	sessionService := session.NewSessionService(sessionRepo, messageRepo, toolRegistry, knowledgeService)
 	
-	// 3.2 创建 SummaryService
+	// 3.2 创建 SummaryService 和后台摘要调度器
 	summaryService := session.NewSummaryService(summaryRepo, messageRepo, sessionRepo, aiService, cfg, log)
+	summaryScheduler := session.NewSummaryScheduler(summaryService, cfg.Session.SummaryWorkers, cfg.Session.SummaryQueueSize, log)
+	summaryScheduler.Start()
 	
-	// 3.3 创建 MessageService
-	messageService := session.NewMessageService(gormDB, sessionRepo, messageRepo, aiService, log)
-	
-	// 注意：SummaryService 已初始化但当前未直接使用，
-	// 它可以在未来的功能中被 MessageService 或其他服务调用
-	_ = summaryService
+	// 3.3 创建 MessageService，发送消息后在后台检查是否需要生成摘要
+	messageService := session.NewMessageService(gormDB, sessionRepo, messageRepo, summaryRepo, attachmentRepo, fileService, knowledgeService, aiService, providerService, toolRegistry, summaryScheduler, log)
 
 	// 4. 创建 Handler 层实例
 	sessionHandler := handler.NewSessionHandler(sessionService, log)
 	messageHandler := handler.NewMessageHandler(messageService, log)
+	summaryHandler := handler.NewSummaryHandler(summaryService, summaryScheduler, log)
 
 	log.Info("会话管理服务初始化成功", logger.Fields{
-		"repositories": []string{"SessionRepository", "MessageRepository", "SummaryRepository"},
-		"services":     []string{"SessionService", "MessageService", "SummaryService"},
-		"handlers":     []string{"SessionHandler", "MessageHandler"},
+		"repositories":   []string{"SessionRepository", "MessageRepository", "SummaryRepository"},
+		"services":       []string{"SessionService", "MessageService", "SummaryService", "SummaryScheduler"},
+		"handlers":       []string{"SessionHandler", "MessageHandler", "SummaryHandler"},
+		"summaryWorkers": cfg.Session.SummaryWorkers,
+		"tools":          len(toolRegistry.List()),
 	})
 
-	return sessionHandler, messageHandler
+	return &sessionComponents{
+		sessionHandler:   sessionHandler,
+		messageHandler:   messageHandler,
+		summaryHandler:   summaryHandler,
+		summaryScheduler: summaryScheduler,
+		messageService:   messageService,
+	}
 }
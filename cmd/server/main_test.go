@@ -85,7 +85,7 @@ func TestInitAIService(t *testing.T) {
 		// 创建一个 mock Genkit 客户端
 		mockClient := &mockGenkitClient{}
 
-		service := initAIService(mockClient, cfg, log)
+		service := initAIService(mockClient, nil, cfg, log)
 		if service == nil {
 			t.Error("期望返回服务实例，但得到 nil")
 		}
@@ -111,6 +111,13 @@ func (m *mockGenkitClient) Generate(ctx context.Context, prompt string, options
 	}, nil
 }
 
+func (m *mockGenkitClient) GenerateStream(ctx context.Context, prompt string, options *genkit.GenerateOptions, callback genkit.StreamCallback) (*genkit.GenerateResult, error) {
+	if err := callback(ctx, "mock response"); err != nil {
+		return nil, err
+	}
+	return m.Generate(ctx, prompt, options)
+}
+
 func (m *mockGenkitClient) SetModel(model ai.Model) {
 	// Mock 实现，不需要实际设置模型
 }
@@ -134,7 +141,7 @@ func TestInitProviderService(t *testing.T) {
 			},
 		}
 
-		_, err := initProviderService(cfg, log)
+		_, _, err := initProviderService(cfg, log)
 		if err == nil {
 			t.Error("期望返回错误，但得到 nil")
 		}
@@ -147,7 +154,7 @@ func TestInitProviderService(t *testing.T) {
 			},
 		}
 
-		service, err := initProviderService(cfg, log)
+		service, _, err := initProviderService(cfg, log)
 		// 如果 models 目录存在且有有效数据，应该成功
 		// 如果不存在，会返回错误
 		if err != nil {
@@ -30,12 +30,12 @@ func NewAbortHandler(aiService ai.AIService, log logger.Logger) *AbortHandler {
 
 // HandleAbort 处理中止对话请求
 // @Summary 中止对话
-// @Description 中止指定消息的对话处理
+// @Description 中止指定消息或会话正在进行的对话处理，返回是否实际取消了生成
 // @Tags chat
 // @Accept json
 // @Produce json
 // @Param request body model.AbortRequest true "中止请求"
-// @Success 200 {object} model.SuccessResponse "成功中止对话"
+// @Success 200 {object} model.ResponseData[model.AbortResponse] "成功中止对话"
 // @Failure 400 {object} model.ErrorResponse "请求参数错误"
 // @Failure 404 {object} model.ErrorResponse "消息不存在"
 // @Failure 422 {object} model.ErrorResponse "参数验证失败"
@@ -62,13 +62,20 @@ func (h *AbortHandler) HandleAbort(w http.ResponseWriter, r *http.Request) {
 	// 3. 记录请求日志
 	h.logger.Info("收到中止对话请求", logger.Fields{
 		"messageId": req.MessageID,
+		"sessionId": req.SessionID,
 	})
 
-	// 4. 调用 AI 服务中止对话
-	err := h.aiService.AbortChat(ctx, req.MessageID)
+	// 4. 调用 AI 服务中止对话，优先按消息ID中止
+	abortKey := req.MessageID
+	if abortKey == "" {
+		abortKey = req.SessionID
+	}
+
+	aborted, err := h.aiService.AbortChat(ctx, abortKey)
 	if err != nil {
 		h.logger.Error("中止对话失败", logger.Fields{
 			"messageId": req.MessageID,
+			"sessionId": req.SessionID,
 			"error":     err,
 		})
 
@@ -82,18 +89,24 @@ func (h *AbortHandler) HandleAbort(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 5. 记录成功日志
-	h.logger.Info("对话已成功中止", logger.Fields{
+	h.logger.Info("中止对话请求处理完成", logger.Fields{
 		"messageId": req.MessageID,
+		"sessionId": req.SessionID,
+		"aborted":   aborted,
 	})
 
 	// 6. 构建并返回成功响应
-	h.writeSuccessResponse(w)
+	h.writeSuccessResponse(w, aborted)
 }
 
 // writeSuccessResponse 写入成功响应
-func (h *AbortHandler) writeSuccessResponse(w http.ResponseWriter) {
-	resp := response.Success[any](nil)
-	resp.Message = "对话已成功中止"
+func (h *AbortHandler) writeSuccessResponse(w http.ResponseWriter, aborted bool) {
+	resp := response.Success(&model.AbortResponse{Aborted: aborted})
+	if aborted {
+		resp.Message = "对话已成功中止"
+	} else {
+		resp.Message = "没有正在进行的对话"
+	}
 	h.writeJSONResponse(w, http.StatusOK, resp)
 }
 
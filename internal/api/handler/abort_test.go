@@ -16,7 +16,7 @@ import (
 
 // mockAIServiceForAbort 用于测试的 mock AI 服务
 type mockAIServiceForAbort struct {
-	abortChatFunc func(ctx context.Context, messageID string) error
+	abortChatFunc func(ctx context.Context, messageID string) (bool, error)
 }
 
 func (m *mockAIServiceForAbort) Chat(ctx context.Context, req *model.ChatRequest) (*model.ChatResponse, error) {
@@ -27,21 +27,21 @@ func (m *mockAIServiceForAbort) ChatStream(ctx context.Context, req *model.ChatR
 	return nil, nil
 }
 
-func (m *mockAIServiceForAbort) AbortChat(ctx context.Context, messageID string) error {
+func (m *mockAIServiceForAbort) AbortChat(ctx context.Context, messageID string) (bool, error) {
 	if m.abortChatFunc != nil {
 		return m.abortChatFunc(ctx, messageID)
 	}
-	return nil
+	return false, nil
 }
 
 func TestAbortHandler_HandleAbort_Success(t *testing.T) {
 	// 创建 mock 服务
 	mockService := &mockAIServiceForAbort{
-		abortChatFunc: func(ctx context.Context, messageID string) error {
+		abortChatFunc: func(ctx context.Context, messageID string) (bool, error) {
 			if messageID == "550e8400-e29b-41d4-a716-446655440000" {
-				return nil
+				return true, nil
 			}
-			return errors.NewNotFoundError("消息不存在")
+			return false, errors.NewNotFoundError("消息不存在")
 		},
 	}
 
@@ -85,8 +85,8 @@ func TestAbortHandler_HandleAbort_Success(t *testing.T) {
 func TestAbortHandler_HandleAbort_MessageNotFound(t *testing.T) {
 	// 创建 mock 服务
 	mockService := &mockAIServiceForAbort{
-		abortChatFunc: func(ctx context.Context, messageID string) error {
-			return errors.NewNotFoundError("消息不存在或已完成")
+		abortChatFunc: func(ctx context.Context, messageID string) (bool, error) {
+			return false, errors.NewNotFoundError("消息不存在或已完成")
 		},
 	}
 
@@ -123,6 +123,41 @@ func TestAbortHandler_HandleAbort_MessageNotFound(t *testing.T) {
 	}
 }
 
+func TestAbortHandler_HandleAbort_BySessionID(t *testing.T) {
+	var abortedKey string
+	mockService := &mockAIServiceForAbort{
+		abortChatFunc: func(ctx context.Context, messageID string) (bool, error) {
+			abortedKey = messageID
+			return false, nil
+		},
+	}
+
+	log := logger.New(logger.ErrorLevel, logger.JSONFormat, io.Discard)
+	handler := NewAbortHandler(mockService, log)
+
+	body, _ := json.Marshal(model.AbortRequest{SessionID: "chat-session-1"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chat/abort", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	handler.HandleAbort(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 实际得到 %d", http.StatusOK, w.Code)
+	}
+	if abortedKey != "chat-session-1" {
+		t.Errorf("期望按会话ID中止, 实际为 %s", abortedKey)
+	}
+
+	var resp model.ResponseData[model.AbortResponse]
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if resp.Data == nil || resp.Data.Aborted {
+		t.Errorf("期望 aborted 为 false, 实际 %+v", resp.Data)
+	}
+}
+
 func TestAbortHandler_HandleAbort_MissingMessageID(t *testing.T) {
 	// 创建 mock 服务
 	mockService := &mockAIServiceForAbort{}
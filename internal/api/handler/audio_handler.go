@@ -0,0 +1,261 @@
+package handler
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"io"
+	"net/http"
+	"time"
+
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/service/audio"
+	"genkit-ai-service/pkg/errors"
+	"genkit-ai-service/pkg/response"
+	"genkit-ai-service/pkg/validator"
+)
+
+// audioChunkSize 流式写出音频时每次读取的字节数
+const audioChunkSize = 32 * 1024
+
+// transcriptionMaxBodySize 语音识别请求体的大小上限，各模型的文件大小上限由服务层校验
+const transcriptionMaxBodySize = 128 << 20
+
+// transcriptionFormMemory 解析语音识别表单时保存在内存中的最大字节数，超出部分写入临时文件
+const transcriptionFormMemory = 8 << 20
+
+// AudioHandler 语音接口处理器
+type AudioHandler struct {
+	speechService        audio.SpeechService
+	transcriptionService audio.TranscriptionService
+	logger               logger.Logger
+	validator            *validator.Validator
+}
+
+// NewAudioHandler 创建语音接口处理器实例
+func NewAudioHandler(speechService audio.SpeechService, transcriptionService audio.TranscriptionService, log logger.Logger) *AudioHandler {
+	return &AudioHandler{
+		speechService:        speechService,
+		transcriptionService: transcriptionService,
+		logger:               log,
+		validator:            validator.New(),
+	}
+}
+
+// HandleSpeech 处理语音合成请求
+// @Summary 语音合成
+// @Description 使用模型目录中的 tts 模型将文本或会话消息的内容合成为语音，以音频流返回。
+// @Description 未指定音色时使用模型的默认音色；文本超过模型的字数限制时按句子拆分后依次合成
+// @Tags audio
+// @Accept json
+// @Produce audio/mpeg
+// @Param request body model.SpeechRequest true "语音合成请求"
+// @Success 200 {file} binary "音频流，Content-Type 由模型的音频格式决定"
+// @Failure 400 {object} model.ErrorResponse "请求参数错误、模型不是语音合成模型或不支持该音色"
+// @Failure 403 {object} model.ErrorResponse "无权访问消息"
+// @Failure 404 {object} model.ErrorResponse "模型或消息不存在"
+// @Failure 422 {object} model.ErrorResponse "参数验证失败"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Failure 503 {object} model.ErrorResponse "模型提供商或会话服务不可用"
+// @Router /audio/speech [post]
+func (h *AudioHandler) HandleSpeech(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 解析请求参数
+	var req model.SpeechRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("解析请求参数失败", logger.Fields{"error": err})
+		h.writeErrorResponse(w, errors.NewBadRequestError("无效的请求参数"))
+		return
+	}
+
+	// 2. 验证请求参数
+	if validationErrors := h.validator.ValidateStruct(&req); validationErrors != nil {
+		h.logger.Warn("请求参数验证失败", logger.Fields{"errors": validationErrors})
+		h.writeValidationErrorResponse(w, validationErrors)
+		return
+	}
+
+	// 3. 从上下文获取用户ID
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		userID = "default-user-id" // 临时默认值
+	}
+
+	h.logger.Info("收到语音合成请求", logger.Fields{
+		"model":     req.Model,
+		"voice":     req.Voice,
+		"messageId": req.MessageID,
+	})
+
+	// 4. 调用服务层开始合成
+	speech, err := h.speechService.Synthesize(ctx, userID, &req)
+	if err != nil {
+		h.logger.Error("语音合成失败", logger.Fields{"error": err, "model": req.Model})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+	defer speech.Audio.Close()
+
+	// 5. 流式写出音频，合成时间可能超过服务器的 WriteTimeout，因此清除写截止时间
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", speech.ContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	buf := make([]byte, audioChunkSize)
+	for {
+		n, err := speech.Audio.Read(buf)
+		if n > 0 {
+			if _, writeErr := w.Write(buf[:n]); writeErr != nil {
+				h.logger.Warn("写入音频失败，客户端可能已断开", logger.Fields{"error": writeErr})
+				return
+			}
+			_ = rc.Flush()
+		}
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			// 响应头已发送，只能记录错误并中断音频流
+			h.logger.Error("读取合成音频失败", logger.Fields{"error": err, "model": req.Model})
+			return
+		}
+	}
+}
+
+// HandleTranscription 处理语音识别请求
+// @Summary 语音识别
+// @Description 使用模型目录中的 speech2text 模型将上传的音频转写为文本。音频放在 file 字段中，
+// @Description 格式由文件扩展名决定，必须在模型支持的格式内，大小不能超过模型的上传上限。
+// @Description 指定 sessionId 时识别出的文本作为用户消息追加到会话当前分支的末尾（不生成 AI 回复）
+// @Tags audio
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "音频文件"
+// @Param model formData string true "语音识别模型名称" example(tongyi/paraformer-realtime-v2)
+// @Param language formData string false "音频的语言" example(zh)
+// @Param sessionId formData string false "追加识别结果的会话ID"
+// @Success 200 {object} model.ResponseData[model.TranscriptionResponse] "识别结果"
+// @Failure 400 {object} model.ErrorResponse "请求参数错误、模型不是语音识别模型或不支持该音频格式"
+// @Failure 403 {object} model.ErrorResponse "无权访问会话"
+// @Failure 404 {object} model.ErrorResponse "模型或会话不存在"
+// @Failure 413 {object} model.ErrorResponse "音频文件过大"
+// @Failure 422 {object} model.ErrorResponse "参数验证失败"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Failure 503 {object} model.ErrorResponse "模型提供商或会话服务不可用"
+// @Router /audio/transcriptions [post]
+func (h *AudioHandler) HandleTranscription(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 解析 multipart 表单，表单字段可能位于文件之后，因此先完整解析
+	r.Body = http.MaxBytesReader(w, r.Body, transcriptionMaxBodySize)
+	if err := r.ParseMultipartForm(transcriptionFormMemory); err != nil {
+		h.logger.Warn("解析语音识别请求失败", logger.Fields{"error": err})
+		var maxBytesErr *http.MaxBytesError
+		if stderrors.As(err, &maxBytesErr) {
+			h.writeErrorResponse(w, errors.NewFileTooLargeError(transcriptionMaxBodySize))
+			return
+		}
+		h.writeErrorResponse(w, errors.NewBadRequestError("请求必须为 multipart/form-data"))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	req := model.TranscriptionRequest{
+		Model:     r.FormValue("model"),
+		Language:  r.FormValue("language"),
+		SessionID: r.FormValue("sessionId"),
+	}
+
+	// 2. 验证请求参数
+	if validationErrors := h.validator.ValidateStruct(&req); validationErrors != nil {
+		h.logger.Warn("请求参数验证失败", logger.Fields{"errors": validationErrors})
+		h.writeValidationErrorResponse(w, validationErrors)
+		return
+	}
+
+	file, header, err := r.FormFile(uploadFieldName)
+	if err != nil {
+		h.writeErrorResponse(w, errors.NewBadRequestError("缺少 file 字段"))
+		return
+	}
+	defer file.Close()
+
+	// 3. 从上下文获取用户ID
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		userID = "default-user-id" // 临时默认值
+	}
+
+	h.logger.Info("收到语音识别请求", logger.Fields{
+		"model":     req.Model,
+		"fileName":  header.Filename,
+		"size":      header.Size,
+		"sessionId": req.SessionID,
+	})
+
+	// 4. 调用服务层识别
+	resp, err := h.transcriptionService.Transcribe(ctx, userID, &req, header.Filename, file)
+	if err != nil {
+		h.logger.Error("语音识别失败", logger.Fields{"error": err, "model": req.Model})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 5. 返回成功响应
+	h.writeJSONResponse(w, http.StatusOK, response.Success(resp))
+}
+
+// writeErrorResponse 写入错误响应
+func (h *AudioHandler) writeErrorResponse(w http.ResponseWriter, appErr *errors.AppError) {
+	resp := response.Error[any](appErr.Code, appErr.Message)
+
+	// 根据错误码确定 HTTP 状态码
+	statusCode := http.StatusInternalServerError
+	switch appErr.Code {
+	case errors.CodeBadRequest:
+		statusCode = http.StatusBadRequest
+	case errors.CodeValidationError:
+		statusCode = http.StatusUnprocessableEntity
+	case errors.CodeNotFound, errors.CodeModelNotFound, errors.CodeProviderNotFound,
+		errors.CodeMessageNotFound, errors.CodeSessionNotFound:
+		statusCode = http.StatusNotFound
+	case errors.CodeMessageAccessDenied, errors.CodeSessionAccessDenied:
+		statusCode = http.StatusForbidden
+	case errors.CodeFileTooLarge:
+		statusCode = http.StatusRequestEntityTooLarge
+	case errors.CodeServiceUnavailable:
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	h.writeJSONResponse(w, statusCode, resp)
+}
+
+// writeValidationErrorResponse 写入验证错误响应
+func (h *AudioHandler) writeValidationErrorResponse(w http.ResponseWriter, validationErrors []validator.ValidationError) {
+	errorData := map[string]interface{}{
+		"errors": validationErrors,
+	}
+
+	resp := response.ErrorWithData(
+		errors.CodeValidationError,
+		errors.MsgValidationError,
+		&errorData,
+	)
+
+	h.writeJSONResponse(w, http.StatusUnprocessableEntity, resp)
+}
+
+// writeJSONResponse 写入 JSON 响应
+func (h *AudioHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("写入响应失败", logger.Fields{"error": err})
+	}
+}
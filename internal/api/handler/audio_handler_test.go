@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/service/audio"
+	"genkit-ai-service/pkg/errors"
+)
+
+// mockSpeechService 模拟语音合成服务，音频内容为输入文本
+type mockSpeechService struct {
+	lastReq    *model.SpeechRequest
+	lastUserID string
+}
+
+func (m *mockSpeechService) Synthesize(ctx context.Context, userID string, req *model.SpeechRequest) (*audio.Speech, error) {
+	m.lastReq = req
+	m.lastUserID = userID
+	switch {
+	case req.Model == "qwen-plus":
+		return nil, errors.NewBadRequestError("模型 'qwen-plus' 不是语音合成模型")
+	case req.MessageID == "550e8400-e29b-41d4-a716-446655440001":
+		return nil, errors.NewMessageAccessDeniedError()
+	}
+	return &audio.Speech{
+		Model:       req.Model,
+		Voice:       "sambert-zhiru-v1",
+		Format:      "mp3",
+		ContentType: "audio/mpeg",
+		Audio:       io.NopCloser(strings.NewReader(req.Input)),
+	}, nil
+}
+
+// mockTranscriptionService 模拟语音识别服务，识别结果为音频内容
+type mockTranscriptionService struct {
+	lastReq      *model.TranscriptionRequest
+	lastFileName string
+}
+
+func (m *mockTranscriptionService) Transcribe(ctx context.Context, userID string, req *model.TranscriptionRequest, fileName string, audio io.Reader) (*model.TranscriptionResponse, error) {
+	m.lastReq = req
+	m.lastFileName = fileName
+	if strings.HasSuffix(fileName, ".txt") {
+		return nil, errors.NewBadRequestError("不支持的音频格式")
+	}
+	data, _ := io.ReadAll(audio)
+	if len(data) > 16 {
+		return nil, errors.NewFileTooLargeError(16)
+	}
+	resp := &model.TranscriptionResponse{Model: req.Model, Text: string(data)}
+	if req.SessionID != "" {
+		resp.MessageID = "msg-1"
+	}
+	return resp, nil
+}
+
+// newTranscriptionRequest 构建 multipart 语音识别请求，fields 为表单字段，fileName 为空时不包含文件
+func newTranscriptionRequest(t *testing.T, fields map[string]string, fileName, content string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if fileName != "" {
+		part, err := writer.CreateFormFile("file", fileName)
+		if err != nil {
+			t.Fatalf("创建表单文件失败: %v", err)
+		}
+		part.Write([]byte(content))
+	}
+	// 表单字段放在文件之后
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			t.Fatalf("写入表单失败: %v", err)
+		}
+	}
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/audio/transcriptions", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestHandleSpeech(t *testing.T) {
+	t.Run("成功合成", func(t *testing.T) {
+		service := &mockSpeechService{}
+		handler := NewAudioHandler(service, &mockTranscriptionService{}, logger.Default())
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/audio/speech",
+			strings.NewReader(`{"model":"tongyi/tts-1","input":"你好","voice":"sambert-zhiru-v1"}`))
+		req.Header.Set("X-User-ID", "test-user")
+		w := httptest.NewRecorder()
+		handler.HandleSpeech(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("期望状态码 200, 得到 %d: %s", w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("Content-Type"); got != "audio/mpeg" {
+			t.Errorf("Content-Type = %s, want audio/mpeg", got)
+		}
+		if w.Body.String() != "你好" {
+			t.Errorf("音频内容 = %q", w.Body.String())
+		}
+		if service.lastUserID != "test-user" || service.lastReq.Voice != "sambert-zhiru-v1" {
+			t.Errorf("请求参数不正确: %+v, userID=%s", service.lastReq, service.lastUserID)
+		}
+	})
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "无效的 JSON", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "缺少模型", body: `{"input":"a"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "缺少文本和消息", body: `{"model":"tongyi/tts-1"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "消息ID无效", body: `{"model":"tongyi/tts-1","messageId":"abc"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "不是语音合成模型", body: `{"model":"qwen-plus","input":"a"}`, wantStatus: http.StatusBadRequest},
+		{name: "无权访问消息", body: `{"model":"tongyi/tts-1","messageId":"550e8400-e29b-41d4-a716-446655440001"}`, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAudioHandler(&mockSpeechService{}, &mockTranscriptionService{}, logger.Default())
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/audio/speech", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			handler.HandleSpeech(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("期望状态码 %d, 得到 %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleTranscription(t *testing.T) {
+	t.Run("成功识别", func(t *testing.T) {
+		service := &mockTranscriptionService{}
+		handler := NewAudioHandler(&mockSpeechService{}, service, logger.Default())
+
+		req := newTranscriptionRequest(t, map[string]string{
+			"model":     "tongyi/paraformer-realtime-v2",
+			"sessionId": "550e8400-e29b-41d4-a716-446655440000",
+		}, "voice.wav", "你好")
+		w := httptest.NewRecorder()
+		handler.HandleTranscription(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("期望状态码 200, 得到 %d: %s", w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), `"text":"你好"`) || !strings.Contains(w.Body.String(), `"messageId":"msg-1"`) {
+			t.Errorf("响应不正确: %s", w.Body.String())
+		}
+		if service.lastFileName != "voice.wav" || service.lastReq.Model != "tongyi/paraformer-realtime-v2" {
+			t.Errorf("请求参数不正确: %+v, fileName=%s", service.lastReq, service.lastFileName)
+		}
+	})
+
+	tests := []struct {
+		name       string
+		req        func(t *testing.T) *http.Request
+		wantStatus int
+	}{
+		{name: "不是 multipart 请求", req: func(t *testing.T) *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/api/v1/audio/transcriptions", strings.NewReader(`{}`))
+		}, wantStatus: http.StatusBadRequest},
+		{name: "缺少模型", req: func(t *testing.T) *http.Request {
+			return newTranscriptionRequest(t, nil, "voice.wav", "a")
+		}, wantStatus: http.StatusUnprocessableEntity},
+		{name: "会话ID无效", req: func(t *testing.T) *http.Request {
+			return newTranscriptionRequest(t, map[string]string{"model": "m", "sessionId": "abc"}, "voice.wav", "a")
+		}, wantStatus: http.StatusUnprocessableEntity},
+		{name: "缺少文件", req: func(t *testing.T) *http.Request {
+			return newTranscriptionRequest(t, map[string]string{"model": "m"}, "", "")
+		}, wantStatus: http.StatusBadRequest},
+		{name: "不支持的格式", req: func(t *testing.T) *http.Request {
+			return newTranscriptionRequest(t, map[string]string{"model": "m"}, "voice.txt", "a")
+		}, wantStatus: http.StatusBadRequest},
+		{name: "文件过大", req: func(t *testing.T) *http.Request {
+			return newTranscriptionRequest(t, map[string]string{"model": "m"}, "voice.wav", strings.Repeat("a", 17))
+		}, wantStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAudioHandler(&mockSpeechService{}, &mockTranscriptionService{}, logger.Default())
+			w := httptest.NewRecorder()
+			handler.HandleTranscription(w, tt.req(t))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("期望状态码 %d, 得到 %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"strings"
+
+	"genkit-ai-service/internal/loader"
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/pkg/errors"
+	"genkit-ai-service/pkg/response"
+)
+
+// CatalogHandler 模型目录管理处理器
+type CatalogHandler struct {
+	reloader   loader.Reloader
+	adminToken string
+	logger     logger.Logger
+}
+
+// NewCatalogHandler 创建模型目录管理处理器实例
+// adminToken 为管理接口的访问令牌，请求需在 Authorization 请求头中以 Bearer 方式携带
+func NewCatalogHandler(reloader loader.Reloader, adminToken string, log logger.Logger) *CatalogHandler {
+	return &CatalogHandler{
+		reloader:   reloader,
+		adminToken: adminToken,
+		logger:     log,
+	}
+}
+
+// ReloadModels 重新加载模型目录
+// @Summary 重新加载模型目录
+// @Description 重新加载 MODELS_DIR 下的提供商和模型配置，无需重启服务。新数据加载到独立的快照，没有错误时原子地替换当前目录并返回新增、移除和变更的提供商和模型；
+// @Description 任何配置文件有错误时保留当前目录并返回错误列表。通过接口注册的自定义模型在重新加载后保留
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "管理接口令牌，格式为 Bearer <ADMIN_TOKEN>"
+// @Success 200 {object} model.ResponseData[model.CatalogReloadReport] "成功重新加载模型目录"
+// @Failure 401 {object} model.ErrorResponse "管理接口令牌无效"
+// @Failure 422 {object} model.ErrorResponse "配置文件有错误，已保留当前目录"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /admin/models/reload [post]
+func (h *CatalogHandler) ReloadModels(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	h.logger.Info("收到重新加载模型目录请求", nil)
+
+	// 1. 验证管理接口令牌
+	if !h.authorized(r) {
+		h.logger.Warn("管理接口令牌无效", nil)
+		h.writeErrorResponse(w, errors.New(errors.CodeUnauthorized, errors.MsgUnauthorized))
+		return
+	}
+
+	// 2. 重新加载模型目录
+	report, err := h.reloader.Reload(ctx)
+	if err != nil {
+		h.logger.Error("重新加载模型目录失败", logger.Fields{"error": err})
+
+		// 配置文件有错误时返回错误列表
+		var loadErrs loader.LoadErrors
+		if stderrors.As(err, &loadErrs) {
+			h.writeLoadErrorResponse(w, loadErrs)
+			return
+		}
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 3. 返回成功响应
+	h.writeJSONResponse(w, http.StatusOK, response.Success(report))
+}
+
+// authorized 检查请求是否携带正确的管理接口令牌，未配置令牌时拒绝所有请求
+func (h *CatalogHandler) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || h.adminToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(h.adminToken)) == 1
+}
+
+// writeLoadErrorResponse 写入配置文件错误响应
+func (h *CatalogHandler) writeLoadErrorResponse(w http.ResponseWriter, loadErrs loader.LoadErrors) {
+	messages := make([]string, len(loadErrs))
+	for i, loadErr := range loadErrs {
+		messages[i] = loadErr.Error()
+	}
+	errorData := map[string]interface{}{
+		"errors": messages,
+	}
+
+	resp := response.ErrorWithData(
+		errors.CodeLoadDataError,
+		errors.MsgLoadDataError,
+		&errorData,
+	)
+
+	h.writeJSONResponse(w, http.StatusUnprocessableEntity, resp)
+}
+
+// writeErrorResponse 写入错误响应
+func (h *CatalogHandler) writeErrorResponse(w http.ResponseWriter, appErr *errors.AppError) {
+	resp := response.Error[any](appErr.Code, appErr.Message)
+
+	// 根据错误码确定 HTTP 状态码
+	statusCode := http.StatusInternalServerError
+	switch appErr.Code {
+	case errors.CodeUnauthorized:
+		statusCode = http.StatusUnauthorized
+	case errors.CodeLoadDataError:
+		statusCode = http.StatusUnprocessableEntity
+	case errors.CodeServiceUnavailable:
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	h.writeJSONResponse(w, statusCode, resp)
+}
+
+// writeJSONResponse 写入 JSON 响应
+func (h *CatalogHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("写入响应失败", logger.Fields{"error": err})
+	}
+}
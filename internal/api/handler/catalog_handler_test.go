@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"genkit-ai-service/internal/loader"
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/pkg/errors"
+)
+
+// mockReloader 模拟模型目录重新加载器
+type mockReloader struct {
+	report *model.CatalogReloadReport
+	err    error
+}
+
+func (m *mockReloader) Reload(ctx context.Context) (*model.CatalogReloadReport, error) {
+	return m.report, m.err
+}
+
+func (m *mockReloader) Watch(ctx context.Context, interval time.Duration) {}
+
+func TestCatalogHandler_ReloadModels(t *testing.T) {
+	report := &model.CatalogReloadReport{
+		CatalogChanges: model.CatalogChanges{
+			Providers: model.CatalogChangeSet{Added: []string{"gamma"}, Removed: []string{}, Changed: []string{}},
+			Models:    model.CatalogChangeSet{Added: []string{"gamma/gamma-chat"}, Removed: []string{}, Changed: []string{}},
+		},
+		ProvidersCount: 3,
+		ModelsCount:    10,
+	}
+	loadErrs := loader.LoadErrors{{Provider: "alpha", Path: "models/alpha/models/llm/a.yaml", Err: fmt.Errorf("yaml 格式错误")}}
+
+	tests := []struct {
+		name       string
+		reloader   *mockReloader
+		wantStatus int
+		wantKey    string
+	}{
+		{name: "重新加载成功", reloader: &mockReloader{report: report}, wantStatus: http.StatusOK, wantKey: "providers"},
+		{name: "配置文件有错误", reloader: &mockReloader{err: errors.NewLoadDataError(loadErrs)}, wantStatus: http.StatusUnprocessableEntity, wantKey: "errors"},
+		{name: "无法读取模型目录", reloader: &mockReloader{err: errors.NewLoadDataError(fmt.Errorf("读取 models 目录失败"))}, wantStatus: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewCatalogHandler(tt.reloader, "admin-token", logger.Default())
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/models/reload", nil)
+			req.Header.Set("Authorization", "Bearer admin-token")
+			w := httptest.NewRecorder()
+
+			handler.ReloadModels(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("期望状态码 %d, 得到 %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantKey == "" {
+				return
+			}
+
+			var resp struct {
+				Data map[string]interface{} `json:"data"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("解析响应失败: %v", err)
+			}
+			if _, ok := resp.Data[tt.wantKey]; !ok {
+				t.Errorf("响应中应包含 %s: %+v", tt.wantKey, resp.Data)
+			}
+		})
+	}
+}
+
+func TestCatalogHandler_ReloadModelsUnauthorized(t *testing.T) {
+	tests := []struct {
+		name          string
+		adminToken    string
+		authorization string
+	}{
+		{name: "缺少令牌", adminToken: "admin-token"},
+		{name: "令牌错误", adminToken: "admin-token", authorization: "Bearer wrong-token"},
+		{name: "不是 Bearer 方式", adminToken: "admin-token", authorization: "admin-token"},
+		{name: "未配置令牌", authorization: "Bearer "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reloader := &mockReloader{err: fmt.Errorf("不应重新加载")}
+			handler := NewCatalogHandler(reloader, tt.adminToken, logger.Default())
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/models/reload", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ReloadModels(w, req)
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("期望状态码 %d, 得到 %d: %s", http.StatusUnauthorized, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package handler
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	"genkit-ai-service/internal/logger"
 	"genkit-ai-service/internal/model"
@@ -30,10 +31,13 @@ func NewChatHandler(aiService ai.AIService, log logger.Logger) *ChatHandler {
 
 // HandleChat 处理对话请求
 // @Summary 发送对话消息
-// @Description 向 AI 发送消息并获取回复，支持通过 messageId 继续对话
+// @Description 向 AI 发送消息并获取回复，支持通过 messageId 继续对话。
+// @Description 请求体 stream 为 true 或 Accept 为 text/event-stream 时以 SSE 流式返回：
+// @Description message 事件携带文本片段，done 事件携带完整响应，error 事件携带错误信息
 // @Tags chat
 // @Accept json
 // @Produce json
+// @Produce text/event-stream
 // @Param request body model.ChatRequest true "对话请求"
 // @Success 200 {object} model.ResponseData[model.ChatResponse] "成功返回 AI 回复"
 // @Failure 400 {object} model.ErrorResponse "请求参数错误"
@@ -64,8 +68,15 @@ func (h *ChatHandler) HandleChat(w http.ResponseWriter, r *http.Request) {
 		"message":    req.Message,
 		"messageId":  req.MessageID,
 		"hasOptions": req.Options != nil,
+		"stream":     req.Stream,
 	})
 
+	// 流式请求以 SSE 返回
+	if wantsEventStream(r, req.Stream) {
+		h.handleChatStream(w, r, &req)
+		return
+	}
+
 	// 4. 调用 AI 服务处理对话
 	chatResp, err := h.aiService.Chat(ctx, &req)
 	if err != nil {
@@ -91,6 +102,73 @@ func (h *ChatHandler) HandleChat(w http.ResponseWriter, r *http.Request) {
 	h.writeSuccessResponse(w, chatResp)
 }
 
+// handleChatStream 以 SSE 方式处理对话请求
+func (h *ChatHandler) handleChatStream(w http.ResponseWriter, r *http.Request, req *model.ChatRequest) {
+	clearWriteDeadline(w)
+
+	chunks, err := h.aiService.ChatStream(r.Context(), req)
+	if err != nil {
+		h.logger.Error("AI 流式服务调用失败", logger.Fields{"error": err})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 响应头延迟到收到第一个片段时写入，以便在生成开始前失败时仍能返回普通错误响应
+	var sse *sseWriter
+	var content strings.Builder
+	clientGone := false
+
+	for chunk := range chunks {
+		if clientGone {
+			// 客户端已断开，继续读取直到通道关闭
+			continue
+		}
+
+		if !chunk.Done {
+			if sse == nil {
+				sse = newSSEWriter(w)
+			}
+			content.WriteString(chunk.Content)
+			if err := sse.WriteChunk(chunk.Content); err != nil {
+				h.logger.Warn("写入流式响应失败，客户端可能已断开", logger.Fields{"error": err})
+				clientGone = true
+			}
+			continue
+		}
+
+		if chunk.Error != nil {
+			h.logger.Error("AI 流式生成失败", logger.Fields{"error": chunk.Error})
+			if sse == nil {
+				h.writeErrorResponse(w, toAppError(chunk.Error))
+			} else {
+				_ = sse.WriteError(toAppError(chunk.Error))
+			}
+			continue
+		}
+
+		if sse == nil {
+			sse = newSSEWriter(w)
+		}
+
+		chatResp := &model.ChatResponse{
+			SessionID: chunk.SessionID,
+			Message:   content.String(),
+			Model:     chunk.Model,
+			Usage:     chunk.Usage,
+			Parsed:    chunk.Parsed,
+		}
+		if err := sse.WriteEvent(sseEventDone, response.Success(chatResp)); err != nil {
+			h.logger.Warn("写入流式完成事件失败", logger.Fields{"error": err})
+		}
+
+		h.logger.Info("流式对话请求处理成功", logger.Fields{
+			"sessionId":     chatResp.SessionID,
+			"model":         chatResp.Model,
+			"messageLength": len(chatResp.Message),
+		})
+	}
+}
+
 // writeSuccessResponse 写入成功响应
 func (h *ChatHandler) writeSuccessResponse(w http.ResponseWriter, data *model.ChatResponse) {
 	resp := response.Success(data)
@@ -99,6 +177,12 @@ func (h *ChatHandler) writeSuccessResponse(w http.ResponseWriter, data *model.Ch
 
 // writeErrorResponse 写入错误响应
 func (h *ChatHandler) writeErrorResponse(w http.ResponseWriter, appErr *errors.AppError) {
+	// 服务层返回的字段级验证错误（如模型参数规则）
+	if fieldErrors, ok := appErr.Err.(validator.FieldErrors); ok {
+		h.writeValidationErrorResponse(w, fieldErrors)
+		return
+	}
+
 	resp := response.Error[any](appErr.Code, appErr.Message)
 	
 	// 根据错误码确定 HTTP 状态码
@@ -108,7 +192,7 @@ func (h *ChatHandler) writeErrorResponse(w http.ResponseWriter, appErr *errors.A
 		statusCode = http.StatusBadRequest
 	case errors.CodeValidationError:
 		statusCode = http.StatusUnprocessableEntity
-	case errors.CodeNotFound:
+	case errors.CodeNotFound, errors.CodeModelNotFound, errors.CodeProviderNotFound:
 		statusCode = http.StatusNotFound
 	case errors.CodeUnauthorized:
 		statusCode = http.StatusUnauthorized
@@ -116,7 +200,7 @@ func (h *ChatHandler) writeErrorResponse(w http.ResponseWriter, appErr *errors.A
 		statusCode = http.StatusForbidden
 	case errors.CodeServiceUnavailable:
 		statusCode = http.StatusServiceUnavailable
-	case errors.CodeAIServiceError, errors.CodeContextCancelled:
+	case errors.CodeAIServiceError, errors.CodeContextCancelled, errors.CodeStructuredOutput:
 		statusCode = http.StatusInternalServerError
 	}
 	
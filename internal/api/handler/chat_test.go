@@ -7,18 +7,20 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
 	"genkit-ai-service/internal/logger"
 	"genkit-ai-service/internal/model"
 	"genkit-ai-service/pkg/errors"
+	"genkit-ai-service/pkg/validator"
 )
 
 // mockAIService 模拟 AI 服务
 type mockAIService struct {
 	chatFunc       func(ctx context.Context, req *model.ChatRequest) (*model.ChatResponse, error)
 	chatStreamFunc func(ctx context.Context, req *model.ChatRequest) (<-chan model.StreamChunk, error)
-	abortChatFunc  func(ctx context.Context, messageID string) error
+	abortChatFunc  func(ctx context.Context, messageID string) (bool, error)
 }
 
 func (m *mockAIService) Chat(ctx context.Context, req *model.ChatRequest) (*model.ChatResponse, error) {
@@ -35,11 +37,11 @@ func (m *mockAIService) ChatStream(ctx context.Context, req *model.ChatRequest)
 	return nil, nil
 }
 
-func (m *mockAIService) AbortChat(ctx context.Context, messageID string) error {
+func (m *mockAIService) AbortChat(ctx context.Context, messageID string) (bool, error) {
 	if m.abortChatFunc != nil {
 		return m.abortChatFunc(ctx, messageID)
 	}
-	return nil
+	return false, nil
 }
 
 // TestHandleChat_Success 测试成功的对话请求
@@ -169,6 +171,42 @@ func TestHandleChat_AIServiceError(t *testing.T) {
 	}
 }
 
+// TestHandleChat_ParameterRuleError 测试模型参数规则校验失败时返回字段错误
+func TestHandleChat_ParameterRuleError(t *testing.T) {
+	mockService := &mockAIService{
+		chatFunc: func(ctx context.Context, req *model.ChatRequest) (*model.ChatResponse, error) {
+			return nil, errors.Wrap(errors.CodeValidationError, errors.MsgValidationError, validator.FieldErrors{
+				{Field: "maxTokens", Message: "模型 'gemini-1.5-flash-001' 的 maxTokens 必须小于或等于 8192"},
+			})
+		},
+	}
+
+	log := logger.New(logger.InfoLevel, logger.JSONFormat, os.Stdout)
+	handler := NewChatHandler(mockService, log)
+
+	body := []byte(`{"message":"你好","model":"gemini-1.5-flash-001","options":{"maxTokens":10000}}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chat", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	handler.HandleChat(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("期望状态码 %d, 得到 %d", http.StatusUnprocessableEntity, w.Code)
+	}
+
+	var resp model.ResponseData[struct {
+		Errors []validator.ValidationError `json:"errors"`
+	}]
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+
+	if resp.Data == nil || len(resp.Data.Errors) != 1 || resp.Data.Errors[0].Field != "maxTokens" {
+		t.Errorf("期望返回 maxTokens 字段错误, 得到 %+v", resp.Data)
+	}
+}
+
 // TestHandleChat_WithOptions 测试带高级参数的请求
 func TestHandleChat_WithOptions(t *testing.T) {
 	mockService := &mockAIService{
@@ -208,3 +246,77 @@ func TestHandleChat_WithOptions(t *testing.T) {
 		t.Errorf("期望状态码 %d, 得到 %d", http.StatusOK, w.Code)
 	}
 }
+
+// TestHandleChat_Stream 测试 SSE 流式对话
+func TestHandleChat_Stream(t *testing.T) {
+	mockService := &mockAIService{
+		chatStreamFunc: func(ctx context.Context, req *model.ChatRequest) (<-chan model.StreamChunk, error) {
+			chunks := make(chan model.StreamChunk, 3)
+			chunks <- model.StreamChunk{Content: "这是"}
+			chunks <- model.StreamChunk{Content: "回复"}
+			chunks <- model.StreamChunk{
+				Done:      true,
+				SessionID: "test-session-123",
+				Model:     "gemini-2.5-flash",
+				Usage:     &model.Usage{TotalTokens: 30},
+			}
+			close(chunks)
+			return chunks, nil
+		},
+	}
+
+	log := logger.New(logger.InfoLevel, logger.JSONFormat, os.Stdout)
+	handler := NewChatHandler(mockService, log)
+
+	body, _ := json.Marshal(model.ChatRequest{Message: "你好"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chat", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	w := httptest.NewRecorder()
+
+	handler.HandleChat(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d", http.StatusOK, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("期望 Content-Type 为 text/event-stream, 得到 %s", ct)
+	}
+
+	bodyStr := w.Body.String()
+	for _, want := range []string{
+		"event: message\ndata: {\"content\":\"这是\"}\n\n",
+		"event: done\n",
+		"\"message\":\"这是回复\"",
+		"\"sessionId\":\"test-session-123\"",
+	} {
+		if !strings.Contains(bodyStr, want) {
+			t.Errorf("期望响应包含 %q, 实际为 %s", want, bodyStr)
+		}
+	}
+}
+
+// TestHandleChat_StreamError 测试流式对话在首个片段前失败时返回普通错误响应
+func TestHandleChat_StreamError(t *testing.T) {
+	mockService := &mockAIService{
+		chatStreamFunc: func(ctx context.Context, req *model.ChatRequest) (<-chan model.StreamChunk, error) {
+			chunks := make(chan model.StreamChunk, 1)
+			chunks <- model.StreamChunk{Done: true, Error: errors.NewServiceUnavailableError("AI 服务")}
+			close(chunks)
+			return chunks, nil
+		},
+	}
+
+	log := logger.New(logger.InfoLevel, logger.JSONFormat, os.Stdout)
+	handler := NewChatHandler(mockService, log)
+
+	body, _ := json.Marshal(model.ChatRequest{Message: "你好", Stream: true})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chat", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.HandleChat(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
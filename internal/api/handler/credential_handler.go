@@ -0,0 +1,331 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/service/credential"
+	"genkit-ai-service/pkg/errors"
+	"genkit-ai-service/pkg/response"
+	"genkit-ai-service/pkg/validator"
+)
+
+// WorkspaceIDHeader 工作空间ID请求头名称，指定时凭证归属于该工作空间
+const WorkspaceIDHeader = "X-Workspace-ID"
+
+// CredentialHandler 模型提供商凭证处理器
+type CredentialHandler struct {
+	credentialService credential.CredentialService
+	logger            logger.Logger
+	validator         *validator.Validator
+}
+
+// NewCredentialHandler 创建模型提供商凭证处理器实例
+func NewCredentialHandler(credentialService credential.CredentialService, log logger.Logger) *CredentialHandler {
+	return &CredentialHandler{
+		credentialService: credentialService,
+		logger:            log,
+		validator:         validator.New(),
+	}
+}
+
+// CreateCredential 创建凭证
+// @Summary 创建凭证
+// @Description 保存提供商级或模型级凭证。凭证按提供商 YAML 中的表单配置校验（必填、选项、显示条件），加密保存，返回脱敏后的凭证。
+// @Description 请求头 X-Workspace-ID 存在时凭证归属于该工作空间，否则归属于当前用户
+// @Tags credentials
+// @Accept json
+// @Produce json
+// @Param X-Workspace-ID header string false "工作空间ID"
+// @Param request body model.CreateCredentialRequest true "创建凭证请求"
+// @Success 200 {object} model.ResponseData[model.ProviderCredential] "成功创建凭证"
+// @Failure 400 {object} model.ErrorResponse "请求参数错误或提供商不支持该凭证"
+// @Failure 404 {object} model.ErrorResponse "提供商不存在"
+// @Failure 409 {object} model.ErrorResponse "凭证已存在"
+// @Failure 422 {object} model.ErrorResponse "参数验证失败"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /credentials [post]
+func (h *CredentialHandler) CreateCredential(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 解析请求参数
+	var req model.CreateCredentialRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("解析请求参数失败", logger.Fields{"error": err})
+		h.writeErrorResponse(w, errors.NewBadRequestError("无效的请求参数"))
+		return
+	}
+
+	// 2. 验证请求参数
+	if validationErrors := h.validator.ValidateStruct(&req); validationErrors != nil {
+		h.logger.Warn("请求参数验证失败", logger.Fields{"errors": validationErrors})
+		h.writeValidationErrorResponse(w, validationErrors)
+		return
+	}
+
+	// 3. 确定凭证所有者
+	owner := credentialOwner(r)
+
+	// 4. 调用服务层创建凭证
+	cred, err := h.credentialService.CreateCredential(ctx, owner, &req)
+	if err != nil {
+		h.logger.Error("创建凭证失败", logger.Fields{
+			"error":      err,
+			"ownerType":  owner.Type,
+			"ownerId":    owner.ID,
+			"providerId": req.ProviderID,
+		})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 5. 返回成功响应
+	h.writeJSONResponse(w, http.StatusOK, response.Success(cred))
+}
+
+// ListCredentials 获取凭证列表
+// @Summary 获取凭证列表
+// @Description 获取当前用户或工作空间的凭证列表，按创建时间倒序排列，凭证均已脱敏
+// @Tags credentials
+// @Produce json
+// @Param X-Workspace-ID header string false "工作空间ID"
+// @Param providerId query string false "只返回该提供商的凭证"
+// @Success 200 {object} model.ResponseData[[]model.ProviderCredential] "成功返回凭证列表"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /credentials [get]
+func (h *CredentialHandler) ListCredentials(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 确定凭证所有者
+	owner := credentialOwner(r)
+	providerID := r.URL.Query().Get("providerId")
+
+	// 2. 调用服务层获取凭证列表
+	credentials, err := h.credentialService.ListCredentials(ctx, owner, providerID)
+	if err != nil {
+		h.logger.Error("获取凭证列表失败", logger.Fields{
+			"error":     err,
+			"ownerType": owner.Type,
+			"ownerId":   owner.ID,
+		})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 3. 返回成功响应
+	h.writeJSONResponse(w, http.StatusOK, response.Success(&credentials))
+}
+
+// GetCredential 获取凭证详情
+// @Summary 获取凭证详情
+// @Description 获取当前用户或工作空间的凭证，凭证已脱敏
+// @Tags credentials
+// @Produce json
+// @Param X-Workspace-ID header string false "工作空间ID"
+// @Param id path string true "凭证ID"
+// @Success 200 {object} model.ResponseData[model.ProviderCredential] "成功返回凭证"
+// @Failure 403 {object} model.ErrorResponse "无权访问"
+// @Failure 404 {object} model.ErrorResponse "凭证不存在"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /credentials/{id} [get]
+func (h *CredentialHandler) GetCredential(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 从URL路径中提取凭证ID
+	credentialID := extractPathSegment(r.URL.Path, "credentials")
+	if credentialID == "" {
+		h.writeErrorResponse(w, errors.NewBadRequestError("凭证ID不能为空"))
+		return
+	}
+
+	// 2. 确定凭证所有者
+	owner := credentialOwner(r)
+
+	// 3. 调用服务层获取凭证
+	cred, err := h.credentialService.GetCredential(ctx, credentialID, owner)
+	if err != nil {
+		h.logger.Error("获取凭证失败", logger.Fields{
+			"error":        err,
+			"credentialId": credentialID,
+			"ownerType":    owner.Type,
+			"ownerId":      owner.ID,
+		})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 4. 返回成功响应
+	h.writeJSONResponse(w, http.StatusOK, response.Success(cred))
+}
+
+// UpdateCredential 更新凭证
+// @Summary 更新凭证
+// @Description 整体替换凭证并重新校验。secret-input 字段传入接口返回的脱敏值时保留原值
+// @Tags credentials
+// @Accept json
+// @Produce json
+// @Param X-Workspace-ID header string false "工作空间ID"
+// @Param id path string true "凭证ID"
+// @Param request body model.UpdateCredentialRequest true "更新凭证请求"
+// @Success 200 {object} model.ResponseData[model.ProviderCredential] "成功更新凭证"
+// @Failure 400 {object} model.ErrorResponse "请求参数错误"
+// @Failure 403 {object} model.ErrorResponse "无权访问"
+// @Failure 404 {object} model.ErrorResponse "凭证不存在"
+// @Failure 422 {object} model.ErrorResponse "参数验证失败"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /credentials/{id} [put]
+func (h *CredentialHandler) UpdateCredential(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 从URL路径中提取凭证ID
+	credentialID := extractPathSegment(r.URL.Path, "credentials")
+	if credentialID == "" {
+		h.writeErrorResponse(w, errors.NewBadRequestError("凭证ID不能为空"))
+		return
+	}
+
+	// 2. 解析请求参数
+	var req model.UpdateCredentialRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("解析请求参数失败", logger.Fields{"error": err})
+		h.writeErrorResponse(w, errors.NewBadRequestError("无效的请求参数"))
+		return
+	}
+
+	// 3. 验证请求参数
+	if validationErrors := h.validator.ValidateStruct(&req); validationErrors != nil {
+		h.logger.Warn("请求参数验证失败", logger.Fields{"errors": validationErrors})
+		h.writeValidationErrorResponse(w, validationErrors)
+		return
+	}
+
+	// 4. 确定凭证所有者
+	owner := credentialOwner(r)
+
+	// 5. 调用服务层更新凭证
+	cred, err := h.credentialService.UpdateCredential(ctx, credentialID, owner, &req)
+	if err != nil {
+		h.logger.Error("更新凭证失败", logger.Fields{
+			"error":        err,
+			"credentialId": credentialID,
+			"ownerType":    owner.Type,
+			"ownerId":      owner.ID,
+		})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 6. 返回成功响应
+	h.writeJSONResponse(w, http.StatusOK, response.Success(cred))
+}
+
+// DeleteCredential 删除凭证
+// @Summary 删除凭证
+// @Description 删除当前用户或工作空间的凭证
+// @Tags credentials
+// @Produce json
+// @Param X-Workspace-ID header string false "工作空间ID"
+// @Param id path string true "凭证ID"
+// @Success 200 {object} model.ResponseData[any] "成功删除凭证"
+// @Failure 403 {object} model.ErrorResponse "无权访问"
+// @Failure 404 {object} model.ErrorResponse "凭证不存在"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /credentials/{id} [delete]
+func (h *CredentialHandler) DeleteCredential(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 从URL路径中提取凭证ID
+	credentialID := extractPathSegment(r.URL.Path, "credentials")
+	if credentialID == "" {
+		h.writeErrorResponse(w, errors.NewBadRequestError("凭证ID不能为空"))
+		return
+	}
+
+	// 2. 确定凭证所有者
+	owner := credentialOwner(r)
+
+	h.logger.Info("收到删除凭证请求", logger.Fields{
+		"credentialId": credentialID,
+		"ownerType":    owner.Type,
+		"ownerId":      owner.ID,
+	})
+
+	// 3. 调用服务层删除凭证
+	if err := h.credentialService.DeleteCredential(ctx, credentialID, owner); err != nil {
+		h.logger.Error("删除凭证失败", logger.Fields{
+			"error":        err,
+			"credentialId": credentialID,
+			"ownerType":    owner.Type,
+			"ownerId":      owner.ID,
+		})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 4. 返回成功响应
+	h.writeJSONResponse(w, http.StatusOK, response.Success[any](nil))
+}
+
+// credentialOwner 从请求头确定凭证所有者：指定工作空间ID时为工作空间，否则为当前用户
+func credentialOwner(r *http.Request) model.CredentialOwner {
+	if workspaceID := r.Header.Get(WorkspaceIDHeader); workspaceID != "" {
+		return model.CredentialOwner{Type: model.CredentialOwnerWorkspace, ID: workspaceID}
+	}
+
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		userID = "default-user-id" // 临时默认值
+	}
+	return model.CredentialOwner{Type: model.CredentialOwnerUser, ID: userID}
+}
+
+// writeErrorResponse 写入错误响应
+func (h *CredentialHandler) writeErrorResponse(w http.ResponseWriter, appErr *errors.AppError) {
+	resp := response.Error[any](appErr.Code, appErr.Message)
+
+	// 根据错误码确定 HTTP 状态码
+	statusCode := http.StatusInternalServerError
+	switch appErr.Code {
+	case errors.CodeBadRequest:
+		statusCode = http.StatusBadRequest
+	case errors.CodeValidationError:
+		statusCode = http.StatusUnprocessableEntity
+	case errors.CodeNotFound, errors.CodeCredentialNotFound, errors.CodeProviderNotFound:
+		statusCode = http.StatusNotFound
+	case errors.CodeForbidden, errors.CodeCredentialAccessDenied:
+		statusCode = http.StatusForbidden
+	case errors.CodeCredentialAlreadyExists:
+		statusCode = http.StatusConflict
+	case errors.CodeServiceUnavailable:
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	h.writeJSONResponse(w, statusCode, resp)
+}
+
+// writeValidationErrorResponse 写入验证错误响应
+func (h *CredentialHandler) writeValidationErrorResponse(w http.ResponseWriter, validationErrors []validator.ValidationError) {
+	errorData := map[string]interface{}{
+		"errors": validationErrors,
+	}
+
+	resp := response.ErrorWithData(
+		errors.CodeValidationError,
+		errors.MsgValidationError,
+		&errorData,
+	)
+
+	h.writeJSONResponse(w, http.StatusUnprocessableEntity, resp)
+}
+
+// writeJSONResponse 写入 JSON 响应
+func (h *CredentialHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("写入响应失败", logger.Fields{"error": err})
+	}
+}
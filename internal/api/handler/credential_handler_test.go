@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/pkg/errors"
+)
+
+// mockCredentialService 模拟凭证服务
+type mockCredentialService struct {
+	credentials map[string]*model.ProviderCredential
+	lastOwner   model.CredentialOwner
+}
+
+func newMockCredentialService() *mockCredentialService {
+	return &mockCredentialService{
+		credentials: map[string]*model.ProviderCredential{
+			"cred-1": {
+				ID:          "cred-1",
+				OwnerType:   model.CredentialOwnerUser,
+				OwnerID:     "test-user",
+				ProviderID:  "openai",
+				Credentials: map[string]string{"api_key": "sk-a****mnop"},
+			},
+		},
+	}
+}
+
+func (m *mockCredentialService) CreateCredential(ctx context.Context, owner model.CredentialOwner, req *model.CreateCredentialRequest) (*model.ProviderCredential, error) {
+	m.lastOwner = owner
+	for _, c := range m.credentials {
+		if c.OwnerType == owner.Type && c.OwnerID == owner.ID && c.ProviderID == req.ProviderID && c.Model == req.Model {
+			return nil, errors.NewCredentialAlreadyExistsError()
+		}
+	}
+	if req.ProviderID == "unknown" {
+		return nil, errors.NewProviderNotFoundError(req.ProviderID)
+	}
+	cred := &model.ProviderCredential{ID: "cred-2", OwnerType: owner.Type, OwnerID: owner.ID, ProviderID: req.ProviderID, Credentials: map[string]string{"api_key": "********"}}
+	m.credentials[cred.ID] = cred
+	return cred, nil
+}
+
+func (m *mockCredentialService) ListCredentials(ctx context.Context, owner model.CredentialOwner, providerID string) ([]*model.ProviderCredential, error) {
+	m.lastOwner = owner
+	var credentials []*model.ProviderCredential
+	for _, c := range m.credentials {
+		if c.OwnerType == owner.Type && c.OwnerID == owner.ID && (providerID == "" || c.ProviderID == providerID) {
+			credentials = append(credentials, c)
+		}
+	}
+	return credentials, nil
+}
+
+func (m *mockCredentialService) GetCredential(ctx context.Context, credentialID string, owner model.CredentialOwner) (*model.ProviderCredential, error) {
+	m.lastOwner = owner
+	c, ok := m.credentials[credentialID]
+	if !ok {
+		return nil, errors.NewCredentialNotFoundError(credentialID)
+	}
+	if c.OwnerType != owner.Type || c.OwnerID != owner.ID {
+		return nil, errors.NewCredentialAccessDeniedError()
+	}
+	return c, nil
+}
+
+func (m *mockCredentialService) UpdateCredential(ctx context.Context, credentialID string, owner model.CredentialOwner, req *model.UpdateCredentialRequest) (*model.ProviderCredential, error) {
+	return m.GetCredential(ctx, credentialID, owner)
+}
+
+func (m *mockCredentialService) DeleteCredential(ctx context.Context, credentialID string, owner model.CredentialOwner) error {
+	if _, err := m.GetCredential(ctx, credentialID, owner); err != nil {
+		return err
+	}
+	delete(m.credentials, credentialID)
+	return nil
+}
+
+func TestCredentialHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		body        string
+		workspaceID string
+		wantStatus  int
+	}{
+		{name: "创建凭证", method: http.MethodPost, path: "/api/v1/credentials", body: `{"providerId":"anthropic","credentials":{"api_key":"sk-1"}}`, wantStatus: http.StatusOK},
+		{name: "凭证已存在", method: http.MethodPost, path: "/api/v1/credentials", body: `{"providerId":"openai","credentials":{"api_key":"sk-1"}}`, wantStatus: http.StatusConflict},
+		{name: "工作空间凭证", method: http.MethodPost, path: "/api/v1/credentials", body: `{"providerId":"openai","credentials":{"api_key":"sk-1"}}`, workspaceID: "ws-1", wantStatus: http.StatusOK},
+		{name: "提供商不存在", method: http.MethodPost, path: "/api/v1/credentials", body: `{"providerId":"unknown","credentials":{"api_key":"sk-1"}}`, wantStatus: http.StatusNotFound},
+		{name: "缺少凭证", method: http.MethodPost, path: "/api/v1/credentials", body: `{"providerId":"openai"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "模型缺少模型类型", method: http.MethodPost, path: "/api/v1/credentials", body: `{"providerId":"openai","model":"gpt-4o","credentials":{"api_key":"sk-1"}}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "无效的 JSON", method: http.MethodPost, path: "/api/v1/credentials", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "凭证详情", method: http.MethodGet, path: "/api/v1/credentials/cred-1", wantStatus: http.StatusOK},
+		{name: "凭证不存在", method: http.MethodGet, path: "/api/v1/credentials/missing", wantStatus: http.StatusNotFound},
+		{name: "其他工作空间无权访问", method: http.MethodGet, path: "/api/v1/credentials/cred-1", workspaceID: "ws-1", wantStatus: http.StatusForbidden},
+		{name: "更新凭证", method: http.MethodPut, path: "/api/v1/credentials/cred-1", body: `{"credentials":{"api_key":"sk-2"}}`, wantStatus: http.StatusOK},
+		{name: "更新缺少凭证", method: http.MethodPut, path: "/api/v1/credentials/cred-1", body: `{}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "删除凭证", method: http.MethodDelete, path: "/api/v1/credentials/cred-1", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewCredentialHandler(newMockCredentialService(), logger.Default())
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("X-User-ID", "test-user")
+			if tt.workspaceID != "" {
+				req.Header.Set(WorkspaceIDHeader, tt.workspaceID)
+			}
+			w := httptest.NewRecorder()
+
+			switch {
+			case tt.method == http.MethodPost:
+				handler.CreateCredential(w, req)
+			case tt.method == http.MethodPut:
+				handler.UpdateCredential(w, req)
+			case tt.method == http.MethodDelete:
+				handler.DeleteCredential(w, req)
+			default:
+				handler.GetCredential(w, req)
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("期望状态码 %d, 得到 %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCredentialHandler_ListCredentials(t *testing.T) {
+	service := newMockCredentialService()
+	handler := NewCredentialHandler(service, logger.Default())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/credentials?providerId=openai", nil)
+	req.Header.Set("X-User-ID", "test-user")
+	w := httptest.NewRecorder()
+	handler.ListCredentials(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 200, 得到 %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if len(resp.Data) != 1 || resp.Data[0]["id"] != "cred-1" {
+		t.Fatalf("凭证列表不正确: %+v", resp.Data)
+	}
+	// 响应中不包含加密后的凭证
+	if _, ok := resp.Data[0]["encryptedCredentials"]; ok {
+		t.Error("响应中不应包含加密凭证")
+	}
+	if service.lastOwner.Type != model.CredentialOwnerUser || service.lastOwner.ID != "test-user" {
+		t.Errorf("凭证所有者不正确: %+v", service.lastOwner)
+	}
+}
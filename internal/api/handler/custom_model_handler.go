@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/service/custommodel"
+	"genkit-ai-service/pkg/errors"
+	"genkit-ai-service/pkg/response"
+	"genkit-ai-service/pkg/validator"
+)
+
+// CustomModelHandler 自定义模型处理器
+type CustomModelHandler struct {
+	customModelService custommodel.CustomModelService
+	logger             logger.Logger
+	validator          *validator.Validator
+}
+
+// NewCustomModelHandler 创建自定义模型处理器实例
+func NewCustomModelHandler(customModelService custommodel.CustomModelService, log logger.Logger) *CustomModelHandler {
+	return &CustomModelHandler{
+		customModelService: customModelService,
+		logger:             log,
+		validator:          validator.New(),
+	}
+}
+
+// RegisterModel 注册自定义模型
+// @Summary 注册自定义模型
+// @Description 在配置方法包含 customizable-model 的提供商（如 azure_openai）下注册自定义模型（如 Azure 部署）。
+// @Description 凭证按提供商 YAML 中的模型凭证表单校验并加密保存；注册后模型合并到提供商的模型列表，可作为会话的 modelName 使用
+// @Tags custom-models
+// @Accept json
+// @Produce json
+// @Param providerId path string true "提供商ID"
+// @Param request body model.RegisterCustomModelRequest true "注册自定义模型请求"
+// @Success 200 {object} model.ResponseData[model.CustomModel] "成功注册自定义模型"
+// @Failure 400 {object} model.ErrorResponse "请求参数错误、提供商不支持自定义模型或凭证无法使用"
+// @Failure 404 {object} model.ErrorResponse "提供商不存在"
+// @Failure 409 {object} model.ErrorResponse "模型已存在"
+// @Failure 422 {object} model.ErrorResponse "参数验证失败"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /providers/{providerId}/custom-models [post]
+func (h *CustomModelHandler) RegisterModel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 验证提供商ID
+	providerID := r.PathValue("providerId")
+	if err := validator.ValidateProviderID(providerID); err != nil {
+		h.writeErrorResponse(w, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	// 2. 解析请求参数
+	var req model.RegisterCustomModelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("解析请求参数失败", logger.Fields{"error": err})
+		h.writeErrorResponse(w, errors.NewBadRequestError("无效的请求参数"))
+		return
+	}
+
+	// 3. 验证请求参数
+	if validationErrors := h.validator.ValidateStruct(&req); validationErrors != nil {
+		h.logger.Warn("请求参数验证失败", logger.Fields{"errors": validationErrors})
+		h.writeValidationErrorResponse(w, validationErrors)
+		return
+	}
+
+	// 4. 获取用户ID
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		userID = "default-user-id" // 临时默认值
+	}
+
+	// 5. 调用服务层注册自定义模型
+	customModel, err := h.customModelService.RegisterModel(ctx, userID, providerID, &req)
+	if err != nil {
+		h.logger.Error("注册自定义模型失败", logger.Fields{
+			"error":      err,
+			"userId":     userID,
+			"providerId": providerID,
+			"model":      req.Model,
+		})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 6. 返回成功响应
+	h.writeJSONResponse(w, http.StatusOK, response.Success(customModel))
+}
+
+// ListModels 获取自定义模型列表
+// @Summary 获取自定义模型列表
+// @Description 获取提供商下注册的自定义模型，按注册时间正序排列，凭证均已脱敏
+// @Tags custom-models
+// @Produce json
+// @Param providerId path string true "提供商ID"
+// @Success 200 {object} model.ResponseData[[]model.CustomModel] "成功返回自定义模型列表"
+// @Failure 400 {object} model.ErrorResponse "提供商ID格式错误"
+// @Failure 404 {object} model.ErrorResponse "提供商不存在"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /providers/{providerId}/custom-models [get]
+func (h *CustomModelHandler) ListModels(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 验证提供商ID
+	providerID := r.PathValue("providerId")
+	if err := validator.ValidateProviderID(providerID); err != nil {
+		h.writeErrorResponse(w, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	// 2. 调用服务层获取自定义模型列表
+	customModels, err := h.customModelService.ListModels(ctx, providerID)
+	if err != nil {
+		h.logger.Error("获取自定义模型列表失败", logger.Fields{
+			"error":      err,
+			"providerId": providerID,
+		})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 3. 返回成功响应
+	h.writeJSONResponse(w, http.StatusOK, response.Success(&customModels))
+}
+
+// DeleteModel 删除自定义模型
+// @Summary 删除自定义模型
+// @Description 删除自定义模型并从提供商的模型列表中移除，预定义模型不能删除
+// @Tags custom-models
+// @Produce json
+// @Param providerId path string true "提供商ID"
+// @Param modelId path string true "模型名称"
+// @Success 200 {object} model.ResponseData[any] "成功删除自定义模型"
+// @Failure 400 {object} model.ErrorResponse "提供商ID或模型名称格式错误"
+// @Failure 404 {object} model.ErrorResponse "自定义模型不存在"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /providers/{providerId}/custom-models/{modelId} [delete]
+func (h *CustomModelHandler) DeleteModel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 验证提供商ID和模型名称
+	providerID := r.PathValue("providerId")
+	if err := validator.ValidateProviderID(providerID); err != nil {
+		h.writeErrorResponse(w, errors.NewBadRequestError(err.Error()))
+		return
+	}
+	modelID := r.PathValue("modelId")
+	if err := validator.ValidateModelID(modelID); err != nil {
+		h.writeErrorResponse(w, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	h.logger.Info("收到删除自定义模型请求", logger.Fields{
+		"providerId": providerID,
+		"model":      modelID,
+	})
+
+	// 2. 调用服务层删除自定义模型
+	if err := h.customModelService.DeleteModel(ctx, providerID, modelID); err != nil {
+		h.logger.Error("删除自定义模型失败", logger.Fields{
+			"error":      err,
+			"providerId": providerID,
+			"model":      modelID,
+		})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 3. 返回成功响应
+	h.writeJSONResponse(w, http.StatusOK, response.Success[any](nil))
+}
+
+// writeErrorResponse 写入错误响应
+func (h *CustomModelHandler) writeErrorResponse(w http.ResponseWriter, appErr *errors.AppError) {
+	resp := response.Error[any](appErr.Code, appErr.Message)
+
+	// 根据错误码确定 HTTP 状态码
+	statusCode := http.StatusInternalServerError
+	switch appErr.Code {
+	case errors.CodeBadRequest:
+		statusCode = http.StatusBadRequest
+	case errors.CodeValidationError:
+		statusCode = http.StatusUnprocessableEntity
+	case errors.CodeNotFound, errors.CodeProviderNotFound, errors.CodeModelNotFound:
+		statusCode = http.StatusNotFound
+	case errors.CodeModelAlreadyExists:
+		statusCode = http.StatusConflict
+	case errors.CodeServiceUnavailable:
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	h.writeJSONResponse(w, statusCode, resp)
+}
+
+// writeValidationErrorResponse 写入验证错误响应
+func (h *CustomModelHandler) writeValidationErrorResponse(w http.ResponseWriter, validationErrors []validator.ValidationError) {
+	errorData := map[string]interface{}{
+		"errors": validationErrors,
+	}
+
+	resp := response.ErrorWithData(
+		errors.CodeValidationError,
+		errors.MsgValidationError,
+		&errorData,
+	)
+
+	h.writeJSONResponse(w, http.StatusUnprocessableEntity, resp)
+}
+
+// writeJSONResponse 写入 JSON 响应
+func (h *CustomModelHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("写入响应失败", logger.Fields{"error": err})
+	}
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/pkg/errors"
+)
+
+// mockCustomModelService 模拟自定义模型服务
+type mockCustomModelService struct {
+	models     map[string]*model.CustomModel
+	lastUserID string
+}
+
+func newMockCustomModelService() *mockCustomModelService {
+	return &mockCustomModelService{
+		models: map[string]*model.CustomModel{
+			"my-deployment": {
+				ID:          "cm-1",
+				ProviderID:  "azure_openai",
+				ModelType:   "llm",
+				Model:       "my-deployment",
+				Credentials: map[string]string{"openai_api_key": "sk-a****mnop"},
+			},
+		},
+	}
+}
+
+func (m *mockCustomModelService) RegisterModel(ctx context.Context, userID, providerID string, req *model.RegisterCustomModelRequest) (*model.CustomModel, error) {
+	m.lastUserID = userID
+	if providerID != "azure_openai" {
+		return nil, errors.NewProviderNotFoundError(providerID)
+	}
+	if _, ok := m.models[req.Model]; ok {
+		return nil, errors.NewModelAlreadyExistsError(req.Model)
+	}
+	customModel := &model.CustomModel{ID: "cm-2", ProviderID: providerID, ModelType: req.ModelType, Model: req.Model, CreatedBy: userID}
+	m.models[req.Model] = customModel
+	return customModel, nil
+}
+
+func (m *mockCustomModelService) ListModels(ctx context.Context, providerID string) ([]*model.CustomModel, error) {
+	if providerID != "azure_openai" {
+		return nil, errors.NewProviderNotFoundError(providerID)
+	}
+	var customModels []*model.CustomModel
+	for _, customModel := range m.models {
+		customModels = append(customModels, customModel)
+	}
+	return customModels, nil
+}
+
+func (m *mockCustomModelService) DeleteModel(ctx context.Context, providerID, modelName string) error {
+	if _, ok := m.models[modelName]; !ok {
+		return errors.NewModelNotFoundError(modelName)
+	}
+	delete(m.models, modelName)
+	return nil
+}
+
+func (m *mockCustomModelService) LoadModels(ctx context.Context) (int, error) {
+	return len(m.models), nil
+}
+
+func newCustomModelMux(service *mockCustomModelService) *http.ServeMux {
+	handler := NewCustomModelHandler(service, logger.Default())
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/providers/{providerId}/custom-models", handler.RegisterModel)
+	mux.HandleFunc("GET /api/v1/providers/{providerId}/custom-models", handler.ListModels)
+	mux.HandleFunc("DELETE /api/v1/providers/{providerId}/custom-models/{modelId}", handler.DeleteModel)
+	return mux
+}
+
+func TestCustomModelHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{name: "注册自定义模型", method: http.MethodPost, path: "/api/v1/providers/azure_openai/custom-models", body: `{"modelType":"llm","model":"new-deployment","credentials":{"openai_api_key":"sk-1"}}`, wantStatus: http.StatusOK},
+		{name: "模型已存在", method: http.MethodPost, path: "/api/v1/providers/azure_openai/custom-models", body: `{"modelType":"llm","model":"my-deployment","credentials":{"openai_api_key":"sk-1"}}`, wantStatus: http.StatusConflict},
+		{name: "提供商不存在", method: http.MethodPost, path: "/api/v1/providers/unknown/custom-models", body: `{"modelType":"llm","model":"new-deployment","credentials":{"openai_api_key":"sk-1"}}`, wantStatus: http.StatusNotFound},
+		{name: "缺少凭证", method: http.MethodPost, path: "/api/v1/providers/azure_openai/custom-models", body: `{"modelType":"llm","model":"new-deployment"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "无效的 JSON", method: http.MethodPost, path: "/api/v1/providers/azure_openai/custom-models", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "无效的提供商ID", method: http.MethodGet, path: "/api/v1/providers/Azure%20OpenAI/custom-models", wantStatus: http.StatusBadRequest},
+		{name: "自定义模型列表", method: http.MethodGet, path: "/api/v1/providers/azure_openai/custom-models", wantStatus: http.StatusOK},
+		{name: "删除自定义模型", method: http.MethodDelete, path: "/api/v1/providers/azure_openai/custom-models/my-deployment", wantStatus: http.StatusOK},
+		{name: "删除不存在的模型", method: http.MethodDelete, path: "/api/v1/providers/azure_openai/custom-models/missing", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := newCustomModelMux(newMockCustomModelService())
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("X-User-ID", "test-user")
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("期望状态码 %d, 得到 %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCustomModelHandler_RegisterModel(t *testing.T) {
+	service := newMockCustomModelService()
+	mux := newCustomModelMux(service)
+
+	body := `{"modelType":"llm","model":"new-deployment","credentials":{"openai_api_key":"sk-1"}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/providers/azure_openai/custom-models", strings.NewReader(body))
+	req.Header.Set("X-User-ID", "test-user")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 200, 得到 %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if resp.Data["model"] != "new-deployment" || resp.Data["providerId"] != "azure_openai" {
+		t.Errorf("自定义模型不正确: %+v", resp.Data)
+	}
+	// 响应中不包含加密后的凭证
+	if _, ok := resp.Data["encryptedCredentials"]; ok {
+		t.Error("响应中不应包含加密凭证")
+	}
+	if service.lastUserID != "test-user" {
+		t.Errorf("用户ID不正确: %s", service.lastUserID)
+	}
+}
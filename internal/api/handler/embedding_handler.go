@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/service/ai"
+	"genkit-ai-service/pkg/errors"
+	"genkit-ai-service/pkg/response"
+	"genkit-ai-service/pkg/validator"
+)
+
+// EmbeddingHandler 文本向量化接口处理器
+type EmbeddingHandler struct {
+	embeddingService ai.EmbeddingService
+	logger           logger.Logger
+	validator        *validator.Validator
+}
+
+// NewEmbeddingHandler 创建文本向量化处理器实例
+func NewEmbeddingHandler(embeddingService ai.EmbeddingService, log logger.Logger) *EmbeddingHandler {
+	return &EmbeddingHandler{
+		embeddingService: embeddingService,
+		logger:           log,
+		validator:        validator.New(),
+	}
+}
+
+// HandleEmbeddings 处理文本向量化请求
+// @Summary 文本向量化
+// @Description 使用模型目录中的 text_embedding 模型为一批文本生成向量。
+// @Description 每条文本的 token 数不能超过模型的上下文大小，输入条数超过模型单次请求上限时自动分批请求
+// @Tags embeddings
+// @Accept json
+// @Produce json
+// @Param request body model.EmbeddingRequest true "向量化请求"
+// @Success 200 {object} model.ResponseData[model.EmbeddingResponse] "成功返回向量"
+// @Failure 400 {object} model.ErrorResponse "请求参数错误或模型不是向量模型"
+// @Failure 404 {object} model.ErrorResponse "模型不存在"
+// @Failure 422 {object} model.ErrorResponse "参数验证失败"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Failure 503 {object} model.ErrorResponse "模型提供商不可用"
+// @Router /embeddings [post]
+func (h *EmbeddingHandler) HandleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 解析请求参数
+	var req model.EmbeddingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("解析请求参数失败", logger.Fields{"error": err})
+		h.writeErrorResponse(w, errors.NewBadRequestError("无效的请求参数"))
+		return
+	}
+
+	// 2. 验证请求参数
+	if validationErrors := h.validator.ValidateStruct(&req); validationErrors != nil {
+		h.logger.Warn("请求参数验证失败", logger.Fields{"errors": validationErrors})
+		h.writeValidationErrorResponse(w, validationErrors)
+		return
+	}
+
+	h.logger.Info("收到向量化请求", logger.Fields{
+		"model":  req.Model,
+		"inputs": len(req.Input),
+	})
+
+	// 3. 调用服务层生成向量
+	resp, err := h.embeddingService.Embed(ctx, &req)
+	if err != nil {
+		h.logger.Error("生成向量失败", logger.Fields{"error": err, "model": req.Model})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 4. 返回成功响应
+	h.writeJSONResponse(w, http.StatusOK, response.Success(resp))
+}
+
+// writeErrorResponse 写入错误响应
+func (h *EmbeddingHandler) writeErrorResponse(w http.ResponseWriter, appErr *errors.AppError) {
+	resp := response.Error[any](appErr.Code, appErr.Message)
+
+	// 根据错误码确定 HTTP 状态码
+	statusCode := http.StatusInternalServerError
+	switch appErr.Code {
+	case errors.CodeBadRequest:
+		statusCode = http.StatusBadRequest
+	case errors.CodeValidationError:
+		statusCode = http.StatusUnprocessableEntity
+	case errors.CodeNotFound, errors.CodeModelNotFound, errors.CodeProviderNotFound:
+		statusCode = http.StatusNotFound
+	case errors.CodeServiceUnavailable:
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	h.writeJSONResponse(w, statusCode, resp)
+}
+
+// writeValidationErrorResponse 写入验证错误响应
+func (h *EmbeddingHandler) writeValidationErrorResponse(w http.ResponseWriter, validationErrors []validator.ValidationError) {
+	errorData := map[string]interface{}{
+		"errors": validationErrors,
+	}
+
+	resp := response.ErrorWithData(
+		errors.CodeValidationError,
+		errors.MsgValidationError,
+		&errorData,
+	)
+
+	h.writeJSONResponse(w, http.StatusUnprocessableEntity, resp)
+}
+
+// writeJSONResponse 写入 JSON 响应
+func (h *EmbeddingHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("写入响应失败", logger.Fields{"error": err})
+	}
+}
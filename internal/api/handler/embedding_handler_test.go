@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/pkg/errors"
+)
+
+// mockEmbeddingService 模拟文本向量化服务
+type mockEmbeddingService struct {
+	lastReq *model.EmbeddingRequest
+}
+
+func (m *mockEmbeddingService) Embed(ctx context.Context, req *model.EmbeddingRequest) (*model.EmbeddingResponse, error) {
+	m.lastReq = req
+	if req.Model == "qwen-plus" {
+		return nil, errors.NewBadRequestError("模型 'qwen-plus' 不是向量模型")
+	}
+	if req.Model == "unknown" {
+		return nil, errors.NewModelNotFoundError(req.Model)
+	}
+
+	resp := &model.EmbeddingResponse{Model: req.Model, Usage: &model.Usage{PromptTokens: len(req.Input), TotalTokens: len(req.Input)}}
+	for i := range req.Input {
+		resp.Data = append(resp.Data, model.EmbeddingData{Index: i, Embedding: []float32{0.1, 0.2}})
+	}
+	return resp, nil
+}
+
+func TestHandleEmbeddings(t *testing.T) {
+	t.Run("成功生成向量", func(t *testing.T) {
+		service := &mockEmbeddingService{}
+		handler := NewEmbeddingHandler(service, logger.Default())
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/embeddings",
+			strings.NewReader(`{"model":"tongyi/text-embedding-v3","input":["你好","世界"],"dimensions":512}`))
+		w := httptest.NewRecorder()
+		handler.HandleEmbeddings(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("期望状态码 200, 得到 %d: %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Data model.EmbeddingResponse `json:"data"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("解析响应失败: %v", err)
+		}
+		if len(resp.Data.Data) != 2 || resp.Data.Data[1].Index != 1 || resp.Data.Usage.TotalTokens != 2 {
+			t.Errorf("响应不正确: %+v", resp.Data)
+		}
+		if service.lastReq.Dimensions == nil || *service.lastReq.Dimensions != 512 {
+			t.Errorf("期望传递 dimensions, 得到 %+v", service.lastReq)
+		}
+	})
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "无效的 JSON", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "缺少模型", body: `{"input":["a"]}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "输入为空", body: `{"model":"text-embedding-004","input":[]}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "输入包含空字符串", body: `{"model":"text-embedding-004","input":["a",""]}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "不是向量模型", body: `{"model":"qwen-plus","input":["a"]}`, wantStatus: http.StatusBadRequest},
+		{name: "模型不存在", body: `{"model":"unknown","input":["a"]}`, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewEmbeddingHandler(&mockEmbeddingService{}, logger.Default())
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/embeddings", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			handler.HandleEmbeddings(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("期望状态码 %d, 得到 %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
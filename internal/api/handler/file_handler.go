@@ -0,0 +1,288 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/service/file"
+	"genkit-ai-service/pkg/errors"
+	"genkit-ai-service/pkg/response"
+)
+
+// uploadFieldName 上传请求中文件内容所在的表单字段名
+const uploadFieldName = "file"
+
+// FileHandler 上传文件处理器
+type FileHandler struct {
+	fileService file.FileService
+	logger      logger.Logger
+}
+
+// NewFileHandler 创建上传文件处理器实例
+func NewFileHandler(fileService file.FileService, log logger.Logger) *FileHandler {
+	return &FileHandler{
+		fileService: fileService,
+		logger:      log,
+	}
+}
+
+// UploadFile 上传文件
+// @Summary 上传文件
+// @Description 以 multipart/form-data 上传文件，文件内容放在 file 字段中。服务根据内容检测 MIME 类型，返回的文件ID可在消息片段中以 fileId 引用
+// @Tags files
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "文件内容"
+// @Success 200 {object} model.ResponseData[model.File] "成功上传文件"
+// @Failure 400 {object} model.ErrorResponse "请求参数错误"
+// @Failure 413 {object} model.ErrorResponse "文件过大"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /files [post]
+func (h *FileHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 从上下文获取用户ID
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		userID = "default-user-id" // 临时默认值
+	}
+
+	// 2. 以流的方式读取 multipart 请求，找到文件字段
+	reader, err := r.MultipartReader()
+	if err != nil {
+		h.logger.Warn("解析上传请求失败", logger.Fields{"error": err})
+		h.writeErrorResponse(w, errors.NewBadRequestError("请求必须为 multipart/form-data"))
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			h.writeErrorResponse(w, errors.NewBadRequestError("缺少 file 字段"))
+			return
+		}
+		if err != nil {
+			h.logger.Warn("解析上传请求失败", logger.Fields{"error": err})
+			h.writeErrorResponse(w, errors.NewBadRequestError("无效的 multipart 请求"))
+			return
+		}
+		if part.FormName() != uploadFieldName {
+			part.Close()
+			continue
+		}
+
+		h.logger.Info("收到上传文件请求", logger.Fields{
+			"userId":   userID,
+			"fileName": part.FileName(),
+		})
+
+		// 3. 调用服务层保存文件
+		uploaded, err := h.fileService.Upload(ctx, userID, part.FileName(), part)
+		part.Close()
+		if err != nil {
+			h.logger.Error("上传文件失败", logger.Fields{"error": err, "userId": userID})
+			h.writeErrorResponse(w, toAppError(err))
+			return
+		}
+
+		// 4. 返回成功响应
+		h.writeJSONResponse(w, http.StatusOK, response.Success(uploaded))
+		return
+	}
+}
+
+// GetFile 获取文件信息
+// @Summary 获取文件信息
+// @Description 获取用户上传的文件的元数据
+// @Tags files
+// @Produce json
+// @Param id path string true "文件ID"
+// @Success 200 {object} model.ResponseData[model.File] "成功返回文件信息"
+// @Failure 403 {object} model.ErrorResponse "无权访问"
+// @Failure 404 {object} model.ErrorResponse "文件不存在"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /files/{id} [get]
+func (h *FileHandler) GetFile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 从URL路径中提取文件ID
+	fileID := h.extractFileID(r.URL.Path)
+	if fileID == "" {
+		h.writeErrorResponse(w, errors.NewBadRequestError("文件ID不能为空"))
+		return
+	}
+
+	// 2. 从上下文获取用户ID
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		userID = "default-user-id" // 临时默认值
+	}
+
+	// 3. 调用服务层获取文件信息
+	found, err := h.fileService.GetFile(ctx, fileID, userID)
+	if err != nil {
+		h.logger.Error("获取文件信息失败", logger.Fields{
+			"error":  err,
+			"fileId": fileID,
+			"userId": userID,
+		})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 4. 返回成功响应
+	h.writeJSONResponse(w, http.StatusOK, response.Success(found))
+}
+
+// DownloadFile 下载文件内容
+// @Summary 下载文件内容
+// @Description 以附件形式返回用户上传的文件内容，Content-Type 为检测到的 MIME 类型
+// @Tags files
+// @Produce octet-stream
+// @Param id path string true "文件ID"
+// @Success 200 {file} file "文件内容"
+// @Failure 403 {object} model.ErrorResponse "无权访问"
+// @Failure 404 {object} model.ErrorResponse "文件不存在"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /files/{id}/content [get]
+func (h *FileHandler) DownloadFile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 从URL路径中提取文件ID
+	fileID := h.extractFileID(strings.TrimSuffix(strings.TrimSuffix(r.URL.Path, "/"), "/content"))
+	if fileID == "" {
+		h.writeErrorResponse(w, errors.NewBadRequestError("文件ID不能为空"))
+		return
+	}
+
+	// 2. 从上下文获取用户ID
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		userID = "default-user-id" // 临时默认值
+	}
+
+	// 3. 调用服务层打开文件内容
+	found, content, err := h.fileService.OpenFile(ctx, fileID, userID)
+	if err != nil {
+		h.logger.Error("打开文件失败", logger.Fields{
+			"error":  err,
+			"fileId": fileID,
+			"userId": userID,
+		})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+	defer content.Close()
+
+	// 4. 以附件形式返回内容，禁止浏览器猜测内容类型
+	w.Header().Set("Content-Type", found.MimeType)
+	w.Header().Set("Content-Length", strconv.FormatInt(found.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": found.Name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, content); err != nil {
+		h.logger.Error("写入文件内容失败", logger.Fields{"error": err, "fileId": fileID})
+	}
+}
+
+// DeleteFile 删除文件
+// @Summary 删除文件
+// @Description 删除用户上传的文件，已发送的消息中引用该文件的附件在之后的对话中不再提供给模型
+// @Tags files
+// @Produce json
+// @Param id path string true "文件ID"
+// @Success 200 {object} model.ResponseData[any] "成功删除文件"
+// @Failure 403 {object} model.ErrorResponse "无权访问"
+// @Failure 404 {object} model.ErrorResponse "文件不存在"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /files/{id} [delete]
+func (h *FileHandler) DeleteFile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 从URL路径中提取文件ID
+	fileID := h.extractFileID(r.URL.Path)
+	if fileID == "" {
+		h.writeErrorResponse(w, errors.NewBadRequestError("文件ID不能为空"))
+		return
+	}
+
+	// 2. 从上下文获取用户ID
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		userID = "default-user-id" // 临时默认值
+	}
+
+	h.logger.Info("收到删除文件请求", logger.Fields{
+		"fileId": fileID,
+		"userId": userID,
+	})
+
+	// 3. 调用服务层删除文件
+	if err := h.fileService.DeleteFile(ctx, fileID, userID); err != nil {
+		h.logger.Error("删除文件失败", logger.Fields{
+			"error":  err,
+			"fileId": fileID,
+			"userId": userID,
+		})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 4. 返回成功响应
+	h.writeJSONResponse(w, http.StatusOK, response.Success[any](nil))
+}
+
+// extractFileID 从URL路径中提取文件ID
+// 路径格式: /api/v1/files/{id}
+func (h *FileHandler) extractFileID(path string) string {
+	path = strings.TrimSuffix(path, "/")
+
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if part == "files" && i+1 < len(parts) {
+			return parts[i+1]
+		}
+	}
+
+	return ""
+}
+
+// writeErrorResponse 写入错误响应
+func (h *FileHandler) writeErrorResponse(w http.ResponseWriter, appErr *errors.AppError) {
+	resp := response.Error[any](appErr.Code, appErr.Message)
+
+	// 根据错误码确定 HTTP 状态码
+	statusCode := http.StatusInternalServerError
+	switch appErr.Code {
+	case errors.CodeBadRequest:
+		statusCode = http.StatusBadRequest
+	case errors.CodeNotFound, errors.CodeFileNotFound:
+		statusCode = http.StatusNotFound
+	case errors.CodeUnauthorized:
+		statusCode = http.StatusUnauthorized
+	case errors.CodeForbidden, errors.CodeFileAccessDenied:
+		statusCode = http.StatusForbidden
+	case errors.CodeFileTooLarge:
+		statusCode = http.StatusRequestEntityTooLarge
+	case errors.CodeServiceUnavailable:
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	h.writeJSONResponse(w, statusCode, resp)
+}
+
+// writeJSONResponse 写入 JSON 响应
+func (h *FileHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("写入响应失败", logger.Fields{"error": err})
+	}
+}
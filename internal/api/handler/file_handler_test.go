@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/pkg/errors"
+)
+
+// mockFileService 模拟上传文件服务
+type mockFileService struct {
+	files    map[string]*model.File
+	contents map[string]string
+	maxSize  int64
+}
+
+func newMockFileService() *mockFileService {
+	return &mockFileService{
+		files: map[string]*model.File{
+			"file-1": {ID: "file-1", UserID: "test-user", Name: "报告.pdf", MimeType: "application/pdf", Size: 4},
+		},
+		contents: map[string]string{"file-1": "%PDF"},
+		maxSize:  8,
+	}
+}
+
+func (m *mockFileService) Upload(ctx context.Context, userID, name string, content io.Reader) (*model.File, error) {
+	data, _ := io.ReadAll(content)
+	if int64(len(data)) > m.maxSize {
+		return nil, errors.NewFileTooLargeError(m.maxSize)
+	}
+	file := &model.File{ID: "file-2", UserID: userID, Name: name, MimeType: "text/plain", Size: int64(len(data))}
+	m.files[file.ID] = file
+	m.contents[file.ID] = string(data)
+	return file, nil
+}
+
+func (m *mockFileService) GetFile(ctx context.Context, fileID, userID string) (*model.File, error) {
+	file, ok := m.files[fileID]
+	if !ok {
+		return nil, errors.NewFileNotFoundError(fileID)
+	}
+	if file.UserID != userID {
+		return nil, errors.NewFileAccessDeniedError()
+	}
+	return file, nil
+}
+
+func (m *mockFileService) OpenFile(ctx context.Context, fileID, userID string) (*model.File, io.ReadCloser, error) {
+	file, err := m.GetFile(ctx, fileID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, io.NopCloser(strings.NewReader(m.contents[fileID])), nil
+}
+
+func (m *mockFileService) DeleteFile(ctx context.Context, fileID, userID string) error {
+	if _, err := m.GetFile(ctx, fileID, userID); err != nil {
+		return err
+	}
+	delete(m.files, fileID)
+	return nil
+}
+
+func (m *mockFileService) ReadFile(ctx context.Context, fileID string) (*model.File, []byte, error) {
+	return m.files[fileID], []byte(m.contents[fileID]), nil
+}
+
+// newUploadRequest 构建 multipart 上传请求
+func newUploadRequest(t *testing.T, field, fileName, content string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("purpose", "chat"); err != nil {
+		t.Fatalf("写入表单失败: %v", err)
+	}
+	part, err := writer.CreateFormFile(field, fileName)
+	if err != nil {
+		t.Fatalf("创建表单文件失败: %v", err)
+	}
+	part.Write([]byte(content))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/files", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("X-User-ID", "test-user")
+	return req
+}
+
+func TestUploadFile(t *testing.T) {
+	handler := NewFileHandler(newMockFileService(), logger.Default())
+
+	t.Run("成功上传", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.UploadFile(w, newUploadRequest(t, "file", "notes.txt", "hello"))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("期望状态码 200, 得到 %d: %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Data model.File `json:"data"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("解析响应失败: %v", err)
+		}
+		if resp.Data.Name != "notes.txt" || resp.Data.Size != 5 || resp.Data.UserID != "test-user" {
+			t.Errorf("文件信息不正确: %+v", resp.Data)
+		}
+	})
+
+	tests := []struct {
+		name       string
+		req        func() *http.Request
+		wantStatus int
+	}{
+		{name: "缺少文件字段", req: func() *http.Request { return newUploadRequest(t, "attachment", "a.txt", "hello") }, wantStatus: http.StatusBadRequest},
+		{name: "文件过大", req: func() *http.Request { return newUploadRequest(t, "file", "a.txt", "0123456789") }, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "不是 multipart 请求", req: func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/api/v1/files", strings.NewReader(`{}`))
+		}, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.UploadFile(w, tt.req())
+			if w.Code != tt.wantStatus {
+				t.Errorf("期望状态码 %d, 得到 %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetAndDeleteFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		userID     string
+		wantStatus int
+	}{
+		{name: "获取文件信息", method: http.MethodGet, path: "/api/v1/files/file-1", userID: "test-user", wantStatus: http.StatusOK},
+		{name: "文件不存在", method: http.MethodGet, path: "/api/v1/files/missing", userID: "test-user", wantStatus: http.StatusNotFound},
+		{name: "无权访问", method: http.MethodGet, path: "/api/v1/files/file-1", userID: "other-user", wantStatus: http.StatusForbidden},
+		{name: "无权删除", method: http.MethodDelete, path: "/api/v1/files/file-1", userID: "other-user", wantStatus: http.StatusForbidden},
+		{name: "删除文件", method: http.MethodDelete, path: "/api/v1/files/file-1", userID: "test-user", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewFileHandler(newMockFileService(), logger.Default())
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("X-User-ID", tt.userID)
+			w := httptest.NewRecorder()
+
+			if tt.method == http.MethodDelete {
+				handler.DeleteFile(w, req)
+			} else {
+				handler.GetFile(w, req)
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("期望状态码 %d, 得到 %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestDownloadFile(t *testing.T) {
+	handler := NewFileHandler(newMockFileService(), logger.Default())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/file-1/content", nil)
+	req.Header.Set("X-User-ID", "test-user")
+	w := httptest.NewRecorder()
+	handler.DownloadFile(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 200, 得到 %d: %s", w.Code, w.Body.String())
+	}
+	if w.Body.String() != "%PDF" {
+		t.Errorf("文件内容不正确: %q", w.Body.String())
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/pdf" {
+		t.Errorf("期望 Content-Type 为 application/pdf, 得到 %s", got)
+	}
+	if got := w.Header().Get("Content-Disposition"); !strings.HasPrefix(got, "attachment;") {
+		t.Errorf("期望以附件形式返回, 得到 %s", got)
+	}
+}
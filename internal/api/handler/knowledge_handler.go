@@ -0,0 +1,486 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/service/knowledge"
+	"genkit-ai-service/pkg/errors"
+	"genkit-ai-service/pkg/response"
+	"genkit-ai-service/pkg/validator"
+)
+
+// KnowledgeHandler 知识库处理器
+type KnowledgeHandler struct {
+	knowledgeService knowledge.KnowledgeService
+	logger           logger.Logger
+	validator        *validator.Validator
+}
+
+// NewKnowledgeHandler 创建知识库处理器实例
+func NewKnowledgeHandler(knowledgeService knowledge.KnowledgeService, log logger.Logger) *KnowledgeHandler {
+	return &KnowledgeHandler{
+		knowledgeService: knowledgeService,
+		logger:           log,
+		validator:        validator.New(),
+	}
+}
+
+// CreateKnowledgeBase 创建知识库
+// @Summary 创建知识库
+// @Description 创建知识库，指定向量模型和分块策略。向量模型必须是目录中的 text_embedding 模型，创建后不能修改
+// @Tags knowledge-bases
+// @Accept json
+// @Produce json
+// @Param request body model.CreateKnowledgeBaseRequest true "创建知识库请求"
+// @Success 200 {object} model.ResponseData[model.KnowledgeBase] "成功创建知识库"
+// @Failure 400 {object} model.ErrorResponse "请求参数错误或模型不是向量模型"
+// @Failure 404 {object} model.ErrorResponse "模型不存在"
+// @Failure 422 {object} model.ErrorResponse "参数验证失败"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /knowledge-bases [post]
+func (h *KnowledgeHandler) CreateKnowledgeBase(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 解析请求参数
+	var req model.CreateKnowledgeBaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("解析请求参数失败", logger.Fields{"error": err})
+		h.writeErrorResponse(w, errors.NewBadRequestError("无效的请求参数"))
+		return
+	}
+
+	// 2. 验证请求参数
+	if validationErrors := h.validator.ValidateStruct(&req); validationErrors != nil {
+		h.logger.Warn("请求参数验证失败", logger.Fields{"errors": validationErrors})
+		h.writeValidationErrorResponse(w, validationErrors)
+		return
+	}
+
+	// 3. 从上下文获取用户ID
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		userID = "default-user-id" // 临时默认值
+	}
+
+	// 4. 调用服务层创建知识库
+	kb, err := h.knowledgeService.CreateKnowledgeBase(ctx, userID, &req)
+	if err != nil {
+		h.logger.Error("创建知识库失败", logger.Fields{"error": err, "userId": userID})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 5. 返回成功响应
+	h.writeJSONResponse(w, http.StatusOK, response.Success(kb))
+}
+
+// ListKnowledgeBases 获取知识库列表
+// @Summary 获取知识库列表
+// @Description 获取当前用户的知识库列表，按创建时间倒序排列
+// @Tags knowledge-bases
+// @Produce json
+// @Success 200 {object} model.ResponseData[[]model.KnowledgeBase] "成功返回知识库列表"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /knowledge-bases [get]
+func (h *KnowledgeHandler) ListKnowledgeBases(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 从上下文获取用户ID
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		userID = "default-user-id" // 临时默认值
+	}
+
+	// 2. 调用服务层获取知识库列表
+	bases, err := h.knowledgeService.ListKnowledgeBases(ctx, userID)
+	if err != nil {
+		h.logger.Error("获取知识库列表失败", logger.Fields{"error": err, "userId": userID})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 3. 返回成功响应
+	h.writeJSONResponse(w, http.StatusOK, response.Success(&bases))
+}
+
+// GetKnowledgeBase 获取知识库详情
+// @Summary 获取知识库详情
+// @Description 获取用户拥有的知识库
+// @Tags knowledge-bases
+// @Produce json
+// @Param id path string true "知识库ID"
+// @Success 200 {object} model.ResponseData[model.KnowledgeBase] "成功返回知识库"
+// @Failure 403 {object} model.ErrorResponse "无权访问"
+// @Failure 404 {object} model.ErrorResponse "知识库不存在"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /knowledge-bases/{id} [get]
+func (h *KnowledgeHandler) GetKnowledgeBase(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 从URL路径中提取知识库ID
+	knowledgeBaseID := extractPathSegment(r.URL.Path, "knowledge-bases")
+	if knowledgeBaseID == "" {
+		h.writeErrorResponse(w, errors.NewBadRequestError("知识库ID不能为空"))
+		return
+	}
+
+	// 2. 从上下文获取用户ID
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		userID = "default-user-id" // 临时默认值
+	}
+
+	// 3. 调用服务层获取知识库
+	kb, err := h.knowledgeService.GetKnowledgeBase(ctx, knowledgeBaseID, userID)
+	if err != nil {
+		h.logger.Error("获取知识库失败", logger.Fields{
+			"error":           err,
+			"knowledgeBaseId": knowledgeBaseID,
+			"userId":          userID,
+		})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 4. 返回成功响应
+	h.writeJSONResponse(w, http.StatusOK, response.Success(kb))
+}
+
+// DeleteKnowledgeBase 删除知识库
+// @Summary 删除知识库
+// @Description 删除用户拥有的知识库及其全部文档和分块，挂载该知识库的会话之后不再从中检索
+// @Tags knowledge-bases
+// @Produce json
+// @Param id path string true "知识库ID"
+// @Success 200 {object} model.ResponseData[any] "成功删除知识库"
+// @Failure 403 {object} model.ErrorResponse "无权访问"
+// @Failure 404 {object} model.ErrorResponse "知识库不存在"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /knowledge-bases/{id} [delete]
+func (h *KnowledgeHandler) DeleteKnowledgeBase(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 从URL路径中提取知识库ID
+	knowledgeBaseID := extractPathSegment(r.URL.Path, "knowledge-bases")
+	if knowledgeBaseID == "" {
+		h.writeErrorResponse(w, errors.NewBadRequestError("知识库ID不能为空"))
+		return
+	}
+
+	// 2. 从上下文获取用户ID
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		userID = "default-user-id" // 临时默认值
+	}
+
+	h.logger.Info("收到删除知识库请求", logger.Fields{
+		"knowledgeBaseId": knowledgeBaseID,
+		"userId":          userID,
+	})
+
+	// 3. 调用服务层删除知识库
+	if err := h.knowledgeService.DeleteKnowledgeBase(ctx, knowledgeBaseID, userID); err != nil {
+		h.logger.Error("删除知识库失败", logger.Fields{
+			"error":           err,
+			"knowledgeBaseId": knowledgeBaseID,
+			"userId":          userID,
+		})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 4. 返回成功响应
+	h.writeJSONResponse(w, http.StatusOK, response.Success[any](nil))
+}
+
+// AddDocument 添加知识库文档
+// @Summary 添加知识库文档
+// @Description 向知识库添加文档，内容直接提交或引用上传的文本文件。文档按知识库的分块策略切分，并使用知识库的向量模型生成向量
+// @Tags knowledge-bases
+// @Accept json
+// @Produce json
+// @Param id path string true "知识库ID"
+// @Param request body model.AddDocumentRequest true "添加文档请求"
+// @Success 200 {object} model.ResponseData[model.KnowledgeDocument] "成功添加文档"
+// @Failure 400 {object} model.ErrorResponse "请求参数错误或文件不是文本文件"
+// @Failure 403 {object} model.ErrorResponse "无权访问"
+// @Failure 404 {object} model.ErrorResponse "知识库或文件不存在"
+// @Failure 422 {object} model.ErrorResponse "参数验证失败"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /knowledge-bases/{id}/documents [post]
+func (h *KnowledgeHandler) AddDocument(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 从URL路径中提取知识库ID
+	knowledgeBaseID := extractPathSegment(r.URL.Path, "knowledge-bases")
+	if knowledgeBaseID == "" {
+		h.writeErrorResponse(w, errors.NewBadRequestError("知识库ID不能为空"))
+		return
+	}
+
+	// 2. 解析请求参数
+	var req model.AddDocumentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("解析请求参数失败", logger.Fields{"error": err})
+		h.writeErrorResponse(w, errors.NewBadRequestError("无效的请求参数"))
+		return
+	}
+
+	// 3. 验证请求参数
+	if validationErrors := h.validator.ValidateStruct(&req); validationErrors != nil {
+		h.logger.Warn("请求参数验证失败", logger.Fields{"errors": validationErrors})
+		h.writeValidationErrorResponse(w, validationErrors)
+		return
+	}
+
+	// 4. 从上下文获取用户ID
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		userID = "default-user-id" // 临时默认值
+	}
+
+	h.logger.Info("收到添加知识库文档请求", logger.Fields{
+		"knowledgeBaseId": knowledgeBaseID,
+		"userId":          userID,
+		"fileId":          req.FileID,
+	})
+
+	// 5. 调用服务层添加文档
+	doc, err := h.knowledgeService.AddDocument(ctx, knowledgeBaseID, userID, &req)
+	if err != nil {
+		h.logger.Error("添加知识库文档失败", logger.Fields{
+			"error":           err,
+			"knowledgeBaseId": knowledgeBaseID,
+			"userId":          userID,
+		})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 6. 返回成功响应
+	h.writeJSONResponse(w, http.StatusOK, response.Success(doc))
+}
+
+// ListDocuments 获取知识库文档列表
+// @Summary 获取知识库文档列表
+// @Description 获取知识库的文档列表，按创建时间倒序排列，不包含文档内容
+// @Tags knowledge-bases
+// @Produce json
+// @Param id path string true "知识库ID"
+// @Success 200 {object} model.ResponseData[[]model.KnowledgeDocument] "成功返回文档列表"
+// @Failure 403 {object} model.ErrorResponse "无权访问"
+// @Failure 404 {object} model.ErrorResponse "知识库不存在"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /knowledge-bases/{id}/documents [get]
+func (h *KnowledgeHandler) ListDocuments(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 从URL路径中提取知识库ID
+	knowledgeBaseID := extractPathSegment(r.URL.Path, "knowledge-bases")
+	if knowledgeBaseID == "" {
+		h.writeErrorResponse(w, errors.NewBadRequestError("知识库ID不能为空"))
+		return
+	}
+
+	// 2. 从上下文获取用户ID
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		userID = "default-user-id" // 临时默认值
+	}
+
+	// 3. 调用服务层获取文档列表
+	docs, err := h.knowledgeService.ListDocuments(ctx, knowledgeBaseID, userID)
+	if err != nil {
+		h.logger.Error("获取知识库文档列表失败", logger.Fields{
+			"error":           err,
+			"knowledgeBaseId": knowledgeBaseID,
+			"userId":          userID,
+		})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 4. 返回成功响应
+	h.writeJSONResponse(w, http.StatusOK, response.Success(&docs))
+}
+
+// DeleteDocument 删除知识库文档
+// @Summary 删除知识库文档
+// @Description 删除知识库中的文档及其分块
+// @Tags knowledge-bases
+// @Produce json
+// @Param id path string true "知识库ID"
+// @Param docId path string true "文档ID"
+// @Success 200 {object} model.ResponseData[any] "成功删除文档"
+// @Failure 403 {object} model.ErrorResponse "无权访问"
+// @Failure 404 {object} model.ErrorResponse "知识库或文档不存在"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /knowledge-bases/{id}/documents/{docId} [delete]
+func (h *KnowledgeHandler) DeleteDocument(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 从URL路径中提取知识库ID和文档ID
+	knowledgeBaseID := extractPathSegment(r.URL.Path, "knowledge-bases")
+	documentID := extractPathSegment(r.URL.Path, "documents")
+	if knowledgeBaseID == "" || documentID == "" {
+		h.writeErrorResponse(w, errors.NewBadRequestError("知识库ID和文档ID不能为空"))
+		return
+	}
+
+	// 2. 从上下文获取用户ID
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		userID = "default-user-id" // 临时默认值
+	}
+
+	// 3. 调用服务层删除文档
+	if err := h.knowledgeService.DeleteDocument(ctx, knowledgeBaseID, documentID, userID); err != nil {
+		h.logger.Error("删除知识库文档失败", logger.Fields{
+			"error":           err,
+			"knowledgeBaseId": knowledgeBaseID,
+			"documentId":      documentID,
+			"userId":          userID,
+		})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 4. 返回成功响应
+	h.writeJSONResponse(w, http.StatusOK, response.Success[any](nil))
+}
+
+// SearchKnowledgeBase 检索知识库
+// @Summary 检索知识库
+// @Description 在知识库中检索与查询最相关的分块，按相似度降序返回。可用于在挂载到会话前检查检索效果
+// @Tags knowledge-bases
+// @Accept json
+// @Produce json
+// @Param id path string true "知识库ID"
+// @Param request body model.SearchKnowledgeRequest true "检索请求"
+// @Success 200 {object} model.ResponseData[[]model.Citation] "成功返回命中的分块"
+// @Failure 400 {object} model.ErrorResponse "请求参数错误"
+// @Failure 403 {object} model.ErrorResponse "无权访问"
+// @Failure 404 {object} model.ErrorResponse "知识库不存在"
+// @Failure 422 {object} model.ErrorResponse "参数验证失败"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /knowledge-bases/{id}/search [post]
+func (h *KnowledgeHandler) SearchKnowledgeBase(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 从URL路径中提取知识库ID
+	knowledgeBaseID := extractPathSegment(r.URL.Path, "knowledge-bases")
+	if knowledgeBaseID == "" {
+		h.writeErrorResponse(w, errors.NewBadRequestError("知识库ID不能为空"))
+		return
+	}
+
+	// 2. 解析并验证请求参数
+	var req model.SearchKnowledgeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("解析请求参数失败", logger.Fields{"error": err})
+		h.writeErrorResponse(w, errors.NewBadRequestError("无效的请求参数"))
+		return
+	}
+	if validationErrors := h.validator.ValidateStruct(&req); validationErrors != nil {
+		h.logger.Warn("请求参数验证失败", logger.Fields{"errors": validationErrors})
+		h.writeValidationErrorResponse(w, validationErrors)
+		return
+	}
+
+	// 3. 从上下文获取用户ID
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		userID = "default-user-id" // 临时默认值
+	}
+
+	// 4. 确认知识库存在且属于用户，检索时不可用的知识库会被跳过
+	if _, err := h.knowledgeService.GetKnowledgeBase(ctx, knowledgeBaseID, userID); err != nil {
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 5. 调用服务层检索
+	citations, err := h.knowledgeService.Search(ctx, userID, []string{knowledgeBaseID}, req.Query, req.TopK)
+	if err != nil {
+		h.logger.Error("检索知识库失败", logger.Fields{
+			"error":           err,
+			"knowledgeBaseId": knowledgeBaseID,
+			"userId":          userID,
+		})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+	if citations == nil {
+		citations = []model.Citation{}
+	}
+
+	// 6. 返回成功响应
+	h.writeJSONResponse(w, http.StatusOK, response.Success(&citations))
+}
+
+// extractPathSegment 从URL路径中提取指定路径段之后的一段
+// 如 /api/v1/knowledge-bases/{id}/documents/{docId} 中 "documents" 之后的 {docId}
+func extractPathSegment(path, name string) string {
+	path = strings.TrimSuffix(path, "/")
+
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if part == name && i+1 < len(parts) {
+			return parts[i+1]
+		}
+	}
+
+	return ""
+}
+
+// writeErrorResponse 写入错误响应
+func (h *KnowledgeHandler) writeErrorResponse(w http.ResponseWriter, appErr *errors.AppError) {
+	resp := response.Error[any](appErr.Code, appErr.Message)
+
+	// 根据错误码确定 HTTP 状态码
+	statusCode := http.StatusInternalServerError
+	switch appErr.Code {
+	case errors.CodeBadRequest:
+		statusCode = http.StatusBadRequest
+	case errors.CodeValidationError:
+		statusCode = http.StatusUnprocessableEntity
+	case errors.CodeNotFound, errors.CodeKnowledgeBaseNotFound, errors.CodeDocumentNotFound,
+		errors.CodeModelNotFound, errors.CodeProviderNotFound, errors.CodeFileNotFound:
+		statusCode = http.StatusNotFound
+	case errors.CodeForbidden, errors.CodeKnowledgeBaseAccessDenied, errors.CodeFileAccessDenied:
+		statusCode = http.StatusForbidden
+	case errors.CodeServiceUnavailable:
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	h.writeJSONResponse(w, statusCode, resp)
+}
+
+// writeValidationErrorResponse 写入验证错误响应
+func (h *KnowledgeHandler) writeValidationErrorResponse(w http.ResponseWriter, validationErrors []validator.ValidationError) {
+	errorData := map[string]interface{}{
+		"errors": validationErrors,
+	}
+
+	resp := response.ErrorWithData(
+		errors.CodeValidationError,
+		errors.MsgValidationError,
+		&errorData,
+	)
+
+	h.writeJSONResponse(w, http.StatusUnprocessableEntity, resp)
+}
+
+// writeJSONResponse 写入 JSON 响应
+func (h *KnowledgeHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("写入响应失败", logger.Fields{"error": err})
+	}
+}
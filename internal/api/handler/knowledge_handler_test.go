@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/pkg/errors"
+)
+
+// mockKnowledgeService 模拟知识库服务
+type mockKnowledgeService struct {
+	bases      map[string]*model.KnowledgeBase
+	lastDocReq *model.AddDocumentRequest
+	lastTopK   int
+}
+
+func newMockKnowledgeService() *mockKnowledgeService {
+	return &mockKnowledgeService{
+		bases: map[string]*model.KnowledgeBase{
+			"kb-1": {ID: "kb-1", UserID: "test-user", Name: "文档", EmbeddingModel: "embed"},
+		},
+	}
+}
+
+func (m *mockKnowledgeService) CreateKnowledgeBase(ctx context.Context, userID string, req *model.CreateKnowledgeBaseRequest) (*model.KnowledgeBase, error) {
+	if req.EmbeddingModel == "chat" {
+		return nil, errors.NewBadRequestError("模型 'chat' 不是向量模型")
+	}
+	kb := &model.KnowledgeBase{ID: "kb-2", UserID: userID, Name: req.Name, EmbeddingModel: req.EmbeddingModel}
+	m.bases[kb.ID] = kb
+	return kb, nil
+}
+
+func (m *mockKnowledgeService) ListKnowledgeBases(ctx context.Context, userID string) ([]*model.KnowledgeBase, error) {
+	var bases []*model.KnowledgeBase
+	for _, kb := range m.bases {
+		if kb.UserID == userID {
+			bases = append(bases, kb)
+		}
+	}
+	return bases, nil
+}
+
+func (m *mockKnowledgeService) GetKnowledgeBase(ctx context.Context, knowledgeBaseID, userID string) (*model.KnowledgeBase, error) {
+	kb, ok := m.bases[knowledgeBaseID]
+	if !ok {
+		return nil, errors.NewKnowledgeBaseNotFoundError(knowledgeBaseID)
+	}
+	if kb.UserID != userID {
+		return nil, errors.NewKnowledgeBaseAccessDeniedError()
+	}
+	return kb, nil
+}
+
+func (m *mockKnowledgeService) DeleteKnowledgeBase(ctx context.Context, knowledgeBaseID, userID string) error {
+	if _, err := m.GetKnowledgeBase(ctx, knowledgeBaseID, userID); err != nil {
+		return err
+	}
+	delete(m.bases, knowledgeBaseID)
+	return nil
+}
+
+func (m *mockKnowledgeService) AddDocument(ctx context.Context, knowledgeBaseID, userID string, req *model.AddDocumentRequest) (*model.KnowledgeDocument, error) {
+	if _, err := m.GetKnowledgeBase(ctx, knowledgeBaseID, userID); err != nil {
+		return nil, err
+	}
+	m.lastDocReq = req
+	return &model.KnowledgeDocument{ID: "doc-1", KnowledgeBaseID: knowledgeBaseID, Name: req.Name, ChunkCount: 1}, nil
+}
+
+func (m *mockKnowledgeService) ListDocuments(ctx context.Context, knowledgeBaseID, userID string) ([]*model.KnowledgeDocument, error) {
+	if _, err := m.GetKnowledgeBase(ctx, knowledgeBaseID, userID); err != nil {
+		return nil, err
+	}
+	return []*model.KnowledgeDocument{{ID: "doc-1", KnowledgeBaseID: knowledgeBaseID, Name: "a.md"}}, nil
+}
+
+func (m *mockKnowledgeService) DeleteDocument(ctx context.Context, knowledgeBaseID, documentID, userID string) error {
+	if _, err := m.GetKnowledgeBase(ctx, knowledgeBaseID, userID); err != nil {
+		return err
+	}
+	if documentID != "doc-1" {
+		return errors.NewDocumentNotFoundError(documentID)
+	}
+	return nil
+}
+
+func (m *mockKnowledgeService) Search(ctx context.Context, userID string, knowledgeBaseIDs []string, query string, topK int) ([]model.Citation, error) {
+	m.lastTopK = topK
+	return []model.Citation{{KnowledgeBaseID: knowledgeBaseIDs[0], DocumentName: "a.md", Content: query, Score: 0.9}}, nil
+}
+
+func TestKnowledgeHandler_CreateKnowledgeBase(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "成功创建", body: `{"name":"文档","embeddingModel":"embed","chunkStrategy":"markdown"}`, wantStatus: http.StatusOK},
+		{name: "缺少向量模型", body: `{"name":"文档"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "未知分块策略", body: `{"name":"文档","embeddingModel":"embed","chunkStrategy":"sentences"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "不是向量模型", body: `{"name":"文档","embeddingModel":"chat"}`, wantStatus: http.StatusBadRequest},
+		{name: "无效的 JSON", body: `{`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewKnowledgeHandler(newMockKnowledgeService(), logger.Default())
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/knowledge-bases", strings.NewReader(tt.body))
+			req.Header.Set("X-User-ID", "test-user")
+			w := httptest.NewRecorder()
+			handler.CreateKnowledgeBase(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("期望状态码 %d, 得到 %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestKnowledgeHandler_Documents(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		userID     string
+		wantStatus int
+	}{
+		{name: "添加文档", method: http.MethodPost, path: "/api/v1/knowledge-bases/kb-1/documents", body: `{"name":"a.md","content":"# 标题"}`, userID: "test-user", wantStatus: http.StatusOK},
+		{name: "缺少内容和文件", method: http.MethodPost, path: "/api/v1/knowledge-bases/kb-1/documents", body: `{"name":"a.md"}`, userID: "test-user", wantStatus: http.StatusUnprocessableEntity},
+		{name: "知识库不存在", method: http.MethodPost, path: "/api/v1/knowledge-bases/missing/documents", body: `{"name":"a.md","content":"a"}`, userID: "test-user", wantStatus: http.StatusNotFound},
+		{name: "无权访问", method: http.MethodGet, path: "/api/v1/knowledge-bases/kb-1/documents", userID: "other-user", wantStatus: http.StatusForbidden},
+		{name: "文档列表", method: http.MethodGet, path: "/api/v1/knowledge-bases/kb-1/documents", userID: "test-user", wantStatus: http.StatusOK},
+		{name: "删除文档", method: http.MethodDelete, path: "/api/v1/knowledge-bases/kb-1/documents/doc-1", userID: "test-user", wantStatus: http.StatusOK},
+		{name: "文档不存在", method: http.MethodDelete, path: "/api/v1/knowledge-bases/kb-1/documents/doc-2", userID: "test-user", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewKnowledgeHandler(newMockKnowledgeService(), logger.Default())
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("X-User-ID", tt.userID)
+			w := httptest.NewRecorder()
+
+			switch tt.method {
+			case http.MethodPost:
+				handler.AddDocument(w, req)
+			case http.MethodDelete:
+				handler.DeleteDocument(w, req)
+			default:
+				handler.ListDocuments(w, req)
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("期望状态码 %d, 得到 %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestKnowledgeHandler_Search(t *testing.T) {
+	service := newMockKnowledgeService()
+	handler := NewKnowledgeHandler(service, logger.Default())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/knowledge-bases/kb-1/search", strings.NewReader(`{"query":"如何部署","topK":3}`))
+	req.Header.Set("X-User-ID", "test-user")
+	w := httptest.NewRecorder()
+	handler.SearchKnowledgeBase(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 200, 得到 %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data []model.Citation `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if len(resp.Data) != 1 || resp.Data[0].KnowledgeBaseID != "kb-1" || service.lastTopK != 3 {
+		t.Errorf("检索结果不正确: %+v, topK=%d", resp.Data, service.lastTopK)
+	}
+
+	t.Run("无权访问", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/knowledge-bases/kb-1/search", strings.NewReader(`{"query":"a"}`))
+		req.Header.Set("X-User-ID", "other-user")
+		w := httptest.NewRecorder()
+		handler.SearchKnowledgeBase(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("期望状态码 403, 得到 %d", w.Code)
+		}
+	})
+}
@@ -3,6 +3,7 @@ package handler
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 
@@ -12,6 +13,8 @@ import (
 	"genkit-ai-service/pkg/errors"
 	"genkit-ai-service/pkg/response"
 	"genkit-ai-service/pkg/validator"
+
+	"github.com/google/uuid"
 )
 
 // MessageHandler 消息处理器
@@ -32,10 +35,14 @@ func NewMessageHandler(messageService session.MessageService, log logger.Logger)
 
 // SendMessage 发送消息
 // @Summary 发送消息
-// @Description 在指定会话中发送消息并获取AI回复
+// @Description 在指定会话中发送消息并获取AI回复。
+// @Description 请求体 stream 为 true 或 Accept 为 text/event-stream 时以 SSE 流式返回：
+// @Description start 事件携带 AI 回复消息ID（可用于中止生成），message 事件携带文本片段，done 事件携带保存后的消息，error 事件携带错误信息
+// @Description 请求体可通过 messageId 指定 AI 回复消息ID，非流式请求可据此在生成过程中中止
 // @Tags messages
 // @Accept json
 // @Produce json
+// @Produce text/event-stream
 // @Param id path string true "会话ID"
 // @Param request body model.SendMessageRequest true "发送消息请求"
 // @Success 200 {object} model.ResponseData[session.MessageResponse] "成功发送消息"
@@ -85,6 +92,7 @@ func (h *MessageHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		"sessionId": sessionID,
 		"userId":    userID,
 		"message":   req.Message,
+		"stream":    req.Stream,
 	})
 
 	// 6. 调用服务层发送消息
@@ -93,6 +101,14 @@ func (h *MessageHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		Message:   req.Message,
 		UserID:    userID,
 		Options:   req.Options,
+		Parts:     req.Parts,
+		MessageID: aiMessageID(req.MessageID),
+	}
+
+	// 流式请求以 SSE 返回
+	if wantsEventStream(r, req.Stream) {
+		h.sendMessageStream(w, r, serviceReq)
+		return
 	}
 
 	messageResp, err := h.messageService.SendMessage(ctx, serviceReq)
@@ -121,15 +137,86 @@ func (h *MessageHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 	h.writeSuccessResponse(w, messageResp)
 }
 
+// sendMessageStream 以 SSE 方式发送消息
+func (h *MessageHandler) sendMessageStream(w http.ResponseWriter, r *http.Request, req *session.SendMessageRequest) {
+	// 预先分配的 AI 回复消息ID在 start 事件中返回，客户端可据此在生成过程中中止
+	h.streamMessage(w, "发送消息", sseStart{MessageID: req.MessageID, SessionID: req.SessionID}, req.UserID,
+		func(onChunk func(content string) error) (*session.MessageResponse, error) {
+			return h.messageService.SendMessageStream(r.Context(), req, onChunk)
+		})
+}
+
+// aiMessageID 返回客户端指定的 AI 回复消息ID，未指定时预先分配
+// 生成开始前即确定消息ID，流式和非流式请求都可以在生成过程中按此ID中止
+func aiMessageID(messageID string) string {
+	if messageID != "" {
+		return messageID
+	}
+	return uuid.New().String()
+}
+
+// streamMessage 以 SSE 方式返回 AI 回复
+// start 为生成开始事件数据，generate 调用服务层并通过 onChunk 推送文本片段
+func (h *MessageHandler) streamMessage(
+	w http.ResponseWriter,
+	action string,
+	start sseStart,
+	userID string,
+	generate func(onChunk func(content string) error) (*session.MessageResponse, error),
+) {
+	// 生成开始前的准备（检索知识库、工具调用等）也可能超过写截止时间，因此立即清除；
+	// 响应头延迟到收到第一个片段时写入，以便在生成开始前失败时仍能返回普通错误响应
+	clearWriteDeadline(w)
+	var sse *sseWriter
+
+	messageResp, err := generate(func(content string) error {
+		if sse == nil {
+			sse = newSSEWriter(w)
+			if err := sse.WriteEvent(sseEventStart, start); err != nil {
+				return err
+			}
+		}
+		return sse.WriteChunk(content)
+	})
+	if err != nil {
+		h.logger.Error("流式"+action+"失败", logger.Fields{
+			"error":     err,
+			"sessionId": start.SessionID,
+			"messageId": start.MessageID,
+			"userId":    userID,
+		})
+		if sse == nil {
+			h.writeErrorResponse(w, toAppError(err))
+		} else {
+			_ = sse.WriteError(toAppError(err))
+		}
+		return
+	}
+
+	if sse == nil {
+		sse = newSSEWriter(w)
+	}
+	if err := sse.WriteEvent(sseEventDone, response.Success(messageResp)); err != nil {
+		h.logger.Warn("写入流式完成事件失败", logger.Fields{"error": err})
+	}
+
+	h.logger.Info("流式"+action+"成功", logger.Fields{
+		"sessionId": messageResp.SessionID,
+		"userId":    userID,
+		"messageId": messageResp.MessageID,
+	})
+}
+
 // GetMessages 获取消息历史
 // @Summary 获取消息历史
-// @Description 获取指定会话的消息历史，支持分页
+// @Description 获取指定会话的消息历史，支持分页和按消息状态过滤
 // @Tags messages
 // @Accept json
 // @Produce json
 // @Param id path string true "会话ID"
 // @Param pageNo query int true "页码" minimum(1) default(1)
 // @Param pageSize query int true "每页大小" minimum(1) maximum(100) default(50)
+// @Param status query string false "消息状态" Enums(pending, streaming, completed, failed, aborted)
 // @Success 200 {object} model.ResponsePaginationData[[]session.MessageDetailResponse] "成功返回消息历史"
 // @Failure 400 {object} model.ErrorResponse "请求参数错误"
 // @Failure 403 {object} model.ErrorResponse "无权访问"
@@ -185,6 +272,7 @@ func (h *MessageHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
 		UserID:    userID,
 		PageNo:    req.PageNo,
 		PageSize:  req.PageSize,
+		Status:    req.Status,
 	}
 
 	messageList, err := h.messageService.GetMessages(ctx, serviceReq)
@@ -277,12 +365,12 @@ func (h *MessageHandler) GetMessageByID(w http.ResponseWriter, r *http.Request)
 
 // AbortMessage 中止消息生成
 // @Summary 中止消息生成
-// @Description 中止指定消息的AI生成过程
+// @Description 中止指定消息的AI生成过程，已生成的部分内容会被保存并标记为已中止；返回是否实际取消了生成
 // @Tags messages
 // @Accept json
 // @Produce json
 // @Param id path string true "消息ID"
-// @Success 200 {object} model.ResponseData[any] "成功中止消息生成"
+// @Success 200 {object} model.ResponseData[model.AbortResponse] "成功中止消息生成"
 // @Failure 400 {object} model.ErrorResponse "请求参数错误"
 // @Failure 403 {object} model.ErrorResponse "无权访问"
 // @Failure 404 {object} model.ErrorResponse "消息不存在"
@@ -312,7 +400,7 @@ func (h *MessageHandler) AbortMessage(w http.ResponseWriter, r *http.Request) {
 	})
 
 	// 4. 调用服务层中止消息生成
-	err := h.messageService.AbortMessage(ctx, messageID, userID)
+	aborted, err := h.messageService.AbortMessage(ctx, messageID, userID)
 	if err != nil {
 		h.logger.Error("中止消息生成失败", logger.Fields{
 			"error":     err,
@@ -328,14 +416,361 @@ func (h *MessageHandler) AbortMessage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 5. 记录响应日志
-	h.logger.Info("中止消息生成成功", logger.Fields{
+	h.logger.Info("中止消息生成请求处理完成", logger.Fields{
 		"messageId": messageID,
 		"userId":    userID,
+		"aborted":   aborted,
 	})
 
 	// 6. 返回成功响应
-	emptyData := struct{}{}
-	h.writeSuccessResponse(w, &emptyData)
+	h.writeSuccessResponse(w, &model.AbortResponse{Aborted: aborted})
+}
+
+// AbortSession 中止会话正在进行的生成
+// @Summary 中止会话生成
+// @Description 中止指定会话正在进行的AI生成过程，已生成的部分内容会被保存并标记为已中止；返回是否实际取消了生成
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param id path string true "会话ID"
+// @Success 200 {object} model.ResponseData[model.AbortResponse] "成功中止会话生成"
+// @Failure 400 {object} model.ErrorResponse "请求参数错误"
+// @Failure 403 {object} model.ErrorResponse "无权访问"
+// @Failure 404 {object} model.ErrorResponse "会话不存在"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /chat/sessions/{id}/abort [post]
+func (h *MessageHandler) AbortSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 从URL路径中提取会话ID
+	sessionID := h.extractSessionID(strings.TrimSuffix(r.URL.Path, "/abort"))
+	if sessionID == "" {
+		h.logger.Warn("会话ID为空")
+		h.writeErrorResponse(w, errors.NewBadRequestError("会话ID不能为空"))
+		return
+	}
+
+	// 2. 从上下文获取用户ID
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		userID = "default-user-id" // 临时默认值
+	}
+
+	h.logger.Info("收到中止会话生成请求", logger.Fields{
+		"sessionId": sessionID,
+		"userId":    userID,
+	})
+
+	// 3. 调用服务层中止会话生成
+	aborted, err := h.messageService.AbortSession(ctx, sessionID, userID)
+	if err != nil {
+		h.logger.Error("中止会话生成失败", logger.Fields{
+			"error":     err,
+			"sessionId": sessionID,
+			"userId":    userID,
+		})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	h.logger.Info("中止会话生成请求处理完成", logger.Fields{
+		"sessionId": sessionID,
+		"userId":    userID,
+		"aborted":   aborted,
+	})
+
+	// 4. 返回成功响应
+	h.writeSuccessResponse(w, &model.AbortResponse{Aborted: aborted})
+}
+
+// RegenerateMessage 重新生成 AI 回复
+// @Summary 重新生成 AI 回复
+// @Description 基于同一条用户消息重新生成 AI 回复，原回复保留为并列的分支，新回复成为当前分支。
+// @Description 请求体 stream 为 true 或 Accept 为 text/event-stream 时以 SSE 流式返回，事件格式与发送消息接口相同
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Produce text/event-stream
+// @Param id path string true "AI 回复消息ID"
+// @Param request body model.RegenerateMessageRequest false "重新生成请求"
+// @Success 200 {object} model.ResponseData[session.MessageResponse] "成功重新生成回复"
+// @Failure 400 {object} model.ErrorResponse "请求参数错误"
+// @Failure 403 {object} model.ErrorResponse "无权访问"
+// @Failure 404 {object} model.ErrorResponse "消息不存在"
+// @Failure 422 {object} model.ErrorResponse "参数验证失败"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /chat/messages/{id}/regenerate [post]
+func (h *MessageHandler) RegenerateMessage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 从URL路径中提取消息ID
+	messageID := h.extractMessageIDFromAction(r.URL.Path, "/regenerate")
+	if messageID == "" {
+		h.logger.Warn("消息ID为空")
+		h.writeErrorResponse(w, errors.NewBadRequestError("消息ID不能为空"))
+		return
+	}
+
+	// 2. 解析请求参数（请求体可为空）
+	var req model.RegenerateMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		h.logger.Error("解析重新生成请求参数失败", logger.Fields{"error": err})
+		h.writeErrorResponse(w, errors.NewBadRequestError("无效的请求参数"))
+		return
+	}
+
+	// 3. 验证请求参数
+	if validationErrors := h.validator.ValidateStruct(&req); validationErrors != nil {
+		h.logger.Warn("重新生成请求参数验证失败", logger.Fields{"errors": validationErrors})
+		h.writeValidationErrorResponse(w, validationErrors)
+		return
+	}
+
+	// 4. 从上下文获取用户ID
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		userID = "default-user-id" // 临时默认值
+	}
+
+	h.logger.Info("收到重新生成消息请求", logger.Fields{
+		"messageId": messageID,
+		"userId":    userID,
+		"stream":    req.Stream,
+	})
+
+	// 5. 调用服务层重新生成
+	serviceReq := &session.RegenerateMessageRequest{
+		MessageID:   messageID,
+		UserID:      userID,
+		Options:     req.Options,
+		AIMessageID: aiMessageID(req.MessageID),
+	}
+
+	// 流式请求以 SSE 返回
+	if wantsEventStream(r, req.Stream) {
+		h.streamMessage(w, "重新生成消息", sseStart{MessageID: serviceReq.AIMessageID}, userID,
+			func(onChunk func(content string) error) (*session.MessageResponse, error) {
+				return h.messageService.RegenerateMessage(ctx, serviceReq, onChunk)
+			})
+		return
+	}
+
+	messageResp, err := h.messageService.RegenerateMessage(ctx, serviceReq, nil)
+	if err != nil {
+		h.logger.Error("重新生成消息失败", logger.Fields{
+			"error":     err,
+			"messageId": messageID,
+			"userId":    userID,
+		})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	h.logger.Info("重新生成消息成功", logger.Fields{
+		"messageId":    messageID,
+		"userId":       userID,
+		"newMessageId": messageResp.MessageID,
+	})
+
+	// 6. 返回成功响应
+	h.writeSuccessResponse(w, messageResp)
+}
+
+// EditMessage 编辑用户消息并重新发送
+// @Summary 编辑并重新发送消息
+// @Description 以修改后的内容重新发送用户消息，在原消息的父消息下创建新的对话分支，原消息及其后续对话保留。
+// @Description 请求体 stream 为 true 或 Accept 为 text/event-stream 时以 SSE 流式返回，事件格式与发送消息接口相同
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Produce text/event-stream
+// @Param id path string true "用户消息ID"
+// @Param request body model.EditMessageRequest true "编辑消息请求"
+// @Success 200 {object} model.ResponseData[session.MessageResponse] "成功重新发送消息"
+// @Failure 400 {object} model.ErrorResponse "请求参数错误"
+// @Failure 403 {object} model.ErrorResponse "无权访问"
+// @Failure 404 {object} model.ErrorResponse "消息不存在"
+// @Failure 422 {object} model.ErrorResponse "参数验证失败"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /chat/messages/{id}/edit [post]
+func (h *MessageHandler) EditMessage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 从URL路径中提取消息ID
+	messageID := h.extractMessageIDFromAction(r.URL.Path, "/edit")
+	if messageID == "" {
+		h.logger.Warn("消息ID为空")
+		h.writeErrorResponse(w, errors.NewBadRequestError("消息ID不能为空"))
+		return
+	}
+
+	// 2. 解析请求参数
+	var req model.EditMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("解析编辑消息请求参数失败", logger.Fields{"error": err})
+		h.writeErrorResponse(w, errors.NewBadRequestError("无效的请求参数"))
+		return
+	}
+
+	// 3. 验证请求参数
+	if validationErrors := h.validator.ValidateStruct(&req); validationErrors != nil {
+		h.logger.Warn("编辑消息请求参数验证失败", logger.Fields{"errors": validationErrors})
+		h.writeValidationErrorResponse(w, validationErrors)
+		return
+	}
+
+	// 4. 从上下文获取用户ID
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		userID = "default-user-id" // 临时默认值
+	}
+
+	h.logger.Info("收到编辑消息请求", logger.Fields{
+		"messageId": messageID,
+		"userId":    userID,
+		"stream":    req.Stream,
+	})
+
+	// 5. 调用服务层编辑并重新发送
+	serviceReq := &session.EditMessageRequest{
+		MessageID:   messageID,
+		Message:     req.Message,
+		UserID:      userID,
+		Options:     req.Options,
+		AIMessageID: aiMessageID(req.MessageID),
+	}
+
+	// 流式请求以 SSE 返回
+	if wantsEventStream(r, req.Stream) {
+		h.streamMessage(w, "编辑消息", sseStart{MessageID: serviceReq.AIMessageID}, userID,
+			func(onChunk func(content string) error) (*session.MessageResponse, error) {
+				return h.messageService.EditMessage(ctx, serviceReq, onChunk)
+			})
+		return
+	}
+
+	messageResp, err := h.messageService.EditMessage(ctx, serviceReq, nil)
+	if err != nil {
+		h.logger.Error("编辑消息失败", logger.Fields{
+			"error":     err,
+			"messageId": messageID,
+			"userId":    userID,
+		})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	h.logger.Info("编辑消息成功", logger.Fields{
+		"messageId":    messageID,
+		"userId":       userID,
+		"newMessageId": messageResp.MessageID,
+	})
+
+	// 6. 返回成功响应
+	h.writeSuccessResponse(w, messageResp)
+}
+
+// GetSiblings 获取消息的并列分支
+// @Summary 获取消息分支
+// @Description 获取与指定消息同属一个父消息的所有消息（按创建顺序），以及其中位于当前分支上的消息ID
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param id path string true "消息ID"
+// @Success 200 {object} model.ResponseData[session.MessageSiblingsResponse] "成功返回消息分支"
+// @Failure 400 {object} model.ErrorResponse "请求参数错误"
+// @Failure 403 {object} model.ErrorResponse "无权访问"
+// @Failure 404 {object} model.ErrorResponse "消息不存在"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /chat/messages/{id}/siblings [get]
+func (h *MessageHandler) GetSiblings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 从URL路径中提取消息ID
+	messageID := h.extractMessageIDFromAction(r.URL.Path, "/siblings")
+	if messageID == "" {
+		h.logger.Warn("消息ID为空")
+		h.writeErrorResponse(w, errors.NewBadRequestError("消息ID不能为空"))
+		return
+	}
+
+	// 2. 从上下文获取用户ID
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		userID = "default-user-id" // 临时默认值
+	}
+
+	// 3. 调用服务层获取分支
+	siblings, err := h.messageService.GetSiblings(ctx, messageID, userID)
+	if err != nil {
+		h.logger.Error("获取消息分支失败", logger.Fields{
+			"error":     err,
+			"messageId": messageID,
+			"userId":    userID,
+		})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 4. 返回成功响应
+	h.writeSuccessResponse(w, siblings)
+}
+
+// SelectBranch 切换当前分支
+// @Summary 切换对话分支
+// @Description 将会话的当前分支切换到指定消息所在的分支（沿最新的子消息延伸到末端），之后获取消息历史和继续对话都基于该分支；返回切换后的分支末端消息
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param id path string true "消息ID"
+// @Success 200 {object} model.ResponseData[session.MessageDetailResponse] "成功切换分支"
+// @Failure 400 {object} model.ErrorResponse "请求参数错误"
+// @Failure 403 {object} model.ErrorResponse "无权访问"
+// @Failure 404 {object} model.ErrorResponse "消息不存在"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /chat/messages/{id}/select [post]
+func (h *MessageHandler) SelectBranch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 从URL路径中提取消息ID
+	messageID := h.extractMessageIDFromAction(r.URL.Path, "/select")
+	if messageID == "" {
+		h.logger.Warn("消息ID为空")
+		h.writeErrorResponse(w, errors.NewBadRequestError("消息ID不能为空"))
+		return
+	}
+
+	// 2. 从上下文获取用户ID
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		userID = "default-user-id" // 临时默认值
+	}
+
+	h.logger.Info("收到切换分支请求", logger.Fields{
+		"messageId": messageID,
+		"userId":    userID,
+	})
+
+	// 3. 调用服务层切换分支
+	leaf, err := h.messageService.SelectBranch(ctx, messageID, userID)
+	if err != nil {
+		h.logger.Error("切换分支失败", logger.Fields{
+			"error":     err,
+			"messageId": messageID,
+			"userId":    userID,
+		})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	h.logger.Info("切换分支成功", logger.Fields{
+		"messageId": messageID,
+		"userId":    userID,
+		"leafId":    leaf.ID,
+	})
+
+	// 4. 返回成功响应
+	h.writeSuccessResponse(w, leaf)
 }
 
 // extractSessionID 从URL路径中提取会话ID
@@ -378,7 +813,7 @@ func (h *MessageHandler) extractMessageID(path string) string {
 }
 
 // extractMessageIDFromAction 从带操作的URL路径中提取消息ID
-// 路径格式: /api/v1/chat/messages/{id}/abort
+// 路径格式: /api/v1/chat/messages/{id}/{action}，如 /abort、/regenerate
 func (h *MessageHandler) extractMessageIDFromAction(path, action string) string {
 	// 移除操作部分
 	path = strings.TrimSuffix(path, action)
@@ -401,6 +836,7 @@ func (h *MessageHandler) parseQueryParams(r *http.Request, target interface{}) e
 		if err := h.parseIntParam(query.Get("pageSize"), &v.PageSize, 50); err != nil {
 			return err
 		}
+		v.Status = query.Get("status")
 	}
 
 	return nil
@@ -451,6 +887,12 @@ func (h *MessageHandler) writeMessagePaginationResponse(w http.ResponseWriter, m
 
 // writeErrorResponse 写入错误响应
 func (h *MessageHandler) writeErrorResponse(w http.ResponseWriter, appErr *errors.AppError) {
+	// 服务层返回的字段级验证错误（如模型参数规则）
+	if fieldErrors, ok := appErr.Err.(validator.FieldErrors); ok {
+		h.writeValidationErrorResponse(w, fieldErrors)
+		return
+	}
+
 	resp := response.Error[any](appErr.Code, appErr.Message)
 
 	// 根据错误码确定 HTTP 状态码
@@ -460,7 +902,7 @@ func (h *MessageHandler) writeErrorResponse(w http.ResponseWriter, appErr *error
 		statusCode = http.StatusBadRequest
 	case errors.CodeValidationError:
 		statusCode = http.StatusUnprocessableEntity
-	case errors.CodeNotFound, errors.CodeSessionNotFound, errors.CodeMessageNotFound:
+	case errors.CodeNotFound, errors.CodeSessionNotFound, errors.CodeMessageNotFound, errors.CodeModelNotFound, errors.CodeProviderNotFound:
 		statusCode = http.StatusNotFound
 	case errors.CodeUnauthorized:
 		statusCode = http.StatusUnauthorized
@@ -468,7 +910,7 @@ func (h *MessageHandler) writeErrorResponse(w http.ResponseWriter, appErr *error
 		statusCode = http.StatusForbidden
 	case errors.CodeServiceUnavailable:
 		statusCode = http.StatusServiceUnavailable
-	case errors.CodeMessageSendFailed:
+	case errors.CodeMessageSendFailed, errors.CodeStructuredOutput:
 		statusCode = http.StatusInternalServerError
 	}
 
@@ -5,8 +5,10 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -19,9 +21,15 @@ import (
 // mockMessageService 模拟消息服务
 type mockMessageService struct {
 	sendMessageFunc      func(ctx context.Context, req *session.SendMessageRequest) (*session.MessageResponse, error)
+	sendMessageStreamFunc func(ctx context.Context, req *session.SendMessageRequest, onChunk func(content string) error) (*session.MessageResponse, error)
 	getMessagesFunc      func(ctx context.Context, req *session.GetMessagesRequest) (*session.MessageListResponse, error)
 	getMessageByIDFunc   func(ctx context.Context, messageID, userID string) (*session.MessageDetailResponse, error)
-	abortMessageFunc     func(ctx context.Context, messageID, userID string) error
+	abortMessageFunc     func(ctx context.Context, messageID, userID string) (bool, error)
+	abortSessionFunc     func(ctx context.Context, sessionID, userID string) (bool, error)
+	regenerateFunc       func(ctx context.Context, req *session.RegenerateMessageRequest, onChunk func(string) error) (*session.MessageResponse, error)
+	editFunc             func(ctx context.Context, req *session.EditMessageRequest, onChunk func(string) error) (*session.MessageResponse, error)
+	getSiblingsFunc      func(ctx context.Context, messageID, userID string) (*session.MessageSiblingsResponse, error)
+	selectBranchFunc     func(ctx context.Context, messageID, userID string) (*session.MessageDetailResponse, error)
 }
 
 func (m *mockMessageService) SendMessage(ctx context.Context, req *session.SendMessageRequest) (*session.MessageResponse, error) {
@@ -31,6 +39,13 @@ func (m *mockMessageService) SendMessage(ctx context.Context, req *session.SendM
 	return nil, errors.New("未实现")
 }
 
+func (m *mockMessageService) SendMessageStream(ctx context.Context, req *session.SendMessageRequest, onChunk func(content string) error) (*session.MessageResponse, error) {
+	if m.sendMessageStreamFunc != nil {
+		return m.sendMessageStreamFunc(ctx, req, onChunk)
+	}
+	return nil, errors.New("未实现")
+}
+
 func (m *mockMessageService) GetMessages(ctx context.Context, req *session.GetMessagesRequest) (*session.MessageListResponse, error) {
 	if m.getMessagesFunc != nil {
 		return m.getMessagesFunc(ctx, req)
@@ -45,11 +60,50 @@ func (m *mockMessageService) GetMessageByID(ctx context.Context, messageID, user
 	return nil, errors.New("未实现")
 }
 
-func (m *mockMessageService) AbortMessage(ctx context.Context, messageID, userID string) error {
+func (m *mockMessageService) AbortMessage(ctx context.Context, messageID, userID string) (bool, error) {
 	if m.abortMessageFunc != nil {
 		return m.abortMessageFunc(ctx, messageID, userID)
 	}
-	return errors.New("未实现")
+	return false, errors.New("未实现")
+}
+
+func (m *mockMessageService) AbortSession(ctx context.Context, sessionID, userID string) (bool, error) {
+	if m.abortSessionFunc != nil {
+		return m.abortSessionFunc(ctx, sessionID, userID)
+	}
+	return false, errors.New("未实现")
+}
+
+func (m *mockMessageService) AppendUserMessage(ctx context.Context, sessionID, userID, content string) (*session.MessageDetailResponse, error) {
+	return nil, errors.New("未实现")
+}
+
+func (m *mockMessageService) RegenerateMessage(ctx context.Context, req *session.RegenerateMessageRequest, onChunk func(string) error) (*session.MessageResponse, error) {
+	if m.regenerateFunc != nil {
+		return m.regenerateFunc(ctx, req, onChunk)
+	}
+	return nil, errors.New("未实现")
+}
+
+func (m *mockMessageService) EditMessage(ctx context.Context, req *session.EditMessageRequest, onChunk func(string) error) (*session.MessageResponse, error) {
+	if m.editFunc != nil {
+		return m.editFunc(ctx, req, onChunk)
+	}
+	return nil, errors.New("未实现")
+}
+
+func (m *mockMessageService) GetSiblings(ctx context.Context, messageID, userID string) (*session.MessageSiblingsResponse, error) {
+	if m.getSiblingsFunc != nil {
+		return m.getSiblingsFunc(ctx, messageID, userID)
+	}
+	return nil, errors.New("未实现")
+}
+
+func (m *mockMessageService) SelectBranch(ctx context.Context, messageID, userID string) (*session.MessageDetailResponse, error) {
+	if m.selectBranchFunc != nil {
+		return m.selectBranchFunc(ctx, messageID, userID)
+	}
+	return nil, errors.New("未实现")
 }
 
 // TestSendMessage 测试发送消息
@@ -122,6 +176,52 @@ func TestSendMessage(t *testing.T) {
 			expectedStatus: http.StatusForbidden,
 			expectedCode:   pkgErrors.CodeSessionAccessDenied,
 		},
+		{
+			name:      "使用客户端指定的 AI 回复消息ID",
+			sessionID: "550e8400-e29b-41d4-a716-446655440000",
+			requestBody: model.SendMessageRequest{
+				Message:   "你好",
+				MessageID: "550e8400-e29b-41d4-a716-446655440005",
+			},
+			mockService: &mockMessageService{
+				sendMessageFunc: func(ctx context.Context, req *session.SendMessageRequest) (*session.MessageResponse, error) {
+					if req.MessageID != "550e8400-e29b-41d4-a716-446655440005" {
+						return nil, pkgErrors.NewBadRequestError("未使用指定的消息ID: " + req.MessageID)
+					}
+					return &session.MessageResponse{MessageID: req.MessageID}, nil
+				},
+			},
+			expectedStatus: http.StatusOK,
+			expectedCode:   pkgErrors.CodeSuccess,
+		},
+		{
+			name:      "未指定时预先分配 AI 回复消息ID",
+			sessionID: "550e8400-e29b-41d4-a716-446655440000",
+			requestBody: model.SendMessageRequest{
+				Message: "你好",
+			},
+			mockService: &mockMessageService{
+				sendMessageFunc: func(ctx context.Context, req *session.SendMessageRequest) (*session.MessageResponse, error) {
+					if req.MessageID == "" {
+						return nil, pkgErrors.NewBadRequestError("期望预先分配 AI 回复消息ID")
+					}
+					return &session.MessageResponse{MessageID: req.MessageID}, nil
+				},
+			},
+			expectedStatus: http.StatusOK,
+			expectedCode:   pkgErrors.CodeSuccess,
+		},
+		{
+			name:      "AI 回复消息ID格式错误",
+			sessionID: "550e8400-e29b-41d4-a716-446655440000",
+			requestBody: model.SendMessageRequest{
+				Message:   "你好",
+				MessageID: "not-a-uuid",
+			},
+			mockService:    &mockMessageService{},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   pkgErrors.CodeValidationError,
+		},
 		{
 			name:           "请求参数为空",
 			sessionID:      "550e8400-e29b-41d4-a716-446655440004",
@@ -169,6 +269,157 @@ func TestSendMessage(t *testing.T) {
 	}
 }
 
+// TestSendMessage_Stream 测试以 SSE 流式发送消息
+func TestSendMessage_Stream(t *testing.T) {
+	mockService := &mockMessageService{
+		sendMessageStreamFunc: func(ctx context.Context, req *session.SendMessageRequest, onChunk func(content string) error) (*session.MessageResponse, error) {
+			if req.MessageID == "" {
+				t.Error("期望流式请求预分配 AI 回复消息ID")
+			}
+			for _, chunk := range []string{"你", "好"} {
+				if err := onChunk(chunk); err != nil {
+					return nil, err
+				}
+			}
+			return &session.MessageResponse{
+				MessageID: "550e8400-e29b-41d4-a716-446655440001",
+				SessionID: req.SessionID,
+				AIMessage: &session.Message{
+					ID:      "550e8400-e29b-41d4-a716-446655440001",
+					Role:    "assistant",
+					Content: "你好",
+				},
+				Model: "gemini-2.5-flash",
+			}, nil
+		},
+	}
+
+	log := logger.New(logger.InfoLevel, logger.JSONFormat, &bytes.Buffer{})
+	handler := NewMessageHandler(mockService, log)
+
+	body, _ := json.Marshal(model.SendMessageRequest{Message: "你好", Stream: true})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chat/sessions/550e8400-e29b-41d4-a716-446655440000/messages", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	handler.SendMessage(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d", http.StatusOK, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("期望 Content-Type 为 text/event-stream, 得到 %s", ct)
+	}
+
+	bodyStr := w.Body.String()
+	if !strings.HasPrefix(bodyStr, "event: start\n") {
+		t.Errorf("期望首个事件为 start, 实际为 %s", bodyStr)
+	}
+	for _, want := range []string{
+		"\"sessionId\":\"550e8400-e29b-41d4-a716-446655440000\"",
+		"event: message\ndata: {\"content\":\"你\"}\n\n",
+		"event: message\ndata: {\"content\":\"好\"}\n\n",
+		"event: done\n",
+		"550e8400-e29b-41d4-a716-446655440001",
+	} {
+		if !strings.Contains(bodyStr, want) {
+			t.Errorf("期望响应包含 %q, 实际为 %s", want, bodyStr)
+		}
+	}
+}
+
+// TestSendMessage_StreamSlowStart 测试首个片段之前的准备超过服务器写超时时，流式响应仍能完整返回
+func TestSendMessage_StreamSlowStart(t *testing.T) {
+	mockService := &mockMessageService{
+		sendMessageStreamFunc: func(ctx context.Context, req *session.SendMessageRequest, onChunk func(content string) error) (*session.MessageResponse, error) {
+			time.Sleep(300 * time.Millisecond)
+			if err := onChunk("你好"); err != nil {
+				return nil, err
+			}
+			return &session.MessageResponse{MessageID: req.MessageID, SessionID: req.SessionID}, nil
+		},
+	}
+
+	log := logger.New(logger.InfoLevel, logger.JSONFormat, &bytes.Buffer{})
+	handler := NewMessageHandler(mockService, log)
+
+	// HTTP/2 在写超时到期时重置流，即使之后清除写截止时间也无法恢复
+	server := httptest.NewUnstartedServer(http.HandlerFunc(handler.SendMessage))
+	server.EnableHTTP2 = true
+	server.Config.WriteTimeout = 100 * time.Millisecond
+	server.StartTLS()
+	defer server.Close()
+
+	body, _ := json.Marshal(model.SendMessageRequest{Message: "你好", Stream: true})
+	resp, err := server.Client().Post(server.URL+"/api/v1/chat/sessions/550e8400-e29b-41d4-a716-446655440000/messages", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("读取流式响应失败: %v", err)
+	}
+	if !strings.Contains(string(data), "event: done\n") {
+		t.Errorf("期望收到 done 事件, 实际为 %s", data)
+	}
+}
+
+// TestSendMessage_StreamErrorBeforeFirstChunk 测试流式发送在首个片段前失败时返回普通错误响应
+func TestSendMessage_StreamErrorBeforeFirstChunk(t *testing.T) {
+	mockService := &mockMessageService{
+		sendMessageStreamFunc: func(ctx context.Context, req *session.SendMessageRequest, onChunk func(content string) error) (*session.MessageResponse, error) {
+			return nil, pkgErrors.NewSessionNotFoundError(req.SessionID)
+		},
+	}
+
+	log := logger.New(logger.InfoLevel, logger.JSONFormat, &bytes.Buffer{})
+	handler := NewMessageHandler(mockService, log)
+
+	body, _ := json.Marshal(model.SendMessageRequest{Message: "你好"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chat/sessions/550e8400-e29b-41d4-a716-446655440000/messages", bytes.NewReader(body))
+	req.Header.Set("Accept", "text/event-stream")
+	w := httptest.NewRecorder()
+
+	handler.SendMessage(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusNotFound, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("期望 Content-Type 为 application/json, 得到 %s", ct)
+	}
+}
+
+// TestSendMessage_StreamErrorAfterChunks 测试流式发送中途失败时输出 error 事件
+func TestSendMessage_StreamErrorAfterChunks(t *testing.T) {
+	mockService := &mockMessageService{
+		sendMessageStreamFunc: func(ctx context.Context, req *session.SendMessageRequest, onChunk func(content string) error) (*session.MessageResponse, error) {
+			if err := onChunk("部分"); err != nil {
+				return nil, err
+			}
+			return nil, pkgErrors.NewMessageSendFailedError(errors.New("AI 服务错误"))
+		},
+	}
+
+	log := logger.New(logger.InfoLevel, logger.JSONFormat, &bytes.Buffer{})
+	handler := NewMessageHandler(mockService, log)
+
+	body, _ := json.Marshal(model.SendMessageRequest{Message: "你好", Stream: true})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chat/sessions/550e8400-e29b-41d4-a716-446655440000/messages", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.SendMessage(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusOK, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "event: error\n") {
+		t.Errorf("期望响应包含 error 事件, 实际为 %s", w.Body.String())
+	}
+}
+
 // TestGetMessages 测试获取消息历史
 func TestGetMessages(t *testing.T) {
 	tests := []struct {
@@ -238,6 +489,29 @@ func TestGetMessages(t *testing.T) {
 			expectedStatus: http.StatusForbidden,
 			expectedCode:   pkgErrors.CodeSessionAccessDenied,
 		},
+		{
+			name:        "按状态过滤消息",
+			sessionID:   "550e8400-e29b-41d4-a716-446655440000",
+			queryParams: "?pageNo=1&pageSize=50&status=failed",
+			mockService: &mockMessageService{
+				getMessagesFunc: func(ctx context.Context, req *session.GetMessagesRequest) (*session.MessageListResponse, error) {
+					if req.Status != "failed" {
+						return nil, pkgErrors.NewBadRequestError("未传递状态过滤条件")
+					}
+					return &session.MessageListResponse{PageNo: 1, PageSize: 50}, nil
+				},
+			},
+			expectedStatus: http.StatusOK,
+			expectedCode:   pkgErrors.CodeSuccess,
+		},
+		{
+			name:           "无效的消息状态",
+			sessionID:      "550e8400-e29b-41d4-a716-446655440000",
+			queryParams:    "?pageNo=1&pageSize=50&status=unknown",
+			mockService:    &mockMessageService{},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   pkgErrors.CodeValidationError,
+		},
 	}
 
 	for _, tt := range tests {
@@ -374,8 +648,8 @@ func TestAbortMessage(t *testing.T) {
 			name:      "成功中止消息生成",
 			messageID: "550e8400-e29b-41d4-a716-446655440030",
 			mockService: &mockMessageService{
-				abortMessageFunc: func(ctx context.Context, messageID, userID string) error {
-					return nil
+				abortMessageFunc: func(ctx context.Context, messageID, userID string) (bool, error) {
+					return true, nil
 				},
 			},
 			expectedStatus: http.StatusOK,
@@ -385,8 +659,8 @@ func TestAbortMessage(t *testing.T) {
 			name:      "消息不存在（幂等操作）",
 			messageID: "550e8400-e29b-41d4-a716-446655440031",
 			mockService: &mockMessageService{
-				abortMessageFunc: func(ctx context.Context, messageID, userID string) error {
-					return nil // 幂等操作，返回成功
+				abortMessageFunc: func(ctx context.Context, messageID, userID string) (bool, error) {
+					return false, nil // 幂等操作，返回成功
 				},
 			},
 			expectedStatus: http.StatusOK,
@@ -396,8 +670,8 @@ func TestAbortMessage(t *testing.T) {
 			name:      "无权访问消息",
 			messageID: "550e8400-e29b-41d4-a716-446655440032",
 			mockService: &mockMessageService{
-				abortMessageFunc: func(ctx context.Context, messageID, userID string) error {
-					return pkgErrors.NewMessageAccessDeniedError()
+				abortMessageFunc: func(ctx context.Context, messageID, userID string) (bool, error) {
+					return false, pkgErrors.NewMessageAccessDeniedError()
 				},
 			},
 			expectedStatus: http.StatusForbidden,
@@ -439,3 +713,210 @@ func TestAbortMessage(t *testing.T) {
 		})
 	}
 }
+
+// TestAbortSession 测试按会话中止生成
+func TestAbortSession(t *testing.T) {
+	mockService := &mockMessageService{
+		abortSessionFunc: func(ctx context.Context, sessionID, userID string) (bool, error) {
+			if sessionID != "550e8400-e29b-41d4-a716-446655440000" {
+				t.Errorf("期望会话ID为 550e8400-e29b-41d4-a716-446655440000, 得到 %s", sessionID)
+			}
+			return true, nil
+		},
+	}
+	handler := NewMessageHandler(mockService, logger.NewTestLogger())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chat/sessions/550e8400-e29b-41d4-a716-446655440000/abort", nil)
+	req.Header.Set("X-User-ID", "test-user-id")
+	w := httptest.NewRecorder()
+
+	handler.AbortSession(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 实际 %d", http.StatusOK, w.Code)
+	}
+
+	var resp model.ResponseData[model.AbortResponse]
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if resp.Data == nil || !resp.Data.Aborted {
+		t.Errorf("期望 aborted 为 true, 实际 %+v", resp.Data)
+	}
+}
+
+// TestRegenerateMessage 测试重新生成 AI 回复
+func TestRegenerateMessage(t *testing.T) {
+	const messageID = "550e8400-e29b-41d4-a716-446655440001"
+
+	newResponse := func(req *session.RegenerateMessageRequest) *session.MessageResponse {
+		return &session.MessageResponse{
+			MessageID: "550e8400-e29b-41d4-a716-446655440002",
+			SessionID: "550e8400-e29b-41d4-a716-446655440000",
+			AIMessage: &session.Message{ID: "550e8400-e29b-41d4-a716-446655440002", Role: "assistant", Content: "新的回复"},
+		}
+	}
+
+	t.Run("请求体为空时同步返回", func(t *testing.T) {
+		mockService := &mockMessageService{
+			regenerateFunc: func(ctx context.Context, req *session.RegenerateMessageRequest, onChunk func(string) error) (*session.MessageResponse, error) {
+				if req.MessageID != messageID || onChunk != nil {
+					t.Errorf("重新生成请求不正确: %+v", req)
+				}
+				return newResponse(req), nil
+			},
+		}
+		handler := NewMessageHandler(mockService, logger.NewTestLogger())
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/chat/messages/"+messageID+"/regenerate", nil)
+		w := httptest.NewRecorder()
+		handler.RegenerateMessage(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("期望状态码 %d, 得到 %d", http.StatusOK, w.Code)
+		}
+		var resp model.ResponseData[session.MessageResponse]
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("解析响应失败: %v", err)
+		}
+		if resp.Data == nil || resp.Data.AIMessage.Content != "新的回复" {
+			t.Errorf("响应数据不正确: %+v", resp.Data)
+		}
+	})
+
+	t.Run("流式返回", func(t *testing.T) {
+		mockService := &mockMessageService{
+			regenerateFunc: func(ctx context.Context, req *session.RegenerateMessageRequest, onChunk func(string) error) (*session.MessageResponse, error) {
+				if req.AIMessageID == "" {
+					t.Error("期望流式请求预分配 AI 回复消息ID")
+				}
+				if err := onChunk("新的回复"); err != nil {
+					return nil, err
+				}
+				return newResponse(req), nil
+			},
+		}
+		handler := NewMessageHandler(mockService, logger.NewTestLogger())
+
+		body, _ := json.Marshal(model.RegenerateMessageRequest{Stream: true})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/chat/messages/"+messageID+"/regenerate", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.RegenerateMessage(w, req)
+
+		bodyStr := w.Body.String()
+		if !strings.HasPrefix(bodyStr, "event: start\n") || strings.Contains(bodyStr, "\"sessionId\":\"\"") {
+			t.Errorf("start 事件不正确: %s", bodyStr)
+		}
+		for _, want := range []string{"event: message\ndata: {\"content\":\"新的回复\"}\n\n", "event: done\n"} {
+			if !strings.Contains(bodyStr, want) {
+				t.Errorf("期望响应包含 %q, 实际为 %s", want, bodyStr)
+			}
+		}
+	})
+
+	t.Run("不是 AI 回复", func(t *testing.T) {
+		mockService := &mockMessageService{
+			regenerateFunc: func(ctx context.Context, req *session.RegenerateMessageRequest, onChunk func(string) error) (*session.MessageResponse, error) {
+				return nil, pkgErrors.NewBadRequestError("只能重新生成 AI 回复")
+			},
+		}
+		handler := NewMessageHandler(mockService, logger.NewTestLogger())
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/chat/messages/"+messageID+"/regenerate", nil)
+		w := httptest.NewRecorder()
+		handler.RegenerateMessage(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("期望状态码 %d, 得到 %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
+
+// TestEditMessage 测试编辑用户消息并重新发送
+func TestEditMessage(t *testing.T) {
+	const messageID = "550e8400-e29b-41d4-a716-446655440001"
+
+	mockService := &mockMessageService{
+		editFunc: func(ctx context.Context, req *session.EditMessageRequest, onChunk func(string) error) (*session.MessageResponse, error) {
+			if req.MessageID != messageID || req.Message != "修改后的问题" {
+				t.Errorf("编辑请求不正确: %+v", req)
+			}
+			return &session.MessageResponse{
+				MessageID:   "550e8400-e29b-41d4-a716-446655440003",
+				UserMessage: &session.Message{ID: "550e8400-e29b-41d4-a716-446655440002", Role: "user", Content: req.Message},
+			}, nil
+		},
+	}
+	handler := NewMessageHandler(mockService, logger.NewTestLogger())
+
+	tests := []struct {
+		name           string
+		body           interface{}
+		expectedStatus int
+	}{
+		{name: "成功编辑", body: model.EditMessageRequest{Message: "修改后的问题"}, expectedStatus: http.StatusOK},
+		{name: "消息内容为空", body: model.EditMessageRequest{}, expectedStatus: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.body)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/chat/messages/"+messageID+"/edit", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			handler.EditMessage(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("期望状态码 %d, 得到 %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+// TestGetSiblingsAndSelectBranch 测试获取消息分支和切换分支
+func TestGetSiblingsAndSelectBranch(t *testing.T) {
+	const messageID = "550e8400-e29b-41d4-a716-446655440001"
+
+	mockService := &mockMessageService{
+		getSiblingsFunc: func(ctx context.Context, id, userID string) (*session.MessageSiblingsResponse, error) {
+			if id != messageID {
+				t.Errorf("期望消息ID为 %s, 得到 %s", messageID, id)
+			}
+			return &session.MessageSiblingsResponse{
+				Siblings: []*session.MessageDetailResponse{{ID: messageID}, {ID: "550e8400-e29b-41d4-a716-446655440002"}},
+				ActiveID: "550e8400-e29b-41d4-a716-446655440002",
+			}, nil
+		},
+		selectBranchFunc: func(ctx context.Context, id, userID string) (*session.MessageDetailResponse, error) {
+			if id != messageID {
+				t.Errorf("期望消息ID为 %s, 得到 %s", messageID, id)
+			}
+			return &session.MessageDetailResponse{ID: "550e8400-e29b-41d4-a716-446655440009"}, nil
+		},
+	}
+	handler := NewMessageHandler(mockService, logger.NewTestLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/chat/messages/"+messageID+"/siblings", nil)
+	w := httptest.NewRecorder()
+	handler.GetSiblings(w, req)
+
+	var siblings model.ResponseData[session.MessageSiblingsResponse]
+	if err := json.NewDecoder(w.Body).Decode(&siblings); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if siblings.Data == nil || len(siblings.Data.Siblings) != 2 || siblings.Data.ActiveID != "550e8400-e29b-41d4-a716-446655440002" {
+		t.Errorf("分支响应不正确: %+v", siblings.Data)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/chat/messages/"+messageID+"/select", nil)
+	w = httptest.NewRecorder()
+	handler.SelectBranch(w, req)
+
+	var leaf model.ResponseData[session.MessageDetailResponse]
+	if err := json.NewDecoder(w.Body).Decode(&leaf); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if leaf.Data == nil || leaf.Data.ID != "550e8400-e29b-41d4-a716-446655440009" {
+		t.Errorf("切换分支响应不正确: %+v", leaf.Data)
+	}
+}
@@ -2,7 +2,10 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"genkit-ai-service/internal/logger"
 	"genkit-ai-service/internal/model"
@@ -178,6 +181,143 @@ func (h *ProviderHandler) GetProviderModels(w http.ResponseWriter, r *http.Reque
 	})
 }
 
+// SearchModels 处理 GET /models 请求
+// @Summary 搜索模型
+// @Description 跨所有提供商搜索模型，支持过滤、排序和分页。价格按每百万 token 计算，使用模型自身的货币
+// @Tags providers
+// @Accept json
+// @Produce json
+// @Param keyword query string false "关键词，匹配模型ID和标签" example(qwen)
+// @Param model_type query string false "模型类型" example(llm)
+// @Param feature query []string false "特性，可重复或以逗号分隔，模型必须具有全部特性" collectionFormat(multi) example(vision)
+// @Param mode query string false "模式（chat、completion）" example(chat)
+// @Param min_context_size query int false "最小上下文大小" minimum(0) example(32000)
+// @Param deprecated query bool false "是否已弃用"
+// @Param currency query string false "货币（如 USD、RMB）" example(USD)
+// @Param max_input_price query number false "每百万 token 的最高输入价格" minimum(0)
+// @Param max_output_price query number false "每百万 token 的最高输出价格" minimum(0)
+// @Param sort_by query string false "排序字段" Enums(provider, model, context_size, input_price, output_price) default(provider)
+// @Param order query string false "排序方向" Enums(asc, desc) default(asc)
+// @Param pageNo query int false "页码" minimum(1) default(1)
+// @Param pageSize query int false "每页大小" minimum(1) maximum(100) default(20)
+// @Success 200 {object} model.ResponsePaginationData[[]model.ModelSearchItem] "成功返回搜索结果"
+// @Failure 400 {object} model.ErrorResponse "请求参数错误"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /models [get]
+func (h *ProviderHandler) SearchModels(w http.ResponseWriter, r *http.Request) {
+	// 解析查询参数
+	req, err := parseSearchModelsRequest(r)
+	if err != nil {
+		h.handleValidationError(w, err, "模型搜索参数验证失败")
+		return
+	}
+
+	// 记录请求日志
+	h.logger.Info("收到搜索模型请求", map[string]interface{}{
+		"method":    r.Method,
+		"path":      r.URL.Path,
+		"keyword":   req.Keyword,
+		"modelType": req.ModelType,
+		"pageNo":    req.PageNo,
+		"pageSize":  req.PageSize,
+	})
+
+	// 调用服务层搜索模型
+	models, total, err := h.providerService.SearchModels(req)
+	if err != nil {
+		// 处理错误
+		h.handleError(w, err, "搜索模型失败", nil)
+		return
+	}
+
+	// 构建分页响应
+	resp := response.Pagination(models, req.PageNo, req.PageSize, total)
+
+	// 返回JSON响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error("编码响应失败", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	// 记录响应日志
+	h.logger.Info("搜索模型成功", map[string]interface{}{
+		"count": len(models),
+		"total": total,
+	})
+}
+
+// parseSearchModelsRequest 解析模型搜索的查询参数，未指定分页参数时使用第1页、每页20条
+func parseSearchModelsRequest(r *http.Request) (*model.SearchModelsRequest, error) {
+	query := r.URL.Query()
+	req := &model.SearchModelsRequest{
+		Keyword:   strings.TrimSpace(query.Get("keyword")),
+		ModelType: query.Get("model_type"),
+		Mode:      query.Get("mode"),
+		Currency:  query.Get("currency"),
+		SortBy:    query.Get("sort_by"),
+		Order:     query.Get("order"),
+		PageNo:    1,
+		PageSize:  20,
+	}
+
+	// 特性可以重复指定或以逗号分隔
+	for _, value := range query["feature"] {
+		for _, feature := range strings.Split(value, ",") {
+			if feature = strings.TrimSpace(feature); feature != "" {
+				req.Features = append(req.Features, feature)
+			}
+		}
+	}
+
+	ints := []struct {
+		name   string
+		target *int
+	}{
+		{name: "min_context_size", target: &req.MinContextSize},
+		{name: "pageNo", target: &req.PageNo},
+		{name: "pageSize", target: &req.PageSize},
+	}
+	for _, param := range ints {
+		if value := query.Get(param.name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s 必须是整数", param.name)
+			}
+			*param.target = parsed
+		}
+	}
+
+	prices := []struct {
+		name   string
+		target **float64
+	}{
+		{name: "max_input_price", target: &req.MaxInputPrice},
+		{name: "max_output_price", target: &req.MaxOutputPrice},
+	}
+	for _, param := range prices {
+		if value := query.Get(param.name); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s 必须是数字", param.name)
+			}
+			*param.target = &parsed
+		}
+	}
+
+	if value := query.Get("deprecated"); value != "" {
+		deprecated, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("deprecated 必须是 true 或 false")
+		}
+		req.Deprecated = &deprecated
+	}
+
+	return req, nil
+}
+
 // GetProviderModel 处理 GET /providers/{providerId}/models/{modelId} 请求
 // @Summary 获取模型详情
 // @Description 获取指定提供商的指定模型的详细信息
@@ -315,6 +455,78 @@ func (h *ProviderHandler) GetModelParameterRules(w http.ResponseWriter, r *http.
 	})
 }
 
+// GetModelVoices 处理 GET /providers/{providerId}/models/{modelId}/voices 请求
+// @Summary 获取模型音色列表
+// @Description 获取语音合成（tts）模型的可用音色，可按语言过滤
+// @Tags providers
+// @Accept json
+// @Produce json
+// @Param providerId path string true "提供商ID" example(tongyi)
+// @Param modelId path string true "模型ID" example(tts-1)
+// @Param language query string false "语言（如 zh-Hans、en-US）" example(zh-Hans)
+// @Success 200 {object} model.ResponseData[[]model.Voice] "成功返回音色列表"
+// @Failure 400 {object} model.ErrorResponse "请求参数错误或模型不是语音合成模型"
+// @Failure 404 {object} model.ErrorResponse "提供商或模型不存在"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /providers/{providerId}/models/{modelId}/voices [get]
+func (h *ProviderHandler) GetModelVoices(w http.ResponseWriter, r *http.Request) {
+	// 获取路径参数
+	providerID := r.PathValue("providerId")
+	modelID := r.PathValue("modelId")
+	language := r.URL.Query().Get("language")
+
+	// 验证提供商ID
+	if err := validator.ValidateProviderID(providerID); err != nil {
+		h.handleValidationError(w, err, "提供商ID验证失败")
+		return
+	}
+
+	// 验证模型ID
+	if err := validator.ValidateModelID(modelID); err != nil {
+		h.handleValidationError(w, err, "模型ID验证失败")
+		return
+	}
+
+	// 记录请求日志
+	h.logger.Info("收到获取模型音色列表请求", map[string]interface{}{
+		"method":     r.Method,
+		"path":       r.URL.Path,
+		"providerId": providerID,
+		"modelId":    modelID,
+		"language":   language,
+	})
+
+	// 调用服务层获取音色列表
+	voices, err := h.providerService.GetModelVoices(providerID, modelID, language)
+	if err != nil {
+		// 处理错误
+		h.handleError(w, err, "获取模型音色列表失败", map[string]interface{}{
+			"providerId": providerID,
+			"modelId":    modelID,
+		})
+		return
+	}
+
+	// 构建成功响应
+	resp := response.Success(&voices)
+
+	// 返回JSON响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error("编码响应失败", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	// 记录响应日志
+	h.logger.Info("获取模型音色列表成功", map[string]interface{}{
+		"providerId": providerID,
+		"modelId":    modelID,
+		"count":      len(voices),
+	})
+}
+
 // handleValidationError 处理验证错误
 func (h *ProviderHandler) handleValidationError(w http.ResponseWriter, err error, logMessage string) {
 	// 记录验证错误日志
@@ -212,3 +212,126 @@ func TestProviderHandlerValidationWithData(t *testing.T) {
 		})
 	}
 }
+
+// TestGetModelVoices 测试获取语音合成模型的音色列表
+func TestGetModelVoices(t *testing.T) {
+	store := storage.NewMemoryStore()
+	store.SetProviders([]model.Provider{{ID: "tongyi", Provider: "tongyi"}})
+	store.SetModels("tongyi", []model.Model{
+		{
+			Model:     "tts-1",
+			ModelType: "tts",
+			ModelProperties: model.ModelProperties{
+				DefaultVoice: "sambert-zhiru-v1",
+				Voices: []model.Voice{
+					{Mode: "sambert-zhiru-v1", Name: "知茹（新闻女声）", Language: []string{"zh-Hans", "en-US"}},
+					{Mode: "sambert-camila-v1", Name: "Camila（西班牙语女声）", Language: []string{"es-ES"}},
+				},
+			},
+		},
+		{Model: "qwen-plus", ModelType: "llm"},
+	})
+	handler := NewProviderHandler(service.NewProviderService(store), logger.Default())
+
+	tests := []struct {
+		name           string
+		modelId        string
+		language       string
+		expectedStatus int
+		expectedCount  int
+	}{
+		{name: "全部音色", modelId: "tts-1", expectedStatus: http.StatusOK, expectedCount: 2},
+		{name: "按语言过滤", modelId: "tts-1", language: "es-ES", expectedStatus: http.StatusOK, expectedCount: 1},
+		{name: "不是语音合成模型", modelId: "qwen-plus", expectedStatus: http.StatusBadRequest},
+		{name: "模型不存在", modelId: "tts-2", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/providers/tongyi/models/"+tt.modelId+"/voices?language="+tt.language, nil)
+			req.SetPathValue("providerId", "tongyi")
+			req.SetPathValue("modelId", tt.modelId)
+			w := httptest.NewRecorder()
+			handler.GetModelVoices(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("期望状态码 %d, 得到 %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp model.ResponseData[[]model.Voice]
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("解析响应失败: %v", err)
+			}
+			if resp.Data == nil || len(*resp.Data) != tt.expectedCount {
+				t.Errorf("期望 %d 个音色, 得到 %+v", tt.expectedCount, resp.Data)
+			}
+		})
+	}
+}
+
+// TestSearchModels 测试跨提供商搜索模型
+func TestSearchModels(t *testing.T) {
+	store := storage.NewMemoryStore()
+	store.SetProviders([]model.Provider{{ID: "gemini"}, {ID: "tongyi"}})
+	store.SetModels("gemini", []model.Model{
+		{Model: "gemini-2.5-pro", ModelType: "llm", Features: []string{"vision", "tool-call"}, ModelProperties: model.ModelProperties{ContextSize: 1048576}},
+		{Model: "text-embedding-004", ModelType: "text_embedding"},
+	})
+	store.SetModels("tongyi", []model.Model{
+		{Model: "qwen-vl-max", ModelType: "llm", Features: []string{"vision"}, ModelProperties: model.ModelProperties{ContextSize: 32768}},
+		{Model: "qwen-plus", ModelType: "llm", Features: []string{"tool-call"}, ModelProperties: model.ModelProperties{ContextSize: 131072}},
+	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/models", NewProviderHandler(service.NewProviderService(store), logger.Default()).SearchModels)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedModels []string
+		expectedTotal  int
+	}{
+		{name: "全部模型", query: "", expectedStatus: http.StatusOK, expectedModels: []string{"gemini-2.5-pro", "text-embedding-004", "qwen-vl-max", "qwen-plus"}, expectedTotal: 4},
+		{name: "按特性过滤", query: "?feature=vision", expectedStatus: http.StatusOK, expectedModels: []string{"gemini-2.5-pro", "qwen-vl-max"}, expectedTotal: 2},
+		{name: "多个特性", query: "?feature=vision,tool-call", expectedStatus: http.StatusOK, expectedModels: []string{"gemini-2.5-pro"}, expectedTotal: 1},
+		{name: "排序和分页", query: "?model_type=llm&sort_by=context_size&pageNo=1&pageSize=2", expectedStatus: http.StatusOK, expectedModels: []string{"qwen-vl-max", "qwen-plus"}, expectedTotal: 3},
+		{name: "整数参数无效", query: "?min_context_size=large", expectedStatus: http.StatusBadRequest},
+		{name: "价格参数无效", query: "?max_input_price=free", expectedStatus: http.StatusBadRequest},
+		{name: "排序字段无效", query: "?sort_by=label", expectedStatus: http.StatusBadRequest},
+		{name: "每页大小超出范围", query: "?pageSize=500", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/models"+tt.query, nil)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("期望状态码 %d, 得到 %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp model.ResponsePaginationData[[]model.ModelSearchItem]
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("解析响应失败: %v", err)
+			}
+			if resp.Data.TotalCount != tt.expectedTotal {
+				t.Errorf("期望总数 %d, 得到 %d", tt.expectedTotal, resp.Data.TotalCount)
+			}
+			if len(resp.Data.Data) != len(tt.expectedModels) {
+				t.Fatalf("期望 %d 个模型, 得到 %+v", len(tt.expectedModels), resp.Data.Data)
+			}
+			for i, item := range resp.Data.Data {
+				if item.Model != tt.expectedModels[i] {
+					t.Errorf("第 %d 个模型期望 %s, 得到 %s", i, tt.expectedModels[i], item.Model)
+				}
+			}
+		})
+	}
+}
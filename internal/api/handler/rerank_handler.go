@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/service/ai"
+	"genkit-ai-service/pkg/errors"
+	"genkit-ai-service/pkg/response"
+	"genkit-ai-service/pkg/validator"
+)
+
+// RerankHandler 文档重排序接口处理器
+type RerankHandler struct {
+	rerankService ai.RerankService
+	logger        logger.Logger
+	validator     *validator.Validator
+}
+
+// NewRerankHandler 创建文档重排序处理器实例
+func NewRerankHandler(rerankService ai.RerankService, log logger.Logger) *RerankHandler {
+	return &RerankHandler{
+		rerankService: rerankService,
+		logger:        log,
+		validator:     validator.New(),
+	}
+}
+
+// HandleRerank 处理文档重排序请求
+// @Summary 文档重排序
+// @Description 使用模型目录中的 rerank 模型计算每个文档与查询的相关性，按得分从高到低返回文档序号。
+// @Description 查询与每个文档的 token 数之和不能超过模型的上下文大小
+// @Tags rerank
+// @Accept json
+// @Produce json
+// @Param request body model.RerankRequest true "重排序请求"
+// @Success 200 {object} model.ResponseData[model.RerankResponse] "成功返回重排序结果"
+// @Failure 400 {object} model.ErrorResponse "请求参数错误或模型不是重排序模型"
+// @Failure 404 {object} model.ErrorResponse "模型不存在"
+// @Failure 422 {object} model.ErrorResponse "参数验证失败"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Failure 503 {object} model.ErrorResponse "模型提供商不可用"
+// @Router /rerank [post]
+func (h *RerankHandler) HandleRerank(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 解析请求参数
+	var req model.RerankRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("解析请求参数失败", logger.Fields{"error": err})
+		h.writeErrorResponse(w, errors.NewBadRequestError("无效的请求参数"))
+		return
+	}
+
+	// 2. 验证请求参数
+	if validationErrors := h.validator.ValidateStruct(&req); validationErrors != nil {
+		h.logger.Warn("请求参数验证失败", logger.Fields{"errors": validationErrors})
+		h.writeValidationErrorResponse(w, validationErrors)
+		return
+	}
+
+	h.logger.Info("收到重排序请求", logger.Fields{
+		"model":     req.Model,
+		"documents": len(req.Documents),
+		"topN":      req.TopN,
+	})
+
+	// 3. 调用服务层重排序
+	resp, err := h.rerankService.Rerank(ctx, &req)
+	if err != nil {
+		h.logger.Error("文档重排序失败", logger.Fields{"error": err, "model": req.Model})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 4. 返回成功响应
+	h.writeJSONResponse(w, http.StatusOK, response.Success(resp))
+}
+
+// writeErrorResponse 写入错误响应
+func (h *RerankHandler) writeErrorResponse(w http.ResponseWriter, appErr *errors.AppError) {
+	resp := response.Error[any](appErr.Code, appErr.Message)
+
+	// 根据错误码确定 HTTP 状态码
+	statusCode := http.StatusInternalServerError
+	switch appErr.Code {
+	case errors.CodeBadRequest:
+		statusCode = http.StatusBadRequest
+	case errors.CodeValidationError:
+		statusCode = http.StatusUnprocessableEntity
+	case errors.CodeNotFound, errors.CodeModelNotFound, errors.CodeProviderNotFound:
+		statusCode = http.StatusNotFound
+	case errors.CodeServiceUnavailable:
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	h.writeJSONResponse(w, statusCode, resp)
+}
+
+// writeValidationErrorResponse 写入验证错误响应
+func (h *RerankHandler) writeValidationErrorResponse(w http.ResponseWriter, validationErrors []validator.ValidationError) {
+	errorData := map[string]interface{}{
+		"errors": validationErrors,
+	}
+
+	resp := response.ErrorWithData(
+		errors.CodeValidationError,
+		errors.MsgValidationError,
+		&errorData,
+	)
+
+	h.writeJSONResponse(w, http.StatusUnprocessableEntity, resp)
+}
+
+// writeJSONResponse 写入 JSON 响应
+func (h *RerankHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("写入响应失败", logger.Fields{"error": err})
+	}
+}
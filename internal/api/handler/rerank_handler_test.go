@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/pkg/errors"
+)
+
+// mockRerankService 模拟文档重排序服务
+type mockRerankService struct {
+	lastReq *model.RerankRequest
+}
+
+func (m *mockRerankService) Rerank(ctx context.Context, req *model.RerankRequest) (*model.RerankResponse, error) {
+	m.lastReq = req
+	if req.Model == "text-embedding-v3" {
+		return nil, errors.NewBadRequestError("模型 'text-embedding-v3' 不是重排序模型")
+	}
+	if req.Model == "unknown" {
+		return nil, errors.NewModelNotFoundError(req.Model)
+	}
+
+	resp := &model.RerankResponse{Model: req.Model}
+	for i := len(req.Documents) - 1; i >= 0; i-- {
+		resp.Results = append(resp.Results, model.RerankResult{Index: i, RelevanceScore: float64(i + 1)})
+	}
+	return resp, nil
+}
+
+func TestHandleRerank(t *testing.T) {
+	t.Run("成功重排序", func(t *testing.T) {
+		service := &mockRerankService{}
+		handler := NewRerankHandler(service, logger.Default())
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/rerank",
+			strings.NewReader(`{"model":"tongyi/gte-rerank","query":"数据库","documents":["缓存","数据库连接"],"topN":1}`))
+		w := httptest.NewRecorder()
+		handler.HandleRerank(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("期望状态码 200, 得到 %d: %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Data model.RerankResponse `json:"data"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("解析响应失败: %v", err)
+		}
+		if len(resp.Data.Results) != 2 || resp.Data.Results[0].Index != 1 || resp.Data.Results[0].RelevanceScore != 2 {
+			t.Errorf("响应不正确: %+v", resp.Data)
+		}
+		if service.lastReq.TopN != 1 {
+			t.Errorf("期望传递 topN, 得到 %+v", service.lastReq)
+		}
+	})
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "无效的 JSON", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "缺少查询", body: `{"model":"tongyi/gte-rerank","documents":["a"]}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "文档为空", body: `{"model":"tongyi/gte-rerank","query":"q","documents":[]}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "topN 无效", body: `{"model":"tongyi/gte-rerank","query":"q","documents":["a"],"topN":-1}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "不是重排序模型", body: `{"model":"text-embedding-v3","query":"q","documents":["a"]}`, wantStatus: http.StatusBadRequest},
+		{name: "模型不存在", body: `{"model":"unknown","query":"q","documents":["a"]}`, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewRerankHandler(&mockRerankService{}, logger.Default())
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/rerank", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			handler.HandleRerank(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("期望状态码 %d, 得到 %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
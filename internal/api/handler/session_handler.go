@@ -704,11 +704,11 @@ func (h *SessionHandler) writeErrorResponse(w http.ResponseWriter, appErr *error
 		statusCode = http.StatusBadRequest
 	case errors.CodeValidationError:
 		statusCode = http.StatusUnprocessableEntity
-	case errors.CodeNotFound, errors.CodeSessionNotFound:
+	case errors.CodeNotFound, errors.CodeSessionNotFound, errors.CodeKnowledgeBaseNotFound:
 		statusCode = http.StatusNotFound
 	case errors.CodeUnauthorized:
 		statusCode = http.StatusUnauthorized
-	case errors.CodeForbidden, errors.CodeSessionAccessDenied:
+	case errors.CodeForbidden, errors.CodeSessionAccessDenied, errors.CodeKnowledgeBaseAccessDenied:
 		statusCode = http.StatusForbidden
 	case errors.CodeServiceUnavailable:
 		statusCode = http.StatusServiceUnavailable
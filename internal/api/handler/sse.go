@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"genkit-ai-service/pkg/errors"
+	"genkit-ai-service/pkg/response"
+)
+
+// SSE 事件名称
+const (
+	// sseEventStart 生成开始事件（会话消息接口在第一个文本片段之前发送）
+	sseEventStart = "start"
+	// sseEventMessage 文本片段事件
+	sseEventMessage = "message"
+	// sseEventDone 生成完成事件
+	sseEventDone = "done"
+	// sseEventError 错误事件
+	sseEventError = "error"
+)
+
+// sseStart 生成开始事件数据
+type sseStart struct {
+	// AI 回复消息ID，可用于中止生成
+	MessageID string `json:"messageId"`
+	// 会话ID（重新生成和编辑消息时不返回）
+	SessionID string `json:"sessionId,omitempty"`
+}
+
+// sseChunk 文本片段事件数据
+type sseChunk struct {
+	Content string `json:"content"`
+}
+
+// sseWriter Server-Sent Events 响应写入器
+type sseWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// wantsEventStream 判断客户端是否请求 SSE 流式响应
+func wantsEventStream(r *http.Request, stream bool) bool {
+	return stream || strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// clearWriteDeadline 清除流式响应的写截止时间
+// 第一个片段之前的准备和生成加上整个流式响应可能超过服务器的 WriteTimeout，
+// 处理器确定以 SSE 返回后、开始生成之前调用；响应头仍延迟到第一个片段时写入
+func clearWriteDeadline(w http.ResponseWriter) {
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+}
+
+// newSSEWriter 写入 SSE 响应头并返回写入器，调用前应已通过 clearWriteDeadline 清除写截止时间
+func newSSEWriter(w http.ResponseWriter) *sseWriter {
+	rc := http.NewResponseController(w)
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	_ = rc.Flush()
+
+	return &sseWriter{w: w, rc: rc}
+}
+
+// WriteEvent 写入一个事件，data 以 JSON 编码
+func (s *sseWriter) WriteEvent(event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("序列化事件数据失败: %w", err)
+	}
+
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+
+	return s.rc.Flush()
+}
+
+// WriteChunk 写入文本片段事件
+func (s *sseWriter) WriteChunk(content string) error {
+	return s.WriteEvent(sseEventMessage, sseChunk{Content: content})
+}
+
+// WriteError 写入错误事件
+func (s *sseWriter) WriteError(appErr *errors.AppError) error {
+	return s.WriteEvent(sseEventError, response.Error[any](appErr.Code, appErr.Message))
+}
+
+// toAppError 将错误转换为应用错误，未知错误视为内部错误
+func toAppError(err error) *errors.AppError {
+	if appErr, ok := err.(*errors.AppError); ok {
+		return appErr
+	}
+	return errors.NewInternalError(err)
+}
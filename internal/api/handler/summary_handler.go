@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/service/session"
+	"genkit-ai-service/pkg/errors"
+	"genkit-ai-service/pkg/response"
+)
+
+// SummaryHandler 会话摘要处理器
+type SummaryHandler struct {
+	summaryService   session.SummaryService
+	summaryScheduler session.SummaryScheduler
+	logger           logger.Logger
+}
+
+// NewSummaryHandler 创建会话摘要处理器实例
+// summaryScheduler 可为 nil，此时摘要列表不包含后台失败记录
+func NewSummaryHandler(summaryService session.SummaryService, summaryScheduler session.SummaryScheduler, log logger.Logger) *SummaryHandler {
+	return &SummaryHandler{
+		summaryService:   summaryService,
+		summaryScheduler: summaryScheduler,
+		logger:           log,
+	}
+}
+
+// ListSummaries 获取会话摘要列表
+// @Summary 获取会话摘要列表
+// @Description 获取指定会话的所有摘要，最新的摘要在前，并返回最近一次后台摘要失败记录
+// @Tags summaries
+// @Accept json
+// @Produce json
+// @Param id path string true "会话ID"
+// @Success 200 {object} model.ResponseData[session.SummaryListResponse] "成功返回摘要列表"
+// @Failure 400 {object} model.ErrorResponse "请求参数错误"
+// @Failure 403 {object} model.ErrorResponse "无权访问"
+// @Failure 404 {object} model.ErrorResponse "会话不存在"
+// @Failure 500 {object} model.ErrorResponse "服务器内部错误"
+// @Router /chat/sessions/{id}/summaries [get]
+func (h *SummaryHandler) ListSummaries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 从URL路径中提取会话ID
+	sessionID := h.extractSessionID(r.URL.Path)
+	if sessionID == "" {
+		h.writeErrorResponse(w, errors.NewBadRequestError("会话ID不能为空"))
+		return
+	}
+
+	// 2. 从上下文获取用户ID
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		userID = "default-user-id" // 临时默认值
+	}
+
+	h.logger.Info("收到获取会话摘要列表请求", logger.Fields{
+		"sessionId": sessionID,
+		"userId":    userID,
+	})
+
+	// 3. 调用服务层获取摘要列表
+	summaries, err := h.summaryService.ListSummaries(ctx, sessionID, userID)
+	if err != nil {
+		h.logger.Error("获取会话摘要列表失败", logger.Fields{
+			"error":     err,
+			"sessionId": sessionID,
+			"userId":    userID,
+		})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	resp := &session.SummaryListResponse{Summaries: summaries}
+	if h.summaryScheduler != nil {
+		resp.LastFailure = h.summaryScheduler.LastFailure(sessionID)
+	}
+
+	// 4. 返回成功响应
+	h.writeJSONResponse(w, http.StatusOK, response.Success(resp))
+}
+
+// GenerateSummary 立即生成会话摘要
+// @Summary 生成会话摘要
+// @Description 立即为会话中上次摘要之后的消息生成摘要，不检查消息数量阈值；没有新消息时返回最新的已有摘要
+// @Tags summaries
+// @Accept json
+// @Produce json
+// @Param id path string true "会话ID"
+// @Success 200 {object} model.ResponseData[model.ChatSummary] "成功生成摘要"
+// @Failure 400 {object} model.ErrorResponse "请求参数错误"
+// @Failure 403 {object} model.ErrorResponse "无权访问"
+// @Failure 404 {object} model.ErrorResponse "会话不存在"
+// @Failure 500 {object} model.ErrorResponse "摘要生成失败"
+// @Router /chat/sessions/{id}/summaries [post]
+func (h *SummaryHandler) GenerateSummary(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. 从URL路径中提取会话ID
+	sessionID := h.extractSessionID(r.URL.Path)
+	if sessionID == "" {
+		h.writeErrorResponse(w, errors.NewBadRequestError("会话ID不能为空"))
+		return
+	}
+
+	// 2. 从上下文获取用户ID
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		userID = "default-user-id" // 临时默认值
+	}
+
+	h.logger.Info("收到生成会话摘要请求", logger.Fields{
+		"sessionId": sessionID,
+		"userId":    userID,
+	})
+
+	// 3. 调用服务层生成摘要
+	summary, err := h.summaryService.TriggerSummary(ctx, sessionID, userID)
+	if err != nil {
+		h.logger.Error("生成会话摘要失败", logger.Fields{
+			"error":     err,
+			"sessionId": sessionID,
+			"userId":    userID,
+		})
+		h.writeErrorResponse(w, toAppError(err))
+		return
+	}
+
+	// 4. 返回成功响应
+	h.writeJSONResponse(w, http.StatusOK, response.Success(summary))
+}
+
+// extractSessionID 从URL路径中提取会话ID
+// 路径格式: /api/v1/chat/sessions/{id}/summaries
+func (h *SummaryHandler) extractSessionID(path string) string {
+	path = strings.TrimSuffix(path, "/")
+	path = strings.TrimSuffix(path, "/summaries")
+
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if part == "sessions" && i+1 < len(parts) {
+			return parts[i+1]
+		}
+	}
+
+	return ""
+}
+
+// writeErrorResponse 写入错误响应
+func (h *SummaryHandler) writeErrorResponse(w http.ResponseWriter, appErr *errors.AppError) {
+	resp := response.Error[any](appErr.Code, appErr.Message)
+
+	// 根据错误码确定 HTTP 状态码
+	statusCode := http.StatusInternalServerError
+	switch appErr.Code {
+	case errors.CodeBadRequest:
+		statusCode = http.StatusBadRequest
+	case errors.CodeNotFound, errors.CodeSessionNotFound, errors.CodeModelNotFound:
+		statusCode = http.StatusNotFound
+	case errors.CodeUnauthorized:
+		statusCode = http.StatusUnauthorized
+	case errors.CodeForbidden, errors.CodeSessionAccessDenied:
+		statusCode = http.StatusForbidden
+	case errors.CodeServiceUnavailable:
+		statusCode = http.StatusServiceUnavailable
+	case errors.CodeSummaryGenerationFailed:
+		statusCode = http.StatusInternalServerError
+	}
+
+	h.writeJSONResponse(w, statusCode, resp)
+}
+
+// writeJSONResponse 写入 JSON 响应
+func (h *SummaryHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("写入响应失败", logger.Fields{"error": err})
+	}
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/service/session"
+	"genkit-ai-service/pkg/errors"
+)
+
+// mockSummaryService 模拟摘要服务
+type mockSummaryService struct {
+	listSummariesFunc  func(ctx context.Context, sessionID, userID string) ([]*model.ChatSummary, error)
+	triggerSummaryFunc func(ctx context.Context, sessionID, userID string) (*model.ChatSummary, error)
+}
+
+func (m *mockSummaryService) GenerateSummary(ctx context.Context, sessionID string) (*model.ChatSummary, error) {
+	return nil, nil
+}
+
+func (m *mockSummaryService) GetSummary(ctx context.Context, sessionID string) (*model.ChatSummary, error) {
+	return nil, nil
+}
+
+func (m *mockSummaryService) ShouldGenerateSummary(ctx context.Context, sessionID string) (bool, error) {
+	return false, nil
+}
+
+func (m *mockSummaryService) ListSummaries(ctx context.Context, sessionID, userID string) ([]*model.ChatSummary, error) {
+	if m.listSummariesFunc != nil {
+		return m.listSummariesFunc(ctx, sessionID, userID)
+	}
+	return []*model.ChatSummary{}, nil
+}
+
+func (m *mockSummaryService) TriggerSummary(ctx context.Context, sessionID, userID string) (*model.ChatSummary, error) {
+	if m.triggerSummaryFunc != nil {
+		return m.triggerSummaryFunc(ctx, sessionID, userID)
+	}
+	return nil, nil
+}
+
+// mockSummaryScheduler 模拟摘要调度器
+type mockSummaryScheduler struct {
+	failures map[string]*session.SummaryFailure
+}
+
+func (m *mockSummaryScheduler) Schedule(sessionID string) bool { return true }
+func (m *mockSummaryScheduler) Start()                         {}
+func (m *mockSummaryScheduler) Stop()                          {}
+
+func (m *mockSummaryScheduler) LastFailure(sessionID string) *session.SummaryFailure {
+	return m.failures[sessionID]
+}
+
+func TestListSummaries(t *testing.T) {
+	mockService := &mockSummaryService{
+		listSummariesFunc: func(ctx context.Context, sessionID, userID string) ([]*model.ChatSummary, error) {
+			if sessionID == "not-found" {
+				return nil, errors.NewSessionNotFoundError(sessionID)
+			}
+			return []*model.ChatSummary{{ID: "summary-1", SessionID: sessionID, Summary: "摘要"}}, nil
+		},
+	}
+	scheduler := &mockSummaryScheduler{failures: map[string]*session.SummaryFailure{
+		"session-1": {Error: "AI 服务不可用", FailedAt: time.Now()},
+	}}
+	handler := NewSummaryHandler(mockService, scheduler, logger.Default())
+
+	t.Run("成功获取摘要列表", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/chat/sessions/session-1/summaries", nil)
+		req.Header.Set("X-User-ID", "test-user")
+
+		w := httptest.NewRecorder()
+		handler.ListSummaries(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("期望状态码 %d, 实际 %d", http.StatusOK, w.Code)
+		}
+
+		var resp struct {
+			Data session.SummaryListResponse `json:"data"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("解析响应失败: %v", err)
+		}
+		if len(resp.Data.Summaries) != 1 || resp.Data.Summaries[0].SessionID != "session-1" {
+			t.Errorf("摘要列表不正确: %+v", resp.Data.Summaries)
+		}
+		if resp.Data.LastFailure == nil || resp.Data.LastFailure.Error != "AI 服务不可用" {
+			t.Errorf("期望返回最近一次失败记录, 实际 %+v", resp.Data.LastFailure)
+		}
+	})
+
+	t.Run("会话不存在", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/chat/sessions/not-found/summaries", nil)
+
+		w := httptest.NewRecorder()
+		handler.ListSummaries(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("期望状态码 %d, 实际 %d", http.StatusNotFound, w.Code)
+		}
+	})
+}
+
+func TestGenerateSummary(t *testing.T) {
+	t.Run("成功生成摘要", func(t *testing.T) {
+		var gotSessionID, gotUserID string
+		mockService := &mockSummaryService{
+			triggerSummaryFunc: func(ctx context.Context, sessionID, userID string) (*model.ChatSummary, error) {
+				gotSessionID, gotUserID = sessionID, userID
+				return &model.ChatSummary{ID: "summary-1", SessionID: sessionID, Summary: "摘要"}, nil
+			},
+		}
+		handler := NewSummaryHandler(mockService, nil, logger.Default())
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/chat/sessions/session-1/summaries", nil)
+		req.Header.Set("X-User-ID", "test-user")
+
+		w := httptest.NewRecorder()
+		handler.GenerateSummary(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("期望状态码 %d, 实际 %d", http.StatusOK, w.Code)
+		}
+		if gotSessionID != "session-1" || gotUserID != "test-user" {
+			t.Errorf("参数不正确: sessionId=%s userId=%s", gotSessionID, gotUserID)
+		}
+	})
+
+	t.Run("生成失败", func(t *testing.T) {
+		mockService := &mockSummaryService{
+			triggerSummaryFunc: func(ctx context.Context, sessionID, userID string) (*model.ChatSummary, error) {
+				return nil, errors.NewSummaryGenerationFailedError(stderrors.New("AI 服务不可用"))
+			},
+		}
+		handler := NewSummaryHandler(mockService, nil, logger.Default())
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/chat/sessions/session-1/summaries", nil)
+
+		w := httptest.NewRecorder()
+		handler.GenerateSummary(w, req)
+
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("期望状态码 %d, 实际 %d", http.StatusInternalServerError, w.Code)
+		}
+	})
+}
@@ -134,4 +134,21 @@ func TestResponseWriter(t *testing.T) {
 			t.Errorf("期望状态码保持为 %d, 得到 %d", http.StatusCreated, rw.statusCode)
 		}
 	})
+	t.Run("支持流式刷新", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		rw := &responseWriter{
+			ResponseWriter: rec,
+			statusCode:     http.StatusOK,
+			written:        false,
+		}
+
+		rw.Write([]byte("data: test\n\n"))
+		if err := http.NewResponseController(rw).Flush(); err != nil {
+			t.Fatalf("期望支持 Flush，得到错误: %v", err)
+		}
+
+		if !rec.Flushed {
+			t.Error("期望底层 ResponseWriter 已刷新")
+		}
+	})
 }
@@ -29,8 +29,8 @@ func (m *mockAIService) ChatStream(ctx context.Context, req *model.ChatRequest)
 	return ch, nil
 }
 
-func (m *mockAIService) AbortChat(ctx context.Context, sessionID string) error {
-	return nil
+func (m *mockAIService) AbortChat(ctx context.Context, sessionID string) (bool, error) {
+	return false, nil
 }
 
 // mockHealthService 模拟健康检查服务
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"net/http"
+
+	"genkit-ai-service/internal/api/handler"
+)
+
+// RegisterAdminRoutes 注册管理相关的API路由
+func RegisterAdminRoutes(mux *http.ServeMux, catalogHandler *handler.CatalogHandler) {
+	// POST /api/v1/admin/models/reload - 重新加载模型目录
+	mux.HandleFunc("POST /api/v1/admin/models/reload", catalogHandler.ReloadModels)
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"net/http"
+
+	"genkit-ai-service/internal/api/handler"
+)
+
+// RegisterAudioRoutes 注册语音相关的API路由
+func RegisterAudioRoutes(mux *http.ServeMux, audioHandler *handler.AudioHandler) {
+	// POST /api/v1/audio/speech - 将文本或会话消息的内容合成为语音
+	mux.HandleFunc("POST /api/v1/audio/speech", audioHandler.HandleSpeech)
+
+	// POST /api/v1/audio/transcriptions - 将上传的音频转写为文本，可追加到会话
+	mux.HandleFunc("POST /api/v1/audio/transcriptions", audioHandler.HandleTranscription)
+}
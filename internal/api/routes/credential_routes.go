@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"net/http"
+
+	"genkit-ai-service/internal/api/handler"
+)
+
+// RegisterCredentialRoutes 注册模型提供商凭证相关的API路由
+func RegisterCredentialRoutes(mux *http.ServeMux, credentialHandler *handler.CredentialHandler) {
+	// POST /api/v1/credentials - 创建凭证
+	mux.HandleFunc("POST /api/v1/credentials", credentialHandler.CreateCredential)
+
+	// GET /api/v1/credentials - 获取凭证列表
+	mux.HandleFunc("GET /api/v1/credentials", credentialHandler.ListCredentials)
+
+	// GET /api/v1/credentials/{id} - 获取凭证详情
+	mux.HandleFunc("GET /api/v1/credentials/{id}", credentialHandler.GetCredential)
+
+	// PUT /api/v1/credentials/{id} - 更新凭证
+	mux.HandleFunc("PUT /api/v1/credentials/{id}", credentialHandler.UpdateCredential)
+
+	// DELETE /api/v1/credentials/{id} - 删除凭证
+	mux.HandleFunc("DELETE /api/v1/credentials/{id}", credentialHandler.DeleteCredential)
+}
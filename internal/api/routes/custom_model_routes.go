@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"net/http"
+
+	"genkit-ai-service/internal/api/handler"
+)
+
+// RegisterCustomModelRoutes 注册自定义模型相关的API路由
+func RegisterCustomModelRoutes(mux *http.ServeMux, customModelHandler *handler.CustomModelHandler) {
+	// POST /api/v1/providers/{providerId}/custom-models - 注册自定义模型
+	mux.HandleFunc("POST /api/v1/providers/{providerId}/custom-models", customModelHandler.RegisterModel)
+
+	// GET /api/v1/providers/{providerId}/custom-models - 获取自定义模型列表
+	mux.HandleFunc("GET /api/v1/providers/{providerId}/custom-models", customModelHandler.ListModels)
+
+	// DELETE /api/v1/providers/{providerId}/custom-models/{modelId} - 删除自定义模型
+	mux.HandleFunc("DELETE /api/v1/providers/{providerId}/custom-models/{modelId}", customModelHandler.DeleteModel)
+}
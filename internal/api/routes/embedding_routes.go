@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"net/http"
+
+	"genkit-ai-service/internal/api/handler"
+)
+
+// RegisterEmbeddingRoutes 注册文本向量化相关的API路由
+func RegisterEmbeddingRoutes(mux *http.ServeMux, embeddingHandler *handler.EmbeddingHandler) {
+	// POST /api/v1/embeddings - 使用向量模型为一批文本生成向量
+	mux.HandleFunc("POST /api/v1/embeddings", embeddingHandler.HandleEmbeddings)
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"net/http"
+
+	"genkit-ai-service/internal/api/handler"
+)
+
+// RegisterFileRoutes 注册上传文件相关的API路由
+func RegisterFileRoutes(mux *http.ServeMux, fileHandler *handler.FileHandler) {
+	// POST /api/v1/files - 上传文件（multipart/form-data）
+	mux.HandleFunc("POST /api/v1/files", fileHandler.UploadFile)
+
+	// GET /api/v1/files/{id} - 获取文件信息
+	mux.HandleFunc("GET /api/v1/files/{id}", fileHandler.GetFile)
+
+	// GET /api/v1/files/{id}/content - 下载文件内容
+	mux.HandleFunc("GET /api/v1/files/{id}/content", fileHandler.DownloadFile)
+
+	// DELETE /api/v1/files/{id} - 删除文件
+	mux.HandleFunc("DELETE /api/v1/files/{id}", fileHandler.DeleteFile)
+}
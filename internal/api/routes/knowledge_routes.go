@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"net/http"
+
+	"genkit-ai-service/internal/api/handler"
+)
+
+// RegisterKnowledgeRoutes 注册知识库相关的API路由
+func RegisterKnowledgeRoutes(mux *http.ServeMux, knowledgeHandler *handler.KnowledgeHandler) {
+	// POST /api/v1/knowledge-bases - 创建知识库
+	mux.HandleFunc("POST /api/v1/knowledge-bases", knowledgeHandler.CreateKnowledgeBase)
+
+	// GET /api/v1/knowledge-bases - 获取知识库列表
+	mux.HandleFunc("GET /api/v1/knowledge-bases", knowledgeHandler.ListKnowledgeBases)
+
+	// GET /api/v1/knowledge-bases/{id} - 获取知识库详情
+	mux.HandleFunc("GET /api/v1/knowledge-bases/{id}", knowledgeHandler.GetKnowledgeBase)
+
+	// DELETE /api/v1/knowledge-bases/{id} - 删除知识库及其文档
+	mux.HandleFunc("DELETE /api/v1/knowledge-bases/{id}", knowledgeHandler.DeleteKnowledgeBase)
+
+	// POST /api/v1/knowledge-bases/{id}/documents - 添加文档（分块并生成向量）
+	mux.HandleFunc("POST /api/v1/knowledge-bases/{id}/documents", knowledgeHandler.AddDocument)
+
+	// GET /api/v1/knowledge-bases/{id}/documents - 获取文档列表
+	mux.HandleFunc("GET /api/v1/knowledge-bases/{id}/documents", knowledgeHandler.ListDocuments)
+
+	// DELETE /api/v1/knowledge-bases/{id}/documents/{docId} - 删除文档
+	mux.HandleFunc("DELETE /api/v1/knowledge-bases/{id}/documents/{docId}", knowledgeHandler.DeleteDocument)
+
+	// POST /api/v1/knowledge-bases/{id}/search - 检索知识库
+	mux.HandleFunc("POST /api/v1/knowledge-bases/{id}/search", knowledgeHandler.SearchKnowledgeBase)
+}
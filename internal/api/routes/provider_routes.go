@@ -23,4 +23,10 @@ func RegisterProviderRoutes(mux *http.ServeMux, handler *handler.ProviderHandler
 
 	// GET /api/v1/providers/{providerId}/models/{modelId}/parameter-rules - 获取模型的参数规则
 	mux.HandleFunc("GET /api/v1/providers/{providerId}/models/{modelId}/parameter-rules", handler.GetModelParameterRules)
+
+	// GET /api/v1/providers/{providerId}/models/{modelId}/voices - 获取语音合成模型的音色列表
+	mux.HandleFunc("GET /api/v1/providers/{providerId}/models/{modelId}/voices", handler.GetModelVoices)
+
+	// GET /api/v1/models - 跨所有提供商搜索模型
+	mux.HandleFunc("GET /api/v1/models", handler.SearchModels)
 }
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"net/http"
+
+	"genkit-ai-service/internal/api/handler"
+)
+
+// RegisterRerankRoutes 注册文档重排序相关的API路由
+func RegisterRerankRoutes(mux *http.ServeMux, rerankHandler *handler.RerankHandler) {
+	// POST /api/v1/rerank - 使用重排序模型按相关性为文档打分排序
+	mux.HandleFunc("POST /api/v1/rerank", rerankHandler.HandleRerank)
+}
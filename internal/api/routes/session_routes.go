@@ -49,4 +49,28 @@ func RegisterSessionRoutes(mux *http.ServeMux, sessionHandler *handler.SessionHa
 
 	// POST /api/v1/chat/messages/{id}/abort - 中止消息生成
 	mux.HandleFunc("POST /api/v1/chat/messages/{id}/abort", messageHandler.AbortMessage)
+
+	// POST /api/v1/chat/messages/{id}/regenerate - 重新生成 AI 回复（原回复保留为并列分支）
+	mux.HandleFunc("POST /api/v1/chat/messages/{id}/regenerate", messageHandler.RegenerateMessage)
+
+	// POST /api/v1/chat/messages/{id}/edit - 编辑用户消息并重新发送（创建新分支）
+	mux.HandleFunc("POST /api/v1/chat/messages/{id}/edit", messageHandler.EditMessage)
+
+	// GET /api/v1/chat/messages/{id}/siblings - 获取与消息并列的所有分支
+	mux.HandleFunc("GET /api/v1/chat/messages/{id}/siblings", messageHandler.GetSiblings)
+
+	// POST /api/v1/chat/messages/{id}/select - 切换到消息所在的分支
+	mux.HandleFunc("POST /api/v1/chat/messages/{id}/select", messageHandler.SelectBranch)
+
+	// POST /api/v1/chat/sessions/{id}/abort - 中止会话正在进行的生成
+	mux.HandleFunc("POST /api/v1/chat/sessions/{id}/abort", messageHandler.AbortSession)
+}
+
+// RegisterSummaryRoutes 注册会话摘要相关的API路由
+func RegisterSummaryRoutes(mux *http.ServeMux, summaryHandler *handler.SummaryHandler) {
+	// GET /api/v1/chat/sessions/{id}/summaries - 获取会话摘要列表
+	mux.HandleFunc("GET /api/v1/chat/sessions/{id}/summaries", summaryHandler.ListSummaries)
+
+	// POST /api/v1/chat/sessions/{id}/summaries - 立即生成会话摘要
+	mux.HandleFunc("POST /api/v1/chat/sessions/{id}/summaries", summaryHandler.GenerateSummary)
 }
@@ -11,12 +11,17 @@ import (
 
 // Config 应用配置结构
 type Config struct {
-	Server   ServerConfig
-	Genkit   GenkitConfig
-	Database DatabaseConfig
-	Log      LogConfig
-	Session  SessionConfig
-	Models   ModelsConfig
+	Server      ServerConfig
+	Genkit      GenkitConfig
+	Database    DatabaseConfig
+	Log         LogConfig
+	Session     SessionConfig
+	Models      ModelsConfig
+	Providers   ProvidersConfig
+	Files       FilesConfig
+	Knowledge   KnowledgeConfig
+	Credentials CredentialsConfig
+	Admin       AdminConfig
 }
 
 // ServerConfig 服务器配置
@@ -58,6 +63,8 @@ type SessionConfig struct {
 	Timeout          time.Duration // 会话超时时间
 	CleanupInterval  time.Duration // 会话清理间隔
 	SummaryThreshold int           // 摘要生成阈值（消息数量）
+	SummaryWorkers   int           // 后台摘要生成工作协程数
+	SummaryQueueSize int           // 后台摘要任务队列大小
 	DefaultPageSize  int           // 默认分页大小
 	MaxPageSize      int           // 最大分页大小
 	MaxTitleLength   int           // 会话标题最大长度
@@ -65,7 +72,40 @@ type SessionConfig struct {
 
 // ModelsConfig 模型配置
 type ModelsConfig struct {
-	Dir string // 模型配置文件目录
+	Dir           string        // 模型配置文件目录
+	WatchInterval time.Duration // 轮询模型目录变化的间隔，为 0 时不监听
+}
+
+// ProvidersConfig 模型提供商后端配置
+// 未配置 API 密钥的提供商不会注册到模型路由中
+type ProvidersConfig struct {
+	DashScopeAPIKey       string // 通义千问 DashScope API密钥
+	DashScopeBaseURL      string // DashScope OpenAI 兼容接口地址
+	AzureOpenAIAPIKey     string // Azure OpenAI API密钥
+	AzureOpenAIEndpoint   string // Azure OpenAI 资源地址
+	AzureOpenAIAPIVersion string // Azure OpenAI API版本
+}
+
+// FilesConfig 上传文件配置
+type FilesConfig struct {
+	Dir       string // 本地文件存储目录
+	MaxSizeMB int    // 单个文件的最大大小（MB）
+}
+
+// KnowledgeConfig 知识库配置
+type KnowledgeConfig struct {
+	TopK        int    // 发送消息时从会话挂载的知识库中检索的分块数量
+	RerankModel string // 检索结果的重排序模型，为空时不重排序
+}
+
+// CredentialsConfig 模型提供商凭证配置
+type CredentialsConfig struct {
+	MasterKey string // 加密凭证的主密钥，为空时不提供凭证管理接口
+}
+
+// AdminConfig 管理接口配置
+type AdminConfig struct {
+	Token string // 管理接口的访问令牌，为空时不提供管理接口
 }
 
 // Load 从环境变量加载配置
@@ -120,6 +160,8 @@ func Load() (*Config, error) {
 		Timeout:          getEnvDuration("SESSION_TIMEOUT", 30*time.Minute),
 		CleanupInterval:  getEnvDuration("SESSION_CLEANUP_INTERVAL", 5*time.Minute),
 		SummaryThreshold: getEnvInt("SESSION_SUMMARY_THRESHOLD", 50),
+		SummaryWorkers:   getEnvInt("SESSION_SUMMARY_WORKERS", 2),
+		SummaryQueueSize: getEnvInt("SESSION_SUMMARY_QUEUE_SIZE", 100),
 		DefaultPageSize:  getEnvInt("SESSION_DEFAULT_PAGE_SIZE", 20),
 		MaxPageSize:      getEnvInt("SESSION_MAX_PAGE_SIZE", 100),
 		MaxTitleLength:   getEnvInt("SESSION_MAX_TITLE_LENGTH", 255),
@@ -127,7 +169,39 @@ func Load() (*Config, error) {
 
 	// 加载模型配置
 	config.Models = ModelsConfig{
-		Dir: getEnv("MODELS_DIR", "./models"),
+		Dir:           getEnv("MODELS_DIR", "./models"),
+		WatchInterval: getEnvDuration("MODELS_WATCH_INTERVAL", 0),
+	}
+
+	// 加载上传文件配置
+	config.Files = FilesConfig{
+		Dir:       getEnv("FILES_DIR", "./data/files"),
+		MaxSizeMB: getEnvInt("FILES_MAX_SIZE_MB", 20),
+	}
+
+	// 加载知识库配置
+	config.Knowledge = KnowledgeConfig{
+		TopK:        getEnvInt("KNOWLEDGE_TOP_K", 5),
+		RerankModel: os.Getenv("KNOWLEDGE_RERANK_MODEL"),
+	}
+
+	// 加载凭证配置
+	config.Credentials = CredentialsConfig{
+		MasterKey: os.Getenv("CREDENTIALS_MASTER_KEY"),
+	}
+
+	// 加载管理接口配置
+	config.Admin = AdminConfig{
+		Token: os.Getenv("ADMIN_TOKEN"),
+	}
+
+	// 加载模型提供商后端配置
+	config.Providers = ProvidersConfig{
+		DashScopeAPIKey:       os.Getenv("DASHSCOPE_API_KEY"),
+		DashScopeBaseURL:      getEnv("DASHSCOPE_BASE_URL", "https://dashscope.aliyuncs.com/compatible-mode/v1"),
+		AzureOpenAIAPIKey:     os.Getenv("AZURE_OPENAI_API_KEY"),
+		AzureOpenAIEndpoint:   os.Getenv("AZURE_OPENAI_ENDPOINT"),
+		AzureOpenAIAPIVersion: getEnv("AZURE_OPENAI_API_VERSION", "2024-10-21"),
 	}
 
 	// 验证配置
@@ -242,6 +316,14 @@ func (c *Config) Validate() error {
 	if c.Session.SummaryThreshold <= 0 {
 		return fmt.Errorf("摘要生成阈值必须大于0")
 	}
+
+	if c.Session.SummaryWorkers <= 0 {
+		return fmt.Errorf("摘要生成工作协程数必须大于0")
+	}
+
+	if c.Session.SummaryQueueSize <= 0 {
+		return fmt.Errorf("摘要任务队列大小必须大于0")
+	}
 	
 	if c.Session.DefaultPageSize <= 0 {
 		return fmt.Errorf("默认分页大小必须大于0")
@@ -263,6 +345,33 @@ func (c *Config) Validate() error {
 	if c.Models.Dir == "" {
 		return fmt.Errorf("模型目录不能为空")
 	}
+	if c.Models.WatchInterval < 0 {
+		return fmt.Errorf("模型目录轮询间隔不能为负数")
+	}
+
+	// 验证上传文件配置
+	if c.Files.Dir == "" {
+		return fmt.Errorf("文件存储目录不能为空")
+	}
+
+	if c.Files.MaxSizeMB <= 0 {
+		return fmt.Errorf("文件最大大小必须大于0")
+	}
+
+	// 验证知识库配置
+	if c.Knowledge.TopK <= 0 {
+		return fmt.Errorf("知识库检索数量必须大于0")
+	}
+
+	// 验证凭证配置
+	if c.Credentials.MasterKey != "" && len(c.Credentials.MasterKey) < 32 {
+		return fmt.Errorf("凭证主密钥长度不能少于32个字符")
+	}
+
+	// 验证模型提供商后端配置
+	if c.Providers.AzureOpenAIAPIKey != "" && c.Providers.AzureOpenAIEndpoint == "" {
+		return fmt.Errorf("配置 Azure OpenAI API密钥时必须同时配置 AZURE_OPENAI_ENDPOINT")
+	}
 
 	return nil
 }
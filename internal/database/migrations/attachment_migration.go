@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"fmt"
+
+	"genkit-ai-service/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// AttachmentMigration 消息附件表的迁移
+// 创建 chat_attachments 表，保存用户消息中的图片、文档、音频和视频片段
+type AttachmentMigration struct {
+	db *gorm.DB
+}
+
+// NewAttachmentMigration 创建消息附件迁移实例
+func NewAttachmentMigration(db *gorm.DB) *AttachmentMigration {
+	return &AttachmentMigration{
+		db: db,
+	}
+}
+
+// Up 执行迁移（创建表和索引）
+func (m *AttachmentMigration) Up() error {
+	if err := m.db.AutoMigrate(&model.ChatAttachment{}); err != nil {
+		return fmt.Errorf("自动迁移 chat_attachments 表失败: %w", err)
+	}
+
+	return nil
+}
+
+// Down 回滚迁移（删除表）
+func (m *AttachmentMigration) Down() error {
+	if err := m.db.Migrator().DropTable(&model.ChatAttachment{}); err != nil {
+		return fmt.Errorf("删除 chat_attachments 表失败: %w", err)
+	}
+
+	return nil
+}
+
+// GetName 获取迁移名称
+func (m *AttachmentMigration) GetName() string {
+	return "attachment_migration"
+}
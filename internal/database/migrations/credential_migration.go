@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"fmt"
+
+	"genkit-ai-service/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CredentialMigration 模型提供商凭证表的迁移
+// 创建 provider_credentials 表，按用户或工作空间保存加密后的提供商级和模型级凭证
+type CredentialMigration struct {
+	db *gorm.DB
+}
+
+// NewCredentialMigration 创建模型提供商凭证迁移实例
+func NewCredentialMigration(db *gorm.DB) *CredentialMigration {
+	return &CredentialMigration{
+		db: db,
+	}
+}
+
+// Up 执行迁移（创建表和唯一索引）
+func (m *CredentialMigration) Up() error {
+	if err := m.db.AutoMigrate(&model.ProviderCredential{}); err != nil {
+		return fmt.Errorf("自动迁移 provider_credentials 表失败: %w", err)
+	}
+
+	return nil
+}
+
+// Down 回滚迁移（删除表）
+func (m *CredentialMigration) Down() error {
+	if err := m.db.Migrator().DropTable(&model.ProviderCredential{}); err != nil {
+		return fmt.Errorf("删除 provider_credentials 表失败: %w", err)
+	}
+
+	return nil
+}
+
+// GetName 获取迁移名称
+func (m *CredentialMigration) GetName() string {
+	return "credential_migration"
+}
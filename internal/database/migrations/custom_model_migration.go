@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"fmt"
+
+	"genkit-ai-service/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CustomModelMigration 自定义模型表的迁移
+// 创建 custom_models 表，保存通过接口注册的自定义模型及其加密后的凭证
+type CustomModelMigration struct {
+	db *gorm.DB
+}
+
+// NewCustomModelMigration 创建自定义模型迁移实例
+func NewCustomModelMigration(db *gorm.DB) *CustomModelMigration {
+	return &CustomModelMigration{
+		db: db,
+	}
+}
+
+// Up 执行迁移（创建表和唯一索引）
+func (m *CustomModelMigration) Up() error {
+	if err := m.db.AutoMigrate(&model.CustomModel{}); err != nil {
+		return fmt.Errorf("自动迁移 custom_models 表失败: %w", err)
+	}
+
+	return nil
+}
+
+// Down 回滚迁移（删除表）
+func (m *CustomModelMigration) Down() error {
+	if err := m.db.Migrator().DropTable(&model.CustomModel{}); err != nil {
+		return fmt.Errorf("删除 custom_models 表失败: %w", err)
+	}
+
+	return nil
+}
+
+// GetName 获取迁移名称
+func (m *CustomModelMigration) GetName() string {
+	return "custom_model_migration"
+}
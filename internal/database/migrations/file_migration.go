@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"fmt"
+
+	"genkit-ai-service/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// FileMigration 上传文件表的迁移
+// 创建 files 表保存上传文件的元数据，文件内容保存在 Blob 存储中
+type FileMigration struct {
+	db *gorm.DB
+}
+
+// NewFileMigration 创建上传文件迁移实例
+func NewFileMigration(db *gorm.DB) *FileMigration {
+	return &FileMigration{
+		db: db,
+	}
+}
+
+// Up 执行迁移（创建表和索引）
+func (m *FileMigration) Up() error {
+	if err := m.db.AutoMigrate(&model.File{}); err != nil {
+		return fmt.Errorf("自动迁移 files 表失败: %w", err)
+	}
+
+	return nil
+}
+
+// Down 回滚迁移（删除表，Blob 存储中的文件内容保留）
+func (m *FileMigration) Down() error {
+	if err := m.db.Migrator().DropTable(&model.File{}); err != nil {
+		return fmt.Errorf("删除 files 表失败: %w", err)
+	}
+
+	return nil
+}
+
+// GetName 获取迁移名称
+func (m *FileMigration) GetName() string {
+	return "file_migration"
+}
@@ -0,0 +1,61 @@
+package migrations
+
+import (
+	"fmt"
+
+	"genkit-ai-service/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// KnowledgeMigration 知识库相关表的迁移
+// 创建 knowledge_bases、knowledge_documents 和 knowledge_chunks 表；
+// PostgreSQL 上先启用 pgvector 扩展，分块的向量保存为 vector 类型
+type KnowledgeMigration struct {
+	db *gorm.DB
+}
+
+// NewKnowledgeMigration 创建知识库迁移实例
+func NewKnowledgeMigration(db *gorm.DB) *KnowledgeMigration {
+	return &KnowledgeMigration{
+		db: db,
+	}
+}
+
+// Up 执行迁移（启用 pgvector 扩展并创建表）
+// 不同知识库可能使用不同维度的向量模型，向量列不限定维度，因此不创建 HNSW/IVFFlat 索引
+func (m *KnowledgeMigration) Up() error {
+	if m.db.Dialector.Name() == "postgres" {
+		if err := m.db.Exec("CREATE EXTENSION IF NOT EXISTS vector").Error; err != nil {
+			return fmt.Errorf("启用 pgvector 扩展失败: %w", err)
+		}
+	}
+
+	if err := m.db.AutoMigrate(
+		&model.KnowledgeBase{},
+		&model.KnowledgeDocument{},
+		&model.KnowledgeChunk{},
+	); err != nil {
+		return fmt.Errorf("自动迁移知识库表失败: %w", err)
+	}
+
+	return nil
+}
+
+// Down 回滚迁移（删除表，pgvector 扩展保留）
+func (m *KnowledgeMigration) Down() error {
+	if err := m.db.Migrator().DropTable(
+		&model.KnowledgeChunk{},
+		&model.KnowledgeDocument{},
+		&model.KnowledgeBase{},
+	); err != nil {
+		return fmt.Errorf("删除知识库表失败: %w", err)
+	}
+
+	return nil
+}
+
+// GetName 获取迁移名称
+func (m *KnowledgeMigration) GetName() string {
+	return "knowledge_migration"
+}
@@ -0,0 +1,65 @@
+package migrations
+
+import (
+	"fmt"
+
+	"genkit-ai-service/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// MessageBranchMigration 消息分支的迁移
+// 为已有消息回填 parent_id（指向同一会话中的上一条消息），并创建按父消息查询的索引
+type MessageBranchMigration struct {
+	db *gorm.DB
+}
+
+// NewMessageBranchMigration 创建消息分支迁移实例
+func NewMessageBranchMigration(db *gorm.DB) *MessageBranchMigration {
+	return &MessageBranchMigration{
+		db: db,
+	}
+}
+
+// Up 执行迁移（回填父消息ID、创建索引）
+func (m *MessageBranchMigration) Up() error {
+	// idx_message_parent 不存在时说明尚未回填，回填和建索引只执行一次
+	if m.db.Migrator().HasIndex(&model.ChatMessage{}, "idx_message_parent") {
+		return nil
+	}
+
+	// 回填：分支功能之前的会话是线性的，父消息即序列号相邻的上一条消息
+	if err := m.db.Exec(`
+		UPDATE chat_messages SET parent_id = prev.id
+		FROM chat_messages prev
+		WHERE chat_messages.parent_id IS NULL
+		AND prev.session_id = chat_messages.session_id
+		AND prev.sequence = chat_messages.sequence - 1
+	`).Error; err != nil {
+		return fmt.Errorf("回填父消息ID失败: %w", err)
+	}
+
+	// idx_message_parent: 用于查询同一父消息下的分支
+	if err := m.db.Exec(`
+		CREATE INDEX IF NOT EXISTS idx_message_parent
+		ON chat_messages(session_id, parent_id)
+	`).Error; err != nil {
+		return fmt.Errorf("创建 idx_message_parent 索引失败: %w", err)
+	}
+
+	return nil
+}
+
+// Down 回滚迁移（删除索引，回填的父消息ID保留）
+func (m *MessageBranchMigration) Down() error {
+	if err := m.db.Exec(`DROP INDEX IF EXISTS idx_message_parent`).Error; err != nil {
+		return fmt.Errorf("删除 idx_message_parent 索引失败: %w", err)
+	}
+
+	return nil
+}
+
+// GetName 获取迁移名称
+func (m *MessageBranchMigration) GetName() string {
+	return "message_branch_migration"
+}
@@ -0,0 +1,76 @@
+package migrations
+
+import (
+	"fmt"
+
+	"genkit-ai-service/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// MessageStatusMigration 消息状态字段的迁移
+// 为 chat_messages 表添加 status 字段和按状态过滤的索引，
+// 并将已有的带错误信息的 AI 回复回填为 failed 状态
+type MessageStatusMigration struct {
+	db *gorm.DB
+}
+
+// NewMessageStatusMigration 创建消息状态迁移实例
+func NewMessageStatusMigration(db *gorm.DB) *MessageStatusMigration {
+	return &MessageStatusMigration{
+		db: db,
+	}
+}
+
+// Up 执行迁移（添加字段、回填数据、创建索引）
+func (m *MessageStatusMigration) Up() error {
+	// 添加 status 字段，已有消息使用默认值 completed
+	if !m.db.Migrator().HasColumn(&model.ChatMessage{}, "Status") {
+		if err := m.db.Migrator().AddColumn(&model.ChatMessage{}, "Status"); err != nil {
+			return fmt.Errorf("添加 status 字段失败: %w", err)
+		}
+	}
+
+	// idx_message_status 不存在时说明尚未回填，回填和建索引只执行一次
+	if m.db.Migrator().HasIndex(&model.ChatMessage{}, "idx_message_status") {
+		return nil
+	}
+
+	// 回填：记录了错误信息的 AI 回复标记为生成失败
+	if err := m.db.Exec(`
+		UPDATE chat_messages SET status = ?
+		WHERE role = 'assistant' AND error IS NOT NULL AND error <> ''
+	`, model.MessageStatusFailed).Error; err != nil {
+		return fmt.Errorf("回填消息状态失败: %w", err)
+	}
+
+	// idx_message_status: 用于按状态过滤会话消息
+	if err := m.db.Exec(`
+		CREATE INDEX IF NOT EXISTS idx_message_status
+		ON chat_messages(session_id, status)
+	`).Error; err != nil {
+		return fmt.Errorf("创建 idx_message_status 索引失败: %w", err)
+	}
+
+	return nil
+}
+
+// Down 回滚迁移（删除索引和字段）
+func (m *MessageStatusMigration) Down() error {
+	if err := m.db.Exec(`DROP INDEX IF EXISTS idx_message_status`).Error; err != nil {
+		return fmt.Errorf("删除 idx_message_status 索引失败: %w", err)
+	}
+
+	if m.db.Migrator().HasColumn(&model.ChatMessage{}, "Status") {
+		if err := m.db.Migrator().DropColumn(&model.ChatMessage{}, "Status"); err != nil {
+			return fmt.Errorf("删除 status 字段失败: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetName 获取迁移名称
+func (m *MessageStatusMigration) GetName() string {
+	return "message_status_migration"
+}
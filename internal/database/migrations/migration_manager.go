@@ -63,6 +63,13 @@ func RunSessionMigrations(db *gorm.DB) error {
 	
 	// 注册会话管理迁移
 	manager.Register(NewSessionMigration(db))
+	manager.Register(NewMessageStatusMigration(db))
+	manager.Register(NewMessageBranchMigration(db))
+	manager.Register(NewAttachmentMigration(db))
+	manager.Register(NewFileMigration(db))
+	manager.Register(NewKnowledgeMigration(db))
+	manager.Register(NewCredentialMigration(db))
+	manager.Register(NewCustomModelMigration(db))
 	
 	// 执行迁移
 	if err := manager.Up(); err != nil {
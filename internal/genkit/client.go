@@ -2,11 +2,15 @@ package genkit
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"sync"
 
 	"github.com/firebase/genkit/go/ai"
 	"github.com/firebase/genkit/go/genkit"
 	"github.com/firebase/genkit/go/plugins/googlegenai"
+	"github.com/google/uuid"
 )
 
 // Client Genkit 客户端接口
@@ -20,14 +24,45 @@ type Client interface {
 	// Generate 生成内容
 	Generate(ctx context.Context, prompt string, options *GenerateOptions) (*GenerateResult, error)
 
+	// GenerateStream 流式生成内容，每收到一个文本片段调用一次 callback，
+	// 全部生成完成后返回完整结果
+	GenerateStream(ctx context.Context, prompt string, options *GenerateOptions, callback StreamCallback) (*GenerateResult, error)
+
 	// Close 关闭客户端
 	Close() error
 }
 
+// Embedder 文本向量化接口，由支持向量模型的客户端实现
+type Embedder interface {
+	// Embed 为一批文本生成向量，返回的向量与输入一一对应
+	Embed(ctx context.Context, inputs []string, options *EmbedOptions) (*EmbedResult, error)
+}
+
+// Reranker 文档重排序接口，由支持重排序模型的客户端实现
+type Reranker interface {
+	// Rerank 计算每个文档与查询的相关性，按得分从高到低返回
+	Rerank(ctx context.Context, query string, documents []string, options *RerankOptions) (*RerankResult, error)
+}
+
+// SpeechSynthesizer 语音合成接口，由支持语音合成模型的客户端实现
+type SpeechSynthesizer interface {
+	// Synthesize 将文本合成为音频，返回的音频流由调用方关闭
+	Synthesize(ctx context.Context, text string, options *SpeechOptions) (io.ReadCloser, error)
+}
+
+// Transcriber 语音识别接口，由支持语音识别模型的客户端实现
+type Transcriber interface {
+	// Transcribe 将音频转写为文本
+	Transcribe(ctx context.Context, audio io.Reader, options *TranscriptionOptions) (*TranscriptionResult, error)
+}
+
 // client Genkit 客户端实现
 type client struct {
 	config *Config
 	g      *genkit.Genkit
+
+	// toolsMu 保护工具定义，Genkit 中同名工具只能定义一次
+	toolsMu sync.Mutex
 }
 
 // NewClient 创建新的 Genkit 客户端
@@ -81,22 +116,302 @@ func (c *client) Generate(ctx context.Context, prompt string, options *GenerateO
 		return nil, fmt.Errorf("模型未初始化，请先通过 InitializeModel 设置模型")
 	}
 
-	if prompt == "" {
+	// 工具调用后继续生成时，历史以工具结果结尾，没有新的提示词
+	if prompt == "" && (options == nil || len(options.History) == 0) {
 		return nil, fmt.Errorf("提示词不能为空")
 	}
 
 	// 调用 Genkit 生成
-	// 注意：当前简化实现，暂不支持自定义 temperature、maxTokens 等参数
-	// 这些参数可以通过 genkit.WithDefaultModel 在初始化时设置
-	resp, err := genkit.Generate(ctx, c.g, ai.WithPrompt(prompt))
+	resp, err := genkit.Generate(ctx, c.g, c.buildRequestOptions(prompt, options)...)
 	if err != nil {
 		return nil, fmt.Errorf("生成内容失败: %w", err)
 	}
 
-	// 构建结果
+	return c.buildResult(resp, options), nil
+}
+
+// Embed 通过 Google AI 的向量模型为一批文本生成向量
+// Google AI 不返回 token 使用情况，结果中的 Usage 为 nil；不支持指定输出维度
+func (c *client) Embed(ctx context.Context, inputs []string, options *EmbedOptions) (*EmbedResult, error) {
+	if c.g == nil {
+		return nil, fmt.Errorf("模型未初始化，请先通过 InitializeModel 设置模型")
+	}
+
+	if options == nil || options.Model == "" {
+		return nil, fmt.Errorf("向量模型名称不能为空")
+	}
+
+	resp, err := genkit.Embed(ctx, c.g,
+		ai.WithEmbedderName("googleai/"+options.Model),
+		ai.WithTextDocs(inputs...),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("生成向量失败: %w", err)
+	}
+
+	if len(resp.Embeddings) != len(inputs) {
+		return nil, fmt.Errorf("生成向量失败: 期望 %d 个向量, 实际返回 %d 个", len(inputs), len(resp.Embeddings))
+	}
+
+	result := &EmbedResult{
+		Embeddings: make([][]float32, len(resp.Embeddings)),
+		Model:      options.Model,
+	}
+	for i, embedding := range resp.Embeddings {
+		result.Embeddings[i] = embedding.Embedding
+	}
+
+	return result, nil
+}
+
+// GenerateStream 流式生成内容
+func (c *client) GenerateStream(ctx context.Context, prompt string, options *GenerateOptions, callback StreamCallback) (*GenerateResult, error) {
+	if c.config == nil {
+		return nil, fmt.Errorf("客户端未初始化")
+	}
+
+	if c.g == nil {
+		return nil, fmt.Errorf("模型未初始化，请先通过 InitializeModel 设置模型")
+	}
+
+	// 工具调用后继续生成时，历史以工具结果结尾，没有新的提示词
+	if prompt == "" && (options == nil || len(options.History) == 0) {
+		return nil, fmt.Errorf("提示词不能为空")
+	}
+
+	if callback == nil {
+		return nil, fmt.Errorf("流式回调不能为空")
+	}
+
+	requestOptions := append(c.buildRequestOptions(prompt, options),
+		ai.WithStreaming(func(ctx context.Context, chunk *ai.ModelResponseChunk) error {
+			text := chunk.Text()
+			if text == "" {
+				return nil
+			}
+			return callback(ctx, text)
+		}),
+	)
+
+	resp, err := genkit.Generate(ctx, c.g, requestOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("流式生成内容失败: %w", err)
+	}
+
+	return c.buildResult(resp, options), nil
+}
+
+// modelName 返回本次请求使用的模型名称
+func (c *client) modelName(options *GenerateOptions) string {
+	if options != nil && options.Model != "" {
+		return options.Model
+	}
+	return c.config.Model
+}
+
+// buildRequestOptions 构建 Genkit 生成请求选项
+func (c *client) buildRequestOptions(prompt string, options *GenerateOptions) []ai.GenerateOption {
+	var requestOptions []ai.GenerateOption
+
+	if options == nil {
+		return append(requestOptions, ai.WithPrompt(prompt), ai.WithConfig(c.buildDefaultConfig()))
+	}
+
+	requestOptions = append(requestOptions, ai.WithConfig(c.buildGenerateConfig(options)))
+
+	// 指定了非默认模型时覆盖初始化时设置的默认模型
+	if options.Model != "" {
+		requestOptions = append(requestOptions, ai.WithModelName("googleai/"+options.Model))
+	}
+
+	if options.System != "" {
+		requestOptions = append(requestOptions, ai.WithSystem(options.System))
+	}
+
+	// 提示词附带媒体时作为多模态 user 消息放在历史之后
+	messages := toGenkitMessages(options.History)
+	if len(options.Media) > 0 {
+		messages = append(messages, toGenkitUserMessage(prompt, options.Media))
+	}
+	if len(messages) > 0 {
+		requestOptions = append(requestOptions, ai.WithMessages(messages...))
+	}
+
+	if prompt != "" && len(options.Media) == 0 {
+		requestOptions = append(requestOptions, ai.WithPrompt(prompt))
+	}
+
+	// 工具由调用方执行，Genkit 只返回模型的工具调用请求
+	if len(options.Tools) > 0 {
+		tools := make([]ai.ToolRef, 0, len(options.Tools))
+		for _, tool := range options.Tools {
+			tools = append(tools, c.defineTool(tool))
+		}
+		requestOptions = append(requestOptions, ai.WithTools(tools...), ai.WithReturnToolRequests(true))
+	}
+
+	// 结构化输出：由 Gemini 按 Schema 约束生成 JSON
+	if options.JSONSchema != nil {
+		requestOptions = append(requestOptions, ai.WithOutputSchema(options.JSONSchema), ai.WithOutputFormat(ai.OutputFormatJSON))
+	}
+
+	return requestOptions
+}
+
+// defineTool 在 Genkit 中定义工具，同名工具只定义一次
+// 工具由调用方执行，这里的实现不会被调用；Genkit 根据 Go 类型推导参数 Schema，
+// 因此参数的 JSON Schema 附加在描述中提供给模型
+func (c *client) defineTool(def ToolDefinition) ai.ToolRef {
+	c.toolsMu.Lock()
+	defer c.toolsMu.Unlock()
+
+	if tool := genkit.LookupTool(c.g, def.Name); tool != nil {
+		return tool
+	}
+
+	description := def.Description
+	if len(def.Parameters) > 0 {
+		if schema, err := json.Marshal(def.Parameters); err == nil {
+			description += "\n参数 JSON Schema: " + string(schema)
+		}
+	}
+
+	return genkit.DefineTool(c.g, def.Name, description,
+		func(ctx *ai.ToolContext, input map[string]any) (any, error) {
+			return nil, fmt.Errorf("工具 '%s' 由调用方执行", def.Name)
+		})
+}
+
+// buildGenerateConfig 根据生成选项构建模型配置
+// 未指定的 temperature、maxTokens 使用客户端默认值，topP、topK 未指定时由模型决定
+func (c *client) buildGenerateConfig(options *GenerateOptions) *ai.GenerationCommonConfig {
+	config := c.buildDefaultConfig()
+
+	if options == nil {
+		return config
+	}
+
+	if options.Temperature != nil {
+		config.Temperature = *options.Temperature
+	}
+
+	if options.MaxTokens != nil {
+		config.MaxOutputTokens = *options.MaxTokens
+	}
+
+	if options.TopP != nil {
+		config.TopP = *options.TopP
+	}
+
+	if options.TopK != nil {
+		config.TopK = *options.TopK
+	}
+
+	return config
+}
+
+// buildDefaultConfig 使用客户端默认参数构建模型配置
+func (c *client) buildDefaultConfig() *ai.GenerationCommonConfig {
+	return &ai.GenerationCommonConfig{
+		Temperature:     c.config.DefaultTemperature,
+		MaxOutputTokens: c.config.DefaultMaxTokens,
+	}
+}
+
+// toGenkitMessages 将历史消息转换为 Genkit 消息，assistant 角色对应 Genkit 的 model 角色
+// 连续的工具结果合并为一条 tool 消息，与上一条 model 消息的工具调用请求对应
+func toGenkitMessages(history []Message) []*ai.Message {
+	messages := make([]*ai.Message, 0, len(history))
+	for _, msg := range history {
+		switch msg.Role {
+		case RoleSystem:
+			messages = append(messages, ai.NewSystemTextMessage(msg.Content))
+		case RoleAssistant:
+			if len(msg.ToolCalls) == 0 {
+				messages = append(messages, ai.NewModelTextMessage(msg.Content))
+				continue
+			}
+			var parts []*ai.Part
+			if msg.Content != "" {
+				parts = append(parts, ai.NewTextPart(msg.Content))
+			}
+			for _, call := range msg.ToolCalls {
+				parts = append(parts, ai.NewToolRequestPart(&ai.ToolRequest{
+					Name:  call.Name,
+					Ref:   call.ID,
+					Input: decodeToolArguments(call.Arguments),
+				}))
+			}
+			messages = append(messages, ai.NewModelMessage(parts...))
+		case RoleTool:
+			part := ai.NewToolResponsePart(&ai.ToolResponse{
+				Name:   msg.Name,
+				Ref:    msg.ToolCallID,
+				Output: decodeToolOutput(msg.Content),
+			})
+			if n := len(messages); n > 0 && messages[n-1].Role == ai.RoleTool {
+				messages[n-1].Content = append(messages[n-1].Content, part)
+				continue
+			}
+			messages = append(messages, ai.NewMessage(ai.RoleTool, nil, part))
+		default:
+			messages = append(messages, toGenkitUserMessage(msg.Content, msg.Media))
+		}
+	}
+	return messages
+}
+
+// toGenkitUserMessage 构建 user 消息，媒体以 data URL 或地址作为媒体片段放在文本之后
+func toGenkitUserMessage(text string, media []Media) *ai.Message {
+	if len(media) == 0 {
+		return ai.NewUserTextMessage(text)
+	}
+
+	parts := make([]*ai.Part, 0, len(media)+1)
+	if text != "" {
+		parts = append(parts, ai.NewTextPart(text))
+	}
+	for _, m := range media {
+		parts = append(parts, ai.NewMediaPart(m.MimeType, m.DataURL()))
+	}
+	return ai.NewUserMessage(parts...)
+}
+
+// decodeToolArguments 解析 JSON 格式的工具调用参数，解析失败时返回空参数
+func decodeToolArguments(arguments string) map[string]any {
+	args := make(map[string]any)
+	if arguments != "" {
+		_ = json.Unmarshal([]byte(arguments), &args)
+	}
+	return args
+}
+
+// decodeToolOutput 将工具结果转换为 JSON 对象，Gemini 要求工具结果为对象，非对象结果包装在 result 字段中
+func decodeToolOutput(content string) map[string]any {
+	var output map[string]any
+	if err := json.Unmarshal([]byte(content), &output); err == nil && output != nil {
+		return output
+	}
+	return map[string]any{"result": content}
+}
+
+// buildResult 将 Genkit 响应转换为生成结果
+func (c *client) buildResult(resp *ai.ModelResponse, options *GenerateOptions) *GenerateResult {
 	result := &GenerateResult{
 		Text:  resp.Text(),
-		Model: c.config.Model,
+		Model: c.modelName(options),
+	}
+
+	// 提取工具调用请求，Gemini 不返回调用ID时生成一个
+	for _, req := range resp.ToolRequests() {
+		call := ToolCall{ID: req.Ref, Name: req.Name}
+		if call.ID == "" {
+			call.ID = "call_" + uuid.New().String()
+		}
+		if args, err := json.Marshal(req.Input); err == nil {
+			call.Arguments = string(args)
+		}
+		result.ToolCalls = append(result.ToolCalls, call)
 	}
 
 	// 提取 token 使用情况
@@ -108,7 +423,7 @@ func (c *client) Generate(ctx context.Context, prompt string, options *GenerateO
 		}
 	}
 
-	return result, nil
+	return result
 }
 
 // Close 关闭客户端
@@ -114,3 +114,58 @@ func TestBuildDefaultConfig(t *testing.T) {
 		t.Errorf("MaxOutputTokens = %v, want %v", config.MaxOutputTokens, 2000)
 	}
 }
+
+func TestToGenkitMessages_ToolCalls(t *testing.T) {
+	messages := toGenkitMessages([]Message{
+		{Role: RoleUser, Content: "杭州和上海的天气"},
+		{Role: RoleAssistant, ToolCalls: []ToolCall{
+			{ID: "call_1", Name: "get_weather", Arguments: `{"city":"杭州"}`},
+			{ID: "call_2", Name: "get_weather", Arguments: `{"city":"上海"}`},
+		}},
+		{Role: RoleTool, ToolCallID: "call_1", Name: "get_weather", Content: `{"weather":"晴"}`},
+		{Role: RoleTool, ToolCallID: "call_2", Name: "get_weather", Content: "小雨"},
+	})
+
+	if len(messages) != 3 {
+		t.Fatalf("消息数量 = %d, want 3（连续的工具结果应合并）", len(messages))
+	}
+
+	requests := messages[1].Content
+	if len(requests) != 2 || requests[0].ToolRequest == nil || requests[0].ToolRequest.Ref != "call_1" {
+		t.Errorf("工具调用请求不正确: %+v", requests)
+	}
+
+	responses := messages[2].Content
+	if len(responses) != 2 {
+		t.Fatalf("工具结果数量 = %d, want 2", len(responses))
+	}
+	if output, _ := responses[0].ToolResponse.Output.(map[string]any); output["weather"] != "晴" {
+		t.Errorf("JSON 对象结果应直接使用, 得到 %+v", responses[0].ToolResponse.Output)
+	}
+	if output, _ := responses[1].ToolResponse.Output.(map[string]any); output["result"] != "小雨" {
+		t.Errorf("非对象结果应包装在 result 中, 得到 %+v", responses[1].ToolResponse.Output)
+	}
+}
+
+func TestToGenkitMessages_Media(t *testing.T) {
+	messages := toGenkitMessages([]Message{
+		{Role: RoleUser, Content: "这张截图是什么", Media: []Media{
+			{MimeType: "image/png", Data: "aGVsbG8="},
+			{MimeType: "application/pdf", URL: "https://example.com/a.pdf"},
+		}},
+	})
+
+	if len(messages) != 1 {
+		t.Fatalf("消息数量 = %d, want 1", len(messages))
+	}
+	parts := messages[0].Content
+	if len(parts) != 3 || parts[0].Text != "这张截图是什么" {
+		t.Fatalf("消息片段不正确: %+v", parts)
+	}
+	if parts[1].ContentType != "image/png" || parts[1].Text != "data:image/png;base64,aGVsbG8=" {
+		t.Errorf("内联数据应以 data URL 发送, 得到 %+v", parts[1])
+	}
+	if parts[2].Text != "https://example.com/a.pdf" {
+		t.Errorf("URL 引用应直接使用, 得到 %+v", parts[2])
+	}
+}
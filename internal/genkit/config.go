@@ -1,11 +1,17 @@
 package genkit
 
+import "context"
+
 // Config Genkit 配置结构
 type Config struct {
 	// API 密钥
 	APIKey string
-	// 模型名称
+	// 模型名称（默认模型）
 	Model string
+	// 服务地址（OpenAI 兼容接口和 Azure OpenAI 使用）
+	BaseURL string
+	// API 版本（Azure OpenAI 使用）
+	APIVersion string
 	// 默认温度值
 	DefaultTemperature float64
 	// 默认最大 token 数
@@ -14,6 +20,12 @@ type Config struct {
 
 // GenerateOptions 生成选项
 type GenerateOptions struct {
+	// 模型名称（可选，为空时使用客户端默认模型）
+	Model string
+	// 系统提示词（可选）
+	System string
+	// 历史对话消息（可选），按时间正序排列，位于本次提示词之前
+	History []Message
 	// 温度值，控制输出的随机性 (0-2)
 	Temperature *float64
 	// 最大 token 数
@@ -22,6 +34,78 @@ type GenerateOptions struct {
 	TopP *float64
 	// Top-k 采样参数
 	TopK *int
+	// 可供模型调用的工具（可选），模型请求调用工具时由调用方执行并在下一次请求的历史中回传结果
+	Tools []ToolDefinition
+	// 回复的 JSON Schema（可选），仅用于原生支持结构化输出的模型，回复是否符合由调用方校验
+	JSONSchema map[string]interface{}
+	// 本次提示词附带的媒体（可选），模型是否支持由路由按模型特性校验
+	Media []Media
+}
+
+// 消息角色
+const (
+	// RoleSystem 系统消息
+	RoleSystem = "system"
+	// RoleUser 用户消息
+	RoleUser = "user"
+	// RoleAssistant 模型回复
+	RoleAssistant = "assistant"
+	// RoleTool 工具执行结果
+	RoleTool = "tool"
+)
+
+// Message 对话消息
+type Message struct {
+	// 角色 (system, user, assistant, tool)
+	Role string
+	// 消息内容，tool 消息为工具执行结果
+	Content string
+	// assistant 消息请求的工具调用
+	ToolCalls []ToolCall
+	// tool 消息对应的工具调用ID
+	ToolCallID string
+	// tool 消息对应的工具名称
+	Name string
+	// user 消息附带的媒体
+	Media []Media
+}
+
+// Media 消息中的媒体内容（图片、文档、音频、视频）
+type Media struct {
+	// MIME 类型
+	MimeType string
+	// base64 编码的内联数据，与 URL 二选一
+	Data string
+	// 媒体地址
+	URL string
+}
+
+// DataURL 返回媒体地址，内联数据以 data URL 表示
+func (m Media) DataURL() string {
+	if m.URL != "" {
+		return m.URL
+	}
+	return "data:" + m.MimeType + ";base64," + m.Data
+}
+
+// ToolDefinition 工具定义
+type ToolDefinition struct {
+	// 工具名称
+	Name string
+	// 工具描述
+	Description string
+	// 参数的 JSON Schema
+	Parameters map[string]interface{}
+}
+
+// ToolCall 模型请求的工具调用
+type ToolCall struct {
+	// 工具调用ID
+	ID string
+	// 工具名称
+	Name string
+	// JSON 格式的调用参数
+	Arguments string
 }
 
 // GenerateResult 生成结果
@@ -32,6 +116,8 @@ type GenerateResult struct {
 	Model string
 	// Token 使用情况
 	Usage *Usage
+	// 模型请求的工具调用，不为空时 Text 可能为空
+	ToolCalls []ToolCall
 }
 
 // Usage Token 使用情况
@@ -43,3 +129,79 @@ type Usage struct {
 	// 总 token 数
 	TotalTokens int
 }
+
+// EmbedOptions 向量化选项
+type EmbedOptions struct {
+	// 向量模型名称
+	Model string
+	// 输出向量的维度（可选），只有部分模型支持
+	Dimensions *int
+}
+
+// EmbedResult 向量化结果
+type EmbedResult struct {
+	// 与输入一一对应的向量
+	Embeddings [][]float32
+	// 使用的模型
+	Model string
+	// Token 使用情况，后端未返回时为 nil
+	Usage *Usage
+}
+
+// RerankOptions 重排序选项
+type RerankOptions struct {
+	// 重排序模型名称
+	Model string
+	// 返回得分最高的文档数量，0 表示返回全部
+	TopN int
+}
+
+// RerankScore 单个文档的相关性得分
+type RerankScore struct {
+	// 文档在输入列表中的下标
+	Index int
+	// 相关性得分，越大越相关
+	Score float64
+}
+
+// RerankResult 重排序结果
+type RerankResult struct {
+	// 按得分从高到低排列的文档得分
+	Results []RerankScore
+	// 使用的模型
+	Model string
+	// Token 使用情况，后端未返回时为 nil
+	Usage *Usage
+}
+
+// SpeechOptions 语音合成选项
+type SpeechOptions struct {
+	// 语音合成模型名称
+	Model string
+	// 音色
+	Voice string
+	// 输出音频格式（如 mp3、wav）
+	Format string
+}
+
+// TranscriptionOptions 语音识别选项
+type TranscriptionOptions struct {
+	// 语音识别模型名称
+	Model string
+	// 音频格式，即文件扩展名（如 mp3、wav）
+	Format string
+	// 音频的语言（可选），如 zh、en
+	Language string
+}
+
+// TranscriptionResult 语音识别结果
+type TranscriptionResult struct {
+	// 识别出的文本
+	Text string
+	// 使用的模型
+	Model string
+}
+
+// StreamCallback 流式生成回调，chunk 为本次收到的文本片段。
+// 返回错误时将中止生成。
+type StreamCallback func(ctx context.Context, chunk string) error
@@ -0,0 +1,49 @@
+package genkit
+
+import (
+	"context"
+	"fmt"
+)
+
+// DashScope OpenAI 兼容接口地址
+const (
+	// dashScopeBaseURL 中国站地址
+	dashScopeBaseURL = "https://dashscope.aliyuncs.com" + dashScopeCompatiblePath
+	// dashScopeInternationalBaseURL 国际站地址
+	dashScopeInternationalBaseURL = "https://dashscope-intl.aliyuncs.com" + dashScopeCompatiblePath
+)
+
+// NewClientFromCredentials 使用提供商 YAML 中凭证表单的变量创建并初始化后端客户端
+// 用于使用自身凭证的自定义模型；defaults 提供默认温度和最大 token 数，可为 nil
+// 支持 Azure OpenAI（openai_api_base、openai_api_key、openai_api_version）和
+// 通义千问（dashscope_api_key、use_international_endpoint）
+func NewClientFromCredentials(ctx context.Context, providerID string, credentials map[string]string, defaults *Config) (Client, error) {
+	config := &Config{}
+	if defaults != nil {
+		config.DefaultTemperature = defaults.DefaultTemperature
+		config.DefaultMaxTokens = defaults.DefaultMaxTokens
+	}
+
+	var client Client
+	switch providerID {
+	case ProviderAzureOpenAI:
+		client = NewAzureOpenAIClient()
+		config.APIKey = credentials["openai_api_key"]
+		config.BaseURL = credentials["openai_api_base"]
+		config.APIVersion = credentials["openai_api_version"]
+	case ProviderTongyi:
+		client = NewDashScopeClient()
+		config.APIKey = credentials["dashscope_api_key"]
+		config.BaseURL = dashScopeBaseURL
+		if credentials["use_international_endpoint"] == "true" {
+			config.BaseURL = dashScopeInternationalBaseURL
+		}
+	default:
+		return nil, fmt.Errorf("模型提供商 '%s' 不支持使用凭证创建客户端", providerID)
+	}
+
+	if err := client.Initialize(ctx, config); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
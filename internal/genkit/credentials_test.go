@@ -0,0 +1,43 @@
+package genkit
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewClientFromCredentials(t *testing.T) {
+	ctx := context.Background()
+	defaults := &Config{DefaultTemperature: 0.5, DefaultMaxTokens: 1024}
+
+	client, err := NewClientFromCredentials(ctx, ProviderAzureOpenAI, map[string]string{
+		"openai_api_base":    "https://example.openai.azure.com",
+		"openai_api_key":     "key",
+		"openai_api_version": "2024-10-21",
+	}, defaults)
+	if err != nil {
+		t.Fatalf("NewClientFromCredentials() error = %v", err)
+	}
+	azure := client.(*openAIClient)
+	if !azure.azure || azure.config.BaseURL != "https://example.openai.azure.com" || azure.config.APIVersion != "2024-10-21" || azure.config.DefaultMaxTokens != 1024 {
+		t.Errorf("Azure OpenAI 客户端配置不正确: %+v", azure.config)
+	}
+
+	client, err = NewClientFromCredentials(ctx, ProviderTongyi, map[string]string{
+		"dashscope_api_key":          "key",
+		"use_international_endpoint": "true",
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewClientFromCredentials() error = %v", err)
+	}
+	if baseURL := client.(*dashScopeClient).config.BaseURL; baseURL != dashScopeInternationalBaseURL {
+		t.Errorf("期望使用国际站地址，实际 %s", baseURL)
+	}
+
+	// 缺少必填凭证或不支持的提供商
+	if _, err := NewClientFromCredentials(ctx, ProviderAzureOpenAI, map[string]string{"openai_api_key": "key"}, nil); err == nil {
+		t.Error("缺少服务地址时应返回错误")
+	}
+	if _, err := NewClientFromCredentials(ctx, ProviderGemini, map[string]string{"google_api_key": "key"}, nil); err == nil {
+		t.Error("不支持的提供商应返回错误")
+	}
+}
@@ -0,0 +1,464 @@
+package genkit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// dashScopeCompatiblePath DashScope OpenAI 兼容模式的路径后缀
+const dashScopeCompatiblePath = "/compatible-mode/v1"
+
+// dashScopeRerankPath DashScope 原生文本重排序接口路径
+const dashScopeRerankPath = "/api/v1/services/rerank/text-rerank/text-rerank"
+
+// dashScopeInferencePath DashScope 原生 WebSocket 推理接口路径（语音合成）
+const dashScopeInferencePath = "/api-ws/v1/inference"
+
+// dashScopeSampleRate 语音合成和语音识别的采样率
+const dashScopeSampleRate = 16000
+
+// dashScopeAudioChunkSize 语音识别时每个二进制帧发送的音频字节数
+const dashScopeAudioChunkSize = 8 * 1024
+
+// dashScopeClient 通义千问 DashScope 客户端
+// 对话和向量化使用 OpenAI 兼容模式，兼容模式不提供的重排序、语音合成和语音识别使用原生接口
+type dashScopeClient struct {
+	*openAIClient
+	dialer *websocket.Dialer
+}
+
+// NewDashScopeClient 创建通义千问 DashScope 客户端
+func NewDashScopeClient() Client {
+	return &dashScopeClient{
+		openAIClient: NewOpenAIClient().(*openAIClient),
+		dialer:       websocket.DefaultDialer,
+	}
+}
+
+// dashScopeRerankRequest 重排序请求
+type dashScopeRerankRequest struct {
+	Model      string                    `json:"model"`
+	Input      dashScopeRerankInput      `json:"input"`
+	Parameters dashScopeRerankParameters `json:"parameters"`
+}
+
+type dashScopeRerankInput struct {
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+}
+
+type dashScopeRerankParameters struct {
+	ReturnDocuments bool `json:"return_documents"`
+	TopN            int  `json:"top_n,omitempty"`
+}
+
+// dashScopeRerankResponse 重排序响应
+type dashScopeRerankResponse struct {
+	Output struct {
+		Results []struct {
+			Index          int     `json:"index"`
+			RelevanceScore float64 `json:"relevance_score"`
+		} `json:"results"`
+	} `json:"output"`
+	Usage *struct {
+		TotalTokens int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// Rerank 计算文档与查询的相关性
+func (c *dashScopeClient) Rerank(ctx context.Context, query string, documents []string, options *RerankOptions) (*RerankResult, error) {
+	if c.config == nil {
+		return nil, fmt.Errorf("客户端未初始化")
+	}
+
+	if options == nil || options.Model == "" {
+		return nil, fmt.Errorf("重排序模型名称不能为空")
+	}
+
+	req := &dashScopeRerankRequest{
+		Model: options.Model,
+		Input: dashScopeRerankInput{
+			Query:     query,
+			Documents: documents,
+		},
+		Parameters: dashScopeRerankParameters{TopN: options.TopN},
+	}
+
+	httpResp, err := c.post(ctx, c.rerankEndpoint(), req, false)
+	if err != nil {
+		return nil, fmt.Errorf("重排序失败: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	var resp dashScopeRerankResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	result := &RerankResult{
+		Results: make([]RerankScore, 0, len(resp.Output.Results)),
+		Model:   req.Model,
+	}
+	seen := make(map[int]bool, len(resp.Output.Results))
+	for _, item := range resp.Output.Results {
+		if item.Index < 0 || item.Index >= len(documents) || seen[item.Index] {
+			return nil, fmt.Errorf("重排序失败: 响应中的文档序号 %d 无效", item.Index)
+		}
+		seen[item.Index] = true
+		result.Results = append(result.Results, RerankScore{Index: item.Index, Score: item.RelevanceScore})
+	}
+	if resp.Usage != nil {
+		result.Usage = &Usage{
+			PromptTokens: resp.Usage.TotalTokens,
+			TotalTokens:  resp.Usage.TotalTokens,
+		}
+	}
+
+	return result, nil
+}
+
+// dashScopeTaskMessage WebSocket 推理任务消息
+type dashScopeTaskMessage struct {
+	Header  dashScopeTaskHeader `json:"header"`
+	Payload interface{}         `json:"payload,omitempty"`
+}
+
+type dashScopeTaskHeader struct {
+	Action       string `json:"action,omitempty"`
+	Event        string `json:"event,omitempty"`
+	TaskID       string `json:"task_id"`
+	Streaming    string `json:"streaming,omitempty"`
+	ErrorCode    string `json:"error_code,omitempty"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+// dashScopeSpeechPayload 语音合成任务参数
+type dashScopeSpeechPayload struct {
+	Model      string                    `json:"model"`
+	TaskGroup  string                    `json:"task_group"`
+	Task       string                    `json:"task"`
+	Function   string                    `json:"function"`
+	Input      dashScopeSpeechInput      `json:"input"`
+	Parameters dashScopeSpeechParameters `json:"parameters"`
+}
+
+type dashScopeSpeechInput struct {
+	Text string `json:"text"`
+}
+
+type dashScopeSpeechParameters struct {
+	TextType   string `json:"text_type"`
+	Format     string `json:"format"`
+	SampleRate int    `json:"sample_rate"`
+}
+
+// Synthesize 将文本合成为音频
+// DashScope 的 sambert 语音合成以音色作为模型名称，通过 WebSocket 以二进制帧流式返回音频。
+// 收到任务开始事件后返回，音频在读取时从连接接收
+func (c *dashScopeClient) Synthesize(ctx context.Context, text string, options *SpeechOptions) (io.ReadCloser, error) {
+	if c.config == nil {
+		return nil, fmt.Errorf("客户端未初始化")
+	}
+
+	if options == nil || options.Model == "" {
+		return nil, fmt.Errorf("语音合成模型名称不能为空")
+	}
+
+	voice := options.Voice
+	if voice == "" {
+		voice = options.Model
+	}
+	format := options.Format
+	if format == "" {
+		format = "mp3"
+	}
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("语音合成失败: %w", err)
+	}
+
+	// 取消请求时关闭连接，使阻塞的读取立即返回
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+
+	taskID := uuid.NewString()
+	runTask := &dashScopeTaskMessage{
+		Header: dashScopeTaskHeader{Action: "run-task", TaskID: taskID, Streaming: "out"},
+		Payload: &dashScopeSpeechPayload{
+			Model:     voice,
+			TaskGroup: "audio",
+			Task:      "tts",
+			Function:  "SpeechSynthesizer",
+			Input:     dashScopeSpeechInput{Text: text},
+			Parameters: dashScopeSpeechParameters{
+				TextType:   "PlainText",
+				Format:     format,
+				SampleRate: dashScopeSampleRate,
+			},
+		},
+	}
+	// 任务失败时直接返回错误
+	if err := startDashScopeTask(conn, runTask); err != nil {
+		stop()
+		conn.Close()
+		return nil, fmt.Errorf("语音合成失败: %w", err)
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		defer stop()
+		defer conn.Close()
+		for {
+			event, err := readDashScopeEvent(conn)
+			if err != nil {
+				writer.CloseWithError(fmt.Errorf("语音合成失败: %w", err))
+				return
+			}
+			if event.Name == "task-finished" {
+				writer.Close()
+				return
+			}
+			if len(event.Audio) > 0 {
+				if _, err := writer.Write(event.Audio); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	return &dashScopeAudio{PipeReader: reader, conn: conn}, nil
+}
+
+// dashScopeRecognitionPayload 语音识别任务参数
+type dashScopeRecognitionPayload struct {
+	Model      string                         `json:"model"`
+	TaskGroup  string                         `json:"task_group"`
+	Task       string                         `json:"task"`
+	Function   string                         `json:"function"`
+	Input      struct{}                       `json:"input"`
+	Parameters dashScopeRecognitionParameters `json:"parameters"`
+}
+
+type dashScopeRecognitionParameters struct {
+	Format        string   `json:"format"`
+	SampleRate    int      `json:"sample_rate"`
+	LanguageHints []string `json:"language_hints,omitempty"`
+}
+
+// dashScopeRecognitionOutput 语音识别 result-generated 事件的 payload
+type dashScopeRecognitionOutput struct {
+	Output struct {
+		Sentence struct {
+			Text string `json:"text"`
+			// 句子结束时间，中间结果为空
+			EndTime     *int64 `json:"end_time"`
+			SentenceEnd bool   `json:"sentence_end"`
+		} `json:"sentence"`
+	} `json:"output"`
+}
+
+// Transcribe 将音频转写为文本
+// DashScope 的 paraformer 实时语音识别通过 WebSocket 双向流式交互：以二进制帧发送音频，
+// 发送完毕后结束任务，识别结果按句子以 result-generated 事件返回，只保留每个句子的最终结果
+func (c *dashScopeClient) Transcribe(ctx context.Context, audio io.Reader, options *TranscriptionOptions) (*TranscriptionResult, error) {
+	if c.config == nil {
+		return nil, fmt.Errorf("客户端未初始化")
+	}
+
+	if options == nil || options.Model == "" {
+		return nil, fmt.Errorf("语音识别模型名称不能为空")
+	}
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("语音识别失败: %w", err)
+	}
+	defer conn.Close()
+
+	// 取消请求时关闭连接，使阻塞的读取立即返回
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	taskID := uuid.NewString()
+	payload := &dashScopeRecognitionPayload{
+		Model:     options.Model,
+		TaskGroup: "audio",
+		Task:      "asr",
+		Function:  "recognition",
+		Parameters: dashScopeRecognitionParameters{
+			Format:     options.Format,
+			SampleRate: dashScopeSampleRate,
+		},
+	}
+	if options.Language != "" {
+		payload.Parameters.LanguageHints = []string{options.Language}
+	}
+	runTask := &dashScopeTaskMessage{
+		Header:  dashScopeTaskHeader{Action: "run-task", TaskID: taskID, Streaming: "duplex"},
+		Payload: payload,
+	}
+	if err := startDashScopeTask(conn, runTask); err != nil {
+		return nil, fmt.Errorf("语音识别失败: %w", err)
+	}
+
+	// 发送音频的同时接收识别结果，发送失败时关闭连接使接收结束
+	go func() {
+		buf := make([]byte, dashScopeAudioChunkSize)
+		for {
+			n, err := audio.Read(buf)
+			if n > 0 {
+				if err := conn.WriteMessage(websocket.BinaryMessage, buf[:n]); err != nil {
+					conn.Close()
+					return
+				}
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				conn.Close()
+				return
+			}
+		}
+		finishTask := &dashScopeTaskMessage{
+			Header:  dashScopeTaskHeader{Action: "finish-task", TaskID: taskID, Streaming: "duplex"},
+			Payload: map[string]interface{}{"input": struct{}{}},
+		}
+		if err := conn.WriteJSON(finishTask); err != nil {
+			conn.Close()
+		}
+	}()
+
+	var sentences []string
+	for {
+		event, err := readDashScopeEvent(conn)
+		if err != nil {
+			return nil, fmt.Errorf("语音识别失败: %w", err)
+		}
+		if event.Name == "task-finished" {
+			break
+		}
+		if event.Name != "result-generated" {
+			continue
+		}
+
+		var output dashScopeRecognitionOutput
+		if err := json.Unmarshal(event.Payload, &output); err != nil {
+			return nil, fmt.Errorf("解析识别结果失败: %w", err)
+		}
+		sentence := output.Output.Sentence
+		if sentence.SentenceEnd || sentence.EndTime != nil {
+			sentences = append(sentences, sentence.Text)
+		}
+	}
+
+	return &TranscriptionResult{
+		Text:  strings.Join(sentences, ""),
+		Model: options.Model,
+	}, nil
+}
+
+// dial 建立原生 WebSocket 推理连接
+func (c *dashScopeClient) dial(ctx context.Context) (*websocket.Conn, error) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+c.config.APIKey)
+	conn, httpResp, err := c.dialer.DialContext(ctx, c.inferenceEndpoint(), header)
+	if err != nil {
+		if httpResp != nil {
+			return nil, fmt.Errorf("HTTP %d: %w", httpResp.StatusCode, err)
+		}
+		return nil, err
+	}
+	return conn, nil
+}
+
+// startDashScopeTask 发送 run-task 指令并等待任务开始
+func startDashScopeTask(conn *websocket.Conn, runTask *dashScopeTaskMessage) error {
+	if err := conn.WriteJSON(runTask); err != nil {
+		return err
+	}
+	for {
+		event, err := readDashScopeEvent(conn)
+		if err != nil {
+			return err
+		}
+		if event.Name == "task-started" {
+			return nil
+		}
+	}
+}
+
+// dashScopeEvent 从 WebSocket 连接读取的一条消息
+type dashScopeEvent struct {
+	// 事件名称，二进制帧为空
+	Name string
+	// 二进制帧携带的音频数据
+	Audio []byte
+	// 事件的 payload
+	Payload json.RawMessage
+}
+
+// readDashScopeEvent 读取一条 WebSocket 消息
+// 二进制帧为音频数据；文本帧为任务事件，task-failed 事件转换为错误
+func readDashScopeEvent(conn *websocket.Conn) (*dashScopeEvent, error) {
+	messageType, data, err := conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	if messageType == websocket.BinaryMessage {
+		return &dashScopeEvent{Audio: data}, nil
+	}
+
+	var message struct {
+		Header  dashScopeTaskHeader `json:"header"`
+		Payload json.RawMessage     `json:"payload"`
+	}
+	if err := json.Unmarshal(data, &message); err != nil {
+		return nil, fmt.Errorf("解析事件失败: %w", err)
+	}
+	if message.Header.Event == "task-failed" {
+		return nil, fmt.Errorf("%s: %s", message.Header.ErrorCode, message.Header.ErrorMessage)
+	}
+	return &dashScopeEvent{Name: message.Header.Event, Payload: message.Payload}, nil
+}
+
+// dashScopeAudio 语音合成的音频流，关闭时同时关闭 WebSocket 连接
+type dashScopeAudio struct {
+	*io.PipeReader
+	conn *websocket.Conn
+}
+
+// Close 关闭音频流和连接
+func (a *dashScopeAudio) Close() error {
+	a.PipeReader.Close()
+	return a.conn.Close()
+}
+
+// nativeBaseURL 由兼容模式地址推导原生接口的根地址
+func (c *dashScopeClient) nativeBaseURL() string {
+	baseURL := strings.TrimRight(c.config.BaseURL, "/")
+	return strings.TrimSuffix(baseURL, dashScopeCompatiblePath)
+}
+
+// rerankEndpoint 原生重排序接口地址
+func (c *dashScopeClient) rerankEndpoint() string {
+	return c.nativeBaseURL() + dashScopeRerankPath
+}
+
+// inferenceEndpoint 原生 WebSocket 推理接口地址
+func (c *dashScopeClient) inferenceEndpoint() string {
+	baseURL := c.nativeBaseURL()
+	if rest, ok := strings.CutPrefix(baseURL, "https://"); ok {
+		baseURL = "wss://" + rest
+	} else if rest, ok := strings.CutPrefix(baseURL, "http://"); ok {
+		baseURL = "ws://" + rest
+	}
+	return baseURL + dashScopeInferencePath
+}
@@ -0,0 +1,221 @@
+package genkit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestDashScopeClient_Rerank(t *testing.T) {
+	var received dashScopeRerankRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != dashScopeRerankPath {
+			t.Errorf("请求路径 = %s, want %s", r.URL.Path, dashScopeRerankPath)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Authorization = %s, want Bearer test-key", got)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Fatalf("解析请求失败: %v", err)
+		}
+		fmt.Fprint(w, `{"output":{"results":[{"index":2,"relevance_score":0.9},{"index":0,"relevance_score":0.3}]},"usage":{"total_tokens":12}}`)
+	}))
+	defer server.Close()
+
+	c := NewDashScopeClient()
+	// 配置的是兼容模式地址，重排序需要改用原生接口
+	if err := c.Initialize(context.Background(), &Config{APIKey: "test-key", BaseURL: server.URL + "/compatible-mode/v1/"}); err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+
+	reranker, ok := c.(Reranker)
+	if !ok {
+		t.Fatal("DashScope 客户端应支持重排序")
+	}
+	if _, ok := c.(Embedder); !ok {
+		t.Fatal("DashScope 客户端应支持向量化")
+	}
+
+	result, err := reranker.Rerank(context.Background(), "查询", []string{"a", "b", "c"}, &RerankOptions{Model: "gte-rerank", TopN: 2})
+	if err != nil {
+		t.Fatalf("Rerank() error = %v", err)
+	}
+	if received.Model != "gte-rerank" || received.Input.Query != "查询" || len(received.Input.Documents) != 3 || received.Parameters.TopN != 2 {
+		t.Errorf("请求参数不正确: %+v", received)
+	}
+	if len(result.Results) != 2 || result.Results[0].Index != 2 || result.Results[0].Score != 0.9 {
+		t.Errorf("重排序结果不正确: %+v", result.Results)
+	}
+	if result.Usage == nil || result.Usage.TotalTokens != 12 {
+		t.Errorf("Usage = %+v, want TotalTokens 12", result.Usage)
+	}
+
+	t.Run("文档序号越界", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"output":{"results":[{"index":5,"relevance_score":0.9}]}}`)
+		}))
+		defer server.Close()
+
+		c := NewDashScopeClient()
+		_ = c.Initialize(context.Background(), &Config{APIKey: "key", BaseURL: server.URL})
+		if _, err := c.(Reranker).Rerank(context.Background(), "q", []string{"a"}, &RerankOptions{Model: "m"}); err == nil {
+			t.Error("期望返回错误")
+		}
+	})
+}
+
+func TestDashScopeClient_Synthesize(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	var received dashScopeTaskMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != dashScopeInferencePath {
+			t.Errorf("请求路径 = %s, want %s", r.URL.Path, dashScopeInferencePath)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Authorization = %s, want Bearer test-key", got)
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("升级连接失败: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		var payload dashScopeSpeechPayload
+		received.Payload = &payload
+		if err := conn.ReadJSON(&received); err != nil {
+			t.Errorf("读取任务失败: %v", err)
+			return
+		}
+		if payload.Input.Text == "失败" {
+			conn.WriteJSON(dashScopeTaskMessage{Header: dashScopeTaskHeader{Event: "task-failed", ErrorCode: "InvalidParameter", ErrorMessage: "bad voice"}})
+			return
+		}
+		conn.WriteJSON(dashScopeTaskMessage{Header: dashScopeTaskHeader{Event: "task-started", TaskID: received.Header.TaskID}})
+		conn.WriteMessage(websocket.BinaryMessage, []byte("ab"))
+		conn.WriteJSON(dashScopeTaskMessage{Header: dashScopeTaskHeader{Event: "result-generated", TaskID: received.Header.TaskID}})
+		conn.WriteMessage(websocket.BinaryMessage, []byte("cd"))
+		conn.WriteJSON(dashScopeTaskMessage{Header: dashScopeTaskHeader{Event: "task-finished", TaskID: received.Header.TaskID}})
+	}))
+	defer server.Close()
+
+	c := NewDashScopeClient()
+	if err := c.Initialize(context.Background(), &Config{APIKey: "test-key", BaseURL: server.URL + "/compatible-mode/v1"}); err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+
+	audio, err := c.(SpeechSynthesizer).Synthesize(context.Background(), "你好", &SpeechOptions{Model: "tts-1", Voice: "sambert-zhiru-v1", Format: "wav"})
+	if err != nil {
+		t.Fatalf("Synthesize() error = %v", err)
+	}
+	data, err := io.ReadAll(audio)
+	audio.Close()
+	if err != nil {
+		t.Fatalf("读取音频失败: %v", err)
+	}
+	if string(data) != "abcd" {
+		t.Errorf("音频 = %q, want abcd", data)
+	}
+
+	// sambert 以音色作为模型名称
+	payload := received.Payload.(*dashScopeSpeechPayload)
+	if received.Header.Action != "run-task" || payload.Model != "sambert-zhiru-v1" || payload.Parameters.Format != "wav" || payload.Input.Text != "你好" {
+		t.Errorf("任务参数不正确: %+v %+v", received.Header, payload)
+	}
+
+	t.Run("任务失败", func(t *testing.T) {
+		_, err := c.(SpeechSynthesizer).Synthesize(context.Background(), "失败", &SpeechOptions{Model: "tts-1", Voice: "unknown"})
+		if err == nil || !strings.Contains(err.Error(), "bad voice") {
+			t.Errorf("期望返回上游错误, 得到 %v", err)
+		}
+	})
+}
+
+func TestDashScopeClient_Transcribe(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	var received dashScopeTaskMessage
+	var audio []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("升级连接失败: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		var payload dashScopeRecognitionPayload
+		received.Payload = &payload
+		if err := conn.ReadJSON(&received); err != nil {
+			t.Errorf("读取任务失败: %v", err)
+			return
+		}
+		if payload.Parameters.Format == "amr" {
+			conn.WriteJSON(dashScopeTaskMessage{Header: dashScopeTaskHeader{Event: "task-failed", ErrorCode: "InvalidParameter", ErrorMessage: "unsupported format"}})
+			return
+		}
+		taskID := received.Header.TaskID
+		conn.WriteJSON(dashScopeTaskMessage{Header: dashScopeTaskHeader{Event: "task-started", TaskID: taskID}})
+
+		// 接收音频直到 finish-task 指令
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				t.Errorf("读取音频失败: %v", err)
+				return
+			}
+			if messageType == websocket.TextMessage {
+				break
+			}
+			audio = append(audio, data...)
+		}
+
+		// 中间结果没有结束时间，只保留每个句子的最终结果
+		results := []string{
+			`{"sentence":{"text":"你好","end_time":null}}`,
+			`{"sentence":{"text":"你好。","end_time":800}}`,
+			`{"sentence":{"text":"今天","end_time":null,"sentence_end":false}}`,
+			`{"sentence":{"text":"今天天气很好。","end_time":null,"sentence_end":true}}`,
+		}
+		for _, result := range results {
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"header":{"event":"result-generated","task_id":"`+taskID+`"},"payload":{"output":`+result+`}}`))
+		}
+		conn.WriteJSON(dashScopeTaskMessage{Header: dashScopeTaskHeader{Event: "task-finished", TaskID: taskID}})
+	}))
+	defer server.Close()
+
+	c := NewDashScopeClient()
+	if err := c.Initialize(context.Background(), &Config{APIKey: "test-key", BaseURL: server.URL + "/compatible-mode/v1"}); err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+
+	input := strings.Repeat("a", dashScopeAudioChunkSize+10)
+	result, err := c.(Transcriber).Transcribe(context.Background(), strings.NewReader(input), &TranscriptionOptions{Model: "paraformer-realtime-v2", Format: "wav", Language: "zh"})
+	if err != nil {
+		t.Fatalf("Transcribe() error = %v", err)
+	}
+	if result.Text != "你好。今天天气很好。" {
+		t.Errorf("Text = %q", result.Text)
+	}
+	if string(audio) != input {
+		t.Errorf("服务端收到 %d 字节音频, want %d", len(audio), len(input))
+	}
+	payload := received.Payload.(*dashScopeRecognitionPayload)
+	if received.Header.Streaming != "duplex" || payload.Model != "paraformer-realtime-v2" || payload.Task != "asr" ||
+		payload.Parameters.Format != "wav" || len(payload.Parameters.LanguageHints) != 1 {
+		t.Errorf("任务参数不正确: %+v %+v", received.Header, payload)
+	}
+
+	t.Run("任务失败", func(t *testing.T) {
+		_, err := c.(Transcriber).Transcribe(context.Background(), strings.NewReader("a"), &TranscriptionOptions{Model: "paraformer-realtime-v2", Format: "amr"})
+		if err == nil || !strings.Contains(err.Error(), "unsupported format") {
+			t.Errorf("期望返回上游错误, 得到 %v", err)
+		}
+	})
+}
@@ -0,0 +1,748 @@
+package genkit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxToolCallsPerTurn 单次回复中工具调用的最大数量，流式分片的 index 超出该范围时视为非法响应
+const maxToolCallsPerTurn = 128
+
+// openAIClient OpenAI 协议客户端
+// 同时支持 OpenAI 兼容接口（如通义千问 DashScope 兼容模式）和 Azure OpenAI
+type openAIClient struct {
+	config     *Config
+	azure      bool
+	httpClient *http.Client
+}
+
+// NewOpenAIClient 创建 OpenAI 兼容接口客户端
+func NewOpenAIClient() Client {
+	return &openAIClient{httpClient: http.DefaultClient}
+}
+
+// NewAzureOpenAIClient 创建 Azure OpenAI 客户端
+// Azure OpenAI 按部署名称路由请求，模型名称即部署名称
+func NewAzureOpenAIClient() Client {
+	return &openAIClient{azure: true, httpClient: http.DefaultClient}
+}
+
+// openAIMessage 对话消息
+// 附带媒体的 user 消息使用 Parts，序列化时 content 为片段数组
+type openAIMessage struct {
+	Role       string              `json:"role"`
+	Content    string              `json:"content"`
+	Parts      []openAIContentPart `json:"-"`
+	ToolCalls  []openAIToolCall    `json:"tool_calls,omitempty"`
+	ToolCallID string              `json:"tool_call_id,omitempty"`
+}
+
+// MarshalJSON 序列化消息，有 Parts 时 content 为片段数组
+func (m openAIMessage) MarshalJSON() ([]byte, error) {
+	type message openAIMessage
+	if len(m.Parts) == 0 {
+		return json.Marshal(message(m))
+	}
+	return json.Marshal(struct {
+		message
+		Content []openAIContentPart `json:"content"`
+	}{message: message(m), Content: m.Parts})
+}
+
+// openAIContentPart 多模态消息片段
+type openAIContentPart struct {
+	Type       string            `json:"type"`
+	Text       string            `json:"text,omitempty"`
+	ImageURL   *openAIImageURL   `json:"image_url,omitempty"`
+	InputAudio *openAIInputAudio `json:"input_audio,omitempty"`
+	File       *openAIFile       `json:"file,omitempty"`
+}
+
+// openAIImageURL 图片片段，URL 可以是 data URL
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+// openAIInputAudio 音频片段，只支持 base64 内联数据
+type openAIInputAudio struct {
+	Data   string `json:"data"`
+	Format string `json:"format"`
+}
+
+// openAIFile 文件片段，FileData 为 data URL
+type openAIFile struct {
+	Filename string `json:"filename"`
+	FileData string `json:"file_data"`
+}
+
+// openAITool 工具定义
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+// openAIFunction 函数工具定义
+type openAIFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// openAIToolCall 工具调用（流式响应中按 Index 分片返回）
+type openAIToolCall struct {
+	Index    *int               `json:"index,omitempty"`
+	ID       string             `json:"id,omitempty"`
+	Type     string             `json:"type,omitempty"`
+	Function openAIFunctionCall `json:"function"`
+}
+
+// openAIFunctionCall 函数调用
+type openAIFunctionCall struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}
+
+// openAIResponseFormat 回复格式
+type openAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+// openAIJSONSchema 结构化输出的 JSON Schema
+type openAIJSONSchema struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema"`
+}
+
+// openAIStreamOptions 流式选项
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// openAIChatRequest 对话补全请求
+type openAIChatRequest struct {
+	Model          string                `json:"model,omitempty"`
+	Messages       []openAIMessage       `json:"messages"`
+	Temperature    *float64              `json:"temperature,omitempty"`
+	MaxTokens      *int                  `json:"max_tokens,omitempty"`
+	TopP           *float64              `json:"top_p,omitempty"`
+	TopK           *int                  `json:"top_k,omitempty"`
+	Tools          []openAITool          `json:"tools,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+	Stream         bool                  `json:"stream,omitempty"`
+	StreamOptions  *openAIStreamOptions  `json:"stream_options,omitempty"`
+}
+
+// openAIUsage Token 使用情况
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// openAIChoice 候选结果
+type openAIChoice struct {
+	Message      openAIMessage `json:"message"`
+	Delta        openAIMessage `json:"delta"`
+	FinishReason string        `json:"finish_reason"`
+}
+
+// openAIChatResponse 对话补全响应（流式响应的每个数据块结构相同）
+type openAIChatResponse struct {
+	Model   string         `json:"model"`
+	Choices []openAIChoice `json:"choices"`
+	Usage   *openAIUsage   `json:"usage"`
+}
+
+// openAIEmbeddingRequest 向量化请求
+type openAIEmbeddingRequest struct {
+	Model          string   `json:"model"`
+	Input          []string `json:"input"`
+	Dimensions     *int     `json:"dimensions,omitempty"`
+	EncodingFormat string   `json:"encoding_format"`
+}
+
+// openAISpeechRequest 语音合成请求
+type openAISpeechRequest struct {
+	Model          string `json:"model"`
+	Input          string `json:"input"`
+	Voice          string `json:"voice"`
+	ResponseFormat string `json:"response_format,omitempty"`
+}
+
+// openAITranscriptionResponse 语音识别响应
+type openAITranscriptionResponse struct {
+	Text string `json:"text"`
+}
+
+// openAIEmbedding 单个输入的向量
+type openAIEmbedding struct {
+	Index     int       `json:"index"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// openAIEmbeddingResponse 向量化响应
+type openAIEmbeddingResponse struct {
+	Data  []openAIEmbedding `json:"data"`
+	Model string            `json:"model"`
+	Usage *openAIUsage      `json:"usage"`
+}
+
+// openAIErrorResponse 错误响应
+type openAIErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Initialize 初始化客户端
+func (c *openAIClient) Initialize(ctx context.Context, config *Config) error {
+	if config == nil {
+		return fmt.Errorf("配置不能为空")
+	}
+
+	if config.APIKey == "" {
+		return fmt.Errorf("API 密钥不能为空")
+	}
+
+	if config.BaseURL == "" {
+		return fmt.Errorf("服务地址不能为空")
+	}
+
+	if c.azure && config.APIVersion == "" {
+		return fmt.Errorf("Azure OpenAI API 版本不能为空")
+	}
+
+	c.config = config
+
+	return nil
+}
+
+// InitializeModel 初始化模型
+// OpenAI 协议按请求指定模型，无需预先初始化
+func (c *openAIClient) InitializeModel(ctx context.Context) error {
+	if c.config == nil {
+		return fmt.Errorf("客户端未初始化，请先调用 Initialize")
+	}
+	return nil
+}
+
+// Generate 生成内容
+func (c *openAIClient) Generate(ctx context.Context, prompt string, options *GenerateOptions) (*GenerateResult, error) {
+	req, err := c.buildRequest(prompt, options, false)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.doRequest(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("生成内容失败: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	var resp openAIChatResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("生成内容失败: 响应中没有候选结果")
+	}
+
+	result := c.buildResult(req.Model, resp.Choices[0].Message.Content, resp.Usage)
+	result.ToolCalls = toToolCalls(resp.Choices[0].Message.ToolCalls)
+
+	return result, nil
+}
+
+// Embed 通过 embeddings 接口为一批文本生成向量
+func (c *openAIClient) Embed(ctx context.Context, inputs []string, options *EmbedOptions) (*EmbedResult, error) {
+	if c.config == nil {
+		return nil, fmt.Errorf("客户端未初始化")
+	}
+
+	if options == nil || options.Model == "" {
+		return nil, fmt.Errorf("向量模型名称不能为空")
+	}
+
+	req := &openAIEmbeddingRequest{
+		Model:          options.Model,
+		Input:          inputs,
+		Dimensions:     options.Dimensions,
+		EncodingFormat: "float",
+	}
+
+	httpResp, err := c.post(ctx, c.endpoint(req.Model, "embeddings"), req, false)
+	if err != nil {
+		return nil, fmt.Errorf("生成向量失败: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	var resp openAIEmbeddingResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	// 按 index 还原输入顺序
+	embeddings := make([][]float32, len(inputs))
+	for _, item := range resp.Data {
+		if item.Index < 0 || item.Index >= len(inputs) || embeddings[item.Index] != nil {
+			return nil, fmt.Errorf("生成向量失败: 响应中的向量序号 %d 无效", item.Index)
+		}
+		embeddings[item.Index] = item.Embedding
+	}
+	for i, embedding := range embeddings {
+		if embedding == nil {
+			return nil, fmt.Errorf("生成向量失败: 响应中缺少第 %d 个输入的向量", i)
+		}
+	}
+
+	result := &EmbedResult{
+		Embeddings: embeddings,
+		Model:      req.Model,
+	}
+	if resp.Usage != nil {
+		result.Usage = &Usage{
+			PromptTokens: resp.Usage.PromptTokens,
+			TotalTokens:  resp.Usage.TotalTokens,
+		}
+	}
+
+	return result, nil
+}
+
+// Synthesize 将文本合成为音频，响应体即音频数据
+func (c *openAIClient) Synthesize(ctx context.Context, text string, options *SpeechOptions) (io.ReadCloser, error) {
+	if c.config == nil {
+		return nil, fmt.Errorf("客户端未初始化")
+	}
+
+	if options == nil || options.Model == "" {
+		return nil, fmt.Errorf("语音合成模型名称不能为空")
+	}
+
+	req := &openAISpeechRequest{
+		Model:          options.Model,
+		Input:          text,
+		Voice:          options.Voice,
+		ResponseFormat: options.Format,
+	}
+
+	httpResp, err := c.post(ctx, c.endpoint(req.Model, "audio/speech"), req, false)
+	if err != nil {
+		return nil, fmt.Errorf("语音合成失败: %w", err)
+	}
+
+	return httpResp.Body, nil
+}
+
+// Transcribe 通过 audio/transcriptions 接口将音频转写为文本
+func (c *openAIClient) Transcribe(ctx context.Context, audio io.Reader, options *TranscriptionOptions) (*TranscriptionResult, error) {
+	if c.config == nil {
+		return nil, fmt.Errorf("客户端未初始化")
+	}
+
+	if options == nil || options.Model == "" {
+		return nil, fmt.Errorf("语音识别模型名称不能为空")
+	}
+
+	// 接口根据文件名的扩展名识别音频格式
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "audio."+options.Format)
+	if err != nil {
+		return nil, fmt.Errorf("构建请求失败: %w", err)
+	}
+	if _, err := io.Copy(part, audio); err != nil {
+		return nil, fmt.Errorf("读取音频失败: %w", err)
+	}
+	fields := [][2]string{
+		{"model", options.Model},
+		{"response_format", "json"},
+		{"language", options.Language},
+	}
+	for _, field := range fields {
+		if field[1] == "" {
+			continue
+		}
+		if err := form.WriteField(field[0], field[1]); err != nil {
+			return nil, fmt.Errorf("构建请求失败: %w", err)
+		}
+	}
+	if err := form.Close(); err != nil {
+		return nil, fmt.Errorf("构建请求失败: %w", err)
+	}
+
+	httpResp, err := c.send(ctx, c.endpoint(options.Model, "audio/transcriptions"), form.FormDataContentType(), &body, false)
+	if err != nil {
+		return nil, fmt.Errorf("语音识别失败: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	var resp openAITranscriptionResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	return &TranscriptionResult{
+		Text:  resp.Text,
+		Model: options.Model,
+	}, nil
+}
+
+// GenerateStream 流式生成内容
+func (c *openAIClient) GenerateStream(ctx context.Context, prompt string, options *GenerateOptions, callback StreamCallback) (*GenerateResult, error) {
+	if callback == nil {
+		return nil, fmt.Errorf("流式回调不能为空")
+	}
+
+	req, err := c.buildRequest(prompt, options, true)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.doRequest(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("流式生成内容失败: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	var text strings.Builder
+	var usage *openAIUsage
+	// 流式响应中的工具调用按 index 分片返回，参数需要拼接
+	var toolCalls []openAIToolCall
+
+	scanner := bufio.NewScanner(httpResp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIChatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("解析流式响应失败: %w", err)
+		}
+
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+
+		for _, choice := range chunk.Choices {
+			toolCalls, err = mergeToolCallDeltas(toolCalls, choice.Delta.ToolCalls)
+			if err != nil {
+				return nil, fmt.Errorf("解析流式响应失败: %w", err)
+			}
+			if choice.Delta.Content == "" {
+				continue
+			}
+			text.WriteString(choice.Delta.Content)
+			if err := callback(ctx, choice.Delta.Content); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取流式响应失败: %w", err)
+	}
+
+	result := c.buildResult(req.Model, text.String(), usage)
+	result.ToolCalls = toToolCalls(toolCalls)
+
+	return result, nil
+}
+
+// Close 关闭客户端
+func (c *openAIClient) Close() error {
+	return nil
+}
+
+// buildRequest 构建对话补全请求
+func (c *openAIClient) buildRequest(prompt string, options *GenerateOptions, stream bool) (*openAIChatRequest, error) {
+	if c.config == nil {
+		return nil, fmt.Errorf("客户端未初始化")
+	}
+
+	// 工具调用后继续生成时，历史以工具结果结尾，没有新的提示词
+	if prompt == "" && (options == nil || len(options.History) == 0) {
+		return nil, fmt.Errorf("提示词不能为空")
+	}
+
+	req := &openAIChatRequest{
+		Model:  c.config.Model,
+		Stream: stream,
+	}
+
+	// 未指定的参数使用客户端默认值
+	if c.config.DefaultTemperature > 0 {
+		temperature := c.config.DefaultTemperature
+		req.Temperature = &temperature
+	}
+	if c.config.DefaultMaxTokens > 0 {
+		maxTokens := c.config.DefaultMaxTokens
+		req.MaxTokens = &maxTokens
+	}
+
+	if options != nil {
+		if options.Model != "" {
+			req.Model = options.Model
+		}
+		if options.System != "" {
+			req.Messages = append(req.Messages, openAIMessage{Role: RoleSystem, Content: options.System})
+		}
+		for _, msg := range options.History {
+			message, err := toOpenAIMessage(msg)
+			if err != nil {
+				return nil, err
+			}
+			req.Messages = append(req.Messages, message)
+		}
+		for _, tool := range options.Tools {
+			req.Tools = append(req.Tools, openAITool{
+				Type: "function",
+				Function: openAIFunction{
+					Name:        tool.Name,
+					Description: tool.Description,
+					Parameters:  tool.Parameters,
+				},
+			})
+		}
+		if options.JSONSchema != nil {
+			req.ResponseFormat = &openAIResponseFormat{
+				Type:       "json_schema",
+				JSONSchema: &openAIJSONSchema{Name: "response", Schema: options.JSONSchema},
+			}
+		}
+		if options.Temperature != nil {
+			req.Temperature = options.Temperature
+		}
+		if options.MaxTokens != nil {
+			req.MaxTokens = options.MaxTokens
+		}
+		req.TopP = options.TopP
+		// top_k 不是 OpenAI 标准参数，Azure OpenAI 不支持
+		if !c.azure {
+			req.TopK = options.TopK
+		}
+	}
+
+	if options != nil && len(options.Media) > 0 {
+		message, err := toOpenAIMessage(Message{Role: RoleUser, Content: prompt, Media: options.Media})
+		if err != nil {
+			return nil, err
+		}
+		req.Messages = append(req.Messages, message)
+	} else if prompt != "" {
+		req.Messages = append(req.Messages, openAIMessage{Role: RoleUser, Content: prompt})
+	}
+
+	if req.Model == "" {
+		return nil, fmt.Errorf("模型名称不能为空")
+	}
+
+	if stream {
+		req.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
+
+	return req, nil
+}
+
+// endpoint 返回接口地址，operation 为接口路径，如 chat/completions、embeddings
+func (c *openAIClient) endpoint(modelName, operation string) string {
+	baseURL := strings.TrimRight(c.config.BaseURL, "/")
+	if c.azure {
+		return fmt.Sprintf("%s/openai/deployments/%s/%s?api-version=%s",
+			baseURL, url.PathEscape(modelName), operation, url.QueryEscape(c.config.APIVersion))
+	}
+	return baseURL + "/" + operation
+}
+
+// doRequest 发送对话补全请求，非 2xx 响应转换为错误
+func (c *openAIClient) doRequest(ctx context.Context, req *openAIChatRequest) (*http.Response, error) {
+	return c.post(ctx, c.endpoint(req.Model, "chat/completions"), req, req.Stream)
+}
+
+// post 以 JSON 格式发送请求，非 2xx 响应转换为错误
+func (c *openAIClient) post(ctx context.Context, endpoint string, payload interface{}, stream bool) (*http.Response, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	return c.send(ctx, endpoint, "application/json", bytes.NewReader(body), stream)
+}
+
+// send 发送 POST 请求，非 2xx 响应转换为错误
+func (c *openAIClient) send(ctx context.Context, endpoint, contentType string, body io.Reader, stream bool) (*http.Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", contentType)
+	if c.azure {
+		httpReq.Header.Set("api-key", c.config.APIKey)
+	} else {
+		httpReq.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	}
+	if stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		defer httpResp.Body.Close()
+		respBody, _ := io.ReadAll(io.LimitReader(httpResp.Body, 64*1024))
+
+		var errResp openAIErrorResponse
+		if json.Unmarshal(respBody, &errResp) == nil && errResp.Error.Message != "" {
+			return nil, fmt.Errorf("HTTP %d: %s", httpResp.StatusCode, errResp.Error.Message)
+		}
+		return nil, fmt.Errorf("HTTP %d: %s", httpResp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	return httpResp, nil
+}
+
+// buildResult 构建生成结果
+func (c *openAIClient) buildResult(modelName, text string, usage *openAIUsage) *GenerateResult {
+	result := &GenerateResult{
+		Text:  text,
+		Model: modelName,
+	}
+
+	if usage != nil {
+		result.Usage = &Usage{
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+			TotalTokens:      usage.TotalTokens,
+		}
+	}
+
+	return result
+}
+
+// toOpenAIMessage 将历史消息转换为 OpenAI 消息
+func toOpenAIMessage(msg Message) (openAIMessage, error) {
+	result := openAIMessage{
+		Role:       msg.Role,
+		Content:    msg.Content,
+		ToolCallID: msg.ToolCallID,
+	}
+	if len(msg.Media) > 0 {
+		parts, err := toOpenAIContentParts(msg.Content, msg.Media)
+		if err != nil {
+			return openAIMessage{}, err
+		}
+		result.Parts = parts
+	}
+	for _, call := range msg.ToolCalls {
+		result.ToolCalls = append(result.ToolCalls, openAIToolCall{
+			ID:   call.ID,
+			Type: "function",
+			Function: openAIFunctionCall{
+				Name:      call.Name,
+				Arguments: call.Arguments,
+			},
+		})
+	}
+	return result, nil
+}
+
+// toOpenAIContentParts 将文本和媒体转换为多模态消息片段
+// 图片以 image_url 发送，音频以 input_audio 发送（需内联数据），其余媒体以 file 发送（需内联数据）；
+// OpenAI 兼容接口不支持视频
+func toOpenAIContentParts(text string, media []Media) ([]openAIContentPart, error) {
+	parts := make([]openAIContentPart, 0, len(media)+1)
+	if text != "" {
+		parts = append(parts, openAIContentPart{Type: "text", Text: text})
+	}
+
+	for i, m := range media {
+		switch {
+		case strings.HasPrefix(m.MimeType, "image/"):
+			parts = append(parts, openAIContentPart{Type: "image_url", ImageURL: &openAIImageURL{URL: m.DataURL()}})
+		case strings.HasPrefix(m.MimeType, "video/"):
+			return nil, fmt.Errorf("OpenAI 兼容接口不支持视频输入")
+		case m.Data == "":
+			return nil, fmt.Errorf("OpenAI 兼容接口不支持通过 URL 引用 %s 类型的媒体", m.MimeType)
+		case strings.HasPrefix(m.MimeType, "audio/"):
+			format := strings.TrimPrefix(m.MimeType, "audio/")
+			if format == "mpeg" {
+				format = "mp3"
+			}
+			parts = append(parts, openAIContentPart{Type: "input_audio", InputAudio: &openAIInputAudio{Data: m.Data, Format: format}})
+		default:
+			parts = append(parts, openAIContentPart{Type: "file", File: &openAIFile{
+				Filename: fmt.Sprintf("attachment-%d", i+1),
+				FileData: m.DataURL(),
+			}})
+		}
+	}
+
+	return parts, nil
+}
+
+// mergeToolCallDeltas 合并流式响应中的工具调用分片
+// 同一 index 的第一个分片携带调用ID和工具名称，之后的分片只追加参数；
+// index 为负数或不小于 maxToolCallsPerTurn 时返回错误
+func mergeToolCallDeltas(calls []openAIToolCall, deltas []openAIToolCall) ([]openAIToolCall, error) {
+	for _, delta := range deltas {
+		index := len(calls)
+		if delta.Index != nil {
+			index = *delta.Index
+		}
+		if index < 0 || index >= maxToolCallsPerTurn {
+			return nil, fmt.Errorf("工具调用序号 %d 无效", index)
+		}
+		for len(calls) <= index {
+			calls = append(calls, openAIToolCall{})
+		}
+
+		call := &calls[index]
+		if delta.ID != "" {
+			call.ID = delta.ID
+		}
+		if delta.Function.Name != "" {
+			call.Function.Name = delta.Function.Name
+		}
+		call.Function.Arguments += delta.Function.Arguments
+	}
+	return calls, nil
+}
+
+// toToolCalls 转换工具调用
+func toToolCalls(calls []openAIToolCall) []ToolCall {
+	var result []ToolCall
+	for _, call := range calls {
+		if call.Function.Name == "" {
+			continue
+		}
+		result = append(result, ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		})
+	}
+	return result
+}
@@ -0,0 +1,543 @@
+package genkit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOpenAIClient_Initialize(t *testing.T) {
+	tests := []struct {
+		name    string
+		client  Client
+		config  *Config
+		wantErr bool
+	}{
+		{name: "配置为空", client: NewOpenAIClient(), config: nil, wantErr: true},
+		{name: "缺少 API 密钥", client: NewOpenAIClient(), config: &Config{BaseURL: "http://localhost"}, wantErr: true},
+		{name: "缺少服务地址", client: NewOpenAIClient(), config: &Config{APIKey: "key"}, wantErr: true},
+		{name: "Azure 缺少 API 版本", client: NewAzureOpenAIClient(), config: &Config{APIKey: "key", BaseURL: "http://localhost"}, wantErr: true},
+		{name: "有效配置", client: NewOpenAIClient(), config: &Config{APIKey: "key", BaseURL: "http://localhost"}, wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.client.Initialize(context.Background(), tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Initialize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOpenAIClient_Generate(t *testing.T) {
+	var received openAIChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("请求路径 = %s, want /v1/chat/completions", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Authorization = %s, want Bearer test-key", got)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Fatalf("解析请求失败: %v", err)
+		}
+		fmt.Fprint(w, `{"model":"qwen-plus","choices":[{"message":{"role":"assistant","content":"你好"}}],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`)
+	}))
+	defer server.Close()
+
+	c := NewOpenAIClient()
+	if err := c.Initialize(context.Background(), &Config{APIKey: "test-key", BaseURL: server.URL + "/v1/"}); err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+
+	temp := 0.5
+	result, err := c.Generate(context.Background(), "hi", &GenerateOptions{Model: "qwen-plus", Temperature: &temp})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if result.Text != "你好" {
+		t.Errorf("Text = %s, want 你好", result.Text)
+	}
+	if result.Model != "qwen-plus" {
+		t.Errorf("Model = %s, want qwen-plus", result.Model)
+	}
+	if result.Usage == nil || result.Usage.TotalTokens != 5 {
+		t.Errorf("Usage = %+v, want TotalTokens 5", result.Usage)
+	}
+	if received.Model != "qwen-plus" || received.Temperature == nil || *received.Temperature != 0.5 {
+		t.Errorf("请求参数不正确: %+v", received)
+	}
+	if len(received.Messages) != 1 || received.Messages[0].Content != "hi" {
+		t.Errorf("请求消息不正确: %+v", received.Messages)
+	}
+}
+
+func TestOpenAIClient_GenerateWithHistory(t *testing.T) {
+	var received openAIChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer server.Close()
+
+	c := NewOpenAIClient()
+	_ = c.Initialize(context.Background(), &Config{APIKey: "test-key", BaseURL: server.URL, Model: "qwen-plus"})
+
+	_, err := c.Generate(context.Background(), "第二个问题", &GenerateOptions{
+		System: "你是一个助手",
+		History: []Message{
+			{Role: RoleUser, Content: "第一个问题"},
+			{Role: RoleAssistant, Content: "第一个回答"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	want := []openAIMessage{
+		{Role: "system", Content: "你是一个助手"},
+		{Role: "user", Content: "第一个问题"},
+		{Role: "assistant", Content: "第一个回答"},
+		{Role: "user", Content: "第二个问题"},
+	}
+	if len(received.Messages) != len(want) {
+		t.Fatalf("消息数量 = %d, want %d", len(received.Messages), len(want))
+	}
+	for i, msg := range want {
+		if !reflect.DeepEqual(received.Messages[i], msg) {
+			t.Errorf("消息[%d] = %+v, want %+v", i, received.Messages[i], msg)
+		}
+	}
+}
+
+func TestOpenAIClient_GenerateStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openAIChatRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if !req.Stream || req.StreamOptions == nil || !req.StreamOptions.IncludeUsage {
+			t.Errorf("期望流式请求并包含 usage，实际 %+v", req)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"你\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"好\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":2,\"total_tokens\":5}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	c := NewOpenAIClient()
+	if err := c.Initialize(context.Background(), &Config{APIKey: "test-key", BaseURL: server.URL, Model: "qwen-plus"}); err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+
+	var chunks []string
+	result, err := c.GenerateStream(context.Background(), "hi", nil, func(ctx context.Context, chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("GenerateStream() error = %v", err)
+	}
+
+	if strings.Join(chunks, "|") != "你|好" {
+		t.Errorf("chunks = %v, want [你 好]", chunks)
+	}
+	if result.Text != "你好" {
+		t.Errorf("Text = %s, want 你好", result.Text)
+	}
+	if result.Usage == nil || result.Usage.CompletionTokens != 2 {
+		t.Errorf("Usage = %+v, want CompletionTokens 2", result.Usage)
+	}
+}
+
+func TestOpenAIClient_Azure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openai/deployments/gpt-4o/chat/completions" {
+			t.Errorf("请求路径 = %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("api-version"); got != "2024-10-21" {
+			t.Errorf("api-version = %s, want 2024-10-21", got)
+		}
+		if got := r.Header.Get("api-key"); got != "azure-key" {
+			t.Errorf("api-key = %s, want azure-key", got)
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer server.Close()
+
+	c := NewAzureOpenAIClient()
+	if err := c.Initialize(context.Background(), &Config{APIKey: "azure-key", BaseURL: server.URL, APIVersion: "2024-10-21"}); err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+
+	result, err := c.Generate(context.Background(), "hi", &GenerateOptions{Model: "gpt-4o"})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if result.Text != "ok" {
+		t.Errorf("Text = %s, want ok", result.Text)
+	}
+}
+
+func TestOpenAIClient_Synthesize(t *testing.T) {
+	var received openAISpeechRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openai/deployments/tts-1/audio/speech" {
+			t.Errorf("请求路径 = %s", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Fatalf("解析请求失败: %v", err)
+		}
+		w.Header().Set("Content-Type", "audio/mpeg")
+		fmt.Fprint(w, "mp3-data")
+	}))
+	defer server.Close()
+
+	c := NewAzureOpenAIClient()
+	if err := c.Initialize(context.Background(), &Config{APIKey: "azure-key", BaseURL: server.URL, APIVersion: "2024-10-21"}); err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+
+	audio, err := c.(SpeechSynthesizer).Synthesize(context.Background(), "你好", &SpeechOptions{Model: "tts-1", Voice: "alloy", Format: "mp3"})
+	if err != nil {
+		t.Fatalf("Synthesize() error = %v", err)
+	}
+	defer audio.Close()
+
+	data, _ := io.ReadAll(audio)
+	if string(data) != "mp3-data" {
+		t.Errorf("音频 = %q, want mp3-data", data)
+	}
+	if received.Input != "你好" || received.Voice != "alloy" || received.ResponseFormat != "mp3" {
+		t.Errorf("请求参数不正确: %+v", received)
+	}
+}
+
+func TestOpenAIClient_Transcribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openai/deployments/whisper-1/audio/transcriptions" {
+			t.Errorf("请求路径 = %s", r.URL.Path)
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Fatalf("读取音频失败: %v", err)
+		}
+		data, _ := io.ReadAll(file)
+		if header.Filename != "audio.mp3" || string(data) != "mp3-data" {
+			t.Errorf("音频不正确: %s %q", header.Filename, data)
+		}
+		if r.FormValue("model") != "whisper-1" || r.FormValue("language") != "zh" {
+			t.Errorf("表单参数不正确: %v", r.MultipartForm.Value)
+		}
+		fmt.Fprint(w, `{"text":"你好"}`)
+	}))
+	defer server.Close()
+
+	c := NewAzureOpenAIClient()
+	if err := c.Initialize(context.Background(), &Config{APIKey: "azure-key", BaseURL: server.URL, APIVersion: "2024-10-21"}); err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+
+	result, err := c.(Transcriber).Transcribe(context.Background(), strings.NewReader("mp3-data"), &TranscriptionOptions{Model: "whisper-1", Format: "mp3", Language: "zh"})
+	if err != nil {
+		t.Fatalf("Transcribe() error = %v", err)
+	}
+	if result.Text != "你好" || result.Model != "whisper-1" {
+		t.Errorf("识别结果不正确: %+v", result)
+	}
+}
+
+func TestOpenAIClient_Embed(t *testing.T) {
+	var received openAIEmbeddingRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			t.Errorf("请求路径 = %s, want /v1/embeddings", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Fatalf("解析请求失败: %v", err)
+		}
+		// 响应中的顺序与输入不同，按 index 还原
+		fmt.Fprint(w, `{"model":"text-embedding-v3","data":[{"index":1,"embedding":[0.3,0.4]},{"index":0,"embedding":[0.1,0.2]}],"usage":{"prompt_tokens":4,"total_tokens":4}}`)
+	}))
+	defer server.Close()
+
+	c := NewOpenAIClient()
+	if err := c.Initialize(context.Background(), &Config{APIKey: "test-key", BaseURL: server.URL + "/v1"}); err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+
+	dimensions := 2
+	result, err := c.(Embedder).Embed(context.Background(), []string{"你好", "世界"}, &EmbedOptions{Model: "text-embedding-v3", Dimensions: &dimensions})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+
+	if received.Model != "text-embedding-v3" || !reflect.DeepEqual(received.Input, []string{"你好", "世界"}) {
+		t.Errorf("请求内容不正确: %+v", received)
+	}
+	if received.Dimensions == nil || *received.Dimensions != 2 || received.EncodingFormat != "float" {
+		t.Errorf("请求参数不正确: %+v", received)
+	}
+	want := [][]float32{{0.1, 0.2}, {0.3, 0.4}}
+	if !reflect.DeepEqual(result.Embeddings, want) {
+		t.Errorf("Embeddings = %v, want %v", result.Embeddings, want)
+	}
+	if result.Usage == nil || result.Usage.PromptTokens != 4 {
+		t.Errorf("Usage = %+v, want prompt_tokens 4", result.Usage)
+	}
+
+	t.Run("Azure 部署路径", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/openai/deployments/text-embedding-3-small/embeddings" {
+				t.Errorf("请求路径 = %s", r.URL.Path)
+			}
+			fmt.Fprint(w, `{"data":[{"index":0,"embedding":[1]}]}`)
+		}))
+		defer server.Close()
+
+		c := NewAzureOpenAIClient()
+		if err := c.Initialize(context.Background(), &Config{APIKey: "azure-key", BaseURL: server.URL, APIVersion: "2024-10-21"}); err != nil {
+			t.Fatalf("初始化失败: %v", err)
+		}
+		if _, err := c.(Embedder).Embed(context.Background(), []string{"a"}, &EmbedOptions{Model: "text-embedding-3-small"}); err != nil {
+			t.Fatalf("Embed() error = %v", err)
+		}
+	})
+
+	t.Run("响应缺少向量", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"data":[{"index":0,"embedding":[1]}]}`)
+		}))
+		defer server.Close()
+
+		c := NewOpenAIClient()
+		_ = c.Initialize(context.Background(), &Config{APIKey: "key", BaseURL: server.URL})
+		if _, err := c.(Embedder).Embed(context.Background(), []string{"a", "b"}, &EmbedOptions{Model: "m"}); err == nil {
+			t.Error("期望返回错误")
+		}
+	})
+}
+
+func TestOpenAIClient_ErrorResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":{"message":"Invalid API key"}}`)
+	}))
+	defer server.Close()
+
+	c := NewOpenAIClient()
+	_ = c.Initialize(context.Background(), &Config{APIKey: "bad", BaseURL: server.URL, Model: "qwen-plus"})
+
+	_, err := c.Generate(context.Background(), "hi", nil)
+	if err == nil {
+		t.Fatal("期望返回错误")
+	}
+	if !strings.Contains(err.Error(), "Invalid API key") {
+		t.Errorf("错误信息应包含上游错误，实际: %v", err)
+	}
+}
+
+func TestOpenAIClient_BuildRequestDefaults(t *testing.T) {
+	c := &openAIClient{config: &Config{Model: "qwen-plus", DefaultTemperature: 0.7, DefaultMaxTokens: 2000}}
+
+	req, err := c.buildRequest("hi", nil, false)
+	if err != nil {
+		t.Fatalf("buildRequest() error = %v", err)
+	}
+	if req.Temperature == nil || *req.Temperature != 0.7 {
+		t.Errorf("Temperature = %v, want 0.7", req.Temperature)
+	}
+	if req.MaxTokens == nil || *req.MaxTokens != 2000 {
+		t.Errorf("MaxTokens = %v, want 2000", req.MaxTokens)
+	}
+
+	temp := 0.3
+	maxTokens := 1000
+	req, err = c.buildRequest("hi", &GenerateOptions{Temperature: &temp, MaxTokens: &maxTokens}, false)
+	if err != nil {
+		t.Fatalf("buildRequest() error = %v", err)
+	}
+	if *req.Temperature != 0.3 || *req.MaxTokens != 1000 {
+		t.Errorf("请求参数应覆盖默认值: temperature=%v maxTokens=%v", *req.Temperature, *req.MaxTokens)
+	}
+}
+
+func TestOpenAIClient_BuildRequestJSONSchema(t *testing.T) {
+	c := &openAIClient{config: &Config{Model: "gpt-4o"}}
+	schema := map[string]interface{}{"type": "object"}
+
+	req, err := c.buildRequest("hi", &GenerateOptions{JSONSchema: schema}, false)
+	if err != nil {
+		t.Fatalf("buildRequest() error = %v", err)
+	}
+	if req.ResponseFormat == nil || req.ResponseFormat.Type != "json_schema" || req.ResponseFormat.JSONSchema == nil {
+		t.Fatalf("ResponseFormat = %+v, want json_schema", req.ResponseFormat)
+	}
+	if req.ResponseFormat.JSONSchema.Schema["type"] != "object" {
+		t.Errorf("Schema = %v", req.ResponseFormat.JSONSchema.Schema)
+	}
+
+	req, _ = c.buildRequest("hi", nil, false)
+	if req.ResponseFormat != nil {
+		t.Errorf("未指定 JSON Schema 时不应设置 ResponseFormat, 得到 %+v", req.ResponseFormat)
+	}
+}
+
+func TestOpenAIClient_BuildRequestMedia(t *testing.T) {
+	c := &openAIClient{config: &Config{Model: "gpt-4o"}}
+
+	req, err := c.buildRequest("这是什么", &GenerateOptions{Media: []Media{
+		{MimeType: "image/png", URL: "https://example.com/a.png"},
+		{MimeType: "audio/mpeg", Data: "aGVsbG8="},
+		{MimeType: "application/pdf", Data: "aGVsbG8="},
+	}}, false)
+	if err != nil {
+		t.Fatalf("buildRequest() error = %v", err)
+	}
+
+	data, err := json.Marshal(req.Messages[len(req.Messages)-1])
+	if err != nil {
+		t.Fatalf("序列化消息失败: %v", err)
+	}
+	var message struct {
+		Content []openAIContentPart `json:"content"`
+	}
+	if err := json.Unmarshal(data, &message); err != nil {
+		t.Fatalf("content 应为片段数组: %v, %s", err, data)
+	}
+	if len(message.Content) != 4 || message.Content[0].Text != "这是什么" {
+		t.Fatalf("消息片段不正确: %s", data)
+	}
+	if message.Content[1].ImageURL == nil || message.Content[1].ImageURL.URL != "https://example.com/a.png" {
+		t.Errorf("图片片段不正确: %+v", message.Content[1])
+	}
+	if message.Content[2].InputAudio == nil || message.Content[2].InputAudio.Format != "mp3" {
+		t.Errorf("音频片段不正确: %+v", message.Content[2])
+	}
+	if message.Content[3].File == nil || message.Content[3].File.FileData != "data:application/pdf;base64,aGVsbG8=" {
+		t.Errorf("文件片段不正确: %+v", message.Content[3])
+	}
+
+	if _, err := c.buildRequest("hi", &GenerateOptions{Media: []Media{{MimeType: "video/mp4", Data: "aGVsbG8="}}}, false); err == nil {
+		t.Error("期望不支持视频输入")
+	}
+
+	// 不带媒体的消息 content 仍为字符串
+	data, _ = json.Marshal(openAIMessage{Role: RoleUser, Content: "hi"})
+	if string(data) != `{"role":"user","content":"hi"}` {
+		t.Errorf("纯文本消息序列化不正确: %s", data)
+	}
+}
+
+func TestOpenAIClient_GenerateWithTools(t *testing.T) {
+	var received openAIChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"杭州\"}"}}]}}]}`)
+	}))
+	defer server.Close()
+
+	c := NewOpenAIClient()
+	_ = c.Initialize(context.Background(), &Config{APIKey: "test-key", BaseURL: server.URL, Model: "qwen-plus"})
+
+	// 工具调用后继续生成：历史以工具结果结尾，没有新的提示词
+	result, err := c.Generate(context.Background(), "", &GenerateOptions{
+		History: []Message{
+			{Role: RoleUser, Content: "杭州天气怎么样"},
+			{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "call_0", Name: "get_weather", Arguments: `{"city":"杭州"}`}}},
+			{Role: RoleTool, ToolCallID: "call_0", Name: "get_weather", Content: "晴"},
+		},
+		Tools: []ToolDefinition{{
+			Name:        "get_weather",
+			Description: "查询天气",
+			Parameters:  map[string]interface{}{"type": "object"},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if len(received.Tools) != 1 || received.Tools[0].Type != "function" || received.Tools[0].Function.Name != "get_weather" {
+		t.Errorf("工具定义不正确: %+v", received.Tools)
+	}
+	if len(received.Messages) != 3 {
+		t.Fatalf("消息数量 = %d, want 3", len(received.Messages))
+	}
+	if calls := received.Messages[1].ToolCalls; len(calls) != 1 || calls[0].ID != "call_0" || calls[0].Function.Name != "get_weather" {
+		t.Errorf("assistant 工具调用不正确: %+v", received.Messages[1])
+	}
+	if msg := received.Messages[2]; msg.Role != "tool" || msg.ToolCallID != "call_0" || msg.Content != "晴" {
+		t.Errorf("工具结果消息不正确: %+v", msg)
+	}
+
+	if len(result.ToolCalls) != 1 || result.ToolCalls[0].Name != "get_weather" || result.ToolCalls[0].Arguments != `{"city":"杭州"}` {
+		t.Errorf("ToolCalls = %+v", result.ToolCalls)
+	}
+}
+
+func TestOpenAIClient_GenerateStreamWithToolCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"id\":\"call_1\",\"type\":\"function\",\"function\":{\"name\":\"get_weather\",\"arguments\":\"\"}}]}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"function\":{\"arguments\":\"{\\\"city\\\":\"}}]}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"function\":{\"arguments\":\"\\\"杭州\\\"}\"}}]}}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	c := NewOpenAIClient()
+	_ = c.Initialize(context.Background(), &Config{APIKey: "test-key", BaseURL: server.URL, Model: "qwen-plus"})
+
+	result, err := c.GenerateStream(context.Background(), "杭州天气怎么样", nil, func(ctx context.Context, chunk string) error {
+		t.Errorf("不期望收到文本片段: %s", chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("GenerateStream() error = %v", err)
+	}
+
+	if len(result.ToolCalls) != 1 || result.ToolCalls[0].ID != "call_1" || result.ToolCalls[0].Arguments != `{"city":"杭州"}` {
+		t.Errorf("ToolCalls = %+v", result.ToolCalls)
+	}
+}
+
+func TestOpenAIClient_GenerateStreamInvalidToolCallIndex(t *testing.T) {
+	tests := []struct {
+		name  string
+		index int
+	}{
+		{name: "负数序号", index: -1},
+		{name: "序号超出上限", index: maxToolCallsPerTurn},
+		{name: "序号过大", index: 1 << 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":%d,\"id\":\"call_1\",\"type\":\"function\",\"function\":{\"name\":\"get_weather\",\"arguments\":\"{}\"}}]}}]}\n\n", tt.index)
+				fmt.Fprint(w, "data: [DONE]\n\n")
+			}))
+			defer server.Close()
+
+			c := NewOpenAIClient()
+			_ = c.Initialize(context.Background(), &Config{APIKey: "test-key", BaseURL: server.URL, Model: "qwen-plus"})
+
+			_, err := c.GenerateStream(context.Background(), "杭州天气怎么样", nil, func(ctx context.Context, chunk string) error {
+				return nil
+			})
+			if err == nil || !strings.Contains(err.Error(), "工具调用序号") {
+				t.Errorf("期望工具调用序号无效的错误, 得到 %v", err)
+			}
+		})
+	}
+}
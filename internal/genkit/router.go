@@ -0,0 +1,311 @@
+package genkit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/pkg/errors"
+)
+
+// 模型目录中的提供商ID（对应 models/ 下的目录名称）
+const (
+	// ProviderGemini Google Gemini
+	ProviderGemini = "gemini"
+	// ProviderTongyi 通义千问
+	ProviderTongyi = "tongyi"
+	// ProviderAzureOpenAI Azure OpenAI
+	ProviderAzureOpenAI = "azure_openai"
+)
+
+// ModelResolver 模型解析接口
+type ModelResolver interface {
+	// ResolveModel 根据模型名称查找模型及其所属提供商ID
+	ResolveModel(modelName string) (string, *model.Model, error)
+}
+
+// Router 多提供商模型路由
+// 根据模型目录解析请求中的模型名称，并分发到该提供商对应的后端客户端
+type Router struct {
+	resolver       ModelResolver
+	defaultBackend Client
+
+	mu            sync.RWMutex
+	backends      map[string]Client // key: provider_id
+	modelBackends map[string]Client // key: provider_id/model，自定义模型使用自身凭证的客户端
+}
+
+// NewRouter 创建模型路由
+// 参数:
+//
+//	resolver: 模型解析器（通常为 ProviderService）
+//	defaultBackend: 未指定模型时使用的默认客户端，可为 nil
+func NewRouter(resolver ModelResolver, defaultBackend Client) *Router {
+	return &Router{
+		resolver:       resolver,
+		defaultBackend: defaultBackend,
+		backends:       make(map[string]Client),
+		modelBackends:  make(map[string]Client),
+	}
+}
+
+// Register 注册提供商后端客户端，客户端应已完成初始化
+func (r *Router) Register(providerID string, backend Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.backends[providerID] = backend
+}
+
+// RegisterModel 注册单个模型的后端客户端，优先于提供商的后端客户端
+// 用于使用自身凭证的自定义模型；已注册的同名模型客户端会被关闭并替换
+func (r *Router) RegisterModel(providerID, modelID string, backend Client) {
+	r.mu.Lock()
+	previous := r.modelBackends[providerID+"/"+modelID]
+	r.modelBackends[providerID+"/"+modelID] = backend
+	r.mu.Unlock()
+
+	if previous != nil && previous != backend {
+		previous.Close()
+	}
+}
+
+// UnregisterModel 移除并关闭单个模型的后端客户端
+func (r *Router) UnregisterModel(providerID, modelID string) {
+	r.mu.Lock()
+	backend := r.modelBackends[providerID+"/"+modelID]
+	delete(r.modelBackends, providerID+"/"+modelID)
+	r.mu.Unlock()
+
+	if backend != nil {
+		backend.Close()
+	}
+}
+
+// HasBackends 是否存在可用的后端客户端
+func (r *Router) HasBackends() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.defaultBackend != nil || len(r.backends) > 0 || len(r.modelBackends) > 0
+}
+
+// Initialize 初始化客户端
+// 各后端客户端在注册前已分别初始化，这里无需处理
+func (r *Router) Initialize(ctx context.Context, config *Config) error {
+	return nil
+}
+
+// InitializeModel 初始化模型
+func (r *Router) InitializeModel(ctx context.Context) error {
+	return nil
+}
+
+// Generate 根据模型名称路由并生成内容
+func (r *Router) Generate(ctx context.Context, prompt string, options *GenerateOptions) (*GenerateResult, error) {
+	backend, routedOptions, err := r.route(options)
+	if err != nil {
+		return nil, err
+	}
+	return backend.Generate(ctx, prompt, routedOptions)
+}
+
+// GenerateStream 根据模型名称路由并流式生成内容
+func (r *Router) GenerateStream(ctx context.Context, prompt string, options *GenerateOptions, callback StreamCallback) (*GenerateResult, error) {
+	backend, routedOptions, err := r.route(options)
+	if err != nil {
+		return nil, err
+	}
+	return backend.GenerateStream(ctx, prompt, routedOptions, callback)
+}
+
+// Embed 根据向量模型名称路由并生成向量
+// 模型必须是目录中的 text_embedding 模型，且其提供商后端支持向量化
+func (r *Router) Embed(ctx context.Context, inputs []string, options *EmbedOptions) (*EmbedResult, error) {
+	if options == nil {
+		options = &EmbedOptions{}
+	}
+	providerID, mdl, backend, err := r.resolveBackend(options.Model, model.ModelTypeTextEmbedding, "向量模型")
+	if err != nil {
+		return nil, err
+	}
+
+	embedder, ok := backend.(Embedder)
+	if !ok {
+		return nil, errors.NewServiceUnavailableError(fmt.Sprintf("模型提供商 '%s' 不支持向量化", providerID))
+	}
+
+	routedOptions := *options
+	routedOptions.Model = mdl.Model
+	return embedder.Embed(ctx, inputs, &routedOptions)
+}
+
+// Rerank 将重排序请求路由到模型所属提供商的客户端
+func (r *Router) Rerank(ctx context.Context, query string, documents []string, options *RerankOptions) (*RerankResult, error) {
+	if options == nil {
+		options = &RerankOptions{}
+	}
+	providerID, mdl, backend, err := r.resolveBackend(options.Model, model.ModelTypeRerank, "重排序模型")
+	if err != nil {
+		return nil, err
+	}
+
+	reranker, ok := backend.(Reranker)
+	if !ok {
+		return nil, errors.NewServiceUnavailableError(fmt.Sprintf("模型提供商 '%s' 不支持重排序", providerID))
+	}
+
+	routedOptions := *options
+	routedOptions.Model = mdl.Model
+	return reranker.Rerank(ctx, query, documents, &routedOptions)
+}
+
+// Synthesize 将语音合成请求路由到模型所属提供商的客户端
+func (r *Router) Synthesize(ctx context.Context, text string, options *SpeechOptions) (io.ReadCloser, error) {
+	if options == nil {
+		options = &SpeechOptions{}
+	}
+	providerID, mdl, backend, err := r.resolveBackend(options.Model, model.ModelTypeTTS, "语音合成模型")
+	if err != nil {
+		return nil, err
+	}
+
+	synthesizer, ok := backend.(SpeechSynthesizer)
+	if !ok {
+		return nil, errors.NewServiceUnavailableError(fmt.Sprintf("模型提供商 '%s' 不支持语音合成", providerID))
+	}
+
+	routedOptions := *options
+	routedOptions.Model = mdl.Model
+	return synthesizer.Synthesize(ctx, text, &routedOptions)
+}
+
+// Transcribe 将音频路由到语音识别模型所属提供商的后端进行转写
+func (r *Router) Transcribe(ctx context.Context, audio io.Reader, options *TranscriptionOptions) (*TranscriptionResult, error) {
+	if options == nil {
+		options = &TranscriptionOptions{}
+	}
+	providerID, mdl, backend, err := r.resolveBackend(options.Model, model.ModelTypeSpeech2Text, "语音识别模型")
+	if err != nil {
+		return nil, err
+	}
+
+	transcriber, ok := backend.(Transcriber)
+	if !ok {
+		return nil, errors.NewServiceUnavailableError(fmt.Sprintf("模型提供商 '%s' 不支持语音识别", providerID))
+	}
+
+	routedOptions := *options
+	routedOptions.Model = mdl.Model
+	return transcriber.Transcribe(ctx, audio, &routedOptions)
+}
+
+// Close 关闭所有后端客户端
+func (r *Router) Close() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var firstErr error
+	closed := make(map[Client]bool)
+	for _, backend := range r.modelBackends {
+		closed[backend] = true
+		if err := backend.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	for _, backend := range r.backends {
+		if closed[backend] {
+			continue
+		}
+		closed[backend] = true
+		if err := backend.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if r.defaultBackend != nil && !closed[r.defaultBackend] {
+		if err := r.defaultBackend.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// backend 返回模型的后端客户端：优先使用模型自身的客户端，其次使用提供商的客户端
+func (r *Router) backend(providerID, modelID string) (Client, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if backend, exists := r.modelBackends[providerID+"/"+modelID]; exists {
+		return backend, true
+	}
+	backend, exists := r.backends[providerID]
+	return backend, exists
+}
+
+// route 解析模型名称并返回目标后端及改写后的生成选项
+func (r *Router) route(options *GenerateOptions) (Client, *GenerateOptions, error) {
+	// 未指定模型时使用默认客户端
+	if options == nil || options.Model == "" {
+		if r.defaultBackend == nil {
+			return nil, nil, errors.NewServiceUnavailableError("未配置默认模型")
+		}
+		return r.defaultBackend, options, nil
+	}
+
+	_, mdl, backend, err := r.resolveBackend(options.Model, model.ModelTypeLLM, "对话模型")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := checkMediaSupport(options, mdl); err != nil {
+		return nil, nil, err
+	}
+
+	routedOptions := *options
+	routedOptions.Model = mdl.Model
+	return backend, &routedOptions, nil
+}
+
+// resolveBackend 根据模型名称查找目录中的模型及其后端客户端
+// kind 为模型类型的名称（如 向量模型），用于错误信息；模型名称为空、模型不是 want 类型或提供商未配置时返回错误。
+// 调用方复制选项时应使用返回模型的 Model 字段，即目录中的模型ID（去掉提供商前缀）
+func (r *Router) resolveBackend(name string, want model.ModelType, kind string) (string, *model.Model, Client, error) {
+	if name == "" {
+		return "", nil, nil, errors.NewBadRequestError(kind + "名称不能为空")
+	}
+
+	providerID, mdl, err := r.resolver.ResolveModel(name)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	if mdl.ModelType != want {
+		return "", nil, nil, errors.NewBadRequestError(fmt.Sprintf("模型 '%s' 不是%s", name, kind))
+	}
+
+	backend, exists := r.backend(providerID, mdl.Model)
+	if !exists {
+		return "", nil, nil, errors.NewServiceUnavailableError(fmt.Sprintf("模型提供商 '%s' 未配置", providerID))
+	}
+
+	return providerID, mdl, backend, nil
+}
+
+// checkMediaSupport 检查本次提示词和历史消息中的媒体是否都有模型支持的特性
+func checkMediaSupport(options *GenerateOptions, mdl *model.Model) error {
+	media := append([]Media(nil), options.Media...)
+	for _, msg := range options.History {
+		media = append(media, msg.Media...)
+	}
+
+	for _, m := range media {
+		if feature := model.MediaFeature(m.MimeType); !mdl.HasFeature(feature) {
+			return errors.NewBadRequestError(fmt.Sprintf("模型 '%s' 不支持 %s 类型的附件（需要 %s 特性）", options.Model, m.MimeType, feature))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,425 @@
+package genkit
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/pkg/errors"
+)
+
+// fakeResolver 测试用模型解析器
+type fakeResolver struct {
+	models map[string]struct {
+		providerID string
+		model      model.Model
+	}
+}
+
+func (f *fakeResolver) ResolveModel(modelName string) (string, *model.Model, error) {
+	entry, ok := f.models[modelName]
+	if !ok {
+		return "", nil, errors.NewModelNotFoundError(modelName)
+	}
+	m := entry.model
+	return entry.providerID, &m, nil
+}
+
+// fakeBackend 测试用后端客户端
+type fakeBackend struct {
+	name        string
+	lastOptions *GenerateOptions
+	closed      bool
+}
+
+func (f *fakeBackend) Initialize(ctx context.Context, config *Config) error { return nil }
+func (f *fakeBackend) InitializeModel(ctx context.Context) error            { return nil }
+func (f *fakeBackend) Close() error                                         { f.closed = true; return nil }
+
+func (f *fakeBackend) Generate(ctx context.Context, prompt string, options *GenerateOptions) (*GenerateResult, error) {
+	f.lastOptions = options
+	return &GenerateResult{Text: f.name}, nil
+}
+
+func (f *fakeBackend) GenerateStream(ctx context.Context, prompt string, options *GenerateOptions, callback StreamCallback) (*GenerateResult, error) {
+	f.lastOptions = options
+	if err := callback(ctx, f.name); err != nil {
+		return nil, err
+	}
+	return &GenerateResult{Text: f.name}, nil
+}
+
+func newTestRouter() (*Router, *fakeBackend, *fakeBackend) {
+	resolver := &fakeResolver{models: map[string]struct {
+		providerID string
+		model      model.Model
+	}{
+		"qwen-plus":           {providerID: ProviderTongyi, model: model.Model{Model: "qwen-plus", ModelType: "llm"}},
+		"azure_openai/gpt-4o": {providerID: ProviderAzureOpenAI, model: model.Model{Model: "gpt-4o", ModelType: "llm"}},
+		"text-embedding-v3":   {providerID: ProviderTongyi, model: model.Model{Model: "text-embedding-v3", ModelType: "text_embedding"}},
+		"qwen-vl-max":         {providerID: ProviderTongyi, model: model.Model{Model: "qwen-vl-max", ModelType: "llm", Features: []string{"vision"}}},
+		"tongyi/gte-rerank":   {providerID: ProviderTongyi, model: model.Model{Model: "gte-rerank", ModelType: "rerank"}},
+		"tongyi/tts-1":        {providerID: ProviderTongyi, model: model.Model{Model: "tts-1", ModelType: "tts"}},
+		"paraformer-v2":       {providerID: ProviderTongyi, model: model.Model{Model: "paraformer-realtime-v2", ModelType: "speech2text"}},
+	}}
+
+	gemini := &fakeBackend{name: "gemini"}
+	tongyi := &fakeBackend{name: "tongyi"}
+
+	router := NewRouter(resolver, gemini)
+	router.Register(ProviderGemini, gemini)
+	router.Register(ProviderTongyi, tongyi)
+
+	return router, gemini, tongyi
+}
+
+func TestRouter_Generate(t *testing.T) {
+	router, _, tongyi := newTestRouter()
+
+	result, err := router.Generate(context.Background(), "hi", &GenerateOptions{Model: "qwen-plus"})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if result.Text != "tongyi" {
+		t.Errorf("期望路由到 tongyi，实际 %s", result.Text)
+	}
+	if tongyi.lastOptions == nil || tongyi.lastOptions.Model != "qwen-plus" {
+		t.Errorf("后端收到的模型不正确: %+v", tongyi.lastOptions)
+	}
+}
+
+func TestRouter_DefaultBackend(t *testing.T) {
+	router, _, _ := newTestRouter()
+
+	result, err := router.Generate(context.Background(), "hi", nil)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if result.Text != "gemini" {
+		t.Errorf("未指定模型时期望使用默认后端，实际 %s", result.Text)
+	}
+}
+
+func TestRouter_ModelBackend(t *testing.T) {
+	router, _, _ := newTestRouter()
+	deployment := &fakeBackend{name: "deployment"}
+	router.RegisterModel(ProviderAzureOpenAI, "gpt-4o", deployment)
+
+	// 模型自身的后端优先于提供商的后端（azure_openai 未注册提供商后端）
+	result, err := router.Generate(context.Background(), "hi", &GenerateOptions{Model: "azure_openai/gpt-4o"})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if result.Text != "deployment" || deployment.lastOptions.Model != "gpt-4o" {
+		t.Errorf("期望路由到模型后端，实际 %s %+v", result.Text, deployment.lastOptions)
+	}
+
+	router.UnregisterModel(ProviderAzureOpenAI, "gpt-4o")
+	if !deployment.closed {
+		t.Error("移除的模型后端应被关闭")
+	}
+	_, err = router.Generate(context.Background(), "hi", &GenerateOptions{Model: "azure_openai/gpt-4o"})
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.CodeServiceUnavailable {
+		t.Errorf("移除后期望服务不可用，实际 %v", err)
+	}
+}
+
+func TestRouter_GenerateStream(t *testing.T) {
+	router, _, _ := newTestRouter()
+
+	var chunks []string
+	_, err := router.GenerateStream(context.Background(), "hi", &GenerateOptions{Model: "qwen-plus"}, func(ctx context.Context, chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("GenerateStream() error = %v", err)
+	}
+	if len(chunks) != 1 || chunks[0] != "tongyi" {
+		t.Errorf("chunks = %v, want [tongyi]", chunks)
+	}
+}
+
+func TestRouter_Errors(t *testing.T) {
+	router, _, _ := newTestRouter()
+
+	tests := []struct {
+		name     string
+		model    string
+		wantCode int
+	}{
+		{name: "未知模型", model: "unknown-model", wantCode: errors.CodeModelNotFound},
+		{name: "提供商未配置", model: "azure_openai/gpt-4o", wantCode: errors.CodeServiceUnavailable},
+		{name: "非对话模型", model: "text-embedding-v3", wantCode: errors.CodeBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := router.Generate(context.Background(), "hi", &GenerateOptions{Model: tt.model})
+			appErr, ok := err.(*errors.AppError)
+			if !ok {
+				t.Fatalf("期望 AppError，实际 %v", err)
+			}
+			if appErr.Code != tt.wantCode {
+				t.Errorf("错误码 = %d, want %d", appErr.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestRouter_MediaSupport(t *testing.T) {
+	router, _, tongyi := newTestRouter()
+	image := Media{MimeType: "image/png", Data: "aGVsbG8="}
+	pdf := Media{MimeType: "application/pdf", URL: "https://example.com/a.pdf"}
+
+	if _, err := router.Generate(context.Background(), "图里是什么", &GenerateOptions{Model: "qwen-vl-max", Media: []Media{image}}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(tongyi.lastOptions.Media) != 1 {
+		t.Errorf("媒体应传递给后端, 得到 %+v", tongyi.lastOptions.Media)
+	}
+
+	tests := []struct {
+		name    string
+		options *GenerateOptions
+	}{
+		{name: "模型不支持图片", options: &GenerateOptions{Model: "qwen-plus", Media: []Media{image}}},
+		{name: "历史中的文档", options: &GenerateOptions{Model: "qwen-vl-max", History: []Message{{Role: RoleUser, Media: []Media{pdf}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := router.Generate(context.Background(), "hi", tt.options)
+			if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.CodeBadRequest {
+				t.Errorf("期望请求错误, 实际 %v", err)
+			}
+		})
+	}
+}
+
+// fakeEmbeddingBackend 测试用支持向量化的后端客户端
+type fakeEmbeddingBackend struct {
+	fakeBackend
+	lastEmbedOptions *EmbedOptions
+}
+
+func (f *fakeEmbeddingBackend) Embed(ctx context.Context, inputs []string, options *EmbedOptions) (*EmbedResult, error) {
+	f.lastEmbedOptions = options
+	embeddings := make([][]float32, len(inputs))
+	for i, input := range inputs {
+		embeddings[i] = []float32{float32(len(input))}
+	}
+	return &EmbedResult{Embeddings: embeddings, Model: options.Model}, nil
+}
+
+func TestRouter_Embed(t *testing.T) {
+	router, _, _ := newTestRouter()
+	embedder := &fakeEmbeddingBackend{fakeBackend: fakeBackend{name: "tongyi"}}
+	router.Register(ProviderTongyi, embedder)
+
+	result, err := router.Embed(context.Background(), []string{"a", "bb"}, &EmbedOptions{Model: "text-embedding-v3"})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if len(result.Embeddings) != 2 || result.Embeddings[1][0] != 2 {
+		t.Errorf("向量结果不正确: %v", result.Embeddings)
+	}
+	if embedder.lastEmbedOptions.Model != "text-embedding-v3" {
+		t.Errorf("期望模型ID为 text-embedding-v3，实际 %s", embedder.lastEmbedOptions.Model)
+	}
+
+	tests := []struct {
+		name     string
+		model    string
+		wantCode int
+	}{
+		{name: "未指定模型", model: "", wantCode: errors.CodeBadRequest},
+		{name: "对话模型", model: "qwen-plus", wantCode: errors.CodeBadRequest},
+		{name: "模型不存在", model: "unknown", wantCode: errors.CodeModelNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := router.Embed(context.Background(), []string{"a"}, &EmbedOptions{Model: tt.model})
+			if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != tt.wantCode {
+				t.Errorf("期望错误码 %d，实际 %v", tt.wantCode, err)
+			}
+		})
+	}
+
+	t.Run("后端不支持向量化", func(t *testing.T) {
+		router, _, _ := newTestRouter()
+		_, err := router.Embed(context.Background(), []string{"a"}, &EmbedOptions{Model: "text-embedding-v3"})
+		if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.CodeServiceUnavailable {
+			t.Errorf("期望服务不可用，实际 %v", err)
+		}
+	})
+}
+
+// fakeRerankBackend 测试用支持重排序的后端客户端
+type fakeRerankBackend struct {
+	fakeBackend
+	lastRerankOptions *RerankOptions
+}
+
+func (f *fakeRerankBackend) Rerank(ctx context.Context, query string, documents []string, options *RerankOptions) (*RerankResult, error) {
+	f.lastRerankOptions = options
+	results := make([]RerankScore, len(documents))
+	for i := range documents {
+		results[i] = RerankScore{Index: i, Score: 1 / float64(i+1)}
+	}
+	return &RerankResult{Results: results, Model: options.Model}, nil
+}
+
+func TestRouter_Rerank(t *testing.T) {
+	router, _, _ := newTestRouter()
+	reranker := &fakeRerankBackend{fakeBackend: fakeBackend{name: "tongyi"}}
+	router.Register(ProviderTongyi, reranker)
+
+	result, err := router.Rerank(context.Background(), "q", []string{"a", "b"}, &RerankOptions{Model: "tongyi/gte-rerank", TopN: 1})
+	if err != nil {
+		t.Fatalf("Rerank() error = %v", err)
+	}
+	if len(result.Results) != 2 {
+		t.Errorf("重排序结果不正确: %v", result.Results)
+	}
+	if reranker.lastRerankOptions.Model != "gte-rerank" || reranker.lastRerankOptions.TopN != 1 {
+		t.Errorf("路由后的选项不正确: %+v", reranker.lastRerankOptions)
+	}
+
+	tests := []struct {
+		name     string
+		model    string
+		wantCode int
+	}{
+		{name: "未指定模型", model: "", wantCode: errors.CodeBadRequest},
+		{name: "向量模型", model: "text-embedding-v3", wantCode: errors.CodeBadRequest},
+		{name: "模型不存在", model: "unknown", wantCode: errors.CodeModelNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := router.Rerank(context.Background(), "q", []string{"a"}, &RerankOptions{Model: tt.model})
+			if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != tt.wantCode {
+				t.Errorf("期望错误码 %d，实际 %v", tt.wantCode, err)
+			}
+		})
+	}
+
+	t.Run("后端不支持重排序", func(t *testing.T) {
+		router, _, _ := newTestRouter()
+		_, err := router.Rerank(context.Background(), "q", []string{"a"}, &RerankOptions{Model: "tongyi/gte-rerank"})
+		if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.CodeServiceUnavailable {
+			t.Errorf("期望服务不可用，实际 %v", err)
+		}
+	})
+}
+
+// fakeSpeechBackend 测试用支持语音合成的后端客户端，音频内容为输入文本
+type fakeSpeechBackend struct {
+	fakeBackend
+	lastSpeechOptions *SpeechOptions
+}
+
+func (f *fakeSpeechBackend) Synthesize(ctx context.Context, text string, options *SpeechOptions) (io.ReadCloser, error) {
+	f.lastSpeechOptions = options
+	return io.NopCloser(strings.NewReader(text)), nil
+}
+
+func TestRouter_Synthesize(t *testing.T) {
+	router, _, _ := newTestRouter()
+	synthesizer := &fakeSpeechBackend{fakeBackend: fakeBackend{name: "tongyi"}}
+	router.Register(ProviderTongyi, synthesizer)
+
+	audio, err := router.Synthesize(context.Background(), "你好", &SpeechOptions{Model: "tongyi/tts-1", Voice: "sambert-zhiru-v1"})
+	if err != nil {
+		t.Fatalf("Synthesize() error = %v", err)
+	}
+	defer audio.Close()
+	if synthesizer.lastSpeechOptions.Model != "tts-1" || synthesizer.lastSpeechOptions.Voice != "sambert-zhiru-v1" {
+		t.Errorf("路由后的选项不正确: %+v", synthesizer.lastSpeechOptions)
+	}
+
+	tests := []struct {
+		name     string
+		model    string
+		wantCode int
+	}{
+		{name: "未指定模型", model: "", wantCode: errors.CodeBadRequest},
+		{name: "对话模型", model: "qwen-plus", wantCode: errors.CodeBadRequest},
+		{name: "模型不存在", model: "unknown", wantCode: errors.CodeModelNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := router.Synthesize(context.Background(), "a", &SpeechOptions{Model: tt.model})
+			if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != tt.wantCode {
+				t.Errorf("期望错误码 %d，实际 %v", tt.wantCode, err)
+			}
+		})
+	}
+
+	t.Run("后端不支持语音合成", func(t *testing.T) {
+		router, _, _ := newTestRouter()
+		_, err := router.Synthesize(context.Background(), "a", &SpeechOptions{Model: "tongyi/tts-1"})
+		if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.CodeServiceUnavailable {
+			t.Errorf("期望服务不可用，实际 %v", err)
+		}
+	})
+}
+
+// fakeTranscriptionBackend 测试用支持语音识别的后端客户端，识别结果为音频内容
+type fakeTranscriptionBackend struct {
+	fakeBackend
+	lastTranscriptionOptions *TranscriptionOptions
+}
+
+func (f *fakeTranscriptionBackend) Transcribe(ctx context.Context, audio io.Reader, options *TranscriptionOptions) (*TranscriptionResult, error) {
+	f.lastTranscriptionOptions = options
+	data, err := io.ReadAll(audio)
+	if err != nil {
+		return nil, err
+	}
+	return &TranscriptionResult{Text: string(data), Model: options.Model}, nil
+}
+
+func TestRouter_Transcribe(t *testing.T) {
+	router, _, _ := newTestRouter()
+	transcriber := &fakeTranscriptionBackend{fakeBackend: fakeBackend{name: "tongyi"}}
+	router.Register(ProviderTongyi, transcriber)
+
+	result, err := router.Transcribe(context.Background(), strings.NewReader("你好"), &TranscriptionOptions{Model: "paraformer-v2", Format: "wav"})
+	if err != nil {
+		t.Fatalf("Transcribe() error = %v", err)
+	}
+	if result.Text != "你好" {
+		t.Errorf("Text = %q, want 你好", result.Text)
+	}
+	if transcriber.lastTranscriptionOptions.Model != "paraformer-realtime-v2" || transcriber.lastTranscriptionOptions.Format != "wav" {
+		t.Errorf("路由后的选项不正确: %+v", transcriber.lastTranscriptionOptions)
+	}
+
+	tests := []struct {
+		name     string
+		model    string
+		wantCode int
+	}{
+		{name: "未指定模型", model: "", wantCode: errors.CodeBadRequest},
+		{name: "语音合成模型", model: "tongyi/tts-1", wantCode: errors.CodeBadRequest},
+		{name: "模型不存在", model: "unknown", wantCode: errors.CodeModelNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := router.Transcribe(context.Background(), strings.NewReader("a"), &TranscriptionOptions{Model: tt.model})
+			if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != tt.wantCode {
+				t.Errorf("期望错误码 %d，实际 %v", tt.wantCode, err)
+			}
+		})
+	}
+
+	t.Run("后端不支持语音识别", func(t *testing.T) {
+		router, _, _ := newTestRouter()
+		_, err := router.Transcribe(context.Background(), strings.NewReader("a"), &TranscriptionOptions{Model: "paraformer-v2"})
+		if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.CodeServiceUnavailable {
+			t.Errorf("期望服务不可用，实际 %v", err)
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package loader
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LoadError 加载单个提供商或模型配置文件的错误
+type LoadError struct {
+	// Provider 提供商ID
+	Provider string
+	// Path 出错的文件或目录路径
+	Path string
+	// Err 原始错误
+	Err error
+}
+
+// Error 实现 error 接口
+func (e *LoadError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+// Unwrap 返回原始错误
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// LoadErrors 加载模型目录时被跳过的全部错误
+type LoadErrors []*LoadError
+
+// Error 实现 error 接口
+func (e LoadErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("模型目录存在 %d 个错误: %s", len(e), strings.Join(messages, "; "))
+}
+
+// add 记录一个加载错误
+func (e *LoadErrors) add(provider, path string, err error) {
+	*e = append(*e, &LoadError{Provider: provider, Path: path, Err: err})
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
 	"genkit-ai-service/internal/storage"
 )
 
@@ -119,4 +120,89 @@ func TestLoadAll(t *testing.T) {
 		}
 		t.Logf("提供商 %s 有 %d 个模型", provider.ID, len(models))
 	}
+
+	// 验证语音合成模型的音色等属性被完整加载
+	tts, err := store.GetModel("tongyi", "tts-1")
+	if err != nil {
+		t.Fatalf("获取 tongyi/tts-1 失败: %v", err)
+	}
+	properties := tts.ModelProperties
+	if properties.DefaultVoice == "" || properties.FindVoice(properties.DefaultVoice) == nil {
+		t.Errorf("默认音色 %q 不在音色列表中", properties.DefaultVoice)
+	}
+	if len(properties.Voices) == 0 || len(properties.Voices[0].Language) == 0 {
+		t.Errorf("音色列表未加载: %+v", properties.Voices)
+	}
+	if properties.WordLimit <= 0 || properties.AudioType == "" {
+		t.Errorf("字数限制或音频格式未加载: %+v", properties)
+	}
+
+	// 验证语音识别模型的文件格式和大小上限被加载
+	stt, err := store.GetModel("tongyi", "paraformer-realtime-v2")
+	if err != nil {
+		t.Fatalf("获取 tongyi/paraformer-realtime-v2 失败: %v", err)
+	}
+	if stt.ModelProperties.FileUploadLimit <= 0 || !stt.ModelProperties.SupportsFileExtension("WAV") || stt.ModelProperties.SupportsFileExtension("txt") {
+		t.Errorf("语音识别模型属性未正确加载: %+v", stt.ModelProperties)
+	}
+}
+
+// TestLoadModels_PredefinedAndModelType 测试 predefined 通配符和模型类型规范化
+func TestLoadModels_PredefinedAndModelType(t *testing.T) {
+	log := logger.New(logger.InfoLevel, logger.TextFormat, os.Stdout)
+	loader := NewModelLoader(storage.NewMemoryStore(), log)
+
+	providerDir := filepath.Join(t.TempDir(), "acme")
+	files := map[string]string{
+		"models/llm/_position.yaml":        "- beta\n",
+		"models/llm/alpha.yaml":            "model: alpha\nmodel_type: llm\n",
+		"models/llm/beta.yaml":             "model: beta\nmodel_type: llm\n",
+		"models/llm/notes.txt":             "不是模型配置",
+		"models/text-embedding/embed.yaml": "model: embed\nmodel_type: text-embedding\n",
+		"models/vision/unknown.yaml":       "model: unknown\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(providerDir, name)), 0o755); err != nil {
+			t.Fatalf("创建目录失败: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(providerDir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("写入文件失败: %v", err)
+		}
+	}
+
+	modelTypes := map[string]model.ModelTypeInfo{
+		"llm": {
+			Position:   "models/llm/_position.yaml",
+			Predefined: []string{"models/llm/*.yaml", "models/llm/*"},
+		},
+		// 键名使用下划线形式，目录使用连字符形式
+		"text_embedding": {Predefined: []string{"models/text-embedding/*.yaml"}},
+		"vision":         {},
+	}
+	models, err := loader.LoadModels(providerDir, "acme", modelTypes)
+	if err != nil {
+		t.Fatalf("加载模型失败: %v", err)
+	}
+
+	var llms []string
+	byID := make(map[string]model.Model)
+	for _, m := range models {
+		byID[m.Model] = m
+		if m.ModelType == model.ModelTypeLLM {
+			llms = append(llms, m.Model)
+		}
+	}
+	if len(models) != 3 {
+		t.Fatalf("期望加载 3 个模型, 得到 %d 个", len(models))
+	}
+	// position 中列出的模型在前，predefined 匹配到的其他模型在后
+	if len(llms) != 2 || llms[0] != "beta" || llms[1] != "alpha" {
+		t.Errorf("llm 模型顺序不正确: %v", llms)
+	}
+	if byID["embed"].ModelType != model.ModelTypeTextEmbedding {
+		t.Errorf("期望模型类型 %s, 得到 %s", model.ModelTypeTextEmbedding, byID["embed"].ModelType)
+	}
+	if _, ok := byID["unknown"]; ok {
+		t.Error("未知模型类型的模型不应被加载")
+	}
 }
@@ -0,0 +1,83 @@
+package loader
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"genkit-ai-service/internal/model"
+)
+
+// modelFile 模型配置文件
+type modelFile struct {
+	// 模型名称（文件名去掉 .yaml）
+	name string
+	// 文件路径
+	path string
+}
+
+// resolveModelTypeDir 返回模型类型的目录
+// 优先使用提供商 YAML 中 models 的键名，目录不存在时依次尝试规范形式（text_embedding）和连字符形式（text-embedding）
+func resolveModelTypeDir(providerDir, key string, modelType model.ModelType) string {
+	candidates := []string{key, modelType.String(), modelType.ProviderForm()}
+	for _, name := range candidates {
+		dir := filepath.Join(providerDir, "models", name)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+	}
+	return filepath.Join(providerDir, "models", key)
+}
+
+// isModelFileName 检查文件名是否是模型配置文件，以 _ 开头的特殊文件（如 _position.yaml）除外
+func isModelFileName(name string) bool {
+	return strings.HasSuffix(name, ".yaml") && !strings.HasPrefix(name, "_")
+}
+
+// matchPredefined 解析 predefined 通配符（相对于提供商目录），返回匹配到的模型配置文件路径
+// 结果按路径排序并去重，通配符格式不合法时返回错误
+func matchPredefined(providerDir string, patterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(providerDir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range matches {
+			if seen[path] || !isModelFileName(filepath.Base(path)) {
+				continue
+			}
+			if info, err := os.Stat(path); err != nil || info.IsDir() {
+				continue
+			}
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// orderModelFiles 按 position 顺序排列模型配置文件，predefined 匹配到但没有列出的文件按路径顺序排在后面
+func orderModelFiles(typeDir string, positions []string, predefined []string) []modelFile {
+	seen := make(map[string]bool)
+	var files []modelFile
+	for _, name := range positions {
+		path := filepath.Join(typeDir, name+".yaml")
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		files = append(files, modelFile{name: name, path: path})
+	}
+	for _, path := range predefined {
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		files = append(files, modelFile{name: strings.TrimSuffix(filepath.Base(path), ".yaml"), path: path})
+	}
+	return files
+}
@@ -19,6 +19,10 @@ type ModelLoader interface {
 	// LoadAll 加载所有提供商和模型数据
 	LoadAll(modelsDir string) error
 
+	// LoadSnapshot 加载所有提供商和模型到新的快照，不修改存储
+	// 出错的配置文件被跳过，此时同时返回快照和 LoadErrors；无法读取 models 目录时快照为 nil
+	LoadSnapshot(modelsDir string) (*storage.Snapshot, error)
+
 	// LoadProviders 加载所有提供商配置
 	LoadProviders(modelsDir string) ([]model.Provider, error)
 
@@ -41,27 +45,42 @@ func NewModelLoader(store storage.Store, log logger.Logger) ModelLoader {
 }
 
 // LoadAll 加载所有提供商和模型数据
+// 出错的配置文件被跳过，其他数据仍然加载到存储
 func (l *modelLoader) LoadAll(modelsDir string) error {
+	snapshot, err := l.LoadSnapshot(modelsDir)
+	if snapshot == nil {
+		return err
+	}
+
+	// 存储提供商和模型数据
+	l.store.ReplaceAll(snapshot)
+
+	return nil
+}
+
+// LoadSnapshot 加载所有提供商和模型到新的快照，不修改存储
+func (l *modelLoader) LoadSnapshot(modelsDir string) (*storage.Snapshot, error) {
 	l.logger.Info("开始加载模型提供商数据", logger.Fields{"dir": modelsDir})
 
 	// 清理并验证基础路径
 	cleanModelsDir := filepath.Clean(modelsDir)
-	
+	var loadErrs LoadErrors
+
 	// 加载提供商
-	providers, err := l.LoadProviders(cleanModelsDir)
+	providers, err := l.loadProviders(cleanModelsDir, &loadErrs)
 	if err != nil {
 		l.logger.Error("加载提供商失败", logger.Fields{"error": err.Error()})
-		return fmt.Errorf("加载提供商失败: %w", err)
+		return nil, fmt.Errorf("加载提供商失败: %w", err)
 	}
 
-	// 存储提供商数据
-	l.store.SetProviders(providers)
+	snapshot := storage.NewSnapshot()
+	snapshot.Providers = providers
 
 	// 加载每个提供商的模型
 	totalModels := 0
 	for _, provider := range providers {
 		providerDir := filepath.Join(cleanModelsDir, provider.ID)
-		models, err := l.LoadModels(providerDir, provider.ID, provider.Models)
+		models, err := l.loadModels(providerDir, provider.ID, provider.Models, &loadErrs)
 		if err != nil {
 			l.logger.Error("加载提供商模型失败", logger.Fields{
 				"provider": provider.ID,
@@ -71,8 +90,7 @@ func (l *modelLoader) LoadAll(modelsDir string) error {
 			continue
 		}
 
-		// 存储模型数据
-		l.store.SetModels(provider.ID, models)
+		snapshot.Models[provider.ID] = models
 		totalModels += len(models)
 
 		l.logger.Info("提供商模型加载完成", logger.Fields{
@@ -84,13 +102,22 @@ func (l *modelLoader) LoadAll(modelsDir string) error {
 	l.logger.Info("模型提供商数据加载完成", logger.Fields{
 		"providers":    len(providers),
 		"total_models": totalModels,
+		"errors":       len(loadErrs),
 	})
 
-	return nil
+	if len(loadErrs) > 0 {
+		return snapshot, loadErrs
+	}
+	return snapshot, nil
 }
 
 // LoadProviders 加载所有提供商配置
 func (l *modelLoader) LoadProviders(modelsDir string) ([]model.Provider, error) {
+	return l.loadProviders(modelsDir, &LoadErrors{})
+}
+
+// loadProviders 加载所有提供商配置，跳过的配置文件错误记录到 loadErrs
+func (l *modelLoader) loadProviders(modelsDir string, loadErrs *LoadErrors) ([]model.Provider, error) {
 	var providers []model.Provider
 
 	// 读取 models 目录
@@ -131,6 +158,7 @@ func (l *modelLoader) LoadProviders(modelsDir string) ([]model.Provider, error)
 				"path":     providerDir,
 				"error":    err.Error(),
 			})
+			loadErrs.add(providerID, providerDir, err)
 			continue
 		}
 
@@ -141,6 +169,7 @@ func (l *modelLoader) LoadProviders(modelsDir string) ([]model.Provider, error)
 				"path":     providerDir,
 				"error":    err.Error(),
 			})
+			loadErrs.add(providerID, providerDir, err)
 			continue
 		}
 
@@ -158,6 +187,7 @@ func (l *modelLoader) LoadProviders(modelsDir string) ([]model.Provider, error)
 				"provider": providerID,
 				"path":     providerDir,
 			})
+			loadErrs.add(providerID, providerDir, fmt.Errorf("未找到提供商配置文件"))
 			continue
 		}
 
@@ -168,6 +198,7 @@ func (l *modelLoader) LoadProviders(modelsDir string) ([]model.Provider, error)
 				"path":     yamlPath,
 				"error":    err.Error(),
 			})
+			loadErrs.add(providerID, yamlPath, err)
 			continue
 		}
 
@@ -179,6 +210,7 @@ func (l *modelLoader) LoadProviders(modelsDir string) ([]model.Provider, error)
 				"path":     yamlPath,
 				"error":    err.Error(),
 			})
+			loadErrs.add(providerID, yamlPath, err)
 			continue
 		}
 
@@ -190,6 +222,7 @@ func (l *modelLoader) LoadProviders(modelsDir string) ([]model.Provider, error)
 				"path":     yamlPath,
 				"error":    err.Error(),
 			})
+			loadErrs.add(providerID, yamlPath, err)
 			continue
 		}
 
@@ -206,14 +239,27 @@ func (l *modelLoader) LoadProviders(modelsDir string) ([]model.Provider, error)
 
 // LoadModels 加载指定提供商的所有模型
 func (l *modelLoader) LoadModels(providerDir string, providerID string, modelTypes map[string]model.ModelTypeInfo) ([]model.Model, error) {
+	return l.loadModels(providerDir, providerID, modelTypes, &LoadErrors{})
+}
+
+// loadModels 加载指定提供商的所有模型，跳过的配置文件错误记录到 loadErrs
+func (l *modelLoader) loadModels(providerDir string, providerID string, modelTypes map[string]model.ModelTypeInfo, loadErrs *LoadErrors) ([]model.Model, error) {
 	var allModels []model.Model
 
 	// 获取基础目录（用于路径安全验证）
 	baseDir := filepath.Dir(filepath.Dir(providerDir)) // 回到 models 目录
 
-	// 遍历每个模型类型
-	for modelType, typeInfo := range modelTypes {
-		modelsDir := filepath.Join(providerDir, "models", modelType)
+	// 遍历每个模型类型，提供商 YAML 中的键名统一为规范的模型类型
+	for typeKey, typeInfo := range modelTypes {
+		modelType, ok := model.ParseModelType(typeKey)
+		if !ok {
+			l.logger.Warn("未知的模型类型，跳过", logger.Fields{
+				"provider": providerID,
+				"type":     typeKey,
+			})
+			continue
+		}
+		modelsDir := resolveModelTypeDir(providerDir, typeKey, modelType)
 
 		// 验证路径安全性
 		if err := l.validatePathSafety(baseDir, modelsDir); err != nil {
@@ -223,6 +269,7 @@ func (l *modelLoader) LoadModels(providerDir string, providerID string, modelTyp
 				"path":     modelsDir,
 				"error":    err.Error(),
 			})
+			loadErrs.add(providerID, modelsDir, err)
 			continue
 		}
 
@@ -250,6 +297,7 @@ func (l *modelLoader) LoadModels(providerDir string, providerID string, modelTyp
 					"path":     positionPath,
 					"error":    err.Error(),
 				})
+				loadErrs.add(providerID, positionPath, err)
 				continue
 			}
 			
@@ -263,6 +311,7 @@ func (l *modelLoader) LoadModels(providerDir string, providerID string, modelTyp
 						"path":     positionPath,
 						"error":    err.Error(),
 					})
+					loadErrs.add(providerID, positionPath, err)
 				}
 			} else {
 				l.logger.Warn("position 文件不存在，将扫描目录", logger.Fields{
@@ -273,8 +322,36 @@ func (l *modelLoader) LoadModels(providerDir string, providerID string, modelTyp
 			}
 		}
 
-		// 如果没有 position 文件或解析失败，扫描目录
-		if len(modelNames) == 0 {
+		// 解析 predefined 通配符，匹配到但没有列在 position 文件中的模型排在后面
+		predefined, err := matchPredefined(providerDir, typeInfo.Predefined)
+		if err != nil {
+			l.logger.Error("解析 predefined 通配符失败", logger.Fields{
+				"provider":   providerID,
+				"type":       modelType,
+				"predefined": typeInfo.Predefined,
+				"error":      err.Error(),
+			})
+			loadErrs.add(providerID, modelsDir, err)
+			continue
+		}
+		var predefinedFiles []string
+		for _, path := range predefined {
+			// predefined 只能匹配提供商目录内的文件
+			if err := l.validatePathSafety(providerDir, path); err != nil {
+				l.logger.Error("predefined 匹配的文件路径不安全", logger.Fields{
+					"provider": providerID,
+					"type":     modelType,
+					"path":     path,
+					"error":    err.Error(),
+				})
+				loadErrs.add(providerID, path, err)
+				continue
+			}
+			predefinedFiles = append(predefinedFiles, path)
+		}
+
+		// 如果没有 position 文件（或解析失败）也没有 predefined 通配符，扫描目录
+		if len(modelNames) == 0 && len(typeInfo.Predefined) == 0 {
 			entries, err := os.ReadDir(modelsDir)
 			if err != nil {
 				l.logger.Error("读取模型目录失败", logger.Fields{
@@ -283,15 +360,13 @@ func (l *modelLoader) LoadModels(providerDir string, providerID string, modelTyp
 					"path":     modelsDir,
 					"error":    err.Error(),
 				})
+				loadErrs.add(providerID, modelsDir, err)
 				continue
 			}
 
 			for _, entry := range entries {
-				if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".yaml") {
-					continue
-				}
-				// 排除特殊文件
-				if strings.HasPrefix(entry.Name(), "_") {
+				// 排除目录和特殊文件
+				if entry.IsDir() || !isModelFileName(entry.Name()) {
 					continue
 				}
 				modelName := strings.TrimSuffix(entry.Name(), ".yaml")
@@ -300,7 +375,11 @@ func (l *modelLoader) LoadModels(providerDir string, providerID string, modelTyp
 		}
 
 		// 加载每个模型
-		for _, modelName := range modelNames {
+		modelFiles := orderModelFiles(modelsDir, modelNames, predefinedFiles)
+		loaded := 0
+		for _, file := range modelFiles {
+			modelName := file.name
+
 			// 验证模型名称的安全性
 			if err := validator.ValidateModelID(modelName); err != nil {
 				l.logger.Warn("模型名称验证失败，跳过", logger.Fields{
@@ -312,7 +391,7 @@ func (l *modelLoader) LoadModels(providerDir string, providerID string, modelTyp
 				continue
 			}
 
-			modelPath := filepath.Join(modelsDir, modelName+".yaml")
+			modelPath := file.path
 			
 			// 验证模型文件路径安全性
 			if err := l.validatePathSafety(baseDir, modelPath); err != nil {
@@ -323,10 +402,21 @@ func (l *modelLoader) LoadModels(providerDir string, providerID string, modelTyp
 					"path":     modelPath,
 					"error":    err.Error(),
 				})
+				loadErrs.add(providerID, modelPath, err)
 				continue
 			}
 			
 			data, err := os.ReadFile(modelPath)
+			if os.IsNotExist(err) {
+				// position 文件只用于排序，列出的模型没有配置文件时跳过
+				l.logger.Warn("模型配置文件不存在，跳过", logger.Fields{
+					"provider": providerID,
+					"type":     modelType,
+					"model":    modelName,
+					"path":     modelPath,
+				})
+				continue
+			}
 			if err != nil {
 				l.logger.Error("读取模型配置文件失败", logger.Fields{
 					"provider": providerID,
@@ -335,6 +425,7 @@ func (l *modelLoader) LoadModels(providerDir string, providerID string, modelTyp
 					"path":     modelPath,
 					"error":    err.Error(),
 				})
+				loadErrs.add(providerID, modelPath, err)
 				continue
 			}
 
@@ -347,19 +438,32 @@ func (l *modelLoader) LoadModels(providerDir string, providerID string, modelTyp
 					"path":     modelPath,
 					"error":    err.Error(),
 				})
+				loadErrs.add(providerID, modelPath, err)
 				continue
 			}
 
-			// 设置 model_type
+			// 设置 model_type，模型配置中的 model_type 与模型类型不一致时以提供商 YAML 为准
+			if mdl.ModelType != "" {
+				if declared, ok := model.ParseModelType(string(mdl.ModelType)); !ok || declared != modelType {
+					l.logger.Warn("模型配置的 model_type 与模型类型不一致", logger.Fields{
+						"provider":   providerID,
+						"type":       modelType,
+						"model":      modelName,
+						"model_type": mdl.ModelType,
+					})
+				}
+			}
 			mdl.ModelType = modelType
 
 			allModels = append(allModels, mdl)
+			loaded++
 		}
 
 		l.logger.Info("模型类型加载完成", logger.Fields{
 			"provider": providerID,
 			"type":     modelType,
-			"count":    len(modelNames),
+			"count":    loaded,
+			"skipped":  len(modelFiles) - loaded,
 		})
 	}
 
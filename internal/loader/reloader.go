@@ -0,0 +1,161 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/storage"
+	"genkit-ai-service/pkg/errors"
+)
+
+// Reloader 模型目录重新加载器
+// 重新加载时先将模型目录加载到新的快照，没有错误时原子地替换存储中的数据，有错误时保留当前数据
+type Reloader interface {
+	// Reload 重新加载模型目录，返回与当前数据相比新增、移除和变更的提供商和模型
+	Reload(ctx context.Context) (*model.CatalogReloadReport, error)
+
+	// Watch 按间隔轮询模型目录，文件发生变化时重新加载，直到 ctx 结束
+	Watch(ctx context.Context, interval time.Duration)
+}
+
+// reloader 模型目录重新加载器实现
+type reloader struct {
+	mu          sync.Mutex
+	loader      ModelLoader
+	store       storage.Store
+	modelsDir   string
+	fingerprint uint64 // 最近一次加载时模型目录的文件指纹
+	logger      logger.Logger
+}
+
+// NewReloader 创建模型目录重新加载器
+func NewReloader(loader ModelLoader, store storage.Store, modelsDir string, log logger.Logger) Reloader {
+	return &reloader{
+		loader:    loader,
+		store:     store,
+		modelsDir: modelsDir,
+		logger:    log,
+	}
+}
+
+// Reload 重新加载模型目录
+func (r *reloader) Reload(ctx context.Context) (*model.CatalogReloadReport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.reload(ctx)
+}
+
+// reload 重新加载模型目录，调用方需持有锁
+func (r *reloader) reload(ctx context.Context) (*model.CatalogReloadReport, error) {
+	// 记录加载时的文件指纹，加载失败时也记录，避免轮询时反复加载同一份有错误的配置
+	fingerprint, err := r.fingerprintDir()
+	if err != nil {
+		r.logger.WarnContext(ctx, "计算模型目录指纹失败", logger.Fields{"error": err.Error()})
+	}
+	r.fingerprint = fingerprint
+
+	snapshot, err := r.loader.LoadSnapshot(r.modelsDir)
+	if err != nil {
+		r.logger.WarnContext(ctx, "重新加载模型目录失败，保留当前数据", logger.Fields{"error": err.Error()})
+		return nil, errors.NewLoadDataError(err)
+	}
+
+	previous := r.store.Snapshot()
+	r.store.ReplaceAll(snapshot)
+
+	report := &model.CatalogReloadReport{
+		CatalogChanges: *r.store.Snapshot().Diff(previous),
+		ProvidersCount: r.store.GetProvidersCount(),
+		ModelsCount:    r.store.GetModelsCount(),
+		ReloadedAt:     time.Now(),
+	}
+
+	r.logger.InfoContext(ctx, "模型目录已重新加载", logger.Fields{
+		"providers":        report.ProvidersCount,
+		"models":           report.ModelsCount,
+		"providersAdded":   len(report.Providers.Added),
+		"providersRemoved": len(report.Providers.Removed),
+		"providersChanged": len(report.Providers.Changed),
+		"modelsAdded":      len(report.Models.Added),
+		"modelsRemoved":    len(report.Models.Removed),
+		"modelsChanged":    len(report.Models.Changed),
+	})
+
+	return report, nil
+}
+
+// Watch 按间隔轮询模型目录，文件发生变化时重新加载
+func (r *reloader) Watch(ctx context.Context, interval time.Duration) {
+	r.mu.Lock()
+	if r.fingerprint == 0 {
+		r.fingerprint, _ = r.fingerprintDir()
+	}
+	r.mu.Unlock()
+
+	r.logger.InfoContext(ctx, "开始监听模型目录变化", logger.Fields{
+		"dir":      r.modelsDir,
+		"interval": interval.String(),
+	})
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.reloadIfChanged(ctx)
+		}
+	}
+}
+
+// reloadIfChanged 模型目录的文件指纹变化时重新加载，错误只记录日志
+func (r *reloader) reloadIfChanged(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fingerprint, err := r.fingerprintDir()
+	if err != nil {
+		r.logger.WarnContext(ctx, "计算模型目录指纹失败", logger.Fields{"error": err.Error()})
+		return
+	}
+	if fingerprint == r.fingerprint {
+		return
+	}
+
+	r.logger.InfoContext(ctx, "检测到模型目录变化，重新加载", logger.Fields{"dir": r.modelsDir})
+	r.reload(ctx)
+}
+
+// fingerprintDir 根据模型目录下所有文件的路径、大小和修改时间计算指纹
+func (r *reloader) fingerprintDir() (uint64, error) {
+	hash := fnv.New64a()
+	err := filepath.WalkDir(r.modelsDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(hash, "%s\x00%d\x00%d\n", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return hash.Sum64(), nil
+}
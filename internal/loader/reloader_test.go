@@ -0,0 +1,182 @@
+package loader
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/storage"
+)
+
+const testProviderYAML = `provider: %s
+supported_model_types:
+  - llm
+models:
+  llm:
+    position: models/llm/_position.yaml
+`
+
+// writeFile 写入测试文件，自动创建目录
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("创建目录失败: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+}
+
+// writeProvider 写入提供商配置和模型配置
+func writeProvider(t *testing.T, modelsDir, providerID string, models map[string]string) {
+	t.Helper()
+	providerDir := filepath.Join(modelsDir, providerID)
+	writeFile(t, filepath.Join(providerDir, "provider", providerID+".yaml"), fmt.Sprintf(testProviderYAML, providerID))
+
+	position := ""
+	for name, content := range models {
+		position += "- " + name + "\n"
+		writeFile(t, filepath.Join(providerDir, "models", "llm", name+".yaml"), content)
+	}
+	writeFile(t, filepath.Join(providerDir, "models", "llm", "_position.yaml"), position)
+}
+
+func newTestReloader(t *testing.T) (string, *storage.MemoryStore, Reloader) {
+	t.Helper()
+	modelsDir := t.TempDir()
+	writeProvider(t, modelsDir, "alpha", map[string]string{
+		"alpha-small": "model: alpha-small\nmodel_properties:\n  context_size: 4096\n",
+		"alpha-large": "model: alpha-large\nmodel_properties:\n  context_size: 32768\n",
+	})
+	writeProvider(t, modelsDir, "beta", map[string]string{
+		"beta-chat": "model: beta-chat\n",
+	})
+
+	log := logger.New(logger.ErrorLevel, logger.TextFormat, os.Stdout)
+	store := storage.NewMemoryStore()
+	modelLoader := NewModelLoader(store, log)
+	if err := modelLoader.LoadAll(modelsDir); err != nil {
+		t.Fatalf("LoadAll() error = %v", err)
+	}
+	return modelsDir, store, NewReloader(modelLoader, store, modelsDir, log)
+}
+
+// TestReload 测试重新加载并报告变更
+func TestReload(t *testing.T) {
+	modelsDir, store, reloader := newTestReloader(t)
+
+	// 通过接口注册的自定义模型在重新加载后保留
+	if err := store.AddModel("alpha", model.Model{Model: "alpha-deployment", FetchFrom: model.FetchFromCustomizableModel}); err != nil {
+		t.Fatalf("AddModel() error = %v", err)
+	}
+
+	// 修改模型、删除模型、删除提供商并新增提供商
+	writeProvider(t, modelsDir, "alpha", map[string]string{
+		"alpha-small": "model: alpha-small\nmodel_properties:\n  context_size: 8192\n",
+	})
+	os.Remove(filepath.Join(modelsDir, "alpha", "models", "llm", "alpha-large.yaml"))
+	os.RemoveAll(filepath.Join(modelsDir, "beta"))
+	writeProvider(t, modelsDir, "gamma", map[string]string{
+		"gamma-chat": "model: gamma-chat\n",
+	})
+
+	report, err := reloader.Reload(context.Background())
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	want := model.CatalogChanges{
+		Providers: model.CatalogChangeSet{Added: []string{"gamma"}, Removed: []string{"beta"}, Changed: []string{}},
+		Models: model.CatalogChangeSet{
+			Added:   []string{"gamma/gamma-chat"},
+			Removed: []string{"alpha/alpha-large", "beta/beta-chat"},
+			Changed: []string{"alpha/alpha-small"},
+		},
+	}
+	if !reflect.DeepEqual(report.CatalogChanges, want) {
+		t.Errorf("变更报告不正确:\n得到 %+v\n期望 %+v", report.CatalogChanges, want)
+	}
+	if report.ProvidersCount != 2 || report.ModelsCount != 3 {
+		t.Errorf("数量不正确: providers=%d models=%d", report.ProvidersCount, report.ModelsCount)
+	}
+
+	if m, err := store.GetModel("alpha", "alpha-small"); err != nil || m.ModelProperties.ContextSize != 8192 {
+		t.Errorf("模型应已更新: %+v %v", m, err)
+	}
+	if _, err := store.GetModel("alpha", "alpha-deployment"); err != nil {
+		t.Errorf("自定义模型应保留: %v", err)
+	}
+
+	// 没有变化时报告为空
+	report, err = reloader.Reload(context.Background())
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if len(report.Providers.Added)+len(report.Providers.Changed)+len(report.Models.Changed)+len(report.Models.Removed) != 0 {
+		t.Errorf("没有变化时不应报告变更: %+v", report.CatalogChanges)
+	}
+}
+
+// TestReload_KeepsSnapshotOnError 测试配置有错误时保留当前数据
+func TestReload_KeepsSnapshotOnError(t *testing.T) {
+	modelsDir, store, reloader := newTestReloader(t)
+	before := store.Snapshot()
+
+	writeFile(t, filepath.Join(modelsDir, "alpha", "models", "llm", "alpha-small.yaml"), "model: [invalid\n")
+	writeProvider(t, modelsDir, "gamma", map[string]string{"gamma-chat": "model: gamma-chat\n"})
+
+	report, err := reloader.Reload(context.Background())
+	if err == nil {
+		t.Fatalf("期望返回错误, 得到报告 %+v", report)
+	}
+	var loadErrs LoadErrors
+	if !stderrors.As(err, &loadErrs) || len(loadErrs) != 1 || loadErrs[0].Provider != "alpha" {
+		t.Errorf("应返回加载错误列表: %v", err)
+	}
+
+	if changes := store.Snapshot().Diff(before); len(changes.Providers.Added)+len(changes.Models.Changed)+len(changes.Models.Removed) != 0 {
+		t.Errorf("加载失败时不应修改当前数据: %+v", changes)
+	}
+}
+
+// TestWatch 测试轮询到文件变化时重新加载
+func TestWatch(t *testing.T) {
+	modelsDir, store, reloader := newTestReloader(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		reloader.Watch(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	// 等待开始监听后再修改文件
+	time.Sleep(30 * time.Millisecond)
+	writeProvider(t, modelsDir, "gamma", map[string]string{"gamma-chat": "model: gamma-chat\n"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := store.GetModel("gamma", "gamma-chat"); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("文件变化后应自动重新加载")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ctx 结束后应停止监听")
+	}
+}
@@ -0,0 +1,481 @@
+package loader
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/pkg/validator"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 校验问题的严重程度
+const (
+	// SeverityError 错误，配置无法按预期加载
+	SeverityError = "error"
+	// SeverityWarning 警告，配置可以加载但可能存在问题
+	SeverityWarning = "warning"
+)
+
+// 校验问题类型
+const (
+	// IssueParseError YAML 无法解析
+	IssueParseError = "parse_error"
+	// IssueUnknownField YAML 中存在未定义的字段
+	IssueUnknownField = "unknown_field"
+	// IssueSchemaViolation 缺少必填字段或字段取值不合法
+	IssueSchemaViolation = "schema_violation"
+	// IssueUnknownTemplate parameter_rules 的 use_template 引用了不存在的参数模板
+	IssueUnknownTemplate = "unknown_template"
+	// IssueMissingModelFile _position.yaml 中列出的模型没有配置文件
+	IssueMissingModelFile = "missing_model_file"
+	// IssueUnlistedModelFile 模型配置文件没有列在 _position.yaml 中也不匹配 predefined，不会被加载（可能是有意隐藏的模型，报告为警告）
+	IssueUnlistedModelFile = "unlisted_model_file"
+	// IssueDuplicateModel 同一提供商下存在重复的模型ID
+	IssueDuplicateModel = "duplicate_model"
+	// IssueInvalidPricing 定价信息不合法
+	IssueInvalidPricing = "invalid_pricing"
+	// IssueUnknownFeature features 中存在未知的特性
+	IssueUnknownFeature = "unknown_feature"
+)
+
+// parameterTypes 参数规则允许的类型
+var parameterTypes = map[string]bool{
+	"float":   true,
+	"int":     true,
+	"string":  true,
+	"boolean": true,
+	"text":    true,
+}
+
+// llmModes 大语言模型允许的模式
+var llmModes = map[string]bool{
+	"chat":       true,
+	"completion": true,
+}
+
+// ValidationIssue 模型目录校验发现的问题
+type ValidationIssue struct {
+	// 严重程度：error 或 warning
+	Severity string `json:"severity"`
+	// 问题类型
+	Kind string `json:"kind"`
+	// 提供商ID
+	Provider string `json:"provider,omitempty"`
+	// 模型ID
+	Model string `json:"model,omitempty"`
+	// 出现问题的文件或目录路径
+	Path string `json:"path"`
+	// 问题描述
+	Message string `json:"message"`
+}
+
+// String 返回问题的单行描述
+func (i ValidationIssue) String() string {
+	return fmt.Sprintf("%s [%s] %s: %s", i.Severity, i.Kind, i.Path, i.Message)
+}
+
+// ValidationReport 模型目录校验报告
+type ValidationReport struct {
+	// 成功解析的提供商数量
+	Providers int `json:"providers"`
+	// 成功解析的模型数量
+	Models int `json:"models"`
+	// 错误数量
+	Errors int `json:"errors"`
+	// 警告数量
+	Warnings int `json:"warnings"`
+	// 发现的问题，按路径排序
+	Issues []ValidationIssue `json:"issues"`
+}
+
+// HasErrors 判断是否存在错误
+func (r *ValidationReport) HasErrors() bool {
+	return r.Errors > 0
+}
+
+// Validate 严格校验模型目录，不修改存储
+// 与加载器跳过出错的配置不同，Validate 检查全部提供商和模型配置并报告所有问题，
+// 包括字段校验、未知的参数模板和特性、_position.yaml 与模型文件不一致、重复的模型ID和不合法的定价；
+// 只有无法读取 models 目录时返回错误
+func Validate(modelsDir string) (*ValidationReport, error) {
+	cleanModelsDir := filepath.Clean(modelsDir)
+	entries, err := os.ReadDir(cleanModelsDir)
+	if err != nil {
+		return nil, fmt.Errorf("读取 models 目录失败: %w", err)
+	}
+
+	v := &catalogValidator{report: &ValidationReport{Issues: []ValidationIssue{}}}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || strings.HasPrefix(entry.Name(), "_") {
+			continue
+		}
+		v.validateProvider(cleanModelsDir, entry.Name())
+	}
+
+	sort.SliceStable(v.report.Issues, func(i, j int) bool {
+		return v.report.Issues[i].Path < v.report.Issues[j].Path
+	})
+	return v.report, nil
+}
+
+// catalogValidator 模型目录校验过程的状态
+type catalogValidator struct {
+	report *ValidationReport
+}
+
+// add 记录一个问题
+func (v *catalogValidator) add(severity, kind, providerID, modelID, path, format string, args ...interface{}) {
+	v.report.Issues = append(v.report.Issues, ValidationIssue{
+		Severity: severity,
+		Kind:     kind,
+		Provider: providerID,
+		Model:    modelID,
+		Path:     path,
+		Message:  fmt.Sprintf(format, args...),
+	})
+	if severity == SeverityError {
+		v.report.Errors++
+	} else {
+		v.report.Warnings++
+	}
+}
+
+// decode 严格解析 YAML 文件，未定义的字段记录为警告，其他解析错误记录为错误并返回 false
+func (v *catalogValidator) decode(providerID, path string, out interface{}) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		v.add(SeverityError, IssueParseError, providerID, "", path, "读取文件失败: %v", err)
+		return false
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err = decoder.Decode(out)
+	if err == nil {
+		return true
+	}
+
+	// 类型错误时解码器仍会解析其余字段，只有未定义的字段可以忽略
+	typeErr, ok := err.(*yaml.TypeError)
+	if !ok {
+		v.add(SeverityError, IssueParseError, providerID, "", path, "%v", err)
+		return false
+	}
+	valid := true
+	for _, message := range typeErr.Errors {
+		if strings.Contains(message, "not found in type") {
+			v.add(SeverityWarning, IssueUnknownField, providerID, "", path, "%s", message)
+			continue
+		}
+		v.add(SeverityError, IssueParseError, providerID, "", path, "%s", message)
+		valid = false
+	}
+	return valid
+}
+
+// validateProvider 校验提供商配置及其全部模型
+func (v *catalogValidator) validateProvider(modelsDir, providerID string) {
+	providerRoot := filepath.Join(modelsDir, providerID)
+	if err := validator.ValidateProviderID(providerID); err != nil {
+		v.add(SeverityError, IssueSchemaViolation, providerID, "", providerRoot, "提供商目录名称不合法: %v", err)
+		return
+	}
+
+	// 查找提供商配置文件
+	providerDir := filepath.Join(providerRoot, "provider")
+	providerEntries, err := os.ReadDir(providerDir)
+	if err != nil {
+		v.add(SeverityError, IssueSchemaViolation, providerID, "", providerDir, "读取提供商目录失败: %v", err)
+		return
+	}
+	var yamlPath string
+	for _, entry := range providerEntries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".yaml") {
+			yamlPath = filepath.Join(providerDir, entry.Name())
+			break
+		}
+	}
+	if yamlPath == "" {
+		v.add(SeverityError, IssueSchemaViolation, providerID, "", providerDir, "未找到提供商配置文件")
+		return
+	}
+
+	var provider model.Provider
+	if !v.decode(providerID, yamlPath, &provider) {
+		return
+	}
+	v.report.Providers++
+	v.checkProvider(providerID, yamlPath, &provider)
+
+	// 按模型类型校验模型，同一提供商下的模型ID不能重复
+	modelPaths := make(map[string]string)
+	typeKeys := make([]string, 0, len(provider.Models))
+	for typeKey := range provider.Models {
+		typeKeys = append(typeKeys, typeKey)
+	}
+	sort.Strings(typeKeys)
+	for _, typeKey := range typeKeys {
+		modelType, ok := model.ParseModelType(typeKey)
+		if !ok {
+			v.add(SeverityError, IssueSchemaViolation, providerID, "", yamlPath, "models 中未知的模型类型 '%s'", typeKey)
+			continue
+		}
+		if !provider.SupportsModelType(modelType) {
+			v.add(SeverityWarning, IssueSchemaViolation, providerID, "", yamlPath, "模型类型 '%s' 没有列在 supported_model_types 中", typeKey)
+		}
+		v.validateModelType(providerRoot, providerID, typeKey, modelType, provider.Models[typeKey], modelPaths)
+	}
+}
+
+// checkProvider 校验提供商配置的字段
+func (v *catalogValidator) checkProvider(providerID, path string, provider *model.Provider) {
+	if provider.Provider == "" {
+		v.add(SeverityError, IssueSchemaViolation, providerID, "", path, "缺少 provider")
+	}
+	if len(provider.Label) == 0 {
+		v.add(SeverityError, IssueSchemaViolation, providerID, "", path, "缺少 label")
+	}
+	if len(provider.SupportedModelTypes) == 0 {
+		v.add(SeverityError, IssueSchemaViolation, providerID, "", path, "缺少 supported_model_types")
+	}
+	for _, supported := range provider.SupportedModelTypes {
+		if _, ok := model.ParseModelType(supported); !ok {
+			v.add(SeverityError, IssueSchemaViolation, providerID, "", path, "未知的模型类型 '%s'", supported)
+		}
+	}
+	if len(provider.ConfigurateMethods) == 0 {
+		v.add(SeverityError, IssueSchemaViolation, providerID, "", path, "缺少 configurate_methods")
+	}
+	for _, method := range provider.ConfigurateMethods {
+		if method != model.FetchFromPredefinedModel && method != model.FetchFromCustomizableModel {
+			v.add(SeverityError, IssueSchemaViolation, providerID, "", path, "未知的配置方法 '%s'", method)
+		}
+	}
+
+	schemas := map[string]model.CredentialSchema{
+		"provider_credential_schema": provider.ProviderCredentialSchema,
+		"model_credential_schema":    provider.ModelCredentialSchema,
+	}
+	for _, name := range []string{"provider_credential_schema", "model_credential_schema"} {
+		for i, field := range schemas[name].CredentialFormSchemas {
+			v.checkFormSchema(providerID, path, fmt.Sprintf("%s.credential_form_schemas[%d]", name, i), field)
+		}
+	}
+}
+
+// checkFormSchema 校验凭证表单配置项
+func (v *catalogValidator) checkFormSchema(providerID, path, location string, field model.CredentialFormSchema) {
+	if field.Variable == "" {
+		v.add(SeverityError, IssueSchemaViolation, providerID, "", path, "%s 缺少 variable", location)
+	}
+	switch field.Type {
+	case model.FormTypeTextInput, model.FormTypeSecretInput:
+	case model.FormTypeSelect, model.FormTypeRadio:
+		if len(field.Options) == 0 {
+			v.add(SeverityError, IssueSchemaViolation, providerID, "", path, "%s (%s) 类型为 %s 但没有 options", location, field.Variable, field.Type)
+		}
+	default:
+		v.add(SeverityError, IssueSchemaViolation, providerID, "", path, "%s (%s) 的类型 '%s' 不合法", location, field.Variable, field.Type)
+	}
+}
+
+// validateModelType 校验一个模型类型目录下的模型、_position.yaml 和 predefined 通配符
+func (v *catalogValidator) validateModelType(providerRoot, providerID, typeKey string, modelType model.ModelType, typeInfo model.ModelTypeInfo, modelPaths map[string]string) {
+	typeDir := resolveModelTypeDir(providerRoot, typeKey, modelType)
+	entries, err := os.ReadDir(typeDir)
+	if err != nil {
+		v.add(SeverityWarning, IssueSchemaViolation, providerID, "", typeDir, "模型类型 %s 的目录不存在或无法读取", typeKey)
+		return
+	}
+
+	// 目录中的模型配置文件（不含扩展名）
+	var files []string
+	fileSet := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() || !isModelFileName(entry.Name()) {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".yaml")
+		files = append(files, name)
+		fileSet[name] = true
+	}
+
+	// 加载器加载 position 文件中列出的模型和 predefined 匹配到的模型，两者都没有配置时加载目录中的全部模型
+	loadedPaths := make(map[string]bool)
+	predefined, err := matchPredefined(providerRoot, typeInfo.Predefined)
+	if err != nil {
+		v.add(SeverityError, IssueSchemaViolation, providerID, "", typeDir, "predefined 通配符不合法: %v", err)
+	}
+	for _, path := range predefined {
+		loadedPaths[path] = true
+	}
+
+	// _position.yaml 列出的模型必须有配置文件
+	listed := make(map[string]bool)
+	if typeInfo.Position != "" {
+		positionPath := filepath.Join(providerRoot, typeInfo.Position)
+		var positions []string
+		if _, err := os.Stat(positionPath); os.IsNotExist(err) {
+			if len(typeInfo.Predefined) == 0 {
+				v.add(SeverityWarning, IssueSchemaViolation, providerID, "", positionPath, "position 文件不存在，将加载目录中的全部模型")
+			} else {
+				v.add(SeverityWarning, IssueSchemaViolation, providerID, "", positionPath, "position 文件不存在，模型按 predefined 匹配顺序加载")
+			}
+		} else if v.decode(providerID, positionPath, &positions) {
+			for _, name := range positions {
+				if listed[name] {
+					v.add(SeverityError, IssueDuplicateModel, providerID, name, positionPath, "模型 '%s' 重复列出", name)
+					continue
+				}
+				listed[name] = true
+				loadedPaths[filepath.Join(typeDir, name+".yaml")] = true
+				if !fileSet[name] {
+					v.add(SeverityError, IssueMissingModelFile, providerID, name, positionPath, "列出的模型 '%s' 没有配置文件 %s.yaml", name, name)
+				}
+			}
+		}
+	}
+
+	var paths []string
+	for _, name := range files {
+		path := filepath.Join(typeDir, name+".yaml")
+		if len(listed) > 0 || len(typeInfo.Predefined) > 0 {
+			if !loadedPaths[path] {
+				v.add(SeverityWarning, IssueUnlistedModelFile, providerID, name, path, "模型未列在 position 文件中也不匹配 predefined，不会被加载")
+			}
+		}
+		paths = append(paths, path)
+	}
+	// predefined 可以匹配模型类型目录以外的文件
+	for _, path := range predefined {
+		if filepath.Dir(path) != typeDir {
+			paths = append(paths, path)
+		}
+	}
+
+	for _, path := range paths {
+		v.validateModel(providerID, modelType, path, strings.TrimSuffix(filepath.Base(path), ".yaml"), modelPaths)
+	}
+}
+
+// validateModel 校验单个模型配置文件
+func (v *catalogValidator) validateModel(providerID string, modelType model.ModelType, path, fileName string, modelPaths map[string]string) {
+	if err := validator.ValidateModelID(fileName); err != nil {
+		v.add(SeverityError, IssueSchemaViolation, providerID, fileName, path, "模型文件名称不合法: %v", err)
+	}
+
+	var mdl model.Model
+	if !v.decode(providerID, path, &mdl) {
+		return
+	}
+	v.report.Models++
+
+	modelID := mdl.Model
+	if modelID == "" {
+		v.add(SeverityError, IssueSchemaViolation, providerID, fileName, path, "缺少 model")
+		modelID = fileName
+	} else if previous, exists := modelPaths[modelID]; exists {
+		v.add(SeverityError, IssueDuplicateModel, providerID, modelID, path, "模型ID '%s' 与 %s 重复", modelID, previous)
+	} else {
+		modelPaths[modelID] = path
+	}
+
+	// 模型配置中的 model_type 必须与所属的模型类型一致
+	if mdl.ModelType != "" {
+		if declared, ok := model.ParseModelType(string(mdl.ModelType)); !ok || declared != modelType {
+			v.add(SeverityError, IssueSchemaViolation, providerID, modelID, path, "model_type '%s' 与模型类型 '%s' 不一致", mdl.ModelType, modelType)
+		}
+	}
+
+	if modelType == model.ModelTypeLLM {
+		if !llmModes[mdl.ModelProperties.Mode] {
+			v.add(SeverityError, IssueSchemaViolation, providerID, modelID, path, "model_properties.mode '%s' 不合法，必须是 chat 或 completion", mdl.ModelProperties.Mode)
+		}
+		if mdl.ModelProperties.ContextSize <= 0 {
+			v.add(SeverityError, IssueSchemaViolation, providerID, modelID, path, "model_properties.context_size 必须大于0")
+		}
+	}
+
+	for _, feature := range mdl.Features {
+		if !isKnownFeature(feature) {
+			v.add(SeverityError, IssueUnknownFeature, providerID, modelID, path, "未知的特性 '%s'", feature)
+		}
+	}
+
+	v.checkParameterRules(providerID, modelID, path, mdl.ParameterRules)
+	v.checkPricing(providerID, modelID, path, mdl.Pricing)
+}
+
+// checkParameterRules 校验参数规则
+func (v *catalogValidator) checkParameterRules(providerID, modelID, path string, rules []model.ParameterRule) {
+	names := make(map[string]bool, len(rules))
+	for i, rule := range rules {
+		if rule.Name == "" {
+			v.add(SeverityError, IssueSchemaViolation, providerID, modelID, path, "parameter_rules[%d] 缺少 name", i)
+		} else if names[rule.Name] {
+			v.add(SeverityError, IssueSchemaViolation, providerID, modelID, path, "参数 '%s' 重复定义", rule.Name)
+		}
+		names[rule.Name] = true
+
+		if rule.UseTemplate != "" {
+			if _, ok := model.ParameterTemplates[rule.UseTemplate]; !ok {
+				v.add(SeverityError, IssueUnknownTemplate, providerID, modelID, path, "参数 '%s' 引用了未知的模板 '%s'", rule.Name, rule.UseTemplate)
+			}
+		} else if rule.Type == "" {
+			v.add(SeverityError, IssueSchemaViolation, providerID, modelID, path, "参数 '%s' 既没有 use_template 也没有 type", rule.Name)
+		}
+		if rule.Type != "" && !parameterTypes[rule.Type] {
+			v.add(SeverityError, IssueSchemaViolation, providerID, modelID, path, "参数 '%s' 的类型 '%s' 不合法", rule.Name, rule.Type)
+		}
+	}
+}
+
+// checkPricing 校验定价信息，未配置定价时跳过
+// 价格和单位必须是非负数，且必须指定货币
+func (v *catalogValidator) checkPricing(providerID, modelID, path string, pricing model.Pricing) {
+	if pricing == (model.Pricing{}) {
+		return
+	}
+
+	prices := []struct {
+		name  string
+		value string
+	}{
+		{name: "input", value: pricing.Input},
+		{name: "output", value: pricing.Output},
+	}
+	for _, price := range prices {
+		if price.value == "" {
+			continue
+		}
+		if number, err := strconv.ParseFloat(price.value, 64); err != nil || number < 0 {
+			v.add(SeverityError, IssueInvalidPricing, providerID, modelID, path, "pricing.%s '%s' 不是合法的价格", price.name, price.value)
+		}
+	}
+	if pricing.Input == "" && pricing.Output == "" {
+		v.add(SeverityError, IssueInvalidPricing, providerID, modelID, path, "pricing 缺少 input 和 output")
+	}
+	if unit, err := strconv.ParseFloat(pricing.Unit, 64); err != nil || unit < 0 {
+		v.add(SeverityError, IssueInvalidPricing, providerID, modelID, path, "pricing.unit '%s' 不是合法的数字", pricing.Unit)
+	}
+	if strings.TrimSpace(pricing.Currency) == "" {
+		v.add(SeverityError, IssueInvalidPricing, providerID, modelID, path, "pricing 缺少 currency")
+	}
+}
+
+// isKnownFeature 判断特性是否在 model.KnownFeatures 中
+func isKnownFeature(feature string) bool {
+	for _, known := range model.KnownFeatures {
+		if known == feature {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package loader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const validatorProviderYAML = `provider: acme
+label:
+  en_US: Acme
+configurate_methods:
+  - predefined-model
+  - manual
+supported_model_types:
+  - llm
+provider_credential_schema:
+  credential_form_schemas:
+    - variable: api_key
+      type: secret-input
+    - variable: region
+      type: select
+models:
+  llm:
+    position: models/llm/_position.yaml
+`
+
+// TestValidate 测试严格校验模型目录
+func TestValidate(t *testing.T) {
+	modelsDir := t.TempDir()
+	files := map[string]string{
+		"acme/provider/acme.yaml":           validatorProviderYAML,
+		"acme/models/llm/_position.yaml":    "- acme-chat\n- acme-ghost\n- acme-copy\n- acme-chat\n",
+		"acme/models/llm/acme-chat.yaml":    "model: acme-chat\nmodel_properties:\n  mode: chat\n  context_size: 8192\nfeatures:\n  - vision\n  - telepathy\nparameter_rules:\n  - name: temperature\n    use_template: temperature\n  - name: seed\n    use_template: random_seed\n  - name: top_k\n    type: integer\npricing:\n  input: '0.001'\n  output: 'free'\n  unit: '0.001'\n",
+		"acme/models/llm/acme-copy.yaml":    "model: acme-chat\nmodel_properties:\n  mode: chat\n  context_size: 8192\n",
+		"acme/models/llm/acme-hidden.yaml":  "model: acme-hidden\nmodel_properties:\n  mode: chat\n  context_size: 8192\n  precision: 2\n",
+		"broken/provider/broken.yaml":       "provider: [broken\n",
+		"acme/models/llm/acme-invalid.yaml": "model: acme-invalid\nmodel_properties:\n  mode: chat\n  context_size: large\n",
+	}
+	for name, content := range files {
+		writeFile(t, filepath.Join(modelsDir, name), content)
+	}
+	// acme-invalid 列在 position 中，只校验解析错误
+	writeFile(t, filepath.Join(modelsDir, "acme/models/llm/_position.yaml"), "- acme-chat\n- acme-ghost\n- acme-copy\n- acme-chat\n- acme-invalid\n")
+
+	report, err := Validate(modelsDir)
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	count := make(map[string]int)
+	for _, issue := range report.Issues {
+		count[issue.Severity+"/"+issue.Kind]++
+		t.Log(issue.String())
+	}
+
+	want := map[string]int{
+		"error/" + IssueParseError:          2, // broken.yaml、acme-invalid.yaml
+		"error/" + IssueSchemaViolation:     3, // 未知的配置方法、select 没有选项、参数类型不合法
+		"error/" + IssueUnknownTemplate:     1,
+		"error/" + IssueMissingModelFile:    1,
+		"error/" + IssueDuplicateModel:      2, // position 重复列出、模型ID重复
+		"error/" + IssueInvalidPricing:      2, // output 不是数字、缺少 currency
+		"error/" + IssueUnknownFeature:      1,
+		"warning/" + IssueUnlistedModelFile: 1,
+		"warning/" + IssueUnknownField:      1,
+	}
+	for key, n := range want {
+		if count[key] != n {
+			t.Errorf("%s: 期望 %d 个问题, 得到 %d 个", key, n, count[key])
+		}
+	}
+	if len(count) != len(want) {
+		t.Errorf("问题类型不符合预期: %v", count)
+	}
+
+	if report.Providers != 1 || report.Models != 3 {
+		t.Errorf("数量不正确: providers=%d models=%d", report.Providers, report.Models)
+	}
+	if !report.HasErrors() || report.Warnings != 2 {
+		t.Errorf("错误和警告数量不正确: errors=%d warnings=%d", report.Errors, report.Warnings)
+	}
+}
+
+// TestValidate_ModelType 测试模型类型和 predefined 通配符的校验
+func TestValidate_ModelType(t *testing.T) {
+	modelsDir := t.TempDir()
+	files := map[string]string{
+		"acme/provider/acme.yaml":                  "provider: acme\nlabel:\n  en_US: Acme\nconfigurate_methods:\n  - predefined-model\nsupported_model_types:\n  - text-embedding\n  - vision\nmodels:\n  text_embedding:\n    predefined:\n      - models/text-embedding/embed-*.yaml\n  rerank: {}\n",
+		"acme/models/text-embedding/embed-v1.yaml": "model: embed-v1\nmodel_type: text-embedding\n",
+		"acme/models/text-embedding/embed-v2.yaml": "model: embed-v2\nmodel_type: llm\n",
+		"acme/models/text-embedding/legacy.yaml":   "model: legacy\nmodel_type: text_embedding\n",
+		"acme/models/rerank/rerank-v1.yaml":        "model: rerank-v1\nmodel_type: rerank\n",
+	}
+	for name, content := range files {
+		writeFile(t, filepath.Join(modelsDir, name), content)
+	}
+
+	report, err := Validate(modelsDir)
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	var messages []string
+	for _, issue := range report.Issues {
+		messages = append(messages, issue.Severity+"/"+issue.Kind+"/"+issue.Model)
+		t.Log(issue.String())
+	}
+	want := []string{
+		"error/" + IssueSchemaViolation + "/",         // 未知的模型类型 vision
+		"warning/" + IssueSchemaViolation + "/",       // rerank 没有列在 supported_model_types 中
+		"error/" + IssueSchemaViolation + "/embed-v2", // model_type 与模型类型不一致
+		"warning/" + IssueUnlistedModelFile + "/legacy",
+	}
+	if len(messages) != len(want) {
+		t.Fatalf("期望 %d 个问题, 得到 %v", len(want), messages)
+	}
+	for _, w := range want {
+		found := false
+		for _, m := range messages {
+			if m == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("缺少问题 %s, 得到 %v", w, messages)
+		}
+	}
+	if report.Models != 4 {
+		t.Errorf("期望校验 4 个模型, 得到 %d 个", report.Models)
+	}
+}
+
+// TestValidate_MissingDir 测试模型目录不存在
+func TestValidate_MissingDir(t *testing.T) {
+	if _, err := Validate(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("模型目录不存在时应返回错误")
+	}
+}
+
+// TestValidate_ModelsDir 校验项目中的模型目录，不允许存在错误
+func TestValidate_ModelsDir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("获取工作目录失败: %v", err)
+	}
+	modelsDir := filepath.Join(wd, "..", "..", "models")
+	if _, err := os.Stat(modelsDir); os.IsNotExist(err) {
+		t.Skipf("models 目录不存在: %s", modelsDir)
+	}
+
+	report, err := Validate(modelsDir)
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	for _, issue := range report.Issues {
+		if issue.Severity == SeverityError {
+			t.Error(issue.String())
+		}
+	}
+	t.Logf("%d 个提供商, %d 个模型, %d 个警告", report.Providers, report.Models, report.Warnings)
+}
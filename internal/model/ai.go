@@ -1,5 +1,11 @@
 package model
 
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
 // ChatResponse 对话响应
 type ChatResponse struct {
 	// 会话ID
@@ -10,6 +16,129 @@ type ChatResponse struct {
 	Model string `json:"model" example:"gemini-1.5-flash"`
 	// Token使用情况
 	Usage *Usage `json:"usage,omitempty"`
+	// 模型请求的工具调用（仅在请求提供了工具时返回）
+	ToolCalls []ToolCall `json:"toolCalls,omitempty"`
+	// 按 JSON Schema 解析的回复（仅在请求指定了 JSON Schema 时返回），message 为原始文本
+	Parsed interface{} `json:"parsed,omitempty"`
+}
+
+// AbortResponse 中止对话响应
+type AbortResponse struct {
+	// 是否取消了正在进行的生成（消息不存在或已完成时为 false）
+	Aborted bool `json:"aborted" example:"true"`
+}
+
+// ChatTurn 历史对话中的一条消息
+type ChatTurn struct {
+	// 角色 (system, user, assistant, function)
+	Role string `json:"role" example:"user"`
+	// 消息内容，function 消息为工具执行结果
+	Content string `json:"content" example:"你好"`
+	// assistant 消息请求的工具调用
+	ToolCalls []ToolCall `json:"toolCalls,omitempty"`
+	// function 消息对应的工具调用ID
+	ToolCallID string `json:"toolCallId,omitempty"`
+	// function 消息对应的工具名称
+	Name string `json:"name,omitempty"`
+	// user 消息附带的媒体片段
+	Parts []MessagePart `json:"parts,omitempty"`
+}
+
+// 消息片段类型
+const (
+	// PartTypeText 文本片段
+	PartTypeText = "text"
+	// PartTypeMedia 媒体片段（图片、文档、音频、视频）
+	PartTypeMedia = "media"
+)
+
+// MessagePart 多模态消息片段
+type MessagePart struct {
+	// 片段类型 (text, media)
+	Type string `json:"type" validate:"required,oneof=text media" example:"media"`
+	// 文本内容（text 片段）
+	Text string `json:"text,omitempty" example:"这张截图里的报错是什么意思？"`
+	// 媒体的 MIME 类型（media 片段），如 image/png、application/pdf、audio/mpeg；引用上传文件时取文件检测到的类型
+	MimeType string `json:"mimeType,omitempty" validate:"omitempty,max=128" example:"image/png"`
+	// base64 编码的媒体内容（media 片段，与 url 二选一）；引用上传文件时由服务端填充文件内容
+	Data string `json:"data,omitempty"`
+	// 媒体地址（media 片段，与 data 二选一），模型需能直接访问
+	URL string `json:"url,omitempty" validate:"omitempty,url,max=2048" example:"https://example.com/screenshot.png"`
+	// 引用的上传文件ID（media 片段，代替 data 和 url），只能在会话消息中使用
+	FileID string `json:"fileId,omitempty" validate:"omitempty,uuid" example:"550e8400-e29b-41d4-a716-446655440000"`
+}
+
+// Validate 检查片段内容是否完整：text 片段需要文本，media 片段需要 MIME 类型以及 data 或 url 之一，
+// 或者引用上传文件的 fileId
+func (p MessagePart) Validate() error {
+	switch p.Type {
+	case PartTypeText:
+		if p.Text == "" {
+			return fmt.Errorf("text 片段的文本不能为空")
+		}
+	case PartTypeMedia:
+		if p.FileID != "" {
+			if p.URL != "" {
+				return fmt.Errorf("引用文件的 media 片段不能同时指定 url")
+			}
+			return nil
+		}
+		if p.MimeType == "" {
+			return fmt.Errorf("media 片段必须指定 mimeType")
+		}
+		if (p.Data == "") == (p.URL == "") {
+			return fmt.Errorf("media 片段必须指定 data、url 或 fileId 之一")
+		}
+		if p.Data != "" {
+			if _, err := base64.StdEncoding.DecodeString(p.Data); err != nil {
+				return fmt.Errorf("media 片段的 data 不是有效的 base64 编码")
+			}
+		}
+	default:
+		return fmt.Errorf("不支持的片段类型 '%s'", p.Type)
+	}
+	return nil
+}
+
+// SplitMessageParts 将文本片段依次并入消息内容，返回合并后的文本和媒体片段
+func SplitMessageParts(message string, parts []MessagePart) (string, []MessagePart) {
+	texts := make([]string, 0, len(parts)+1)
+	if message != "" {
+		texts = append(texts, message)
+	}
+
+	var media []MessagePart
+	for _, part := range parts {
+		if part.Type == PartTypeText {
+			if part.Text != "" {
+				texts = append(texts, part.Text)
+			}
+			continue
+		}
+		media = append(media, part)
+	}
+
+	return strings.Join(texts, "\n\n"), media
+}
+
+// ToolDefinition 提供给模型的工具定义
+type ToolDefinition struct {
+	// 工具名称
+	Name string `json:"name" example:"get_current_time"`
+	// 工具描述，模型据此决定是否调用
+	Description string `json:"description" example:"获取当前时间"`
+	// 参数的 JSON Schema
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// ToolCall 模型发起的一次工具调用
+type ToolCall struct {
+	// 工具调用ID，工具结果通过该ID与调用对应
+	ID string `json:"id" example:"call_abc123"`
+	// 工具名称
+	Name string `json:"name" example:"get_current_time"`
+	// JSON 格式的调用参数
+	Arguments string `json:"arguments" example:"{\"timezone\":\"Asia/Shanghai\"}"`
 }
 
 // Usage Token使用情况
@@ -28,6 +157,16 @@ type StreamChunk struct {
 	Content string `json:"content"`
 	// 是否完成
 	Done bool `json:"done"`
+	// 会话ID（仅在完成块中返回）
+	SessionID string `json:"sessionId,omitempty"`
+	// 使用的模型名称（仅在完成块中返回）
+	Model string `json:"model,omitempty"`
+	// Token使用情况（仅在完成块中返回）
+	Usage *Usage `json:"usage,omitempty"`
+	// 模型请求的工具调用（仅在完成块中返回）
+	ToolCalls []ToolCall `json:"toolCalls,omitempty"`
+	// 按 JSON Schema 解析的回复（仅在完成块中返回）
+	Parsed interface{} `json:"parsed,omitempty"`
 	// 错误信息
-	Error error `json:"error,omitempty"`
+	Error error `json:"-"`
 }
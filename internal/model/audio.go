@@ -0,0 +1,33 @@
+package model
+
+// SpeechRequest 语音合成请求
+type SpeechRequest struct {
+	// 语音合成模型名称（支持 "提供商/模型" 格式），必须是 tts 类型的模型
+	Model string `json:"model" validate:"required,max=128" example:"tongyi/tts-1"`
+	// 要合成的文本（与 messageId 二选一）
+	Input string `json:"input,omitempty" validate:"required_without=MessageID" example:"你好，欢迎使用语音合成。"`
+	// 要合成内容的会话消息ID（与 input 二选一）
+	MessageID string `json:"messageId,omitempty" validate:"omitempty,uuid" example:"550e8400-e29b-41d4-a716-446655440000"`
+	// 音色（可选，默认使用模型的默认音色），必须是模型音色列表中的音色
+	Voice string `json:"voice,omitempty" validate:"omitempty,max=128" example:"sambert-zhiru-v1"`
+}
+
+// TranscriptionRequest 语音识别请求，以 multipart/form-data 表单字段提交，音频放在 file 字段中
+type TranscriptionRequest struct {
+	// 语音识别模型名称（支持 "提供商/模型" 格式），必须是 speech2text 类型的模型
+	Model string `json:"model" validate:"required,max=128" example:"tongyi/paraformer-realtime-v2"`
+	// 音频的语言（可选），如 zh、en
+	Language string `json:"language,omitempty" validate:"omitempty,max=16" example:"zh"`
+	// 会话ID（可选），指定时将识别出的文本作为用户消息追加到会话中
+	SessionID string `json:"sessionId,omitempty" validate:"omitempty,uuid" example:"550e8400-e29b-41d4-a716-446655440000"`
+}
+
+// TranscriptionResponse 语音识别响应
+type TranscriptionResponse struct {
+	// 使用的模型
+	Model string `json:"model" example:"paraformer-realtime-v2"`
+	// 识别出的文本
+	Text string `json:"text" example:"你好，今天天气怎么样？"`
+	// 追加到会话中的用户消息ID，未指定会话或未识别出文本时为空
+	MessageID string `json:"messageId,omitempty" example:"550e8400-e29b-41d4-a716-446655440001"`
+}
@@ -0,0 +1,87 @@
+package model
+
+import "time"
+
+// CatalogChangeSet 模型目录中一类条目的变更
+type CatalogChangeSet struct {
+	// 新增的条目
+	Added []string `json:"added" example:"tongyi"`
+	// 移除的条目
+	Removed []string `json:"removed"`
+	// 配置发生变化的条目
+	Changed []string `json:"changed" example:"gemini"`
+}
+
+// CatalogChanges 两次加载之间模型目录的变更
+type CatalogChanges struct {
+	// 提供商变更，条目为提供商ID
+	Providers CatalogChangeSet `json:"providers"`
+	// 模型变更，条目为 provider/model 形式
+	Models CatalogChangeSet `json:"models"`
+}
+
+// CatalogReloadReport 模型目录重新加载报告
+type CatalogReloadReport struct {
+	// 与上一次加载相比的变更
+	CatalogChanges
+	// 重新加载后的提供商数量
+	ProvidersCount int `json:"providers_count" example:"3"`
+	// 重新加载后的模型数量
+	ModelsCount int `json:"models_count" example:"42"`
+	// 重新加载时间
+	ReloadedAt time.Time `json:"reloaded_at" example:"2024-01-01T00:00:00Z"`
+}
+
+// 模型搜索的排序字段
+const (
+	// ModelSortByProvider 按提供商ID排序，同一提供商内保持模型目录中的顺序
+	ModelSortByProvider = "provider"
+	// ModelSortByModel 按模型ID排序
+	ModelSortByModel = "model"
+	// ModelSortByContextSize 按上下文大小排序
+	ModelSortByContextSize = "context_size"
+	// ModelSortByInputPrice 按每百万 token 输入价格排序，没有价格的模型排在最后
+	ModelSortByInputPrice = "input_price"
+	// ModelSortByOutputPrice 按每百万 token 输出价格排序，没有价格的模型排在最后
+	ModelSortByOutputPrice = "output_price"
+)
+
+// SearchModelsRequest 跨提供商搜索模型的请求（查询参数）
+// 价格按每百万 token 计算，使用模型自身的货币，比较价格时通常同时指定 Currency
+type SearchModelsRequest struct {
+	// 关键词，匹配模型ID和标签（不区分大小写）
+	Keyword string `json:"keyword,omitempty" example:"qwen"`
+	// 模型类型，支持 text-embedding 和 text_embedding 两种形式
+	ModelType string `json:"model_type,omitempty" example:"llm"`
+	// 特性，模型必须具有全部特性
+	Features []string `json:"features,omitempty" example:"vision,tool-call"`
+	// 模式（chat、completion）
+	Mode string `json:"mode,omitempty" example:"chat"`
+	// 最小上下文大小
+	MinContextSize int `json:"min_context_size,omitempty" example:"32000"`
+	// 是否已弃用，为空时不过滤
+	Deprecated *bool `json:"deprecated,omitempty" example:"false"`
+	// 货币（如 USD、RMB）
+	Currency string `json:"currency,omitempty" example:"USD"`
+	// 每百万 token 的最高输入价格
+	MaxInputPrice *float64 `json:"max_input_price,omitempty" example:"1"`
+	// 每百万 token 的最高输出价格
+	MaxOutputPrice *float64 `json:"max_output_price,omitempty" example:"4"`
+	// 排序字段，默认按提供商排序
+	SortBy string `json:"sort_by,omitempty" example:"context_size"`
+	// 排序方向（asc、desc），默认 asc
+	Order string `json:"order,omitempty" example:"desc"`
+	// 页码
+	PageNo int `json:"pageNo" example:"1"`
+	// 每页大小
+	PageSize int `json:"pageSize" example:"20"`
+}
+
+// ModelSearchItem 模型搜索结果项
+type ModelSearchItem struct {
+	// 提供商ID
+	Provider string `json:"provider" example:"tongyi"`
+	ModelListItem
+	// 是否已弃用
+	Deprecated bool `json:"deprecated,omitempty" example:"false"`
+}
@@ -0,0 +1,82 @@
+package model
+
+import "time"
+
+// 凭证的所有者类型
+const (
+	// CredentialOwnerUser 用户个人的凭证
+	CredentialOwnerUser = "user"
+	// CredentialOwnerWorkspace 工作空间共享的凭证
+	CredentialOwnerWorkspace = "workspace"
+)
+
+// 凭证表单字段类型
+const (
+	// FormTypeTextInput 文本输入
+	FormTypeTextInput = "text-input"
+	// FormTypeSecretInput 密钥输入，返回时脱敏
+	FormTypeSecretInput = "secret-input"
+	// FormTypeSelect 下拉选择，值必须是选项之一
+	FormTypeSelect = "select"
+	// FormTypeRadio 单选，值必须是选项之一
+	FormTypeRadio = "radio"
+)
+
+// CredentialOwner 凭证的所有者
+type CredentialOwner struct {
+	// 所有者类型 (user, workspace)
+	Type string
+	// 用户ID或工作空间ID
+	ID string
+}
+
+// ProviderCredential 模型提供商凭证实体
+// 按所有者（用户或工作空间）保存提供商级或模型级的凭证，同一所有者在同一范围内只有一份凭证。
+// 凭证按提供商 YAML 中的表单配置校验后整体加密保存，接口只返回脱敏后的凭证
+type ProviderCredential struct {
+	// 凭证ID
+	ID string `gorm:"type:uuid;primary_key" json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	// 所有者类型 (user, workspace)
+	OwnerType string `gorm:"type:varchar(16);not null;uniqueIndex:idx_credential_scope" json:"ownerType" example:"user"`
+	// 所有者ID（用户ID或工作空间ID）
+	OwnerID string `gorm:"type:varchar(128);not null;uniqueIndex:idx_credential_scope" json:"ownerId"`
+	// 提供商ID
+	ProviderID string `gorm:"type:varchar(64);not null;uniqueIndex:idx_credential_scope" json:"providerId" example:"tongyi"`
+	// 模型类型，为空时为提供商级凭证
+	ModelType string `gorm:"type:varchar(32);not null;default:'';uniqueIndex:idx_credential_scope" json:"modelType,omitempty" example:"llm"`
+	// 模型名称，为空时为提供商级凭证
+	Model string `gorm:"type:varchar(255);not null;default:'';uniqueIndex:idx_credential_scope" json:"model,omitempty" example:"gpt-4o-deployment"`
+	// 加密后的凭证
+	EncryptedCredentials string `gorm:"type:text;not null" json:"-"`
+	// 创建时间
+	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	// 更新时间
+	UpdatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updatedAt"`
+
+	// 脱敏后的凭证，secret-input 类型的字段只保留首尾字符
+	Credentials map[string]string `gorm:"-" json:"credentials"`
+}
+
+// TableName 指定表名
+func (ProviderCredential) TableName() string {
+	return "provider_credentials"
+}
+
+// CreateCredentialRequest 创建凭证请求
+type CreateCredentialRequest struct {
+	// 提供商ID
+	ProviderID string `json:"providerId" validate:"required,max=64" example:"tongyi"`
+	// 模型类型（可选），指定时为模型级凭证，必须是提供商支持的模型类型
+	ModelType string `json:"modelType,omitempty" validate:"required_with=Model,omitempty,max=32" example:"llm"`
+	// 模型名称（可选），与模型类型一起指定
+	Model string `json:"model,omitempty" validate:"required_with=ModelType,omitempty,max=255" example:"qwen-plus"`
+	// 凭证，键为提供商表单配置中的变量名
+	Credentials map[string]string `json:"credentials" validate:"required"`
+}
+
+// UpdateCredentialRequest 更新凭证请求
+// 凭证整体替换；secret-input 字段传入脱敏后的值时保留原值
+type UpdateCredentialRequest struct {
+	// 凭证，键为提供商表单配置中的变量名
+	Credentials map[string]string `json:"credentials" validate:"required"`
+}
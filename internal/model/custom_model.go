@@ -0,0 +1,60 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// CustomModel 自定义模型实体
+// 配置方法包含 customizable-model 的提供商（如 Azure OpenAI）没有预定义全部可用模型，
+// 通过接口注册的部署以自定义模型保存，启动时合并到模型目录，可以像目录中的模型一样使用。
+// 凭证按提供商的模型凭证表单配置校验后加密保存，用于创建该模型的后端客户端
+type CustomModel struct {
+	// 自定义模型ID
+	ID string `gorm:"type:uuid;primary_key" json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	// 提供商ID
+	ProviderID string `gorm:"type:varchar(64);not null;uniqueIndex:idx_custom_model" json:"providerId" example:"azure_openai"`
+	// 模型类型（目录中的形式，如 llm、text_embedding）
+	ModelType string `gorm:"type:varchar(32);not null" json:"modelType" example:"llm"`
+	// 模型名称（部署名称）
+	Model string `gorm:"type:varchar(255);not null;uniqueIndex:idx_custom_model" json:"model" example:"gpt-4o-deployment"`
+	// 基础模型，目录中存在同名模型时继承其属性和参数规则
+	BaseModel string `gorm:"type:varchar(255)" json:"baseModel,omitempty" example:"gpt-4o-mini"`
+	// 上下文大小，为 0 时使用基础模型的上下文大小
+	ContextSize int `gorm:"not null;default:0" json:"contextSize,omitempty" example:"128000"`
+	// 特性列表，为空时使用基础模型的特性
+	Features datatypes.JSONSlice[string] `gorm:"type:jsonb" json:"features,omitempty"`
+	// 加密后的凭证
+	EncryptedCredentials string `gorm:"type:text;not null" json:"-"`
+	// 注册该模型的用户ID
+	CreatedBy string `gorm:"type:varchar(128)" json:"createdBy,omitempty"`
+	// 创建时间
+	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	// 更新时间
+	UpdatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updatedAt"`
+
+	// 脱敏后的凭证，secret-input 类型的字段只保留首尾字符
+	Credentials map[string]string `gorm:"-" json:"credentials"`
+}
+
+// TableName 指定表名
+func (CustomModel) TableName() string {
+	return "custom_models"
+}
+
+// RegisterCustomModelRequest 注册自定义模型请求
+type RegisterCustomModelRequest struct {
+	// 模型类型，必须是提供商支持的模型类型
+	ModelType string `json:"modelType" validate:"required,max=32" example:"llm"`
+	// 模型名称（部署名称），只允许字母、数字、下划线、连字符和点号
+	Model string `json:"model" validate:"required,max=255" example:"gpt-4o-deployment"`
+	// 基础模型（可选），未指定时取凭证中的 base_model_name
+	BaseModel string `json:"baseModel,omitempty" validate:"omitempty,max=255" example:"gpt-4o-mini"`
+	// 上下文大小（可选），未指定时使用基础模型的上下文大小
+	ContextSize int `json:"contextSize,omitempty" validate:"omitempty,min=1" example:"128000"`
+	// 特性列表（可选），未指定时使用基础模型的特性
+	Features []string `json:"features,omitempty" validate:"omitempty,dive,required" example:"tool-call,vision"`
+	// 凭证，键为提供商模型凭证表单配置中的变量名
+	Credentials map[string]string `json:"credentials" validate:"required"`
+}
@@ -0,0 +1,29 @@
+package model
+
+// EmbeddingRequest 文本向量化请求
+type EmbeddingRequest struct {
+	// 向量模型名称（支持 "提供商/模型" 格式），必须是 text_embedding 类型的模型
+	Model string `json:"model" validate:"required,max=128" example:"tongyi/text-embedding-v3"`
+	// 待向量化的文本，每条文本的 token 数不能超过模型的上下文大小
+	Input []string `json:"input" validate:"required,min=1,max=2048,dive,required" example:"你好,世界"`
+	// 输出向量的维度（可选，仅部分模型支持）
+	Dimensions *int `json:"dimensions,omitempty" validate:"omitempty,gt=0" example:"1024"`
+}
+
+// EmbeddingResponse 文本向量化响应
+type EmbeddingResponse struct {
+	// 使用的模型名称
+	Model string `json:"model" example:"text-embedding-v3"`
+	// 向量结果，与输入一一对应
+	Data []EmbeddingData `json:"data"`
+	// Token使用情况
+	Usage *Usage `json:"usage,omitempty"`
+}
+
+// EmbeddingData 单条文本的向量
+type EmbeddingData struct {
+	// 输入文本的序号（从 0 开始）
+	Index int `json:"index" example:"0"`
+	// 向量
+	Embedding []float32 `json:"embedding"`
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// File 上传文件实体
+// 文件内容保存在 Blob 存储中，以内容的 SHA-256 作为存储键，相同内容只保存一份
+type File struct {
+	// 文件ID
+	ID string `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	// 所属用户ID
+	UserID string `gorm:"type:uuid;not null;index:idx_user_files" json:"userId"`
+	// 原始文件名
+	Name string `gorm:"type:varchar(255);not null" json:"name" example:"report.pdf"`
+	// 检测到的 MIME 类型
+	MimeType string `gorm:"type:varchar(128);not null" json:"mimeType" example:"application/pdf"`
+	// 文件大小（字节）
+	Size int64 `gorm:"not null" json:"size" example:"102400"`
+	// 文件内容的 SHA-256（十六进制）
+	SHA256 string `gorm:"column:sha256;type:char(64);not null;index:idx_file_sha256" json:"sha256"`
+	// 创建时间
+	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP;index:idx_user_files" json:"createdAt"`
+}
+
+// TableName 指定表名
+func (File) TableName() string {
+	return "files"
+}
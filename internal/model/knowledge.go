@@ -0,0 +1,223 @@
+package model
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 知识库的分块策略
+const (
+	// ChunkStrategyTokens 按 token 数分块，相邻块之间保留重叠部分
+	ChunkStrategyTokens = "tokens"
+	// ChunkStrategyMarkdown 按 Markdown 标题分块，超过块大小的章节再按 token 数切分
+	ChunkStrategyMarkdown = "markdown"
+)
+
+// KnowledgeBase 知识库实体
+// 知识库中的文档按分块策略切分后使用向量模型生成向量，会话挂载知识库后发送消息时检索相关分块注入提示词。
+// 知识库相关表的ID在应用中生成（不依赖 gen_random_uuid），以便在 SQLite 上运行测试
+type KnowledgeBase struct {
+	// 知识库ID
+	ID string `gorm:"type:uuid;primary_key" json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	// 所属用户ID
+	UserID string `gorm:"type:uuid;not null;index:idx_user_knowledge_bases" json:"userId"`
+	// 名称
+	Name string `gorm:"type:varchar(255);not null" json:"name" example:"内部文档"`
+	// 描述
+	Description string `gorm:"type:text" json:"description" example:"研发团队的内部技术文档"`
+	// 向量模型（"提供商/模型" 格式），创建后不能修改
+	EmbeddingModel string `gorm:"type:varchar(128);not null" json:"embeddingModel" example:"tongyi/text-embedding-v3"`
+	// 分块策略 (tokens, markdown)
+	ChunkStrategy string `gorm:"type:varchar(16);not null" json:"chunkStrategy" example:"markdown"`
+	// 每块的最大 token 数
+	ChunkSize int `gorm:"not null" json:"chunkSize" example:"512"`
+	// 相邻块之间重叠的 token 数（仅按 token 数切分时使用）
+	ChunkOverlap int `gorm:"not null;default:0" json:"chunkOverlap" example:"64"`
+	// 文档数量
+	DocumentCount int `gorm:"default:0" json:"documentCount"`
+	// 创建时间
+	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	// 更新时间
+	UpdatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (KnowledgeBase) TableName() string {
+	return "knowledge_bases"
+}
+
+// KnowledgeDocument 知识库文档实体
+type KnowledgeDocument struct {
+	// 文档ID
+	ID string `gorm:"type:uuid;primary_key" json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	// 所属知识库ID
+	KnowledgeBaseID string `gorm:"type:uuid;not null;index:idx_knowledge_documents" json:"knowledgeBaseId"`
+	// 文档名称
+	Name string `gorm:"type:varchar(255);not null" json:"name" example:"部署手册.md"`
+	// 来源的上传文件ID（可选）
+	FileID *string `gorm:"type:uuid" json:"fileId,omitempty"`
+	// 文档内容
+	Content string `gorm:"type:text;not null" json:"-"`
+	// 分块数量
+	ChunkCount int `gorm:"default:0" json:"chunkCount" example:"12"`
+	// 估算的 token 数量
+	Tokens int `gorm:"default:0" json:"tokens" example:"4096"`
+	// 创建时间
+	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"createdAt"`
+
+	// 关联
+	KnowledgeBase *KnowledgeBase `gorm:"foreignKey:KnowledgeBaseID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TableName 指定表名
+func (KnowledgeDocument) TableName() string {
+	return "knowledge_documents"
+}
+
+// KnowledgeChunk 知识库文档分块实体
+type KnowledgeChunk struct {
+	// 分块ID
+	ID string `gorm:"type:uuid;primary_key" json:"id"`
+	// 所属知识库ID
+	KnowledgeBaseID string `gorm:"type:uuid;not null;index:idx_knowledge_chunks" json:"knowledgeBaseId"`
+	// 所属文档ID
+	DocumentID string `gorm:"type:uuid;not null;index:idx_document_chunks" json:"documentId"`
+	// 在文档中的位置，从 0 开始
+	Position int `gorm:"not null" json:"position"`
+	// 所在章节的标题路径（按 Markdown 标题分块时），如 "部署 > 配置"
+	Heading string `gorm:"type:text" json:"heading,omitempty"`
+	// 分块内容
+	Content string `gorm:"type:text;not null" json:"content"`
+	// 估算的 token 数量
+	Tokens int `gorm:"default:0" json:"tokens"`
+	// 内容的向量，PostgreSQL 中为 pgvector 的 vector 类型
+	Embedding Vector `gorm:"type:vector;not null" json:"-"`
+	// 创建时间
+	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"createdAt"`
+
+	// 关联
+	Document *KnowledgeDocument `gorm:"foreignKey:DocumentID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TableName 指定表名
+func (KnowledgeChunk) TableName() string {
+	return "knowledge_chunks"
+}
+
+// Vector 向量，以 pgvector 的文本格式（如 "[0.1,0.2]"）读写数据库
+type Vector []float32
+
+// Value 实现 driver.Valuer 接口
+func (v Vector) Value() (driver.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, f := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(f), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String(), nil
+}
+
+// Scan 实现 sql.Scanner 接口
+func (v *Vector) Scan(src interface{}) error {
+	var text string
+	switch value := src.(type) {
+	case nil:
+		*v = nil
+		return nil
+	case string:
+		text = value
+	case []byte:
+		text = string(value)
+	default:
+		return fmt.Errorf("无法将 %T 解析为向量", src)
+	}
+
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "[") || !strings.HasSuffix(text, "]") {
+		return fmt.Errorf("无效的向量格式: %q", text)
+	}
+	text = strings.TrimSpace(text[1 : len(text)-1])
+	if text == "" {
+		*v = Vector{}
+		return nil
+	}
+
+	fields := strings.Split(text, ",")
+	result := make(Vector, len(fields))
+	for i, field := range fields {
+		f, err := strconv.ParseFloat(strings.TrimSpace(field), 32)
+		if err != nil {
+			return fmt.Errorf("无效的向量分量 %q: %w", field, err)
+		}
+		result[i] = float32(f)
+	}
+	*v = result
+	return nil
+}
+
+// Citation 回复引用的知识库分块，保存在 AI 回复的 Meta.citations 中
+type Citation struct {
+	// 知识库ID
+	KnowledgeBaseID string `json:"knowledgeBaseId"`
+	// 文档ID
+	DocumentID string `json:"documentId"`
+	// 文档名称
+	DocumentName string `json:"documentName" example:"部署手册.md"`
+	// 分块ID
+	ChunkID string `json:"chunkId"`
+	// 分块在文档中的位置
+	Position int `json:"position" example:"3"`
+	// 所在章节的标题路径
+	Heading string `json:"heading,omitempty" example:"部署 > 配置"`
+	// 分块内容
+	Content string `json:"content"`
+	// 与查询的余弦相似度（-1 到 1）
+	Score float64 `json:"score" example:"0.82"`
+	// 重排序模型给出的相关性得分，未启用重排序时为空
+	RerankScore *float64 `json:"rerankScore,omitempty" example:"0.91"`
+}
+
+// CreateKnowledgeBaseRequest 创建知识库请求
+type CreateKnowledgeBaseRequest struct {
+	// 名称
+	Name string `json:"name" validate:"required,max=255" example:"内部文档"`
+	// 描述（可选）
+	Description string `json:"description,omitempty" example:"研发团队的内部技术文档"`
+	// 向量模型（支持 "提供商/模型" 格式），必须是 text_embedding 类型的模型
+	EmbeddingModel string `json:"embeddingModel" validate:"required,max=128" example:"tongyi/text-embedding-v3"`
+	// 分块策略（可选，tokens 或 markdown，默认 tokens）
+	ChunkStrategy string `json:"chunkStrategy,omitempty" validate:"omitempty,oneof=tokens markdown" example:"markdown"`
+	// 每块的最大 token 数（可选，默认 512，不能超过向量模型的上下文大小）
+	ChunkSize int `json:"chunkSize,omitempty" validate:"omitempty,min=32,max=8192" example:"512"`
+	// 相邻块之间重叠的 token 数（可选，必须小于 chunkSize）
+	ChunkOverlap *int `json:"chunkOverlap,omitempty" validate:"omitempty,min=0" example:"64"`
+}
+
+// AddDocumentRequest 添加知识库文档请求
+type AddDocumentRequest struct {
+	// 文档名称（引用上传文件时可为空，默认使用文件名）
+	Name string `json:"name,omitempty" validate:"omitempty,max=255" example:"部署手册.md"`
+	// 文档内容（与 fileId 二选一）
+	Content string `json:"content,omitempty" validate:"required_without=FileID" example:"# 部署\n..."`
+	// 上传文件ID（与 content 二选一），文件必须是文本类型
+	FileID string `json:"fileId,omitempty" validate:"omitempty,uuid" example:"550e8400-e29b-41d4-a716-446655440000"`
+}
+
+// SearchKnowledgeRequest 检索知识库请求
+type SearchKnowledgeRequest struct {
+	// 查询文本
+	Query string `json:"query" validate:"required" example:"如何配置数据库连接？"`
+	// 返回的分块数量（可选，默认使用服务配置）
+	TopK int `json:"topK,omitempty" validate:"omitempty,min=1,max=50" example:"5"`
+}
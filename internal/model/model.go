@@ -1,5 +1,10 @@
 package model
 
+import (
+	"strconv"
+	"strings"
+)
+
 // Model 模型完整信息
 type Model struct {
 	// 模型标识
@@ -7,7 +12,7 @@ type Model struct {
 	// 多语言标签
 	Label map[string]string `yaml:"label" json:"label" example:"en_US:Gemini 1.5 Flash,zh_Hans:Gemini 1.5 Flash"`
 	// 模型类型（llm、tts、text_embedding等）
-	ModelType string `yaml:"model_type" json:"model_type" example:"llm"`
+	ModelType ModelType `yaml:"model_type" json:"model_type" example:"llm"`
 	// 特性列表
 	Features []string `yaml:"features,omitempty" json:"features,omitempty" example:"tool-call,multi-tool-call,stream-tool-call"`
 	// 模型属性
@@ -18,6 +23,76 @@ type Model struct {
 	Pricing Pricing `yaml:"pricing,omitempty" json:"pricing,omitempty"`
 	// 是否已弃用
 	Deprecated bool `yaml:"deprecated,omitempty" json:"deprecated,omitempty" example:"false"`
+	// 模型来源，自定义模型为 customizable-model，目录中的模型为空
+	FetchFrom string `yaml:"fetch_from,omitempty" json:"fetch_from,omitempty" example:"customizable-model"`
+}
+
+// 模型来源（对应提供商的配置方法）
+const (
+	// FetchFromPredefinedModel 模型目录中预定义的模型
+	FetchFromPredefinedModel = "predefined-model"
+	// FetchFromCustomizableModel 通过接口注册的自定义模型
+	FetchFromCustomizableModel = "customizable-model"
+)
+
+// 模型特性
+const (
+	// FeatureToolCall 支持工具调用
+	FeatureToolCall = "tool-call"
+	// FeatureMultiToolCall 支持一次回复中调用多个工具
+	FeatureMultiToolCall = "multi-tool-call"
+	// FeatureStreamToolCall 支持流式工具调用
+	FeatureStreamToolCall = "stream-tool-call"
+	// FeatureAgentThought 支持智能体推理
+	FeatureAgentThought = "agent-thought"
+	// FeatureStructuredOutput 支持结构化输出
+	FeatureStructuredOutput = "structured-output"
+	// FeatureVision 支持图片输入
+	FeatureVision = "vision"
+	// FeatureDocument 支持文档输入
+	FeatureDocument = "document"
+	// FeatureVideo 支持视频输入
+	FeatureVideo = "video"
+	// FeatureAudio 支持音频输入
+	FeatureAudio = "audio"
+)
+
+// KnownFeatures 模型 YAML 中 features 允许使用的特性
+var KnownFeatures = []string{
+	FeatureToolCall,
+	FeatureMultiToolCall,
+	FeatureStreamToolCall,
+	FeatureAgentThought,
+	FeatureStructuredOutput,
+	FeatureVision,
+	FeatureDocument,
+	FeatureVideo,
+	FeatureAudio,
+}
+
+// HasFeature 判断模型是否具有指定特性
+func (m *Model) HasFeature(feature string) bool {
+	for _, f := range m.Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// MediaFeature 返回处理指定 MIME 类型的媒体所需的模型特性
+// 图片需要 vision，视频需要 video，音频需要 audio，其余（如 PDF、文本文件）需要 document
+func MediaFeature(mimeType string) string {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return FeatureVision
+	case strings.HasPrefix(mimeType, "video/"):
+		return FeatureVideo
+	case strings.HasPrefix(mimeType, "audio/"):
+		return FeatureAudio
+	default:
+		return FeatureDocument
+	}
 }
 
 // ModelListItem 模型列表项（用于列表接口）
@@ -27,7 +102,7 @@ type ModelListItem struct {
 	// 多语言标签
 	Label map[string]string `json:"label"`
 	// 模型类型
-	ModelType string `json:"model_type"`
+	ModelType ModelType `json:"model_type"`
 	// 特性列表
 	Features []string `json:"features,omitempty"`
 	// 模型属性
@@ -36,6 +111,8 @@ type ModelListItem struct {
 	ParameterRules []ParameterRule `json:"parameter_rules,omitempty"`
 	// 定价信息
 	Pricing Pricing `json:"pricing,omitempty"`
+	// 模型来源，自定义模型为 customizable-model
+	FetchFrom string `json:"fetch_from,omitempty"`
 }
 
 // ModelProperties 模型属性
@@ -44,6 +121,66 @@ type ModelProperties struct {
 	Mode string `yaml:"mode" json:"mode"`
 	// 上下文大小
 	ContextSize int `yaml:"context_size" json:"context_size"`
+	// 单次请求最多的输入条数（向量模型）
+	MaxChunks int `yaml:"max_chunks,omitempty" json:"max_chunks,omitempty"`
+	// 默认音色（语音合成模型）
+	DefaultVoice string `yaml:"default_voice,omitempty" json:"default_voice,omitempty"`
+	// 可用音色列表（语音合成模型）
+	Voices []Voice `yaml:"voices,omitempty" json:"voices,omitempty"`
+	// 单次合成的最大字数，超过时按句子拆分（语音合成模型）
+	WordLimit int `yaml:"word_limit,omitempty" json:"word_limit,omitempty"`
+	// 输出音频格式（语音合成模型）
+	AudioType string `yaml:"audio_type,omitempty" json:"audio_type,omitempty"`
+	// 最大并发合成数（语音合成模型）
+	MaxWorkers int `yaml:"max_workers,omitempty" json:"max_workers,omitempty"`
+	// 上传音频文件的大小上限，单位 MB（语音识别模型）
+	FileUploadLimit int `yaml:"file_upload_limit,omitempty" json:"file_upload_limit,omitempty"`
+	// 支持的音频文件扩展名，以逗号分隔（语音识别模型）
+	SupportedFileExtensions string `yaml:"supported_file_extensions,omitempty" json:"supported_file_extensions,omitempty"`
+}
+
+// Voice 语音合成模型的音色
+type Voice struct {
+	// 音色标识，合成时作为 voice 参数
+	Mode string `yaml:"mode" json:"mode" example:"sambert-zhiru-v1"`
+	// 音色名称
+	Name string `yaml:"name" json:"name" example:"知茹（新闻女声）"`
+	// 支持的语言
+	Language []string `yaml:"language" json:"language" example:"zh-Hans,en-US"`
+}
+
+// SupportsLanguage 判断音色是否支持指定语言
+func (v Voice) SupportsLanguage(language string) bool {
+	for _, l := range v.Language {
+		if strings.EqualFold(l, language) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindVoice 在模型的音色列表中查找音色，不存在时返回 nil
+func (p *ModelProperties) FindVoice(mode string) *Voice {
+	for i := range p.Voices {
+		if p.Voices[i].Mode == mode {
+			return &p.Voices[i]
+		}
+	}
+	return nil
+}
+
+// SupportsFileExtension 判断模型是否支持指定扩展名的文件（不含点，忽略大小写）
+// 未配置支持的扩展名时不做限制
+func (p *ModelProperties) SupportsFileExtension(ext string) bool {
+	if strings.TrimSpace(p.SupportedFileExtensions) == "" {
+		return true
+	}
+	for _, supported := range strings.Split(p.SupportedFileExtensions, ",") {
+		if strings.EqualFold(strings.TrimSpace(supported), ext) {
+			return true
+		}
+	}
+	return false
 }
 
 // ParameterRule 参数规则
@@ -70,6 +207,49 @@ type ParameterRule struct {
 	Options []string `yaml:"options,omitempty" json:"options,omitempty"`
 }
 
+// ParameterTemplates 参数模板，对应模型 YAML 中 parameter_rules 的 use_template 引用
+// 模板只提供类型、标签和取值范围，默认值由各模型 YAML 声明；
+// 未声明默认值的参数使用服务配置中的默认值（GENKIT_DEFAULT_*）
+var ParameterTemplates = map[string]ParameterRule{
+	"temperature": {
+		Label: map[string]string{"zh_Hans": "温度", "en_US": "Temperature"},
+		Type:  "float",
+		Min:   0.0,
+		Max:   2.0,
+	},
+	"top_p": {
+		Label: map[string]string{"zh_Hans": "Top P", "en_US": "Top P"},
+		Type:  "float",
+		Min:   0.0,
+		Max:   1.0,
+	},
+	"max_tokens": {
+		Label: map[string]string{"zh_Hans": "最大标记", "en_US": "Max Tokens"},
+		Type:  "int",
+		Min:   1,
+	},
+	"presence_penalty": {
+		Label: map[string]string{"zh_Hans": "存在惩罚", "en_US": "Presence Penalty"},
+		Type:  "float",
+		Min:   -2.0,
+		Max:   2.0,
+	},
+	"frequency_penalty": {
+		Label: map[string]string{"zh_Hans": "频率惩罚", "en_US": "Frequency Penalty"},
+		Type:  "float",
+		Min:   -2.0,
+		Max:   2.0,
+	},
+	"response_format": {
+		Label: map[string]string{"zh_Hans": "回复格式", "en_US": "Response Format"},
+		Type:  "string",
+	},
+	"json_schema": {
+		Label: map[string]string{"zh_Hans": "JSON Schema", "en_US": "JSON Schema"},
+		Type:  "text",
+	},
+}
+
 // Pricing 定价信息
 type Pricing struct {
 	// 输入价格
@@ -81,3 +261,26 @@ type Pricing struct {
 	// 货币
 	Currency string `yaml:"currency" json:"currency"`
 }
+
+// InputPerMillion 返回每百万 token 的输入价格（input × unit × 1000000），未配置价格或单位时返回 false
+func (p Pricing) InputPerMillion() (float64, bool) {
+	return perMillion(p.Input, p.Unit)
+}
+
+// OutputPerMillion 返回每百万 token 的输出价格（output × unit × 1000000），未配置价格或单位时返回 false
+func (p Pricing) OutputPerMillion() (float64, bool) {
+	return perMillion(p.Output, p.Unit)
+}
+
+// perMillion 按计价单位换算每百万 token 的价格
+func perMillion(price, unit string) (float64, bool) {
+	value, err := strconv.ParseFloat(strings.TrimSpace(price), 64)
+	if err != nil {
+		return 0, false
+	}
+	unitValue, err := strconv.ParseFloat(strings.TrimSpace(unit), 64)
+	if err != nil || unitValue <= 0 {
+		return 0, false
+	}
+	return value * unitValue * 1000000, true
+}
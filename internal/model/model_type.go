@@ -0,0 +1,77 @@
+package model
+
+import "strings"
+
+// ModelType 模型类型
+// 规范形式与模型目录中的目录名一致（如 text_embedding），
+// 提供商 YAML 的 supported_model_types 和模型配置的 model_type 使用连字符形式（如 text-embedding）
+type ModelType string
+
+// 模型类型
+const (
+	// ModelTypeLLM 大语言模型
+	ModelTypeLLM ModelType = "llm"
+	// ModelTypeTextEmbedding 文本嵌入模型
+	ModelTypeTextEmbedding ModelType = "text_embedding"
+	// ModelTypeRerank 重排序模型
+	ModelTypeRerank ModelType = "rerank"
+	// ModelTypeSpeech2Text 语音转文本模型
+	ModelTypeSpeech2Text ModelType = "speech2text"
+	// ModelTypeTTS 文本转语音模型
+	ModelTypeTTS ModelType = "tts"
+	// ModelTypeModeration 内容审核模型
+	ModelTypeModeration ModelType = "moderation"
+)
+
+// ModelTypes 全部模型类型
+var ModelTypes = []ModelType{
+	ModelTypeLLM,
+	ModelTypeTextEmbedding,
+	ModelTypeRerank,
+	ModelTypeSpeech2Text,
+	ModelTypeTTS,
+	ModelTypeModeration,
+}
+
+// modelTypeAliases 模型类型的其他写法
+var modelTypeAliases = map[string]ModelType{
+	"text_generation": ModelTypeLLM,
+	"embedding":       ModelTypeTextEmbedding,
+	"embeddings":      ModelTypeTextEmbedding,
+	"reranking":       ModelTypeRerank,
+	"speech_to_text":  ModelTypeSpeech2Text,
+	"text_to_speech":  ModelTypeTTS,
+}
+
+// ParseModelType 将模型类型标识统一为规范形式，不区分大小写、连字符和下划线
+// 例如 text-embedding、text_embedding 和 embeddings 都返回 ModelTypeTextEmbedding
+func ParseModelType(s string) (ModelType, bool) {
+	normalized := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(s)), "-", "_")
+	for _, t := range ModelTypes {
+		if string(t) == normalized {
+			return t, true
+		}
+	}
+	t, ok := modelTypeAliases[normalized]
+	return t, ok
+}
+
+// String 返回模型类型的规范形式
+func (t ModelType) String() string {
+	return string(t)
+}
+
+// ProviderForm 返回提供商 YAML 中使用的连字符形式（如 text-embedding）
+func (t ModelType) ProviderForm() string {
+	return strings.ReplaceAll(string(t), "_", "-")
+}
+
+// SupportsModelType 检查提供商的 supported_model_types 是否包含该模型类型
+func (p *Provider) SupportsModelType(t ModelType) bool {
+	for _, supported := range p.SupportedModelTypes {
+		if parsed, ok := ParseModelType(supported); ok && parsed == t {
+			return true
+		}
+	}
+	return false
+}
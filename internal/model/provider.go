@@ -58,10 +58,20 @@ type ProviderHelp struct {
 
 // CredentialSchema 凭证配置
 type CredentialSchema struct {
+	// 模型名称输入框配置（仅模型凭证）
+	Model *CredentialModelSchema `yaml:"model,omitempty" json:"model,omitempty"`
 	// 凭证表单配置列表
 	CredentialFormSchemas []CredentialFormSchema `yaml:"credential_form_schemas" json:"credential_form_schemas"`
 }
 
+// CredentialModelSchema 模型凭证中模型名称输入框的配置
+type CredentialModelSchema struct {
+	// 标签（多语言）
+	Label map[string]string `yaml:"label" json:"label"`
+	// 占位符（多语言）
+	Placeholder map[string]string `yaml:"placeholder,omitempty" json:"placeholder,omitempty"`
+}
+
 // CredentialFormSchema 凭证表单配置项
 type CredentialFormSchema struct {
 	// 变量名
@@ -78,6 +88,8 @@ type CredentialFormSchema struct {
 	Placeholder map[string]string `yaml:"placeholder,omitempty" json:"placeholder,omitempty"`
 	// 选项列表
 	Options []FormOption `yaml:"options,omitempty" json:"options,omitempty"`
+	// 显示条件，全部满足时才显示该字段
+	ShowOn []ShowOnCondition `yaml:"show_on,omitempty" json:"show_on,omitempty"`
 }
 
 // FormOption 表单选项
@@ -86,6 +98,17 @@ type FormOption struct {
 	Label map[string]string `yaml:"label" json:"label"`
 	// 选项值
 	Value string `yaml:"value" json:"value"`
+	// 显示条件，全部满足时才显示该选项
+	ShowOn []ShowOnCondition `yaml:"show_on,omitempty" json:"show_on,omitempty"`
+}
+
+// ShowOnCondition 表单字段或选项的显示条件：变量的值等于 Value 时显示
+// 变量 __model_type 表示凭证所属的模型类型
+type ShowOnCondition struct {
+	// 变量名
+	Variable string `yaml:"variable" json:"variable"`
+	// 变量值
+	Value string `yaml:"value" json:"value"`
 }
 
 // ModelTypeInfo 模型类型信息
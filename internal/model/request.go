@@ -2,12 +2,26 @@ package model
 
 // ChatRequest 对话请求
 type ChatRequest struct {
-	// 用户消息内容
-	Message string `json:"message" validate:"required" example:"你好，请介绍一下你自己"`
-	// 消息ID（可选，用于继续对话）
+	// 用户消息内容（指定 parts 时可为空）
+	Message string `json:"message" validate:"required_without=Parts" example:"你好，请介绍一下你自己"`
+	// 多模态消息片段（可选），文本片段依次追加在 message 之后，媒体片段需要模型支持对应的特性
+	Parts []MessagePart `json:"parts,omitempty" validate:"omitempty,dive"`
+	// 消息ID（可选，用于继续对话；生成过程中可按该ID中止）
 	MessageID string `json:"messageId,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`
+	// 会话ID（可选，生成过程中可按该ID中止）
+	SessionID string `json:"sessionId,omitempty" validate:"omitempty,max=128" example:"session-123456"`
+	// 模型名称（可选，支持 "提供商/模型" 格式，未指定时使用默认模型）
+	Model string `json:"model,omitempty" validate:"omitempty,max=128" example:"qwen-plus"`
+	// 是否以 SSE 流式返回（可选，也可通过 Accept: text/event-stream 指定）
+	Stream bool `json:"stream,omitempty" example:"false"`
 	// AI高级参数（可选）
 	Options *ChatOptions `json:"options,omitempty"`
+	// 系统提示词（由会话消息服务根据会话配置填充）
+	SystemPrompt string `json:"-"`
+	// 历史对话（由会话消息服务根据会话消息填充），按时间正序排列
+	History []ChatTurn `json:"-"`
+	// 提供给模型的工具（由会话消息服务根据会话启用的工具填充）
+	Tools []ToolDefinition `json:"-"`
 }
 
 // ChatOptions AI高级参数
@@ -20,12 +34,20 @@ type ChatOptions struct {
 	TopP *float64 `json:"topP,omitempty" validate:"omitempty,gte=0,lte=1" example:"0.9"`
 	// Top-K采样参数
 	TopK *int `json:"topK,omitempty" validate:"omitempty,gt=0" example:"40"`
+	// 回复的 JSON Schema（可选），指定后模型按 Schema 输出 JSON，校验通过后在 parsed 中返回解析结果
+	JSONSchema map[string]interface{} `json:"jsonSchema,omitempty"`
+	// 会话中保存的 JSON Schema 名称（可选，仅用于会话消息），与 jsonSchema 同时指定时以 jsonSchema 为准
+	SchemaName string `json:"schemaName,omitempty" validate:"omitempty,max=64" example:"invoice"`
+	// 模型是否原生支持 JSON Schema（由参数规则引擎根据模型的 json_schema 参数规则设置）
+	NativeJSONSchema bool `json:"-"`
 }
 
 // AbortRequest 中止对话请求
 type AbortRequest struct {
-	// 消息ID（必填）
-	MessageID string `json:"messageId" validate:"required,uuid" example:"550e8400-e29b-41d4-a716-446655440000"`
+	// 消息ID（与会话ID至少指定一个）
+	MessageID string `json:"messageId,omitempty" validate:"omitempty,uuid" example:"550e8400-e29b-41d4-a716-446655440000"`
+	// 会话ID（与消息ID至少指定一个），中止该会话正在进行的生成
+	SessionID string `json:"sessionId,omitempty" validate:"required_without=MessageID,max=128" example:"session-123456"`
 }
 
 // CreateSessionRequest 创建会话请求
@@ -40,6 +62,12 @@ type CreateSessionRequest struct {
 	Temperature *float64 `json:"temperature,omitempty" validate:"omitempty,gte=0,lte=2" example:"0.7"`
 	// TopP参数（可选，0-1）
 	TopP *float64 `json:"topP,omitempty" validate:"omitempty,gte=0,lte=1" example:"0.9"`
+	// 启用的工具名称（可选）
+	Tools []string `json:"tools,omitempty" validate:"omitempty,dive,max=64" example:"get_current_time"`
+	// 保存的 JSON Schema（可选），key 为 Schema 名称，发送消息时通过 options.schemaName 引用
+	Schemas map[string]interface{} `json:"schemas,omitempty"`
+	// 挂载的知识库ID（可选），发送消息时从中检索相关内容
+	KnowledgeBases []string `json:"knowledgeBases,omitempty" validate:"omitempty,max=10,dive,uuid" example:"550e8400-e29b-41d4-a716-446655440000"`
 	// 元数据（可选）
 	Meta map[string]interface{} `json:"meta,omitempty"`
 }
@@ -70,6 +98,12 @@ type UpdateSessionRequest struct {
 	TopP *float64 `json:"topP,omitempty" validate:"omitempty,gte=0,lte=1" example:"0.95"`
 	// 模型名称（可选）
 	ModelName *string `json:"modelName,omitempty" validate:"omitempty,max=128" example:"gpt-4-turbo"`
+	// 启用的工具名称（可选），传空数组时清空
+	Tools *[]string `json:"tools,omitempty" validate:"omitempty,dive,max=64" example:"get_current_time"`
+	// 保存的 JSON Schema（可选），整体替换会话中的 Schema，传空对象时清空
+	Schemas map[string]interface{} `json:"schemas,omitempty"`
+	// 挂载的知识库ID（可选），传空数组时取消挂载
+	KnowledgeBases *[]string `json:"knowledgeBases,omitempty" validate:"omitempty,max=10,dive,uuid" example:"550e8400-e29b-41d4-a716-446655440000"`
 }
 
 // SearchSessionsRequest 搜索会话请求
@@ -86,8 +120,36 @@ type SearchSessionsRequest struct {
 type SendMessageRequest struct {
 	// 会话ID
 	SessionID string `json:"sessionId" validate:"required,uuid" example:"550e8400-e29b-41d4-a716-446655440000"`
-	// 消息内容
-	Message string `json:"message" validate:"required" example:"你好，请介绍一下你自己"`
+	// 消息内容（指定 parts 时可为空）
+	Message string `json:"message" validate:"required_without=Parts" example:"你好，请介绍一下你自己"`
+	// 多模态消息片段（可选），文本片段依次追加在 message 之后，媒体片段保存为消息附件
+	Parts []MessagePart `json:"parts,omitempty" validate:"omitempty,dive"`
+	// AI 回复消息ID（可选），由客户端预先生成，生成过程中可按此ID中止；为空时由服务端生成
+	MessageID string `json:"messageId,omitempty" validate:"omitempty,uuid" example:"7c9e6679-7425-40de-944b-e07fc1f90ae7"`
+	// 是否以 SSE 流式返回（可选，也可通过 Accept: text/event-stream 指定）
+	Stream bool `json:"stream,omitempty" example:"false"`
+	// AI高级参数（可选）
+	Options *ChatOptions `json:"options,omitempty"`
+}
+
+// RegenerateMessageRequest 重新生成 AI 回复请求
+type RegenerateMessageRequest struct {
+	// AI 回复消息ID（可选），由客户端预先生成，生成过程中可按此ID中止；为空时由服务端生成
+	MessageID string `json:"messageId,omitempty" validate:"omitempty,uuid" example:"7c9e6679-7425-40de-944b-e07fc1f90ae7"`
+	// 是否以 SSE 流式返回（可选，也可通过 Accept: text/event-stream 指定）
+	Stream bool `json:"stream,omitempty" example:"false"`
+	// AI高级参数（可选）
+	Options *ChatOptions `json:"options,omitempty"`
+}
+
+// EditMessageRequest 编辑用户消息并重新发送请求
+type EditMessageRequest struct {
+	// 修改后的消息内容
+	Message string `json:"message" validate:"required" example:"请用更简洁的方式介绍一下你自己"`
+	// AI 回复消息ID（可选），由客户端预先生成，生成过程中可按此ID中止；为空时由服务端生成
+	MessageID string `json:"messageId,omitempty" validate:"omitempty,uuid" example:"7c9e6679-7425-40de-944b-e07fc1f90ae7"`
+	// 是否以 SSE 流式返回（可选，也可通过 Accept: text/event-stream 指定）
+	Stream bool `json:"stream,omitempty" example:"false"`
 	// AI高级参数（可选）
 	Options *ChatOptions `json:"options,omitempty"`
 }
@@ -100,6 +162,8 @@ type GetMessagesRequest struct {
 	PageNo int `json:"pageNo" validate:"required,min=1" example:"1"`
 	// 每页大小
 	PageSize int `json:"pageSize" validate:"required,min=1,max=100" example:"50"`
+	// 消息状态（可选）
+	Status string `json:"status,omitempty" validate:"omitempty,oneof=pending streaming completed failed aborted" example:"failed"`
 }
 
 // AbortMessageRequest 中止消息生成请求
@@ -0,0 +1,31 @@
+package model
+
+// RerankRequest 文档重排序请求
+type RerankRequest struct {
+	// 重排序模型名称（支持 "提供商/模型" 格式），必须是 rerank 类型的模型
+	Model string `json:"model" validate:"required,max=128" example:"tongyi/gte-rerank"`
+	// 查询文本
+	Query string `json:"query" validate:"required" example:"如何配置数据库连接"`
+	// 待排序的文档
+	Documents []string `json:"documents" validate:"required,min=1,max=500,dive,required" example:"配置数据库连接,启用缓存"`
+	// 返回得分最高的文档数量（可选，默认返回全部）
+	TopN int `json:"topN,omitempty" validate:"omitempty,min=1" example:"3"`
+}
+
+// RerankResponse 文档重排序响应
+type RerankResponse struct {
+	// 使用的模型名称
+	Model string `json:"model" example:"gte-rerank"`
+	// 按相关性得分从高到低排列的结果
+	Results []RerankResult `json:"results"`
+	// Token使用情况
+	Usage *Usage `json:"usage,omitempty"`
+}
+
+// RerankResult 单个文档的重排序结果
+type RerankResult struct {
+	// 文档在请求中的序号（从 0 开始）
+	Index int `json:"index" example:"0"`
+	// 相关性得分，越大越相关
+	RelevanceScore float64 `json:"relevanceScore" example:"0.92"`
+}
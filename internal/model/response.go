@@ -93,6 +93,12 @@ type SessionResponse struct {
 	IsPinned bool `json:"isPinned" example:"false"`
 	// 是否归档
 	IsArchived bool `json:"isArchived" example:"false"`
+	// 启用的工具名称
+	Tools []string `json:"tools,omitempty" example:"get_current_time"`
+	// 保存的 JSON Schema，key 为 Schema 名称
+	Schemas map[string]interface{} `json:"schemas,omitempty"`
+	// 挂载的知识库ID
+	KnowledgeBases []string `json:"knowledgeBases,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`
 	// 最后一条消息
 	LastMessage *MessagePreview `json:"lastMessage,omitempty"`
 	// 元数据
@@ -146,7 +152,7 @@ type MessageDetailResponse struct {
 	// 创建时间
 	CreatedAt string `json:"createdAt" example:"2024-01-01T12:00:00Z"`
 	// 工具调用
-	ToolCalls map[string]interface{} `json:"toolCalls,omitempty"`
+	ToolCalls []ToolCall `json:"toolCalls,omitempty"`
 	// 错误信息
 	Error string `json:"error,omitempty" example:""`
 	// 元数据
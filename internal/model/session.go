@@ -28,7 +28,7 @@ type ChatSession struct {
 	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"createdAt"`
 	// 更新时间
 	UpdatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updatedAt"`
-	// 最后一条消息ID
+	// 最后一条消息ID，即当前分支的末端消息
 	LastMessageID *string `gorm:"type:uuid" json:"lastMessageId"`
 	// 消息数量
 	MessageCount int `gorm:"default:0" json:"messageCount"`
@@ -38,6 +38,12 @@ type ChatSession struct {
 	IsArchived bool `gorm:"default:false;index:idx_archived" json:"isArchived"`
 	// 是否删除
 	IsDeleted bool `gorm:"default:false;index:idx_deleted" json:"isDeleted"`
+	// 启用的工具名称，仅在模型支持工具调用时提供给模型
+	Tools datatypes.JSONSlice[string] `gorm:"type:jsonb" json:"tools"`
+	// 保存的 JSON Schema，key 为 Schema 名称，发送消息时通过名称引用以获得结构化输出
+	Schemas datatypes.JSONMap `gorm:"type:jsonb" json:"schemas"`
+	// 挂载的知识库ID，发送消息时从中检索相关内容注入提示词
+	KnowledgeBases datatypes.JSONSlice[string] `gorm:"type:jsonb" json:"knowledgeBases"`
 	// 元数据
 	Meta datatypes.JSON `gorm:"type:jsonb" json:"meta"`
 }
@@ -63,24 +69,78 @@ type ChatMessage struct {
 	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP;index:idx_created" json:"createdAt"`
 	// 消息序列号
 	Sequence int `gorm:"not null" json:"sequence"`
-	// 工具调用信息
+	// 工具调用信息：AI 回复为模型发起的工具调用列表，function 消息为其对应的单个工具调用
 	ToolCalls datatypes.JSON `gorm:"type:jsonb" json:"toolCalls"`
 	// 错误信息
 	Error string `gorm:"type:text" json:"error"`
-	// 父消息ID
+	// 消息状态 (pending, streaming, completed, failed, aborted)
+	Status string `gorm:"type:varchar(16);not null;default:'completed'" json:"status"`
+	// 父消息ID：用户消息指向上一条 AI 回复，AI 回复指向对应的用户消息，会话的第一条消息为空
 	ParentID *string `gorm:"type:uuid" json:"parentId"`
 	// 元数据
 	Meta datatypes.JSON `gorm:"type:jsonb" json:"meta"`
 
+	// 附件（保存在 chat_attachments 表中，由消息服务按需加载）
+	Attachments []*ChatAttachment `gorm:"-" json:"-"`
+
 	// 关联
 	Session *ChatSession `gorm:"foreignKey:SessionID;constraint:OnDelete:CASCADE" json:"-"`
 }
 
+// 消息状态
+// AI 回复在生成前以 pending 状态创建，收到首个流式片段后变为 streaming，
+// 结束后更新为 completed、failed 或 aborted；用户消息直接以 completed 状态创建
+const (
+	// MessageStatusPending 等待生成
+	MessageStatusPending = "pending"
+	// MessageStatusStreaming 正在流式生成
+	MessageStatusStreaming = "streaming"
+	// MessageStatusCompleted 生成完成
+	MessageStatusCompleted = "completed"
+	// MessageStatusFailed 生成失败，失败原因见 Error 字段
+	MessageStatusFailed = "failed"
+	// MessageStatusAborted 生成被中止，内容为中止前已生成的部分
+	MessageStatusAborted = "aborted"
+)
+
 // TableName 指定表名
 func (ChatMessage) TableName() string {
 	return "chat_messages"
 }
 
+// ChatAttachment 消息附件实体
+// 保存用户消息中的媒体片段，内联数据以二进制保存，URL 引用只保存地址，引用上传文件只保存文件ID
+type ChatAttachment struct {
+	// 附件ID
+	ID string `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	// 所属消息ID
+	MessageID string `gorm:"type:uuid;not null;index:idx_attachment_message" json:"messageId"`
+	// 会话ID
+	SessionID string `gorm:"type:uuid;not null" json:"sessionId"`
+	// 在消息媒体片段中的位置，从 0 开始
+	Position int `gorm:"not null" json:"position"`
+	// MIME 类型
+	MimeType string `gorm:"type:varchar(128);not null" json:"mimeType"`
+	// 内联数据
+	Data []byte `gorm:"type:bytea" json:"-"`
+	// 媒体地址
+	URL string `gorm:"type:text" json:"url,omitempty"`
+	// 数据大小（字节），URL 引用为 0
+	Size int `gorm:"default:0" json:"size"`
+	// 引用的上传文件ID，内容在加载附件时从文件存储读取到 Data
+	FileID *string `gorm:"type:uuid" json:"fileId,omitempty"`
+	// 创建时间
+	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"createdAt"`
+
+	// 关联
+	Message *ChatMessage `gorm:"foreignKey:MessageID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TableName 指定表名
+func (ChatAttachment) TableName() string {
+	return "chat_attachments"
+}
+
 // ChatSummary 会话摘要实体
 type ChatSummary struct {
 	// 摘要ID
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"genkit-ai-service/internal/model"
+)
+
+// AttachmentRepository 消息附件数据访问接口
+type AttachmentRepository interface {
+	// Create 批量创建附件
+	Create(ctx context.Context, attachments []*model.ChatAttachment) error
+
+	// GetByMessageIDs 获取多条消息的附件（按消息ID和位置排序）
+	GetByMessageIDs(ctx context.Context, messageIDs []string) ([]*model.ChatAttachment, error)
+}
+
+// attachmentRepository 消息附件数据访问实现
+type attachmentRepository struct {
+	db *gorm.DB
+}
+
+// NewAttachmentRepository 创建消息附件数据访问实例
+func NewAttachmentRepository(db *gorm.DB) AttachmentRepository {
+	return &attachmentRepository{
+		db: db,
+	}
+}
+
+// Create 批量创建附件
+func (r *attachmentRepository) Create(ctx context.Context, attachments []*model.ChatAttachment) error {
+	if len(attachments) == 0 {
+		return nil
+	}
+	if err := r.db.WithContext(ctx).Create(attachments).Error; err != nil {
+		return fmt.Errorf("创建附件失败: %w", err)
+	}
+	return nil
+}
+
+// GetByMessageIDs 获取多条消息的附件（按消息ID和位置排序）
+func (r *attachmentRepository) GetByMessageIDs(ctx context.Context, messageIDs []string) ([]*model.ChatAttachment, error) {
+	var attachments []*model.ChatAttachment
+	if len(messageIDs) == 0 {
+		return attachments, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Where("message_id IN ?", messageIDs).
+		Order("message_id, position ASC").
+		Find(&attachments).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("查询消息附件失败: %w", err)
+	}
+
+	return attachments, nil
+}
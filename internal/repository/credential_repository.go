@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"genkit-ai-service/internal/model"
+)
+
+// CredentialRepository 模型提供商凭证数据访问接口
+// 凭证ID为空时在写入前生成
+type CredentialRepository interface {
+	// Create 创建凭证
+	Create(ctx context.Context, credential *model.ProviderCredential) error
+
+	// GetByID 根据ID获取凭证，不存在时返回 ErrNotFound
+	GetByID(ctx context.Context, credentialID string) (*model.ProviderCredential, error)
+
+	// FindByScope 获取所有者在指定范围（提供商、模型类型、模型）内的凭证，不存在时返回 ErrNotFound
+	FindByScope(ctx context.Context, owner model.CredentialOwner, providerID, modelType, modelName string) (*model.ProviderCredential, error)
+
+	// List 获取所有者的凭证列表，providerID 不为空时只返回该提供商的凭证，按创建时间倒序排列
+	List(ctx context.Context, owner model.CredentialOwner, providerID string) ([]*model.ProviderCredential, error)
+
+	// UpdateCredentials 更新加密后的凭证
+	UpdateCredentials(ctx context.Context, credentialID, encrypted string, updatedAt time.Time) error
+
+	// Delete 删除凭证
+	Delete(ctx context.Context, credentialID string) error
+}
+
+// credentialRepository 模型提供商凭证数据访问实现
+type credentialRepository struct {
+	db *gorm.DB
+}
+
+// NewCredentialRepository 创建模型提供商凭证数据访问实例
+func NewCredentialRepository(db *gorm.DB) CredentialRepository {
+	return &credentialRepository{
+		db: db,
+	}
+}
+
+// Create 创建凭证
+func (r *credentialRepository) Create(ctx context.Context, credential *model.ProviderCredential) error {
+	if credential.ID == "" {
+		credential.ID = uuid.New().String()
+	}
+	if err := r.db.WithContext(ctx).Create(credential).Error; err != nil {
+		return fmt.Errorf("创建凭证失败: %w", err)
+	}
+	return nil
+}
+
+// GetByID 根据ID获取凭证，不存在时返回 ErrNotFound
+func (r *credentialRepository) GetByID(ctx context.Context, credentialID string) (*model.ProviderCredential, error) {
+	var credential model.ProviderCredential
+	err := r.db.WithContext(ctx).
+		Where("id = ?", credentialID).
+		First(&credential).Error
+
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("查询凭证失败: %w", err)
+	}
+
+	return &credential, nil
+}
+
+// FindByScope 获取所有者在指定范围内的凭证，不存在时返回 ErrNotFound
+func (r *credentialRepository) FindByScope(ctx context.Context, owner model.CredentialOwner, providerID, modelType, modelName string) (*model.ProviderCredential, error) {
+	var credential model.ProviderCredential
+	err := r.db.WithContext(ctx).
+		Where("owner_type = ? AND owner_id = ? AND provider_id = ? AND model_type = ? AND model = ?",
+			owner.Type, owner.ID, providerID, modelType, modelName).
+		First(&credential).Error
+
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("查询凭证失败: %w", err)
+	}
+
+	return &credential, nil
+}
+
+// List 获取所有者的凭证列表
+func (r *credentialRepository) List(ctx context.Context, owner model.CredentialOwner, providerID string) ([]*model.ProviderCredential, error) {
+	query := r.db.WithContext(ctx).
+		Where("owner_type = ? AND owner_id = ?", owner.Type, owner.ID)
+	if providerID != "" {
+		query = query.Where("provider_id = ?", providerID)
+	}
+
+	var credentials []*model.ProviderCredential
+	if err := query.Order("created_at DESC").Find(&credentials).Error; err != nil {
+		return nil, fmt.Errorf("查询凭证列表失败: %w", err)
+	}
+	return credentials, nil
+}
+
+// UpdateCredentials 更新加密后的凭证
+func (r *credentialRepository) UpdateCredentials(ctx context.Context, credentialID, encrypted string, updatedAt time.Time) error {
+	result := r.db.WithContext(ctx).
+		Model(&model.ProviderCredential{}).
+		Where("id = ?", credentialID).
+		Updates(map[string]interface{}{
+			"encrypted_credentials": encrypted,
+			"updated_at":            updatedAt,
+		})
+
+	if result.Error != nil {
+		return fmt.Errorf("更新凭证失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete 删除凭证
+func (r *credentialRepository) Delete(ctx context.Context, credentialID string) error {
+	if err := r.db.WithContext(ctx).Where("id = ?", credentialID).Delete(&model.ProviderCredential{}).Error; err != nil {
+		return fmt.Errorf("删除凭证失败: %w", err)
+	}
+	return nil
+}
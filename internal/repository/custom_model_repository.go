@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"genkit-ai-service/internal/model"
+)
+
+// CustomModelRepository 自定义模型数据访问接口
+// 自定义模型ID为空时在写入前生成
+type CustomModelRepository interface {
+	// Create 创建自定义模型
+	Create(ctx context.Context, customModel *model.CustomModel) error
+
+	// FindByName 根据提供商ID和模型名称获取自定义模型，不存在时返回 ErrNotFound
+	FindByName(ctx context.Context, providerID, modelName string) (*model.CustomModel, error)
+
+	// List 获取自定义模型列表，providerID 不为空时只返回该提供商的模型，按创建时间正序排列
+	List(ctx context.Context, providerID string) ([]*model.CustomModel, error)
+
+	// Delete 删除自定义模型
+	Delete(ctx context.Context, customModelID string) error
+}
+
+// customModelRepository 自定义模型数据访问实现
+type customModelRepository struct {
+	db *gorm.DB
+}
+
+// NewCustomModelRepository 创建自定义模型数据访问实例
+func NewCustomModelRepository(db *gorm.DB) CustomModelRepository {
+	return &customModelRepository{
+		db: db,
+	}
+}
+
+// Create 创建自定义模型
+func (r *customModelRepository) Create(ctx context.Context, customModel *model.CustomModel) error {
+	if customModel.ID == "" {
+		customModel.ID = uuid.New().String()
+	}
+	if err := r.db.WithContext(ctx).Create(customModel).Error; err != nil {
+		return fmt.Errorf("创建自定义模型失败: %w", err)
+	}
+	return nil
+}
+
+// FindByName 根据提供商ID和模型名称获取自定义模型，不存在时返回 ErrNotFound
+func (r *customModelRepository) FindByName(ctx context.Context, providerID, modelName string) (*model.CustomModel, error) {
+	var customModel model.CustomModel
+	err := r.db.WithContext(ctx).
+		Where("provider_id = ? AND model = ?", providerID, modelName).
+		First(&customModel).Error
+
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("查询自定义模型失败: %w", err)
+	}
+
+	return &customModel, nil
+}
+
+// List 获取自定义模型列表
+func (r *customModelRepository) List(ctx context.Context, providerID string) ([]*model.CustomModel, error) {
+	query := r.db.WithContext(ctx)
+	if providerID != "" {
+		query = query.Where("provider_id = ?", providerID)
+	}
+
+	var customModels []*model.CustomModel
+	if err := query.Order("created_at ASC").Find(&customModels).Error; err != nil {
+		return nil, fmt.Errorf("查询自定义模型列表失败: %w", err)
+	}
+	return customModels, nil
+}
+
+// Delete 删除自定义模型
+func (r *customModelRepository) Delete(ctx context.Context, customModelID string) error {
+	if err := r.db.WithContext(ctx).Where("id = ?", customModelID).Delete(&model.CustomModel{}).Error; err != nil {
+		return fmt.Errorf("删除自定义模型失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"genkit-ai-service/internal/model"
+)
+
+// FileRepository 上传文件元数据访问接口
+type FileRepository interface {
+	// Create 创建文件记录
+	Create(ctx context.Context, file *model.File) error
+
+	// GetByID 根据ID获取文件记录，不存在时返回 ErrNotFound
+	GetByID(ctx context.Context, fileID string) (*model.File, error)
+
+	// Delete 删除文件记录
+	Delete(ctx context.Context, fileID string) error
+
+	// CountBySHA256 统计引用相同内容的文件记录数量
+	CountBySHA256(ctx context.Context, sha256 string) (int64, error)
+}
+
+// fileRepository 上传文件元数据访问实现
+type fileRepository struct {
+	db *gorm.DB
+}
+
+// NewFileRepository 创建上传文件元数据访问实例
+func NewFileRepository(db *gorm.DB) FileRepository {
+	return &fileRepository{
+		db: db,
+	}
+}
+
+// Create 创建文件记录
+func (r *fileRepository) Create(ctx context.Context, file *model.File) error {
+	if err := r.db.WithContext(ctx).Create(file).Error; err != nil {
+		return fmt.Errorf("创建文件记录失败: %w", err)
+	}
+	return nil
+}
+
+// GetByID 根据ID获取文件记录，不存在时返回 ErrNotFound
+func (r *fileRepository) GetByID(ctx context.Context, fileID string) (*model.File, error) {
+	var file model.File
+	err := r.db.WithContext(ctx).
+		Where("id = ?", fileID).
+		First(&file).Error
+
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("查询文件记录失败: %w", err)
+	}
+
+	return &file, nil
+}
+
+// Delete 删除文件记录
+func (r *fileRepository) Delete(ctx context.Context, fileID string) error {
+	if err := r.db.WithContext(ctx).Where("id = ?", fileID).Delete(&model.File{}).Error; err != nil {
+		return fmt.Errorf("删除文件记录失败: %w", err)
+	}
+	return nil
+}
+
+// CountBySHA256 统计引用相同内容的文件记录数量
+func (r *fileRepository) CountBySHA256(ctx context.Context, sha256 string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&model.File{}).
+		Where("sha256 = ?", sha256).
+		Count(&count).Error
+
+	if err != nil {
+		return 0, fmt.Errorf("统计文件记录失败: %w", err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,301 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"genkit-ai-service/internal/model"
+)
+
+// chunkInsertBatchSize 批量写入分块时每批的数量
+const chunkInsertBatchSize = 100
+
+// ChunkMatch 向量检索命中的分块
+type ChunkMatch struct {
+	model.KnowledgeChunk
+	// 分块所属文档的名称
+	DocumentName string
+	// 与查询向量的余弦相似度
+	Score float64
+}
+
+// KnowledgeRepository 知识库数据访问接口
+// 知识库、文档和分块的ID为空时在写入前生成
+type KnowledgeRepository interface {
+	// CreateBase 创建知识库
+	CreateBase(ctx context.Context, kb *model.KnowledgeBase) error
+
+	// GetBase 根据ID获取知识库，不存在时返回 ErrNotFound
+	GetBase(ctx context.Context, knowledgeBaseID string) (*model.KnowledgeBase, error)
+
+	// ListBases 获取用户的知识库列表，按创建时间倒序排列
+	ListBases(ctx context.Context, userID string) ([]*model.KnowledgeBase, error)
+
+	// DeleteBase 删除知识库及其文档和分块
+	DeleteBase(ctx context.Context, knowledgeBaseID string) error
+
+	// CreateDocument 在同一事务中保存文档及其分块，并更新知识库的文档数量
+	CreateDocument(ctx context.Context, doc *model.KnowledgeDocument, chunks []*model.KnowledgeChunk) error
+
+	// GetDocument 根据ID获取文档，不存在时返回 ErrNotFound
+	GetDocument(ctx context.Context, documentID string) (*model.KnowledgeDocument, error)
+
+	// ListDocuments 获取知识库的文档列表，按创建时间倒序排列
+	ListDocuments(ctx context.Context, knowledgeBaseID string) ([]*model.KnowledgeDocument, error)
+
+	// DeleteDocument 删除文档及其分块，并更新知识库的文档数量
+	DeleteDocument(ctx context.Context, doc *model.KnowledgeDocument) error
+
+	// SearchChunks 在指定知识库中检索与查询向量余弦相似度最高的 topK 个分块，按相似度降序排列
+	// PostgreSQL 使用 pgvector 的 <=> 运算符在数据库中排序，其他数据库（如测试使用的 SQLite）在进程内计算
+	SearchChunks(ctx context.Context, knowledgeBaseIDs []string, query model.Vector, topK int) ([]*ChunkMatch, error)
+}
+
+// knowledgeRepository 知识库数据访问实现
+type knowledgeRepository struct {
+	db *gorm.DB
+}
+
+// NewKnowledgeRepository 创建知识库数据访问实例
+func NewKnowledgeRepository(db *gorm.DB) KnowledgeRepository {
+	return &knowledgeRepository{
+		db: db,
+	}
+}
+
+// CreateBase 创建知识库
+func (r *knowledgeRepository) CreateBase(ctx context.Context, kb *model.KnowledgeBase) error {
+	if kb.ID == "" {
+		kb.ID = uuid.New().String()
+	}
+	if err := r.db.WithContext(ctx).Create(kb).Error; err != nil {
+		return fmt.Errorf("创建知识库失败: %w", err)
+	}
+	return nil
+}
+
+// GetBase 根据ID获取知识库，不存在时返回 ErrNotFound
+func (r *knowledgeRepository) GetBase(ctx context.Context, knowledgeBaseID string) (*model.KnowledgeBase, error) {
+	var kb model.KnowledgeBase
+	err := r.db.WithContext(ctx).
+		Where("id = ?", knowledgeBaseID).
+		First(&kb).Error
+
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("查询知识库失败: %w", err)
+	}
+
+	return &kb, nil
+}
+
+// ListBases 获取用户的知识库列表，按创建时间倒序排列
+func (r *knowledgeRepository) ListBases(ctx context.Context, userID string) ([]*model.KnowledgeBase, error) {
+	var bases []*model.KnowledgeBase
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&bases).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("查询知识库列表失败: %w", err)
+	}
+
+	return bases, nil
+}
+
+// DeleteBase 删除知识库及其文档和分块
+func (r *knowledgeRepository) DeleteBase(ctx context.Context, knowledgeBaseID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("knowledge_base_id = ?", knowledgeBaseID).Delete(&model.KnowledgeChunk{}).Error; err != nil {
+			return fmt.Errorf("删除知识库分块失败: %w", err)
+		}
+		if err := tx.Where("knowledge_base_id = ?", knowledgeBaseID).Delete(&model.KnowledgeDocument{}).Error; err != nil {
+			return fmt.Errorf("删除知识库文档失败: %w", err)
+		}
+		if err := tx.Where("id = ?", knowledgeBaseID).Delete(&model.KnowledgeBase{}).Error; err != nil {
+			return fmt.Errorf("删除知识库失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// CreateDocument 在同一事务中保存文档及其分块，并更新知识库的文档数量
+func (r *knowledgeRepository) CreateDocument(ctx context.Context, doc *model.KnowledgeDocument, chunks []*model.KnowledgeChunk) error {
+	if doc.ID == "" {
+		doc.ID = uuid.New().String()
+	}
+	for _, chunk := range chunks {
+		if chunk.ID == "" {
+			chunk.ID = uuid.New().String()
+		}
+		chunk.DocumentID = doc.ID
+		chunk.KnowledgeBaseID = doc.KnowledgeBaseID
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(doc).Error; err != nil {
+			return fmt.Errorf("创建文档失败: %w", err)
+		}
+		if len(chunks) > 0 {
+			if err := tx.CreateInBatches(chunks, chunkInsertBatchSize).Error; err != nil {
+				return fmt.Errorf("保存文档分块失败: %w", err)
+			}
+		}
+		if err := r.adjustDocumentCount(tx, doc.KnowledgeBaseID, 1); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// GetDocument 根据ID获取文档，不存在时返回 ErrNotFound
+func (r *knowledgeRepository) GetDocument(ctx context.Context, documentID string) (*model.KnowledgeDocument, error) {
+	var doc model.KnowledgeDocument
+	err := r.db.WithContext(ctx).
+		Where("id = ?", documentID).
+		First(&doc).Error
+
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("查询文档失败: %w", err)
+	}
+
+	return &doc, nil
+}
+
+// ListDocuments 获取知识库的文档列表，按创建时间倒序排列
+func (r *knowledgeRepository) ListDocuments(ctx context.Context, knowledgeBaseID string) ([]*model.KnowledgeDocument, error) {
+	var docs []*model.KnowledgeDocument
+	err := r.db.WithContext(ctx).
+		Omit("content").
+		Where("knowledge_base_id = ?", knowledgeBaseID).
+		Order("created_at DESC").
+		Find(&docs).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("查询文档列表失败: %w", err)
+	}
+
+	return docs, nil
+}
+
+// DeleteDocument 删除文档及其分块，并更新知识库的文档数量
+func (r *knowledgeRepository) DeleteDocument(ctx context.Context, doc *model.KnowledgeDocument) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("document_id = ?", doc.ID).Delete(&model.KnowledgeChunk{}).Error; err != nil {
+			return fmt.Errorf("删除文档分块失败: %w", err)
+		}
+		if err := tx.Where("id = ?", doc.ID).Delete(&model.KnowledgeDocument{}).Error; err != nil {
+			return fmt.Errorf("删除文档失败: %w", err)
+		}
+		if err := r.adjustDocumentCount(tx, doc.KnowledgeBaseID, -1); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// adjustDocumentCount 调整知识库的文档数量并刷新更新时间
+func (r *knowledgeRepository) adjustDocumentCount(tx *gorm.DB, knowledgeBaseID string, delta int) error {
+	err := tx.Model(&model.KnowledgeBase{}).
+		Where("id = ?", knowledgeBaseID).
+		Updates(map[string]interface{}{
+			"document_count": gorm.Expr("document_count + ?", delta),
+			"updated_at":     time.Now(),
+		}).Error
+
+	if err != nil {
+		return fmt.Errorf("更新知识库文档数量失败: %w", err)
+	}
+	return nil
+}
+
+// SearchChunks 在指定知识库中检索与查询向量余弦相似度最高的 topK 个分块，按相似度降序排列
+func (r *knowledgeRepository) SearchChunks(ctx context.Context, knowledgeBaseIDs []string, query model.Vector, topK int) ([]*ChunkMatch, error) {
+	if len(knowledgeBaseIDs) == 0 || len(query) == 0 || topK <= 0 {
+		return nil, nil
+	}
+
+	if r.db.Dialector.Name() == "postgres" {
+		return r.searchChunksPgvector(ctx, knowledgeBaseIDs, query, topK)
+	}
+	return r.searchChunksInProcess(ctx, knowledgeBaseIDs, query, topK)
+}
+
+// searchChunksPgvector 使用 pgvector 的余弦距离运算符在数据库中检索
+func (r *knowledgeRepository) searchChunksPgvector(ctx context.Context, knowledgeBaseIDs []string, query model.Vector, topK int) ([]*ChunkMatch, error) {
+	var matches []*ChunkMatch
+	err := r.db.WithContext(ctx).
+		Table("knowledge_chunks AS c").
+		Select("c.id, c.knowledge_base_id, c.document_id, c.position, c.heading, c.content, c.tokens, c.created_at, "+
+			"d.name AS document_name, 1 - (c.embedding <=> CAST(? AS vector)) AS score", query).
+		Joins("JOIN knowledge_documents AS d ON d.id = c.document_id").
+		Where("c.knowledge_base_id IN ?", knowledgeBaseIDs).
+		Order(gorm.Expr("c.embedding <=> CAST(? AS vector)", query)).
+		Limit(topK).
+		Scan(&matches).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("检索知识库分块失败: %w", err)
+	}
+
+	return matches, nil
+}
+
+// searchChunksInProcess 加载知识库的全部分块，在进程内计算余弦相似度
+// 维度与查询向量不同的分块被跳过
+func (r *knowledgeRepository) searchChunksInProcess(ctx context.Context, knowledgeBaseIDs []string, query model.Vector, topK int) ([]*ChunkMatch, error) {
+	var candidates []*ChunkMatch
+	err := r.db.WithContext(ctx).
+		Table("knowledge_chunks AS c").
+		Select("c.*, d.name AS document_name").
+		Joins("JOIN knowledge_documents AS d ON d.id = c.document_id").
+		Where("c.knowledge_base_id IN ?", knowledgeBaseIDs).
+		Scan(&candidates).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("检索知识库分块失败: %w", err)
+	}
+
+	matches := candidates[:0]
+	for _, candidate := range candidates {
+		if len(candidate.Embedding) != len(query) {
+			continue
+		}
+		candidate.Score = cosineSimilarity(query, candidate.Embedding)
+		candidate.Embedding = nil
+		matches = append(matches, candidate)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if len(matches) > topK {
+		matches = matches[:topK]
+	}
+
+	return matches, nil
+}
+
+// cosineSimilarity 计算两个等长向量的余弦相似度，任一向量为零向量时返回 0
+func cosineSimilarity(a, b model.Vector) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"genkit-ai-service/internal/model"
+)
+
+// newTestKnowledgeRepository 创建基于内存 SQLite 的知识库仓库，检索走进程内余弦相似度
+func newTestKnowledgeRepository(t *testing.T) (KnowledgeRepository, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(&model.KnowledgeBase{}, &model.KnowledgeDocument{}, &model.KnowledgeChunk{}); err != nil {
+		t.Fatalf("迁移知识库表失败: %v", err)
+	}
+	return NewKnowledgeRepository(db), db
+}
+
+func TestKnowledgeRepository_SearchChunks(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newTestKnowledgeRepository(t)
+
+	kb := &model.KnowledgeBase{UserID: "user-1", Name: "文档", EmbeddingModel: "m", ChunkStrategy: model.ChunkStrategyTokens, ChunkSize: 128, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	other := &model.KnowledgeBase{UserID: "user-1", Name: "其他", EmbeddingModel: "m", ChunkStrategy: model.ChunkStrategyTokens, ChunkSize: 128, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	for _, base := range []*model.KnowledgeBase{kb, other} {
+		if err := repo.CreateBase(ctx, base); err != nil {
+			t.Fatalf("创建知识库失败: %v", err)
+		}
+	}
+
+	doc := &model.KnowledgeDocument{KnowledgeBaseID: kb.ID, Name: "部署.md", Content: "...", CreatedAt: time.Now()}
+	chunks := []*model.KnowledgeChunk{
+		{Position: 0, Content: "数据库", Embedding: model.Vector{1, 0, 0}, CreatedAt: time.Now()},
+		{Position: 1, Content: "缓存", Embedding: model.Vector{0.6, 0.8, 0}, CreatedAt: time.Now()},
+		{Position: 2, Content: "日志", Embedding: model.Vector{0, 0, 1}, CreatedAt: time.Now()},
+		{Position: 3, Content: "维度不同", Embedding: model.Vector{1, 0}, CreatedAt: time.Now()},
+	}
+	if err := repo.CreateDocument(ctx, doc, chunks); err != nil {
+		t.Fatalf("创建文档失败: %v", err)
+	}
+	otherDoc := &model.KnowledgeDocument{KnowledgeBaseID: other.ID, Name: "其他.md", Content: "...", CreatedAt: time.Now()}
+	if err := repo.CreateDocument(ctx, otherDoc, []*model.KnowledgeChunk{
+		{Position: 0, Content: "其他知识库", Embedding: model.Vector{1, 0, 0}, CreatedAt: time.Now()},
+	}); err != nil {
+		t.Fatalf("创建文档失败: %v", err)
+	}
+
+	matches, err := repo.SearchChunks(ctx, []string{kb.ID}, model.Vector{1, 0, 0}, 2)
+	if err != nil {
+		t.Fatalf("检索失败: %v", err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("期望返回 2 个分块, 得到 %d", len(matches))
+	}
+	if matches[0].Content != "数据库" || matches[1].Content != "缓存" {
+		t.Errorf("分块顺序不正确: %s, %s", matches[0].Content, matches[1].Content)
+	}
+	if matches[0].Score < 0.999 || matches[1].Score < 0.599 || matches[1].Score > 0.601 {
+		t.Errorf("相似度不正确: %f, %f", matches[0].Score, matches[1].Score)
+	}
+	if matches[0].DocumentName != "部署.md" || matches[0].DocumentID != doc.ID || matches[0].KnowledgeBaseID != kb.ID {
+		t.Errorf("分块信息不正确: %+v", matches[0])
+	}
+
+	t.Run("多个知识库", func(t *testing.T) {
+		matches, err := repo.SearchChunks(ctx, []string{kb.ID, other.ID}, model.Vector{1, 0, 0}, 10)
+		if err != nil {
+			t.Fatalf("检索失败: %v", err)
+		}
+		if len(matches) != 4 {
+			t.Errorf("期望跳过维度不同的分块后返回 4 个, 得到 %d", len(matches))
+		}
+	})
+
+	t.Run("删除文档后不再命中", func(t *testing.T) {
+		if err := repo.DeleteDocument(ctx, doc); err != nil {
+			t.Fatalf("删除文档失败: %v", err)
+		}
+		matches, err := repo.SearchChunks(ctx, []string{kb.ID}, model.Vector{1, 0, 0}, 10)
+		if err != nil {
+			t.Fatalf("检索失败: %v", err)
+		}
+		if len(matches) != 0 {
+			t.Errorf("期望没有命中, 得到 %d", len(matches))
+		}
+		base, err := repo.GetBase(ctx, kb.ID)
+		if err != nil || base.DocumentCount != 0 {
+			t.Errorf("期望文档数量为 0, 得到 %+v %v", base, err)
+		}
+	})
+}
+
+func TestKnowledgeRepository_DeleteBase(t *testing.T) {
+	ctx := context.Background()
+	repo, db := newTestKnowledgeRepository(t)
+
+	kb := &model.KnowledgeBase{UserID: "user-1", Name: "文档", EmbeddingModel: "m", ChunkStrategy: model.ChunkStrategyTokens, ChunkSize: 128, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := repo.CreateBase(ctx, kb); err != nil {
+		t.Fatalf("创建知识库失败: %v", err)
+	}
+	doc := &model.KnowledgeDocument{KnowledgeBaseID: kb.ID, Name: "a.md", Content: "a", CreatedAt: time.Now()}
+	if err := repo.CreateDocument(ctx, doc, []*model.KnowledgeChunk{{Content: "a", Embedding: model.Vector{1}, CreatedAt: time.Now()}}); err != nil {
+		t.Fatalf("创建文档失败: %v", err)
+	}
+
+	base, _ := repo.GetBase(ctx, kb.ID)
+	if base.DocumentCount != 1 {
+		t.Errorf("期望文档数量为 1, 得到 %d", base.DocumentCount)
+	}
+
+	if err := repo.DeleteBase(ctx, kb.ID); err != nil {
+		t.Fatalf("删除知识库失败: %v", err)
+	}
+	if _, err := repo.GetBase(ctx, kb.ID); err != ErrNotFound {
+		t.Errorf("期望知识库不存在, 得到 %v", err)
+	}
+	var count int64
+	db.Model(&model.KnowledgeChunk{}).Count(&count)
+	if count != 0 {
+		t.Errorf("期望分块被删除, 剩余 %d", count)
+	}
+}
@@ -17,12 +17,21 @@ type MessageRepository interface {
 	// GetByID 根据ID获取消息
 	GetByID(ctx context.Context, messageID string) (*model.ChatMessage, error)
 
-	// GetBySessionID 获取会话的消息列表（支持分页）
-	GetBySessionID(ctx context.Context, sessionID string, page, pageSize int) ([]*model.ChatMessage, int, error)
+	// GetBySessionID 获取会话的消息列表（支持分页和过滤）
+	GetBySessionID(ctx context.Context, sessionID string, page, pageSize int, filters *MessageFilters) ([]*model.ChatMessage, int, error)
+
+	// UpdateFields 更新指定字段
+	UpdateFields(ctx context.Context, messageID string, fields map[string]interface{}) error
 
 	// GetLatestMessages 获取最新的N条消息
 	GetLatestMessages(ctx context.Context, sessionID string, limit int) ([]*model.ChatMessage, error)
 
+	// GetBranch 获取从根消息到指定消息的分支（按序列号正序排列）
+	GetBranch(ctx context.Context, sessionID, leafMessageID string) ([]*model.ChatMessage, error)
+
+	// GetChildren 获取指定父消息的子消息（按序列号正序排列），parentID 为 nil 时返回会话的根消息
+	GetChildren(ctx context.Context, sessionID string, parentID *string) ([]*model.ChatMessage, error)
+
 	// GetNextSequence 获取下一个序列号
 	GetNextSequence(ctx context.Context, sessionID string) (int, error)
 
@@ -33,6 +42,23 @@ type MessageRepository interface {
 	GetMessagesAfter(ctx context.Context, sessionID string, afterMessageID string) ([]*model.ChatMessage, error)
 }
 
+// MessageFilters 消息过滤条件
+type MessageFilters struct {
+	// 消息状态，为空时不过滤
+	Status string
+	// 分支末端消息ID，不为空时只返回从根消息到该消息的分支上的消息
+	BranchLeafID string
+}
+
+// branchQuery 从指定消息沿 parent_id 向上查找，返回从根消息到该消息的分支上的所有消息ID
+const branchQuery = `
+	WITH RECURSIVE branch AS (
+		SELECT id, parent_id FROM chat_messages WHERE id = ?
+		UNION ALL
+		SELECT m.id, m.parent_id FROM chat_messages m JOIN branch b ON m.id = b.parent_id
+	)
+	SELECT id FROM branch`
+
 // messageRepository 消息数据访问实现
 type messageRepository struct {
 	db *gorm.DB
@@ -70,8 +96,8 @@ func (r *messageRepository) GetByID(ctx context.Context, messageID string) (*mod
 	return &message, nil
 }
 
-// GetBySessionID 获取会话的消息列表（支持分页）
-func (r *messageRepository) GetBySessionID(ctx context.Context, sessionID string, page, pageSize int) ([]*model.ChatMessage, int, error) {
+// GetBySessionID 获取会话的消息列表（支持分页和过滤）
+func (r *messageRepository) GetBySessionID(ctx context.Context, sessionID string, page, pageSize int, filters *MessageFilters) ([]*model.ChatMessage, int, error) {
 	var messages []*model.ChatMessage
 	var total int64
 
@@ -79,6 +105,16 @@ func (r *messageRepository) GetBySessionID(ctx context.Context, sessionID string
 	query := r.db.WithContext(ctx).Model(&model.ChatMessage{}).
 		Where("session_id = ?", sessionID)
 
+	// 应用过滤条件
+	if filters != nil {
+		if filters.Status != "" {
+			query = query.Where("status = ?", filters.Status)
+		}
+		if filters.BranchLeafID != "" {
+			query = query.Where("id IN ("+branchQuery+")", filters.BranchLeafID)
+		}
+	}
+
 	// 统计总数
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, fmt.Errorf("统计消息总数失败: %w", err)
@@ -99,6 +135,24 @@ func (r *messageRepository) GetBySessionID(ctx context.Context, sessionID string
 	return messages, int(total), nil
 }
 
+// UpdateFields 更新指定字段
+func (r *messageRepository) UpdateFields(ctx context.Context, messageID string, fields map[string]interface{}) error {
+	result := r.db.WithContext(ctx).
+		Model(&model.ChatMessage{}).
+		Where("id = ?", messageID).
+		Updates(fields)
+
+	if result.Error != nil {
+		return fmt.Errorf("更新消息字段失败: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("消息不存在")
+	}
+
+	return nil
+}
+
 // GetLatestMessages 获取最新的N条消息
 func (r *messageRepository) GetLatestMessages(ctx context.Context, sessionID string, limit int) ([]*model.ChatMessage, error) {
 	var messages []*model.ChatMessage
@@ -121,6 +175,40 @@ func (r *messageRepository) GetLatestMessages(ctx context.Context, sessionID str
 	return messages, nil
 }
 
+// GetBranch 获取从根消息到指定消息的分支（按序列号正序排列）
+func (r *messageRepository) GetBranch(ctx context.Context, sessionID, leafMessageID string) ([]*model.ChatMessage, error) {
+	var messages []*model.ChatMessage
+
+	err := r.db.WithContext(ctx).
+		Where("session_id = ? AND id IN ("+branchQuery+")", sessionID, leafMessageID).
+		Order("sequence ASC").
+		Find(&messages).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("查询消息分支失败: %w", err)
+	}
+
+	return messages, nil
+}
+
+// GetChildren 获取指定父消息的子消息（按序列号正序排列）
+func (r *messageRepository) GetChildren(ctx context.Context, sessionID string, parentID *string) ([]*model.ChatMessage, error) {
+	var messages []*model.ChatMessage
+
+	query := r.db.WithContext(ctx).Where("session_id = ?", sessionID)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+
+	if err := query.Order("sequence ASC").Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("查询子消息失败: %w", err)
+	}
+
+	return messages, nil
+}
+
 // GetNextSequence 获取下一个序列号
 func (r *messageRepository) GetNextSequence(ctx context.Context, sessionID string) (int, error) {
 	var maxSequence int
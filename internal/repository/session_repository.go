@@ -79,7 +79,7 @@ func (r *sessionRepository) GetByID(ctx context.Context, sessionID string) (*mod
 
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("会话不存在")
+			return nil, fmt.Errorf("会话不存在: %w", err)
 		}
 		return nil, fmt.Errorf("查询会话失败: %w", err)
 	}
@@ -14,6 +14,10 @@ type ContextManager interface {
 	// CreateSession 创建新会话
 	CreateSession(ctx context.Context) (string, context.Context, context.CancelFunc)
 
+	// CreateSessionWithKeys 创建新会话并以指定的键注册，第一个键作为会话ID，
+	// 通过任意一个键都可以获取或取消该会话；未指定键时生成随机ID
+	CreateSessionWithKeys(ctx context.Context, keys ...string) (string, context.Context, context.CancelFunc)
+
 	// GetSession 获取会话上下文
 	GetSession(sessionID string) (context.Context, bool)
 
@@ -34,6 +38,7 @@ type ContextManager interface {
 type sessionInfo struct {
 	ctx        context.Context
 	cancel     context.CancelFunc
+	keys       []string
 	createdAt  time.Time
 	lastAccess time.Time
 }
@@ -60,8 +65,23 @@ func NewContextManager(timeout, cleanupInterval time.Duration) ContextManager {
 
 // CreateSession 创建新会话
 func (cm *contextManager) CreateSession(ctx context.Context) (string, context.Context, context.CancelFunc) {
-	// 生成唯一的会话ID
-	sessionID := uuid.New().String()
+	return cm.CreateSessionWithKeys(ctx)
+}
+
+// CreateSessionWithKeys 创建新会话并以指定的键注册
+// 键已被其他会话占用时，新会话覆盖旧的注册，旧会话仍可通过其他键访问
+func (cm *contextManager) CreateSessionWithKeys(ctx context.Context, keys ...string) (string, context.Context, context.CancelFunc) {
+	sessionKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key != "" {
+			sessionKeys = append(sessionKeys, key)
+		}
+	}
+	if len(sessionKeys) == 0 {
+		// 生成唯一的会话ID
+		sessionKeys = append(sessionKeys, uuid.New().String())
+	}
+	sessionID := sessionKeys[0]
 
 	// 创建可取消的上下文
 	sessionCtx, cancel := context.WithCancel(ctx)
@@ -71,19 +91,22 @@ func (cm *contextManager) CreateSession(ctx context.Context) (string, context.Co
 	info := &sessionInfo{
 		ctx:        sessionCtx,
 		cancel:     cancel,
+		keys:       sessionKeys,
 		createdAt:  now,
 		lastAccess: now,
 	}
 
 	// 存储会话信息
 	cm.mu.Lock()
-	cm.sessions[sessionID] = info
+	for _, key := range sessionKeys {
+		cm.sessions[key] = info
+	}
 	cm.mu.Unlock()
 
 	// 返回包装的取消函数，确保在取消时清理会话
 	wrappedCancel := func() {
 		cancel()
-		cm.CleanupSession(sessionID)
+		cm.removeSession(info)
 	}
 
 	return sessionID, sessionCtx, wrappedCancel
@@ -134,8 +157,26 @@ func (cm *contextManager) CleanupSession(sessionID string) {
 	if info, exists := cm.sessions[sessionID]; exists {
 		// 确保取消函数被调用
 		info.cancel()
-		// 从映射中删除
-		delete(cm.sessions, sessionID)
+		// 从映射中删除会话的所有键
+		cm.deleteKeysLocked(info)
+	}
+}
+
+// removeSession 取消并清理指定会话
+func (cm *contextManager) removeSession(info *sessionInfo) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	info.cancel()
+	cm.deleteKeysLocked(info)
+}
+
+// deleteKeysLocked 删除会话的所有键，键已被其他会话覆盖时保留，调用方需持有写锁
+func (cm *contextManager) deleteKeysLocked(info *sessionInfo) {
+	for _, key := range info.keys {
+		if cm.sessions[key] == info {
+			delete(cm.sessions, key)
+		}
 	}
 }
 
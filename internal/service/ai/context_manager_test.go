@@ -122,6 +122,34 @@ func TestCleanupSession(t *testing.T) {
 	}
 }
 
+func TestCreateSessionWithKeys(t *testing.T) {
+	cm := NewContextManager(30*time.Minute, 5*time.Minute)
+
+	sessionID, sessionCtx, cancel := cm.CreateSessionWithKeys(context.Background(), "", "message-1", "session-1")
+
+	// 第一个非空键作为会话ID
+	if sessionID != "message-1" {
+		t.Errorf("期望会话ID为 message-1, 得到 %s", sessionID)
+	}
+
+	// 所有键都可以取消同一个会话
+	if err := cm.CancelSession("session-1"); err != nil {
+		t.Fatalf("通过别名取消会话失败: %v", err)
+	}
+	if sessionCtx.Err() != context.Canceled {
+		t.Error("会话上下文未被取消")
+	}
+
+	// 取消后所有键都被移除
+	cancel()
+	if _, exists := cm.GetSession("message-1"); exists {
+		t.Error("取消后不应该找到会话")
+	}
+	if _, exists := cm.GetSession("session-1"); exists {
+		t.Error("取消后不应该找到会话别名")
+	}
+}
+
 func TestSessionTimeout(t *testing.T) {
 	// 使用较短的超时时间进行测试
 	timeout := 100 * time.Millisecond
@@ -0,0 +1,137 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"genkit-ai-service/internal/genkit"
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/pkg/errors"
+	"genkit-ai-service/pkg/tokenizer"
+)
+
+// defaultMaxChunks 模型目录未配置 max_chunks 时单次请求的最大输入条数
+const defaultMaxChunks = 16
+
+// EmbeddingService 文本向量化服务接口
+type EmbeddingService interface {
+	// Embed 使用目录中的向量模型为一批文本生成向量
+	// 输入超过模型单次请求的条数上限时分批请求，结果按输入顺序返回
+	Embed(ctx context.Context, req *model.EmbeddingRequest) (*model.EmbeddingResponse, error)
+}
+
+// embeddingService 文本向量化服务实现
+type embeddingService struct {
+	embedder genkit.Embedder
+	resolver genkit.ModelResolver
+	logger   logger.Logger
+}
+
+// NewEmbeddingService 创建文本向量化服务
+// 参数:
+//
+//	embedder: 向量化客户端（通常为模型路由）
+//	resolver: 模型解析器，用于读取模型的上下文大小和单次请求条数上限
+//	log: 日志记录器
+//
+// 返回:
+//
+//	EmbeddingService: 文本向量化服务实例
+func NewEmbeddingService(embedder genkit.Embedder, resolver genkit.ModelResolver, log logger.Logger) EmbeddingService {
+	return &embeddingService{
+		embedder: embedder,
+		resolver: resolver,
+		logger:   log,
+	}
+}
+
+// Embed 使用目录中的向量模型为一批文本生成向量
+func (s *embeddingService) Embed(ctx context.Context, req *model.EmbeddingRequest) (*model.EmbeddingResponse, error) {
+	startTime := time.Now()
+
+	// 1. 解析模型并校验模型类型
+	providerID, mdl, err := s.resolver.ResolveModel(req.Model)
+	if err != nil {
+		return nil, err
+	}
+	if mdl.ModelType != model.ModelTypeTextEmbedding {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("模型 '%s' 不是向量模型", req.Model))
+	}
+
+	// 2. 按模型的上下文大小校验每条输入
+	tokens := make([]int, len(req.Input))
+	for i, input := range req.Input {
+		tokens[i] = tokenizer.Estimate(input)
+		if contextSize := mdl.ModelProperties.ContextSize; contextSize > 0 && tokens[i] > contextSize {
+			return nil, errors.NewBadRequestError(fmt.Sprintf(
+				"input[%d] 约 %d 个 token，超过模型 '%s' 的上下文大小 %d", i, tokens[i], req.Model, contextSize))
+		}
+	}
+
+	// 3. 按模型单次请求的条数上限分批生成向量
+	batchSize := mdl.ModelProperties.MaxChunks
+	if batchSize <= 0 {
+		batchSize = defaultMaxChunks
+	}
+
+	options := &genkit.EmbedOptions{
+		Model:      providerID + "/" + mdl.Model,
+		Dimensions: req.Dimensions,
+	}
+
+	resp := &model.EmbeddingResponse{
+		Model: mdl.Model,
+		Data:  make([]model.EmbeddingData, 0, len(req.Input)),
+		Usage: &model.Usage{},
+	}
+
+	for start := 0; start < len(req.Input); start += batchSize {
+		end := start + batchSize
+		if end > len(req.Input) {
+			end = len(req.Input)
+		}
+
+		result, err := s.embedder.Embed(ctx, req.Input[start:end], options)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "生成向量失败", logger.Fields{
+				"model": req.Model,
+				"batch": start / batchSize,
+				"error": err.Error(),
+			})
+			if appErr, ok := err.(*errors.AppError); ok {
+				return nil, appErr
+			}
+			return nil, errors.NewAIServiceError(err)
+		}
+		if len(result.Embeddings) != end-start {
+			return nil, errors.NewAIServiceError(fmt.Errorf("期望 %d 个向量，实际返回 %d 个", end-start, len(result.Embeddings)))
+		}
+
+		for i, embedding := range result.Embeddings {
+			resp.Data = append(resp.Data, model.EmbeddingData{Index: start + i, Embedding: embedding})
+		}
+
+		// 后端未返回用量时按估算的 token 数计算
+		if result.Usage != nil {
+			resp.Usage.PromptTokens += result.Usage.PromptTokens
+			resp.Usage.TotalTokens += result.Usage.TotalTokens
+		} else {
+			for _, count := range tokens[start:end] {
+				resp.Usage.PromptTokens += count
+				resp.Usage.TotalTokens += count
+			}
+		}
+	}
+
+	s.logger.InfoContext(ctx, "向量生成完成", logger.Fields{
+		"model":    req.Model,
+		"inputs":   len(req.Input),
+		"batches":  (len(req.Input) + batchSize - 1) / batchSize,
+		"tokens":   resp.Usage.TotalTokens,
+		"duration": time.Since(startTime).Milliseconds(),
+	})
+
+	return resp, nil
+}
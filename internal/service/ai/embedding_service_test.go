@@ -0,0 +1,170 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"genkit-ai-service/internal/genkit"
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	apperrors "genkit-ai-service/pkg/errors"
+)
+
+// fakeEmbedder 模拟向量化后端，向量的第一个分量为输入文本的长度
+type fakeEmbedder struct {
+	batches [][]string
+	options *genkit.EmbedOptions
+	usage   *genkit.Usage
+	err     error
+}
+
+func (f *fakeEmbedder) Embed(ctx context.Context, inputs []string, options *genkit.EmbedOptions) (*genkit.EmbedResult, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.batches = append(f.batches, inputs)
+	f.options = options
+
+	embeddings := make([][]float32, len(inputs))
+	for i, input := range inputs {
+		embeddings[i] = []float32{float32(len(input)), 0.5}
+	}
+	return &genkit.EmbedResult{Embeddings: embeddings, Model: options.Model, Usage: f.usage}, nil
+}
+
+// fakeModelResolver 测试用模型解析器，key 为模型名称，提供商ID取名称中的前缀
+type fakeModelResolver map[string]model.Model
+
+func (f fakeModelResolver) ResolveModel(modelName string) (string, *model.Model, error) {
+	m, ok := f[modelName]
+	if !ok {
+		return "", nil, apperrors.NewModelNotFoundError(modelName)
+	}
+	providerID, _, found := strings.Cut(modelName, "/")
+	if !found {
+		providerID = "gemini"
+	}
+	return providerID, &m, nil
+}
+
+func newTestEmbeddingService(t *testing.T, embedder *fakeEmbedder) EmbeddingService {
+	resolver := fakeModelResolver{
+		"tongyi/text-embedding-v3": {
+			Model:           "text-embedding-v3",
+			ModelType:       "text_embedding",
+			ModelProperties: model.ModelProperties{ContextSize: 8, MaxChunks: 2},
+		},
+		"text-embedding-004": {
+			Model:           "text-embedding-004",
+			ModelType:       "text_embedding",
+			ModelProperties: model.ModelProperties{ContextSize: 2048},
+		},
+		"qwen-plus": {Model: "qwen-plus", ModelType: "llm"},
+	}
+	log := logger.New(logger.InfoLevel, logger.JSONFormat, &testWriter{t: t})
+	return NewEmbeddingService(embedder, resolver, log)
+}
+
+func TestEmbed_Batching(t *testing.T) {
+	embedder := &fakeEmbedder{}
+	service := newTestEmbeddingService(t, embedder)
+
+	resp, err := service.Embed(context.Background(), &model.EmbeddingRequest{
+		Model: "tongyi/text-embedding-v3",
+		Input: []string{"a", "bb", "ccc", "dddd", "eeeee"},
+	})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+
+	// max_chunks 为 2，5 条输入分 3 批请求
+	if len(embedder.batches) != 3 || len(embedder.batches[2]) != 1 {
+		t.Errorf("期望分 3 批请求, 得到 %v", embedder.batches)
+	}
+	if embedder.options.Model != "tongyi/text-embedding-v3" {
+		t.Errorf("期望以完整模型名称路由, 得到 %s", embedder.options.Model)
+	}
+
+	if resp.Model != "text-embedding-v3" || len(resp.Data) != 5 {
+		t.Fatalf("响应不正确: %+v", resp)
+	}
+	for i, data := range resp.Data {
+		if data.Index != i || data.Embedding[0] != float32(i+1) {
+			t.Errorf("第 %d 个向量顺序不正确: %+v", i, data)
+		}
+	}
+
+	// 后端未返回用量时按估算值累计，每条输入向上取整
+	if resp.Usage == nil || resp.Usage.PromptTokens != 6 || resp.Usage.TotalTokens != 6 {
+		t.Errorf("期望估算 6 个 token, 得到 %+v", resp.Usage)
+	}
+}
+
+func TestEmbed_DefaultMaxChunksAndUsage(t *testing.T) {
+	embedder := &fakeEmbedder{usage: &genkit.Usage{PromptTokens: 3, TotalTokens: 3}}
+	service := newTestEmbeddingService(t, embedder)
+
+	input := make([]string, defaultMaxChunks+1)
+	for i := range input {
+		input[i] = "text"
+	}
+	resp, err := service.Embed(context.Background(), &model.EmbeddingRequest{Model: "text-embedding-004", Input: input})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+
+	if len(embedder.batches) != 2 || len(embedder.batches[0]) != defaultMaxChunks {
+		t.Errorf("未配置 max_chunks 时期望每批 %d 条, 得到 %d 批", defaultMaxChunks, len(embedder.batches))
+	}
+	if resp.Usage.PromptTokens != 6 {
+		t.Errorf("期望累计后端返回的用量 6, 得到 %d", resp.Usage.PromptTokens)
+	}
+}
+
+func TestEmbed_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      *model.EmbeddingRequest
+		err      error
+		wantCode int
+	}{
+		{
+			name:     "模型不存在",
+			req:      &model.EmbeddingRequest{Model: "unknown", Input: []string{"a"}},
+			wantCode: apperrors.CodeModelNotFound,
+		},
+		{
+			name:     "不是向量模型",
+			req:      &model.EmbeddingRequest{Model: "qwen-plus", Input: []string{"a"}},
+			wantCode: apperrors.CodeBadRequest,
+		},
+		{
+			name:     "超过上下文大小",
+			req:      &model.EmbeddingRequest{Model: "tongyi/text-embedding-v3", Input: []string{"ok", strings.Repeat("长", 9)}},
+			wantCode: apperrors.CodeBadRequest,
+		},
+		{
+			name:     "后端调用失败",
+			req:      &model.EmbeddingRequest{Model: "text-embedding-004", Input: []string{"a"}},
+			err:      errors.New("quota exceeded"),
+			wantCode: apperrors.CodeAIServiceError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			embedder := &fakeEmbedder{err: tt.err}
+			service := newTestEmbeddingService(t, embedder)
+
+			_, err := service.Embed(context.Background(), tt.req)
+			if appErr, ok := err.(*apperrors.AppError); !ok || appErr.Code != tt.wantCode {
+				t.Errorf("期望错误码 %d, 得到 %v", tt.wantCode, err)
+			}
+			if tt.err == nil && len(embedder.batches) != 0 {
+				t.Error("校验失败时不应调用后端")
+			}
+		})
+	}
+}
@@ -2,19 +2,35 @@ package ai
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"genkit-ai-service/internal/genkit"
 	"genkit-ai-service/internal/logger"
 	"genkit-ai-service/internal/model"
 	"genkit-ai-service/pkg/errors"
+	"genkit-ai-service/pkg/jsonschema"
 )
 
+// streamBufferSize 流式输出通道缓冲大小
+const streamBufferSize = 16
+
+// maxSchemaRetries 回复不符合 JSON Schema 时要求模型修正的最大次数
+const maxSchemaRetries = 2
+
+// ParameterRules 模型参数规则接口
+type ParameterRules interface {
+	// Apply 按模型参数规则校验对话参数，并为未指定的参数填充模型默认值
+	Apply(modelName string, options *model.ChatOptions) (*model.ChatOptions, error)
+}
+
 // genkitService 基于 Genkit 的 AI 服务实现
 type genkitService struct {
 	client         genkit.Client
 	contextManager ContextManager
+	rules          ParameterRules
 	logger         logger.Logger
 }
 
@@ -22,13 +38,15 @@ type genkitService struct {
 // 参数:
 //   client: Genkit 客户端
 //   contextManager: 上下文管理器
+//   rules: 模型参数规则，为 nil 时不校验参数
 //   log: 日志记录器
 // 返回:
 //   AIService: AI 服务实例
-func NewGenkitService(client genkit.Client, contextManager ContextManager, log logger.Logger) AIService {
+func NewGenkitService(client genkit.Client, contextManager ContextManager, rules ParameterRules, log logger.Logger) AIService {
 	return &genkitService{
 		client:         client,
 		contextManager: contextManager,
+		rules:          rules,
 		logger:         log,
 	}
 }
@@ -37,26 +55,18 @@ func NewGenkitService(client genkit.Client, contextManager ContextManager, log l
 func (s *genkitService) Chat(ctx context.Context, req *model.ChatRequest) (*model.ChatResponse, error) {
 	startTime := time.Now()
 
-	// 创建或获取会话
-	var sessionID string
-	var sessionCtx context.Context
+	// 按模型参数规则校验参数
+	chatOptions, err := s.applyParameterRules(ctx, req)
+	if err != nil {
+		return nil, err
+	}
 
-	if req.MessageID != "" {
-		// 使用现有会话（通过消息ID）
-		sessionID = req.MessageID
-		existingCtx, exists := s.contextManager.GetSession(sessionID)
-		if !exists {
-			s.logger.WarnContext(ctx, "会话不存在，创建新会话", logger.Fields{
-				"requestedMessageId": req.MessageID,
-			})
-			sessionID, sessionCtx, _ = s.contextManager.CreateSession(ctx)
-		} else {
-			sessionCtx = existingCtx
-		}
-	} else {
-		// 创建新会话
-		sessionID, sessionCtx, _ = s.contextManager.CreateSession(ctx)
+	// 创建会话，生成结束后释放，之后不能再被中止
+	sessionID, sessionCtx, release, err := s.acquireSession(ctx, req)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 
 	// 记录请求日志
 	s.logger.InfoContext(sessionCtx, "开始处理对话请求", logger.Fields{
@@ -64,26 +74,23 @@ func (s *genkitService) Chat(ctx context.Context, req *model.ChatRequest) (*mode
 		"message":   req.Message,
 	})
 
-	// 构建生成选项
-	options := s.buildGenerateOptions(req.Options)
+	// 构建生成选项，文本片段并入提示词，媒体片段随提示词发送
+	prompt, media := model.SplitMessageParts(req.Message, req.Parts)
+	options := s.buildGenerateOptions(req, chatOptions, media)
 
 	// 调用 Genkit 生成响应
-	result, err := s.client.Generate(sessionCtx, req.Message, options)
+	result, err := s.client.Generate(sessionCtx, prompt, options)
 	if err != nil {
-		// 检查是否是上下文取消错误
-		if sessionCtx.Err() == context.Canceled {
-			s.logger.WarnContext(ctx, "对话请求被取消", logger.Fields{
-				"sessionId": sessionID,
-				"error":     err.Error(),
-			})
-			return nil, errors.NewContextCancelledError()
-		}
+		return nil, s.handleGenerateError(ctx, sessionCtx, sessionID, err)
+	}
 
-		s.logger.ErrorContext(ctx, "AI 生成失败", logger.Fields{
-			"sessionId": sessionID,
-			"error":     err.Error(),
-		})
-		return nil, errors.NewAIServiceError(err)
+	// 指定了 JSON Schema 时校验回复，模型发起工具调用时没有最终回复，不做校验
+	var parsed interface{}
+	if schema := responseSchema(chatOptions); schema != nil && len(result.ToolCalls) == 0 {
+		result, parsed, err = s.generateStructured(sessionCtx, prompt, options, schema, result)
+		if err != nil {
+			return nil, s.handleGenerateError(ctx, sessionCtx, sessionID, err)
+		}
 	}
 
 	// 构建响应
@@ -93,14 +100,10 @@ func (s *genkitService) Chat(ctx context.Context, req *model.ChatRequest) (*mode
 		Model:     result.Model,
 	}
 
-	// 添加 token 使用情况
-	if result.Usage != nil {
-		response.Usage = &model.Usage{
-			PromptTokens:     result.Usage.PromptTokens,
-			CompletionTokens: result.Usage.CompletionTokens,
-			TotalTokens:      result.Usage.TotalTokens,
-		}
-	}
+	// 添加 token 使用情况、工具调用和结构化输出
+	response.Usage = toModelUsage(result.Usage)
+	response.ToolCalls = toModelToolCalls(result.ToolCalls)
+	response.Parsed = parsed
 
 	// 记录成功日志
 	duration := time.Since(startTime)
@@ -114,15 +117,76 @@ func (s *genkitService) Chat(ctx context.Context, req *model.ChatRequest) (*mode
 	return response, nil
 }
 
-// ChatStream 流式对话（预留接口）
+// ChatStream 流式对话
+// 返回的通道依次输出文本片段，最后一个块的 Done 为 true 并携带模型和 token 使用情况；
+// 生成失败时最后一个块携带 Error。通道在生成结束后关闭，调用方应读取直到通道关闭。
+// 指定了 JSON Schema 时在生成结束后校验完整回复，片段已推送无法重试，不符合时最后一个块携带错误
 func (s *genkitService) ChatStream(ctx context.Context, req *model.ChatRequest) (<-chan model.StreamChunk, error) {
-	// TODO: 实现流式对话功能
-	// 当前版本暂不实现，返回未实现错误
-	return nil, fmt.Errorf("流式对话功能暂未实现")
+	chatOptions, err := s.applyParameterRules(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID, sessionCtx, release, err := s.acquireSession(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(sessionCtx, "开始处理流式对话请求", logger.Fields{
+		"sessionId": sessionID,
+		"message":   req.Message,
+	})
+
+	prompt, media := model.SplitMessageParts(req.Message, req.Parts)
+	options := s.buildGenerateOptions(req, chatOptions, media)
+	chunks := make(chan model.StreamChunk, streamBufferSize)
+
+	go func() {
+		defer close(chunks)
+		defer release()
+		startTime := time.Now()
+
+		result, err := s.client.GenerateStream(sessionCtx, prompt, options, func(cbCtx context.Context, text string) error {
+			select {
+			case chunks <- model.StreamChunk{Content: text}:
+				return nil
+			case <-cbCtx.Done():
+				return cbCtx.Err()
+			}
+		})
+
+		final := model.StreamChunk{Done: true, SessionID: sessionID}
+		if schema := responseSchema(chatOptions); err == nil && schema != nil && len(result.ToolCalls) == 0 {
+			if final.Parsed, err = parseStructured(result.Text, schema); err != nil {
+				err = errors.NewStructuredOutputError(err)
+			}
+		}
+		if err != nil {
+			final.Error = s.handleGenerateError(ctx, sessionCtx, sessionID, err)
+		} else {
+			final.Model = result.Model
+			final.Usage = toModelUsage(result.Usage)
+			final.ToolCalls = toModelToolCalls(result.ToolCalls)
+
+			s.logger.InfoContext(sessionCtx, "流式对话请求处理完成", logger.Fields{
+				"sessionId": sessionID,
+				"model":     result.Model,
+				"duration":  time.Since(startTime).String(),
+				"tokens":    final.Usage,
+			})
+		}
+
+		select {
+		case chunks <- final:
+		case <-ctx.Done():
+		}
+	}()
+
+	return chunks, nil
 }
 
 // AbortChat 中止对话
-func (s *genkitService) AbortChat(ctx context.Context, messageID string) error {
+func (s *genkitService) AbortChat(ctx context.Context, messageID string) (bool, error) {
 	s.logger.InfoContext(ctx, "尝试中止对话", logger.Fields{
 		"messageId": messageID,
 	})
@@ -134,7 +198,7 @@ func (s *genkitService) AbortChat(ctx context.Context, messageID string) error {
 		s.logger.InfoContext(ctx, "会话不存在，无需中止", logger.Fields{
 			"messageId": messageID,
 		})
-		return nil
+		return false, nil
 	}
 
 	// 检查会话是否已经完成
@@ -143,7 +207,7 @@ func (s *genkitService) AbortChat(ctx context.Context, messageID string) error {
 		s.logger.InfoContext(ctx, "会话已完成或已取消，无需中止", logger.Fields{
 			"messageId": messageID,
 		})
-		return nil
+		return false, nil
 	}
 
 	// 取消会话
@@ -153,26 +217,280 @@ func (s *genkitService) AbortChat(ctx context.Context, messageID string) error {
 			"messageId": messageID,
 			"error":     err.Error(),
 		})
-		return errors.NewInternalError(err)
+		return false, errors.NewInternalError(err)
 	}
 
 	s.logger.InfoContext(ctx, "会话已成功中止", logger.Fields{
 		"messageId": messageID,
 	})
 
-	return nil
+	return true, nil
 }
 
-// buildGenerateOptions 构建生成选项
-func (s *genkitService) buildGenerateOptions(options *model.ChatOptions) *genkit.GenerateOptions {
+// acquireSession 为本次生成创建会话，以消息ID和会话ID注册，可通过任意一个中止；
+// 返回的 release 在生成结束后调用。消息ID对应的生成仍在进行时拒绝请求，
+// 避免两个请求共用同一个取消范围
+func (s *genkitService) acquireSession(ctx context.Context, req *model.ChatRequest) (string, context.Context, func(), error) {
+	if req.MessageID != "" {
+		if _, exists := s.contextManager.GetSession(req.MessageID); exists {
+			return "", nil, nil, errors.NewBadRequestError(fmt.Sprintf("消息 '%s' 正在生成中", req.MessageID))
+		}
+	}
+
+	sessionID, sessionCtx, cancel := s.contextManager.CreateSessionWithKeys(ctx, req.MessageID, req.SessionID)
+	return sessionID, sessionCtx, cancel, nil
+}
+
+// handleGenerateError 将生成错误转换为应用错误
+func (s *genkitService) handleGenerateError(ctx, sessionCtx context.Context, sessionID string, err error) error {
+	// 检查是否是上下文取消错误
+	if sessionCtx.Err() == context.Canceled {
+		s.logger.WarnContext(ctx, "对话请求被取消", logger.Fields{
+			"sessionId": sessionID,
+			"error":     err.Error(),
+		})
+		return errors.NewContextCancelledError()
+	}
+
+	// 模型路由产生的应用错误（如模型不存在）直接返回
+	if appErr, ok := err.(*errors.AppError); ok {
+		s.logger.WarnContext(ctx, "AI 生成请求无法处理", logger.Fields{
+			"sessionId": sessionID,
+			"error":     err.Error(),
+		})
+		return appErr
+	}
+
+	s.logger.ErrorContext(ctx, "AI 生成失败", logger.Fields{
+		"sessionId": sessionID,
+		"error":     err.Error(),
+	})
+	return errors.NewAIServiceError(err)
+}
+
+// toGenkitMedia 转换消息中的媒体片段，文本片段被忽略
+func toGenkitMedia(parts []model.MessagePart) []genkit.Media {
+	var media []genkit.Media
+	for _, part := range parts {
+		if part.Type != model.PartTypeMedia {
+			continue
+		}
+		media = append(media, genkit.Media{
+			MimeType: part.MimeType,
+			Data:     part.Data,
+			URL:      part.URL,
+		})
+	}
+	return media
+}
+
+// toModelUsage 转换 token 使用情况
+func toModelUsage(usage *genkit.Usage) *model.Usage {
+	if usage == nil {
+		return nil
+	}
+	return &model.Usage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+}
+
+// toModelToolCalls 转换工具调用
+func toModelToolCalls(calls []genkit.ToolCall) []model.ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	result := make([]model.ToolCall, 0, len(calls))
+	for _, call := range calls {
+		result = append(result, model.ToolCall{
+			ID:        call.ID,
+			Name:      call.Name,
+			Arguments: call.Arguments,
+		})
+	}
+	return result
+}
+
+// generateStructured 校验回复是否符合 JSON Schema，不符合时把校验错误反馈给模型并重新生成
+// 最多修正 maxSchemaRetries 次；返回最终的生成结果（token 使用情况为各次之和）和解析后的回复
+func (s *genkitService) generateStructured(ctx context.Context, prompt string, options *genkit.GenerateOptions, schema map[string]interface{}, result *genkit.GenerateResult) (*genkit.GenerateResult, interface{}, error) {
+	retryOptions := *options
+	retryOptions.History = append([]genkit.Message(nil), options.History...)
+	usage := result.Usage
+
+	for attempt := 1; ; attempt++ {
+		parsed, err := parseStructured(result.Text, schema)
+		if err == nil {
+			result.Usage = usage
+			return result, parsed, nil
+		}
+		if attempt > maxSchemaRetries {
+			return nil, nil, errors.NewStructuredOutputError(err)
+		}
+
+		s.logger.WarnContext(ctx, "模型回复不符合 JSON Schema，要求模型修正", logger.Fields{
+			"attempt": attempt,
+			"error":   err.Error(),
+		})
+
+		// 历史中依次追加本次提问和不符合要求的回复，修正要求作为新的提示词
+		if prompt != "" {
+			retryOptions.History = append(retryOptions.History, genkit.Message{Role: genkit.RoleUser, Content: prompt})
+		}
+		retryOptions.History = append(retryOptions.History, genkit.Message{Role: genkit.RoleAssistant, Content: result.Text})
+		prompt = fmt.Sprintf("你的回复不符合要求的 JSON Schema：%v\n请修正后只输出符合 Schema 的 JSON。", err)
+
+		result, err = s.client.Generate(ctx, prompt, &retryOptions)
+		if err != nil {
+			return nil, nil, err
+		}
+		usage = addUsage(usage, result.Usage)
+	}
+}
+
+// responseSchema 返回对话参数中的 JSON Schema，未指定时返回 nil
+func responseSchema(options *model.ChatOptions) map[string]interface{} {
 	if options == nil {
 		return nil
 	}
+	return options.JSONSchema
+}
+
+// parseStructured 解析回复中的 JSON 并按 Schema 校验，回复被 Markdown 代码块包裹时取代码块中的内容
+func parseStructured(text string, schema map[string]interface{}) (interface{}, error) {
+	content := strings.TrimSpace(text)
+	if strings.HasPrefix(content, "```") {
+		content = strings.TrimPrefix(content, "```")
+		content = strings.TrimPrefix(content, "json")
+		content = strings.TrimSuffix(strings.TrimSpace(content), "```")
+	}
+
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(content), &parsed); err != nil {
+		return nil, fmt.Errorf("回复不是有效的 JSON: %w", err)
+	}
+	if err := jsonschema.Validate(schema, parsed); err != nil {
+		return nil, err
+	}
+
+	return parsed, nil
+}
+
+// addUsage 累加 token 使用情况
+func addUsage(a, b *genkit.Usage) *genkit.Usage {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	return &genkit.Usage{
+		PromptTokens:     a.PromptTokens + b.PromptTokens,
+		CompletionTokens: a.CompletionTokens + b.CompletionTokens,
+		TotalTokens:      a.TotalTokens + b.TotalTokens,
+	}
+}
 
-	return &genkit.GenerateOptions{
-		Temperature: options.Temperature,
-		MaxTokens:   options.MaxTokens,
-		TopP:        options.TopP,
-		TopK:        options.TopK,
+// withSchemaInstruction 在系统提示词后追加按 JSON Schema 输出的要求
+func withSchemaInstruction(system string, schema map[string]interface{}) string {
+	data, _ := json.Marshal(schema)
+	instruction := "请只输出符合以下 JSON Schema 的 JSON，不要输出其他内容：\n" + string(data)
+	if system == "" {
+		return instruction
 	}
+	return system + "\n\n" + instruction
+}
+
+// applyParameterRules 按模型参数规则校验对话参数，返回填充默认值后的参数
+// 会话中保存的 Schema 名称和消息片段引用的上传文件应由会话消息服务解析
+func (s *genkitService) applyParameterRules(ctx context.Context, req *model.ChatRequest) (*model.ChatOptions, error) {
+	for i, part := range req.Parts {
+		if err := part.Validate(); err != nil {
+			return nil, errors.NewBadRequestError(fmt.Sprintf("parts[%d]: %v", i, err))
+		}
+		if part.FileID != "" && part.Data == "" {
+			return nil, errors.NewBadRequestError(fmt.Sprintf("parts[%d]: fileId 只能在会话消息中使用", i))
+		}
+	}
+
+	if req.Options != nil && req.Options.SchemaName != "" && req.Options.JSONSchema == nil {
+		return nil, errors.NewBadRequestError("schemaName 只能在会话消息中使用，请直接指定 jsonSchema")
+	}
+
+	if s.rules == nil {
+		return req.Options, nil
+	}
+
+	chatOptions, err := s.rules.Apply(req.Model, req.Options)
+	if err != nil {
+		s.logger.WarnContext(ctx, "对话参数不符合模型参数规则", logger.Fields{
+			"model": req.Model,
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	return chatOptions, nil
+}
+
+// buildGenerateOptions 构建生成选项
+func (s *genkitService) buildGenerateOptions(req *model.ChatRequest, chatOptions *model.ChatOptions, media []model.MessagePart) *genkit.GenerateOptions {
+	if req.Model == "" && chatOptions == nil && req.SystemPrompt == "" && len(req.History) == 0 && len(req.Tools) == 0 && len(media) == 0 {
+		return nil
+	}
+
+	options := &genkit.GenerateOptions{
+		Model:  req.Model,
+		System: req.SystemPrompt,
+		Media:  toGenkitMedia(media),
+	}
+
+	for _, turn := range req.History {
+		msg := genkit.Message{
+			Role:       turn.Role,
+			Content:    turn.Content,
+			ToolCallID: turn.ToolCallID,
+			Name:       turn.Name,
+			Media:      toGenkitMedia(turn.Parts),
+		}
+		// 会话中的工具结果消息角色为 function，对应模型接口的 tool 角色
+		if turn.Role == "function" {
+			msg.Role = genkit.RoleTool
+		}
+		for _, call := range turn.ToolCalls {
+			msg.ToolCalls = append(msg.ToolCalls, genkit.ToolCall{
+				ID:        call.ID,
+				Name:      call.Name,
+				Arguments: call.Arguments,
+			})
+		}
+		options.History = append(options.History, msg)
+	}
+
+	for _, tool := range req.Tools {
+		options.Tools = append(options.Tools, genkit.ToolDefinition{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Parameters:  tool.Parameters,
+		})
+	}
+
+	if chatOptions != nil {
+		options.Temperature = chatOptions.Temperature
+		options.MaxTokens = chatOptions.MaxTokens
+		options.TopP = chatOptions.TopP
+		options.TopK = chatOptions.TopK
+
+		// 原生支持结构化输出的模型由接口约束输出，其余模型通过系统提示词要求按 Schema 输出
+		if chatOptions.JSONSchema != nil {
+			if chatOptions.NativeJSONSchema {
+				options.JSONSchema = chatOptions.JSONSchema
+			} else {
+				options.System = withSchemaInstruction(options.System, chatOptions.JSONSchema)
+			}
+		}
+	}
+
+	return options
 }
@@ -3,6 +3,7 @@ package ai
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,17 +12,43 @@ import (
 	"genkit-ai-service/internal/genkit"
 	"genkit-ai-service/internal/logger"
 	"genkit-ai-service/internal/model"
+	apperrors "genkit-ai-service/pkg/errors"
 )
 
 // mockGenkitClient 模拟 Genkit 客户端
 type mockGenkitClient struct {
-	generateFunc func(ctx context.Context, prompt string, options *genkit.GenerateOptions) (*genkit.GenerateResult, error)
+	generateFunc       func(ctx context.Context, prompt string, options *genkit.GenerateOptions) (*genkit.GenerateResult, error)
+	generateStreamFunc func(ctx context.Context, prompt string, options *genkit.GenerateOptions, callback genkit.StreamCallback) (*genkit.GenerateResult, error)
 }
 
 func (m *mockGenkitClient) Initialize(ctx context.Context, config *genkit.Config) error {
 	return nil
 }
 
+func (m *mockGenkitClient) InitializeModel(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockGenkitClient) GenerateStream(ctx context.Context, prompt string, options *genkit.GenerateOptions, callback genkit.StreamCallback) (*genkit.GenerateResult, error) {
+	if m.generateStreamFunc != nil {
+		return m.generateStreamFunc(ctx, prompt, options, callback)
+	}
+	for _, chunk := range []string{"测试", "响应"} {
+		if err := callback(ctx, chunk); err != nil {
+			return nil, err
+		}
+	}
+	return &genkit.GenerateResult{
+		Text:  "测试响应",
+		Model: "test-model",
+		Usage: &genkit.Usage{
+			PromptTokens:     10,
+			CompletionTokens: 20,
+			TotalTokens:      30,
+		},
+	}, nil
+}
+
 func (m *mockGenkitClient) Generate(ctx context.Context, prompt string, options *genkit.GenerateOptions) (*genkit.GenerateResult, error) {
 	if m.generateFunc != nil {
 		return m.generateFunc(ctx, prompt, options)
@@ -49,7 +76,7 @@ func TestNewGenkitService(t *testing.T) {
 	contextManager := NewContextManager(30*time.Minute, 5*time.Minute)
 	log := logger.New(logger.InfoLevel, logger.JSONFormat, &testWriter{t: t})
 
-	service := NewGenkitService(client, contextManager, log)
+	service := NewGenkitService(client, contextManager, nil, log)
 	if service == nil {
 		t.Fatal("服务创建失败")
 	}
@@ -71,7 +98,7 @@ func TestChat_Success(t *testing.T) {
 	contextManager := NewContextManager(30*time.Minute, 5*time.Minute)
 	log := logger.New(logger.InfoLevel, logger.JSONFormat, &testWriter{t: t})
 
-	service := NewGenkitService(client, contextManager, log)
+	service := NewGenkitService(client, contextManager, nil, log)
 
 	req := &model.ChatRequest{
 		Message: "你好",
@@ -130,7 +157,7 @@ func TestChat_WithOptions(t *testing.T) {
 	contextManager := NewContextManager(30*time.Minute, 5*time.Minute)
 	log := logger.New(logger.InfoLevel, logger.JSONFormat, &testWriter{t: t})
 
-	service := NewGenkitService(client, contextManager, log)
+	service := NewGenkitService(client, contextManager, nil, log)
 
 	temp := 0.8
 	maxTokens := 1000
@@ -148,13 +175,252 @@ func TestChat_WithOptions(t *testing.T) {
 	}
 }
 
+// TestChat_WithTools 测试工具定义、工具调用历史和工具调用结果的转换
+func TestChat_WithTools(t *testing.T) {
+	client := &mockGenkitClient{
+		generateFunc: func(ctx context.Context, prompt string, options *genkit.GenerateOptions) (*genkit.GenerateResult, error) {
+			if prompt != "" {
+				t.Errorf("工具调用后继续生成时不应有提示词, 得到 %s", prompt)
+			}
+			if len(options.Tools) != 1 || options.Tools[0].Name != "get_weather" {
+				t.Errorf("工具定义不正确: %+v", options.Tools)
+			}
+			if len(options.History) != 3 {
+				t.Fatalf("历史消息数量 = %d, want 3", len(options.History))
+			}
+			if calls := options.History[1].ToolCalls; len(calls) != 1 || calls[0].ID != "call_1" {
+				t.Errorf("历史工具调用不正确: %+v", options.History[1])
+			}
+			if msg := options.History[2]; msg.Role != genkit.RoleTool || msg.ToolCallID != "call_1" || msg.Name != "get_weather" {
+				t.Errorf("function 消息应转换为 tool 消息, 得到 %+v", msg)
+			}
+			return &genkit.GenerateResult{
+				Model:     "test-model",
+				ToolCalls: []genkit.ToolCall{{ID: "call_2", Name: "get_weather", Arguments: `{"city":"上海"}`}},
+			}, nil
+		},
+	}
+	contextManager := NewContextManager(30*time.Minute, 5*time.Minute)
+	service := NewGenkitService(client, contextManager, nil, logger.NewTestLogger())
+
+	resp, err := service.Chat(context.Background(), &model.ChatRequest{
+		History: []model.ChatTurn{
+			{Role: "user", Content: "杭州天气怎么样"},
+			{Role: "assistant", ToolCalls: []model.ToolCall{{ID: "call_1", Name: "get_weather", Arguments: `{"city":"杭州"}`}}},
+			{Role: "function", ToolCallID: "call_1", Name: "get_weather", Content: "晴"},
+		},
+		Tools: []model.ToolDefinition{{Name: "get_weather", Description: "查询天气"}},
+	})
+	if err != nil {
+		t.Fatalf("对话失败: %v", err)
+	}
+
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "call_2" || resp.ToolCalls[0].Arguments != `{"city":"上海"}` {
+		t.Errorf("ToolCalls = %+v", resp.ToolCalls)
+	}
+}
+
+// stubParameterRules 模拟模型参数规则
+type stubParameterRules struct {
+	applyFunc func(modelName string, options *model.ChatOptions) (*model.ChatOptions, error)
+}
+
+func (r *stubParameterRules) Apply(modelName string, options *model.ChatOptions) (*model.ChatOptions, error) {
+	return r.applyFunc(modelName, options)
+}
+
+// TestChat_ParameterRules 测试按模型参数规则校验并填充参数
+func TestChat_ParameterRules(t *testing.T) {
+	var received *genkit.GenerateOptions
+	client := &mockGenkitClient{
+		generateFunc: func(ctx context.Context, prompt string, options *genkit.GenerateOptions) (*genkit.GenerateResult, error) {
+			received = options
+			return &genkit.GenerateResult{Text: "测试响应", Model: "test-model"}, nil
+		},
+	}
+	rules := &stubParameterRules{
+		applyFunc: func(modelName string, options *model.ChatOptions) (*model.ChatOptions, error) {
+			if options != nil && options.MaxTokens != nil && *options.MaxTokens > 8192 {
+				return nil, apperrors.NewValidationError("maxTokens 必须小于或等于 8192")
+			}
+			maxTokens := 8192
+			return &model.ChatOptions{MaxTokens: &maxTokens}, nil
+		},
+	}
+	contextManager := NewContextManager(30*time.Minute, 5*time.Minute)
+	log := logger.New(logger.InfoLevel, logger.JSONFormat, &testWriter{t: t})
+
+	service := NewGenkitService(client, contextManager, rules, log)
+
+	if _, err := service.Chat(context.Background(), &model.ChatRequest{Message: "你好"}); err != nil {
+		t.Fatalf("对话失败: %v", err)
+	}
+	if received == nil || received.MaxTokens == nil || *received.MaxTokens != 8192 {
+		t.Errorf("期望使用模型默认的最大 token 数, 实际 %+v", received)
+	}
+
+	maxTokens := 10000
+	_, err := service.Chat(context.Background(), &model.ChatRequest{
+		Message: "你好",
+		Options: &model.ChatOptions{MaxTokens: &maxTokens},
+	})
+	appErr, ok := err.(*apperrors.AppError)
+	if !ok || appErr.Code != apperrors.CodeValidationError {
+		t.Errorf("期望参数验证错误, 实际 %v", err)
+	}
+}
+
+// TestChat_StructuredOutput 测试按 JSON Schema 校验回复，不符合时要求模型修正
+func TestChat_StructuredOutput(t *testing.T) {
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"city": map[string]interface{}{"type": "string"}},
+		"required":   []interface{}{"city"},
+	}
+	replies := []string{`{"name":"杭州"}`, "```json\n{\"city\":\"杭州\"}\n```"}
+
+	var prompts []string
+	var histories [][]genkit.Message
+	client := &mockGenkitClient{
+		generateFunc: func(ctx context.Context, prompt string, options *genkit.GenerateOptions) (*genkit.GenerateResult, error) {
+			if options.JSONSchema == nil {
+				t.Error("原生支持结构化输出时应传递 JSON Schema")
+			}
+			prompts = append(prompts, prompt)
+			histories = append(histories, options.History)
+			text := replies[len(prompts)-1]
+			return &genkit.GenerateResult{Text: text, Model: "test-model", Usage: &genkit.Usage{TotalTokens: 10}}, nil
+		},
+	}
+	contextManager := NewContextManager(30*time.Minute, 5*time.Minute)
+	service := NewGenkitService(client, contextManager, nil, logger.NewTestLogger())
+
+	resp, err := service.Chat(context.Background(), &model.ChatRequest{
+		Message: "提取城市",
+		Options: &model.ChatOptions{JSONSchema: schema, NativeJSONSchema: true},
+	})
+	if err != nil {
+		t.Fatalf("对话失败: %v", err)
+	}
+
+	if len(prompts) != 2 {
+		t.Fatalf("期望修正一次, 实际生成 %d 次", len(prompts))
+	}
+	if !strings.Contains(prompts[1], "缺少必需的属性 'city'") {
+		t.Errorf("修正提示词应包含校验错误, 得到 %s", prompts[1])
+	}
+	if h := histories[1]; len(h) != 2 || h[0].Content != "提取城市" || h[1].Content != replies[0] {
+		t.Errorf("修正时历史应包含原提问和不符合要求的回复, 得到 %+v", h)
+	}
+	if parsed, ok := resp.Parsed.(map[string]interface{}); !ok || parsed["city"] != "杭州" {
+		t.Errorf("Parsed = %#v", resp.Parsed)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 20 {
+		t.Errorf("token 使用情况应为各次生成之和, 得到 %+v", resp.Usage)
+	}
+}
+
+// TestChat_StructuredOutputFailed 测试多次修正后仍不符合 JSON Schema
+func TestChat_StructuredOutputFailed(t *testing.T) {
+	calls := 0
+	var system string
+	client := &mockGenkitClient{
+		generateFunc: func(ctx context.Context, prompt string, options *genkit.GenerateOptions) (*genkit.GenerateResult, error) {
+			calls++
+			system = options.System
+			if options.JSONSchema != nil {
+				t.Error("不支持结构化输出的模型不应传递 JSON Schema")
+			}
+			return &genkit.GenerateResult{Text: "杭州", Model: "test-model"}, nil
+		},
+	}
+	contextManager := NewContextManager(30*time.Minute, 5*time.Minute)
+	service := NewGenkitService(client, contextManager, nil, logger.NewTestLogger())
+
+	_, err := service.Chat(context.Background(), &model.ChatRequest{
+		Message: "提取城市",
+		Options: &model.ChatOptions{JSONSchema: map[string]interface{}{"type": "object"}},
+	})
+	appErr, ok := err.(*apperrors.AppError)
+	if !ok || appErr.Code != apperrors.CodeStructuredOutput {
+		t.Fatalf("期望结构化输出错误, 实际 %v", err)
+	}
+	if calls != maxSchemaRetries+1 {
+		t.Errorf("期望生成 %d 次, 实际 %d 次", maxSchemaRetries+1, calls)
+	}
+	if !strings.Contains(system, `{"type":"object"}`) {
+		t.Errorf("系统提示词应包含 JSON Schema, 得到 %s", system)
+	}
+
+	_, err = service.Chat(context.Background(), &model.ChatRequest{
+		Message: "提取城市",
+		Options: &model.ChatOptions{SchemaName: "city"},
+	})
+	if appErr, ok := err.(*apperrors.AppError); !ok || appErr.Code != apperrors.CodeBadRequest {
+		t.Errorf("未解析的 Schema 名称应返回请求错误, 实际 %v", err)
+	}
+}
+
+// TestChat_Parts 测试多模态消息片段：文本片段并入提示词，媒体片段随提示词发送
+func TestChat_Parts(t *testing.T) {
+	var receivedPrompt string
+	var received *genkit.GenerateOptions
+	client := &mockGenkitClient{
+		generateFunc: func(ctx context.Context, prompt string, options *genkit.GenerateOptions) (*genkit.GenerateResult, error) {
+			receivedPrompt = prompt
+			received = options
+			return &genkit.GenerateResult{Text: "一只猫", Model: "test-model"}, nil
+		},
+	}
+	contextManager := NewContextManager(30*time.Minute, 5*time.Minute)
+	service := NewGenkitService(client, contextManager, nil, logger.NewTestLogger())
+
+	_, err := service.Chat(context.Background(), &model.ChatRequest{
+		Message: "图里是什么",
+		Parts: []model.MessagePart{
+			{Type: model.PartTypeMedia, MimeType: "image/png", Data: "aGVsbG8="},
+			{Type: model.PartTypeText, Text: "用一句话回答"},
+		},
+		History: []model.ChatTurn{{Role: "user", Content: "看这个", Parts: []model.MessagePart{
+			{Type: model.PartTypeMedia, MimeType: "application/pdf", URL: "https://example.com/a.pdf"},
+		}}},
+	})
+	if err != nil {
+		t.Fatalf("对话失败: %v", err)
+	}
+	if receivedPrompt != "图里是什么\n\n用一句话回答" {
+		t.Errorf("文本片段应并入提示词, 得到 %q", receivedPrompt)
+	}
+	if len(received.Media) != 1 || received.Media[0].MimeType != "image/png" || received.Media[0].Data != "aGVsbG8=" {
+		t.Errorf("媒体片段不正确: %+v", received.Media)
+	}
+	if len(received.History) != 1 || len(received.History[0].Media) != 1 || received.History[0].Media[0].URL != "https://example.com/a.pdf" {
+		t.Errorf("历史消息的媒体不正确: %+v", received.History)
+	}
+
+	_, err = service.Chat(context.Background(), &model.ChatRequest{
+		Parts: []model.MessagePart{{Type: model.PartTypeMedia, MimeType: "image/png", Data: "不是base64"}},
+	})
+	if appErr, ok := err.(*apperrors.AppError); !ok || appErr.Code != apperrors.CodeBadRequest {
+		t.Errorf("期望无效的片段返回请求错误, 实际 %v", err)
+	}
+
+	// 对话接口不能解析上传文件的引用
+	_, err = service.Chat(context.Background(), &model.ChatRequest{
+		Parts: []model.MessagePart{{Type: model.PartTypeMedia, FileID: "550e8400-e29b-41d4-a716-446655440000"}},
+	})
+	if appErr, ok := err.(*apperrors.AppError); !ok || appErr.Code != apperrors.CodeBadRequest {
+		t.Errorf("期望引用文件的片段返回请求错误, 实际 %v", err)
+	}
+}
+
 // TestChat_WithExistingSession 测试使用现有会话
 func TestChat_WithExistingSession(t *testing.T) {
 	client := &mockGenkitClient{}
 	contextManager := NewContextManager(30*time.Minute, 5*time.Minute)
 	log := logger.New(logger.InfoLevel, logger.JSONFormat, &testWriter{t: t})
 
-	service := NewGenkitService(client, contextManager, log)
+	service := NewGenkitService(client, contextManager, nil, log)
 
 	// 第一次对话，创建会话
 	req1 := &model.ChatRequest{
@@ -184,6 +450,50 @@ func TestChat_WithExistingSession(t *testing.T) {
 	}
 }
 
+// TestChat_DuplicateMessageID 测试消息ID正在生成时拒绝重复请求，且不影响原请求
+func TestChat_DuplicateMessageID(t *testing.T) {
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	client := &mockGenkitClient{
+		generateFunc: func(ctx context.Context, prompt string, options *genkit.GenerateOptions) (*genkit.GenerateResult, error) {
+			close(started)
+			select {
+			case <-finish:
+				return &genkit.GenerateResult{Text: "测试响应", Model: "test-model"}, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		},
+	}
+	contextManager := NewContextManager(30*time.Minute, 5*time.Minute)
+	log := logger.New(logger.InfoLevel, logger.JSONFormat, &testWriter{t: t})
+
+	service := NewGenkitService(client, contextManager, nil, log)
+
+	messageID := "550e8400-e29b-41d4-a716-446655440000"
+	done := make(chan error, 1)
+	go func() {
+		_, err := service.Chat(context.Background(), &model.ChatRequest{Message: "你好", MessageID: messageID})
+		done <- err
+	}()
+	<-started
+
+	_, err := service.Chat(context.Background(), &model.ChatRequest{Message: "再见", MessageID: messageID})
+	if appErr, ok := err.(*apperrors.AppError); !ok || appErr.Code != apperrors.CodeBadRequest {
+		t.Errorf("期望重复的消息ID返回请求错误, 实际 %v", err)
+	}
+
+	// 被拒绝的请求不应释放原请求的会话
+	if _, exists := contextManager.GetSession(messageID); !exists {
+		t.Fatal("原请求的会话不应被释放")
+	}
+
+	close(finish)
+	if err := <-done; err != nil {
+		t.Errorf("原请求不应失败: %v", err)
+	}
+}
+
 // TestChat_ContextCancelled 测试上下文取消
 func TestChat_ContextCancelled(t *testing.T) {
 	client := &mockGenkitClient{
@@ -195,7 +505,7 @@ func TestChat_ContextCancelled(t *testing.T) {
 	contextManager := NewContextManager(30*time.Minute, 5*time.Minute)
 	log := logger.New(logger.InfoLevel, logger.JSONFormat, &testWriter{t: t})
 
-	service := NewGenkitService(client, contextManager, log)
+	service := NewGenkitService(client, contextManager, nil, log)
 
 	req := &model.ChatRequest{
 		Message: "你好",
@@ -217,7 +527,7 @@ func TestChat_GenerateError(t *testing.T) {
 	contextManager := NewContextManager(30*time.Minute, 5*time.Minute)
 	log := logger.New(logger.InfoLevel, logger.JSONFormat, &testWriter{t: t})
 
-	service := NewGenkitService(client, contextManager, log)
+	service := NewGenkitService(client, contextManager, nil, log)
 
 	req := &model.ChatRequest{
 		Message: "你好",
@@ -247,7 +557,7 @@ func TestAbortChat_Success(t *testing.T) {
 
 	log := logger.New(logger.InfoLevel, logger.JSONFormat, &testWriter{t: t})
 
-	service := NewGenkitService(client, contextManager, log)
+	service := NewGenkitService(client, contextManager, nil, log)
 
 	// 启动对话
 	req := &model.ChatRequest{
@@ -271,10 +581,59 @@ func TestAbortChat_Success(t *testing.T) {
 	testSessionID, _, _ := contextManager.CreateSession(context.Background())
 
 	// 中止对话
-	err := service.AbortChat(context.Background(), testSessionID)
+	_, err := service.AbortChat(context.Background(), testSessionID)
 	if err != nil {
 		t.Logf("中止对话返回错误（可能是会话已完成）: %v", err)
 	}
+
+	// 等待对话结束，避免测试结束后 goroutine 继续写日志
+	<-done
+}
+
+// TestAbortChat_BySessionID 测试按会话ID中止正在进行的对话
+func TestAbortChat_BySessionID(t *testing.T) {
+	started := make(chan struct{})
+	client := &mockGenkitClient{
+		generateFunc: func(ctx context.Context, prompt string, options *genkit.GenerateOptions) (*genkit.GenerateResult, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+	contextManager := NewContextManager(30*time.Minute, 5*time.Minute)
+	log := logger.New(logger.InfoLevel, logger.JSONFormat, &testWriter{t: t})
+
+	service := NewGenkitService(client, contextManager, nil, log)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := service.Chat(context.Background(), &model.ChatRequest{
+			Message:   "你好",
+			MessageID: "550e8400-e29b-41d4-a716-446655440000",
+			SessionID: "session-1",
+		})
+		done <- err
+	}()
+	<-started
+
+	aborted, err := service.AbortChat(context.Background(), "session-1")
+	if err != nil {
+		t.Fatalf("中止对话失败: %v", err)
+	}
+	if !aborted {
+		t.Error("期望报告已中止正在进行的对话")
+	}
+
+	err = <-done
+	appErr, ok := err.(*apperrors.AppError)
+	if !ok || appErr.Code != apperrors.CodeContextCancelled {
+		t.Errorf("期望上下文取消错误, 得到 %v", err)
+	}
+
+	// 对话结束后再次中止不应报告已中止
+	if aborted, _ := service.AbortChat(context.Background(), "550e8400-e29b-41d4-a716-446655440000"); aborted {
+		t.Error("对话结束后不应报告已中止")
+	}
 }
 
 // TestAbortChat_MessageNotFound 测试中止不存在的消息
@@ -283,29 +642,96 @@ func TestAbortChat_MessageNotFound(t *testing.T) {
 	contextManager := NewContextManager(30*time.Minute, 5*time.Minute)
 	log := logger.New(logger.InfoLevel, logger.JSONFormat, &testWriter{t: t})
 
-	service := NewGenkitService(client, contextManager, log)
+	service := NewGenkitService(client, contextManager, nil, log)
 
 	// 中止不存在的消息应该返回 nil（幂等操作）
-	err := service.AbortChat(context.Background(), "non-existent-message")
+	aborted, err := service.AbortChat(context.Background(), "non-existent-message")
 	if err != nil {
 		t.Fatalf("期望返回 nil，实际返回错误: %v", err)
 	}
+	if aborted {
+		t.Error("不存在的消息不应报告已中止")
+	}
 }
 
-// TestChatStream_NotImplemented 测试流式对话（未实现）
-func TestChatStream_NotImplemented(t *testing.T) {
+// TestChatStream_Success 测试成功的流式对话
+func TestChatStream_Success(t *testing.T) {
 	client := &mockGenkitClient{}
 	contextManager := NewContextManager(30*time.Minute, 5*time.Minute)
 	log := logger.New(logger.InfoLevel, logger.JSONFormat, &testWriter{t: t})
 
-	service := NewGenkitService(client, contextManager, log)
+	service := NewGenkitService(client, contextManager, nil, log)
 
 	req := &model.ChatRequest{
 		Message: "你好",
 	}
 
-	_, err := service.ChatStream(context.Background(), req)
-	if err == nil {
-		t.Fatal("期望返回未实现错误")
+	chunks, err := service.ChatStream(context.Background(), req)
+	if err != nil {
+		t.Fatalf("流式对话失败: %v", err)
+	}
+
+	var content string
+	var final model.StreamChunk
+	for chunk := range chunks {
+		if chunk.Done {
+			final = chunk
+			continue
+		}
+		content += chunk.Content
+	}
+
+	if content != "测试响应" {
+		t.Errorf("期望拼接内容为 '测试响应'，实际为 '%s'", content)
+	}
+	if !final.Done {
+		t.Fatal("期望收到完成块")
+	}
+	if final.Error != nil {
+		t.Errorf("期望完成块无错误，实际为 %v", final.Error)
+	}
+	if final.Model != "test-model" {
+		t.Errorf("期望模型为 'test-model'，实际为 '%s'", final.Model)
+	}
+	if final.Usage == nil || final.Usage.TotalTokens != 30 {
+		t.Errorf("期望完成块携带 token 使用情况，实际为 %+v", final.Usage)
+	}
+}
+
+// TestChatStream_GenerateError 测试流式对话生成失败
+func TestChatStream_GenerateError(t *testing.T) {
+	client := &mockGenkitClient{
+		generateStreamFunc: func(ctx context.Context, prompt string, options *genkit.GenerateOptions, callback genkit.StreamCallback) (*genkit.GenerateResult, error) {
+			if err := callback(ctx, "部分"); err != nil {
+				return nil, err
+			}
+			return nil, errors.New("生成失败")
+		},
+	}
+	contextManager := NewContextManager(30*time.Minute, 5*time.Minute)
+	log := logger.New(logger.InfoLevel, logger.JSONFormat, &testWriter{t: t})
+
+	service := NewGenkitService(client, contextManager, nil, log)
+
+	chunks, err := service.ChatStream(context.Background(), &model.ChatRequest{Message: "你好"})
+	if err != nil {
+		t.Fatalf("流式对话失败: %v", err)
+	}
+
+	var final model.StreamChunk
+	for chunk := range chunks {
+		final = chunk
+	}
+
+	if !final.Done || final.Error == nil {
+		t.Fatalf("期望最后一个块携带错误，实际为 %+v", final)
+	}
+
+	appErr, ok := final.Error.(*apperrors.AppError)
+	if !ok {
+		t.Fatalf("期望 AppError 类型，实际为 %T", final.Error)
+	}
+	if appErr.Code != apperrors.CodeAIServiceError {
+		t.Errorf("期望错误码为 %d，实际为 %d", apperrors.CodeAIServiceError, appErr.Code)
 	}
 }
@@ -0,0 +1,129 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"genkit-ai-service/internal/genkit"
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/pkg/errors"
+	"genkit-ai-service/pkg/tokenizer"
+)
+
+// RerankService 文档重排序服务接口
+type RerankService interface {
+	// Rerank 使用目录中的重排序模型计算每个文档与查询的相关性
+	// 结果按得分从高到低返回，最多返回 TopN 条
+	Rerank(ctx context.Context, req *model.RerankRequest) (*model.RerankResponse, error)
+}
+
+// rerankService 文档重排序服务实现
+type rerankService struct {
+	reranker genkit.Reranker
+	resolver genkit.ModelResolver
+	logger   logger.Logger
+}
+
+// NewRerankService 创建文档重排序服务
+// 参数:
+//
+//	reranker: 重排序客户端（通常为模型路由）
+//	resolver: 模型解析器，用于校验模型类型和上下文大小
+//	log: 日志记录器
+//
+// 返回:
+//
+//	RerankService: 文档重排序服务实例
+func NewRerankService(reranker genkit.Reranker, resolver genkit.ModelResolver, log logger.Logger) RerankService {
+	return &rerankService{
+		reranker: reranker,
+		resolver: resolver,
+		logger:   log,
+	}
+}
+
+// Rerank 使用目录中的重排序模型计算每个文档与查询的相关性
+func (s *rerankService) Rerank(ctx context.Context, req *model.RerankRequest) (*model.RerankResponse, error) {
+	startTime := time.Now()
+
+	// 1. 解析模型并校验模型类型
+	providerID, mdl, err := s.resolver.ResolveModel(req.Model)
+	if err != nil {
+		return nil, err
+	}
+	if mdl.ModelType != model.ModelTypeRerank {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("模型 '%s' 不是重排序模型", req.Model))
+	}
+
+	// 2. 查询与每个文档拼接后不能超过模型的上下文大小
+	queryTokens := tokenizer.Estimate(req.Query)
+	totalTokens := 0
+	for i, document := range req.Documents {
+		tokens := queryTokens + tokenizer.Estimate(document)
+		if contextSize := mdl.ModelProperties.ContextSize; contextSize > 0 && tokens > contextSize {
+			return nil, errors.NewBadRequestError(fmt.Sprintf(
+				"query 与 documents[%d] 约 %d 个 token，超过模型 '%s' 的上下文大小 %d", i, tokens, req.Model, contextSize))
+		}
+		totalTokens += tokens
+	}
+
+	topN := req.TopN
+	if topN <= 0 || topN > len(req.Documents) {
+		topN = len(req.Documents)
+	}
+
+	// 3. 调用重排序模型
+	result, err := s.reranker.Rerank(ctx, req.Query, req.Documents, &genkit.RerankOptions{
+		Model: providerID + "/" + mdl.Model,
+		TopN:  topN,
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "文档重排序失败", logger.Fields{
+			"model": req.Model,
+			"error": err.Error(),
+		})
+		if appErr, ok := err.(*errors.AppError); ok {
+			return nil, appErr
+		}
+		return nil, errors.NewAIServiceError(err)
+	}
+
+	// 4. 校验并按得分排序，后端返回多于 TopN 条时截断
+	results := make([]model.RerankResult, 0, len(result.Results))
+	for _, score := range result.Results {
+		if score.Index < 0 || score.Index >= len(req.Documents) {
+			return nil, errors.NewAIServiceError(fmt.Errorf("重排序结果中的文档序号 %d 无效", score.Index))
+		}
+		results = append(results, model.RerankResult{Index: score.Index, RelevanceScore: score.Score})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].RelevanceScore > results[j].RelevanceScore
+	})
+	if len(results) > topN {
+		results = results[:topN]
+	}
+
+	resp := &model.RerankResponse{
+		Model:   mdl.Model,
+		Results: results,
+	}
+	// 后端未返回用量时按估算的 token 数计算
+	if result.Usage != nil {
+		resp.Usage = &model.Usage{PromptTokens: result.Usage.PromptTokens, TotalTokens: result.Usage.TotalTokens}
+	} else {
+		resp.Usage = &model.Usage{PromptTokens: totalTokens, TotalTokens: totalTokens}
+	}
+
+	s.logger.InfoContext(ctx, "文档重排序完成", logger.Fields{
+		"model":     req.Model,
+		"documents": len(req.Documents),
+		"results":   len(results),
+		"tokens":    resp.Usage.TotalTokens,
+		"duration":  time.Since(startTime).Milliseconds(),
+	})
+
+	return resp, nil
+}
@@ -0,0 +1,145 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"genkit-ai-service/internal/genkit"
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	apperrors "genkit-ai-service/pkg/errors"
+)
+
+// fakeReranker 模拟重排序后端，文档越长得分越高，返回顺序与输入一致
+type fakeReranker struct {
+	calls   int
+	options *genkit.RerankOptions
+	results []genkit.RerankScore
+	err     error
+}
+
+func (f *fakeReranker) Rerank(ctx context.Context, query string, documents []string, options *genkit.RerankOptions) (*genkit.RerankResult, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.options = options
+
+	results := f.results
+	if results == nil {
+		for i, document := range documents {
+			results = append(results, genkit.RerankScore{Index: i, Score: float64(len(document))})
+		}
+	}
+	return &genkit.RerankResult{Results: results, Model: options.Model}, nil
+}
+
+func newTestRerankService(t *testing.T, reranker *fakeReranker) RerankService {
+	resolver := fakeModelResolver{
+		"tongyi/gte-rerank": {
+			Model:           "gte-rerank",
+			ModelType:       "rerank",
+			ModelProperties: model.ModelProperties{ContextSize: 8},
+		},
+		"tongyi/text-embedding-v3": {Model: "text-embedding-v3", ModelType: "text_embedding"},
+	}
+	log := logger.New(logger.InfoLevel, logger.JSONFormat, &testWriter{t: t})
+	return NewRerankService(reranker, resolver, log)
+}
+
+func TestRerank(t *testing.T) {
+	reranker := &fakeReranker{}
+	service := newTestRerankService(t, reranker)
+
+	resp, err := service.Rerank(context.Background(), &model.RerankRequest{
+		Model:     "tongyi/gte-rerank",
+		Query:     "q",
+		Documents: []string{"a", "ccc", "bb"},
+		TopN:      2,
+	})
+	if err != nil {
+		t.Fatalf("Rerank() error = %v", err)
+	}
+
+	if reranker.options.Model != "tongyi/gte-rerank" || reranker.options.TopN != 2 {
+		t.Errorf("重排序选项不正确: %+v", reranker.options)
+	}
+	// 后端未排序且返回了全部文档，服务按得分排序并截断到 TopN
+	if resp.Model != "gte-rerank" || len(resp.Results) != 2 || resp.Results[0].Index != 1 || resp.Results[1].Index != 2 {
+		t.Errorf("重排序结果不正确: %+v", resp.Results)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 6 {
+		t.Errorf("期望估算 6 个 token, 得到 %+v", resp.Usage)
+	}
+
+	t.Run("未指定 TopN 返回全部", func(t *testing.T) {
+		resp, err := service.Rerank(context.Background(), &model.RerankRequest{
+			Model:     "tongyi/gte-rerank",
+			Query:     "q",
+			Documents: []string{"a", "bb"},
+			TopN:      5,
+		})
+		if err != nil {
+			t.Fatalf("Rerank() error = %v", err)
+		}
+		if reranker.options.TopN != 2 || len(resp.Results) != 2 {
+			t.Errorf("TopN 应限制为文档数量, 得到选项 %+v, 结果 %d 条", reranker.options, len(resp.Results))
+		}
+	})
+}
+
+func TestRerank_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      *model.RerankRequest
+		reranker *fakeReranker
+		wantCode int
+	}{
+		{
+			name:     "模型不存在",
+			req:      &model.RerankRequest{Model: "unknown", Query: "q", Documents: []string{"a"}},
+			reranker: &fakeReranker{},
+			wantCode: apperrors.CodeModelNotFound,
+		},
+		{
+			name:     "不是重排序模型",
+			req:      &model.RerankRequest{Model: "tongyi/text-embedding-v3", Query: "q", Documents: []string{"a"}},
+			reranker: &fakeReranker{},
+			wantCode: apperrors.CodeBadRequest,
+		},
+		{
+			name:     "超过上下文大小",
+			req:      &model.RerankRequest{Model: "tongyi/gte-rerank", Query: "q", Documents: []string{"a", strings.Repeat("长", 8)}},
+			reranker: &fakeReranker{},
+			wantCode: apperrors.CodeBadRequest,
+		},
+		{
+			name:     "后端调用失败",
+			req:      &model.RerankRequest{Model: "tongyi/gte-rerank", Query: "q", Documents: []string{"a"}},
+			reranker: &fakeReranker{err: errors.New("quota exceeded")},
+			wantCode: apperrors.CodeAIServiceError,
+		},
+		{
+			name:     "文档序号无效",
+			req:      &model.RerankRequest{Model: "tongyi/gte-rerank", Query: "q", Documents: []string{"a"}},
+			reranker: &fakeReranker{results: []genkit.RerankScore{{Index: 3, Score: 1}}},
+			wantCode: apperrors.CodeAIServiceError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestRerankService(t, tt.reranker)
+
+			_, err := service.Rerank(context.Background(), tt.req)
+			if appErr, ok := err.(*apperrors.AppError); !ok || appErr.Code != tt.wantCode {
+				t.Errorf("期望错误码 %d, 得到 %v", tt.wantCode, err)
+			}
+			if tt.wantCode != apperrors.CodeAIServiceError && tt.reranker.calls != 0 {
+				t.Error("校验失败时不应调用后端")
+			}
+		})
+	}
+}
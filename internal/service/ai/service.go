@@ -19,8 +19,9 @@ type AIService interface {
 	//   error: 错误信息
 	Chat(ctx context.Context, req *model.ChatRequest) (*model.ChatResponse, error)
 
-	// ChatStream 流式对话（预留接口）
-	// 支持流式返回 AI 生成的内容
+	// ChatStream 流式对话
+	// 通道依次输出文本片段，最后一个块的 Done 为 true，携带模型、token 使用情况、工具调用或错误；
+	// 生成结束后通道关闭，调用方应读取直到通道关闭
 	// 参数:
 	//   ctx: 上下文，用于控制请求生命周期
 	//   req: 对话请求，包含用户消息和可选参数
@@ -33,8 +34,9 @@ type AIService interface {
 	// 取消正在进行的对话请求
 	// 参数:
 	//   ctx: 上下文
-	//   messageID: 要中止的消息ID
+	//   messageID: 要中止的消息ID或会话ID
 	// 返回:
-	//   error: 错误信息，如果消息不存在或已完成则返回错误
-	AbortChat(ctx context.Context, messageID string) error
+	//   bool: 是否取消了正在进行的生成，消息不存在或已完成时为 false
+	//   error: 错误信息
+	AbortChat(ctx context.Context, messageID string) (bool, error)
 }
@@ -0,0 +1,275 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"genkit-ai-service/internal/genkit"
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/service/session"
+	"genkit-ai-service/pkg/errors"
+)
+
+// defaultAudioType 模型目录未配置 audio_type 时的输出音频格式
+const defaultAudioType = "mp3"
+
+// sentenceTerminators 拆分长文本时的句子结束符
+const sentenceTerminators = "。！？；.!?;\n"
+
+// audioContentTypes 音频格式对应的 Content-Type
+var audioContentTypes = map[string]string{
+	"mp3":  "audio/mpeg",
+	"wav":  "audio/wav",
+	"pcm":  "audio/pcm",
+	"opus": "audio/ogg",
+	"aac":  "audio/aac",
+	"flac": "audio/flac",
+}
+
+// SpeechService 语音合成服务接口
+type SpeechService interface {
+	// Synthesize 将文本或会话消息的内容合成为语音
+	// 返回时第一段文本已开始合成，音频在读取时流式生成，调用方负责关闭 Speech.Audio
+	Synthesize(ctx context.Context, userID string, req *model.SpeechRequest) (*Speech, error)
+}
+
+// MessageReader 会话消息读取接口，用于合成消息的内容
+type MessageReader interface {
+	// GetMessageByID 获取用户有权访问的消息详情
+	GetMessageByID(ctx context.Context, messageID, userID string) (*session.MessageDetailResponse, error)
+}
+
+// Speech 语音合成结果
+type Speech struct {
+	// 使用的模型
+	Model string
+	// 使用的音色
+	Voice string
+	// 音频格式
+	Format string
+	// 音频的 Content-Type
+	ContentType string
+	// 音频流
+	Audio io.ReadCloser
+}
+
+// speechService 语音合成服务实现
+type speechService struct {
+	synthesizer genkit.SpeechSynthesizer
+	resolver    genkit.ModelResolver
+	messages    MessageReader
+	logger      logger.Logger
+}
+
+// NewSpeechService 创建语音合成服务
+// 参数:
+//
+//	synthesizer: 语音合成客户端（通常为模型路由）
+//	resolver: 模型解析器，用于读取模型的音色、音频格式和字数限制
+//	messages: 会话消息读取接口，为 nil 时不支持合成消息内容
+//	log: 日志记录器
+//
+// 返回:
+//
+//	SpeechService: 语音合成服务实例
+func NewSpeechService(synthesizer genkit.SpeechSynthesizer, resolver genkit.ModelResolver, messages MessageReader, log logger.Logger) SpeechService {
+	return &speechService{
+		synthesizer: synthesizer,
+		resolver:    resolver,
+		messages:    messages,
+		logger:      log,
+	}
+}
+
+// Synthesize 将文本或会话消息的内容合成为语音
+func (s *speechService) Synthesize(ctx context.Context, userID string, req *model.SpeechRequest) (*Speech, error) {
+	startTime := time.Now()
+
+	// 1. 解析模型并校验模型类型
+	providerID, mdl, err := s.resolver.ResolveModel(req.Model)
+	if err != nil {
+		return nil, err
+	}
+	if mdl.ModelType != model.ModelTypeTTS {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("模型 '%s' 不是语音合成模型", req.Model))
+	}
+	properties := mdl.ModelProperties
+
+	// 2. 确定音色，模型配置了音色列表时必须是其中之一
+	voice := req.Voice
+	if voice == "" {
+		voice = properties.DefaultVoice
+	}
+	if len(properties.Voices) > 0 && properties.FindVoice(voice) == nil {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("模型 '%s' 不支持音色 '%s'", req.Model, voice))
+	}
+
+	// 3. 获取要合成的文本
+	text, err := s.resolveText(ctx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. 超过模型字数限制时按句子拆分，依次合成
+	format := properties.AudioType
+	if format == "" {
+		format = defaultAudioType
+	}
+	options := &genkit.SpeechOptions{
+		Model:  providerID + "/" + mdl.Model,
+		Voice:  voice,
+		Format: format,
+	}
+	segments := splitText(text, properties.WordLimit)
+
+	// 第一段同步开始合成，使参数和后端错误在返回音频之前报告
+	first, err := s.synthesizer.Synthesize(ctx, segments[0], options)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "语音合成失败", logger.Fields{
+			"model": req.Model,
+			"voice": voice,
+			"error": err.Error(),
+		})
+		if appErr, ok := err.(*errors.AppError); ok {
+			return nil, appErr
+		}
+		return nil, errors.NewAIServiceError(err)
+	}
+
+	audio := first
+	if len(segments) > 1 {
+		audio = s.streamSegments(ctx, first, segments[1:], options)
+	}
+
+	contentType, ok := audioContentTypes[format]
+	if !ok {
+		contentType = "application/octet-stream"
+	}
+
+	s.logger.InfoContext(ctx, "语音合成开始", logger.Fields{
+		"model":    req.Model,
+		"voice":    voice,
+		"format":   format,
+		"chars":    utf8.RuneCountInString(text),
+		"segments": len(segments),
+		"duration": time.Since(startTime).Milliseconds(),
+	})
+
+	return &Speech{
+		Model:       mdl.Model,
+		Voice:       voice,
+		Format:      format,
+		ContentType: contentType,
+		Audio:       audio,
+	}, nil
+}
+
+// resolveText 获取要合成的文本：指定消息时使用消息内容，否则使用请求中的文本
+func (s *speechService) resolveText(ctx context.Context, userID string, req *model.SpeechRequest) (string, error) {
+	text := req.Input
+	if req.MessageID != "" {
+		if s.messages == nil {
+			return "", errors.NewServiceUnavailableError("会话服务不可用，不能合成消息内容")
+		}
+		message, err := s.messages.GetMessageByID(ctx, req.MessageID, userID)
+		if err != nil {
+			return "", err
+		}
+		text = message.Content
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", errors.NewBadRequestError("要合成的文本为空")
+	}
+	return text, nil
+}
+
+// streamSegments 依次合成剩余的文本段，将各段音频拼接为一个音频流
+// 读取方关闭音频流或上下文取消时停止合成
+func (s *speechService) streamSegments(ctx context.Context, first io.ReadCloser, rest []string, options *genkit.SpeechOptions) io.ReadCloser {
+	reader, writer := io.Pipe()
+
+	go func() {
+		current := first
+		for i := 0; ; i++ {
+			_, err := io.Copy(writer, current)
+			current.Close()
+			if err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+			if i == len(rest) {
+				writer.Close()
+				return
+			}
+
+			current, err = s.synthesizer.Synthesize(ctx, rest[i], options)
+			if err != nil {
+				s.logger.ErrorContext(ctx, "语音合成失败", logger.Fields{
+					"model":   options.Model,
+					"segment": i + 1,
+					"error":   err.Error(),
+				})
+				writer.CloseWithError(err)
+				return
+			}
+		}
+	}()
+
+	return reader
+}
+
+// splitText 将文本拆分为不超过 limit 个字符的段，尽量在句子结束处拆分
+// limit 不大于 0 时不拆分；单个句子超过 limit 时按字符数截断
+func splitText(text string, limit int) []string {
+	if limit <= 0 || utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+
+	var segments []string
+	var current []rune
+	flush := func(runes []rune) {
+		if segment := strings.TrimSpace(string(runes)); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	for _, sentence := range splitSentences(text) {
+		runes := []rune(sentence)
+		if len(current)+len(runes) > limit {
+			flush(current)
+			current = current[:0]
+		}
+		for len(runes) > limit {
+			flush(runes[:limit])
+			runes = runes[limit:]
+		}
+		current = append(current, runes...)
+	}
+	flush(current)
+
+	return segments
+}
+
+// splitSentences 在句子结束符之后拆分文本，结束符保留在句子末尾
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for i, r := range text {
+		if strings.ContainsRune(sentenceTerminators, r) {
+			end := i + utf8.RuneLen(r)
+			sentences = append(sentences, text[start:end])
+			start = end
+		}
+	}
+	if start < len(text) {
+		sentences = append(sentences, text[start:])
+	}
+	return sentences
+}
@@ -0,0 +1,204 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"genkit-ai-service/internal/genkit"
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/service/session"
+	"genkit-ai-service/pkg/errors"
+)
+
+// stubSynthesizer 本地语音合成后端，音频内容为 "[文本]"
+type stubSynthesizer struct {
+	texts   []string
+	options *genkit.SpeechOptions
+	// failAt 第几次调用时返回错误（从 1 开始），0 表示不失败
+	failAt int
+}
+
+func (s *stubSynthesizer) Synthesize(ctx context.Context, text string, options *genkit.SpeechOptions) (io.ReadCloser, error) {
+	s.texts = append(s.texts, text)
+	s.options = options
+	if s.failAt == len(s.texts) {
+		return nil, fmt.Errorf("quota exceeded")
+	}
+	return io.NopCloser(strings.NewReader("[" + text + "]")), nil
+}
+
+// stubResolver 测试用模型解析器
+type stubResolver map[string]model.Model
+
+func (r stubResolver) ResolveModel(modelName string) (string, *model.Model, error) {
+	m, ok := r[modelName]
+	if !ok {
+		return "", nil, errors.NewModelNotFoundError(modelName)
+	}
+	return "tongyi", &m, nil
+}
+
+// stubMessages 测试用会话消息读取
+type stubMessages map[string]*session.MessageDetailResponse
+
+func (m stubMessages) GetMessageByID(ctx context.Context, messageID, userID string) (*session.MessageDetailResponse, error) {
+	message, ok := m[messageID]
+	if !ok {
+		return nil, errors.NewMessageNotFoundError(messageID)
+	}
+	if userID != "user-1" {
+		return nil, errors.NewMessageAccessDeniedError()
+	}
+	return message, nil
+}
+
+func newTestSpeechService(synthesizer *stubSynthesizer, messages MessageReader) SpeechService {
+	resolver := stubResolver{
+		"tts-1": {
+			Model:     "tts-1",
+			ModelType: "tts",
+			ModelProperties: model.ModelProperties{
+				DefaultVoice: "sambert-zhiru-v1",
+				Voices: []model.Voice{
+					{Mode: "sambert-zhiru-v1", Name: "知茹", Language: []string{"zh-Hans", "en-US"}},
+					{Mode: "sambert-camila-v1", Name: "Camila", Language: []string{"es-ES"}},
+				},
+				WordLimit: 6,
+				AudioType: "wav",
+			},
+		},
+		"qwen-plus": {Model: "qwen-plus", ModelType: "llm"},
+	}
+	return NewSpeechService(synthesizer, resolver, messages, logger.Default())
+}
+
+func readSpeech(t *testing.T, speech *Speech) string {
+	t.Helper()
+	defer speech.Audio.Close()
+	data, err := io.ReadAll(speech.Audio)
+	if err != nil {
+		t.Fatalf("读取音频失败: %v", err)
+	}
+	return string(data)
+}
+
+func TestSynthesize(t *testing.T) {
+	synthesizer := &stubSynthesizer{}
+	service := newTestSpeechService(synthesizer, nil)
+
+	speech, err := service.Synthesize(context.Background(), "user-1", &model.SpeechRequest{Model: "tts-1", Input: "你好。"})
+	if err != nil {
+		t.Fatalf("Synthesize() error = %v", err)
+	}
+	if got := readSpeech(t, speech); got != "[你好。]" {
+		t.Errorf("音频 = %q", got)
+	}
+	// 未指定音色时使用默认音色，格式取模型的 audio_type
+	if speech.Voice != "sambert-zhiru-v1" || speech.Format != "wav" || speech.ContentType != "audio/wav" {
+		t.Errorf("合成结果不正确: %+v", speech)
+	}
+	if synthesizer.options.Model != "tongyi/tts-1" || synthesizer.options.Voice != "sambert-zhiru-v1" || synthesizer.options.Format != "wav" {
+		t.Errorf("合成选项不正确: %+v", synthesizer.options)
+	}
+}
+
+func TestSynthesize_SplitByWordLimit(t *testing.T) {
+	synthesizer := &stubSynthesizer{}
+	service := newTestSpeechService(synthesizer, nil)
+
+	speech, err := service.Synthesize(context.Background(), "user-1", &model.SpeechRequest{
+		Model: "tts-1",
+		Input: "你好。天气很好！一二三四五六七八",
+		Voice: "sambert-camila-v1",
+	})
+	if err != nil {
+		t.Fatalf("Synthesize() error = %v", err)
+	}
+
+	// 字数限制为 6，按句子拆分，超长句子按字数截断
+	want := "[你好。][天气很好！][一二三四五六][七八]"
+	if got := readSpeech(t, speech); got != want {
+		t.Errorf("音频 = %q, want %q", got, want)
+	}
+	if !reflect.DeepEqual(synthesizer.texts, []string{"你好。", "天气很好！", "一二三四五六", "七八"}) {
+		t.Errorf("拆分结果不正确: %q", synthesizer.texts)
+	}
+
+	t.Run("后续段落失败", func(t *testing.T) {
+		synthesizer := &stubSynthesizer{failAt: 2}
+		service := newTestSpeechService(synthesizer, nil)
+		speech, err := service.Synthesize(context.Background(), "user-1", &model.SpeechRequest{Model: "tts-1", Input: "你好。天气很好！"})
+		if err != nil {
+			t.Fatalf("Synthesize() error = %v", err)
+		}
+		defer speech.Audio.Close()
+		if _, err := io.ReadAll(speech.Audio); err == nil {
+			t.Error("期望读取音频时返回错误")
+		}
+	})
+}
+
+func TestSynthesize_Message(t *testing.T) {
+	messageID := "550e8400-e29b-41d4-a716-446655440000"
+	messages := stubMessages{messageID: {ID: messageID, Role: "assistant", Content: "  回复内容  "}}
+	synthesizer := &stubSynthesizer{}
+	service := newTestSpeechService(synthesizer, messages)
+
+	speech, err := service.Synthesize(context.Background(), "user-1", &model.SpeechRequest{Model: "tts-1", MessageID: messageID})
+	if err != nil {
+		t.Fatalf("Synthesize() error = %v", err)
+	}
+	if got := readSpeech(t, speech); got != "[回复内容]" {
+		t.Errorf("音频 = %q", got)
+	}
+
+	_, err = service.Synthesize(context.Background(), "user-2", &model.SpeechRequest{Model: "tts-1", MessageID: messageID})
+	assertErrorCode(t, err, errors.CodeMessageAccessDenied)
+}
+
+func TestSynthesize_Invalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         *model.SpeechRequest
+		synthesizer *stubSynthesizer
+		messages    MessageReader
+		wantCode    int
+	}{
+		{name: "模型不存在", req: &model.SpeechRequest{Model: "unknown", Input: "a"}, wantCode: errors.CodeModelNotFound},
+		{name: "不是语音合成模型", req: &model.SpeechRequest{Model: "qwen-plus", Input: "a"}, wantCode: errors.CodeBadRequest},
+		{name: "不支持的音色", req: &model.SpeechRequest{Model: "tts-1", Input: "a", Voice: "alloy"}, wantCode: errors.CodeBadRequest},
+		{name: "文本为空", req: &model.SpeechRequest{Model: "tts-1", Input: " \n"}, wantCode: errors.CodeBadRequest},
+		{name: "会话服务不可用", req: &model.SpeechRequest{Model: "tts-1", MessageID: "550e8400-e29b-41d4-a716-446655440000"}, wantCode: errors.CodeServiceUnavailable},
+		{name: "消息不存在", req: &model.SpeechRequest{Model: "tts-1", MessageID: "550e8400-e29b-41d4-a716-446655440001"}, messages: stubMessages{}, wantCode: errors.CodeMessageNotFound},
+		{name: "后端调用失败", req: &model.SpeechRequest{Model: "tts-1", Input: "a"}, synthesizer: &stubSynthesizer{failAt: 1}, wantCode: errors.CodeAIServiceError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			synthesizer := tt.synthesizer
+			if synthesizer == nil {
+				synthesizer = &stubSynthesizer{}
+			}
+			service := newTestSpeechService(synthesizer, tt.messages)
+
+			_, err := service.Synthesize(context.Background(), "user-1", tt.req)
+			assertErrorCode(t, err, tt.wantCode)
+			if tt.synthesizer == nil && len(synthesizer.texts) != 0 {
+				t.Error("校验失败时不应调用后端")
+			}
+		})
+	}
+}
+
+func assertErrorCode(t *testing.T, err error, code int) {
+	t.Helper()
+	appErr, ok := err.(*errors.AppError)
+	if !ok || appErr.Code != code {
+		t.Errorf("期望错误码 %d, 得到 %v", code, err)
+	}
+}
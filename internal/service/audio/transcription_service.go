@@ -0,0 +1,146 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"genkit-ai-service/internal/genkit"
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/service/session"
+	"genkit-ai-service/pkg/errors"
+)
+
+// TranscriptionService 语音识别服务接口
+type TranscriptionService interface {
+	// Transcribe 将上传的音频转写为文本，fileName 为上传的文件名，用于确定音频格式
+	// 请求指定会话时将识别出的文本作为用户消息追加到会话中
+	Transcribe(ctx context.Context, userID string, req *model.TranscriptionRequest, fileName string, audio io.Reader) (*model.TranscriptionResponse, error)
+}
+
+// MessageWriter 会话消息写入接口，用于将识别出的文本追加到会话
+type MessageWriter interface {
+	// AppendUserMessage 在会话当前分支末尾追加一条用户消息
+	AppendUserMessage(ctx context.Context, sessionID, userID, content string) (*session.MessageDetailResponse, error)
+}
+
+// transcriptionService 语音识别服务实现
+type transcriptionService struct {
+	transcriber genkit.Transcriber
+	resolver    genkit.ModelResolver
+	messages    MessageWriter
+	logger      logger.Logger
+}
+
+// NewTranscriptionService 创建语音识别服务
+// 参数:
+//
+//	transcriber: 语音识别客户端（通常为模型路由）
+//	resolver: 模型解析器，用于读取模型支持的文件格式和大小上限
+//	messages: 会话消息写入接口，为 nil 时不支持追加到会话
+//	log: 日志记录器
+//
+// 返回:
+//
+//	TranscriptionService: 语音识别服务实例
+func NewTranscriptionService(transcriber genkit.Transcriber, resolver genkit.ModelResolver, messages MessageWriter, log logger.Logger) TranscriptionService {
+	return &transcriptionService{
+		transcriber: transcriber,
+		resolver:    resolver,
+		messages:    messages,
+		logger:      log,
+	}
+}
+
+// Transcribe 将上传的音频转写为文本
+func (s *transcriptionService) Transcribe(ctx context.Context, userID string, req *model.TranscriptionRequest, fileName string, audio io.Reader) (*model.TranscriptionResponse, error) {
+	startTime := time.Now()
+
+	// 1. 解析模型并校验模型类型
+	providerID, mdl, err := s.resolver.ResolveModel(req.Model)
+	if err != nil {
+		return nil, err
+	}
+	if mdl.ModelType != model.ModelTypeSpeech2Text {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("模型 '%s' 不是语音识别模型", req.Model))
+	}
+	properties := mdl.ModelProperties
+
+	if req.SessionID != "" && s.messages == nil {
+		return nil, errors.NewServiceUnavailableError("会话服务不可用，不能将识别结果追加到会话")
+	}
+
+	// 2. 按扩展名校验音频格式
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(fileName), "."))
+	if format == "" {
+		return nil, errors.NewBadRequestError("无法从文件名确定音频格式")
+	}
+	if !properties.SupportsFileExtension(format) {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("模型 '%s' 不支持 %s 格式的音频，支持的格式: %s",
+			req.Model, format, properties.SupportedFileExtensions))
+	}
+
+	// 3. 读取音频并校验大小，模型未配置大小上限时不做限制
+	reader := audio
+	var maxSize int64
+	if properties.FileUploadLimit > 0 {
+		maxSize = int64(properties.FileUploadLimit) << 20
+		reader = io.LimitReader(audio, maxSize+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("读取音频失败: %v", err))
+	}
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		return nil, errors.NewFileTooLargeError(maxSize)
+	}
+	if len(data) == 0 {
+		return nil, errors.NewBadRequestError("音频内容为空")
+	}
+
+	// 4. 调用后端识别
+	result, err := s.transcriber.Transcribe(ctx, bytes.NewReader(data), &genkit.TranscriptionOptions{
+		Model:    providerID + "/" + mdl.Model,
+		Format:   format,
+		Language: req.Language,
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "语音识别失败", logger.Fields{
+			"model": req.Model,
+			"error": err.Error(),
+		})
+		if appErr, ok := err.(*errors.AppError); ok {
+			return nil, appErr
+		}
+		return nil, errors.NewAIServiceError(err)
+	}
+
+	resp := &model.TranscriptionResponse{
+		Model: mdl.Model,
+		Text:  strings.TrimSpace(result.Text),
+	}
+
+	// 5. 指定会话时将识别出的文本追加为用户消息，未识别出文本时不追加
+	if req.SessionID != "" && resp.Text != "" {
+		message, err := s.messages.AppendUserMessage(ctx, req.SessionID, userID, resp.Text)
+		if err != nil {
+			return nil, err
+		}
+		resp.MessageID = message.ID
+	}
+
+	s.logger.InfoContext(ctx, "语音识别完成", logger.Fields{
+		"model":     req.Model,
+		"format":    format,
+		"size":      len(data),
+		"sessionId": req.SessionID,
+		"duration":  time.Since(startTime).Milliseconds(),
+	})
+
+	return resp, nil
+}
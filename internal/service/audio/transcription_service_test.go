@@ -0,0 +1,145 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"genkit-ai-service/internal/genkit"
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/service/session"
+	"genkit-ai-service/pkg/errors"
+)
+
+// stubTranscriber 本地语音识别后端，识别结果为音频内容
+type stubTranscriber struct {
+	calls   int
+	options *genkit.TranscriptionOptions
+	err     error
+}
+
+func (s *stubTranscriber) Transcribe(ctx context.Context, audio io.Reader, options *genkit.TranscriptionOptions) (*genkit.TranscriptionResult, error) {
+	s.calls++
+	s.options = options
+	if s.err != nil {
+		return nil, s.err
+	}
+	data, err := io.ReadAll(audio)
+	if err != nil {
+		return nil, err
+	}
+	return &genkit.TranscriptionResult{Text: string(data), Model: options.Model}, nil
+}
+
+// stubMessageWriter 测试用会话消息写入
+type stubMessageWriter struct {
+	appended []string
+}
+
+func (m *stubMessageWriter) AppendUserMessage(ctx context.Context, sessionID, userID, content string) (*session.MessageDetailResponse, error) {
+	if userID != "user-1" {
+		return nil, errors.NewSessionAccessDeniedError()
+	}
+	m.appended = append(m.appended, content)
+	return &session.MessageDetailResponse{ID: fmt.Sprintf("msg-%d", len(m.appended)), SessionID: sessionID, Role: "user", Content: content}, nil
+}
+
+func newTestTranscriptionService(transcriber *stubTranscriber, messages MessageWriter) TranscriptionService {
+	resolver := stubResolver{
+		"paraformer-v2": {
+			Model:     "paraformer-realtime-v2",
+			ModelType: "speech2text",
+			ModelProperties: model.ModelProperties{
+				FileUploadLimit:         1,
+				SupportedFileExtensions: "mp3,wav",
+			},
+		},
+		"tts-1": {Model: "tts-1", ModelType: "tts"},
+	}
+	return NewTranscriptionService(transcriber, resolver, messages, logger.Default())
+}
+
+func TestTranscribe(t *testing.T) {
+	transcriber := &stubTranscriber{}
+	service := newTestTranscriptionService(transcriber, nil)
+
+	resp, err := service.Transcribe(context.Background(), "user-1", &model.TranscriptionRequest{Model: "paraformer-v2", Language: "zh"},
+		"voice.WAV", strings.NewReader(" 你好 "))
+	if err != nil {
+		t.Fatalf("Transcribe() error = %v", err)
+	}
+	if resp.Model != "paraformer-realtime-v2" || resp.Text != "你好" || resp.MessageID != "" {
+		t.Errorf("识别结果不正确: %+v", resp)
+	}
+	// 音频格式取自扩展名
+	if transcriber.options.Model != "tongyi/paraformer-realtime-v2" || transcriber.options.Format != "wav" || transcriber.options.Language != "zh" {
+		t.Errorf("识别选项不正确: %+v", transcriber.options)
+	}
+}
+
+func TestTranscribe_Session(t *testing.T) {
+	sessionID := "550e8400-e29b-41d4-a716-446655440000"
+	messages := &stubMessageWriter{}
+	service := newTestTranscriptionService(&stubTranscriber{}, messages)
+
+	resp, err := service.Transcribe(context.Background(), "user-1", &model.TranscriptionRequest{Model: "paraformer-v2", SessionID: sessionID},
+		"voice.mp3", strings.NewReader("你好"))
+	if err != nil {
+		t.Fatalf("Transcribe() error = %v", err)
+	}
+	if resp.MessageID != "msg-1" || len(messages.appended) != 1 || messages.appended[0] != "你好" {
+		t.Errorf("期望识别结果追加为用户消息, 得到 %+v %q", resp, messages.appended)
+	}
+
+	// 未识别出文本时不追加
+	if _, err := service.Transcribe(context.Background(), "user-1", &model.TranscriptionRequest{Model: "paraformer-v2", SessionID: sessionID},
+		"voice.mp3", strings.NewReader(" ")); err != nil {
+		t.Fatalf("Transcribe() error = %v", err)
+	}
+	if len(messages.appended) != 1 {
+		t.Errorf("空文本不应追加到会话, 得到 %q", messages.appended)
+	}
+
+	_, err = service.Transcribe(context.Background(), "user-2", &model.TranscriptionRequest{Model: "paraformer-v2", SessionID: sessionID},
+		"voice.mp3", strings.NewReader("你好"))
+	assertErrorCode(t, err, errors.CodeSessionAccessDenied)
+}
+
+func TestTranscribe_Invalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         *model.TranscriptionRequest
+		fileName    string
+		audio       string
+		transcriber *stubTranscriber
+		wantCode    int
+	}{
+		{name: "模型不存在", req: &model.TranscriptionRequest{Model: "unknown"}, fileName: "a.mp3", audio: "a", wantCode: errors.CodeModelNotFound},
+		{name: "不是语音识别模型", req: &model.TranscriptionRequest{Model: "tts-1"}, fileName: "a.mp3", audio: "a", wantCode: errors.CodeBadRequest},
+		{name: "会话服务不可用", req: &model.TranscriptionRequest{Model: "paraformer-v2", SessionID: "550e8400-e29b-41d4-a716-446655440000"}, fileName: "a.mp3", audio: "a", wantCode: errors.CodeServiceUnavailable},
+		{name: "缺少扩展名", req: &model.TranscriptionRequest{Model: "paraformer-v2"}, fileName: "audio", audio: "a", wantCode: errors.CodeBadRequest},
+		{name: "不支持的格式", req: &model.TranscriptionRequest{Model: "paraformer-v2"}, fileName: "a.flac", audio: "a", wantCode: errors.CodeBadRequest},
+		{name: "超过大小上限", req: &model.TranscriptionRequest{Model: "paraformer-v2"}, fileName: "a.mp3", audio: strings.Repeat("a", 1<<20+1), wantCode: errors.CodeFileTooLarge},
+		{name: "音频为空", req: &model.TranscriptionRequest{Model: "paraformer-v2"}, fileName: "a.mp3", audio: "", wantCode: errors.CodeBadRequest},
+		{name: "后端调用失败", req: &model.TranscriptionRequest{Model: "paraformer-v2"}, fileName: "a.mp3", audio: "a", transcriber: &stubTranscriber{err: fmt.Errorf("quota exceeded")}, wantCode: errors.CodeAIServiceError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transcriber := tt.transcriber
+			if transcriber == nil {
+				transcriber = &stubTranscriber{}
+			}
+			service := newTestTranscriptionService(transcriber, nil)
+
+			_, err := service.Transcribe(context.Background(), "user-1", tt.req, tt.fileName, strings.NewReader(tt.audio))
+			assertErrorCode(t, err, tt.wantCode)
+			if tt.transcriber == nil && transcriber.calls != 0 {
+				t.Error("校验失败时不应调用后端")
+			}
+		})
+	}
+}
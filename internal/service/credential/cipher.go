@@ -0,0 +1,106 @@
+package credential
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// cipherVersion 密文的版本前缀，更换加密方式时用于区分旧密文
+const cipherVersion = "v1:"
+
+// Cipher 凭证加解密接口
+type Cipher interface {
+	// Encrypt 加密明文，返回可保存的文本密文
+	Encrypt(plaintext []byte) (string, error)
+
+	// Decrypt 解密 Encrypt 返回的密文
+	Decrypt(ciphertext string) ([]byte, error)
+}
+
+// aesCipher 使用 AES-256-GCM 的加解密实现
+type aesCipher struct {
+	aead cipher.AEAD
+}
+
+// NewAESCipher 创建 AES-256-GCM 加解密实例，密钥为主密钥的 SHA-256
+// 密文格式为 "v1:" 加 base64 编码的随机 nonce 和密文
+func NewAESCipher(masterKey string) (Cipher, error) {
+	if masterKey == "" {
+		return nil, fmt.Errorf("主密钥不能为空")
+	}
+
+	key := sha256.Sum256([]byte(masterKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("创建加密器失败: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("创建加密器失败: %w", err)
+	}
+
+	return &aesCipher{aead: aead}, nil
+}
+
+// Encrypt 加密明文
+func (c *aesCipher) Encrypt(plaintext []byte) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("生成随机数失败: %w", err)
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, plaintext, nil)
+	return cipherVersion + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密密文，主密钥不匹配或密文被篡改时返回错误
+func (c *aesCipher) Decrypt(ciphertext string) ([]byte, error) {
+	encoded, ok := strings.CutPrefix(ciphertext, cipherVersion)
+	if !ok {
+		return nil, fmt.Errorf("不支持的密文格式")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("解码密文失败: %w", err)
+	}
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, fmt.Errorf("密文长度无效")
+	}
+
+	plaintext, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("解密失败: %w", err)
+	}
+	return plaintext, nil
+}
+
+// EncryptCredentials 序列化并加密凭证
+func EncryptCredentials(c Cipher, values map[string]string) (string, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("序列化凭证失败: %w", err)
+	}
+	return c.Encrypt(data)
+}
+
+// DecryptCredentials 解密并反序列化 EncryptCredentials 返回的密文
+func DecryptCredentials(c Cipher, ciphertext string) (map[string]string, error) {
+	data, err := c.Decrypt(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("解析凭证失败: %w", err)
+	}
+	return values, nil
+}
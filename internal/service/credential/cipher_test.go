@@ -0,0 +1,40 @@
+package credential
+
+import "testing"
+
+func TestAESCipher(t *testing.T) {
+	cipher, err := NewAESCipher("test-master-key-0123456789abcdefghij")
+	if err != nil {
+		t.Fatalf("NewAESCipher() error = %v", err)
+	}
+
+	first, err := cipher.Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	second, _ := cipher.Encrypt([]byte("secret"))
+	if first == second {
+		t.Error("相同明文的密文应不同")
+	}
+
+	plaintext, err := cipher.Decrypt(first)
+	if err != nil || string(plaintext) != "secret" {
+		t.Fatalf("Decrypt() = %q, %v", plaintext, err)
+	}
+
+	// 主密钥不同或密文被篡改时无法解密
+	other, _ := NewAESCipher("another-master-key-0123456789abcdef")
+	if _, err := other.Decrypt(first); err == nil {
+		t.Error("主密钥不同时应解密失败")
+	}
+	if _, err := cipher.Decrypt(first[:len(first)-2] + "AA"); err == nil {
+		t.Error("密文被篡改时应解密失败")
+	}
+	if _, err := cipher.Decrypt("plain"); err == nil {
+		t.Error("不支持的密文格式应解密失败")
+	}
+
+	if _, err := NewAESCipher(""); err == nil {
+		t.Error("主密钥为空时应返回错误")
+	}
+}
@@ -0,0 +1,326 @@
+package credential
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/repository"
+	"genkit-ai-service/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// CredentialService 模型提供商凭证业务逻辑接口
+// 凭证按提供商 YAML 中的表单配置校验后加密保存，返回的凭证均已脱敏
+type CredentialService interface {
+	// CreateCredential 创建凭证，未指定模型时为提供商级凭证，否则为模型级凭证
+	CreateCredential(ctx context.Context, owner model.CredentialOwner, req *model.CreateCredentialRequest) (*model.ProviderCredential, error)
+
+	// ListCredentials 获取所有者的凭证列表，providerID 不为空时只返回该提供商的凭证
+	ListCredentials(ctx context.Context, owner model.CredentialOwner, providerID string) ([]*model.ProviderCredential, error)
+
+	// GetCredential 获取所有者的凭证
+	GetCredential(ctx context.Context, credentialID string, owner model.CredentialOwner) (*model.ProviderCredential, error)
+
+	// UpdateCredential 整体替换凭证，secret-input 字段传入脱敏后的值时保留原值
+	UpdateCredential(ctx context.Context, credentialID string, owner model.CredentialOwner, req *model.UpdateCredentialRequest) (*model.ProviderCredential, error)
+
+	// DeleteCredential 删除所有者的凭证
+	DeleteCredential(ctx context.Context, credentialID string, owner model.CredentialOwner) error
+}
+
+// ProviderCatalog 提供商查询接口，用于获取凭证的表单配置
+type ProviderCatalog interface {
+	// GetProviderByID 根据ID获取提供商详情
+	GetProviderByID(providerID string) (*model.Provider, error)
+}
+
+// credentialService 模型提供商凭证业务逻辑实现
+type credentialService struct {
+	repo    repository.CredentialRepository
+	catalog ProviderCatalog
+	cipher  Cipher
+	logger  logger.Logger
+}
+
+// NewCredentialService 创建模型提供商凭证服务实例
+func NewCredentialService(
+	repo repository.CredentialRepository,
+	catalog ProviderCatalog,
+	cipher Cipher,
+	log logger.Logger,
+) CredentialService {
+	return &credentialService{
+		repo:    repo,
+		catalog: catalog,
+		cipher:  cipher,
+		logger:  log,
+	}
+}
+
+// logInfo 安全地记录信息日志
+func (s *credentialService) logInfo(ctx context.Context, msg string, fields logger.Fields) {
+	if s.logger != nil {
+		s.logger.InfoContext(ctx, msg, fields)
+	}
+}
+
+// logWarn 安全地记录警告日志
+func (s *credentialService) logWarn(ctx context.Context, msg string, fields logger.Fields) {
+	if s.logger != nil {
+		s.logger.WarnContext(ctx, msg, fields)
+	}
+}
+
+// CreateCredential 创建凭证
+func (s *credentialService) CreateCredential(ctx context.Context, owner model.CredentialOwner, req *model.CreateCredentialRequest) (*model.ProviderCredential, error) {
+	// 1. 模型类型统一为规范形式，按表单配置校验凭证
+	req.ModelType = canonicalModelType(req.ModelType)
+	fields, err := s.formSchemas(req.ProviderID, req.ModelType)
+	if err != nil {
+		return nil, err
+	}
+	values, err := ValidateCredentials(fields, req.ModelType, req.Credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 同一范围内只能有一份凭证
+	_, err = s.repo.FindByScope(ctx, owner, req.ProviderID, req.ModelType, req.Model)
+	if err == nil {
+		return nil, errors.NewCredentialAlreadyExistsError()
+	}
+	if err != repository.ErrNotFound {
+		return nil, errors.NewInternalError(err)
+	}
+
+	// 3. 加密保存
+	encrypted, err := s.encrypt(values)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	credential := &model.ProviderCredential{
+		OwnerType:            owner.Type,
+		OwnerID:              owner.ID,
+		ProviderID:           req.ProviderID,
+		ModelType:            req.ModelType,
+		Model:                req.Model,
+		EncryptedCredentials: encrypted,
+		CreatedAt:            now,
+		UpdatedAt:            now,
+	}
+	if err := s.repo.Create(ctx, credential); err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+
+	s.logInfo(ctx, "凭证已创建", logger.Fields{
+		"credentialId": credential.ID,
+		"ownerType":    owner.Type,
+		"ownerId":      owner.ID,
+		"providerId":   credential.ProviderID,
+		"modelType":    credential.ModelType,
+		"model":        credential.Model,
+	})
+
+	credential.Credentials = MaskCredentials(fields, values)
+	return credential, nil
+}
+
+// ListCredentials 获取所有者的凭证列表
+func (s *credentialService) ListCredentials(ctx context.Context, owner model.CredentialOwner, providerID string) ([]*model.ProviderCredential, error) {
+	credentials, err := s.repo.List(ctx, owner, providerID)
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+
+	for _, credential := range credentials {
+		if err := s.fillMasked(credential); err != nil {
+			return nil, err
+		}
+	}
+	return credentials, nil
+}
+
+// GetCredential 获取所有者的凭证
+func (s *credentialService) GetCredential(ctx context.Context, credentialID string, owner model.CredentialOwner) (*model.ProviderCredential, error) {
+	credential, err := s.getOwnedCredential(ctx, credentialID, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.fillMasked(credential); err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+// UpdateCredential 整体替换凭证
+func (s *credentialService) UpdateCredential(ctx context.Context, credentialID string, owner model.CredentialOwner, req *model.UpdateCredentialRequest) (*model.ProviderCredential, error) {
+	credential, err := s.getOwnedCredential(ctx, credentialID, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := s.formSchemas(credential.ProviderID, credential.ModelType)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.decrypt(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. 密钥字段未修改（仍为脱敏值）时保留原值
+	submitted := make(map[string]string, len(req.Credentials))
+	secrets := secretFields(fields)
+	for name, value := range req.Credentials {
+		if old, ok := current[name]; ok && secrets[name] && value == maskSecret(old) {
+			value = old
+		}
+		submitted[name] = value
+	}
+
+	// 2. 校验并加密保存
+	values, err := ValidateCredentials(fields, credential.ModelType, submitted)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := s.encrypt(values)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := s.repo.UpdateCredentials(ctx, credential.ID, encrypted, now); err != nil {
+		if err == repository.ErrNotFound {
+			return nil, errors.NewCredentialNotFoundError(credentialID)
+		}
+		return nil, errors.NewInternalError(err)
+	}
+
+	s.logInfo(ctx, "凭证已更新", logger.Fields{
+		"credentialId": credential.ID,
+		"ownerType":    owner.Type,
+		"ownerId":      owner.ID,
+	})
+
+	credential.EncryptedCredentials = encrypted
+	credential.UpdatedAt = now
+	credential.Credentials = MaskCredentials(fields, values)
+	return credential, nil
+}
+
+// DeleteCredential 删除所有者的凭证
+func (s *credentialService) DeleteCredential(ctx context.Context, credentialID string, owner model.CredentialOwner) error {
+	credential, err := s.getOwnedCredential(ctx, credentialID, owner)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, credential.ID); err != nil {
+		return errors.NewInternalError(err)
+	}
+
+	s.logInfo(ctx, "凭证已删除", logger.Fields{
+		"credentialId": credential.ID,
+		"ownerType":    owner.Type,
+		"ownerId":      owner.ID,
+	})
+
+	return nil
+}
+
+// getOwnedCredential 获取凭证并检查所有者，凭证ID不是有效的 UUID 时视为不存在
+func (s *credentialService) getOwnedCredential(ctx context.Context, credentialID string, owner model.CredentialOwner) (*model.ProviderCredential, error) {
+	if _, err := uuid.Parse(credentialID); err != nil {
+		return nil, errors.NewCredentialNotFoundError(credentialID)
+	}
+
+	credential, err := s.repo.GetByID(ctx, credentialID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, errors.NewCredentialNotFoundError(credentialID)
+		}
+		return nil, errors.NewInternalError(err)
+	}
+
+	if credential.OwnerType != owner.Type || credential.OwnerID != owner.ID {
+		s.logWarn(ctx, "无权访问凭证", logger.Fields{
+			"credentialId": credentialID,
+			"ownerType":    owner.Type,
+			"ownerId":      owner.ID,
+		})
+		return nil, errors.NewCredentialAccessDeniedError()
+	}
+
+	return credential, nil
+}
+
+// formSchemas 获取凭证范围对应的表单配置
+// 提供商级凭证使用提供商凭证配置，模型级凭证使用模型凭证配置且模型类型必须是提供商支持的类型
+func (s *credentialService) formSchemas(providerID, modelType string) ([]model.CredentialFormSchema, error) {
+	provider, err := s.catalog.GetProviderByID(providerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if modelType == "" {
+		if len(provider.ProviderCredentialSchema.CredentialFormSchemas) == 0 {
+			return nil, errors.NewBadRequestError(fmt.Sprintf("提供商 '%s' 不支持提供商级凭证", providerID))
+		}
+		return provider.ProviderCredentialSchema.CredentialFormSchemas, nil
+	}
+
+	if t, ok := model.ParseModelType(modelType); !ok || !provider.SupportsModelType(t) {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("提供商 '%s' 不支持模型类型 '%s'", providerID, modelType))
+	}
+	if len(provider.ModelCredentialSchema.CredentialFormSchemas) == 0 {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("提供商 '%s' 不支持模型级凭证", providerID))
+	}
+	return provider.ModelCredentialSchema.CredentialFormSchemas, nil
+}
+
+// fillMasked 解密凭证并填充脱敏后的值
+// 提供商已从目录中移除时无法确定密钥字段，所有值均脱敏
+func (s *credentialService) fillMasked(credential *model.ProviderCredential) error {
+	values, err := s.decrypt(credential)
+	if err != nil {
+		return err
+	}
+
+	fields, err := s.formSchemas(credential.ProviderID, credential.ModelType)
+	if err != nil {
+		masked := make(map[string]string, len(values))
+		for name, value := range values {
+			masked[name] = maskSecret(value)
+		}
+		credential.Credentials = masked
+		return nil
+	}
+
+	credential.Credentials = MaskCredentials(fields, values)
+	return nil
+}
+
+// encrypt 序列化并加密凭证
+func (s *credentialService) encrypt(values map[string]string) (string, error) {
+	encrypted, err := EncryptCredentials(s.cipher, values)
+	if err != nil {
+		return "", errors.NewInternalError(err)
+	}
+	return encrypted, nil
+}
+
+// decrypt 解密凭证，主密钥变更后无法解密时返回内部错误
+func (s *credentialService) decrypt(credential *model.ProviderCredential) (map[string]string, error) {
+	values, err := DecryptCredentials(s.cipher, credential.EncryptedCredentials)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("凭证 %s: %w", credential.ID, err))
+	}
+	return values, nil
+}
@@ -0,0 +1,262 @@
+package credential
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/repository"
+	"genkit-ai-service/pkg/errors"
+)
+
+// testProviders 测试用提供商目录
+type testProviders map[string]*model.Provider
+
+func (p testProviders) GetProviderByID(providerID string) (*model.Provider, error) {
+	provider, ok := p[providerID]
+	if !ok {
+		return nil, errors.NewProviderNotFoundError(providerID)
+	}
+	return provider, nil
+}
+
+var (
+	testUser      = model.CredentialOwner{Type: model.CredentialOwnerUser, ID: "user-1"}
+	testWorkspace = model.CredentialOwner{Type: model.CredentialOwnerWorkspace, ID: "ws-1"}
+)
+
+func newTestProviders() testProviders {
+	return testProviders{
+		"openai": {
+			ID:                  "openai",
+			SupportedModelTypes: []string{"llm", "text-embedding"},
+			ProviderCredentialSchema: model.CredentialSchema{
+				CredentialFormSchemas: []model.CredentialFormSchema{
+					{Variable: "api_key", Type: model.FormTypeSecretInput, Required: true},
+					{Variable: "base_url", Type: model.FormTypeTextInput, Default: "https://api.openai.com/v1"},
+				},
+			},
+			ModelCredentialSchema: model.CredentialSchema{
+				CredentialFormSchemas: []model.CredentialFormSchema{
+					{Variable: "api_key", Type: model.FormTypeSecretInput, Required: true},
+					{
+						Variable: "mode",
+						Type:     model.FormTypeSelect,
+						Required: true,
+						ShowOn:   []model.ShowOnCondition{{Variable: "__model_type", Value: "llm"}},
+						Options:  []model.FormOption{{Value: "chat"}, {Value: "completion"}},
+					},
+				},
+			},
+		},
+		"local": {ID: "local", SupportedModelTypes: []string{"llm"}},
+	}
+}
+
+func newTestCredentialService(t *testing.T) (CredentialService, repository.CredentialRepository) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(&model.ProviderCredential{}); err != nil {
+		t.Fatalf("迁移凭证表失败: %v", err)
+	}
+
+	cipher, err := NewAESCipher("test-master-key-0123456789abcdefghij")
+	if err != nil {
+		t.Fatalf("NewAESCipher() error = %v", err)
+	}
+
+	repo := repository.NewCredentialRepository(db)
+	return NewCredentialService(repo, newTestProviders(), cipher, nil), repo
+}
+
+func assertErrorCode(t *testing.T, err error, code int) {
+	t.Helper()
+	appErr, ok := err.(*errors.AppError)
+	if !ok || appErr.Code != code {
+		t.Errorf("期望错误码 %d, 得到 %v", code, err)
+	}
+}
+
+func TestCreateCredential(t *testing.T) {
+	service, repo := newTestCredentialService(t)
+	ctx := context.Background()
+
+	credential, err := service.CreateCredential(ctx, testUser, &model.CreateCredentialRequest{
+		ProviderID:  "openai",
+		Credentials: map[string]string{"api_key": "sk-abcdefghijklmnop"},
+	})
+	if err != nil {
+		t.Fatalf("CreateCredential() error = %v", err)
+	}
+
+	// 返回脱敏后的凭证并补全默认值
+	if credential.Credentials["api_key"] != "sk-a***********mnop" {
+		t.Errorf("密钥应脱敏, 得到 %q", credential.Credentials["api_key"])
+	}
+	if credential.Credentials["base_url"] != "https://api.openai.com/v1" {
+		t.Errorf("应补全默认值, 得到 %q", credential.Credentials["base_url"])
+	}
+
+	// 数据库中不保存明文
+	stored, err := repo.GetByID(ctx, credential.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if strings.Contains(stored.EncryptedCredentials, "sk-abcdefghijklmnop") {
+		t.Error("数据库中不应保存明文密钥")
+	}
+
+	// 同一范围内只能有一份凭证，其他所有者不受影响
+	_, err = service.CreateCredential(ctx, testUser, &model.CreateCredentialRequest{
+		ProviderID:  "openai",
+		Credentials: map[string]string{"api_key": "sk-other"},
+	})
+	assertErrorCode(t, err, errors.CodeCredentialAlreadyExists)
+
+	if _, err := service.CreateCredential(ctx, testWorkspace, &model.CreateCredentialRequest{
+		ProviderID:  "openai",
+		Credentials: map[string]string{"api_key": "sk-other"},
+	}); err != nil {
+		t.Fatalf("工作空间凭证创建失败: %v", err)
+	}
+
+	// 模型级凭证按模型区分范围
+	modelCredential, err := service.CreateCredential(ctx, testUser, &model.CreateCredentialRequest{
+		ProviderID:  "openai",
+		ModelType:   "llm",
+		Model:       "gpt-4o-deployment",
+		Credentials: map[string]string{"api_key": "sk-model-key-123456", "mode": "chat"},
+	})
+	if err != nil {
+		t.Fatalf("模型级凭证创建失败: %v", err)
+	}
+	if modelCredential.Credentials["mode"] != "chat" {
+		t.Errorf("非密钥字段不应脱敏, 得到 %q", modelCredential.Credentials["mode"])
+	}
+
+	credentials, err := service.ListCredentials(ctx, testUser, "openai")
+	if err != nil {
+		t.Fatalf("ListCredentials() error = %v", err)
+	}
+	if len(credentials) != 2 {
+		t.Fatalf("期望 2 份凭证, 得到 %d", len(credentials))
+	}
+	for _, c := range credentials {
+		if strings.HasPrefix(c.Credentials["api_key"], "sk-") && !strings.Contains(c.Credentials["api_key"], "*") {
+			t.Errorf("列表中的密钥应脱敏, 得到 %q", c.Credentials["api_key"])
+		}
+	}
+}
+
+func TestCreateCredential_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      *model.CreateCredentialRequest
+		wantCode int
+	}{
+		{name: "提供商不存在", req: &model.CreateCredentialRequest{ProviderID: "unknown", Credentials: map[string]string{"api_key": "k"}}, wantCode: errors.CodeProviderNotFound},
+		{name: "不支持提供商级凭证", req: &model.CreateCredentialRequest{ProviderID: "local", Credentials: map[string]string{"api_key": "k"}}, wantCode: errors.CodeBadRequest},
+		{name: "不支持的模型类型", req: &model.CreateCredentialRequest{ProviderID: "openai", ModelType: "tts", Model: "tts-1", Credentials: map[string]string{"api_key": "k"}}, wantCode: errors.CodeBadRequest},
+		{name: "缺少必填字段", req: &model.CreateCredentialRequest{ProviderID: "openai", Credentials: map[string]string{"base_url": "https://example.com"}}, wantCode: errors.CodeValidationError},
+		{name: "未知字段", req: &model.CreateCredentialRequest{ProviderID: "openai", Credentials: map[string]string{"api_key": "k", "region": "us"}}, wantCode: errors.CodeValidationError},
+		{name: "选项不存在", req: &model.CreateCredentialRequest{ProviderID: "openai", ModelType: "llm", Model: "m", Credentials: map[string]string{"api_key": "k", "mode": "edit"}}, wantCode: errors.CodeValidationError},
+		{name: "条件字段缺失", req: &model.CreateCredentialRequest{ProviderID: "openai", ModelType: "llm", Model: "m", Credentials: map[string]string{"api_key": "k"}}, wantCode: errors.CodeValidationError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestCredentialService(t)
+			_, err := service.CreateCredential(context.Background(), testUser, tt.req)
+			assertErrorCode(t, err, tt.wantCode)
+		})
+	}
+}
+
+func TestUpdateCredential(t *testing.T) {
+	service, repo := newTestCredentialService(t)
+	ctx := context.Background()
+
+	created, err := service.CreateCredential(ctx, testUser, &model.CreateCredentialRequest{
+		ProviderID:  "openai",
+		Credentials: map[string]string{"api_key": "sk-abcdefghijklmnop", "base_url": "https://proxy.example.com"},
+	})
+	if err != nil {
+		t.Fatalf("CreateCredential() error = %v", err)
+	}
+	before, _ := repo.GetByID(ctx, created.ID)
+
+	// 传入脱敏后的密钥时保留原值，其他字段整体替换
+	updated, err := service.UpdateCredential(ctx, created.ID, testUser, &model.UpdateCredentialRequest{
+		Credentials: map[string]string{"api_key": created.Credentials["api_key"]},
+	})
+	if err != nil {
+		t.Fatalf("UpdateCredential() error = %v", err)
+	}
+	if updated.Credentials["api_key"] != created.Credentials["api_key"] {
+		t.Errorf("密钥应保留原值, 得到 %q", updated.Credentials["api_key"])
+	}
+	if updated.Credentials["base_url"] != "https://api.openai.com/v1" {
+		t.Errorf("未传入的字段应恢复默认值, 得到 %q", updated.Credentials["base_url"])
+	}
+	after, _ := repo.GetByID(ctx, created.ID)
+	if after.EncryptedCredentials == before.EncryptedCredentials {
+		t.Error("更新后应重新加密")
+	}
+
+	// 传入新的密钥时替换
+	updated, err = service.UpdateCredential(ctx, created.ID, testUser, &model.UpdateCredentialRequest{
+		Credentials: map[string]string{"api_key": "sk-new-key-9876543210"},
+	})
+	if err != nil {
+		t.Fatalf("UpdateCredential() error = %v", err)
+	}
+	if updated.Credentials["api_key"] != "sk-n*************3210" {
+		t.Errorf("密钥应替换为新值, 得到 %q", updated.Credentials["api_key"])
+	}
+
+	// 其他所有者无权更新
+	_, err = service.UpdateCredential(ctx, created.ID, testWorkspace, &model.UpdateCredentialRequest{
+		Credentials: map[string]string{"api_key": "sk-x"},
+	})
+	assertErrorCode(t, err, errors.CodeCredentialAccessDenied)
+}
+
+func TestGetAndDeleteCredential(t *testing.T) {
+	service, _ := newTestCredentialService(t)
+	ctx := context.Background()
+
+	created, err := service.CreateCredential(ctx, testWorkspace, &model.CreateCredentialRequest{
+		ProviderID:  "openai",
+		Credentials: map[string]string{"api_key": "sk-abcdefghijklmnop"},
+	})
+	if err != nil {
+		t.Fatalf("CreateCredential() error = %v", err)
+	}
+
+	got, err := service.GetCredential(ctx, created.ID, testWorkspace)
+	if err != nil {
+		t.Fatalf("GetCredential() error = %v", err)
+	}
+	if got.Credentials["api_key"] != created.Credentials["api_key"] {
+		t.Errorf("期望脱敏密钥 %q, 得到 %q", created.Credentials["api_key"], got.Credentials["api_key"])
+	}
+
+	_, err = service.GetCredential(ctx, created.ID, testUser)
+	assertErrorCode(t, err, errors.CodeCredentialAccessDenied)
+	_, err = service.GetCredential(ctx, "not-a-uuid", testWorkspace)
+	assertErrorCode(t, err, errors.CodeCredentialNotFound)
+
+	assertErrorCode(t, service.DeleteCredential(ctx, created.ID, testUser), errors.CodeCredentialAccessDenied)
+	if err := service.DeleteCredential(ctx, created.ID, testWorkspace); err != nil {
+		t.Fatalf("DeleteCredential() error = %v", err)
+	}
+	_, err = service.GetCredential(ctx, created.ID, testWorkspace)
+	assertErrorCode(t, err, errors.CodeCredentialNotFound)
+}
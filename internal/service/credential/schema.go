@@ -0,0 +1,142 @@
+package credential
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/pkg/errors"
+)
+
+// modelTypeVariable 显示条件中表示凭证所属模型类型的变量名
+const modelTypeVariable = "__model_type"
+
+// maskVisibleRunes 脱敏时首尾各保留的字符数
+const maskVisibleRunes = 4
+
+// ValidateCredentials 按表单配置校验凭证，返回补全默认值后的凭证
+// 不满足显示条件的字段被忽略；必填字段不能为空；select 和 radio 字段的值必须是可见选项之一；
+// 表单配置中不存在的字段视为错误
+func ValidateCredentials(fields []model.CredentialFormSchema, modelType string, values map[string]string) (map[string]string, error) {
+	known := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		known[field.Variable] = true
+	}
+	var unknown []string
+	for name := range values {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, errors.NewValidationError(fmt.Sprintf("未知的凭证字段: %s", strings.Join(unknown, ", ")))
+	}
+
+	result := make(map[string]string, len(fields))
+	for _, field := range fields {
+		if !showOn(field.ShowOn, modelType, values) {
+			continue
+		}
+
+		value := strings.TrimSpace(values[field.Variable])
+		if value == "" {
+			value = field.Default
+		}
+		if value == "" {
+			if field.Required {
+				return nil, errors.NewValidationError(fmt.Sprintf("凭证字段 '%s' 不能为空", field.Variable))
+			}
+			continue
+		}
+
+		if field.Type == model.FormTypeSelect || field.Type == model.FormTypeRadio {
+			if !hasOption(field.Options, modelType, values, value) {
+				return nil, errors.NewValidationError(fmt.Sprintf("凭证字段 '%s' 的值 '%s' 不在可选范围内", field.Variable, value))
+			}
+		}
+
+		result[field.Variable] = value
+	}
+
+	return result, nil
+}
+
+// showOn 判断显示条件是否全部满足，没有条件时总是显示
+func showOn(conditions []model.ShowOnCondition, modelType string, values map[string]string) bool {
+	for _, condition := range conditions {
+		if condition.Variable == modelTypeVariable {
+			if !sameModelType(modelType, condition.Value) {
+				return false
+			}
+			continue
+		}
+		if values[condition.Variable] != condition.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// sameModelType 判断两个模型类型标识是否相同，不区分连字符和下划线形式
+func sameModelType(a, b string) bool {
+	typeA, okA := model.ParseModelType(a)
+	typeB, okB := model.ParseModelType(b)
+	if okA && okB {
+		return typeA == typeB
+	}
+	return a == b
+}
+
+// canonicalModelType 返回模型类型的规范形式，无法识别的模型类型原样返回
+func canonicalModelType(modelType string) string {
+	if t, ok := model.ParseModelType(modelType); ok {
+		return t.String()
+	}
+	return modelType
+}
+
+// hasOption 判断值是否为满足显示条件的选项之一
+func hasOption(options []model.FormOption, modelType string, values map[string]string, value string) bool {
+	for _, option := range options {
+		if option.Value == value && showOn(option.ShowOn, modelType, values) {
+			return true
+		}
+	}
+	return false
+}
+
+// secretFields 返回表单配置中 secret-input 类型的字段名
+func secretFields(fields []model.CredentialFormSchema) map[string]bool {
+	secrets := make(map[string]bool)
+	for _, field := range fields {
+		if field.Type == model.FormTypeSecretInput {
+			secrets[field.Variable] = true
+		}
+	}
+	return secrets
+}
+
+// MaskCredentials 返回脱敏后的凭证，secret-input 字段只保留首尾字符
+func MaskCredentials(fields []model.CredentialFormSchema, values map[string]string) map[string]string {
+	secrets := secretFields(fields)
+	masked := make(map[string]string, len(values))
+	for name, value := range values {
+		if secrets[name] {
+			value = maskSecret(value)
+		}
+		masked[name] = value
+	}
+	return masked
+}
+
+// maskSecret 脱敏密钥：保留首尾各 4 个字符，中间替换为星号；较短的密钥全部替换
+func maskSecret(secret string) string {
+	runes := []rune(secret)
+	if len(runes) <= maskVisibleRunes*2 {
+		return strings.Repeat("*", utf8.RuneCountInString(secret))
+	}
+	return string(runes[:maskVisibleRunes]) + strings.Repeat("*", len(runes)-maskVisibleRunes*2) + string(runes[len(runes)-maskVisibleRunes:])
+}
@@ -0,0 +1,22 @@
+package credential
+
+import "testing"
+
+func TestMaskSecret(t *testing.T) {
+	tests := []struct {
+		secret string
+		want   string
+	}{
+		{secret: "", want: ""},
+		{secret: "short", want: "*****"},
+		{secret: "12345678", want: "********"},
+		{secret: "sk-abcdefghijklmnop", want: "sk-a***********mnop"},
+		{secret: "密钥密钥一二三四五六", want: "密钥密钥**三四五六"},
+	}
+
+	for _, tt := range tests {
+		if got := maskSecret(tt.secret); got != tt.want {
+			t.Errorf("maskSecret(%q) = %q, 期望 %q", tt.secret, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package custommodel
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"genkit-ai-service/internal/genkit"
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/repository"
+	"genkit-ai-service/internal/service/credential"
+	"genkit-ai-service/pkg/errors"
+	"genkit-ai-service/pkg/validator"
+)
+
+// 自定义模型凭证中有特殊含义的变量
+const (
+	// baseModelVariable 基础模型（Azure OpenAI 的 base_model_name）
+	baseModelVariable = "base_model_name"
+	// contextSizeVariable 上下文大小（通义千问的 context_size）
+	contextSizeVariable = "context_size"
+)
+
+// CustomModelService 自定义模型业务逻辑接口
+// 自定义模型保存在数据库中并合并到模型目录，之后可以像目录中的模型一样按名称使用（如会话的 modelName）
+type CustomModelService interface {
+	// RegisterModel 在提供商下注册自定义模型，提供商的配置方法必须包含 customizable-model
+	RegisterModel(ctx context.Context, userID, providerID string, req *model.RegisterCustomModelRequest) (*model.CustomModel, error)
+
+	// ListModels 获取提供商的自定义模型列表，凭证已脱敏
+	ListModels(ctx context.Context, providerID string) ([]*model.CustomModel, error)
+
+	// DeleteModel 删除自定义模型并从模型目录中移除，目录中预定义的模型不能删除
+	DeleteModel(ctx context.Context, providerID, modelName string) error
+
+	// LoadModels 将数据库中的自定义模型合并到模型目录并注册后端客户端，返回成功合并的数量
+	// 单个模型合并失败时记录警告并跳过
+	LoadModels(ctx context.Context) (int, error)
+}
+
+// ModelCatalog 模型目录接口，用于查询提供商配置和合并自定义模型
+type ModelCatalog interface {
+	// GetProviderByID 根据ID获取提供商详情
+	GetProviderByID(providerID string) (*model.Provider, error)
+
+	// GetProviderModel 获取提供商的指定模型
+	GetProviderModel(providerID, modelID string) (*model.Model, error)
+
+	// AddModel 将模型合并到提供商的模型列表
+	AddModel(providerID string, m model.Model) error
+
+	// RemoveModel 从提供商的模型列表中移除模型
+	RemoveModel(providerID, modelID string) error
+}
+
+// BackendRegistry 模型后端注册接口，自定义模型使用自身凭证的后端客户端
+type BackendRegistry interface {
+	// RegisterModel 注册单个模型的后端客户端
+	RegisterModel(providerID, modelID string, backend genkit.Client)
+
+	// UnregisterModel 移除并关闭单个模型的后端客户端
+	UnregisterModel(providerID, modelID string)
+}
+
+// BackendFactory 使用凭证创建并初始化提供商的后端客户端
+type BackendFactory func(ctx context.Context, providerID string, credentials map[string]string) (genkit.Client, error)
+
+// customModelService 自定义模型业务逻辑实现
+type customModelService struct {
+	repo       repository.CustomModelRepository
+	catalog    ModelCatalog
+	backends   BackendRegistry
+	newBackend BackendFactory
+	cipher     credential.Cipher
+	logger     logger.Logger
+}
+
+// NewCustomModelService 创建自定义模型服务实例
+// backends 或 newBackend 为 nil 时自定义模型只合并到目录，请求路由到提供商的后端客户端
+func NewCustomModelService(
+	repo repository.CustomModelRepository,
+	catalog ModelCatalog,
+	backends BackendRegistry,
+	newBackend BackendFactory,
+	cipher credential.Cipher,
+	log logger.Logger,
+) CustomModelService {
+	return &customModelService{
+		repo:       repo,
+		catalog:    catalog,
+		backends:   backends,
+		newBackend: newBackend,
+		cipher:     cipher,
+		logger:     log,
+	}
+}
+
+// logInfo 安全地记录信息日志
+func (s *customModelService) logInfo(ctx context.Context, msg string, fields logger.Fields) {
+	if s.logger != nil {
+		s.logger.InfoContext(ctx, msg, fields)
+	}
+}
+
+// logWarn 安全地记录警告日志
+func (s *customModelService) logWarn(ctx context.Context, msg string, fields logger.Fields) {
+	if s.logger != nil {
+		s.logger.WarnContext(ctx, msg, fields)
+	}
+}
+
+// RegisterModel 在提供商下注册自定义模型
+func (s *customModelService) RegisterModel(ctx context.Context, userID, providerID string, req *model.RegisterCustomModelRequest) (*model.CustomModel, error) {
+	// 1. 校验模型名称和提供商
+	if err := validator.ValidateModelID(req.Model); err != nil {
+		return nil, errors.NewValidationError(err.Error())
+	}
+
+	provider, err := s.catalog.GetProviderByID(providerID)
+	if err != nil {
+		return nil, err
+	}
+	modelType, ok := model.ParseModelType(req.ModelType)
+	if !ok {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("提供商 '%s' 不支持模型类型 '%s'", providerID, req.ModelType))
+	}
+	fields, err := customizableSchema(provider, modelType)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 按模型凭证表单配置校验凭证
+	values, err := credential.ValidateCredentials(fields, modelType.String(), req.Credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 模型名称不能与目录中的模型重复
+	if _, err := s.catalog.GetProviderModel(providerID, req.Model); err == nil {
+		return nil, errors.NewModelAlreadyExistsError(req.Model)
+	}
+	_, err = s.repo.FindByName(ctx, providerID, req.Model)
+	if err == nil {
+		return nil, errors.NewModelAlreadyExistsError(req.Model)
+	}
+	if err != repository.ErrNotFound {
+		return nil, errors.NewInternalError(err)
+	}
+
+	// 4. 确定基础模型和上下文大小，未指定时取凭证中的值
+	customModel := &model.CustomModel{
+		ProviderID:  providerID,
+		ModelType:   modelType.String(),
+		Model:       req.Model,
+		BaseModel:   req.BaseModel,
+		ContextSize: req.ContextSize,
+		Features:    req.Features,
+		CreatedBy:   userID,
+	}
+	if customModel.BaseModel == "" {
+		customModel.BaseModel = values[baseModelVariable]
+	}
+	if customModel.ContextSize == 0 && values[contextSizeVariable] != "" {
+		contextSize, err := strconv.Atoi(values[contextSizeVariable])
+		if err != nil || contextSize <= 0 {
+			return nil, errors.NewValidationError(fmt.Sprintf("凭证字段 '%s' 必须是正整数", contextSizeVariable))
+		}
+		customModel.ContextSize = contextSize
+	}
+
+	// 5. 使用凭证创建后端客户端，凭证无法使用时不保存
+	backend, err := s.createBackend(ctx, providerID, values)
+	if err != nil {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("无法使用凭证创建模型后端: %v", err))
+	}
+
+	// 6. 加密保存并合并到模型目录
+	encrypted, err := credential.EncryptCredentials(s.cipher, values)
+	if err != nil {
+		closeBackend(backend)
+		return nil, errors.NewInternalError(err)
+	}
+	now := time.Now()
+	customModel.EncryptedCredentials = encrypted
+	customModel.CreatedAt = now
+	customModel.UpdatedAt = now
+	if err := s.repo.Create(ctx, customModel); err != nil {
+		closeBackend(backend)
+		return nil, errors.NewInternalError(err)
+	}
+
+	if err := s.catalog.AddModel(providerID, s.buildModel(customModel)); err != nil {
+		closeBackend(backend)
+		if deleteErr := s.repo.Delete(ctx, customModel.ID); deleteErr != nil {
+			s.logWarn(ctx, "回滚自定义模型失败", logger.Fields{"customModelId": customModel.ID, "error": deleteErr})
+		}
+		return nil, err
+	}
+	if backend != nil {
+		s.backends.RegisterModel(providerID, customModel.Model, backend)
+	}
+
+	s.logInfo(ctx, "自定义模型已注册", logger.Fields{
+		"customModelId": customModel.ID,
+		"providerId":    providerID,
+		"model":         customModel.Model,
+		"modelType":     customModel.ModelType,
+		"baseModel":     customModel.BaseModel,
+		"userId":        userID,
+	})
+
+	customModel.Credentials = credential.MaskCredentials(fields, values)
+	return customModel, nil
+}
+
+// ListModels 获取提供商的自定义模型列表
+func (s *customModelService) ListModels(ctx context.Context, providerID string) ([]*model.CustomModel, error) {
+	provider, err := s.catalog.GetProviderByID(providerID)
+	if err != nil {
+		return nil, err
+	}
+
+	customModels, err := s.repo.List(ctx, providerID)
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+
+	fields := provider.ModelCredentialSchema.CredentialFormSchemas
+	for _, customModel := range customModels {
+		values, err := credential.DecryptCredentials(s.cipher, customModel.EncryptedCredentials)
+		if err != nil {
+			return nil, errors.NewInternalError(fmt.Errorf("自定义模型 %s: %w", customModel.ID, err))
+		}
+		customModel.Credentials = credential.MaskCredentials(fields, values)
+	}
+	return customModels, nil
+}
+
+// DeleteModel 删除自定义模型并从模型目录中移除
+func (s *customModelService) DeleteModel(ctx context.Context, providerID, modelName string) error {
+	customModel, err := s.repo.FindByName(ctx, providerID, modelName)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return errors.NewModelNotFoundError(modelName)
+		}
+		return errors.NewInternalError(err)
+	}
+
+	if err := s.repo.Delete(ctx, customModel.ID); err != nil {
+		return errors.NewInternalError(err)
+	}
+
+	// 启动时未能合并到目录的模型在目录中不存在，忽略该错误
+	if err := s.catalog.RemoveModel(providerID, modelName); err != nil {
+		s.logWarn(ctx, "从模型目录移除自定义模型失败", logger.Fields{"providerId": providerID, "model": modelName, "error": err})
+	}
+	if s.backends != nil {
+		s.backends.UnregisterModel(providerID, modelName)
+	}
+
+	s.logInfo(ctx, "自定义模型已删除", logger.Fields{
+		"customModelId": customModel.ID,
+		"providerId":    providerID,
+		"model":         modelName,
+	})
+
+	return nil
+}
+
+// LoadModels 将数据库中的自定义模型合并到模型目录并注册后端客户端
+func (s *customModelService) LoadModels(ctx context.Context) (int, error) {
+	customModels, err := s.repo.List(ctx, "")
+	if err != nil {
+		return 0, errors.NewInternalError(err)
+	}
+
+	loaded := 0
+	for _, customModel := range customModels {
+		fields := logger.Fields{
+			"providerId": customModel.ProviderID,
+			"model":      customModel.Model,
+		}
+
+		if err := s.catalog.AddModel(customModel.ProviderID, s.buildModel(customModel)); err != nil {
+			fields["error"] = err
+			s.logWarn(ctx, "自定义模型未合并到模型目录", fields)
+			continue
+		}
+		loaded++
+
+		values, err := credential.DecryptCredentials(s.cipher, customModel.EncryptedCredentials)
+		if err != nil {
+			fields["error"] = err
+			s.logWarn(ctx, "解密自定义模型凭证失败，请求将路由到提供商的后端", fields)
+			continue
+		}
+		backend, err := s.createBackend(ctx, customModel.ProviderID, values)
+		if err != nil {
+			fields["error"] = err
+			s.logWarn(ctx, "创建自定义模型后端失败，请求将路由到提供商的后端", fields)
+			continue
+		}
+		if backend != nil {
+			s.backends.RegisterModel(customModel.ProviderID, customModel.Model, backend)
+		}
+	}
+
+	return loaded, nil
+}
+
+// createBackend 使用凭证创建后端客户端，未配置后端注册时返回 nil
+func (s *customModelService) createBackend(ctx context.Context, providerID string, values map[string]string) (genkit.Client, error) {
+	if s.backends == nil || s.newBackend == nil {
+		return nil, nil
+	}
+	return s.newBackend(ctx, providerID, values)
+}
+
+// buildModel 生成合并到模型目录的模型
+// 目录中存在同类型的基础模型时继承其标签以外的属性、特性、参数规则和定价，再以自定义模型的配置覆盖
+func (s *customModelService) buildModel(customModel *model.CustomModel) model.Model {
+	var m model.Model
+	if customModel.BaseModel != "" {
+		base, err := s.catalog.GetProviderModel(customModel.ProviderID, customModel.BaseModel)
+		if err == nil && base.ModelType.String() == customModel.ModelType && base.FetchFrom != model.FetchFromCustomizableModel {
+			m = *base
+		}
+	}
+
+	m.Model = customModel.Model
+	m.Label = map[string]string{"en_US": customModel.Model}
+	m.ModelType = model.ModelType(customModel.ModelType)
+	m.FetchFrom = model.FetchFromCustomizableModel
+	m.Deprecated = false
+	if customModel.ContextSize > 0 {
+		m.ModelProperties.ContextSize = customModel.ContextSize
+	}
+	if len(customModel.Features) > 0 {
+		m.Features = append([]string(nil), customModel.Features...)
+	}
+	return m
+}
+
+// customizableSchema 检查提供商是否支持注册该类型的自定义模型，返回模型凭证表单配置
+func customizableSchema(provider *model.Provider, modelType model.ModelType) ([]model.CredentialFormSchema, error) {
+	customizable := false
+	for _, method := range provider.ConfigurateMethods {
+		if method == model.FetchFromCustomizableModel {
+			customizable = true
+			break
+		}
+	}
+	if !customizable {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("提供商 '%s' 不支持自定义模型", provider.ID))
+	}
+
+	if !provider.SupportsModelType(modelType) {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("提供商 '%s' 不支持模型类型 '%s'", provider.ID, modelType))
+	}
+
+	if len(provider.ModelCredentialSchema.CredentialFormSchemas) == 0 {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("提供商 '%s' 没有模型凭证配置", provider.ID))
+	}
+	return provider.ModelCredentialSchema.CredentialFormSchemas, nil
+}
+
+// closeBackend 关闭未注册的后端客户端
+func closeBackend(backend genkit.Client) {
+	if backend != nil {
+		backend.Close()
+	}
+}
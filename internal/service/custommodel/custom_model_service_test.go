@@ -0,0 +1,306 @@
+package custommodel
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"genkit-ai-service/internal/genkit"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/repository"
+	"genkit-ai-service/internal/service"
+	"genkit-ai-service/internal/service/credential"
+	"genkit-ai-service/internal/storage"
+	"genkit-ai-service/pkg/errors"
+)
+
+// stubBackend 测试用后端客户端
+type stubBackend struct {
+	genkit.Client
+	credentials map[string]string
+	closed      bool
+}
+
+func (b *stubBackend) Close() error {
+	b.closed = true
+	return nil
+}
+
+// stubRegistry 测试用模型后端注册表
+type stubRegistry map[string]genkit.Client
+
+func (r stubRegistry) RegisterModel(providerID, modelID string, backend genkit.Client) {
+	r[providerID+"/"+modelID] = backend
+}
+
+func (r stubRegistry) UnregisterModel(providerID, modelID string) {
+	if backend, ok := r[providerID+"/"+modelID]; ok {
+		backend.Close()
+		delete(r, providerID+"/"+modelID)
+	}
+}
+
+// stubFactory 测试用后端工厂，API 密钥为 invalid 时返回错误
+func stubFactory(ctx context.Context, providerID string, credentials map[string]string) (genkit.Client, error) {
+	if credentials["openai_api_key"] == "invalid" {
+		return nil, fmt.Errorf("API 密钥无效")
+	}
+	return &stubBackend{credentials: credentials}, nil
+}
+
+var azureSchema = model.CredentialSchema{
+	CredentialFormSchemas: []model.CredentialFormSchema{
+		{Variable: "openai_api_base", Type: model.FormTypeTextInput, Required: true},
+		{Variable: "openai_api_key", Type: model.FormTypeSecretInput, Required: true},
+		{
+			Variable: "base_model_name",
+			Type:     model.FormTypeSelect,
+			Required: true,
+			Options: []model.FormOption{
+				{Value: "gpt-4o-mini", ShowOn: []model.ShowOnCondition{{Variable: "__model_type", Value: "llm"}}},
+				{Value: "text-embedding-3-small", ShowOn: []model.ShowOnCondition{{Variable: "__model_type", Value: "text-embedding"}}},
+			},
+		},
+	},
+}
+
+type testEnv struct {
+	service  CustomModelService
+	catalog  service.ProviderService
+	registry stubRegistry
+	repo     repository.CustomModelRepository
+	cipher   credential.Cipher
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(&model.CustomModel{}); err != nil {
+		t.Fatalf("迁移自定义模型表失败: %v", err)
+	}
+
+	store := storage.NewMemoryStore()
+	store.SetProviders([]model.Provider{
+		{
+			ID:                    "azure_openai",
+			ConfigurateMethods:    []string{model.FetchFromCustomizableModel},
+			SupportedModelTypes:   []string{"llm", "text-embedding"},
+			ModelCredentialSchema: azureSchema,
+		},
+		{
+			ID:                  "gemini",
+			ConfigurateMethods:  []string{model.FetchFromPredefinedModel},
+			SupportedModelTypes: []string{"llm"},
+		},
+	})
+	store.SetModels("azure_openai", []model.Model{{
+		Model:           "gpt-4o-mini",
+		Label:           map[string]string{"en_US": "gpt-4o-mini"},
+		ModelType:       "llm",
+		Features:        []string{"tool-call", "vision"},
+		ModelProperties: model.ModelProperties{Mode: "chat", ContextSize: 128000},
+		ParameterRules:  []model.ParameterRule{{Name: "temperature"}},
+	}})
+
+	cipher, err := credential.NewAESCipher("test-master-key-0123456789abcdefghij")
+	if err != nil {
+		t.Fatalf("NewAESCipher() error = %v", err)
+	}
+
+	env := &testEnv{
+		catalog:  service.NewProviderService(store),
+		registry: stubRegistry{},
+		repo:     repository.NewCustomModelRepository(db),
+		cipher:   cipher,
+	}
+	env.service = NewCustomModelService(env.repo, env.catalog, env.registry, stubFactory, cipher, nil)
+	return env
+}
+
+func assertErrorCode(t *testing.T, err error, code int) {
+	t.Helper()
+	appErr, ok := err.(*errors.AppError)
+	if !ok || appErr.Code != code {
+		t.Errorf("期望错误码 %d, 得到 %v", code, err)
+	}
+}
+
+func validRequest() *model.RegisterCustomModelRequest {
+	return &model.RegisterCustomModelRequest{
+		ModelType: "llm",
+		Model:     "my-gpt-deployment",
+		Credentials: map[string]string{
+			"openai_api_base": "https://example.openai.azure.com",
+			"openai_api_key":  "sk-abcdefghijklmnop",
+			"base_model_name": "gpt-4o-mini",
+		},
+	}
+}
+
+func TestRegisterModel(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	req := validRequest()
+	req.ContextSize = 32000
+	customModel, err := env.service.RegisterModel(ctx, "user-1", "azure_openai", req)
+	if err != nil {
+		t.Fatalf("RegisterModel() error = %v", err)
+	}
+	if customModel.BaseModel != "gpt-4o-mini" || customModel.CreatedBy != "user-1" {
+		t.Errorf("自定义模型不正确: %+v", customModel)
+	}
+	if customModel.Credentials["openai_api_key"] != "sk-a***********mnop" {
+		t.Errorf("密钥应脱敏, 得到 %q", customModel.Credentials["openai_api_key"])
+	}
+
+	// 合并到模型目录，继承基础模型的属性并覆盖上下文大小
+	providerID, mdl, err := env.catalog.ResolveModel("my-gpt-deployment")
+	if err != nil {
+		t.Fatalf("ResolveModel() error = %v", err)
+	}
+	if providerID != "azure_openai" || mdl.FetchFrom != model.FetchFromCustomizableModel {
+		t.Errorf("目录中的模型不正确: %s %+v", providerID, mdl)
+	}
+	if mdl.ModelProperties.ContextSize != 32000 || mdl.ModelProperties.Mode != "chat" || !mdl.HasFeature("vision") || len(mdl.ParameterRules) != 1 {
+		t.Errorf("应继承基础模型的属性: %+v", mdl)
+	}
+	items, _ := env.catalog.GetProviderModels("azure_openai")
+	if len(items) != 2 {
+		t.Errorf("提供商模型列表应包含自定义模型, 得到 %d 个", len(items))
+	}
+
+	// 注册使用自身凭证的后端
+	backend, ok := env.registry["azure_openai/my-gpt-deployment"].(*stubBackend)
+	if !ok || backend.credentials["openai_api_key"] != "sk-abcdefghijklmnop" {
+		t.Errorf("应使用明文凭证注册模型后端: %+v", env.registry)
+	}
+
+	// 数据库中的凭证已加密
+	stored, err := env.repo.FindByName(ctx, "azure_openai", "my-gpt-deployment")
+	if err != nil {
+		t.Fatalf("FindByName() error = %v", err)
+	}
+	values, err := credential.DecryptCredentials(env.cipher, stored.EncryptedCredentials)
+	if err != nil || values["openai_api_key"] != "sk-abcdefghijklmnop" {
+		t.Errorf("解密后的凭证不正确: %v %v", values, err)
+	}
+
+	// 同名模型不能重复注册，也不能与预定义模型重名
+	_, err = env.service.RegisterModel(ctx, "user-1", "azure_openai", validRequest())
+	assertErrorCode(t, err, errors.CodeModelAlreadyExists)
+	req = validRequest()
+	req.Model = "gpt-4o-mini"
+	_, err = env.service.RegisterModel(ctx, "user-1", "azure_openai", req)
+	assertErrorCode(t, err, errors.CodeModelAlreadyExists)
+}
+
+func TestRegisterModel_Invalid(t *testing.T) {
+	tests := []struct {
+		name       string
+		providerID string
+		modify     func(req *model.RegisterCustomModelRequest)
+		wantCode   int
+	}{
+		{name: "提供商不存在", providerID: "unknown", wantCode: errors.CodeProviderNotFound},
+		{name: "提供商不支持自定义模型", providerID: "gemini", wantCode: errors.CodeBadRequest},
+		{name: "不支持的模型类型", providerID: "azure_openai", modify: func(req *model.RegisterCustomModelRequest) { req.ModelType = "tts" }, wantCode: errors.CodeBadRequest},
+		{name: "模型名称包含斜杠", providerID: "azure_openai", modify: func(req *model.RegisterCustomModelRequest) { req.Model = "a/b" }, wantCode: errors.CodeValidationError},
+		{name: "缺少必填凭证", providerID: "azure_openai", modify: func(req *model.RegisterCustomModelRequest) { delete(req.Credentials, "openai_api_key") }, wantCode: errors.CodeValidationError},
+		{name: "基础模型与模型类型不匹配", providerID: "azure_openai", modify: func(req *model.RegisterCustomModelRequest) {
+			req.Credentials["base_model_name"] = "text-embedding-3-small"
+		}, wantCode: errors.CodeValidationError},
+		{name: "凭证无法使用", providerID: "azure_openai", modify: func(req *model.RegisterCustomModelRequest) { req.Credentials["openai_api_key"] = "invalid" }, wantCode: errors.CodeBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			req := validRequest()
+			if tt.modify != nil {
+				tt.modify(req)
+			}
+
+			_, err := env.service.RegisterModel(context.Background(), "user-1", tt.providerID, req)
+			assertErrorCode(t, err, tt.wantCode)
+			if len(env.registry) != 0 {
+				t.Error("注册失败时不应注册模型后端")
+			}
+		})
+	}
+}
+
+func TestRegisterModel_TextEmbedding(t *testing.T) {
+	env := newTestEnv(t)
+
+	req := validRequest()
+	req.ModelType = "text_embedding"
+	req.Model = "embedding-deployment"
+	req.Credentials["base_model_name"] = "text-embedding-3-small"
+	if _, err := env.service.RegisterModel(context.Background(), "user-1", "azure_openai", req); err != nil {
+		t.Fatalf("RegisterModel() error = %v", err)
+	}
+
+	// 目录中使用下划线形式的模型类型
+	mdl, err := env.catalog.GetProviderModel("azure_openai", "embedding-deployment")
+	if err != nil || mdl.ModelType != "text_embedding" {
+		t.Errorf("目录中的模型类型不正确: %+v %v", mdl, err)
+	}
+}
+
+func TestDeleteAndLoadModels(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	if _, err := env.service.RegisterModel(ctx, "user-1", "azure_openai", validRequest()); err != nil {
+		t.Fatalf("RegisterModel() error = %v", err)
+	}
+	req := validRequest()
+	req.Model = "other-deployment"
+	if _, err := env.service.RegisterModel(ctx, "user-1", "azure_openai", req); err != nil {
+		t.Fatalf("RegisterModel() error = %v", err)
+	}
+
+	list, err := env.service.ListModels(ctx, "azure_openai")
+	if err != nil || len(list) != 2 {
+		t.Fatalf("ListModels() = %d, %v", len(list), err)
+	}
+	if list[0].Credentials["openai_api_key"] == "sk-abcdefghijklmnop" {
+		t.Error("列表中的密钥应脱敏")
+	}
+
+	// 预定义模型不能删除
+	assertErrorCode(t, env.service.DeleteModel(ctx, "azure_openai", "gpt-4o-mini"), errors.CodeModelNotFound)
+
+	backend := env.registry["azure_openai/my-gpt-deployment"].(*stubBackend)
+	if err := env.service.DeleteModel(ctx, "azure_openai", "my-gpt-deployment"); err != nil {
+		t.Fatalf("DeleteModel() error = %v", err)
+	}
+	if _, _, err := env.catalog.ResolveModel("my-gpt-deployment"); err == nil {
+		t.Error("删除后不应能解析模型")
+	}
+	if !backend.closed {
+		t.Error("删除后应关闭模型后端")
+	}
+
+	// 重启后从数据库加载到新的目录
+	fresh := newTestEnv(t)
+	fresh.repo = env.repo
+	fresh.service = NewCustomModelService(env.repo, fresh.catalog, fresh.registry, stubFactory, env.cipher, nil)
+	loaded, err := fresh.service.LoadModels(ctx)
+	if err != nil || loaded != 1 {
+		t.Fatalf("LoadModels() = %d, %v", loaded, err)
+	}
+	if _, _, err := fresh.catalog.ResolveModel("azure_openai/other-deployment"); err != nil {
+		t.Errorf("加载后应能解析模型: %v", err)
+	}
+	if _, ok := fresh.registry["azure_openai/other-deployment"]; !ok {
+		t.Error("加载后应注册模型后端")
+	}
+}
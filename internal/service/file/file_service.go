@@ -0,0 +1,297 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/repository"
+	"genkit-ai-service/internal/storage"
+	"genkit-ai-service/pkg/errors"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/google/uuid"
+)
+
+// maxNameLength 文件名的最大长度（字符数）
+const maxNameLength = 255
+
+// FileService 上传文件业务逻辑接口
+type FileService interface {
+	// Upload 保存用户上传的文件，根据内容检测 MIME 类型；内容超过大小限制时返回文件过大错误
+	Upload(ctx context.Context, userID, name string, content io.Reader) (*model.File, error)
+
+	// GetFile 获取用户拥有的文件元数据
+	GetFile(ctx context.Context, fileID, userID string) (*model.File, error)
+
+	// OpenFile 打开用户拥有的文件内容，调用方负责关闭返回的 ReadCloser
+	OpenFile(ctx context.Context, fileID, userID string) (*model.File, io.ReadCloser, error)
+
+	// DeleteFile 删除用户拥有的文件，没有其他文件引用相同内容时一并删除存储的内容
+	DeleteFile(ctx context.Context, fileID, userID string) error
+
+	// ReadFile 读取文件元数据和全部内容，不检查文件归属，由调用方负责
+	ReadFile(ctx context.Context, fileID string) (*model.File, []byte, error)
+}
+
+// fileService 上传文件业务逻辑实现
+type fileService struct {
+	fileRepo repository.FileRepository
+	blobs    storage.BlobStore
+	maxSize  int64
+	logger   logger.Logger
+}
+
+// NewFileService 创建上传文件服务实例
+// 文件内容以 SHA-256 为键保存到 blobs，相同内容只保存一份；maxSize 为单个文件的最大字节数
+func NewFileService(fileRepo repository.FileRepository, blobs storage.BlobStore, maxSize int64, log logger.Logger) FileService {
+	return &fileService{
+		fileRepo: fileRepo,
+		blobs:    blobs,
+		maxSize:  maxSize,
+		logger:   log,
+	}
+}
+
+// logInfo 安全地记录信息日志
+func (s *fileService) logInfo(ctx context.Context, msg string, fields logger.Fields) {
+	if s.logger != nil {
+		s.logger.InfoContext(ctx, msg, fields)
+	}
+}
+
+// logWarn 安全地记录警告日志
+func (s *fileService) logWarn(ctx context.Context, msg string, fields logger.Fields) {
+	if s.logger != nil {
+		s.logger.WarnContext(ctx, msg, fields)
+	}
+}
+
+// Upload 保存用户上传的文件，根据内容检测 MIME 类型；内容超过大小限制时返回文件过大错误
+func (s *fileService) Upload(ctx context.Context, userID, name string, content io.Reader) (*model.File, error) {
+	name = cleanName(name)
+	if name == "" {
+		return nil, errors.NewBadRequestError("文件名不能为空")
+	}
+
+	// 多读一个字节用于判断是否超过大小限制
+	data, err := io.ReadAll(io.LimitReader(content, s.maxSize+1))
+	if err != nil {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("读取上传文件失败: %v", err))
+	}
+	if int64(len(data)) > s.maxSize {
+		return nil, errors.NewFileTooLargeError(s.maxSize)
+	}
+	if len(data) == 0 {
+		return nil, errors.NewBadRequestError("文件内容不能为空")
+	}
+
+	sum := sha256.Sum256(data)
+	key := hex.EncodeToString(sum[:])
+
+	// 相同内容已经保存过时不再重复写入
+	exists, err := s.blobs.Exists(ctx, key)
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+	if !exists {
+		if err := s.blobs.Put(ctx, key, bytes.NewReader(data)); err != nil {
+			return nil, errors.NewInternalError(err)
+		}
+	}
+
+	file := &model.File{
+		UserID:    userID,
+		Name:      name,
+		MimeType:  detectMimeType(name, data),
+		Size:      int64(len(data)),
+		SHA256:    key,
+		CreatedAt: time.Now(),
+	}
+	if err := s.fileRepo.Create(ctx, file); err != nil {
+		if !exists {
+			s.removeBlob(ctx, key)
+		}
+		return nil, errors.NewInternalError(err)
+	}
+
+	s.logInfo(ctx, "文件上传成功", logger.Fields{
+		"fileId":   file.ID,
+		"userId":   userID,
+		"mimeType": file.MimeType,
+		"size":     file.Size,
+		"dedup":    exists,
+	})
+
+	return file, nil
+}
+
+// GetFile 获取用户拥有的文件元数据
+func (s *fileService) GetFile(ctx context.Context, fileID, userID string) (*model.File, error) {
+	file, err := s.getFile(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	if file.UserID != userID {
+		s.logWarn(ctx, "用户无权访问文件", logger.Fields{
+			"fileId":    fileID,
+			"userId":    userID,
+			"fileOwner": file.UserID,
+		})
+		return nil, errors.NewFileAccessDeniedError()
+	}
+
+	return file, nil
+}
+
+// OpenFile 打开用户拥有的文件内容，调用方负责关闭返回的 ReadCloser
+func (s *fileService) OpenFile(ctx context.Context, fileID, userID string) (*model.File, io.ReadCloser, error) {
+	file, err := s.GetFile(ctx, fileID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, err := s.openBlob(ctx, file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return file, content, nil
+}
+
+// DeleteFile 删除用户拥有的文件，没有其他文件引用相同内容时一并删除存储的内容
+func (s *fileService) DeleteFile(ctx context.Context, fileID, userID string) error {
+	file, err := s.GetFile(ctx, fileID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.fileRepo.Delete(ctx, file.ID); err != nil {
+		return errors.NewInternalError(err)
+	}
+
+	// 存储内容的清理失败不影响删除结果，只记录日志
+	count, err := s.fileRepo.CountBySHA256(ctx, file.SHA256)
+	if err != nil {
+		s.logWarn(ctx, "统计文件内容引用失败，跳过清理", logger.Fields{
+			"fileId": file.ID,
+			"error":  err.Error(),
+		})
+	} else if count == 0 {
+		s.removeBlob(ctx, file.SHA256)
+	}
+
+	s.logInfo(ctx, "文件已删除", logger.Fields{
+		"fileId": file.ID,
+		"userId": userID,
+	})
+
+	return nil
+}
+
+// ReadFile 读取文件元数据和全部内容，不检查文件归属，由调用方负责
+func (s *fileService) ReadFile(ctx context.Context, fileID string) (*model.File, []byte, error) {
+	file, err := s.getFile(ctx, fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, err := s.openBlob(ctx, file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer content.Close()
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, nil, errors.NewInternalError(err)
+	}
+
+	return file, data, nil
+}
+
+// getFile 获取文件元数据，文件ID不是有效的 UUID 时视为不存在
+func (s *fileService) getFile(ctx context.Context, fileID string) (*model.File, error) {
+	if _, err := uuid.Parse(fileID); err != nil {
+		return nil, errors.NewFileNotFoundError(fileID)
+	}
+
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		if stderrors.Is(err, repository.ErrNotFound) {
+			return nil, errors.NewFileNotFoundError(fileID)
+		}
+		return nil, errors.NewInternalError(err)
+	}
+
+	return file, nil
+}
+
+// openBlob 打开文件的存储内容
+func (s *fileService) openBlob(ctx context.Context, file *model.File) (io.ReadCloser, error) {
+	content, err := s.blobs.Get(ctx, file.SHA256)
+	if err != nil {
+		if stderrors.Is(err, storage.ErrBlobNotFound) {
+			s.logWarn(ctx, "文件内容不存在", logger.Fields{
+				"fileId": file.ID,
+				"sha256": file.SHA256,
+			})
+			return nil, errors.NewFileNotFoundError(file.ID)
+		}
+		return nil, errors.NewInternalError(err)
+	}
+
+	return content, nil
+}
+
+// removeBlob 删除存储的内容，失败时只记录日志
+func (s *fileService) removeBlob(ctx context.Context, key string) {
+	if err := s.blobs.Delete(ctx, key); err != nil {
+		s.logWarn(ctx, "删除文件内容失败", logger.Fields{
+			"sha256": key,
+			"error":  err.Error(),
+		})
+	}
+}
+
+// cleanName 去掉文件名中的路径部分，并截断到 maxNameLength 个字符
+func cleanName(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "." || name == "/" {
+		return ""
+	}
+	if utf8.RuneCountInString(name) > maxNameLength {
+		name = string([]rune(name)[:maxNameLength])
+	}
+	return name
+}
+
+// detectMimeType 根据文件内容检测 MIME 类型，不带参数（如 charset）
+// 纯文本和无法识别的二进制内容再按扩展名细化，如 .md、.csv
+func detectMimeType(name string, data []byte) string {
+	detected := mimetype.Detect(data)
+	mimeType := detected.String()
+
+	if detected.Is("text/plain") || detected.Is("application/octet-stream") {
+		if byExt := mime.TypeByExtension(filepath.Ext(name)); byExt != "" {
+			mimeType = byExt
+		}
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+		return mediaType
+	}
+	return mimeType
+}
@@ -0,0 +1,203 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/repository"
+	"genkit-ai-service/internal/storage"
+	"genkit-ai-service/pkg/errors"
+)
+
+// testFileRepository 测试用文件仓库
+type testFileRepository struct {
+	files map[string]*model.File
+	next  int
+}
+
+func newTestFileRepository() *testFileRepository {
+	return &testFileRepository{files: make(map[string]*model.File)}
+}
+
+func (r *testFileRepository) Create(ctx context.Context, file *model.File) error {
+	r.next++
+	file.ID = fmt.Sprintf("550e8400-e29b-41d4-a716-%012d", r.next)
+	r.files[file.ID] = file
+	return nil
+}
+
+func (r *testFileRepository) GetByID(ctx context.Context, fileID string) (*model.File, error) {
+	file, ok := r.files[fileID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return file, nil
+}
+
+func (r *testFileRepository) Delete(ctx context.Context, fileID string) error {
+	delete(r.files, fileID)
+	return nil
+}
+
+func (r *testFileRepository) CountBySHA256(ctx context.Context, sha256 string) (int64, error) {
+	var count int64
+	for _, file := range r.files {
+		if file.SHA256 == sha256 {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func newTestFileService(t *testing.T, maxSize int64) (FileService, *testFileRepository, storage.BlobStore) {
+	t.Helper()
+	blobs, err := storage.NewLocalBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("创建文件存储失败: %v", err)
+	}
+	fileRepo := newTestFileRepository()
+	return NewFileService(fileRepo, blobs, maxSize, nil), fileRepo, blobs
+}
+
+// pngHeader PNG 文件头，用于检测 MIME 类型
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00")
+
+func TestUpload(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		fileName     string
+		content      []byte
+		wantName     string
+		wantMimeType string
+	}{
+		{name: "按内容检测图片", fileName: "photo.txt", content: pngHeader, wantName: "photo.txt", wantMimeType: "image/png"},
+		{name: "PDF", fileName: "report.pdf", content: []byte("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n"), wantName: "report.pdf", wantMimeType: "application/pdf"},
+		{name: "纯文本去掉 charset", fileName: "notes", content: []byte("hello world"), wantName: "notes", wantMimeType: "text/plain"},
+		{name: "去掉路径", fileName: `C:\Users\me\a.png`, content: pngHeader, wantName: "a.png", wantMimeType: "image/png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _, _ := newTestFileService(t, 1<<20)
+
+			file, err := service.Upload(ctx, "user-1", tt.fileName, bytes.NewReader(tt.content))
+			if err != nil {
+				t.Fatalf("上传失败: %v", err)
+			}
+			if file.Name != tt.wantName || file.MimeType != tt.wantMimeType {
+				t.Errorf("期望 %s (%s), 得到 %s (%s)", tt.wantName, tt.wantMimeType, file.Name, file.MimeType)
+			}
+			if file.Size != int64(len(tt.content)) || len(file.SHA256) != 64 || file.UserID != "user-1" {
+				t.Errorf("文件元数据不正确: %+v", file)
+			}
+		})
+	}
+}
+
+func TestUpload_Invalid(t *testing.T) {
+	ctx := context.Background()
+	service, fileRepo, _ := newTestFileService(t, 10)
+
+	tests := []struct {
+		name     string
+		fileName string
+		content  string
+		wantCode int
+	}{
+		{name: "超过大小限制", fileName: "a.txt", content: strings.Repeat("a", 11), wantCode: errors.CodeFileTooLarge},
+		{name: "空文件", fileName: "a.txt", content: "", wantCode: errors.CodeBadRequest},
+		{name: "缺少文件名", fileName: "", content: "abc", wantCode: errors.CodeBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Upload(ctx, "user-1", tt.fileName, strings.NewReader(tt.content))
+			if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != tt.wantCode {
+				t.Errorf("期望错误码 %d, 得到 %v", tt.wantCode, err)
+			}
+		})
+	}
+
+	if len(fileRepo.files) != 0 {
+		t.Errorf("无效的上传不应保存文件记录, 得到 %d 条", len(fileRepo.files))
+	}
+
+	// 恰好等于大小限制时允许上传
+	if _, err := service.Upload(ctx, "user-1", "a.txt", strings.NewReader(strings.Repeat("a", 10))); err != nil {
+		t.Errorf("期望等于大小限制的文件上传成功, 得到 %v", err)
+	}
+}
+
+func TestFileOwnershipAndDedup(t *testing.T) {
+	ctx := context.Background()
+	service, _, blobs := newTestFileService(t, 1<<20)
+
+	first, err := service.Upload(ctx, "user-1", "a.txt", strings.NewReader("same content"))
+	if err != nil {
+		t.Fatalf("上传失败: %v", err)
+	}
+	second, err := service.Upload(ctx, "user-2", "b.txt", strings.NewReader("same content"))
+	if err != nil {
+		t.Fatalf("上传失败: %v", err)
+	}
+	if first.ID == second.ID || first.SHA256 != second.SHA256 {
+		t.Fatalf("相同内容应创建独立的文件记录并共享存储: %+v %+v", first, second)
+	}
+
+	t.Run("读取自己的文件", func(t *testing.T) {
+		file, content, err := service.OpenFile(ctx, first.ID, "user-1")
+		if err != nil {
+			t.Fatalf("打开文件失败: %v", err)
+		}
+		defer content.Close()
+		data, _ := io.ReadAll(content)
+		if file.Name != "a.txt" || string(data) != "same content" {
+			t.Errorf("文件内容不正确: %s %q", file.Name, data)
+		}
+	})
+
+	t.Run("无权访问", func(t *testing.T) {
+		_, err := service.GetFile(ctx, first.ID, "user-2")
+		if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.CodeFileAccessDenied {
+			t.Errorf("期望无权访问, 得到 %v", err)
+		}
+		if err := service.DeleteFile(ctx, first.ID, "user-2"); err == nil {
+			t.Error("期望不能删除其他用户的文件")
+		}
+	})
+
+	t.Run("文件不存在", func(t *testing.T) {
+		for _, id := range []string{"550e8400-e29b-41d4-a716-999999999999", "not-a-uuid"} {
+			_, err := service.GetFile(ctx, id, "user-1")
+			if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.CodeFileNotFound {
+				t.Errorf("%s: 期望文件不存在, 得到 %v", id, err)
+			}
+		}
+	})
+
+	t.Run("删除最后一个引用时清理存储", func(t *testing.T) {
+		if err := service.DeleteFile(ctx, first.ID, "user-1"); err != nil {
+			t.Fatalf("删除文件失败: %v", err)
+		}
+		if exists, _ := blobs.Exists(ctx, first.SHA256); !exists {
+			t.Fatal("仍被引用的内容不应删除")
+		}
+		if _, data, err := service.ReadFile(ctx, second.ID); err != nil || string(data) != "same content" {
+			t.Errorf("期望仍能读取共享内容的文件, 得到 %q %v", data, err)
+		}
+
+		if err := service.DeleteFile(ctx, second.ID, "user-2"); err != nil {
+			t.Fatalf("删除文件失败: %v", err)
+		}
+		if exists, _ := blobs.Exists(ctx, first.SHA256); exists {
+			t.Error("没有引用的内容应被删除")
+		}
+	})
+}
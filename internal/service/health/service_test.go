@@ -2,7 +2,6 @@ package health
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"testing"
 	"time"
@@ -10,6 +9,7 @@ import (
 	"genkit-ai-service/internal/genkit"
 
 	"github.com/firebase/genkit/go/ai"
+	"gorm.io/gorm"
 )
 
 // mockGenkitClient 模拟 Genkit 客户端
@@ -31,6 +31,14 @@ func (m *mockGenkitClient) Generate(ctx context.Context, prompt string, options
 	}, nil
 }
 
+func (m *mockGenkitClient) InitializeModel(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockGenkitClient) GenerateStream(ctx context.Context, prompt string, options *genkit.GenerateOptions, callback genkit.StreamCallback) (*genkit.GenerateResult, error) {
+	return m.Generate(ctx, prompt, options)
+}
+
 func (m *mockGenkitClient) SetModel(model ai.Model) {}
 
 func (m *mockGenkitClient) Close() error {
@@ -54,7 +62,11 @@ func (m *mockDatabase) Ping(ctx context.Context) error {
 	return m.pingErr
 }
 
-func (m *mockDatabase) GetDB() *sql.DB {
+func (m *mockDatabase) GetDB() *gorm.DB {
+	return nil
+}
+
+func (m *mockDatabase) AutoMigrate(models ...interface{}) error {
 	return nil
 }
 
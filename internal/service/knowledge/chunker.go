@@ -0,0 +1,286 @@
+package knowledge
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/pkg/tokenizer"
+)
+
+// asciiCharsPerToken 与 tokenizer 的估算规则一致：ASCII 字符平均每 4 个为 1 个 token
+const asciiCharsPerToken = 4
+
+// Chunk 文档分块
+type Chunk struct {
+	// 分块内容
+	Content string
+	// 所在章节的标题路径，如 "部署 > 配置"
+	Heading string
+	// 估算的 token 数量
+	Tokens int
+}
+
+// Chunker 文档分块接口
+type Chunker interface {
+	// Split 将文档内容切分为分块，忽略只有空白的部分
+	Split(text string) []Chunk
+}
+
+// NewChunker 根据分块策略创建分块器
+// size 为每块的最大 token 数，overlap 为按 token 数切分时相邻块之间重叠的 token 数
+func NewChunker(strategy string, size, overlap int) (Chunker, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("分块大小必须大于0")
+	}
+	if overlap < 0 || overlap >= size {
+		return nil, fmt.Errorf("分块重叠必须大于等于0且小于分块大小")
+	}
+
+	tokens := &tokenChunker{size: size, overlap: overlap}
+	switch strategy {
+	case model.ChunkStrategyTokens:
+		return tokens, nil
+	case model.ChunkStrategyMarkdown:
+		return &markdownChunker{tokens: tokens}, nil
+	default:
+		return nil, fmt.Errorf("不支持的分块策略 '%s'", strategy)
+	}
+}
+
+// textUnit 切分的最小单位：一个宽字符或一个 ASCII 单词，连同其后的空白
+type textUnit struct {
+	text  string
+	wide  int
+	ascii int
+}
+
+// tokenCounter 按 tokenizer.Estimate 的规则累计 token 数
+type tokenCounter struct {
+	wide  int
+	ascii int
+}
+
+func (c *tokenCounter) add(u textUnit)    { c.wide += u.wide; c.ascii += u.ascii }
+func (c *tokenCounter) remove(u textUnit) { c.wide -= u.wide; c.ascii -= u.ascii }
+
+func (c *tokenCounter) tokens() int {
+	return c.wide + (c.ascii+asciiCharsPerToken-1)/asciiCharsPerToken
+}
+
+// tokenChunker 按 token 数切分，相邻块之间保留 overlap 个 token 的重叠
+type tokenChunker struct {
+	size    int
+	overlap int
+}
+
+// Split 将文档内容切分为分块
+func (c *tokenChunker) Split(text string) []Chunk {
+	return c.split(text, "")
+}
+
+// split 切分文本，分块的标题路径为 heading
+func (c *tokenChunker) split(text, heading string) []Chunk {
+	units := c.splitUnits(text)
+
+	var chunks []Chunk
+	for start := 0; start < len(units); {
+		// 尽量多地放入单位，直到超过块大小
+		var counter tokenCounter
+		end := start
+		for end < len(units) {
+			counter.add(units[end])
+			if counter.tokens() > c.size && end > start {
+				counter.remove(units[end])
+				break
+			}
+			end++
+		}
+
+		if chunk := newChunk(units[start:end], heading); chunk.Content != "" {
+			chunks = append(chunks, chunk)
+		}
+		if end >= len(units) {
+			break
+		}
+
+		// 下一块从末尾向前保留不超过 overlap 个 token 的单位开始，且至少前进一个单位
+		next := end
+		var overlap tokenCounter
+		for next > start+1 {
+			overlap.add(units[next-1])
+			if overlap.tokens() > c.overlap {
+				break
+			}
+			next--
+		}
+		start = next
+	}
+
+	return chunks
+}
+
+// splitUnits 将文本拆分为切分单位，超过块大小的 ASCII 单词按字符数截断
+func (c *tokenChunker) splitUnits(text string) []textUnit {
+	maxWordChars := c.size * asciiCharsPerToken
+
+	var units []textUnit
+	for len(text) > 0 {
+		r, width := utf8.DecodeRuneInString(text)
+		end := width
+		unit := textUnit{}
+
+		if r > unicode.MaxASCII && !unicode.IsSpace(r) {
+			unit.wide = 1
+		} else {
+			// 连续的非空白 ASCII 字符组成一个单词
+			for end < len(text) && unit.ascii+1 < maxWordChars {
+				next, nextWidth := utf8.DecodeRuneInString(text[end:])
+				if next > unicode.MaxASCII || unicode.IsSpace(next) || unicode.IsSpace(r) {
+					break
+				}
+				end += nextWidth
+				unit.ascii++
+			}
+			unit.ascii++
+		}
+
+		// 单位之后的空白并入该单位
+		for end < len(text) {
+			next, nextWidth := utf8.DecodeRuneInString(text[end:])
+			if !unicode.IsSpace(next) {
+				break
+			}
+			end += nextWidth
+			unit.ascii++
+		}
+
+		unit.text = text[:end]
+		units = append(units, unit)
+		text = text[end:]
+	}
+
+	return units
+}
+
+// newChunk 由连续的切分单位构建分块
+func newChunk(units []textUnit, heading string) Chunk {
+	var b strings.Builder
+	for _, u := range units {
+		b.WriteString(u.text)
+	}
+	content := strings.TrimSpace(b.String())
+	return Chunk{
+		Content: content,
+		Heading: heading,
+		Tokens:  tokenizer.Estimate(content),
+	}
+}
+
+// markdownChunker 按 Markdown 标题切分，每个章节为一块，超过块大小的章节再按 token 数切分
+type markdownChunker struct {
+	tokens *tokenChunker
+}
+
+// markdownSection Markdown 章节
+type markdownSection struct {
+	heading string
+	body    strings.Builder
+	hasText bool
+}
+
+// Split 将文档内容切分为分块
+// 分块内容以章节标题开头，标题路径由各级标题组成；代码块中以 # 开头的行不视为标题
+func (c *markdownChunker) Split(text string) []Chunk {
+	var sections []*markdownSection
+	current := &markdownSection{}
+	sections = append(sections, current)
+
+	var headings []string
+	fence := ""
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if fence == "" {
+			if level, title := parseHeading(trimmed); level > 0 {
+				// 标题层级：同级或更高级的标题结束之前的子章节
+				if level <= len(headings) {
+					headings = headings[:level-1]
+				}
+				for len(headings) < level-1 {
+					headings = append(headings, "")
+				}
+				headings = append(headings, title)
+
+				current = &markdownSection{heading: joinHeadings(headings)}
+				sections = append(sections, current)
+				current.body.WriteString(line + "\n")
+				continue
+			}
+		}
+
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			if fence == "" {
+				fence = trimmed[:3]
+			} else if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		}
+
+		current.body.WriteString(line + "\n")
+		if trimmed != "" {
+			current.hasText = true
+		}
+	}
+
+	var chunks []Chunk
+	for _, section := range sections {
+		// 只有标题没有正文的章节由其子章节的标题路径体现
+		if !section.hasText {
+			continue
+		}
+
+		content := strings.TrimSpace(section.body.String())
+		tokens := tokenizer.Estimate(content)
+		if tokens <= c.tokens.size {
+			chunks = append(chunks, Chunk{Content: content, Heading: section.heading, Tokens: tokens})
+			continue
+		}
+		chunks = append(chunks, c.tokens.split(content, section.heading)...)
+	}
+
+	return chunks
+}
+
+// parseHeading 解析 ATX 风格的 Markdown 标题，返回层级（1-6）和标题文本，不是标题时层级为 0
+func parseHeading(line string) (int, string) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0, ""
+	}
+	if level < len(line) && line[level] != ' ' && line[level] != '\t' {
+		return 0, ""
+	}
+
+	title := strings.TrimSpace(strings.TrimRight(strings.TrimSpace(line[level:]), "#"))
+	if title == "" {
+		return 0, ""
+	}
+	return level, title
+}
+
+// joinHeadings 将各级标题连接为标题路径，跳过缺失的层级
+func joinHeadings(headings []string) string {
+	parts := make([]string, 0, len(headings))
+	for _, heading := range headings {
+		if heading != "" {
+			parts = append(parts, heading)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
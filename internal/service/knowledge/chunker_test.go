@@ -0,0 +1,131 @@
+package knowledge
+
+import (
+	"strings"
+	"testing"
+
+	"genkit-ai-service/internal/model"
+)
+
+func TestNewChunker_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		size     int
+		overlap  int
+	}{
+		{name: "块大小为0", strategy: model.ChunkStrategyTokens, size: 0},
+		{name: "重叠不小于块大小", strategy: model.ChunkStrategyTokens, size: 10, overlap: 10},
+		{name: "重叠为负数", strategy: model.ChunkStrategyTokens, size: 10, overlap: -1},
+		{name: "未知策略", strategy: "sentences", size: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewChunker(tt.strategy, tt.size, tt.overlap); err == nil {
+				t.Error("期望返回错误")
+			}
+		})
+	}
+}
+
+func TestTokenChunker(t *testing.T) {
+	// 每个单词 4 个字符加一个空格，约 1.25 个 token
+	words := make([]string, 40)
+	for i := range words {
+		words[i] = string(rune('a'+i%26)) + "abc"
+	}
+	text := strings.Join(words, " ")
+
+	chunker, err := NewChunker(model.ChunkStrategyTokens, 10, 3)
+	if err != nil {
+		t.Fatalf("创建分块器失败: %v", err)
+	}
+	chunks := chunker.Split(text)
+
+	if len(chunks) < 2 {
+		t.Fatalf("期望切分为多个块, 得到 %d", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if chunk.Tokens > 10 {
+			t.Errorf("第 %d 块超过块大小: %d", i, chunk.Tokens)
+		}
+		if i > 0 {
+			// 重叠 3 个 token：下一块以上一块末尾的两个单词开头
+			prev := strings.Fields(chunks[i-1].Content)
+			if !strings.HasSuffix(chunks[i-1].Content, strings.Fields(chunk.Content)[0]+" "+prev[len(prev)-1]) {
+				t.Errorf("第 %d 块没有与上一块重叠: %q / %q", i, chunks[i-1].Content, chunk.Content)
+			}
+		}
+	}
+	if last := chunks[len(chunks)-1].Content; !strings.HasSuffix(last, words[len(words)-1]) {
+		t.Errorf("最后一块应包含文档末尾: %q", last)
+	}
+
+	t.Run("中文按字切分", func(t *testing.T) {
+		chunker, _ := NewChunker(model.ChunkStrategyTokens, 4, 0)
+		chunks := chunker.Split("一二三四五六七八九")
+		got := make([]string, len(chunks))
+		for i, chunk := range chunks {
+			got[i] = chunk.Content
+		}
+		if strings.Join(got, "|") != "一二三四|五六七八|九" {
+			t.Errorf("切分结果不正确: %v", got)
+		}
+	})
+
+	t.Run("空白文档", func(t *testing.T) {
+		if chunks := chunker.Split(" \n\t "); len(chunks) != 0 {
+			t.Errorf("期望没有分块, 得到 %v", chunks)
+		}
+	})
+}
+
+func TestMarkdownChunker(t *testing.T) {
+	doc := strings.Join([]string{
+		"部署前请阅读本文。",
+		"# 部署",
+		"## 配置",
+		"设置 DATABASE_HOST。",
+		"```bash",
+		"# 这不是标题",
+		"export DATABASE_HOST=db",
+		"```",
+		"## 启动",
+		"运行 ./server 启动服务。",
+		"# 运维",
+		"查看日志。",
+	}, "\n")
+
+	chunker, err := NewChunker(model.ChunkStrategyMarkdown, 512, 0)
+	if err != nil {
+		t.Fatalf("创建分块器失败: %v", err)
+	}
+	chunks := chunker.Split(doc)
+
+	wantHeadings := []string{"", "部署 > 配置", "部署 > 启动", "运维"}
+	if len(chunks) != len(wantHeadings) {
+		t.Fatalf("期望 %d 个分块, 得到 %d: %+v", len(wantHeadings), len(chunks), chunks)
+	}
+	for i, want := range wantHeadings {
+		if chunks[i].Heading != want {
+			t.Errorf("第 %d 块的标题路径期望 %q, 得到 %q", i, want, chunks[i].Heading)
+		}
+	}
+	if !strings.HasPrefix(chunks[1].Content, "## 配置") || !strings.Contains(chunks[1].Content, "# 这不是标题") {
+		t.Errorf("章节内容不正确: %q", chunks[1].Content)
+	}
+
+	t.Run("超过块大小的章节按 token 数切分", func(t *testing.T) {
+		chunker, _ := NewChunker(model.ChunkStrategyMarkdown, 8, 0)
+		chunks := chunker.Split("# 长章节\n" + strings.Repeat("内容", 10))
+		if len(chunks) < 2 {
+			t.Fatalf("期望切分为多个块, 得到 %d", len(chunks))
+		}
+		for _, chunk := range chunks {
+			if chunk.Heading != "长章节" || chunk.Tokens > 8 {
+				t.Errorf("分块不正确: %+v", chunk)
+			}
+		}
+	})
+}
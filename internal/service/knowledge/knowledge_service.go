@@ -0,0 +1,541 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"genkit-ai-service/internal/logger"
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/repository"
+	"genkit-ai-service/internal/service/ai"
+	"genkit-ai-service/pkg/errors"
+	"genkit-ai-service/pkg/tokenizer"
+
+	"github.com/google/uuid"
+)
+
+// 创建知识库时未指定分块参数使用的默认值
+const (
+	// defaultChunkSize 默认每块的最大 token 数，超过向量模型的上下文大小时取上下文大小
+	defaultChunkSize = 512
+	// defaultOverlapRatio 默认的分块重叠为块大小的 1/8
+	defaultOverlapRatio = 8
+)
+
+// rerankCandidateMultiplier 启用重排序时向量检索的候选数量为 topK 的倍数
+const rerankCandidateMultiplier = 4
+
+// KnowledgeService 知识库业务逻辑接口
+type KnowledgeService interface {
+	// CreateKnowledgeBase 创建知识库，向量模型必须是目录中的 text_embedding 模型
+	CreateKnowledgeBase(ctx context.Context, userID string, req *model.CreateKnowledgeBaseRequest) (*model.KnowledgeBase, error)
+
+	// ListKnowledgeBases 获取用户的知识库列表
+	ListKnowledgeBases(ctx context.Context, userID string) ([]*model.KnowledgeBase, error)
+
+	// GetKnowledgeBase 获取用户拥有的知识库
+	GetKnowledgeBase(ctx context.Context, knowledgeBaseID, userID string) (*model.KnowledgeBase, error)
+
+	// DeleteKnowledgeBase 删除用户拥有的知识库及其文档和分块
+	DeleteKnowledgeBase(ctx context.Context, knowledgeBaseID, userID string) error
+
+	// AddDocument 向知识库添加文档：按知识库的分块策略切分内容，生成向量后保存
+	AddDocument(ctx context.Context, knowledgeBaseID, userID string, req *model.AddDocumentRequest) (*model.KnowledgeDocument, error)
+
+	// ListDocuments 获取知识库的文档列表
+	ListDocuments(ctx context.Context, knowledgeBaseID, userID string) ([]*model.KnowledgeDocument, error)
+
+	// DeleteDocument 删除知识库中的文档及其分块
+	DeleteDocument(ctx context.Context, knowledgeBaseID, documentID, userID string) error
+
+	// Search 在用户的知识库中检索与查询最相关的分块，按相似度降序返回
+	// 配置了重排序模型时先按向量召回更多候选，再按重排序得分降序返回
+	// 不存在或不属于该用户的知识库被跳过；topK 不大于 0 时使用服务配置的数量
+	Search(ctx context.Context, userID string, knowledgeBaseIDs []string, query string, topK int) ([]model.Citation, error)
+}
+
+// ModelCatalog 模型目录查询接口，用于校验知识库的向量模型
+type ModelCatalog interface {
+	// ResolveModel 根据模型名称查找模型及其所属提供商ID
+	ResolveModel(modelName string) (string, *model.Model, error)
+}
+
+// FileReader 上传文件的读取接口，用于从上传文件添加文档
+type FileReader interface {
+	// GetFile 获取用户拥有的文件元数据
+	GetFile(ctx context.Context, fileID, userID string) (*model.File, error)
+
+	// ReadFile 读取文件元数据和全部内容，不检查文件归属
+	ReadFile(ctx context.Context, fileID string) (*model.File, []byte, error)
+}
+
+// knowledgeService 知识库业务逻辑实现
+type knowledgeService struct {
+	repo        repository.KnowledgeRepository
+	embeddings  ai.EmbeddingService
+	catalog     ModelCatalog
+	files       FileReader
+	topK        int
+	reranker    ai.RerankService
+	rerankModel string
+	logger      logger.Logger
+}
+
+// NewKnowledgeService 创建知识库服务实例
+// topK 为检索时默认返回的分块数量；files 为 nil 时不支持从上传文件添加文档；
+// reranker 为 nil 或 rerankModel 为空时检索结果只按向量相似度排序
+func NewKnowledgeService(
+	repo repository.KnowledgeRepository,
+	embeddings ai.EmbeddingService,
+	catalog ModelCatalog,
+	files FileReader,
+	topK int,
+	reranker ai.RerankService,
+	rerankModel string,
+	log logger.Logger,
+) KnowledgeService {
+	return &knowledgeService{
+		repo:        repo,
+		embeddings:  embeddings,
+		catalog:     catalog,
+		files:       files,
+		topK:        topK,
+		reranker:    reranker,
+		rerankModel: rerankModel,
+		logger:      log,
+	}
+}
+
+// logInfo 安全地记录信息日志
+func (s *knowledgeService) logInfo(ctx context.Context, msg string, fields logger.Fields) {
+	if s.logger != nil {
+		s.logger.InfoContext(ctx, msg, fields)
+	}
+}
+
+// logWarn 安全地记录警告日志
+func (s *knowledgeService) logWarn(ctx context.Context, msg string, fields logger.Fields) {
+	if s.logger != nil {
+		s.logger.WarnContext(ctx, msg, fields)
+	}
+}
+
+// CreateKnowledgeBase 创建知识库
+func (s *knowledgeService) CreateKnowledgeBase(ctx context.Context, userID string, req *model.CreateKnowledgeBaseRequest) (*model.KnowledgeBase, error) {
+	// 1. 校验向量模型
+	_, mdl, err := s.catalog.ResolveModel(req.EmbeddingModel)
+	if err != nil {
+		return nil, err
+	}
+	if mdl.ModelType != model.ModelTypeTextEmbedding {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("模型 '%s' 不是向量模型", req.EmbeddingModel))
+	}
+	contextSize := mdl.ModelProperties.ContextSize
+
+	// 2. 确定分块参数
+	strategy := req.ChunkStrategy
+	if strategy == "" {
+		strategy = model.ChunkStrategyTokens
+	}
+
+	size := req.ChunkSize
+	if size == 0 {
+		size = defaultChunkSize
+		if contextSize > 0 && size > contextSize {
+			size = contextSize
+		}
+	} else if contextSize > 0 && size > contextSize {
+		return nil, errors.NewBadRequestError(fmt.Sprintf(
+			"分块大小 %d 超过模型 '%s' 的上下文大小 %d", size, req.EmbeddingModel, contextSize))
+	}
+
+	overlap := size / defaultOverlapRatio
+	if req.ChunkOverlap != nil {
+		overlap = *req.ChunkOverlap
+	}
+
+	if _, err := NewChunker(strategy, size, overlap); err != nil {
+		return nil, errors.NewValidationError(err.Error())
+	}
+
+	// 3. 保存知识库
+	now := time.Now()
+	kb := &model.KnowledgeBase{
+		UserID:         userID,
+		Name:           req.Name,
+		Description:    req.Description,
+		EmbeddingModel: req.EmbeddingModel,
+		ChunkStrategy:  strategy,
+		ChunkSize:      size,
+		ChunkOverlap:   overlap,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := s.repo.CreateBase(ctx, kb); err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+
+	s.logInfo(ctx, "知识库已创建", logger.Fields{
+		"knowledgeBaseId": kb.ID,
+		"userId":          userID,
+		"embeddingModel":  kb.EmbeddingModel,
+		"chunkStrategy":   kb.ChunkStrategy,
+	})
+
+	return kb, nil
+}
+
+// ListKnowledgeBases 获取用户的知识库列表
+func (s *knowledgeService) ListKnowledgeBases(ctx context.Context, userID string) ([]*model.KnowledgeBase, error) {
+	bases, err := s.repo.ListBases(ctx, userID)
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+	return bases, nil
+}
+
+// GetKnowledgeBase 获取用户拥有的知识库，知识库ID不是有效的 UUID 时视为不存在
+func (s *knowledgeService) GetKnowledgeBase(ctx context.Context, knowledgeBaseID, userID string) (*model.KnowledgeBase, error) {
+	if _, err := uuid.Parse(knowledgeBaseID); err != nil {
+		return nil, errors.NewKnowledgeBaseNotFoundError(knowledgeBaseID)
+	}
+
+	kb, err := s.repo.GetBase(ctx, knowledgeBaseID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, errors.NewKnowledgeBaseNotFoundError(knowledgeBaseID)
+		}
+		return nil, errors.NewInternalError(err)
+	}
+
+	if kb.UserID != userID {
+		s.logWarn(ctx, "用户无权访问知识库", logger.Fields{
+			"knowledgeBaseId": knowledgeBaseID,
+			"userId":          userID,
+			"owner":           kb.UserID,
+		})
+		return nil, errors.NewKnowledgeBaseAccessDeniedError()
+	}
+
+	return kb, nil
+}
+
+// DeleteKnowledgeBase 删除用户拥有的知识库及其文档和分块
+func (s *knowledgeService) DeleteKnowledgeBase(ctx context.Context, knowledgeBaseID, userID string) error {
+	kb, err := s.GetKnowledgeBase(ctx, knowledgeBaseID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteBase(ctx, kb.ID); err != nil {
+		return errors.NewInternalError(err)
+	}
+
+	s.logInfo(ctx, "知识库已删除", logger.Fields{
+		"knowledgeBaseId": kb.ID,
+		"userId":          userID,
+	})
+
+	return nil
+}
+
+// AddDocument 向知识库添加文档
+func (s *knowledgeService) AddDocument(ctx context.Context, knowledgeBaseID, userID string, req *model.AddDocumentRequest) (*model.KnowledgeDocument, error) {
+	startTime := time.Now()
+
+	kb, err := s.GetKnowledgeBase(ctx, knowledgeBaseID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. 确定文档名称和内容
+	doc := &model.KnowledgeDocument{
+		KnowledgeBaseID: kb.ID,
+		Name:            req.Name,
+		Content:         req.Content,
+		CreatedAt:       time.Now(),
+	}
+	if req.FileID != "" {
+		file, content, err := s.readTextFile(ctx, req.FileID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if doc.Name == "" {
+			doc.Name = file.Name
+		}
+		doc.FileID = &file.ID
+		doc.Content = content
+	}
+	if doc.Name == "" {
+		return nil, errors.NewValidationError("文档名称不能为空")
+	}
+
+	// 2. 按知识库的分块策略切分
+	chunker, err := NewChunker(kb.ChunkStrategy, kb.ChunkSize, kb.ChunkOverlap)
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+	parts := chunker.Split(doc.Content)
+	if len(parts) == 0 {
+		return nil, errors.NewBadRequestError("文档内容为空")
+	}
+
+	// 3. 为每个分块生成向量，带标题路径的分块连同标题一起向量化
+	inputs := make([]string, len(parts))
+	for i, part := range parts {
+		inputs[i] = embeddingInput(part.Heading, part.Content)
+	}
+	embedded, err := s.embeddings.Embed(ctx, &model.EmbeddingRequest{
+		Model: kb.EmbeddingModel,
+		Input: inputs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make([]*model.KnowledgeChunk, len(parts))
+	for i, part := range parts {
+		chunks[i] = &model.KnowledgeChunk{
+			Position:  i,
+			Heading:   part.Heading,
+			Content:   part.Content,
+			Tokens:    part.Tokens,
+			CreatedAt: doc.CreatedAt,
+		}
+	}
+	for _, data := range embedded.Data {
+		chunks[data.Index].Embedding = data.Embedding
+	}
+
+	// 4. 保存文档和分块
+	doc.ChunkCount = len(chunks)
+	doc.Tokens = tokenizer.Estimate(doc.Content)
+	if err := s.repo.CreateDocument(ctx, doc, chunks); err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+
+	s.logInfo(ctx, "知识库文档已添加", logger.Fields{
+		"knowledgeBaseId": kb.ID,
+		"documentId":      doc.ID,
+		"chunks":          doc.ChunkCount,
+		"tokens":          doc.Tokens,
+		"duration":        time.Since(startTime).Milliseconds(),
+	})
+
+	return doc, nil
+}
+
+// readTextFile 读取用户拥有的文本文件内容
+func (s *knowledgeService) readTextFile(ctx context.Context, fileID, userID string) (*model.File, string, error) {
+	if s.files == nil {
+		return nil, "", errors.NewServiceUnavailableError("文件服务未启用")
+	}
+
+	if _, err := s.files.GetFile(ctx, fileID, userID); err != nil {
+		return nil, "", err
+	}
+	file, data, err := s.files.ReadFile(ctx, fileID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !isTextMimeType(file.MimeType) || !utf8.Valid(data) {
+		return nil, "", errors.NewBadRequestError(fmt.Sprintf("文件 '%s' 不是文本文件", file.Name))
+	}
+
+	return file, string(data), nil
+}
+
+// isTextMimeType 判断 MIME 类型是否为可以直接作为文档内容的文本
+func isTextMimeType(mimeType string) bool {
+	mediaType, _, _ := strings.Cut(mimeType, ";")
+	mediaType = strings.TrimSpace(mediaType)
+	return strings.HasPrefix(mediaType, "text/") || mediaType == "application/json"
+}
+
+// embeddingInput 生成分块向量化和重排序的输入文本
+func embeddingInput(heading, content string) string {
+	if heading == "" {
+		return content
+	}
+	return heading + "\n\n" + content
+}
+
+// ListDocuments 获取知识库的文档列表
+func (s *knowledgeService) ListDocuments(ctx context.Context, knowledgeBaseID, userID string) ([]*model.KnowledgeDocument, error) {
+	kb, err := s.GetKnowledgeBase(ctx, knowledgeBaseID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := s.repo.ListDocuments(ctx, kb.ID)
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+	return docs, nil
+}
+
+// DeleteDocument 删除知识库中的文档及其分块
+func (s *knowledgeService) DeleteDocument(ctx context.Context, knowledgeBaseID, documentID, userID string) error {
+	kb, err := s.GetKnowledgeBase(ctx, knowledgeBaseID, userID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := uuid.Parse(documentID); err != nil {
+		return errors.NewDocumentNotFoundError(documentID)
+	}
+	doc, err := s.repo.GetDocument(ctx, documentID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return errors.NewDocumentNotFoundError(documentID)
+		}
+		return errors.NewInternalError(err)
+	}
+	if doc.KnowledgeBaseID != kb.ID {
+		return errors.NewDocumentNotFoundError(documentID)
+	}
+
+	if err := s.repo.DeleteDocument(ctx, doc); err != nil {
+		return errors.NewInternalError(err)
+	}
+
+	s.logInfo(ctx, "知识库文档已删除", logger.Fields{
+		"knowledgeBaseId": kb.ID,
+		"documentId":      doc.ID,
+	})
+
+	return nil
+}
+
+// Search 在用户的知识库中检索与查询最相关的分块
+// 不同知识库可能使用不同的向量模型：按向量模型分组，每组分别向量化查询并检索，再按相似度合并
+func (s *knowledgeService) Search(ctx context.Context, userID string, knowledgeBaseIDs []string, query string, topK int) ([]model.Citation, error) {
+	if topK <= 0 {
+		topK = s.topK
+	}
+	if strings.TrimSpace(query) == "" || topK <= 0 {
+		return nil, nil
+	}
+
+	// 1. 按向量模型对知识库分组，保持知识库的顺序以使结果稳定
+	var embeddingModels []string
+	groups := make(map[string][]string)
+	for _, knowledgeBaseID := range knowledgeBaseIDs {
+		kb, err := s.GetKnowledgeBase(ctx, knowledgeBaseID, userID)
+		if err != nil {
+			if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.CodeInternalError {
+				return nil, err
+			}
+			s.logWarn(ctx, "跳过不可用的知识库", logger.Fields{
+				"knowledgeBaseId": knowledgeBaseID,
+				"error":           err.Error(),
+			})
+			continue
+		}
+		if _, ok := groups[kb.EmbeddingModel]; !ok {
+			embeddingModels = append(embeddingModels, kb.EmbeddingModel)
+		}
+		groups[kb.EmbeddingModel] = append(groups[kb.EmbeddingModel], kb.ID)
+	}
+
+	// 启用重排序时召回更多候选分块
+	candidates := topK
+	if s.rerankEnabled() {
+		candidates = topK * rerankCandidateMultiplier
+	}
+
+	// 2. 每组向量化查询并检索
+	var matches []*repository.ChunkMatch
+	for _, embeddingModel := range embeddingModels {
+		embedded, err := s.embeddings.Embed(ctx, &model.EmbeddingRequest{
+			Model: embeddingModel,
+			Input: []string{query},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(embedded.Data) == 0 {
+			continue
+		}
+
+		found, err := s.repo.SearchChunks(ctx, groups[embeddingModel], embedded.Data[0].Embedding, candidates)
+		if err != nil {
+			return nil, errors.NewInternalError(err)
+		}
+		matches = append(matches, found...)
+	}
+
+	// 3. 合并各组结果
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if len(matches) > candidates {
+		matches = matches[:candidates]
+	}
+
+	citations := make([]model.Citation, len(matches))
+	for i, match := range matches {
+		citations[i] = model.Citation{
+			KnowledgeBaseID: match.KnowledgeBaseID,
+			DocumentID:      match.DocumentID,
+			DocumentName:    match.DocumentName,
+			ChunkID:         match.ID,
+			Position:        match.Position,
+			Heading:         match.Heading,
+			Content:         match.Content,
+			Score:           match.Score,
+		}
+	}
+
+	// 4. 按重排序得分排序，失败时退回向量相似度顺序
+	if s.rerankEnabled() && len(citations) > 0 {
+		reranked, err := s.rerank(ctx, query, citations, topK)
+		if err == nil {
+			return reranked, nil
+		}
+		s.logWarn(ctx, "知识库检索结果重排序失败，使用向量相似度排序", logger.Fields{
+			"model": s.rerankModel,
+			"error": err.Error(),
+		})
+	}
+
+	if len(citations) > topK {
+		citations = citations[:topK]
+	}
+	return citations, nil
+}
+
+// rerankEnabled 是否对检索结果重排序
+func (s *knowledgeService) rerankEnabled() bool {
+	return s.reranker != nil && s.rerankModel != ""
+}
+
+// rerank 使用重排序模型对候选分块打分，返回得分最高的 topK 个分块
+func (s *knowledgeService) rerank(ctx context.Context, query string, citations []model.Citation, topK int) ([]model.Citation, error) {
+	documents := make([]string, len(citations))
+	for i, citation := range citations {
+		documents[i] = embeddingInput(citation.Heading, citation.Content)
+	}
+
+	resp, err := s.reranker.Rerank(ctx, &model.RerankRequest{
+		Model:     s.rerankModel,
+		Query:     query,
+		Documents: documents,
+		TopN:      topK,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reranked := make([]model.Citation, 0, len(resp.Results))
+	for _, result := range resp.Results {
+		citation := citations[result.Index]
+		score := result.RelevanceScore
+		citation.RerankScore = &score
+		reranked = append(reranked, citation)
+	}
+	return reranked, nil
+}
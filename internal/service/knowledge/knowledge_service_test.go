@@ -0,0 +1,292 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/repository"
+	"genkit-ai-service/internal/service/ai"
+	"genkit-ai-service/pkg/errors"
+)
+
+// keywordEmbeddings 按关键词出现次数生成向量的测试向量化服务
+type keywordEmbeddings struct {
+	keywords []string
+	requests []*model.EmbeddingRequest
+}
+
+func (e *keywordEmbeddings) Embed(ctx context.Context, req *model.EmbeddingRequest) (*model.EmbeddingResponse, error) {
+	e.requests = append(e.requests, req)
+	resp := &model.EmbeddingResponse{Model: req.Model}
+	for i, input := range req.Input {
+		embedding := make([]float32, len(e.keywords))
+		for j, keyword := range e.keywords {
+			embedding[j] = float32(strings.Count(input, keyword))
+		}
+		resp.Data = append(resp.Data, model.EmbeddingData{Index: i, Embedding: embedding})
+	}
+	return resp, nil
+}
+
+// testCatalog 测试用模型目录
+type testCatalog map[string]*model.Model
+
+func (c testCatalog) ResolveModel(modelName string) (string, *model.Model, error) {
+	mdl, ok := c[modelName]
+	if !ok {
+		return "", nil, errors.NewModelNotFoundError(modelName)
+	}
+	return "test", mdl, nil
+}
+
+// testFiles 测试用上传文件读取
+type testFiles map[string]*testFile
+
+type testFile struct {
+	file    *model.File
+	content []byte
+}
+
+func (f testFiles) GetFile(ctx context.Context, fileID, userID string) (*model.File, error) {
+	entry, ok := f[fileID]
+	if !ok {
+		return nil, errors.NewFileNotFoundError(fileID)
+	}
+	if entry.file.UserID != userID {
+		return nil, errors.NewFileAccessDeniedError()
+	}
+	return entry.file, nil
+}
+
+func (f testFiles) ReadFile(ctx context.Context, fileID string) (*model.File, []byte, error) {
+	entry, ok := f[fileID]
+	if !ok {
+		return nil, nil, errors.NewFileNotFoundError(fileID)
+	}
+	return entry.file, entry.content, nil
+}
+
+// testReranker 测试用重排序服务，得分为文档中查询的出现次数
+type testReranker struct {
+	requests []*model.RerankRequest
+	err      error
+}
+
+func (r *testReranker) Rerank(ctx context.Context, req *model.RerankRequest) (*model.RerankResponse, error) {
+	r.requests = append(r.requests, req)
+	if r.err != nil {
+		return nil, r.err
+	}
+	resp := &model.RerankResponse{Model: req.Model}
+	for i, document := range req.Documents {
+		resp.Results = append(resp.Results, model.RerankResult{Index: i, RelevanceScore: float64(strings.Count(document, req.Query))})
+	}
+	sort.SliceStable(resp.Results, func(i, j int) bool { return resp.Results[i].RelevanceScore > resp.Results[j].RelevanceScore })
+	if len(resp.Results) > req.TopN {
+		resp.Results = resp.Results[:req.TopN]
+	}
+	return resp, nil
+}
+
+func newTestKnowledgeService(t *testing.T, files FileReader) (KnowledgeService, *keywordEmbeddings) {
+	return newTestKnowledgeServiceWithReranker(t, files, nil)
+}
+
+func newTestKnowledgeServiceWithReranker(t *testing.T, files FileReader, reranker ai.RerankService) (KnowledgeService, *keywordEmbeddings) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(&model.KnowledgeBase{}, &model.KnowledgeDocument{}, &model.KnowledgeChunk{}); err != nil {
+		t.Fatalf("迁移知识库表失败: %v", err)
+	}
+
+	embeddings := &keywordEmbeddings{keywords: []string{"数据库", "缓存", "日志"}}
+	catalog := testCatalog{
+		"embed": {Model: "embed", ModelType: "text_embedding", ModelProperties: model.ModelProperties{ContextSize: 256}},
+		"chat":  {Model: "chat", ModelType: "llm"},
+	}
+	return NewKnowledgeService(repository.NewKnowledgeRepository(db), embeddings, catalog, files, 2, reranker, "rerank", nil), embeddings
+}
+
+func assertErrorCode(t *testing.T, err error, code int) {
+	t.Helper()
+	appErr, ok := err.(*errors.AppError)
+	if !ok || appErr.Code != code {
+		t.Errorf("期望错误码 %d, 得到 %v", code, err)
+	}
+}
+
+func TestCreateKnowledgeBase(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newTestKnowledgeService(t, nil)
+
+	kb, err := service.CreateKnowledgeBase(ctx, "user-1", &model.CreateKnowledgeBaseRequest{Name: "文档", EmbeddingModel: "embed"})
+	if err != nil {
+		t.Fatalf("创建知识库失败: %v", err)
+	}
+	// 默认块大小受模型上下文大小限制，重叠为块大小的 1/8
+	if kb.ChunkStrategy != model.ChunkStrategyTokens || kb.ChunkSize != 256 || kb.ChunkOverlap != 32 {
+		t.Errorf("默认分块参数不正确: %+v", kb)
+	}
+
+	overlap := 64
+	tests := []struct {
+		name string
+		req  *model.CreateKnowledgeBaseRequest
+		code int
+	}{
+		{name: "模型不存在", req: &model.CreateKnowledgeBaseRequest{Name: "a", EmbeddingModel: "unknown"}, code: errors.CodeModelNotFound},
+		{name: "不是向量模型", req: &model.CreateKnowledgeBaseRequest{Name: "a", EmbeddingModel: "chat"}, code: errors.CodeBadRequest},
+		{name: "块大小超过上下文", req: &model.CreateKnowledgeBaseRequest{Name: "a", EmbeddingModel: "embed", ChunkSize: 512}, code: errors.CodeBadRequest},
+		{name: "重叠不小于块大小", req: &model.CreateKnowledgeBaseRequest{Name: "a", EmbeddingModel: "embed", ChunkSize: 64, ChunkOverlap: &overlap}, code: errors.CodeValidationError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.CreateKnowledgeBase(ctx, "user-1", tt.req)
+			assertErrorCode(t, err, tt.code)
+		})
+	}
+
+	t.Run("其他用户无权访问", func(t *testing.T) {
+		_, err := service.GetKnowledgeBase(ctx, kb.ID, "user-2")
+		assertErrorCode(t, err, errors.CodeKnowledgeBaseAccessDenied)
+		_, err = service.GetKnowledgeBase(ctx, "not-a-uuid", "user-1")
+		assertErrorCode(t, err, errors.CodeKnowledgeBaseNotFound)
+	})
+}
+
+func TestAddDocumentAndSearch(t *testing.T) {
+	ctx := context.Background()
+	fileID := "550e8400-e29b-41d4-a716-446655440000"
+	files := testFiles{
+		fileID:                                 {file: &model.File{ID: fileID, UserID: "user-1", Name: "运维.md", MimeType: "text/markdown; charset=utf-8"}, content: []byte("# 运维\n查看日志。")},
+		"550e8400-e29b-41d4-a716-446655440001": {file: &model.File{ID: "550e8400-e29b-41d4-a716-446655440001", UserID: "user-1", Name: "a.png", MimeType: "image/png"}, content: []byte{0x89}},
+	}
+	service, embeddings := newTestKnowledgeService(t, files)
+
+	kb, err := service.CreateKnowledgeBase(ctx, "user-1", &model.CreateKnowledgeBaseRequest{Name: "文档", EmbeddingModel: "embed", ChunkStrategy: model.ChunkStrategyMarkdown})
+	if err != nil {
+		t.Fatalf("创建知识库失败: %v", err)
+	}
+
+	doc, err := service.AddDocument(ctx, kb.ID, "user-1", &model.AddDocumentRequest{
+		Name:    "部署.md",
+		Content: "# 数据库\n配置数据库连接。\n# 缓存\n启用缓存。",
+	})
+	if err != nil {
+		t.Fatalf("添加文档失败: %v", err)
+	}
+	if doc.ChunkCount != 2 {
+		t.Errorf("期望 2 个分块, 得到 %d", doc.ChunkCount)
+	}
+	if input := embeddings.requests[len(embeddings.requests)-1].Input[0]; !strings.HasPrefix(input, "数据库\n\n# 数据库") {
+		t.Errorf("分块应连同标题路径一起向量化: %q", input)
+	}
+
+	fileDoc, err := service.AddDocument(ctx, kb.ID, "user-1", &model.AddDocumentRequest{FileID: fileID})
+	if err != nil {
+		t.Fatalf("从文件添加文档失败: %v", err)
+	}
+	if fileDoc.Name != "运维.md" || fileDoc.FileID == nil || *fileDoc.FileID != fileID {
+		t.Errorf("文件文档信息不正确: %+v", fileDoc)
+	}
+
+	t.Run("非文本文件", func(t *testing.T) {
+		_, err := service.AddDocument(ctx, kb.ID, "user-1", &model.AddDocumentRequest{FileID: "550e8400-e29b-41d4-a716-446655440001"})
+		assertErrorCode(t, err, errors.CodeBadRequest)
+	})
+
+	t.Run("空白文档", func(t *testing.T) {
+		_, err := service.AddDocument(ctx, kb.ID, "user-1", &model.AddDocumentRequest{Name: "空", Content: "  \n"})
+		assertErrorCode(t, err, errors.CodeBadRequest)
+	})
+
+	t.Run("检索", func(t *testing.T) {
+		citations, err := service.Search(ctx, "user-1", []string{kb.ID}, "缓存怎么配置", 0)
+		if err != nil {
+			t.Fatalf("检索失败: %v", err)
+		}
+		// 未指定 topK 时使用服务配置的 2
+		if len(citations) != 2 {
+			t.Fatalf("期望 2 条引用, 得到 %d", len(citations))
+		}
+		top := citations[0]
+		if top.Heading != "缓存" || top.DocumentName != "部署.md" || top.DocumentID != doc.ID || top.Score < 0.99 {
+			t.Errorf("最相关的分块不正确: %+v", top)
+		}
+	})
+
+	t.Run("跳过其他用户和不存在的知识库", func(t *testing.T) {
+		citations, err := service.Search(ctx, "user-2", []string{kb.ID, "550e8400-e29b-41d4-a716-44665544ffff"}, "缓存", 5)
+		if err != nil {
+			t.Fatalf("检索失败: %v", err)
+		}
+		if len(citations) != 0 {
+			t.Errorf("期望没有引用, 得到 %d", len(citations))
+		}
+	})
+
+	t.Run("删除文档", func(t *testing.T) {
+		err := service.DeleteDocument(ctx, kb.ID, fileDoc.ID, "user-2")
+		assertErrorCode(t, err, errors.CodeKnowledgeBaseAccessDenied)
+
+		if err := service.DeleteDocument(ctx, kb.ID, fileDoc.ID, "user-1"); err != nil {
+			t.Fatalf("删除文档失败: %v", err)
+		}
+		docs, err := service.ListDocuments(ctx, kb.ID, "user-1")
+		if err != nil || len(docs) != 1 || docs[0].ID != doc.ID {
+			t.Errorf("删除后的文档列表不正确: %v %v", docs, err)
+		}
+		err = service.DeleteDocument(ctx, kb.ID, fileDoc.ID, "user-1")
+		assertErrorCode(t, err, errors.CodeDocumentNotFound)
+	})
+}
+
+func TestSearch_Rerank(t *testing.T) {
+	ctx := context.Background()
+	reranker := &testReranker{}
+	service, _ := newTestKnowledgeServiceWithReranker(t, nil, reranker)
+
+	kb, err := service.CreateKnowledgeBase(ctx, "user-1", &model.CreateKnowledgeBaseRequest{Name: "文档", EmbeddingModel: "embed", ChunkStrategy: model.ChunkStrategyMarkdown})
+	if err != nil {
+		t.Fatalf("创建知识库失败: %v", err)
+	}
+	// 向量检索时只含“日志”的乙与查询最相似，重排序时“日志”出现次数最多的甲得分最高
+	if _, err := service.AddDocument(ctx, kb.ID, "user-1", &model.AddDocumentRequest{
+		Name:    "运维.md",
+		Content: "# 甲\n数据库 日志 日志 日志 日志\n# 乙\n日志\n# 丙\n缓存",
+	}); err != nil {
+		t.Fatalf("添加文档失败: %v", err)
+	}
+
+	citations, err := service.Search(ctx, "user-1", []string{kb.ID}, "日志", 1)
+	if err != nil {
+		t.Fatalf("检索失败: %v", err)
+	}
+	if len(reranker.requests) != 1 || len(reranker.requests[0].Documents) != 3 || reranker.requests[0].TopN != 1 {
+		t.Fatalf("应以 topK 的倍数召回候选后重排序: %+v", reranker.requests)
+	}
+	if len(citations) != 1 || citations[0].Heading != "甲" || citations[0].RerankScore == nil || *citations[0].RerankScore != 4 {
+		t.Errorf("重排序结果不正确: %+v", citations)
+	}
+
+	t.Run("重排序失败时使用向量排序", func(t *testing.T) {
+		reranker.err = fmt.Errorf("quota exceeded")
+		citations, err := service.Search(ctx, "user-1", []string{kb.ID}, "日志", 1)
+		if err != nil {
+			t.Fatalf("检索失败: %v", err)
+		}
+		if len(citations) != 1 || citations[0].Heading != "乙" || citations[0].RerankScore != nil {
+			t.Errorf("期望退回向量相似度排序: %+v", citations)
+		}
+	})
+}
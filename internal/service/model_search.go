@@ -0,0 +1,204 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/pkg/errors"
+)
+
+// maxModelPageSize 模型搜索的最大分页大小
+const maxModelPageSize = 100
+
+// modelSortFields 模型搜索支持的排序字段
+var modelSortFields = map[string]bool{
+	model.ModelSortByProvider:    true,
+	model.ModelSortByModel:       true,
+	model.ModelSortByContextSize: true,
+	model.ModelSortByInputPrice:  true,
+	model.ModelSortByOutputPrice: true,
+}
+
+// SearchModels 跨提供商搜索模型，返回当前页的模型和匹配的总数
+// 默认按提供商ID排序，同一提供商内保持模型目录中的顺序
+func (s *providerService) SearchModels(req *model.SearchModelsRequest) ([]model.ModelSearchItem, int, error) {
+	modelType, err := validateSearchModelsRequest(req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	providers := s.store.GetProviders()
+	providerIDs := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		providerIDs = append(providerIDs, provider.ID)
+	}
+	sort.Strings(providerIDs)
+
+	// 按条件过滤所有提供商的模型
+	var matched []model.ModelSearchItem
+	for _, providerID := range providerIDs {
+		models, err := s.store.GetModels(providerID)
+		if err != nil {
+			continue
+		}
+		for _, m := range models {
+			if !matchModel(&m, req, modelType) {
+				continue
+			}
+			matched = append(matched, model.ModelSearchItem{
+				Provider:      providerID,
+				ModelListItem: toModelListItem(m),
+				Deprecated:    m.Deprecated,
+			})
+		}
+	}
+
+	sortModelSearchItems(matched, req.SortBy, req.Order == "desc")
+
+	// 分页
+	total := len(matched)
+	start := (req.PageNo - 1) * req.PageSize
+	if start >= total {
+		return []model.ModelSearchItem{}, total, nil
+	}
+	end := start + req.PageSize
+	if end > total {
+		end = total
+	}
+	return matched[start:end], total, nil
+}
+
+// validateSearchModelsRequest 验证模型搜索请求，返回规范形式的模型类型（未指定时为空）
+func validateSearchModelsRequest(req *model.SearchModelsRequest) (model.ModelType, error) {
+	if req.PageNo < 1 {
+		return "", errors.NewValidationError("pageNo 必须大于等于1")
+	}
+	if req.PageSize < 1 || req.PageSize > maxModelPageSize {
+		return "", errors.NewValidationError(fmt.Sprintf("pageSize 必须在1到%d之间", maxModelPageSize))
+	}
+	if req.SortBy != "" && !modelSortFields[req.SortBy] {
+		return "", errors.NewValidationError(fmt.Sprintf("不支持的排序字段 '%s'", req.SortBy))
+	}
+	if req.Order != "" && req.Order != "asc" && req.Order != "desc" {
+		return "", errors.NewValidationError("order 必须是 asc 或 desc")
+	}
+	if req.MinContextSize < 0 {
+		return "", errors.NewValidationError("min_context_size 不能为负数")
+	}
+	if (req.MaxInputPrice != nil && *req.MaxInputPrice < 0) || (req.MaxOutputPrice != nil && *req.MaxOutputPrice < 0) {
+		return "", errors.NewValidationError("价格不能为负数")
+	}
+
+	if req.ModelType == "" {
+		return "", nil
+	}
+	modelType, ok := model.ParseModelType(req.ModelType)
+	if !ok {
+		return "", errors.NewValidationError(fmt.Sprintf("未知的模型类型 '%s'", req.ModelType))
+	}
+	return modelType, nil
+}
+
+// matchModel 判断模型是否满足全部搜索条件
+// 指定最高价格时，没有价格的模型不匹配
+func matchModel(m *model.Model, req *model.SearchModelsRequest, modelType model.ModelType) bool {
+	if modelType != "" && m.ModelType != modelType {
+		return false
+	}
+	if req.Mode != "" && !strings.EqualFold(m.ModelProperties.Mode, req.Mode) {
+		return false
+	}
+	if req.MinContextSize > 0 && m.ModelProperties.ContextSize < req.MinContextSize {
+		return false
+	}
+	if req.Deprecated != nil && m.Deprecated != *req.Deprecated {
+		return false
+	}
+	if req.Currency != "" && !strings.EqualFold(strings.TrimSpace(m.Pricing.Currency), strings.TrimSpace(req.Currency)) {
+		return false
+	}
+	for _, feature := range req.Features {
+		if !hasFeature(m.Features, feature) {
+			return false
+		}
+	}
+	if req.MaxInputPrice != nil {
+		if price, ok := m.Pricing.InputPerMillion(); !ok || price > *req.MaxInputPrice {
+			return false
+		}
+	}
+	if req.MaxOutputPrice != nil {
+		if price, ok := m.Pricing.OutputPerMillion(); !ok || price > *req.MaxOutputPrice {
+			return false
+		}
+	}
+	if req.Keyword != "" && !matchKeyword(m, req.Keyword) {
+		return false
+	}
+	return true
+}
+
+// hasFeature 判断特性列表是否包含指定特性（不区分大小写）
+func hasFeature(features []string, feature string) bool {
+	for _, f := range features {
+		if strings.EqualFold(f, feature) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchKeyword 判断模型ID或任一语言的标签是否包含关键词（不区分大小写）
+func matchKeyword(m *model.Model, keyword string) bool {
+	keyword = strings.ToLower(keyword)
+	if strings.Contains(strings.ToLower(m.Model), keyword) {
+		return true
+	}
+	for _, label := range m.Label {
+		if strings.Contains(strings.ToLower(label), keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortModelSearchItems 按排序字段稳定排序，按价格排序时没有价格的模型总是排在最后
+func sortModelSearchItems(items []model.ModelSearchItem, sortBy string, desc bool) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := &items[i], &items[j]
+		var cmp int
+		switch sortBy {
+		case model.ModelSortByModel:
+			cmp = strings.Compare(a.Model, b.Model)
+		case model.ModelSortByContextSize:
+			cmp = a.ModelProperties.ContextSize - b.ModelProperties.ContextSize
+		case model.ModelSortByInputPrice, model.ModelSortByOutputPrice:
+			priceA, okA := searchItemPrice(a, sortBy)
+			priceB, okB := searchItemPrice(b, sortBy)
+			if okA != okB {
+				return okA
+			}
+			if priceA < priceB {
+				cmp = -1
+			} else if priceA > priceB {
+				cmp = 1
+			}
+		default:
+			cmp = strings.Compare(a.Provider, b.Provider)
+		}
+		if desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+}
+
+// searchItemPrice 返回排序使用的每百万 token 价格
+func searchItemPrice(item *model.ModelSearchItem, sortBy string) (float64, bool) {
+	if sortBy == model.ModelSortByInputPrice {
+		return item.Pricing.InputPerMillion()
+	}
+	return item.Pricing.OutputPerMillion()
+}
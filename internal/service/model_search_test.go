@@ -0,0 +1,192 @@
+package service
+
+import (
+	stderrors "errors"
+	"strings"
+	"testing"
+
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/storage"
+	"genkit-ai-service/pkg/errors"
+)
+
+// newSearchProviderService 创建包含多个提供商模型的提供商服务
+func newSearchProviderService() ProviderService {
+	store := storage.NewMemoryStore()
+	store.SetProviders([]model.Provider{{ID: "tongyi"}, {ID: "gemini"}})
+	store.SetModels("gemini", []model.Model{
+		{
+			Model:           "gemini-2.5-pro",
+			Label:           map[string]string{"en_US": "Gemini 2.5 Pro"},
+			ModelType:       model.ModelTypeLLM,
+			Features:        []string{"vision", "tool-call"},
+			ModelProperties: model.ModelProperties{Mode: "chat", ContextSize: 1048576},
+			Pricing:         model.Pricing{Input: "1.25", Output: "10", Unit: "0.000001", Currency: "USD"},
+		},
+		{
+			Model:           "gemini-1.5-flash",
+			Label:           map[string]string{"en_US": "Gemini 1.5 Flash"},
+			ModelType:       model.ModelTypeLLM,
+			Features:        []string{"vision"},
+			ModelProperties: model.ModelProperties{Mode: "chat", ContextSize: 1048576},
+			Pricing:         model.Pricing{Input: "0.075", Output: "0.3", Unit: "0.000001", Currency: "USD"},
+			Deprecated:      true,
+		},
+		{
+			Model:     "text-embedding-004",
+			ModelType: model.ModelTypeTextEmbedding,
+		},
+	})
+	store.SetModels("tongyi", []model.Model{
+		{
+			Model:           "qwen-plus",
+			Label:           map[string]string{"zh_Hans": "通义千问 Plus"},
+			ModelType:       model.ModelTypeLLM,
+			Features:        []string{"tool-call"},
+			ModelProperties: model.ModelProperties{Mode: "chat", ContextSize: 131072},
+			Pricing:         model.Pricing{Input: "0.0008", Output: "0.002", Unit: "0.001", Currency: "RMB "},
+		},
+		{
+			Model:           "qwen-long",
+			Label:           map[string]string{"zh_Hans": "通义千问 Long"},
+			ModelType:       model.ModelTypeLLM,
+			ModelProperties: model.ModelProperties{Mode: "chat", ContextSize: 10000000},
+		},
+	})
+	return NewProviderService(store)
+}
+
+// modelIDs 返回搜索结果的 provider/model 列表
+func modelIDs(items []model.ModelSearchItem) string {
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.Provider+"/"+item.Model)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestSearchModels(t *testing.T) {
+	service := newSearchProviderService()
+	price := func(v float64) *float64 { return &v }
+	deprecated := false
+
+	tests := []struct {
+		name      string
+		req       model.SearchModelsRequest
+		want      string
+		wantTotal int
+	}{
+		{
+			name:      "默认按提供商排序",
+			req:       model.SearchModelsRequest{},
+			want:      "gemini/gemini-2.5-pro,gemini/gemini-1.5-flash,gemini/text-embedding-004,tongyi/qwen-plus,tongyi/qwen-long",
+			wantTotal: 5,
+		},
+		{
+			name:      "模型类型使用连字符形式",
+			req:       model.SearchModelsRequest{ModelType: "text-embedding"},
+			want:      "gemini/text-embedding-004",
+			wantTotal: 1,
+		},
+		{
+			name:      "具有全部特性",
+			req:       model.SearchModelsRequest{Features: []string{"vision", "tool-call"}},
+			want:      "gemini/gemini-2.5-pro",
+			wantTotal: 1,
+		},
+		{
+			name:      "关键词匹配标签",
+			req:       model.SearchModelsRequest{Keyword: "千问"},
+			want:      "tongyi/qwen-plus,tongyi/qwen-long",
+			wantTotal: 2,
+		},
+		{
+			name:      "最小上下文大小并按上下文降序",
+			req:       model.SearchModelsRequest{Mode: "chat", MinContextSize: 1000000, SortBy: model.ModelSortByContextSize, Order: "desc"},
+			want:      "tongyi/qwen-long,gemini/gemini-2.5-pro,gemini/gemini-1.5-flash",
+			wantTotal: 3,
+		},
+		{
+			name:      "排除已弃用",
+			req:       model.SearchModelsRequest{ModelType: "llm", Deprecated: &deprecated, Keyword: "gemini"},
+			want:      "gemini/gemini-2.5-pro",
+			wantTotal: 1,
+		},
+		{
+			name:      "最高价格按每百万 token 计算",
+			req:       model.SearchModelsRequest{Currency: "USD", MaxInputPrice: price(1), MaxOutputPrice: price(1)},
+			want:      "gemini/gemini-1.5-flash",
+			wantTotal: 1,
+		},
+		{
+			name:      "货币不区分大小写和空白",
+			req:       model.SearchModelsRequest{Currency: "rmb", MaxInputPrice: price(1)},
+			want:      "tongyi/qwen-plus",
+			wantTotal: 1,
+		},
+		{
+			name:      "按价格排序时没有价格的模型排在最后",
+			req:       model.SearchModelsRequest{ModelType: "llm", SortBy: model.ModelSortByOutputPrice, Order: "desc"},
+			want:      "gemini/gemini-2.5-pro,tongyi/qwen-plus,gemini/gemini-1.5-flash,tongyi/qwen-long",
+			wantTotal: 4,
+		},
+		{
+			name:      "分页",
+			req:       model.SearchModelsRequest{SortBy: model.ModelSortByModel, PageNo: 2, PageSize: 2},
+			want:      "tongyi/qwen-long,tongyi/qwen-plus",
+			wantTotal: 5,
+		},
+		{
+			name:      "页码超出范围",
+			req:       model.SearchModelsRequest{PageNo: 4, PageSize: 2},
+			want:      "",
+			wantTotal: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req.PageNo == 0 {
+				req.PageNo, req.PageSize = 1, 20
+			}
+			items, total, err := service.SearchModels(&req)
+			if err != nil {
+				t.Fatalf("SearchModels() error = %v", err)
+			}
+			if got := modelIDs(items); got != tt.want {
+				t.Errorf("SearchModels() = %s, want %s", got, tt.want)
+			}
+			if total != tt.wantTotal {
+				t.Errorf("total = %d, want %d", total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestSearchModels_Validation(t *testing.T) {
+	service := newSearchProviderService()
+	negative := -1.0
+
+	tests := []struct {
+		name string
+		req  model.SearchModelsRequest
+	}{
+		{name: "页码无效", req: model.SearchModelsRequest{PageNo: 0, PageSize: 20}},
+		{name: "每页大小超出范围", req: model.SearchModelsRequest{PageNo: 1, PageSize: 101}},
+		{name: "未知的模型类型", req: model.SearchModelsRequest{PageNo: 1, PageSize: 20, ModelType: "vision"}},
+		{name: "不支持的排序字段", req: model.SearchModelsRequest{PageNo: 1, PageSize: 20, SortBy: "label"}},
+		{name: "排序方向无效", req: model.SearchModelsRequest{PageNo: 1, PageSize: 20, Order: "up"}},
+		{name: "价格为负数", req: model.SearchModelsRequest{PageNo: 1, PageSize: 20, MaxInputPrice: &negative}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := service.SearchModels(&tt.req)
+			var appErr *errors.AppError
+			if !stderrors.As(err, &appErr) || appErr.Code != errors.CodeValidationError {
+				t.Errorf("期望验证错误, 得到 %v", err)
+			}
+		})
+	}
+}
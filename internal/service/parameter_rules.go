@@ -0,0 +1,233 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/pkg/errors"
+	"genkit-ai-service/pkg/jsonschema"
+	"genkit-ai-service/pkg/validator"
+)
+
+// chatOptionFields 参数规则（模板名或参数名）与对话参数字段的对应关系
+var chatOptionFields = map[string]string{
+	"temperature": "temperature",
+	"top_p":       "topP",
+	"top_k":       "topK",
+	"max_tokens":  "maxTokens",
+}
+
+// ResolveParameterRules 展开参数规则中的 use_template 引用
+// 模型 YAML 中显式声明的字段优先，未声明的字段取自模板；返回新的规则列表，不修改传入的规则
+func ResolveParameterRules(rules []model.ParameterRule) []model.ParameterRule {
+	resolved := make([]model.ParameterRule, 0, len(rules))
+	for _, rule := range rules {
+		template, ok := model.ParameterTemplates[rule.UseTemplate]
+		if !ok {
+			resolved = append(resolved, rule)
+			continue
+		}
+
+		if rule.Label == nil {
+			rule.Label = template.Label
+		}
+		if rule.Type == "" {
+			rule.Type = template.Type
+		}
+		if rule.Default == nil {
+			rule.Default = template.Default
+		}
+		if rule.Min == nil {
+			rule.Min = template.Min
+		}
+		if rule.Max == nil {
+			rule.Max = template.Max
+		}
+		if rule.Options == nil {
+			rule.Options = template.Options
+		}
+
+		resolved = append(resolved, rule)
+	}
+	return resolved
+}
+
+// ParameterRuleEngine 模型参数规则引擎
+type ParameterRuleEngine interface {
+	// Apply 按模型参数规则校验对话参数，并为未指定的参数填充模型默认值
+	// 只执行对应对话参数字段（temperature、top_p、top_k、max_tokens）的数值规则：校验 min/max 并填充默认值；
+	// string、select 等类型的规则及其 options 仅用于模型参数展示，对话参数中没有对应字段，不做校验。
+	// modelName 为空时使用默认模型；返回新的参数对象，不修改传入的 options。
+	// 参数超出模型允许范围或 JSON Schema 无效时返回 422 应用错误，原始错误为 validator.FieldErrors；
+	// 模型声明了 json_schema 参数规则时设置 NativeJSONSchema
+	Apply(modelName string, options *model.ChatOptions) (*model.ChatOptions, error)
+}
+
+// parameterRuleEngine 参数规则引擎实现
+type parameterRuleEngine struct {
+	providerService ProviderService
+	defaultModel    string
+}
+
+// NewParameterRuleEngine 创建参数规则引擎
+// 参数:
+//
+//	providerService: 提供商服务，用于查找模型的参数规则
+//	defaultModel: 请求未指定模型时使用的默认模型名称
+func NewParameterRuleEngine(providerService ProviderService, defaultModel string) ParameterRuleEngine {
+	return &parameterRuleEngine{
+		providerService: providerService,
+		defaultModel:    defaultModel,
+	}
+}
+
+// Apply 按模型参数规则校验对话参数并填充默认值
+func (e *parameterRuleEngine) Apply(modelName string, options *model.ChatOptions) (*model.ChatOptions, error) {
+	if options != nil && options.JSONSchema != nil {
+		if err := jsonschema.Check(options.JSONSchema); err != nil {
+			return nil, errors.Wrap(errors.CodeValidationError, errors.MsgValidationError, validator.FieldErrors{{
+				Field:   "jsonSchema",
+				Message: fmt.Sprintf("JSON Schema 无效: %v", err),
+			}})
+		}
+	}
+
+	name := modelName
+	if name == "" {
+		name = e.defaultModel
+	}
+	if name == "" {
+		return options, nil
+	}
+
+	_, m, err := e.providerService.ResolveModel(name)
+	if err != nil {
+		// 默认模型不在模型目录中时不做校验
+		if modelName == "" {
+			return options, nil
+		}
+		return nil, err
+	}
+
+	result, fieldErrors := ApplyParameterRules(m, options)
+	for i := range fieldErrors {
+		fieldErrors[i].Message = fmt.Sprintf("模型 '%s' 的 %s", name, fieldErrors[i].Message)
+	}
+
+	if len(fieldErrors) > 0 {
+		return nil, errors.Wrap(errors.CodeValidationError, errors.MsgValidationError, fieldErrors)
+	}
+
+	return result, nil
+}
+
+// ApplyParameterRules 按模型的参数规则校验对话参数并填充默认值
+// 规则展开 use_template 后按模板名（未引用模板时按参数名）对应到对话参数字段；
+// 返回新的参数对象和校验错误，有校验错误时参数对象中未出错的字段仍已填充默认值，不修改传入的 options
+func ApplyParameterRules(m *model.Model, options *model.ChatOptions) (*model.ChatOptions, validator.FieldErrors) {
+	result := &model.ChatOptions{}
+	if options != nil {
+		*result = *options
+	}
+
+	var fieldErrors validator.FieldErrors
+	for _, rule := range ResolveParameterRules(m.ParameterRules) {
+		key := rule.UseTemplate
+		if key == "" {
+			key = rule.Name
+		}
+		// 声明了 json_schema 参数的模型原生支持按 Schema 输出
+		if key == "json_schema" && result.JSONSchema != nil {
+			result.NativeJSONSchema = true
+		}
+		field, ok := chatOptionFields[key]
+		if !ok {
+			continue
+		}
+
+		if fieldErr := applyRule(result, field, rule); fieldErr != nil {
+			fieldErrors = append(fieldErrors, *fieldErr)
+		}
+	}
+
+	return result, fieldErrors
+}
+
+// applyRule 对单个数值参数应用规则：已指定时校验 min/max 范围，未指定时填充默认值
+func applyRule(options *model.ChatOptions, field string, rule model.ParameterRule) *validator.ValidationError {
+	var value *float64
+	switch field {
+	case "temperature":
+		value = options.Temperature
+	case "topP":
+		value = options.TopP
+	case "maxTokens":
+		if options.MaxTokens != nil {
+			v := float64(*options.MaxTokens)
+			value = &v
+		}
+	case "topK":
+		if options.TopK != nil {
+			v := float64(*options.TopK)
+			value = &v
+		}
+	}
+
+	if value == nil {
+		if defaultValue, ok := toFloat(rule.Default); ok {
+			setChatOption(options, field, defaultValue)
+		}
+		return nil
+	}
+
+	if min, ok := toFloat(rule.Min); ok && *value < min {
+		return &validator.ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("%s 必须大于或等于 %v", field, min),
+		}
+	}
+	if max, ok := toFloat(rule.Max); ok && *value > max {
+		return &validator.ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("%s 必须小于或等于 %v", field, max),
+		}
+	}
+
+	return nil
+}
+
+// setChatOption 设置对话参数字段的值
+func setChatOption(options *model.ChatOptions, field string, value float64) {
+	switch field {
+	case "temperature":
+		options.Temperature = &value
+	case "topP":
+		options.TopP = &value
+	case "maxTokens":
+		v := int(value)
+		options.MaxTokens = &v
+	case "topK":
+		v := int(value)
+		options.TopK = &v
+	}
+}
+
+// toFloat 将 YAML 中的数值转换为 float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"testing"
+
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/storage"
+	"genkit-ai-service/pkg/errors"
+	"genkit-ai-service/pkg/validator"
+)
+
+// newTestProviderService 创建包含测试模型的提供商服务
+func newTestProviderService() ProviderService {
+	store := storage.NewMemoryStore()
+	store.SetProviders([]model.Provider{{ID: "gemini", Provider: "gemini"}})
+	store.SetModels("gemini", []model.Model{
+		{
+			Model:     "gemini-1.5-flash-001",
+			ModelType: "llm",
+			ParameterRules: []model.ParameterRule{
+				{Name: "temperature", UseTemplate: "temperature"},
+				{Name: "top_p", UseTemplate: "top_p"},
+				{Name: "top_k", Type: "int"},
+				{Name: "max_output_tokens", UseTemplate: "max_tokens", Default: 8192, Min: 1, Max: 8192},
+				{Name: "json_schema", UseTemplate: "json_schema"},
+			},
+		},
+	})
+	return NewProviderService(store)
+}
+
+func TestResolveParameterRules(t *testing.T) {
+	rules := ResolveParameterRules([]model.ParameterRule{
+		{Name: "temperature", UseTemplate: "temperature"},
+		{Name: "max_tokens", UseTemplate: "max_tokens", Max: 4096},
+		{Name: "seed", Type: "int"},
+	})
+
+	if len(rules) != 3 {
+		t.Fatalf("规则数量 = %d, want 3", len(rules))
+	}
+	if rules[0].Type != "float" || rules[0].Min != 0.0 || rules[0].Max != 2.0 {
+		t.Errorf("temperature 模板未展开: %+v", rules[0])
+	}
+	if rules[1].Type != "int" || rules[1].Min != 1 || rules[1].Max != 4096 {
+		t.Errorf("max_tokens 应保留模型声明的上限: %+v", rules[1])
+	}
+	if rules[2].Type != "int" || rules[2].Min != nil {
+		t.Errorf("未使用模板的规则不应改变: %+v", rules[2])
+	}
+}
+
+func TestParameterRuleEngine_Apply(t *testing.T) {
+	engine := NewParameterRuleEngine(newTestProviderService(), "gemini/gemini-1.5-flash-001")
+
+	t.Run("填充模型默认值", func(t *testing.T) {
+		temp := 0.5
+		options := &model.ChatOptions{Temperature: &temp}
+
+		result, err := engine.Apply("gemini-1.5-flash-001", options)
+		if err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+		if result.MaxTokens == nil || *result.MaxTokens != 8192 {
+			t.Errorf("MaxTokens = %v, want 8192", result.MaxTokens)
+		}
+		if *result.Temperature != 0.5 {
+			t.Errorf("Temperature = %v, want 0.5", *result.Temperature)
+		}
+		if options.MaxTokens != nil {
+			t.Error("不应修改传入的参数")
+		}
+	})
+
+	t.Run("超出模型上限", func(t *testing.T) {
+		maxTokens := 10000
+		temp := 2.5
+
+		_, err := engine.Apply("", &model.ChatOptions{MaxTokens: &maxTokens, Temperature: &temp})
+		appErr, ok := err.(*errors.AppError)
+		if !ok || appErr.Code != errors.CodeValidationError {
+			t.Fatalf("期望验证错误, 实际 %v", err)
+		}
+
+		fieldErrors, ok := appErr.Err.(validator.FieldErrors)
+		if !ok || len(fieldErrors) != 2 {
+			t.Fatalf("期望 2 个字段错误, 实际 %v", appErr.Err)
+		}
+		fields := map[string]bool{}
+		for _, fieldError := range fieldErrors {
+			fields[fieldError.Field] = true
+		}
+		if !fields["maxTokens"] || !fields["temperature"] {
+			t.Errorf("字段错误不正确: %+v", fieldErrors)
+		}
+	})
+
+	t.Run("原生支持 JSON Schema", func(t *testing.T) {
+		schema := map[string]interface{}{"type": "object"}
+
+		result, err := engine.Apply("", &model.ChatOptions{JSONSchema: schema})
+		if err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+		if !result.NativeJSONSchema {
+			t.Error("声明了 json_schema 参数规则的模型应设置 NativeJSONSchema")
+		}
+	})
+
+	t.Run("无效的 JSON Schema", func(t *testing.T) {
+		_, err := engine.Apply("", &model.ChatOptions{JSONSchema: map[string]interface{}{"type": "date"}})
+		appErr, ok := err.(*errors.AppError)
+		if !ok || appErr.Code != errors.CodeValidationError {
+			t.Fatalf("期望验证错误, 实际 %v", err)
+		}
+		if fieldErrors, ok := appErr.Err.(validator.FieldErrors); !ok || fieldErrors[0].Field != "jsonSchema" {
+			t.Errorf("字段错误不正确: %v", appErr.Err)
+		}
+	})
+
+	t.Run("模型不存在", func(t *testing.T) {
+		_, err := engine.Apply("unknown-model", nil)
+		appErr, ok := err.(*errors.AppError)
+		if !ok || appErr.Code != errors.CodeModelNotFound {
+			t.Errorf("期望模型不存在错误, 实际 %v", err)
+		}
+	})
+
+	t.Run("默认模型不在目录中时跳过校验", func(t *testing.T) {
+		engine := NewParameterRuleEngine(newTestProviderService(), "gemini/unknown")
+		maxTokens := 100000
+
+		result, err := engine.Apply("", &model.ChatOptions{MaxTokens: &maxTokens})
+		if err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+		if *result.MaxTokens != maxTokens {
+			t.Errorf("MaxTokens = %v, want %v", *result.MaxTokens, maxTokens)
+		}
+	})
+}
@@ -1,8 +1,12 @@
 package service
 
 import (
+	"sort"
+	"strings"
+
 	"genkit-ai-service/internal/model"
 	"genkit-ai-service/internal/storage"
+	"genkit-ai-service/pkg/errors"
 )
 
 // ProviderService 提供商服务接口
@@ -16,11 +20,27 @@ type ProviderService interface {
 	// GetProviderModels 获取提供商的所有模型（返回列表项格式）
 	GetProviderModels(providerID string) ([]model.ModelListItem, error)
 
+	// SearchModels 跨提供商搜索模型，返回当前页的模型和匹配的总数
+	SearchModels(req *model.SearchModelsRequest) ([]model.ModelSearchItem, int, error)
+
 	// GetProviderModel 获取提供商的指定模型
 	GetProviderModel(providerID, modelID string) (*model.Model, error)
 
-	// GetModelParameterRules 获取模型的参数规则
+	// GetModelParameterRules 获取模型的参数规则（已展开 use_template 引用）
 	GetModelParameterRules(providerID, modelID string) ([]model.ParameterRule, error)
+
+	// GetModelVoices 获取语音合成模型的音色列表，language 不为空时只返回支持该语言的音色
+	GetModelVoices(providerID, modelID, language string) ([]model.Voice, error)
+
+	// ResolveModel 根据模型名称查找模型及其所属提供商ID
+	// 支持 "提供商ID/模型ID" 和仅模型ID两种形式
+	ResolveModel(modelName string) (string, *model.Model, error)
+
+	// AddModel 将模型合并到提供商的模型列表，用于注册自定义模型
+	AddModel(providerID string, m model.Model) error
+
+	// RemoveModel 从提供商的模型列表中移除模型
+	RemoveModel(providerID, modelID string) error
 }
 
 // providerService 提供商服务实现
@@ -80,20 +100,26 @@ func (s *providerService) GetProviderModels(providerID string) ([]model.ModelLis
 	// 转换为列表项格式
 	listItems := make([]model.ModelListItem, 0, len(models))
 	for _, m := range models {
-		listItems = append(listItems, model.ModelListItem{
-			Model:           m.Model,
-			Label:           m.Label,
-			ModelType:       m.ModelType,
-			Features:        m.Features,
-			ModelProperties: m.ModelProperties,
-			ParameterRules:  m.ParameterRules,
-			Pricing:         m.Pricing,
-		})
+		listItems = append(listItems, toModelListItem(m))
 	}
 
 	return listItems, nil
 }
 
+// toModelListItem 将模型转换为列表项格式
+func toModelListItem(m model.Model) model.ModelListItem {
+	return model.ModelListItem{
+		Model:           m.Model,
+		Label:           m.Label,
+		ModelType:       m.ModelType,
+		Features:        m.Features,
+		ModelProperties: m.ModelProperties,
+		ParameterRules:  m.ParameterRules,
+		Pricing:         m.Pricing,
+		FetchFrom:       m.FetchFrom,
+	}
+}
+
 // GetProviderModel 获取提供商的指定模型
 func (s *providerService) GetProviderModel(providerID, modelID string) (*model.Model, error) {
 	// 从存储层获取模型
@@ -118,5 +144,69 @@ func (s *providerService) GetModelParameterRules(providerID, modelID string) ([]
 		return []model.ParameterRule{}, nil
 	}
 
-	return m.ParameterRules, nil
+	// 展开 use_template 引用，返回具体的类型和取值范围
+	return ResolveParameterRules(m.ParameterRules), nil
+}
+
+// GetModelVoices 获取语音合成模型的音色列表
+func (s *providerService) GetModelVoices(providerID, modelID, language string) ([]model.Voice, error) {
+	m, err := s.store.GetModel(providerID, modelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.ModelType != model.ModelTypeTTS {
+		return nil, errors.NewBadRequestError("模型 '" + modelID + "' 不是语音合成模型")
+	}
+
+	voices := make([]model.Voice, 0, len(m.ModelProperties.Voices))
+	for _, voice := range m.ModelProperties.Voices {
+		if language == "" || voice.SupportsLanguage(language) {
+			voices = append(voices, voice)
+		}
+	}
+
+	return voices, nil
+}
+
+// ResolveModel 根据模型名称查找模型及其所属提供商ID
+// 仅提供模型ID时按提供商ID字母顺序查找，返回第一个匹配的模型，保证结果稳定
+func (s *providerService) ResolveModel(modelName string) (string, *model.Model, error) {
+	if modelName == "" {
+		return "", nil, errors.NewModelNotFoundError("")
+	}
+
+	// "提供商ID/模型ID" 形式
+	if providerID, modelID, found := strings.Cut(modelName, "/"); found {
+		m, err := s.store.GetModel(providerID, modelID)
+		if err != nil {
+			return "", nil, errors.NewModelNotFoundError(modelName)
+		}
+		return providerID, m, nil
+	}
+
+	providers := s.store.GetProviders()
+	providerIDs := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		providerIDs = append(providerIDs, provider.ID)
+	}
+	sort.Strings(providerIDs)
+
+	for _, providerID := range providerIDs {
+		if m, err := s.store.GetModel(providerID, modelName); err == nil {
+			return providerID, m, nil
+		}
+	}
+
+	return "", nil, errors.NewModelNotFoundError(modelName)
+}
+
+// AddModel 将模型合并到提供商的模型列表
+func (s *providerService) AddModel(providerID string, m model.Model) error {
+	return s.store.AddModel(providerID, m)
+}
+
+// RemoveModel 从提供商的模型列表中移除模型
+func (s *providerService) RemoveModel(providerID, modelID string) error {
+	return s.store.RemoveModel(providerID, modelID)
 }
@@ -0,0 +1,210 @@
+package session
+
+import (
+	"fmt"
+
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/service"
+	"genkit-ai-service/pkg/errors"
+	"genkit-ai-service/pkg/tokenizer"
+)
+
+// defaultReservedOutputTokens 请求和模型配置都未指定 max_tokens 时为回复预留的 token 数
+const defaultReservedOutputTokens = 2000
+
+// mediaPartTokens 每个媒体附件估算的 token 数
+// 附件实际占用的 token 数取决于模型和媒体大小，按固定值预留
+const mediaPartTokens = 1000
+
+// ModelCatalog 模型目录查询接口
+type ModelCatalog interface {
+	// ResolveModel 根据模型名称查找模型及其所属提供商ID
+	ResolveModel(modelName string) (string, *model.Model, error)
+}
+
+// ContextWindowInfo 上下文窗口组装信息
+type ContextWindowInfo struct {
+	// 模型上下文大小
+	ContextSize int `json:"contextSize"`
+	// 为回复预留的 token 数（max_tokens）
+	ReservedTokens int `json:"reservedTokens"`
+	// 估算的提示词 token 数
+	PromptTokens int `json:"promptTokens"`
+	// 保留的历史消息数量
+	IncludedMessages int `json:"includedMessages"`
+	// 因超出上下文被丢弃的历史消息数量
+	DroppedMessages int `json:"droppedMessages"`
+	// 被丢弃的历史消息ID
+	DroppedMessageIDs []string `json:"droppedMessageIds,omitempty"`
+}
+
+// contextWindow 按 token 预算组装的对话上下文
+type contextWindow struct {
+	history []model.ChatTurn
+	info    *ContextWindowInfo
+}
+
+// buildContextWindow 在模型上下文大小内组装对话上下文
+// 系统提示词、摘要和新消息（包括其媒体片段）始终保留，历史消息从最新往前保留，超出预算的最旧消息被丢弃。
+// contextSize 不大于 0 时不做裁剪。
+func buildContextWindow(contextSize, reservedTokens int, systemPrompt string, summary *model.ChatTurn, messages []*model.ChatMessage, newMessage string, newParts []model.MessagePart) (*contextWindow, error) {
+	fixedTokens := tokenizer.EstimateMessage(newMessage) + len(newParts)*mediaPartTokens
+	if systemPrompt != "" {
+		fixedTokens += tokenizer.EstimateMessage(systemPrompt)
+	}
+	if summary != nil {
+		fixedTokens += tokenizer.EstimateMessage(summary.Content)
+	}
+
+	info := &ContextWindowInfo{
+		ContextSize:    contextSize,
+		ReservedTokens: reservedTokens,
+		PromptTokens:   fixedTokens,
+	}
+
+	if contextSize > 0 && fixedTokens+reservedTokens > contextSize {
+		return nil, errors.NewBadRequestError(fmt.Sprintf(
+			"消息过长：提示词约 %d tokens，加上 max_tokens %d 超出模型上下文大小 %d",
+			fixedTokens, reservedTokens, contextSize))
+	}
+
+	// 从最新的消息往前累计，找到预算内能保留的最早位置
+	start := len(messages)
+	budget := contextSize - reservedTokens - fixedTokens
+	for i := len(messages) - 1; i >= 0; i-- {
+		tokens := messageTokens(messages[i])
+		if contextSize > 0 && tokens > budget {
+			break
+		}
+		budget -= tokens
+		info.PromptTokens += tokens
+		start = i
+	}
+
+	// 保留的历史以 user 消息开头，避免出现没有提问的回答或没有调用的工具结果
+	for start < len(messages) && messages[start].Role != "user" {
+		info.PromptTokens -= messageTokens(messages[start])
+		start++
+	}
+
+	window := &contextWindow{info: info}
+	if summary != nil {
+		window.history = append(window.history, *summary)
+	}
+
+	for _, msg := range messages[:start] {
+		info.DroppedMessageIDs = append(info.DroppedMessageIDs, msg.ID)
+	}
+	info.DroppedMessages = start
+
+	for _, msg := range messages[start:] {
+		window.history = append(window.history, toChatTurn(msg))
+	}
+	info.IncludedMessages = len(messages) - start
+
+	return window, nil
+}
+
+// toChatTurn 将历史消息转换为对话轮次
+// 用户消息携带附件，AI 回复携带其发起的工具调用，function 消息携带对应的工具调用ID和工具名称
+func toChatTurn(msg *model.ChatMessage) model.ChatTurn {
+	turn := model.ChatTurn{
+		Role:    msg.Role,
+		Content: msg.Content,
+	}
+
+	toolCalls := decodeToolCalls(msg.ToolCalls)
+	switch msg.Role {
+	case "user":
+		turn.Parts = attachmentParts(msg.Attachments)
+	case "assistant":
+		turn.ToolCalls = toolCalls
+	case "function":
+		if len(toolCalls) > 0 {
+			turn.ToolCallID = toolCalls[0].ID
+			turn.Name = toolCalls[0].Name
+		}
+	}
+
+	return turn
+}
+
+// messageTokens 返回消息的 token 数，优先使用已保存的 token 数
+// 没有保存 token 数时按内容和工具调用估算；附件按 mediaPartTokens 计算
+func messageTokens(msg *model.ChatMessage) int {
+	tokens := len(msg.Attachments) * mediaPartTokens
+	if msg.Tokens > 0 {
+		return tokens + msg.Tokens + tokenizer.MessageOverhead
+	}
+	return tokens + tokenizer.EstimateMessage(msg.Content) + tokenizer.Estimate(string(msg.ToolCalls))
+}
+
+// turnTokens 估算对话轮次的 token 数，包括工具调用和媒体片段
+func turnTokens(turn model.ChatTurn) int {
+	tokens := tokenizer.EstimateMessage(turn.Content) + len(turn.Parts)*mediaPartTokens
+	for _, call := range turn.ToolCalls {
+		tokens += tokenizer.Estimate(call.Name) + tokenizer.Estimate(call.Arguments)
+	}
+	return tokens
+}
+
+// fitHistory 工具调用轮次追加到历史对话后，按模型上下文大小重新裁剪历史对话
+// 开头的摘要和最后一条 user 消息起的本轮对话始终保留，更早的历史从最旧的开始丢弃，
+// 保留的历史以 user 消息开头。返回裁剪后的历史和丢弃的轮次数量；contextSize 不大于 0 时不做裁剪
+func fitHistory(contextSize, reservedTokens int, systemPrompt string, history []model.ChatTurn) ([]model.ChatTurn, int) {
+	if contextSize <= 0 {
+		return history, 0
+	}
+
+	head := 0
+	if len(history) > 0 && history[0].Role == "system" {
+		head = 1
+	}
+	last := len(history)
+	for last > head && history[last-1].Role != "user" {
+		last--
+	}
+	if last > head {
+		last--
+	}
+
+	total := reservedTokens
+	if systemPrompt != "" {
+		total += tokenizer.EstimateMessage(systemPrompt)
+	}
+	for _, turn := range history {
+		total += turnTokens(turn)
+	}
+
+	// 从最旧的历史开始丢弃，直到不超出上下文或只剩本轮对话
+	start := head
+	for start < last && total > contextSize {
+		total -= turnTokens(history[start])
+		start++
+	}
+	for start < last && history[start].Role != "user" {
+		start++
+	}
+	if start == head {
+		return history, 0
+	}
+
+	fitted := append(append([]model.ChatTurn{}, history[:head]...), history[start:]...)
+	return fitted, start - head
+}
+
+// reservedOutputTokens 返回为回复预留的 token 数
+// 按模型参数规则填充默认值后取 max_tokens（包括通过 use_template 引用 max_tokens 模板的
+// max_output_tokens 等参数），与 AI 服务实际发送的参数一致；都未指定时使用全局默认值
+func reservedOutputTokens(m *model.Model, options *model.ChatOptions) int {
+	if m != nil {
+		// 参数校验错误由 AI 服务返回，这里只需要填充后的默认值
+		options, _ = service.ApplyParameterRules(m, options)
+	}
+
+	if options != nil && options.MaxTokens != nil && *options.MaxTokens > 0 {
+		return *options.MaxTokens
+	}
+
+	return defaultReservedOutputTokens
+}
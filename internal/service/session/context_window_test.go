@@ -0,0 +1,230 @@
+package session
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"genkit-ai-service/internal/model"
+	"genkit-ai-service/pkg/errors"
+	"genkit-ai-service/pkg/tokenizer"
+
+	"gorm.io/datatypes"
+)
+
+// newHistoryMessages 创建交替的 user/assistant 历史消息，每条消息占用 tokens 个 token
+func newHistoryMessages(count, tokens int) []*model.ChatMessage {
+	messages := make([]*model.ChatMessage, 0, count)
+	for i := 0; i < count; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		messages = append(messages, &model.ChatMessage{
+			ID:       string(rune('a' + i)),
+			Role:     role,
+			Content:  "内容",
+			Tokens:   tokens,
+			Sequence: i + 1,
+		})
+	}
+	return messages
+}
+
+func TestBuildContextWindow(t *testing.T) {
+	t.Run("上下文大小未知时保留全部历史", func(t *testing.T) {
+		messages := newHistoryMessages(4, 1000)
+
+		window, err := buildContextWindow(0, 100, "", nil, messages, "你好", nil)
+		if err != nil {
+			t.Fatalf("组装上下文失败: %v", err)
+		}
+		if len(window.history) != 4 || window.info.DroppedMessages != 0 {
+			t.Errorf("期望保留 4 条历史, 得到 %d 条, 丢弃 %d 条", len(window.history), window.info.DroppedMessages)
+		}
+	})
+
+	t.Run("超出预算时丢弃最早的消息", func(t *testing.T) {
+		messages := newHistoryMessages(4, 100)
+		perMessage := 100 + tokenizer.MessageOverhead
+		fixed := tokenizer.EstimateMessage("你好")
+		// 只能容纳 2 条历史消息
+		contextSize := fixed + 500 + perMessage*2
+
+		window, err := buildContextWindow(contextSize, 500, "", nil, messages, "你好", nil)
+		if err != nil {
+			t.Fatalf("组装上下文失败: %v", err)
+		}
+
+		if window.info.DroppedMessages != 2 || window.info.IncludedMessages != 2 {
+			t.Errorf("期望丢弃 2 条保留 2 条, 得到丢弃 %d 保留 %d", window.info.DroppedMessages, window.info.IncludedMessages)
+		}
+		if strings.Join(window.info.DroppedMessageIDs, ",") != "a,b" {
+			t.Errorf("丢弃的消息ID = %v, 期望 [a b]", window.info.DroppedMessageIDs)
+		}
+		if window.info.PromptTokens != fixed+perMessage*2 {
+			t.Errorf("PromptTokens = %d, 期望 %d", window.info.PromptTokens, fixed+perMessage*2)
+		}
+	})
+
+	t.Run("保留的历史不以 assistant 消息开头", func(t *testing.T) {
+		messages := newHistoryMessages(4, 100)
+		perMessage := 100 + tokenizer.MessageOverhead
+		fixed := tokenizer.EstimateMessage("你好")
+		// 能容纳 3 条，但第 2 条是 assistant 消息
+		contextSize := fixed + perMessage*3
+
+		window, err := buildContextWindow(contextSize, 0, "", nil, messages, "你好", nil)
+		if err != nil {
+			t.Fatalf("组装上下文失败: %v", err)
+		}
+
+		if len(window.history) != 2 || window.history[0].Role != "user" {
+			t.Errorf("期望保留以 user 开头的 2 条历史, 得到 %+v", window.history)
+		}
+	})
+
+	t.Run("摘要始终保留在历史开头", func(t *testing.T) {
+		summary := &model.ChatTurn{Role: "system", Content: "摘要"}
+		messages := newHistoryMessages(2, 10)
+
+		window, err := buildContextWindow(10000, 100, "系统提示词", summary, messages, "你好", nil)
+		if err != nil {
+			t.Fatalf("组装上下文失败: %v", err)
+		}
+
+		if len(window.history) != 3 || !reflect.DeepEqual(window.history[0], *summary) {
+			t.Errorf("期望摘要位于历史开头, 得到 %+v", window.history)
+		}
+	})
+
+	t.Run("新消息本身超出上下文时返回错误", func(t *testing.T) {
+		_, err := buildContextWindow(100, 50, "", nil, nil, strings.Repeat("长", 80), nil)
+
+		appErr, ok := err.(*errors.AppError)
+		if !ok || appErr.Code != errors.CodeBadRequest {
+			t.Errorf("期望请求参数错误, 得到 %v", err)
+		}
+	})
+}
+
+func TestBuildContextWindow_ToolCallsAndAttachments(t *testing.T) {
+	t.Run("历史消息的附件和工具调用计入预算", func(t *testing.T) {
+		toolCalls := `[{"id":"call-1","name":"lookup","arguments":"` + strings.Repeat("a", 400) + `"}]`
+		messages := []*model.ChatMessage{
+			{ID: "a", Role: "user", Content: "看图", Attachments: []*model.ChatAttachment{{MimeType: "image/png"}}},
+			{ID: "b", Role: "assistant", Content: "好的"},
+			{ID: "c", Role: "user", Content: "查一下"},
+			{ID: "d", Role: "assistant", ToolCalls: datatypes.JSON(toolCalls)},
+			{ID: "e", Role: "function", Content: "结果", Tokens: 2},
+			{ID: "f", Role: "assistant", Content: "查到了"},
+		}
+		if got := messageTokens(messages[0]); got != mediaPartTokens+tokenizer.EstimateMessage("看图") {
+			t.Errorf("附件消息的 token 数 = %d", got)
+		}
+		if got := messageTokens(messages[3]); got <= 100 {
+			t.Errorf("工具调用消息的 token 数 = %d, 期望计入工具调用参数", got)
+		}
+
+		// 预算能容纳后 4 条，但容纳不了带附件的第一轮
+		contextSize := tokenizer.EstimateMessage("你好") + 500
+		window, err := buildContextWindow(contextSize, 0, "", nil, messages, "你好", nil)
+		if err != nil {
+			t.Fatalf("组装上下文失败: %v", err)
+		}
+		if strings.Join(window.info.DroppedMessageIDs, ",") != "a,b" {
+			t.Errorf("丢弃的消息ID = %v, 期望 [a b]", window.info.DroppedMessageIDs)
+		}
+	})
+
+	t.Run("新消息的媒体片段计入预算", func(t *testing.T) {
+		parts := []model.MessagePart{{Type: model.PartTypeMedia, MimeType: "image/png"}}
+
+		_, err := buildContextWindow(mediaPartTokens, 0, "", nil, nil, "你好", parts)
+		appErr, ok := err.(*errors.AppError)
+		if !ok || appErr.Code != errors.CodeBadRequest {
+			t.Errorf("期望请求参数错误, 得到 %v", err)
+		}
+	})
+}
+
+func TestFitHistory(t *testing.T) {
+	summary := model.ChatTurn{Role: "system", Content: "摘要"}
+	longResult := strings.Repeat("长", 300)
+	history := []model.ChatTurn{
+		summary,
+		{Role: "user", Content: "第一个问题"},
+		{Role: "assistant", Content: strings.Repeat("答", 200)},
+		{Role: "user", Content: "第二个问题"},
+		{Role: "assistant", ToolCalls: []model.ToolCall{{ID: "call-1", Name: "lookup", Arguments: "{}"}}},
+		{Role: "function", Content: longResult, ToolCallID: "call-1", Name: "lookup"},
+	}
+
+	t.Run("上下文足够时不裁剪", func(t *testing.T) {
+		fitted, dropped := fitHistory(10000, 100, "", history)
+		if dropped != 0 || len(fitted) != len(history) {
+			t.Errorf("期望不裁剪, 得到丢弃 %d 条", dropped)
+		}
+	})
+
+	t.Run("工具结果超出上下文时丢弃最早的历史", func(t *testing.T) {
+		fitted, dropped := fitHistory(600, 100, "", history)
+		if dropped != 2 {
+			t.Fatalf("期望丢弃 2 条, 得到 %d 条", dropped)
+		}
+		if len(fitted) != 4 || !reflect.DeepEqual(fitted[0], summary) || fitted[1].Content != "第二个问题" {
+			t.Errorf("期望保留摘要和本轮对话, 得到 %+v", fitted)
+		}
+	})
+
+	t.Run("本轮对话始终保留", func(t *testing.T) {
+		fitted, dropped := fitHistory(10, 0, "", history)
+		if dropped != 2 || fitted[1].Role != "user" || len(fitted) != 4 {
+			t.Errorf("期望只丢弃之前的历史, 得到丢弃 %d 条: %+v", dropped, fitted)
+		}
+	})
+
+	t.Run("上下文大小未知时不裁剪", func(t *testing.T) {
+		if _, dropped := fitHistory(0, 100, "", history); dropped != 0 {
+			t.Errorf("期望不裁剪, 得到丢弃 %d 条", dropped)
+		}
+	})
+}
+
+func TestReservedOutputTokens(t *testing.T) {
+	maxTokens := 512
+	m := &model.Model{
+		ParameterRules: []model.ParameterRule{
+			{Name: "temperature", Default: 0.7},
+			{Name: "max_tokens", Default: 8192},
+		},
+	}
+	// 通过 use_template 引用 max_tokens 模板的参数（如 Gemini 的 max_output_tokens）
+	templated := &model.Model{
+		ParameterRules: []model.ParameterRule{
+			{Name: "max_output_tokens", UseTemplate: "max_tokens", Default: 8192, Max: 8192},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		model   *model.Model
+		options *model.ChatOptions
+		want    int
+	}{
+		{name: "使用请求参数", model: m, options: &model.ChatOptions{MaxTokens: &maxTokens}, want: 512},
+		{name: "使用模型默认值", model: m, options: nil, want: 8192},
+		{name: "使用全局默认值", model: nil, options: &model.ChatOptions{}, want: defaultReservedOutputTokens},
+		{name: "使用模板参数的默认值", model: templated, options: nil, want: 8192},
+		{name: "模板参数使用请求参数", model: templated, options: &model.ChatOptions{MaxTokens: &maxTokens}, want: 512},
+		{name: "模型未声明 max_tokens", model: &model.Model{}, options: nil, want: defaultReservedOutputTokens},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reservedOutputTokens(tt.model, tt.options); got != tt.want {
+				t.Errorf("reservedOutputTokens() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,15 +2,23 @@ package session
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"genkit-ai-service/internal/logger"
 	"genkit-ai-service/internal/model"
 	"genkit-ai-service/internal/repository"
 	"genkit-ai-service/internal/service/ai"
+	"genkit-ai-service/internal/service/tool"
 	"genkit-ai-service/pkg/errors"
+	"genkit-ai-service/pkg/tokenizer"
 
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -19,14 +27,64 @@ type MessageService interface {
 	// SendMessage 发送消息（包含AI回复）
 	SendMessage(ctx context.Context, req *SendMessageRequest) (*MessageResponse, error)
 
+	// SendMessageStream 以流式方式发送消息，每收到一个文本片段调用一次 onChunk
+	SendMessageStream(ctx context.Context, req *SendMessageRequest, onChunk func(content string) error) (*MessageResponse, error)
+
 	// GetMessages 获取消息历史
 	GetMessages(ctx context.Context, req *GetMessagesRequest) (*MessageListResponse, error)
 
 	// GetMessageByID 获取单条消息
 	GetMessageByID(ctx context.Context, messageID, userID string) (*MessageDetailResponse, error)
 
-	// AbortMessage 中止消息生成
-	AbortMessage(ctx context.Context, messageID, userID string) error
+	// RegenerateMessage 为 AI 回复重新生成一个并列的回复，新回复成为当前分支
+	// onChunk 不为 nil 时以流式方式生成，每收到一个文本片段调用一次
+	RegenerateMessage(ctx context.Context, req *RegenerateMessageRequest, onChunk func(content string) error) (*MessageResponse, error)
+
+	// EditMessage 编辑用户消息并重新发送，在原消息的父消息下创建新的对话分支
+	// onChunk 不为 nil 时以流式方式生成，每收到一个文本片段调用一次
+	EditMessage(ctx context.Context, req *EditMessageRequest, onChunk func(content string) error) (*MessageResponse, error)
+
+	// GetSiblings 获取与指定消息同属一个父消息的所有分支
+	GetSiblings(ctx context.Context, messageID, userID string) (*MessageSiblingsResponse, error)
+
+	// SelectBranch 切换到指定消息所在的分支，返回切换后当前分支的末端消息
+	SelectBranch(ctx context.Context, messageID, userID string) (*MessageDetailResponse, error)
+
+	// AbortMessage 中止消息生成，返回是否取消了正在进行的生成
+	AbortMessage(ctx context.Context, messageID, userID string) (bool, error)
+
+	// AbortSession 中止会话正在进行的生成，返回是否取消了正在进行的生成
+	AbortSession(ctx context.Context, sessionID, userID string) (bool, error)
+
+	// AppendUserMessage 在会话当前分支末尾追加一条用户消息，不生成 AI 回复
+	AppendUserMessage(ctx context.Context, sessionID, userID, content string) (*MessageDetailResponse, error)
+}
+
+// historyMessageLimit 构建对话上下文时最多加载的最近消息数量，实际保留的消息由 token 预算决定
+const historyMessageLimit = 100
+
+// maxToolRounds 一次对话中最多执行的工具调用轮数，达到后不再提供工具，要求模型直接回答
+const maxToolRounds = 5
+
+// toolCallTimeout 单次工具调用的超时时间
+const toolCallTimeout = 30 * time.Second
+
+// maxAttachmentSize 单个内联附件的最大字节数
+const maxAttachmentSize = 20 << 20
+
+// FileReader 上传文件的读取接口，用于解析消息片段中引用的文件
+type FileReader interface {
+	// ReadFile 读取文件元数据和全部内容，不检查文件归属
+	ReadFile(ctx context.Context, fileID string) (*model.File, []byte, error)
+}
+
+// KnowledgeRetriever 知识库检索接口，用于校验会话挂载的知识库和在发送消息时检索相关内容
+type KnowledgeRetriever interface {
+	// GetKnowledgeBase 获取用户拥有的知识库
+	GetKnowledgeBase(ctx context.Context, knowledgeBaseID, userID string) (*model.KnowledgeBase, error)
+
+	// Search 在用户的知识库中检索与查询最相关的分块，topK 不大于 0 时使用默认数量
+	Search(ctx context.Context, userID string, knowledgeBaseIDs []string, query string, topK int) ([]model.Citation, error)
 }
 
 // messageService 消息服务实现
@@ -34,8 +92,28 @@ type messageService struct {
 	db                *gorm.DB
 	sessionRepo       repository.SessionRepository
 	messageRepo       repository.MessageRepository
+	summaryRepo       repository.SummaryRepository
+	attachmentRepo    repository.AttachmentRepository
+	files             FileReader
+	knowledge         KnowledgeRetriever
 	aiService         ai.AIService
+	catalog           ModelCatalog
+	toolRegistry      tool.Registry
+	summaryScheduler  SummaryScheduler
 	logger            logger.Logger
+
+	// 正在进行的生成，key: 生成过程中创建的 AI 回复消息ID
+	generationsMu sync.Mutex
+	generations   map[string]*generation
+}
+
+// generation 正在进行的生成
+// 发送消息时在任何 I/O 之前登记一次，直到生成（包括工具调用后的多轮生成）结束才移除，
+// 可按生成过程中创建的任意 AI 回复消息ID或会话ID查找并取消
+type generation struct {
+	sessionID  string
+	messageIDs []string
+	cancel     context.CancelFunc
 }
 
 // logInfo 安全地记录信息日志
@@ -60,28 +138,89 @@ func (s *messageService) logError(ctx context.Context, msg string, fields logger
 }
 
 // NewMessageService 创建消息服务实例
+// toolRegistry 为会话启用的工具的来源，为 nil 时不向模型提供工具；
+// attachmentRepo 为 nil 时不支持发送带媒体片段的消息；files 为 nil 时不支持在消息片段中引用上传文件；
+// knowledge 为 nil 时不从会话挂载的知识库中检索内容
 func NewMessageService(
 	db *gorm.DB,
 	sessionRepo repository.SessionRepository,
 	messageRepo repository.MessageRepository,
+	summaryRepo repository.SummaryRepository,
+	attachmentRepo repository.AttachmentRepository,
+	files FileReader,
+	knowledge KnowledgeRetriever,
 	aiService ai.AIService,
+	catalog ModelCatalog,
+	toolRegistry tool.Registry,
+	summaryScheduler SummaryScheduler,
 	log logger.Logger,
 ) MessageService {
 	return &messageService{
-		db:          db,
-		sessionRepo: sessionRepo,
-		messageRepo: messageRepo,
-		aiService:   aiService,
-		logger:      log,
+		db:               db,
+		sessionRepo:      sessionRepo,
+		messageRepo:      messageRepo,
+		summaryRepo:      summaryRepo,
+		attachmentRepo:   attachmentRepo,
+		files:            files,
+		knowledge:        knowledge,
+		aiService:        aiService,
+		catalog:          catalog,
+		toolRegistry:     toolRegistry,
+		summaryScheduler: summaryScheduler,
+		logger:           log,
+		generations:      make(map[string]*generation),
 	}
 }
 
 // SendMessageRequest 发送消息请求
 type SendMessageRequest struct {
 	SessionID string              `json:"sessionId" validate:"required,uuid"`
-	Message   string              `json:"message" validate:"required"`
+	Message   string              `json:"message" validate:"required_without=Parts"`
 	UserID    string              `json:"userId" validate:"required,uuid"`
 	Options   *model.ChatOptions  `json:"options,omitempty"`
+	// 多模态消息片段（可选），文本片段并入消息内容，媒体片段保存为消息附件
+	Parts []model.MessagePart `json:"parts,omitempty"`
+	// AI 回复消息ID（可选），由调用方预先生成以便在生成过程中中止，为空时自动生成
+	MessageID string `json:"-"`
+}
+
+// RegenerateMessageRequest 重新生成 AI 回复请求
+type RegenerateMessageRequest struct {
+	// 要重新生成的 AI 回复消息ID
+	MessageID string             `json:"messageId" validate:"required,uuid"`
+	UserID    string             `json:"userId" validate:"required,uuid"`
+	Options   *model.ChatOptions `json:"options,omitempty"`
+	// 新 AI 回复消息ID（可选），由调用方预先生成以便在生成过程中中止，为空时自动生成
+	AIMessageID string `json:"-"`
+}
+
+// EditMessageRequest 编辑用户消息并重新发送请求
+type EditMessageRequest struct {
+	// 被编辑的用户消息ID
+	MessageID string             `json:"messageId" validate:"required,uuid"`
+	Message   string             `json:"message" validate:"required"`
+	UserID    string             `json:"userId" validate:"required,uuid"`
+	Options   *model.ChatOptions `json:"options,omitempty"`
+	// 新 AI 回复消息ID（可选），由调用方预先生成以便在生成过程中中止，为空时自动生成
+	AIMessageID string `json:"-"`
+}
+
+// MessageSiblingsResponse 同一父消息下的分支列表
+type MessageSiblingsResponse struct {
+	// 父消息ID，会话的第一条消息为空
+	ParentID *string `json:"parentId,omitempty"`
+	// 按创建顺序排列的分支消息
+	Siblings []*MessageDetailResponse `json:"siblings"`
+	// 当前分支上的消息ID
+	ActiveID string `json:"activeId,omitempty"`
+}
+
+// conversationTurn 一轮对话在消息树中的位置
+type conversationTurn struct {
+	// 新用户消息的父消息ID，同时也是历史对话的末端消息，为 nil 时作为会话的第一条消息
+	parentID *string
+	// 重新生成时复用的用户消息，为 nil 时创建新的用户消息
+	userMessage *model.ChatMessage
 }
 
 // MessageResponse 消息响应
@@ -92,15 +231,32 @@ type MessageResponse struct {
 	AIMessage   *Message     `json:"aiMessage"`
 	Model       string       `json:"model"`
 	Usage       *model.Usage `json:"usage,omitempty"`
+	Meta        *MessageMeta `json:"meta,omitempty"`
+	// 本轮对话中的工具调用过程：发起工具调用的 AI 回复和 function 消息，按顺序排列
+	ToolMessages []*Message `json:"toolMessages,omitempty"`
+	// 指定 JSON Schema 时按 Schema 解析后的 AI 回复
+	Parsed interface{} `json:"parsed,omitempty"`
+}
+
+// MessageMeta 消息响应元数据
+type MessageMeta struct {
+	// 上下文窗口组装信息，包含因超出模型上下文而丢弃的历史消息
+	ContextWindow *ContextWindowInfo `json:"contextWindow,omitempty"`
+	// 从会话挂载的知识库中检索并注入提示词的分块，同时保存在 AI 回复的 Meta 中
+	Citations []model.Citation `json:"citations,omitempty"`
 }
 
 // Message 消息
 type Message struct {
 	ID        string    `json:"id"`
+	ParentID  *string   `json:"parentId,omitempty"`
 	Role      string    `json:"role"`
 	Content   string    `json:"content"`
+	Status    string    `json:"status"`
 	Sequence  int       `json:"sequence"`
 	CreatedAt time.Time `json:"createdAt"`
+	// 工具调用：AI 回复为发起的工具调用，function 消息为其对应的工具调用
+	ToolCalls []model.ToolCall `json:"toolCalls,omitempty"`
 }
 
 // GetMessagesRequest 获取消息历史请求
@@ -109,6 +265,8 @@ type GetMessagesRequest struct {
 	UserID    string `json:"userId" validate:"required,uuid"`
 	PageNo    int    `json:"pageNo" validate:"required,min=1"`
 	PageSize  int    `json:"pageSize" validate:"required,min=1,max=100"`
+	// 消息状态（可选），为空时返回所有状态的消息
+	Status string `json:"status,omitempty"`
 }
 
 // MessageListResponse 消息列表响应
@@ -124,132 +282,1085 @@ type MessageListResponse struct {
 type MessageDetailResponse struct {
 	ID        string                 `json:"id"`
 	SessionID string                 `json:"sessionId"`
+	ParentID  *string                `json:"parentId,omitempty"`
 	Role      string                 `json:"role"`
 	Content   string                 `json:"content"`
 	Tokens    int                    `json:"tokens"`
 	Sequence  int                    `json:"sequence"`
 	CreatedAt time.Time              `json:"createdAt"`
-	ToolCalls map[string]interface{} `json:"toolCalls,omitempty"`
+	Status    string                 `json:"status"`
+	ToolCalls []model.ToolCall       `json:"toolCalls,omitempty"`
 	Error     string                 `json:"error,omitempty"`
 	Meta      map[string]interface{} `json:"meta,omitempty"`
 }
 
 // SendMessage 发送消息
+// 用户消息和状态为 pending 的 AI 回复在生成前保存，生成结束后更新 AI 回复的内容和状态；
+// 生成失败的对话轮次保留在会话中，AI 回复标记为 failed 并记录失败原因
 func (s *messageService) SendMessage(ctx context.Context, req *SendMessageRequest) (*MessageResponse, error) {
+	return s.sendMessage(ctx, req, nil)
+}
+
+// SendMessageStream 以流式方式发送消息
+// 每收到一个文本片段调用一次 onChunk，AI 回复在收到首个片段后标记为 streaming，
+// 生成完成后保存拼接后的内容。onChunk 返回错误时（例如客户端断开）将取消生成，
+// AI 回复保存已生成的部分内容并标记为 aborted。
+func (s *messageService) SendMessageStream(ctx context.Context, req *SendMessageRequest, onChunk func(content string) error) (*MessageResponse, error) {
+	return s.sendMessageStream(ctx, req, nil, onChunk)
+}
+
+// sendMessage 在指定位置发送消息并生成回复，turn 为 nil 时追加到当前分支末尾
+func (s *messageService) sendMessage(ctx context.Context, req *SendMessageRequest, turn *conversationTurn) (*MessageResponse, error) {
 	s.logInfo(ctx, "开始发送消息", logger.Fields{
 		"sessionId": req.SessionID,
 		"userId":    req.UserID,
 	})
 
-	// 1. 验证会话存在且属于用户
-	session, err := s.sessionRepo.GetByID(ctx, req.SessionID)
+	// 1. 登记生成，从此刻起即可按 AI 回复消息ID或会话ID中止
+	gen, genCtx, err := s.startGeneration(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer s.finishGeneration(gen)
+
+	// 2. 验证会话存在且属于用户
+	session, err := s.getOwnedSession(ctx, req.SessionID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if turn == nil {
+		turn = &conversationTurn{parentID: session.LastMessageID}
+	}
+
+	// 3. 构建包含历史对话的请求
+	chatReq, meta, err := s.buildChatRequest(ctx, session, req, turn.parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. 保存用户消息和待生成的 AI 回复
+	userMessage, aiMessage, err := s.beginConversation(ctx, req, turn, chatReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// 5. 调用 AI 服务生成回复，中止时取消 genCtx
+	// 模型发起工具调用时执行工具并继续生成，直到模型给出最终回复
+	var toolMessages []*model.ChatMessage
+	var aiResponse *model.ChatResponse
+	for round := 1; ; round++ {
+		aiResponse, err = s.aiService.Chat(genCtx, chatReq)
+		if err != nil || len(aiResponse.ToolCalls) == 0 || len(chatReq.Tools) == 0 {
+			break
+		}
+
+		var saved []*model.ChatMessage
+		aiMessage, saved, err = s.continueWithTools(ctx, gen, chatReq, aiMessage, aiResponse, round)
+		if err != nil {
+			return nil, err
+		}
+		toolMessages = append(toolMessages, saved...)
+	}
+
+	status := model.MessageStatusCompleted
+	if err != nil {
+		if !isAborted(ctx, err) {
+			s.logError(ctx, "AI 生成回复失败", logger.Fields{
+				"sessionId": req.SessionID,
+				"messageId": aiMessage.ID,
+				"error":     err.Error(),
+			})
+			s.failAIMessage(ctx, aiMessage, err)
+			return nil, wrapAIError(err)
+		}
+
+		// 非流式生成被中止时没有已生成的内容
+		s.logInfo(ctx, "AI 生成回复已中止", logger.Fields{
+			"sessionId": req.SessionID,
+			"messageId": aiMessage.ID,
+		})
+		status = model.MessageStatusAborted
+		aiResponse = &model.ChatResponse{Model: session.ModelName}
+	}
+
+	// 6. 更新 AI 回复
+	return s.finishConversation(ctx, req, userMessage, toolMessages, aiMessage, aiResponse, status, meta)
+}
+
+// sendMessageStream 在指定位置以流式方式发送消息并生成回复，turn 为 nil 时追加到当前分支末尾
+func (s *messageService) sendMessageStream(ctx context.Context, req *SendMessageRequest, turn *conversationTurn, onChunk func(content string) error) (*MessageResponse, error) {
+	s.logInfo(ctx, "开始流式发送消息", logger.Fields{
+		"sessionId": req.SessionID,
+		"userId":    req.UserID,
+	})
+
+	// 1. 登记生成，从此刻起即可按 AI 回复消息ID或会话ID中止
+	gen, genCtx, err := s.startGeneration(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer s.finishGeneration(gen)
+
+	// 2. 验证会话存在且属于用户
+	session, err := s.getOwnedSession(ctx, req.SessionID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if turn == nil {
+		turn = &conversationTurn{parentID: session.LastMessageID}
+	}
+
+	// 3. 构建包含历史对话的请求
+	chatReq, meta, err := s.buildChatRequest(ctx, session, req, turn.parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. 保存用户消息和待生成的 AI 回复
+	userMessage, aiMessage, err := s.beginConversation(ctx, req, turn, chatReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// 5. 调用 AI 服务流式生成回复，中止或推送片段失败时取消 genCtx
+	// 模型发起工具调用时执行工具并继续生成，直到模型给出最终回复
+	var toolMessages []*model.ChatMessage
+	var content string
+	var final model.StreamChunk
+	for round := 1; ; round++ {
+		chunks, err := s.aiService.ChatStream(genCtx, chatReq)
+		if err != nil {
+			s.logError(ctx, "AI 流式生成回复失败", logger.Fields{
+				"sessionId": req.SessionID,
+				"messageId": aiMessage.ID,
+				"error":     err.Error(),
+			})
+			s.failAIMessage(ctx, aiMessage, err)
+			return nil, wrapAIError(err)
+		}
+
+		var chunkErr error
+		content, final, chunkErr = s.readStream(ctx, chunks, aiMessage, onChunk, gen.cancel)
+
+		if chunkErr != nil {
+			s.logWarn(ctx, "推送流式片段失败，已取消生成", logger.Fields{
+				"sessionId": req.SessionID,
+				"messageId": aiMessage.ID,
+				"error":     chunkErr.Error(),
+			})
+			s.abortAIMessage(ctx, aiMessage, content)
+			return nil, errors.NewMessageSendFailedError(chunkErr)
+		}
+
+		if !final.Done {
+			if ctx.Err() != nil {
+				// 通道在未收到完成块时关闭，说明请求上下文已取消
+				s.abortAIMessage(ctx, aiMessage, content)
+				return nil, errors.NewContextCancelledError()
+			}
+			// 生成被中止时完成块可能未送达，按中止处理
+			final = model.StreamChunk{Done: true, Error: errors.NewContextCancelledError()}
+		}
+
+		if final.Error != nil || len(final.ToolCalls) == 0 || len(chatReq.Tools) == 0 {
+			break
+		}
+
+		var saved []*model.ChatMessage
+		aiMessage, saved, err = s.continueWithTools(ctx, gen, chatReq, aiMessage, &model.ChatResponse{
+			Message:   content,
+			Model:     final.Model,
+			Usage:     final.Usage,
+			ToolCalls: final.ToolCalls,
+		}, round)
+		if err != nil {
+			return nil, err
+		}
+		toolMessages = append(toolMessages, saved...)
+	}
+
+	status := model.MessageStatusCompleted
+	if final.Error != nil {
+		if !isAborted(ctx, final.Error) {
+			s.logError(ctx, "AI 流式生成回复失败", logger.Fields{
+				"sessionId": req.SessionID,
+				"messageId": aiMessage.ID,
+				"error":     final.Error.Error(),
+			})
+			aiMessage.Content = content
+			s.failAIMessage(ctx, aiMessage, final.Error)
+			return nil, wrapAIError(final.Error)
+		}
+
+		// 生成被中止时保存已生成的部分内容
+		s.logInfo(ctx, "AI 流式生成回复已中止", logger.Fields{
+			"sessionId":     req.SessionID,
+			"messageId":     aiMessage.ID,
+			"contentLength": len(content),
+		})
+		status = model.MessageStatusAborted
+		final.Model = session.ModelName
+	}
+
+	// 6. 更新 AI 回复
+	return s.finishConversation(ctx, req, userMessage, toolMessages, aiMessage, &model.ChatResponse{
+		Message: content,
+		Model:   final.Model,
+		Usage:   final.Usage,
+		Parsed:  final.Parsed,
+	}, status, meta)
+}
+
+// readStream 读取流式生成结果，每个文本片段推送给 onChunk
+// AI 回复在收到首个片段后标记为 streaming。onChunk 返回错误时调用 cancel 取消生成，
+// 并继续读取直到通道关闭；返回拼接后的内容、完成块和推送片段的错误
+func (s *messageService) readStream(ctx context.Context, chunks <-chan model.StreamChunk, aiMessage *model.ChatMessage, onChunk func(content string) error, cancel context.CancelFunc) (string, model.StreamChunk, error) {
+	var content strings.Builder
+	var final model.StreamChunk
+	var chunkErr error
+
+	for chunk := range chunks {
+		if chunk.Done {
+			final = chunk
+			continue
+		}
+		if chunkErr != nil {
+			// 已取消生成，继续读取直到通道关闭
+			continue
+		}
+		if aiMessage.Status == model.MessageStatusPending {
+			s.markStreaming(ctx, aiMessage)
+		}
+		content.WriteString(chunk.Content)
+		if err := onChunk(chunk.Content); err != nil {
+			chunkErr = err
+			cancel()
+		}
+	}
+
+	return content.String(), final, chunkErr
+}
+
+// sessionTools 返回本次对话提供给模型的工具定义
+// 只有模型特性包含 tool-call 时才提供会话启用的工具，未注册的工具被忽略
+func (s *messageService) sessionTools(ctx context.Context, session *model.ChatSession, chatModel *model.Model) []model.ToolDefinition {
+	if s.toolRegistry == nil || len(session.Tools) == 0 {
+		return nil
+	}
+
+	if chatModel == nil || !chatModel.HasFeature(model.FeatureToolCall) {
+		s.logInfo(ctx, "模型不支持工具调用，本次对话不提供工具", logger.Fields{
+			"sessionId": session.ID,
+			"model":     session.ModelName,
+		})
+		return nil
+	}
+
+	var tools []model.ToolDefinition
+	for _, name := range session.Tools {
+		t, exists := s.toolRegistry.Get(name)
+		if !exists {
+			s.logWarn(ctx, "会话启用的工具未注册", logger.Fields{
+				"sessionId": session.ID,
+				"tool":      name,
+			})
+			continue
+		}
+		tools = append(tools, t.Definition())
+	}
+
+	return tools
+}
+
+// continueWithTools 保存模型发起的工具调用，执行工具并准备下一轮生成
+// 当前 AI 回复保存为带工具调用的已完成消息，每个工具结果保存为一条 function 消息，
+// 并创建新的待生成 AI 回复作为当前分支末端；工具调用和结果追加到 chatReq 的历史对话中，
+// chatReq.MessageID 更新为新 AI 回复的ID，新ID在保存前加入生成登记。
+// 达到 maxToolRounds 后不再提供工具。返回新的 AI 回复和本轮保存的消息
+func (s *messageService) continueWithTools(ctx context.Context, gen *generation, chatReq *model.ChatRequest, aiMessage *model.ChatMessage, aiResponse *model.ChatResponse, round int) (*model.ChatMessage, []*model.ChatMessage, error) {
+	s.logInfo(ctx, "模型发起工具调用", logger.Fields{
+		"sessionId": chatReq.SessionID,
+		"messageId": aiMessage.ID,
+		"round":     round,
+		"toolCalls": len(aiResponse.ToolCalls),
+	})
+
+	// 1. 保存发起工具调用的 AI 回复
+	toolCallsJSON, err := json.Marshal(aiResponse.ToolCalls)
+	if err != nil {
+		return nil, nil, errors.NewInternalError(fmt.Errorf("序列化工具调用失败: %w", err))
+	}
+	aiMessage.Content = aiResponse.Message
+	aiMessage.ToolCalls = datatypes.JSON(toolCallsJSON)
+	aiMessage.Status = model.MessageStatusCompleted
+	if aiResponse.Usage != nil {
+		aiMessage.Tokens = aiResponse.Usage.CompletionTokens
+	}
+	if err := s.updateAIMessage(ctx, aiMessage); err != nil {
+		return nil, nil, errors.NewInternalError(err)
+	}
+
+	// 2. 依次执行工具调用
+	functionMessages := make([]*model.ChatMessage, 0, len(aiResponse.ToolCalls))
+	for _, call := range aiResponse.ToolCalls {
+		callJSON, err := json.Marshal([]model.ToolCall{call})
+		if err != nil {
+			return nil, nil, errors.NewInternalError(fmt.Errorf("序列化工具调用失败: %w", err))
+		}
+
+		message := &model.ChatMessage{
+			SessionID: chatReq.SessionID,
+			Role:      "function",
+			ToolCalls: datatypes.JSON(callJSON),
+			Status:    model.MessageStatusCompleted,
+		}
+		result, callErr := s.executeToolCall(ctx, chatReq.Tools, call)
+		if callErr != nil {
+			// 工具调用失败时将错误信息作为工具结果回传给模型，由模型决定如何继续
+			result = fmt.Sprintf("工具调用失败: %s", callErr.Error())
+			message.Error = callErr.Error()
+		}
+		message.Content = result
+		message.Tokens = tokenizer.Estimate(result)
+		functionMessages = append(functionMessages, message)
+	}
+
+	// 3. 保存工具结果和下一轮待生成的 AI 回复，新回复的ID先加入生成登记，
+	// 保存后即可按新消息ID中止
+	nextMessage := &model.ChatMessage{
+		ID:        uuid.New().String(),
+		SessionID: chatReq.SessionID,
+		Role:      "assistant",
+		Status:    model.MessageStatusPending,
+	}
+	s.addGenerationKey(gen, nextMessage.ID)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		nextSeq, err := s.messageRepo.GetNextSequence(ctx, chatReq.SessionID)
+		if err != nil {
+			return fmt.Errorf("获取消息序列号失败: %w", err)
+		}
+
+		parent := aiMessage
+		for _, message := range append(functionMessages, nextMessage) {
+			parentID := parent.ID
+			message.ParentID = &parentID
+			message.Sequence = nextSeq
+			message.CreatedAt = time.Now()
+			if err := s.messageRepo.Create(ctx, message); err != nil {
+				return fmt.Errorf("保存消息失败: %w", err)
+			}
+			if err := s.sessionRepo.IncrementMessageCount(ctx, chatReq.SessionID); err != nil {
+				return fmt.Errorf("更新会话消息计数失败: %w", err)
+			}
+			parent = message
+			nextSeq++
+		}
+
+		if err := s.sessionRepo.UpdateLastMessage(ctx, chatReq.SessionID, nextMessage.ID); err != nil {
+			return fmt.Errorf("更新会话最后消息失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		s.logError(ctx, "保存工具调用结果失败", logger.Fields{
+			"sessionId": chatReq.SessionID,
+			"messageId": aiMessage.ID,
+			"error":     err.Error(),
+		})
+		return nil, nil, errors.NewInternalError(err)
+	}
+
+	// 4. 后续生成保存在新的 AI 回复中
+	chatReq.MessageID = nextMessage.ID
+
+	// 5. 将本轮的提问、工具调用和结果加入历史对话，下一轮只基于历史生成
+	if chatReq.Message != "" {
+		chatReq.History = append(chatReq.History, model.ChatTurn{Role: "user", Content: chatReq.Message})
+		chatReq.Message = ""
+	}
+	chatReq.History = append(chatReq.History, toChatTurn(aiMessage))
+	for _, message := range functionMessages {
+		chatReq.History = append(chatReq.History, toChatTurn(message))
+	}
+	s.fitToolHistory(ctx, chatReq)
+
+	if round >= maxToolRounds {
+		s.logWarn(ctx, "工具调用轮数达到上限，要求模型直接回答", logger.Fields{
+			"sessionId": chatReq.SessionID,
+			"rounds":    round,
+		})
+		chatReq.Tools = nil
+	}
+
+	saved := append([]*model.ChatMessage{aiMessage}, functionMessages...)
+	return nextMessage, saved, nil
+}
+
+// fitToolHistory 工具调用和结果加入历史对话后，按模型上下文大小丢弃最早的历史
+func (s *messageService) fitToolHistory(ctx context.Context, chatReq *model.ChatRequest) {
+	if s.catalog == nil || chatReq.Model == "" {
+		return
+	}
+	_, chatModel, err := s.catalog.ResolveModel(chatReq.Model)
+	if err != nil {
+		return
+	}
+
+	history, dropped := fitHistory(chatModel.ModelProperties.ContextSize, reservedOutputTokens(chatModel, chatReq.Options),
+		chatReq.SystemPrompt, chatReq.History)
+	if dropped == 0 {
+		return
+	}
+	chatReq.History = history
+	s.logInfo(ctx, "工具调用结果超出模型上下文，已丢弃最早的历史", logger.Fields{
+		"sessionId":    chatReq.SessionID,
+		"model":        chatReq.Model,
+		"contextSize":  chatModel.ModelProperties.ContextSize,
+		"droppedTurns": dropped,
+	})
+}
+
+// executeToolCall 执行一次工具调用，只允许调用本次对话提供给模型的工具
+func (s *messageService) executeToolCall(ctx context.Context, offered []model.ToolDefinition, call model.ToolCall) (string, error) {
+	allowed := false
+	for _, def := range offered {
+		if def.Name == call.Name {
+			allowed = true
+			break
+		}
+	}
+
+	t, exists := s.toolRegistry.Get(call.Name)
+	if !allowed || !exists {
+		return "", fmt.Errorf("工具 '%s' 不可用", call.Name)
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, toolCallTimeout)
+	defer cancel()
+
+	start := time.Now()
+	result, err := t.Call(callCtx, call.Arguments)
+	fields := logger.Fields{
+		"tool":       call.Name,
+		"toolCallId": call.ID,
+		"durationMs": time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+		s.logWarn(ctx, "工具调用失败", fields)
+		return "", err
+	}
+
+	s.logInfo(ctx, "工具调用完成", fields)
+	return result, nil
+}
+
+// getOwnedSession 获取会话并验证其属于指定用户
+func (s *messageService) getOwnedSession(ctx context.Context, sessionID, userID string) (*model.ChatSession, error) {
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, errors.NewSessionNotFoundError(req.SessionID)
+			return nil, errors.NewSessionNotFoundError(sessionID)
 		}
 		s.logError(ctx, "获取会话失败", logger.Fields{
-			"sessionId": req.SessionID,
+			"sessionId": sessionID,
 			"error":     err.Error(),
 		})
 		return nil, errors.NewInternalError(err)
 	}
 
 	// 验证会话所有权
-	if session.UserID != req.UserID {
+	if session.UserID != userID {
 		s.logWarn(ctx, "用户尝试访问其他用户的会话", logger.Fields{
-			"sessionId":    req.SessionID,
-			"userId":       req.UserID,
+			"sessionId":    sessionID,
+			"userId":       userID,
 			"sessionOwner": session.UserID,
 		})
 		return nil, errors.NewSessionAccessDeniedError()
 	}
 
-	// 2. 开始数据库事务
-	var userMessage *model.ChatMessage
-	var aiMessage *model.ChatMessage
-	var aiResponse *model.ChatResponse
+	return session, nil
+}
 
-	err = s.db.Transaction(func(tx *gorm.DB) error {
-		// 2.1 获取下一个序列号
-		nextSeq, err := s.messageRepo.GetNextSequence(ctx, req.SessionID)
-		if err != nil {
-			return fmt.Errorf("获取消息序列号失败: %w", err)
+// buildChatRequest 构建 AI 对话请求
+// 使用会话配置的模型、系统提示词和采样参数，历史对话依次为最新摘要和摘要之后的最近消息，
+// 历史消息取自以 leafID 为末端的分支，并按模型上下文大小裁剪
+func (s *messageService) buildChatRequest(ctx context.Context, session *model.ChatSession, req *SendMessageRequest, leafID *string) (*model.ChatRequest, *MessageMeta, error) {
+	summary, messages, err := s.loadHistory(ctx, session.ID, leafID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	options, err := resolveSessionSchema(session, mergeSessionOptions(session, req.Options))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	contextSize := 0
+	var chatModel *model.Model
+	if s.catalog != nil && session.ModelName != "" {
+		if _, m, err := s.catalog.ResolveModel(session.ModelName); err == nil {
+			chatModel = m
+			contextSize = m.ModelProperties.ContextSize
 		}
+	}
 
-		// 2.2 保存用户消息
-		userMessage = &model.ChatMessage{
-			SessionID: req.SessionID,
-			Role:      "user",
-			Content:   req.Message,
-			Sequence:  nextSeq,
-			CreatedAt: time.Now(),
+	// 文本片段并入消息内容，媒体片段需要模型支持对应的特性
+	message, media, err := s.splitParts(ctx, session, chatModel, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 检索会话挂载的知识库，相关分块作为参考资料附加到系统提示词
+	systemPrompt := session.SystemPrompt
+	citations := s.retrieveKnowledge(ctx, session, req.UserID, message)
+	if len(citations) > 0 {
+		systemPrompt = appendCitations(systemPrompt, citations)
+	}
+
+	window, err := buildContextWindow(contextSize, reservedOutputTokens(chatModel, options),
+		systemPrompt, summary, messages, message, media)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if window.info.DroppedMessages > 0 {
+		s.logInfo(ctx, "历史消息超出模型上下文，已丢弃最早的消息", logger.Fields{
+			"sessionId":       session.ID,
+			"model":           session.ModelName,
+			"contextSize":     contextSize,
+			"droppedMessages": window.info.DroppedMessages,
+		})
+	}
+
+	// AI 服务以 AI 回复消息ID和会话ID登记本次生成，用于中止
+	messageID := req.MessageID
+	if messageID == "" {
+		messageID = uuid.New().String()
+	}
+
+	chatReq := &model.ChatRequest{
+		Message:      message,
+		Parts:        media,
+		MessageID:    messageID,
+		SessionID:    session.ID,
+		Model:        session.ModelName,
+		Options:      options,
+		SystemPrompt: systemPrompt,
+		History:      window.history,
+		Tools:        s.sessionTools(ctx, session, chatModel),
+	}
+
+	return chatReq, &MessageMeta{ContextWindow: window.info, Citations: citations}, nil
+}
+
+// retrieveKnowledge 从会话挂载的知识库中检索与消息相关的分块
+// 检索失败不影响发送消息，只记录日志
+func (s *messageService) retrieveKnowledge(ctx context.Context, session *model.ChatSession, userID, message string) []model.Citation {
+	if s.knowledge == nil || len(session.KnowledgeBases) == 0 || strings.TrimSpace(message) == "" {
+		return nil
+	}
+
+	citations, err := s.knowledge.Search(ctx, userID, session.KnowledgeBases, message, 0)
+	if err != nil {
+		s.logWarn(ctx, "检索知识库失败，不使用参考资料", logger.Fields{
+			"sessionId":      session.ID,
+			"knowledgeBases": []string(session.KnowledgeBases),
+			"error":          err.Error(),
+		})
+		return nil
+	}
+
+	s.logInfo(ctx, "已检索知识库", logger.Fields{
+		"sessionId": session.ID,
+		"citations": len(citations),
+	})
+	return citations
+}
+
+// appendCitations 将检索到的分块按编号附加到系统提示词，要求模型以编号标注引用来源
+func appendCitations(systemPrompt string, citations []model.Citation) string {
+	var b strings.Builder
+	if systemPrompt != "" {
+		b.WriteString(systemPrompt)
+		b.WriteString("\n\n")
+	}
+	b.WriteString("以下是从知识库中检索到的参考资料。回答时优先依据这些资料，并以 [编号] 标注引用的来源；资料与问题无关时忽略。\n")
+	for i, citation := range citations {
+		source := citation.DocumentName
+		if citation.Heading != "" {
+			source += " > " + citation.Heading
 		}
+		fmt.Fprintf(&b, "\n[%d] %s\n%s\n", i+1, source, citation.Content)
+	}
+	return b.String()
+}
 
-		if err := s.messageRepo.Create(ctx, userMessage); err != nil {
-			return fmt.Errorf("保存用户消息失败: %w", err)
+// splitParts 校验消息片段，返回合并文本片段后的消息内容和媒体片段
+// 媒体片段需要附件存储可用、模型具有对应的特性，且内联数据不超过 maxAttachmentSize；
+// 引用上传文件的片段需要文件属于当前用户，返回的片段中填充文件的 MIME 类型和内容
+func (s *messageService) splitParts(ctx context.Context, session *model.ChatSession, chatModel *model.Model, req *SendMessageRequest) (string, []model.MessagePart, error) {
+	for i, part := range req.Parts {
+		if err := part.Validate(); err != nil {
+			return "", nil, errors.NewBadRequestError(fmt.Sprintf("parts[%d]: %v", i, err))
 		}
+	}
 
-		s.logInfo(ctx, "用户消息已保存", logger.Fields{
-			"messageId": userMessage.ID,
-			"sequence":  userMessage.Sequence,
+	message, media := model.SplitMessageParts(req.Message, req.Parts)
+	if message == "" && len(media) == 0 {
+		return "", nil, errors.NewBadRequestError("消息内容不能为空")
+	}
+	if len(media) == 0 {
+		return message, nil, nil
+	}
+	if s.attachmentRepo == nil {
+		return "", nil, errors.NewBadRequestError("当前服务不支持发送附件")
+	}
+
+	for i := range media {
+		if media[i].FileID == "" {
+			continue
+		}
+		if err := s.resolveFilePart(ctx, &media[i], req.UserID); err != nil {
+			return "", nil, err
+		}
+	}
+
+	for _, part := range media {
+		if base64.StdEncoding.DecodedLen(len(part.Data)) > maxAttachmentSize {
+			return "", nil, errors.NewBadRequestError(fmt.Sprintf("附件大小不能超过 %d MB", maxAttachmentSize>>20))
+		}
+		feature := model.MediaFeature(part.MimeType)
+		if chatModel == nil || !chatModel.HasFeature(feature) {
+			return "", nil, errors.NewBadRequestError(fmt.Sprintf("模型 '%s' 不支持 %s 类型的附件（需要 %s 特性）", session.ModelName, part.MimeType, feature))
+		}
+	}
+
+	return message, media, nil
+}
+
+// resolveFilePart 读取片段引用的上传文件，填充 MIME 类型和 base64 编码的内容
+func (s *messageService) resolveFilePart(ctx context.Context, part *model.MessagePart, userID string) error {
+	if s.files == nil {
+		return errors.NewBadRequestError("当前服务不支持引用上传文件")
+	}
+
+	file, data, err := s.files.ReadFile(ctx, part.FileID)
+	if err != nil {
+		return err
+	}
+	if file.UserID != userID {
+		s.logWarn(ctx, "用户无权引用文件", logger.Fields{
+			"fileId":    part.FileID,
+			"userId":    userID,
+			"fileOwner": file.UserID,
 		})
+		return errors.NewFileAccessDeniedError()
+	}
 
-		// 2.3 调用 AI 服务生成回复
-		chatReq := &model.ChatRequest{
-			Message:   req.Message,
-			MessageID: req.SessionID, // 使用会话ID作为消息ID传递给AI服务
-			Options:   req.Options,
+	part.MimeType = file.MimeType
+	part.Data = base64.StdEncoding.EncodeToString(data)
+	return nil
+}
+
+// saveAttachments 保存用户消息的媒体片段，内联数据解码后保存，引用上传文件的片段只保存文件ID
+func (s *messageService) saveAttachments(ctx context.Context, message *model.ChatMessage, media []model.MessagePart) error {
+	if len(media) == 0 {
+		return nil
+	}
+
+	attachments := make([]*model.ChatAttachment, 0, len(media))
+	for i, part := range media {
+		attachment := &model.ChatAttachment{
+			MessageID: message.ID,
+			SessionID: message.SessionID,
+			Position:  i,
+			MimeType:  part.MimeType,
+			URL:       part.URL,
+			CreatedAt: time.Now(),
 		}
+		if part.Data != "" {
+			data, err := base64.StdEncoding.DecodeString(part.Data)
+			if err != nil {
+				return fmt.Errorf("解码附件失败: %w", err)
+			}
+			attachment.Size = len(data)
+			if part.FileID == "" {
+				attachment.Data = data
+			}
+		}
+		if part.FileID != "" {
+			// 文件内容已在文件存储中，加载附件时再读取
+			fileID := part.FileID
+			attachment.FileID = &fileID
+		}
+		attachments = append(attachments, attachment)
+	}
 
-		aiResponse, err = s.aiService.Chat(ctx, chatReq)
-		if err != nil {
-			// AI 服务失败，保存错误信息到用户消息
-			userMessage.Error = err.Error()
-			if updateErr := s.messageRepo.Create(ctx, userMessage); updateErr != nil {
-				s.logError(ctx, "更新消息错误信息失败", logger.Fields{
-					"messageId": userMessage.ID,
-					"error":     updateErr.Error(),
-				})
+	if err := s.attachmentRepo.Create(ctx, attachments); err != nil {
+		return err
+	}
+	message.Attachments = attachments
+	return nil
+}
+
+// loadAttachments 为用户消息加载附件，覆盖消息上已有的附件
+func (s *messageService) loadAttachments(ctx context.Context, messages []*model.ChatMessage) error {
+	if s.attachmentRepo == nil {
+		return nil
+	}
+
+	var ids []string
+	for _, msg := range messages {
+		if msg.Role == "user" {
+			ids = append(ids, msg.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	attachments, err := s.attachmentRepo.GetByMessageIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	grouped := make(map[string][]*model.ChatAttachment)
+	for _, attachment := range attachments {
+		if attachment.FileID != nil {
+			ok, err := s.loadAttachmentFile(ctx, attachment)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
 			}
-			return errors.NewMessageSendFailedError(err)
 		}
+		grouped[attachment.MessageID] = append(grouped[attachment.MessageID], attachment)
+	}
+	for _, msg := range messages {
+		if msg.Role == "user" {
+			msg.Attachments = grouped[msg.ID]
+		}
+	}
+	return nil
+}
 
-		// 2.4 保存 AI 回复消息
-		aiMessage = &model.ChatMessage{
-			SessionID: req.SessionID,
-			Role:      "assistant",
-			Content:   aiResponse.Message,
-			Sequence:  nextSeq + 1,
-			CreatedAt: time.Now(),
+// loadAttachmentFile 从文件存储读取引用上传文件的附件内容
+// 文件已删除或无法引用文件时返回 false，该附件不再提供给模型
+func (s *messageService) loadAttachmentFile(ctx context.Context, attachment *model.ChatAttachment) (bool, error) {
+	if s.files == nil {
+		return false, nil
+	}
+
+	_, data, err := s.files.ReadFile(ctx, *attachment.FileID)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.CodeFileNotFound {
+			s.logWarn(ctx, "附件引用的文件已删除，跳过该附件", logger.Fields{
+				"messageId": attachment.MessageID,
+				"fileId":    *attachment.FileID,
+			})
+			return false, nil
 		}
+		return false, err
+	}
 
-		// 如果有 token 使用信息，保存到消息中
-		if aiResponse.Usage != nil {
-			aiMessage.Tokens = aiResponse.Usage.CompletionTokens
+	attachment.Data = data
+	return true, nil
+}
+
+// attachmentParts 将附件转换为媒体片段，引用上传文件的附件保留文件ID
+func attachmentParts(attachments []*model.ChatAttachment) []model.MessagePart {
+	var parts []model.MessagePart
+	for _, attachment := range attachments {
+		part := model.MessagePart{
+			Type:     model.PartTypeMedia,
+			MimeType: attachment.MimeType,
+			URL:      attachment.URL,
+		}
+		if len(attachment.Data) > 0 {
+			part.Data = base64.StdEncoding.EncodeToString(attachment.Data)
 		}
+		if attachment.FileID != nil {
+			part.FileID = *attachment.FileID
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
 
-		if err := s.messageRepo.Create(ctx, aiMessage); err != nil {
-			return fmt.Errorf("保存AI消息失败: %w", err)
+// loadHistory 加载以 leafID 为末端的分支上最新的摘要和摘要之后的最近消息
+// leafID 为 nil 时没有摘要和历史消息；
+// 只返回有内容、工具调用或附件的 user、assistant 和 function 消息，生成失败的对话轮次不包含在内。
+// 用户消息的附件一并加载
+func (s *messageService) loadHistory(ctx context.Context, sessionID string, leafID *string) (*model.ChatTurn, []*model.ChatMessage, error) {
+	if leafID == nil {
+		return nil, nil, nil
+	}
+
+	messages, err := s.messageRepo.GetBranch(ctx, sessionID, *leafID)
+	if err != nil {
+		s.logError(ctx, "获取历史消息失败", logger.Fields{
+			"sessionId": sessionID,
+			"error":     err.Error(),
+		})
+		return nil, nil, errors.NewInternalError(err)
+	}
+
+	// 只使用最后一条消息位于当前分支上的摘要，并只保留其后的消息；
+	// 基于其他分支（重新生成、编辑或切换分支前）生成的摘要不使用
+	var summaryTurn *model.ChatTurn
+	if s.summaryRepo != nil {
+		summaries, err := s.summaryRepo.GetBySessionID(ctx, sessionID)
+		if err != nil {
+			s.logError(ctx, "获取会话摘要失败", logger.Fields{
+				"sessionId": sessionID,
+				"error":     err.Error(),
+			})
+			return nil, nil, errors.NewInternalError(err)
+		}
+		if summary, position := branchSummary(summaries, messages); summary != nil {
+			summaryTurn = &model.ChatTurn{
+				Role:    "system",
+				Content: "以下是之前对话的摘要：\n" + summary.Summary,
+			}
+			messages = messages[position+1:]
 		}
+	}
 
-		s.logInfo(ctx, "AI消息已保存", logger.Fields{
-			"messageId": aiMessage.ID,
-			"sequence":  aiMessage.Sequence,
-			"tokens":    aiMessage.Tokens,
+	if len(messages) > historyMessageLimit {
+		messages = messages[len(messages)-historyMessageLimit:]
+	}
+
+	if err := s.loadAttachments(ctx, messages); err != nil {
+		s.logError(ctx, "获取历史消息附件失败", logger.Fields{
+			"sessionId": sessionID,
+			"error":     err.Error(),
 		})
+		return nil, nil, errors.NewInternalError(err)
+	}
 
-		// 2.5 更新会话信息
-		if err := s.sessionRepo.UpdateLastMessage(ctx, req.SessionID, aiMessage.ID); err != nil {
-			return fmt.Errorf("更新会话最后消息失败: %w", err)
+	history := make([]*model.ChatMessage, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Role != "user" && msg.Role != "assistant" && msg.Role != "function" {
+			continue
+		}
+		// 生成失败的对话轮次不计入上下文，同时移除本轮的工具调用和对应的用户消息
+		if msg.Status == model.MessageStatusFailed && msg.Role == "assistant" {
+			n := len(history)
+			for n > 0 && history[n-1].Role != "user" {
+				n--
+			}
+			if n > 0 {
+				n--
+			}
+			history = history[:n]
+			continue
+		}
+		if msg.Content == "" && len(msg.ToolCalls) == 0 && len(msg.Attachments) == 0 {
+			continue
+		}
+		history = append(history, msg)
+	}
+
+	return summaryTurn, history, nil
+}
+
+// mergeSessionOptions 合并请求参数和会话配置，请求中未指定的温度和 TopP 使用会话配置
+func mergeSessionOptions(session *model.ChatSession, options *model.ChatOptions) *model.ChatOptions {
+	if session.Temperature == nil && session.TopP == nil {
+		return options
+	}
+
+	merged := &model.ChatOptions{}
+	if options != nil {
+		*merged = *options
+	}
+	if merged.Temperature == nil {
+		merged.Temperature = session.Temperature
+	}
+	if merged.TopP == nil {
+		merged.TopP = session.TopP
+	}
+
+	return merged
+}
+
+// resolveSessionSchema 将对话参数中的 Schema 名称解析为会话中保存的 JSON Schema
+// 同时指定 jsonSchema 时以 jsonSchema 为准
+func resolveSessionSchema(session *model.ChatSession, options *model.ChatOptions) (*model.ChatOptions, error) {
+	if options == nil || options.SchemaName == "" || options.JSONSchema != nil {
+		return options, nil
+	}
+
+	schema, ok := session.Schemas[options.SchemaName].(map[string]interface{})
+	if !ok {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("会话中不存在名为 '%s' 的 Schema", options.SchemaName))
+	}
+
+	resolved := *options
+	resolved.JSONSchema = schema
+	return &resolved, nil
+}
+
+// isAborted 判断生成是否被中止：生成上下文已取消而请求本身仍然有效
+func isAborted(ctx context.Context, err error) bool {
+	appErr, ok := err.(*errors.AppError)
+	return ok && appErr.Code == errors.CodeContextCancelled && ctx.Err() == nil
+}
+
+// startGeneration 登记一次生成并返回派生自请求上下文的生成上下文，中止时取消生成上下文
+// 未指定 AI 回复消息ID时生成新的ID；消息ID正在生成中时拒绝请求
+func (s *messageService) startGeneration(ctx context.Context, req *SendMessageRequest) (*generation, context.Context, error) {
+	if req.MessageID == "" {
+		req.MessageID = uuid.New().String()
+	}
+
+	s.generationsMu.Lock()
+	defer s.generationsMu.Unlock()
+
+	if _, exists := s.generations[req.MessageID]; exists {
+		return nil, nil, errors.NewBadRequestError(fmt.Sprintf("消息 '%s' 正在生成中", req.MessageID))
+	}
+
+	genCtx, cancel := context.WithCancel(ctx)
+	gen := &generation{
+		sessionID:  req.SessionID,
+		messageIDs: []string{req.MessageID},
+		cancel:     cancel,
+	}
+	s.generations[req.MessageID] = gen
+	return gen, genCtx, nil
+}
+
+// addGenerationKey 将工具调用后新建的 AI 回复消息ID加入生成登记
+func (s *messageService) addGenerationKey(gen *generation, messageID string) {
+	s.generationsMu.Lock()
+	defer s.generationsMu.Unlock()
+
+	gen.messageIDs = append(gen.messageIDs, messageID)
+	s.generations[messageID] = gen
+}
+
+// finishGeneration 移除生成的全部登记并释放上下文
+func (s *messageService) finishGeneration(gen *generation) {
+	s.generationsMu.Lock()
+	defer s.generationsMu.Unlock()
+
+	for _, messageID := range gen.messageIDs {
+		if s.generations[messageID] == gen {
+			delete(s.generations, messageID)
+		}
+	}
+	gen.cancel()
+}
+
+// findGeneration 按 AI 回复消息ID查找正在进行的生成
+func (s *messageService) findGeneration(messageID string) (*generation, bool) {
+	s.generationsMu.Lock()
+	defer s.generationsMu.Unlock()
+
+	gen, exists := s.generations[messageID]
+	return gen, exists
+}
+
+// sessionGenerations 返回会话正在进行的全部生成
+func (s *messageService) sessionGenerations(sessionID string) []*generation {
+	s.generationsMu.Lock()
+	defer s.generationsMu.Unlock()
+
+	var generations []*generation
+	seen := make(map[*generation]bool)
+	for _, gen := range s.generations {
+		if gen.sessionID == sessionID && !seen[gen] {
+			seen[gen] = true
+			generations = append(generations, gen)
+		}
+	}
+	return generations
+}
+
+// wrapAIError 转换 AI 服务错误
+// 模型不存在、提供商未配置等请求层面的错误直接返回，其余错误包装为消息发送失败
+func wrapAIError(err error) error {
+	if appErr, ok := err.(*errors.AppError); ok {
+		switch appErr.Code {
+		case errors.CodeBadRequest, errors.CodeValidationError, errors.CodeModelNotFound, errors.CodeProviderNotFound, errors.CodeServiceUnavailable, errors.CodeStructuredOutput:
+			return appErr
+		}
+	}
+	return errors.NewMessageSendFailedError(err)
+}
+
+// beginConversation 在事务中保存用户消息和状态为 pending 的 AI 回复，并更新会话信息
+// 新用户消息挂在 turn.parentID 下，重新生成时复用 turn.userMessage；
+// AI 回复挂在用户消息下并成为当前分支的末端。新用户消息的内容和附件取自 chatReq，
+// AI 回复使用生成前分配的 chatReq.MessageID
+func (s *messageService) beginConversation(ctx context.Context, req *SendMessageRequest, turn *conversationTurn, chatReq *model.ChatRequest) (*model.ChatMessage, *model.ChatMessage, error) {
+	userMessage := turn.userMessage
+	var aiMessage *model.ChatMessage
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 1. 获取下一个序列号
+		nextSeq, err := s.messageRepo.GetNextSequence(ctx, req.SessionID)
+		if err != nil {
+			return fmt.Errorf("获取消息序列号失败: %w", err)
+		}
+
+		// 2. 保存用户消息
+		if userMessage == nil {
+			userMessage = &model.ChatMessage{
+				SessionID: req.SessionID,
+				Role:      "user",
+				Content:   chatReq.Message,
+				Tokens:    tokenizer.Estimate(chatReq.Message),
+				Status:    model.MessageStatusCompleted,
+				Sequence:  nextSeq,
+				ParentID:  turn.parentID,
+				CreatedAt: time.Now(),
+			}
+
+			if err := s.messageRepo.Create(ctx, userMessage); err != nil {
+				return fmt.Errorf("保存用户消息失败: %w", err)
+			}
+			if err := s.saveAttachments(ctx, userMessage, chatReq.Parts); err != nil {
+				return fmt.Errorf("保存消息附件失败: %w", err)
+			}
+			if err := s.sessionRepo.IncrementMessageCount(ctx, req.SessionID); err != nil {
+				return fmt.Errorf("更新会话消息计数失败: %w", err)
+			}
+
+			s.logInfo(ctx, "用户消息已保存", logger.Fields{
+				"messageId": userMessage.ID,
+				"sequence":  userMessage.Sequence,
+			})
+			nextSeq++
+		}
+
+		// 3. 保存待生成的 AI 回复
+		parentID := userMessage.ID
+		aiMessage = &model.ChatMessage{
+			ID:        chatReq.MessageID,
+			SessionID: req.SessionID,
+			Role:      "assistant",
+			Status:    model.MessageStatusPending,
+			Sequence:  nextSeq,
+			ParentID:  &parentID,
+			CreatedAt: time.Now(),
 		}
 
-		if err := s.sessionRepo.IncrementMessageCount(ctx, req.SessionID); err != nil {
-			return fmt.Errorf("更新会话消息计数失败: %w", err)
+		if err := s.messageRepo.Create(ctx, aiMessage); err != nil {
+			return fmt.Errorf("保存AI消息失败: %w", err)
 		}
 		if err := s.sessionRepo.IncrementMessageCount(ctx, req.SessionID); err != nil {
 			return fmt.Errorf("更新会话消息计数失败: %w", err)
 		}
 
+		// 4. 更新会话的当前分支
+		if err := s.sessionRepo.UpdateLastMessage(ctx, req.SessionID, aiMessage.ID); err != nil {
+			return fmt.Errorf("更新会话最后消息失败: %w", err)
+		}
+
 		return nil
 	})
 
@@ -260,31 +1371,54 @@ func (s *messageService) SendMessage(ctx context.Context, req *SendMessageReques
 		})
 		// 如果是已知的应用错误，直接返回
 		if appErr, ok := err.(*errors.AppError); ok {
-			return nil, appErr
+			return nil, nil, appErr
+		}
+		return nil, nil, errors.NewInternalError(err)
+	}
+
+	return userMessage, aiMessage, nil
+}
+
+// finishConversation 保存 AI 回复的内容和最终状态（completed 或 aborted），并构建响应
+// toolMessages 为本轮对话中发起工具调用的 AI 回复和 function 消息
+func (s *messageService) finishConversation(ctx context.Context, req *SendMessageRequest, userMessage *model.ChatMessage, toolMessages []*model.ChatMessage, aiMessage *model.ChatMessage, aiResponse *model.ChatResponse, status string, meta *MessageMeta) (*MessageResponse, error) {
+	aiMessage.Content = aiResponse.Message
+	aiMessage.Status = status
+	// 记录回复引用的知识库分块
+	if meta != nil && len(meta.Citations) > 0 {
+		if data, err := json.Marshal(map[string]interface{}{"citations": meta.Citations}); err == nil {
+			aiMessage.Meta = datatypes.JSON(data)
 		}
+	}
+	// 如果有 token 使用信息，保存到消息中
+	if aiResponse.Usage != nil {
+		aiMessage.Tokens = aiResponse.Usage.CompletionTokens
+	}
+
+	if err := s.updateAIMessage(ctx, aiMessage); err != nil {
 		return nil, errors.NewInternalError(err)
 	}
 
-	// 3. 构建响应
+	s.logInfo(ctx, "AI消息已保存", logger.Fields{
+		"messageId": aiMessage.ID,
+		"sequence":  aiMessage.Sequence,
+		"tokens":    aiMessage.Tokens,
+		"status":    aiMessage.Status,
+	})
+
+	// 构建响应
 	response := &MessageResponse{
-		MessageID: aiMessage.ID,
-		SessionID: req.SessionID,
-		UserMessage: &Message{
-			ID:        userMessage.ID,
-			Role:      userMessage.Role,
-			Content:   userMessage.Content,
-			Sequence:  userMessage.Sequence,
-			CreatedAt: userMessage.CreatedAt,
-		},
-		AIMessage: &Message{
-			ID:        aiMessage.ID,
-			Role:      aiMessage.Role,
-			Content:   aiMessage.Content,
-			Sequence:  aiMessage.Sequence,
-			CreatedAt: aiMessage.CreatedAt,
-		},
-		Model: aiResponse.Model,
-		Usage: aiResponse.Usage,
+		MessageID:   aiMessage.ID,
+		SessionID:   req.SessionID,
+		UserMessage: newMessage(userMessage),
+		AIMessage:   newMessage(aiMessage),
+		Model:       aiResponse.Model,
+		Usage:       aiResponse.Usage,
+		Meta:        meta,
+		Parsed:      aiResponse.Parsed,
+	}
+	for _, msg := range toolMessages {
+		response.ToolMessages = append(response.ToolMessages, newMessage(msg))
 	}
 
 	s.logInfo(ctx, "消息发送成功", logger.Fields{
@@ -294,9 +1428,93 @@ func (s *messageService) SendMessage(ctx context.Context, req *SendMessageReques
 		"model":     aiResponse.Model,
 	})
 
+	// 后台检查是否需要生成摘要，不影响本次请求
+	if s.summaryScheduler != nil {
+		s.summaryScheduler.Schedule(req.SessionID)
+	}
+
 	return response, nil
 }
 
+// newMessage 将消息实体转换为消息响应
+func newMessage(msg *model.ChatMessage) *Message {
+	return &Message{
+		ID:        msg.ID,
+		ParentID:  msg.ParentID,
+		Role:      msg.Role,
+		Content:   msg.Content,
+		Status:    msg.Status,
+		Sequence:  msg.Sequence,
+		CreatedAt: msg.CreatedAt,
+		ToolCalls: decodeToolCalls(msg.ToolCalls),
+	}
+}
+
+// decodeToolCalls 解析消息中保存的工具调用，内容无法解析时返回 nil
+func decodeToolCalls(data datatypes.JSON) []model.ToolCall {
+	if len(data) == 0 {
+		return nil
+	}
+
+	var toolCalls []model.ToolCall
+	if err := json.Unmarshal(data, &toolCalls); err != nil {
+		return nil
+	}
+	return toolCalls
+}
+
+// markStreaming 将 AI 回复标记为正在流式生成，更新失败不影响生成
+func (s *messageService) markStreaming(ctx context.Context, aiMessage *model.ChatMessage) {
+	aiMessage.Status = model.MessageStatusStreaming
+	if err := s.messageRepo.UpdateFields(ctx, aiMessage.ID, map[string]interface{}{
+		"status": aiMessage.Status,
+	}); err != nil {
+		s.logWarn(ctx, "更新AI消息状态失败", logger.Fields{
+			"messageId": aiMessage.ID,
+			"status":    aiMessage.Status,
+			"error":     err.Error(),
+		})
+	}
+}
+
+// failAIMessage 将 AI 回复标记为生成失败并记录失败原因，已生成的部分内容一并保存
+func (s *messageService) failAIMessage(ctx context.Context, aiMessage *model.ChatMessage, genErr error) {
+	aiMessage.Status = model.MessageStatusFailed
+	aiMessage.Error = genErr.Error()
+
+	_ = s.updateAIMessage(ctx, aiMessage)
+}
+
+// abortAIMessage 将 AI 回复标记为已中止，并保存已生成的部分内容
+func (s *messageService) abortAIMessage(ctx context.Context, aiMessage *model.ChatMessage, content string) {
+	aiMessage.Status = model.MessageStatusAborted
+	aiMessage.Content = content
+
+	_ = s.updateAIMessage(ctx, aiMessage)
+}
+
+// updateAIMessage 保存 AI 回复的内容、工具调用、Token 数量、状态、错误信息和元数据
+// 请求上下文已取消（如客户端断开）时仍需保存，因此不继承其取消信号
+func (s *messageService) updateAIMessage(ctx context.Context, aiMessage *model.ChatMessage) error {
+	err := s.messageRepo.UpdateFields(context.WithoutCancel(ctx), aiMessage.ID, map[string]interface{}{
+		"content":    aiMessage.Content,
+		"tool_calls": aiMessage.ToolCalls,
+		"tokens":     aiMessage.Tokens,
+		"status":     aiMessage.Status,
+		"error":      aiMessage.Error,
+		"meta":       aiMessage.Meta,
+	})
+	if err != nil {
+		s.logError(ctx, "更新AI消息失败", logger.Fields{
+			"messageId": aiMessage.ID,
+			"status":    aiMessage.Status,
+			"error":     err.Error(),
+		})
+		return fmt.Errorf("更新AI消息失败: %w", err)
+	}
+	return nil
+}
+
 // GetMessages 获取消息历史
 func (s *messageService) GetMessages(ctx context.Context, req *GetMessagesRequest) (*MessageListResponse, error) {
 	s.logInfo(ctx, "获取消息历史", logger.Fields{
@@ -304,6 +1522,7 @@ func (s *messageService) GetMessages(ctx context.Context, req *GetMessagesReques
 		"userId":    req.UserID,
 		"pageNo":    req.PageNo,
 		"pageSize":  req.PageSize,
+		"status":    req.Status,
 	})
 
 	// 1. 验证会话存在且属于用户
@@ -330,7 +1549,14 @@ func (s *messageService) GetMessages(ctx context.Context, req *GetMessagesReques
 	}
 
 	// 2. 查询消息列表
-	messages, totalCount, err := s.messageRepo.GetBySessionID(ctx, req.SessionID, req.PageNo, req.PageSize)
+	// 只返回当前分支上的消息
+	filters := &repository.MessageFilters{
+		Status: req.Status,
+	}
+	if session.LastMessageID != nil {
+		filters.BranchLeafID = *session.LastMessageID
+	}
+	messages, totalCount, err := s.messageRepo.GetBySessionID(ctx, req.SessionID, req.PageNo, req.PageSize, filters)
 	if err != nil {
 		s.logError(ctx, "查询消息列表失败", logger.Fields{
 			"sessionId": req.SessionID,
@@ -342,28 +1568,7 @@ func (s *messageService) GetMessages(ctx context.Context, req *GetMessagesReques
 	// 3. 转换为响应格式
 	messageDetails := make([]*MessageDetailResponse, 0, len(messages))
 	for _, msg := range messages {
-		detail := &MessageDetailResponse{
-			ID:        msg.ID,
-			SessionID: msg.SessionID,
-			Role:      msg.Role,
-			Content:   msg.Content,
-			Tokens:    msg.Tokens,
-			Sequence:  msg.Sequence,
-			CreatedAt: msg.CreatedAt,
-			Error:     msg.Error,
-		}
-
-		// 处理 ToolCalls 和 Meta（如果有）
-		if msg.ToolCalls != nil {
-			detail.ToolCalls = make(map[string]interface{})
-			// 这里需要根据实际的 ToolCalls 结构进行转换
-		}
-		if msg.Meta != nil {
-			detail.Meta = make(map[string]interface{})
-			// 这里需要根据实际的 Meta 结构进行转换
-		}
-
-		messageDetails = append(messageDetails, detail)
+		messageDetails = append(messageDetails, newMessageDetail(msg))
 	}
 
 	// 4. 计算总页数
@@ -432,69 +1637,385 @@ func (s *messageService) GetMessageByID(ctx context.Context, messageID, userID s
 	}
 
 	// 3. 构建响应
-	response := &MessageDetailResponse{
-		ID:        message.ID,
-		SessionID: message.SessionID,
-		Role:      message.Role,
-		Content:   message.Content,
-		Tokens:    message.Tokens,
-		Sequence:  message.Sequence,
-		CreatedAt: message.CreatedAt,
-		Error:     message.Error,
+	response := newMessageDetail(message)
+
+	s.logInfo(ctx, "消息详情查询成功", logger.Fields{
+		"messageId": messageID,
+	})
+
+	return response, nil
+}
+
+// newMessageDetail 将消息实体转换为消息详情响应
+func newMessageDetail(msg *model.ChatMessage) *MessageDetailResponse {
+	detail := &MessageDetailResponse{
+		ID:        msg.ID,
+		SessionID: msg.SessionID,
+		ParentID:  msg.ParentID,
+		Role:      msg.Role,
+		Content:   msg.Content,
+		Tokens:    msg.Tokens,
+		Sequence:  msg.Sequence,
+		CreatedAt: msg.CreatedAt,
+		Status:    msg.Status,
+		ToolCalls: decodeToolCalls(msg.ToolCalls),
+		Error:     msg.Error,
 	}
 
-	// 处理 ToolCalls 和 Meta（如果有）
-	if message.ToolCalls != nil {
-		response.ToolCalls = make(map[string]interface{})
-		// 这里需要根据实际的 ToolCalls 结构进行转换
+	// 处理 Meta（如果有）
+	if len(msg.Meta) > 0 {
+		detail.Meta = make(map[string]interface{})
+		_ = json.Unmarshal(msg.Meta, &detail.Meta)
 	}
-	if message.Meta != nil {
-		response.Meta = make(map[string]interface{})
-		// 这里需要根据实际的 Meta 结构进行转换
+
+	return detail
+}
+
+// RegenerateMessage 重新生成 AI 回复
+// 新回复与原回复挂在同一条用户消息下，历史对话取自该用户消息之前的分支，原回复保留
+func (s *messageService) RegenerateMessage(ctx context.Context, req *RegenerateMessageRequest, onChunk func(content string) error) (*MessageResponse, error) {
+	s.logInfo(ctx, "重新生成AI回复", logger.Fields{
+		"messageId": req.MessageID,
+		"userId":    req.UserID,
+	})
+
+	original, _, err := s.getOwnedMessage(ctx, req.MessageID, req.UserID)
+	if err != nil {
+		return nil, err
 	}
 
-	s.logInfo(ctx, "消息详情查询成功", logger.Fields{
-		"messageId": messageID,
+	if original.Role != "assistant" || original.ParentID == nil {
+		return nil, errors.NewBadRequestError("只能重新生成 AI 回复")
+	}
+
+	userMessage, err := s.turnUserMessage(ctx, original)
+	if err != nil {
+		s.logError(ctx, "查询AI回复对应的用户消息失败", logger.Fields{
+			"messageId": original.ID,
+			"parentId":  *original.ParentID,
+			"error":     err.Error(),
+		})
+		return nil, errors.NewInternalError(err)
+	}
+
+	if err := s.loadAttachments(ctx, []*model.ChatMessage{userMessage}); err != nil {
+		s.logError(ctx, "查询用户消息附件失败", logger.Fields{
+			"messageId": userMessage.ID,
+			"error":     err.Error(),
+		})
+		return nil, errors.NewInternalError(err)
+	}
+
+	sendReq := &SendMessageRequest{
+		SessionID: original.SessionID,
+		Message:   userMessage.Content,
+		UserID:    req.UserID,
+		Options:   req.Options,
+		Parts:     attachmentParts(userMessage.Attachments),
+		MessageID: req.AIMessageID,
+	}
+	turn := &conversationTurn{parentID: userMessage.ParentID, userMessage: userMessage}
+
+	if onChunk != nil {
+		return s.sendMessageStream(ctx, sendReq, turn, onChunk)
+	}
+	return s.sendMessage(ctx, sendReq, turn)
+}
+
+// turnUserMessage 沿父消息向上查找 AI 回复所在对话轮次的用户消息
+// 使用工具时 AI 回复与用户消息之间还有发起工具调用的回复和 function 消息
+func (s *messageService) turnUserMessage(ctx context.Context, aiMessage *model.ChatMessage) (*model.ChatMessage, error) {
+	parentID := aiMessage.ParentID
+	for parentID != nil {
+		parent, err := s.messageRepo.GetByID(ctx, *parentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.Role == "user" {
+			return parent, nil
+		}
+		parentID = parent.ParentID
+	}
+	return nil, fmt.Errorf("AI 回复 %s 没有对应的用户消息", aiMessage.ID)
+}
+
+// EditMessage 编辑用户消息并重新发送
+// 编辑后的消息与原消息挂在同一父消息下，原消息及其后续对话保留在原分支中
+func (s *messageService) EditMessage(ctx context.Context, req *EditMessageRequest, onChunk func(content string) error) (*MessageResponse, error) {
+	s.logInfo(ctx, "编辑并重新发送消息", logger.Fields{
+		"messageId": req.MessageID,
+		"userId":    req.UserID,
 	})
 
+	original, _, err := s.getOwnedMessage(ctx, req.MessageID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if original.Role != "user" {
+		return nil, errors.NewBadRequestError("只能编辑用户消息")
+	}
+
+	// 编辑只修改文本，原消息的附件保留在编辑后的消息中
+	if err := s.loadAttachments(ctx, []*model.ChatMessage{original}); err != nil {
+		s.logError(ctx, "查询用户消息附件失败", logger.Fields{
+			"messageId": original.ID,
+			"error":     err.Error(),
+		})
+		return nil, errors.NewInternalError(err)
+	}
+
+	sendReq := &SendMessageRequest{
+		SessionID: original.SessionID,
+		Message:   req.Message,
+		UserID:    req.UserID,
+		Options:   req.Options,
+		Parts:     attachmentParts(original.Attachments),
+		MessageID: req.AIMessageID,
+	}
+	turn := &conversationTurn{parentID: original.ParentID}
+
+	if onChunk != nil {
+		return s.sendMessageStream(ctx, sendReq, turn, onChunk)
+	}
+	return s.sendMessage(ctx, sendReq, turn)
+}
+
+// GetSiblings 获取与指定消息同属一个父消息的所有分支
+func (s *messageService) GetSiblings(ctx context.Context, messageID, userID string) (*MessageSiblingsResponse, error) {
+	message, session, err := s.getOwnedMessage(ctx, messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	siblings, err := s.messageRepo.GetChildren(ctx, message.SessionID, message.ParentID)
+	if err != nil {
+		s.logError(ctx, "查询分支消息失败", logger.Fields{
+			"messageId": messageID,
+			"error":     err.Error(),
+		})
+		return nil, errors.NewInternalError(err)
+	}
+
+	// 当前分支上的消息
+	active := make(map[string]bool)
+	if session.LastMessageID != nil {
+		branch, err := s.messageRepo.GetBranch(ctx, session.ID, *session.LastMessageID)
+		if err != nil {
+			s.logError(ctx, "查询当前分支失败", logger.Fields{
+				"sessionId": session.ID,
+				"error":     err.Error(),
+			})
+			return nil, errors.NewInternalError(err)
+		}
+		for _, msg := range branch {
+			active[msg.ID] = true
+		}
+	}
+
+	response := &MessageSiblingsResponse{
+		ParentID: message.ParentID,
+		Siblings: make([]*MessageDetailResponse, 0, len(siblings)),
+	}
+	for _, sibling := range siblings {
+		response.Siblings = append(response.Siblings, newMessageDetail(sibling))
+		if active[sibling.ID] {
+			response.ActiveID = sibling.ID
+		}
+	}
+
 	return response, nil
 }
 
-// AbortMessage 中止消息生成
-func (s *messageService) AbortMessage(ctx context.Context, messageID, userID string) error {
-	s.logInfo(ctx, "中止消息生成", logger.Fields{
+// SelectBranch 切换到指定消息所在的分支
+// 从指定消息开始沿最新的子消息向下查找分支末端，并将其设为会话的当前分支
+func (s *messageService) SelectBranch(ctx context.Context, messageID, userID string) (*MessageDetailResponse, error) {
+	s.logInfo(ctx, "切换消息分支", logger.Fields{
 		"messageId": messageID,
 		"userId":    userID,
 	})
 
-	// 1. 查询消息
+	leaf, _, err := s.getOwnedMessage(ctx, messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		children, err := s.messageRepo.GetChildren(ctx, leaf.SessionID, &leaf.ID)
+		if err != nil {
+			s.logError(ctx, "查询子消息失败", logger.Fields{
+				"messageId": leaf.ID,
+				"error":     err.Error(),
+			})
+			return nil, errors.NewInternalError(err)
+		}
+		if len(children) == 0 {
+			break
+		}
+		leaf = children[len(children)-1]
+	}
+
+	if err := s.sessionRepo.UpdateLastMessage(ctx, leaf.SessionID, leaf.ID); err != nil {
+		s.logError(ctx, "更新会话当前分支失败", logger.Fields{
+			"sessionId": leaf.SessionID,
+			"messageId": leaf.ID,
+			"error":     err.Error(),
+		})
+		return nil, errors.NewInternalError(err)
+	}
+
+	s.logInfo(ctx, "消息分支已切换", logger.Fields{
+		"sessionId": leaf.SessionID,
+		"messageId": messageID,
+		"leafId":    leaf.ID,
+	})
+
+	return newMessageDetail(leaf), nil
+}
+
+// AppendUserMessage 在会话当前分支末尾追加一条用户消息
+// 用于将语音转写等外部输入的文本写入会话，追加的消息成为当前分支的末端，不触发 AI 回复
+func (s *messageService) AppendUserMessage(ctx context.Context, sessionID, userID, content string) (*MessageDetailResponse, error) {
+	s.logInfo(ctx, "追加用户消息", logger.Fields{
+		"sessionId": sessionID,
+		"userId":    userID,
+	})
+
+	// 1. 验证会话存在且属于用户
+	session, err := s.getOwnedSession(ctx, sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 保存用户消息并更新会话的当前分支
+	var userMessage *model.ChatMessage
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		nextSeq, err := s.messageRepo.GetNextSequence(ctx, sessionID)
+		if err != nil {
+			return fmt.Errorf("获取消息序列号失败: %w", err)
+		}
+
+		userMessage = &model.ChatMessage{
+			SessionID: sessionID,
+			Role:      "user",
+			Content:   content,
+			Tokens:    tokenizer.Estimate(content),
+			Status:    model.MessageStatusCompleted,
+			Sequence:  nextSeq,
+			ParentID:  session.LastMessageID,
+			CreatedAt: time.Now(),
+		}
+		if err := s.messageRepo.Create(ctx, userMessage); err != nil {
+			return fmt.Errorf("保存用户消息失败: %w", err)
+		}
+		if err := s.sessionRepo.IncrementMessageCount(ctx, sessionID); err != nil {
+			return fmt.Errorf("更新会话消息计数失败: %w", err)
+		}
+		if err := s.sessionRepo.UpdateLastMessage(ctx, sessionID, userMessage.ID); err != nil {
+			return fmt.Errorf("更新会话最后消息失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		s.logError(ctx, "追加用户消息失败", logger.Fields{
+			"sessionId": sessionID,
+			"error":     err.Error(),
+		})
+		return nil, errors.NewInternalError(err)
+	}
+
+	s.logInfo(ctx, "用户消息已追加", logger.Fields{
+		"sessionId": sessionID,
+		"messageId": userMessage.ID,
+		"sequence":  userMessage.Sequence,
+	})
+
+	return newMessageDetail(userMessage), nil
+}
+
+// getOwnedMessage 获取消息及其所属会话，并验证会话属于指定用户
+func (s *messageService) getOwnedMessage(ctx context.Context, messageID, userID string) (*model.ChatMessage, *model.ChatSession, error) {
 	message, err := s.messageRepo.GetByID(ctx, messageID)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			// 消息不存在，视为幂等操作，直接返回成功
-			s.logInfo(ctx, "消息不存在，无需中止", logger.Fields{
-				"messageId": messageID,
-			})
-			return nil
+			return nil, nil, errors.NewMessageNotFoundError(messageID)
 		}
 		s.logError(ctx, "查询消息失败", logger.Fields{
 			"messageId": messageID,
 			"error":     err.Error(),
 		})
-		return errors.NewInternalError(err)
+		return nil, nil, errors.NewInternalError(err)
 	}
 
-	// 2. 验证消息所属会话的所有权
 	session, err := s.sessionRepo.GetByID(ctx, message.SessionID)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return errors.NewSessionNotFoundError(message.SessionID)
+			return nil, nil, errors.NewSessionNotFoundError(message.SessionID)
 		}
 		s.logError(ctx, "获取会话失败", logger.Fields{
 			"sessionId": message.SessionID,
 			"error":     err.Error(),
 		})
-		return errors.NewInternalError(err)
+		return nil, nil, errors.NewInternalError(err)
+	}
+
+	if session.UserID != userID {
+		s.logWarn(ctx, "用户尝试访问其他用户的消息", logger.Fields{
+			"messageId":    messageID,
+			"userId":       userID,
+			"sessionOwner": session.UserID,
+		})
+		return nil, nil, errors.NewMessageAccessDeniedError()
+	}
+
+	return message, session, nil
+}
+
+// AbortMessage 中止消息生成
+// 消息正在生成时取消上游模型调用，已生成的部分内容由发送消息的请求保存并标记为已中止；
+// 消息已生成完成时视为幂等操作，返回 false
+func (s *messageService) AbortMessage(ctx context.Context, messageID, userID string) (bool, error) {
+	s.logInfo(ctx, "中止消息生成", logger.Fields{
+		"messageId": messageID,
+		"userId":    userID,
+	})
+
+	// 1. 查找消息所属会话：生成在保存 AI 回复之前登记，
+	// 正在生成的消息从生成登记中查找，其他消息从消息表中查找
+	gen, generating := s.findGeneration(messageID)
+	var sessionID string
+	if generating {
+		sessionID = gen.sessionID
+	} else {
+		message, err := s.messageRepo.GetByID(ctx, messageID)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				// 消息不存在，视为幂等操作，直接返回成功
+				s.logInfo(ctx, "消息不存在，无需中止", logger.Fields{
+					"messageId": messageID,
+				})
+				return false, nil
+			}
+			s.logError(ctx, "查询消息失败", logger.Fields{
+				"messageId": messageID,
+				"error":     err.Error(),
+			})
+			return false, errors.NewInternalError(err)
+		}
+		sessionID = message.SessionID
+	}
+
+	// 2. 验证消息所属会话的所有权
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return false, errors.NewSessionNotFoundError(sessionID)
+		}
+		s.logError(ctx, "获取会话失败", logger.Fields{
+			"sessionId": sessionID,
+			"error":     err.Error(),
+		})
+		return false, errors.NewInternalError(err)
 	}
 
 	if session.UserID != userID {
@@ -503,24 +2024,59 @@ func (s *messageService) AbortMessage(ctx context.Context, messageID, userID str
 			"userId":       userID,
 			"sessionOwner": session.UserID,
 		})
-		return errors.NewMessageAccessDeniedError()
+		return false, errors.NewMessageAccessDeniedError()
 	}
 
-	// 3. 调用 AI 服务中止会话
-	// 注意：这里使用 SessionID 而不是 MessageID，因为 AI 服务是基于会话的
-	if err := s.aiService.AbortChat(ctx, message.SessionID); err != nil {
-		s.logError(ctx, "中止AI会话失败", logger.Fields{
-			"sessionId": message.SessionID,
+	if !generating {
+		s.logInfo(ctx, "消息已生成完成，无需中止", logger.Fields{
 			"messageId": messageID,
+		})
+		return false, nil
+	}
+
+	// 3. 取消生成
+	return s.abortGeneration(ctx, messageID, sessionID, []*generation{gen})
+}
+
+// AbortSession 中止会话正在进行的生成
+func (s *messageService) AbortSession(ctx context.Context, sessionID, userID string) (bool, error) {
+	s.logInfo(ctx, "中止会话生成", logger.Fields{
+		"sessionId": sessionID,
+		"userId":    userID,
+	})
+
+	if _, err := s.getOwnedSession(ctx, sessionID, userID); err != nil {
+		return false, err
+	}
+
+	return s.abortGeneration(ctx, sessionID, sessionID, s.sessionGenerations(sessionID))
+}
+
+// abortGeneration 取消登记的生成，并按 AI 回复消息ID或会话ID取消 AI 服务中正在进行的生成
+// 登记的生成已取消时，AI 服务中止失败只记录日志
+func (s *messageService) abortGeneration(ctx context.Context, key, sessionID string, generations []*generation) (bool, error) {
+	for _, gen := range generations {
+		gen.cancel()
+	}
+
+	aborted, err := s.aiService.AbortChat(ctx, key)
+	if err != nil {
+		s.logError(ctx, "中止AI生成失败", logger.Fields{
+			"sessionId": sessionID,
+			"key":       key,
 			"error":     err.Error(),
 		})
-		return errors.NewInternalError(err)
+		if len(generations) == 0 {
+			return false, errors.NewInternalError(err)
+		}
 	}
+	aborted = aborted || len(generations) > 0
 
-	s.logInfo(ctx, "消息生成已中止", logger.Fields{
-		"messageId": messageID,
-		"sessionId": message.SessionID,
+	s.logInfo(ctx, "中止AI生成请求处理完成", logger.Fields{
+		"sessionId": sessionID,
+		"key":       key,
+		"aborted":   aborted,
 	})
 
-	return nil
+	return aborted, nil
 }
@@ -2,15 +2,34 @@ package session
 
 import (
 	"context"
+	stderrors "errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"genkit-ai-service/internal/model"
+	"genkit-ai-service/internal/repository"
+	"genkit-ai-service/internal/service/tool"
 	"genkit-ai-service/pkg/errors"
 
+	"gorm.io/datatypes"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+// newTestDB 创建测试用内存数据库，仅用于提供事务
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	return db
+}
+
 // testMessageRepository 测试用消息仓库
 type testMessageRepository struct {
 	messages      map[string]*model.ChatMessage
@@ -30,9 +49,11 @@ func (m *testMessageRepository) Create(ctx context.Context, message *model.ChatM
 		return m.returnError
 	}
 	if message.ID == "" {
-		message.ID = "test-msg-id"
+		message.ID = fmt.Sprintf("test-msg-%d", len(m.messages)+1)
 	}
-	m.messages[message.ID] = message
+	// 保存副本，使后续更新必须经过 UpdateFields
+	stored := *message
+	m.messages[message.ID] = &stored
 	return nil
 }
 
@@ -47,29 +68,128 @@ func (m *testMessageRepository) GetByID(ctx context.Context, messageID string) (
 	return msg, nil
 }
 
-func (m *testMessageRepository) GetBySessionID(ctx context.Context, sessionID string, page, pageSize int) ([]*model.ChatMessage, int, error) {
+func (m *testMessageRepository) GetBySessionID(ctx context.Context, sessionID string, page, pageSize int, filters *repository.MessageFilters) ([]*model.ChatMessage, int, error) {
 	if m.returnError != nil {
 		return nil, 0, m.returnError
 	}
 	var result []*model.ChatMessage
 	for _, msg := range m.messages {
-		if msg.SessionID == sessionID {
-			result = append(result, msg)
+		if msg.SessionID != sessionID {
+			continue
+		}
+		if filters != nil && filters.Status != "" && msg.Status != filters.Status {
+			continue
+		}
+		result = append(result, msg)
+	}
+	if filters != nil && filters.BranchLeafID != "" {
+		branch, _ := m.GetBranch(ctx, sessionID, filters.BranchLeafID)
+		onBranch := make(map[string]bool)
+		for _, msg := range branch {
+			onBranch[msg.ID] = true
 		}
+		filtered := result[:0]
+		for _, msg := range result {
+			if onBranch[msg.ID] {
+				filtered = append(filtered, msg)
+			}
+		}
+		result = filtered
 	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Sequence < result[j].Sequence })
 	return result, len(result), nil
 }
 
+func (m *testMessageRepository) GetBranch(ctx context.Context, sessionID, leafMessageID string) ([]*model.ChatMessage, error) {
+	if m.returnError != nil {
+		return nil, m.returnError
+	}
+	var branch []*model.ChatMessage
+	for id := &leafMessageID; id != nil; {
+		msg, exists := m.messages[*id]
+		if !exists || msg.SessionID != sessionID {
+			break
+		}
+		branch = append([]*model.ChatMessage{msg}, branch...)
+		id = msg.ParentID
+	}
+	return branch, nil
+}
+
+func (m *testMessageRepository) GetChildren(ctx context.Context, sessionID string, parentID *string) ([]*model.ChatMessage, error) {
+	if m.returnError != nil {
+		return nil, m.returnError
+	}
+	result := []*model.ChatMessage{}
+	for _, msg := range m.messages {
+		if msg.SessionID != sessionID {
+			continue
+		}
+		if (parentID == nil && msg.ParentID == nil) || (parentID != nil && msg.ParentID != nil && *msg.ParentID == *parentID) {
+			result = append(result, msg)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Sequence < result[j].Sequence })
+	return result, nil
+}
+
+func (m *testMessageRepository) UpdateFields(ctx context.Context, messageID string, fields map[string]interface{}) error {
+	if m.returnError != nil {
+		return m.returnError
+	}
+	msg, exists := m.messages[messageID]
+	if !exists {
+		return gorm.ErrRecordNotFound
+	}
+	updated := *msg
+	for field, value := range fields {
+		switch field {
+		case "content":
+			updated.Content = value.(string)
+		case "tokens":
+			updated.Tokens = value.(int)
+		case "status":
+			updated.Status = value.(string)
+		case "error":
+			updated.Error = value.(string)
+		case "tool_calls":
+			updated.ToolCalls = value.(datatypes.JSON)
+		case "meta":
+			updated.Meta = value.(datatypes.JSON)
+		}
+	}
+	m.messages[messageID] = &updated
+	return nil
+}
+
 func (m *testMessageRepository) GetLatestMessages(ctx context.Context, sessionID string, limit int) ([]*model.ChatMessage, error) {
-	return []*model.ChatMessage{}, nil
+	if m.returnError != nil {
+		return nil, m.returnError
+	}
+	result := []*model.ChatMessage{}
+	for _, msg := range m.messages {
+		if msg.SessionID == sessionID {
+			result = append(result, msg)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Sequence < result[j].Sequence })
+	if len(result) > limit {
+		result = result[len(result)-limit:]
+	}
+	return result, nil
 }
 
+// GetNextSequence 与真实仓库一致，返回会话中已有消息的最大序列号加一
 func (m *testMessageRepository) GetNextSequence(ctx context.Context, sessionID string) (int, error) {
 	if m.returnError != nil {
 		return 0, m.returnError
 	}
 	seq := m.nextSequence
-	m.nextSequence++
+	for _, msg := range m.messages {
+		if msg.SessionID == sessionID && msg.Sequence >= seq {
+			seq = msg.Sequence + 1
+		}
+	}
 	return seq, nil
 }
 
@@ -81,11 +201,31 @@ func (m *testMessageRepository) GetMessagesAfter(ctx context.Context, sessionID
 	return []*model.ChatMessage{}, nil
 }
 
+// linkBranch 将消息依次串联为一个分支，并设为会话的当前分支
+func linkBranch(sessionRepo *mockSessionRepository, messageRepo *testMessageRepository, sessionID string, ids ...string) {
+	for i := 1; i < len(ids); i++ {
+		parentID := ids[i-1]
+		messageRepo.messages[ids[i]].ParentID = &parentID
+	}
+	leafID := ids[len(ids)-1]
+	sessionRepo.sessions[sessionID].LastMessageID = &leafID
+}
+
 // testAIService 测试用AI服务
 type testAIService struct {
-	response    *model.ChatResponse
-	returnError error
-	abortError  error
+	response     *model.ChatResponse
+	returnError  error
+	abortError   error
+	streamChunks []string
+	lastRequest  *model.ChatRequest
+	// 依次返回的回复，用完后返回 response
+	responses []*model.ChatResponse
+	// 每次调用时请求的副本
+	requests []model.ChatRequest
+	// 每次调用 Chat 或 ChatStream 时先调用的钩子
+	onChat func(req *model.ChatRequest)
+	// AbortChat 收到的消息ID或会话ID
+	abortedKeys []string
 }
 
 func newTestAIService() *testAIService {
@@ -104,18 +244,510 @@ func newTestAIService() *testAIService {
 }
 
 func (m *testAIService) Chat(ctx context.Context, req *model.ChatRequest) (*model.ChatResponse, error) {
+	m.lastRequest = req
+	m.requests = append(m.requests, *req)
+	if m.onChat != nil {
+		m.onChat(req)
+	}
+	if ctx.Err() != nil {
+		return nil, errors.NewContextCancelledError()
+	}
 	if m.returnError != nil {
 		return nil, m.returnError
 	}
-	return m.response, nil
+	return m.nextResponse(), nil
+}
+
+func (m *testAIService) nextResponse() *model.ChatResponse {
+	if len(m.responses) > 0 {
+		resp := m.responses[0]
+		m.responses = m.responses[1:]
+		return resp
+	}
+	return m.response
 }
 
 func (m *testAIService) ChatStream(ctx context.Context, req *model.ChatRequest) (<-chan model.StreamChunk, error) {
-	return nil, nil
+	m.lastRequest = req
+	m.requests = append(m.requests, *req)
+	if m.onChat != nil {
+		m.onChat(req)
+	}
+	resp := m.nextResponse()
+	contents := m.streamChunks
+	if resp != m.response {
+		contents = []string{resp.Message}
+	}
+	chunks := make(chan model.StreamChunk, len(contents)+1)
+	for _, content := range contents {
+		chunks <- model.StreamChunk{Content: content}
+	}
+	switch {
+	case ctx.Err() != nil:
+		// 上下文已取消时完成块可能未送达
+	case m.returnError != nil:
+		chunks <- model.StreamChunk{Done: true, Error: m.returnError}
+	default:
+		chunks <- model.StreamChunk{Done: true, Model: resp.Model, Usage: resp.Usage, ToolCalls: resp.ToolCalls}
+	}
+	close(chunks)
+	return chunks, nil
+}
+
+func (m *testAIService) AbortChat(ctx context.Context, sessionID string) (bool, error) {
+	m.abortedKeys = append(m.abortedKeys, sessionID)
+	if m.abortError != nil {
+		return false, m.abortError
+	}
+	return true, nil
+}
+
+// TestSendMessage_History 测试发送消息时携带会话配置和历史对话
+func TestSendMessage_History(t *testing.T) {
+	ctx := context.Background()
+	userID := "user-123"
+	sessionID := "session-123"
+	temperature := 0.3
+
+	sessionRepo := newMockSessionRepository()
+	sessionRepo.sessions[sessionID] = &model.ChatSession{
+		ID:           sessionID,
+		UserID:       userID,
+		Title:        "测试会话",
+		ModelName:    "qwen-plus",
+		SystemPrompt: "你是一个翻译助手",
+		Temperature:  &temperature,
+	}
+
+	messageRepo := newTestMessageRepository()
+	history := []struct {
+		id      string
+		role    string
+		content string
+	}{
+		{"msg-1", "user", "第一个问题"},
+		{"msg-2", "assistant", "第一个回答"},
+		{"msg-3", "user", "第二个问题"},
+		{"msg-4", "assistant", "第二个回答"},
+	}
+	for i, h := range history {
+		messageRepo.messages[h.id] = &model.ChatMessage{
+			ID:        h.id,
+			SessionID: sessionID,
+			Role:      h.role,
+			Content:   h.content,
+			Sequence:  i + 1,
+		}
+	}
+	messageRepo.nextSequence = len(history) + 1
+	linkBranch(sessionRepo, messageRepo, sessionID, "msg-1", "msg-2", "msg-3", "msg-4")
+
+	summaryRepo := newMockSummaryRepository()
+	summaryRepo.summaries[sessionID] = []*model.ChatSummary{
+		{SessionID: sessionID, Summary: "用户询问了第一个问题", LastMessageID: "msg-2"},
+	}
+
+	aiService := newTestAIService()
+	service := NewMessageService(newTestDB(t), sessionRepo, messageRepo, summaryRepo, nil, nil, nil, aiService, nil, nil, nil, nil)
+
+	topP := 0.8
+	_, err := service.SendMessage(ctx, &SendMessageRequest{
+		SessionID: sessionID,
+		Message:   "第三个问题",
+		UserID:    userID,
+		Options:   &model.ChatOptions{TopP: &topP},
+	})
+	if err != nil {
+		t.Fatalf("发送消息失败: %v", err)
+	}
+
+	req := aiService.lastRequest
+	if req.Message != "第三个问题" || req.Model != "qwen-plus" || req.SystemPrompt != "你是一个翻译助手" {
+		t.Errorf("请求不正确: message=%s model=%s system=%s", req.Message, req.Model, req.SystemPrompt)
+	}
+
+	wantHistory := []model.ChatTurn{
+		{Role: "system", Content: "以下是之前对话的摘要：\n用户询问了第一个问题"},
+		{Role: "user", Content: "第二个问题"},
+		{Role: "assistant", Content: "第二个回答"},
+	}
+	if len(req.History) != len(wantHistory) {
+		t.Fatalf("期望 %d 条历史, 得到 %d: %+v", len(wantHistory), len(req.History), req.History)
+	}
+	for i, turn := range wantHistory {
+		if !reflect.DeepEqual(req.History[i], turn) {
+			t.Errorf("历史[%d] = %+v, 期望 %+v", i, req.History[i], turn)
+		}
+	}
+
+	if req.Options == nil || req.Options.Temperature == nil || *req.Options.Temperature != temperature {
+		t.Errorf("期望使用会话温度 %v", temperature)
+	}
+	if req.Options.TopP == nil || *req.Options.TopP != topP {
+		t.Errorf("期望使用请求中的 TopP %v", topP)
+	}
+}
+
+// TestSendMessage_SummaryOnInactiveBranch 测试摘要位于其他分支时不使用摘要
+func TestSendMessage_SummaryOnInactiveBranch(t *testing.T) {
+	ctx := context.Background()
+	userID := "user-123"
+	sessionID := "session-123"
+
+	sessionRepo := newMockSessionRepository()
+	sessionRepo.sessions[sessionID] = &model.ChatSession{ID: sessionID, UserID: userID, Title: "测试会话"}
+
+	messageRepo := newTestMessageRepository()
+	for i, role := range []string{"user", "assistant", "user", "assistant"} {
+		id := fmt.Sprintf("msg-%d", i+1)
+		messageRepo.messages[id] = &model.ChatMessage{
+			ID:        id,
+			SessionID: sessionID,
+			Role:      role,
+			Content:   "历史消息",
+			Sequence:  i + 1,
+		}
+	}
+	messageRepo.nextSequence = 5
+	linkBranch(sessionRepo, messageRepo, sessionID, "msg-1", "msg-2", "msg-3", "msg-4")
+
+	// 摘要的最后一条消息是重新生成前被放弃的回复
+	summaryRepo := newMockSummaryRepository()
+	summaryRepo.summaries[sessionID] = []*model.ChatSummary{
+		{SessionID: sessionID, Summary: "旧分支的摘要", LastMessageID: "abandoned-msg"},
+	}
+
+	aiService := newTestAIService()
+	service := NewMessageService(newTestDB(t), sessionRepo, messageRepo, summaryRepo, nil, nil, nil, aiService, nil, nil, nil, nil)
+
+	if _, err := service.SendMessage(ctx, &SendMessageRequest{SessionID: sessionID, Message: "新问题", UserID: userID}); err != nil {
+		t.Fatalf("发送消息失败: %v", err)
+	}
+
+	history := aiService.lastRequest.History
+	if len(history) != 4 {
+		t.Fatalf("期望发送当前分支的 4 条历史, 得到 %d: %+v", len(history), history)
+	}
+	for _, turn := range history {
+		if turn.Role == "system" {
+			t.Errorf("不应使用其他分支的摘要: %+v", turn)
+		}
+	}
+}
+
+// TestSendMessage_StatusLifecycle 测试 AI 回复的状态流转以及失败对话轮次的保留
+func TestSendMessage_StatusLifecycle(t *testing.T) {
+	ctx := context.Background()
+	userID := "user-123"
+	sessionID := "session-123"
+
+	sessionRepo := newMockSessionRepository()
+	sessionRepo.sessions[sessionID] = &model.ChatSession{ID: sessionID, UserID: userID}
+	messageRepo := newTestMessageRepository()
+	aiService := newTestAIService()
+	service := NewMessageService(newTestDB(t), sessionRepo, messageRepo, nil, nil, nil, nil, aiService, nil, nil, nil, nil)
+
+	// 1. 生成失败：对话轮次保留，AI 回复标记为 failed
+	aiService.returnError = stderrors.New("AI 服务错误")
+	if _, err := service.SendMessage(ctx, &SendMessageRequest{
+		SessionID: sessionID,
+		Message:   "第一个问题",
+		UserID:    userID,
+		MessageID: "ai-msg-1",
+	}); err == nil {
+		t.Fatal("期望返回错误")
+	}
+
+	failed := messageRepo.messages["ai-msg-1"]
+	if failed == nil || failed.Status != model.MessageStatusFailed || failed.Error == "" {
+		t.Fatalf("期望 AI 回复标记为失败并记录原因, 得到 %+v", failed)
+	}
+	if len(messageRepo.messages) != 2 {
+		t.Fatalf("期望保留 2 条消息, 得到 %d", len(messageRepo.messages))
+	}
+
+	// 2. 再次发送：失败的对话轮次不计入上下文；生成期间 AI 回复为 streaming
+	aiService.returnError = nil
+	aiService.streamChunks = []string{"第二个回答"}
+	resp, err := service.SendMessageStream(ctx, &SendMessageRequest{
+		SessionID: sessionID,
+		Message:   "第二个问题",
+		UserID:    userID,
+		MessageID: "ai-msg-2",
+	}, func(content string) error {
+		if status := messageRepo.messages["ai-msg-2"].Status; status != model.MessageStatusStreaming {
+			t.Errorf("期望生成期间状态为 streaming, 得到 %s", status)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("发送消息失败: %v", err)
+	}
+	if len(aiService.lastRequest.History) != 0 {
+		t.Errorf("期望失败的对话轮次不计入上下文, 得到 %+v", aiService.lastRequest.History)
+	}
+	if resp.AIMessage.Status != model.MessageStatusCompleted || messageRepo.messages["ai-msg-2"].Status != model.MessageStatusCompleted {
+		t.Errorf("期望 AI 回复状态为 completed")
+	}
+
+	// 3. 按状态过滤消息
+	list, err := service.GetMessages(ctx, &GetMessagesRequest{
+		SessionID: sessionID,
+		UserID:    userID,
+		PageNo:    1,
+		PageSize:  20,
+		Status:    model.MessageStatusFailed,
+	})
+	if err != nil {
+		t.Fatalf("获取消息失败: %v", err)
+	}
+	if len(list.Messages) != 1 || list.Messages[0].ID != "ai-msg-1" || list.Messages[0].Error == "" {
+		t.Errorf("期望只返回失败的 AI 回复, 得到 %+v", list.Messages)
+	}
 }
 
-func (m *testAIService) AbortChat(ctx context.Context, sessionID string) error {
-	return m.abortError
+// testModelCatalog 测试用模型目录
+type testModelCatalog struct {
+	models map[string]*model.Model
+}
+
+func (c *testModelCatalog) ResolveModel(modelName string) (string, *model.Model, error) {
+	m, ok := c.models[modelName]
+	if !ok {
+		return "", nil, errors.NewModelNotFoundError(modelName)
+	}
+	return "test-provider", m, nil
+}
+
+// TestSendMessage_ContextWindow 测试历史消息超出模型上下文时裁剪并在响应元数据中报告
+func TestSendMessage_ContextWindow(t *testing.T) {
+	ctx := context.Background()
+	userID := "user-123"
+	sessionID := "session-123"
+
+	sessionRepo := newMockSessionRepository()
+	sessionRepo.sessions[sessionID] = &model.ChatSession{
+		ID:        sessionID,
+		UserID:    userID,
+		Title:     "测试会话",
+		ModelName: "small-model",
+	}
+
+	messageRepo := newTestMessageRepository()
+	for i := 1; i <= 6; i++ {
+		role := "user"
+		if i%2 == 0 {
+			role = "assistant"
+		}
+		id := fmt.Sprintf("msg-%d", i)
+		messageRepo.messages[id] = &model.ChatMessage{
+			ID:        id,
+			SessionID: sessionID,
+			Role:      role,
+			Content:   "历史消息",
+			Tokens:    300,
+			Sequence:  i,
+		}
+	}
+	messageRepo.nextSequence = 7
+	linkBranch(sessionRepo, messageRepo, sessionID, "msg-1", "msg-2", "msg-3", "msg-4", "msg-5", "msg-6")
+
+	maxTokens := 100
+	catalog := &testModelCatalog{models: map[string]*model.Model{
+		"small-model": {Model: "small-model", ModelProperties: model.ModelProperties{ContextSize: 1000}},
+	}}
+
+	aiService := newTestAIService()
+	service := NewMessageService(newTestDB(t), sessionRepo, messageRepo, nil, nil, nil, nil, aiService, catalog, nil, nil, nil)
+
+	resp, err := service.SendMessage(ctx, &SendMessageRequest{
+		SessionID: sessionID,
+		Message:   "新问题",
+		UserID:    userID,
+		Options:   &model.ChatOptions{MaxTokens: &maxTokens},
+	})
+	if err != nil {
+		t.Fatalf("发送消息失败: %v", err)
+	}
+
+	if resp.Meta == nil || resp.Meta.ContextWindow == nil {
+		t.Fatal("期望响应元数据包含上下文窗口信息")
+	}
+	info := resp.Meta.ContextWindow
+	if info.ContextSize != 1000 || info.ReservedTokens != 100 {
+		t.Errorf("上下文信息不正确: %+v", info)
+	}
+	if info.DroppedMessages != 4 || info.IncludedMessages != 2 {
+		t.Errorf("期望丢弃 4 条保留 2 条, 得到丢弃 %d 保留 %d", info.DroppedMessages, info.IncludedMessages)
+	}
+	if len(aiService.lastRequest.History) != 2 {
+		t.Errorf("期望发送 2 条历史, 得到 %d", len(aiService.lastRequest.History))
+	}
+}
+
+// TestSendMessageStream 测试流式发送消息
+func TestSendMessageStream(t *testing.T) {
+	ctx := context.Background()
+	userID := "user-123"
+	sessionID := "session-123"
+
+	newFixture := func() (*mockSessionRepository, *testMessageRepository, *testAIService) {
+		sessionRepo := newMockSessionRepository()
+		sessionRepo.sessions[sessionID] = &model.ChatSession{
+			ID:     sessionID,
+			UserID: userID,
+			Title:  "测试会话",
+		}
+		aiService := newTestAIService()
+		aiService.streamChunks = []string{"AI", "回复"}
+		return sessionRepo, newTestMessageRepository(), aiService
+	}
+
+	t.Run("成功推送片段并保存拼接后的回复", func(t *testing.T) {
+		sessionRepo, messageRepo, aiService := newFixture()
+		service := NewMessageService(newTestDB(t), sessionRepo, messageRepo, nil, nil, nil, nil, aiService, nil, nil, nil, nil)
+
+		var received []string
+		resp, err := service.SendMessageStream(ctx, &SendMessageRequest{
+			SessionID: sessionID,
+			Message:   "你好",
+			UserID:    userID,
+		}, func(content string) error {
+			received = append(received, content)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("流式发送消息失败: %v", err)
+		}
+
+		if len(received) != 2 {
+			t.Errorf("期望收到 2 个片段, 得到 %d", len(received))
+		}
+		if resp.AIMessage.Content != "AI回复" {
+			t.Errorf("期望 AI 回复为 'AI回复', 得到 '%s'", resp.AIMessage.Content)
+		}
+
+		saved := messageRepo.messages[resp.AIMessage.ID]
+		if saved == nil {
+			t.Fatal("期望 AI 回复已保存")
+		}
+		if saved.Content != "AI回复" || saved.Tokens != 20 {
+			t.Errorf("保存的 AI 回复不正确: content=%s tokens=%d", saved.Content, saved.Tokens)
+		}
+		if len(messageRepo.messages) != 2 {
+			t.Errorf("期望保存 2 条消息, 得到 %d", len(messageRepo.messages))
+		}
+	})
+
+	t.Run("生成失败时保留对话并标记为失败", func(t *testing.T) {
+		sessionRepo, messageRepo, aiService := newFixture()
+		aiService.returnError = stderrors.New("AI 服务错误")
+		service := NewMessageService(newTestDB(t), sessionRepo, messageRepo, nil, nil, nil, nil, aiService, nil, nil, nil, nil)
+
+		_, err := service.SendMessageStream(ctx, &SendMessageRequest{
+			SessionID: sessionID,
+			Message:   "你好",
+			UserID:    userID,
+			MessageID: "ai-msg-1",
+		}, func(content string) error { return nil })
+
+		appErr, ok := err.(*errors.AppError)
+		if !ok || appErr.Code != errors.CodeMessageSendFailed {
+			t.Fatalf("期望消息发送失败错误, 得到 %v", err)
+		}
+		if len(messageRepo.messages) != 2 {
+			t.Fatalf("期望保留 2 条消息, 实际保存了 %d 条", len(messageRepo.messages))
+		}
+		saved := messageRepo.messages["ai-msg-1"]
+		if saved.Status != model.MessageStatusFailed || saved.Error != "AI 服务错误" {
+			t.Errorf("期望 AI 回复标记为失败并记录原因: status=%s error=%s", saved.Status, saved.Error)
+		}
+		if saved.Content != "AI回复" {
+			t.Errorf("期望保存失败前已生成的内容, 得到 '%s'", saved.Content)
+		}
+	})
+
+	t.Run("推送片段失败时中止并保存已生成的内容", func(t *testing.T) {
+		sessionRepo, messageRepo, aiService := newFixture()
+		service := NewMessageService(newTestDB(t), sessionRepo, messageRepo, nil, nil, nil, nil, aiService, nil, nil, nil, nil)
+
+		_, err := service.SendMessageStream(ctx, &SendMessageRequest{
+			SessionID: sessionID,
+			Message:   "你好",
+			UserID:    userID,
+			MessageID: "ai-msg-1",
+		}, func(content string) error { return stderrors.New("客户端已断开") })
+
+		if err == nil {
+			t.Fatal("期望返回错误")
+		}
+		saved := messageRepo.messages["ai-msg-1"]
+		if saved == nil {
+			t.Fatal("期望保留 AI 回复")
+		}
+		if saved.Status != model.MessageStatusAborted || saved.Content != "AI" {
+			t.Errorf("期望保存中止前的内容: status=%s content=%s", saved.Status, saved.Content)
+		}
+	})
+
+	t.Run("生成被中止时保存已生成的部分内容", func(t *testing.T) {
+		sessionRepo, messageRepo, aiService := newFixture()
+		aiService.returnError = errors.NewContextCancelledError()
+		service := NewMessageService(newTestDB(t), sessionRepo, messageRepo, nil, nil, nil, nil, aiService, nil, nil, nil, nil)
+
+		resp, err := service.SendMessageStream(ctx, &SendMessageRequest{
+			SessionID: sessionID,
+			Message:   "你好",
+			UserID:    userID,
+			MessageID: "ai-msg-1",
+		}, func(content string) error { return nil })
+		if err != nil {
+			t.Fatalf("中止的生成不应返回错误: %v", err)
+		}
+
+		if aiService.lastRequest.MessageID != "ai-msg-1" || aiService.lastRequest.SessionID != sessionID {
+			t.Errorf("生成请求未携带消息ID和会话ID: %+v", aiService.lastRequest)
+		}
+
+		saved := messageRepo.messages["ai-msg-1"]
+		if saved == nil {
+			t.Fatal("期望按预分配的消息ID保存 AI 回复")
+		}
+		if saved.Content != "AI回复" || saved.Status != model.MessageStatusAborted {
+			t.Errorf("保存的 AI 回复不正确: content=%s status=%s", saved.Content, saved.Status)
+		}
+		if resp.AIMessage.Status != model.MessageStatusAborted {
+			t.Errorf("期望响应状态为 aborted, 得到 %s", resp.AIMessage.Status)
+		}
+	})
+
+	t.Run("中止后未收到完成块时按中止保存", func(t *testing.T) {
+		sessionRepo, messageRepo, aiService := newFixture()
+		service := NewMessageService(newTestDB(t), sessionRepo, messageRepo, nil, nil, nil, nil, aiService, nil, nil, nil, nil)
+		aiService.onChat = func(req *model.ChatRequest) {
+			if _, err := service.AbortMessage(ctx, "ai-msg-1", userID); err != nil {
+				t.Errorf("中止消息失败: %v", err)
+			}
+		}
+
+		resp, err := service.SendMessageStream(ctx, &SendMessageRequest{
+			SessionID: sessionID,
+			Message:   "你好",
+			UserID:    userID,
+			MessageID: "ai-msg-1",
+		}, func(content string) error { return nil })
+		if err != nil {
+			t.Fatalf("中止的生成不应返回错误: %v", err)
+		}
+
+		saved := messageRepo.messages["ai-msg-1"]
+		if saved.Content != "AI回复" || saved.Status != model.MessageStatusAborted {
+			t.Errorf("保存的 AI 回复不正确: content=%s status=%s", saved.Content, saved.Status)
+		}
+		if resp.AIMessage.Status != model.MessageStatusAborted {
+			t.Errorf("期望响应状态为 aborted, 得到 %s", resp.AIMessage.Status)
+		}
+	})
 }
 
 // TestGetMessageByID 测试获取单条消息
@@ -152,7 +784,7 @@ func TestGetMessageByID(t *testing.T) {
 		messageRepo.messages[messageID] = message
 
 		// 创建服务
-		service := NewMessageService(nil, sessionRepo, messageRepo, aiService, nil)
+		service := NewMessageService(nil, sessionRepo, messageRepo, nil, nil, nil, nil, aiService, nil, nil, nil, nil)
 
 		// 执行测试
 		result, err := service.GetMessageByID(ctx, messageID, userID)
@@ -180,7 +812,7 @@ func TestGetMessageByID(t *testing.T) {
 		messageRepo := newTestMessageRepository()
 		aiService := newTestAIService()
 
-		service := NewMessageService(nil, sessionRepo, messageRepo, aiService, nil)
+		service := NewMessageService(nil, sessionRepo, messageRepo, nil, nil, nil, nil, aiService, nil, nil, nil, nil)
 
 		result, err := service.GetMessageByID(ctx, messageID, userID)
 
@@ -226,7 +858,7 @@ func TestGetMessageByID(t *testing.T) {
 		}
 		messageRepo.messages[messageID] = message
 
-		service := NewMessageService(nil, sessionRepo, messageRepo, aiService, nil)
+		service := NewMessageService(nil, sessionRepo, messageRepo, nil, nil, nil, nil, aiService, nil, nil, nil, nil)
 
 		result, err := service.GetMessageByID(ctx, messageID, userID)
 
@@ -250,6 +882,45 @@ func TestGetMessageByID(t *testing.T) {
 func TestAbortMessage(t *testing.T) {
 	ctx := context.Background()
 
+	t.Run("中止正在生成的消息", func(t *testing.T) {
+		sessionRepo := newMockSessionRepository()
+		sessionRepo.sessions["session-123"] = &model.ChatSession{ID: "session-123", UserID: "user-123"}
+		aiService := newTestAIService()
+
+		service := NewMessageService(nil, sessionRepo, newTestMessageRepository(), nil, nil, nil, nil, aiService, nil, nil, nil, nil).(*messageService)
+		aiService.abortError = stderrors.New("AI 服务不可用")
+		gen, genCtx, err := service.startGeneration(ctx, &SendMessageRequest{SessionID: "session-123", MessageID: "ai-msg-1"})
+		if err != nil {
+			t.Fatalf("登记生成失败: %v", err)
+		}
+		defer service.finishGeneration(gen)
+
+		// 同一消息ID正在生成时拒绝重复登记
+		_, _, err = service.startGeneration(ctx, &SendMessageRequest{SessionID: "session-123", MessageID: "ai-msg-1"})
+		if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.CodeBadRequest {
+			t.Errorf("期望重复的消息ID返回请求错误, 得到 %v", err)
+		}
+
+		if _, err := service.AbortMessage(ctx, "ai-msg-1", "user-456"); err == nil {
+			t.Error("期望其他用户中止时返回错误")
+		}
+		if genCtx.Err() != nil {
+			t.Fatal("其他用户中止时不应取消生成")
+		}
+
+		// 生成尚未到达 AI 服务时也能中止
+		aborted, err := service.AbortMessage(ctx, "ai-msg-1", "user-123")
+		if err != nil {
+			t.Fatalf("中止消息失败: %v", err)
+		}
+		if !aborted {
+			t.Error("期望报告已中止正在进行的生成")
+		}
+		if genCtx.Err() == nil {
+			t.Error("期望生成上下文已取消")
+		}
+	})
+
 	t.Run("成功中止消息", func(t *testing.T) {
 		messageID := "msg-123"
 		userID := "user-123"
@@ -276,9 +947,9 @@ func TestAbortMessage(t *testing.T) {
 		}
 		messageRepo.messages[messageID] = message
 
-		service := NewMessageService(nil, sessionRepo, messageRepo, aiService, nil)
+		service := NewMessageService(nil, sessionRepo, messageRepo, nil, nil, nil, nil, aiService, nil, nil, nil, nil)
 
-		err := service.AbortMessage(ctx, messageID, userID)
+		_, err := service.AbortMessage(ctx, messageID, userID)
 
 		if err != nil {
 			t.Fatalf("中止消息失败: %v", err)
@@ -293,9 +964,9 @@ func TestAbortMessage(t *testing.T) {
 		messageRepo := newTestMessageRepository()
 		aiService := newTestAIService()
 
-		service := NewMessageService(nil, sessionRepo, messageRepo, aiService, nil)
+		service := NewMessageService(nil, sessionRepo, messageRepo, nil, nil, nil, nil, aiService, nil, nil, nil, nil)
 
-		err := service.AbortMessage(ctx, messageID, userID)
+		_, err := service.AbortMessage(ctx, messageID, userID)
 
 		if err != nil {
 			t.Errorf("期望成功，但返回错误: %v", err)
@@ -329,9 +1000,9 @@ func TestAbortMessage(t *testing.T) {
 		}
 		messageRepo.messages[messageID] = message
 
-		service := NewMessageService(nil, sessionRepo, messageRepo, aiService, nil)
+		service := NewMessageService(nil, sessionRepo, messageRepo, nil, nil, nil, nil, aiService, nil, nil, nil, nil)
 
-		err := service.AbortMessage(ctx, messageID, userID)
+		_, err := service.AbortMessage(ctx, messageID, userID)
 
 		if err == nil {
 			t.Error("期望返回错误，但没有错误")
@@ -345,3 +1016,772 @@ func TestAbortMessage(t *testing.T) {
 		}
 	})
 }
+
+// TestAbortSession 测试按会话中止生成
+func TestAbortSession(t *testing.T) {
+	ctx := context.Background()
+
+	sessionRepo := newMockSessionRepository()
+	sessionRepo.sessions["session-123"] = &model.ChatSession{ID: "session-123", UserID: "user-123"}
+	aiService := newTestAIService()
+	service := NewMessageService(nil, sessionRepo, newTestMessageRepository(), nil, nil, nil, nil, aiService, nil, nil, nil, nil)
+
+	aborted, err := service.AbortSession(ctx, "session-123", "user-123")
+	if err != nil {
+		t.Fatalf("中止会话失败: %v", err)
+	}
+	if !aborted {
+		t.Error("期望报告已中止正在进行的生成")
+	}
+
+	_, err = service.AbortSession(ctx, "session-123", "user-456")
+	appErr, ok := err.(*errors.AppError)
+	if !ok || appErr.Code != errors.CodeSessionAccessDenied {
+		t.Errorf("期望会话访问拒绝错误, 得到 %v", err)
+	}
+}
+
+// TestAppendUserMessage 测试在当前分支末尾追加用户消息
+func TestAppendUserMessage(t *testing.T) {
+	ctx := context.Background()
+	userID := "user-123"
+	sessionID := "session-123"
+
+	sessionRepo := newMockSessionRepository()
+	sessionRepo.sessions[sessionID] = &model.ChatSession{ID: sessionID, UserID: userID}
+	messageRepo := newTestMessageRepository()
+	aiService := newTestAIService()
+	service := NewMessageService(newTestDB(t), sessionRepo, messageRepo, nil, nil, nil, nil, aiService, nil, nil, nil, nil)
+
+	first, err := service.SendMessage(ctx, &SendMessageRequest{
+		SessionID: sessionID, Message: "问题1", UserID: userID, MessageID: "ai-1",
+	})
+	if err != nil {
+		t.Fatalf("发送消息失败: %v", err)
+	}
+
+	appended, err := service.AppendUserMessage(ctx, sessionID, userID, "转写的文本")
+	if err != nil {
+		t.Fatalf("追加用户消息失败: %v", err)
+	}
+	if appended.Role != "user" || appended.Content != "转写的文本" || appended.ParentID == nil || *appended.ParentID != first.AIMessage.ID {
+		t.Errorf("追加的消息不正确: %+v", appended)
+	}
+	if *sessionRepo.sessions[sessionID].LastMessageID != appended.ID {
+		t.Error("追加的消息应成为当前分支的末端")
+	}
+	// 不生成 AI 回复
+	if len(messageRepo.messages) != 3 {
+		t.Errorf("期望共 3 条消息, 得到 %d", len(messageRepo.messages))
+	}
+
+	_, err = service.AppendUserMessage(ctx, sessionID, "user-456", "x")
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.CodeSessionAccessDenied {
+		t.Errorf("期望会话访问拒绝错误, 得到 %v", err)
+	}
+}
+
+// TestMessageBranching 测试重新生成、编辑重发和分支切换
+func TestMessageBranching(t *testing.T) {
+	ctx := context.Background()
+	userID := "user-123"
+	sessionID := "session-123"
+
+	sessionRepo := newMockSessionRepository()
+	sessionRepo.sessions[sessionID] = &model.ChatSession{ID: sessionID, UserID: userID}
+	messageRepo := newTestMessageRepository()
+	aiService := newTestAIService()
+	service := NewMessageService(newTestDB(t), sessionRepo, messageRepo, nil, nil, nil, nil, aiService, nil, nil, nil, nil)
+
+	first, err := service.SendMessage(ctx, &SendMessageRequest{
+		SessionID: sessionID, Message: "问题1", UserID: userID, MessageID: "ai-1",
+	})
+	if err != nil {
+		t.Fatalf("发送消息失败: %v", err)
+	}
+
+	// 1. 重新生成：新回复与原回复挂在同一条用户消息下，历史不包含原回复
+	regenerated, err := service.RegenerateMessage(ctx, &RegenerateMessageRequest{
+		MessageID: "ai-1", UserID: userID, AIMessageID: "ai-2",
+	}, nil)
+	if err != nil {
+		t.Fatalf("重新生成失败: %v", err)
+	}
+	if regenerated.UserMessage.ID != first.UserMessage.ID || *regenerated.AIMessage.ParentID != first.UserMessage.ID {
+		t.Errorf("期望复用原用户消息, 得到 %+v", regenerated.UserMessage)
+	}
+	if aiService.lastRequest.Message != "问题1" || len(aiService.lastRequest.History) != 0 {
+		t.Errorf("重新生成的请求不正确: %+v", aiService.lastRequest)
+	}
+	if len(messageRepo.messages) != 3 {
+		t.Errorf("期望保留原回复, 共 3 条消息, 得到 %d", len(messageRepo.messages))
+	}
+
+	siblings, err := service.GetSiblings(ctx, "ai-1", userID)
+	if err != nil {
+		t.Fatalf("获取分支失败: %v", err)
+	}
+	if len(siblings.Siblings) != 2 || siblings.Siblings[0].ID != "ai-1" || siblings.ActiveID != "ai-2" {
+		t.Errorf("分支列表不正确: active=%s siblings=%d", siblings.ActiveID, len(siblings.Siblings))
+	}
+
+	// 2. 编辑重发：新用户消息与原消息挂在同一父消息下，历史取自父消息之前的分支
+	second, err := service.SendMessage(ctx, &SendMessageRequest{
+		SessionID: sessionID, Message: "问题2", UserID: userID, MessageID: "ai-3",
+	})
+	if err != nil {
+		t.Fatalf("发送消息失败: %v", err)
+	}
+	edited, err := service.EditMessage(ctx, &EditMessageRequest{
+		MessageID: second.UserMessage.ID, Message: "修改后的问题2", UserID: userID, AIMessageID: "ai-4",
+	}, nil)
+	if err != nil {
+		t.Fatalf("编辑消息失败: %v", err)
+	}
+	if edited.UserMessage.ID == second.UserMessage.ID || *edited.UserMessage.ParentID != "ai-2" {
+		t.Errorf("期望在 ai-2 下创建新的用户消息, 得到 %+v", edited.UserMessage)
+	}
+	if aiService.lastRequest.Message != "修改后的问题2" || len(aiService.lastRequest.History) != 2 {
+		t.Errorf("编辑重发的请求不正确: %+v", aiService.lastRequest)
+	}
+
+	// 3. 切换分支：切换到原回复后，消息列表只包含该分支
+	leaf, err := service.SelectBranch(ctx, "ai-1", userID)
+	if err != nil {
+		t.Fatalf("切换分支失败: %v", err)
+	}
+	if leaf.ID != "ai-1" || *sessionRepo.sessions[sessionID].LastMessageID != "ai-1" {
+		t.Errorf("期望当前分支末端为 ai-1, 得到 %s", leaf.ID)
+	}
+	list, err := service.GetMessages(ctx, &GetMessagesRequest{SessionID: sessionID, UserID: userID, PageNo: 1, PageSize: 20})
+	if err != nil {
+		t.Fatalf("获取消息失败: %v", err)
+	}
+	if len(list.Messages) != 2 || list.Messages[1].ID != "ai-1" {
+		t.Errorf("期望当前分支包含 2 条消息, 得到 %d", len(list.Messages))
+	}
+
+	// 切换到中间的消息时沿最新的子消息找到分支末端
+	if leaf, err := service.SelectBranch(ctx, "ai-2", userID); err != nil || leaf.ID != "ai-4" {
+		t.Errorf("期望分支末端为 ai-4, 得到 %v %v", leaf, err)
+	}
+
+	// 4. 角色校验
+	if _, err := service.RegenerateMessage(ctx, &RegenerateMessageRequest{MessageID: first.UserMessage.ID, UserID: userID}, nil); err == nil {
+		t.Error("期望重新生成用户消息时返回错误")
+	}
+	if _, err := service.EditMessage(ctx, &EditMessageRequest{MessageID: "ai-1", Message: "x", UserID: userID}, nil); err == nil {
+		t.Error("期望编辑 AI 回复时返回错误")
+	}
+	if _, err := service.GetSiblings(ctx, "ai-1", "user-456"); err == nil {
+		t.Error("期望其他用户访问时返回错误")
+	}
+}
+
+// TestSendMessage_ToolCalls 测试模型发起工具调用时执行工具并继续生成
+func TestSendMessage_ToolCalls(t *testing.T) {
+	ctx := context.Background()
+	userID := "user-123"
+	sessionID := "session-123"
+
+	registry := tool.NewRegistry()
+	calls := 0
+	_ = registry.Register(tool.NewFunc("lookup", "查询", nil, func(ctx context.Context, arguments string) (string, error) {
+		calls++
+		return `{"answer":42}`, nil
+	}))
+	catalog := &testModelCatalog{models: map[string]*model.Model{
+		"tool-model":  {Model: "tool-model", Features: []string{"tool-call"}},
+		"plain-model": {Model: "plain-model"},
+	}}
+
+	newService := func(modelName string, aiService *testAIService) (MessageService, *testMessageRepository) {
+		sessionRepo := newMockSessionRepository()
+		sessionRepo.sessions[sessionID] = &model.ChatSession{
+			ID: sessionID, UserID: userID, ModelName: modelName,
+			Tools: datatypes.NewJSONSlice([]string{"lookup"}),
+		}
+		messageRepo := newTestMessageRepository()
+		return NewMessageService(newTestDB(t), sessionRepo, messageRepo, nil, nil, nil, nil, aiService, catalog, registry, nil, nil), messageRepo
+	}
+	toolCallResponse := func(id, name string) *model.ChatResponse {
+		return &model.ChatResponse{
+			Model:     "tool-model",
+			ToolCalls: []model.ToolCall{{ID: id, Name: name, Arguments: `{}`}},
+		}
+	}
+
+	t.Run("执行工具后继续生成", func(t *testing.T) {
+		aiService := newTestAIService()
+		aiService.responses = []*model.ChatResponse{toolCallResponse("call-1", "lookup")}
+		service, messageRepo := newService("tool-model", aiService)
+
+		resp, err := service.SendMessage(ctx, &SendMessageRequest{
+			SessionID: sessionID, Message: "问题", UserID: userID, MessageID: "ai-1",
+		})
+		if err != nil {
+			t.Fatalf("发送消息失败: %v", err)
+		}
+
+		if calls != 1 || len(aiService.requests) != 2 {
+			t.Fatalf("期望执行 1 次工具、调用 2 次模型, 得到 %d 次和 %d 次", calls, len(aiService.requests))
+		}
+		if len(aiService.requests[0].Tools) != 1 || aiService.requests[0].Tools[0].Name != "lookup" {
+			t.Errorf("期望向模型提供会话启用的工具, 得到 %+v", aiService.requests[0].Tools)
+		}
+
+		// 第二轮请求的历史包含提问、工具调用和工具结果
+		second := aiService.requests[1]
+		if second.Message != "" || len(second.History) != 3 {
+			t.Fatalf("第二轮请求不正确: %+v", second)
+		}
+		if second.History[1].Role != "assistant" || len(second.History[1].ToolCalls) != 1 {
+			t.Errorf("期望历史包含工具调用, 得到 %+v", second.History[1])
+		}
+		if turn := second.History[2]; turn.Role != "function" || turn.ToolCallID != "call-1" || turn.Content != `{"answer":42}` {
+			t.Errorf("期望历史包含工具结果, 得到 %+v", turn)
+		}
+
+		// 工具调用过程保存为消息：发起调用的回复、function 消息、最终回复依次串联
+		if len(resp.ToolMessages) != 2 || resp.ToolMessages[0].ID != "ai-1" || resp.ToolMessages[1].Role != "function" {
+			t.Fatalf("工具调用消息不正确: %+v", resp.ToolMessages)
+		}
+		if len(resp.ToolMessages[0].ToolCalls) != 1 || resp.ToolMessages[0].ToolCalls[0].ID != "call-1" {
+			t.Errorf("期望发起调用的回复包含工具调用, 得到 %+v", resp.ToolMessages[0])
+		}
+		if resp.AIMessage.Content != "AI回复" || *resp.AIMessage.ParentID != resp.ToolMessages[1].ID {
+			t.Errorf("最终回复不正确: %+v", resp.AIMessage)
+		}
+
+		detail, err := service.GetMessageByID(ctx, resp.ToolMessages[1].ID, userID)
+		if err != nil {
+			t.Fatalf("获取消息失败: %v", err)
+		}
+		if len(detail.ToolCalls) != 1 || detail.ToolCalls[0].Name != "lookup" {
+			t.Errorf("期望 function 消息详情包含工具调用, 得到 %+v", detail.ToolCalls)
+		}
+
+		// 重新生成最终回复时沿工具调用消息找到用户消息，后续对话的历史包含工具调用过程
+		if _, err := service.RegenerateMessage(ctx, &RegenerateMessageRequest{MessageID: resp.AIMessage.ID, UserID: userID}, nil); err != nil {
+			t.Fatalf("重新生成失败: %v", err)
+		}
+		if aiService.lastRequest.Message != "问题" {
+			t.Errorf("期望重新生成使用原用户消息, 得到 %q", aiService.lastRequest.Message)
+		}
+		if len(messageRepo.messages) != 5 {
+			t.Errorf("期望共 5 条消息, 得到 %d", len(messageRepo.messages))
+		}
+	})
+
+	t.Run("工具调用后按新回复的消息ID中止", func(t *testing.T) {
+		aiService := newTestAIService()
+		aiService.responses = []*model.ChatResponse{toolCallResponse("call-1", "lookup")}
+		service, messageRepo := newService("tool-model", aiService)
+
+		// 第二轮生成时中止工具调用后新创建的待生成回复
+		var pendingID string
+		var aborted bool
+		var abortErr error
+		aiService.onChat = func(req *model.ChatRequest) {
+			if len(aiService.requests) != 2 {
+				return
+			}
+			for id, message := range messageRepo.messages {
+				if message.Role == "assistant" && message.Status == model.MessageStatusPending {
+					pendingID = id
+				}
+			}
+			aborted, abortErr = service.AbortMessage(ctx, pendingID, userID)
+		}
+
+		resp, err := service.SendMessage(ctx, &SendMessageRequest{
+			SessionID: sessionID, Message: "问题", UserID: userID, MessageID: "ai-1",
+		})
+		if err != nil {
+			t.Fatalf("发送消息失败: %v", err)
+		}
+
+		if pendingID == "" || pendingID != resp.AIMessage.ID {
+			t.Fatalf("期望第二轮生成的回复为 %s, 得到 %s", resp.AIMessage.ID, pendingID)
+		}
+		if abortErr != nil || !aborted {
+			t.Fatalf("期望中止新回复的生成, 得到 aborted=%v err=%v", aborted, abortErr)
+		}
+		if aiService.requests[1].MessageID != pendingID {
+			t.Errorf("期望第二轮请求使用新回复的消息ID, 得到 %s", aiService.requests[1].MessageID)
+		}
+		if len(aiService.abortedKeys) != 1 || aiService.abortedKeys[0] != pendingID {
+			t.Errorf("期望按新回复的消息ID中止, 得到 %v", aiService.abortedKeys)
+		}
+		if resp.AIMessage.Status != model.MessageStatusAborted {
+			t.Errorf("期望新回复标记为已中止, 得到 %s", resp.AIMessage.Status)
+		}
+
+		// 生成结束后移除全部登记
+		for _, id := range []string{"ai-1", pendingID} {
+			if _, generating := service.(*messageService).findGeneration(id); generating {
+				t.Errorf("生成结束后消息 %s 仍在登记中", id)
+			}
+		}
+	})
+
+	t.Run("工具调用后按最初的消息ID中止", func(t *testing.T) {
+		aiService := newTestAIService()
+		aiService.responses = []*model.ChatResponse{toolCallResponse("call-1", "lookup")}
+		service, _ := newService("tool-model", aiService)
+
+		// 工具调用期间生成仍在登记中，按客户端已知的最初消息ID中止整个生成
+		var aborted bool
+		aiService.onChat = func(req *model.ChatRequest) {
+			if len(aiService.requests) == 2 {
+				aborted, _ = service.AbortMessage(ctx, "ai-1", userID)
+			}
+		}
+
+		resp, err := service.SendMessage(ctx, &SendMessageRequest{
+			SessionID: sessionID, Message: "问题", UserID: userID, MessageID: "ai-1",
+		})
+		if err != nil {
+			t.Fatalf("发送消息失败: %v", err)
+		}
+		if !aborted {
+			t.Error("期望按最初的消息ID中止生成")
+		}
+		if resp.AIMessage.ID == "ai-1" || resp.AIMessage.Status != model.MessageStatusAborted {
+			t.Errorf("期望工具调用后的新回复标记为已中止, 得到 %s %s", resp.AIMessage.ID, resp.AIMessage.Status)
+		}
+	})
+
+	t.Run("不可用的工具和轮数上限", func(t *testing.T) {
+		aiService := newTestAIService()
+		for i := 0; i < maxToolRounds+1; i++ {
+			aiService.responses = append(aiService.responses, toolCallResponse(fmt.Sprintf("call-%d", i), "unknown"))
+		}
+		service, _ := newService("tool-model", aiService)
+
+		resp, err := service.SendMessage(ctx, &SendMessageRequest{SessionID: sessionID, Message: "问题", UserID: userID})
+		if err != nil {
+			t.Fatalf("发送消息失败: %v", err)
+		}
+
+		if len(aiService.requests) != maxToolRounds+1 {
+			t.Errorf("期望调用 %d 次模型, 得到 %d", maxToolRounds+1, len(aiService.requests))
+		}
+		if last := aiService.requests[maxToolRounds]; len(last.Tools) != 0 {
+			t.Errorf("期望达到上限后不再提供工具, 得到 %+v", last.Tools)
+		}
+		if len(resp.ToolMessages) != maxToolRounds*2 {
+			t.Errorf("期望 %d 条工具调用消息, 得到 %d", maxToolRounds*2, len(resp.ToolMessages))
+		}
+		if content := resp.ToolMessages[1].Content; content != "工具调用失败: 工具 'unknown' 不可用" {
+			t.Errorf("期望不可用的工具返回错误信息, 得到 %q", content)
+		}
+	})
+
+	t.Run("流式生成", func(t *testing.T) {
+		aiService := newTestAIService()
+		aiService.streamChunks = []string{"最终", "回复"}
+		aiService.responses = []*model.ChatResponse{toolCallResponse("call-1", "lookup")}
+		service, _ := newService("tool-model", aiService)
+
+		var streamed []string
+		resp, err := service.SendMessageStream(ctx, &SendMessageRequest{SessionID: sessionID, Message: "问题", UserID: userID},
+			func(content string) error {
+				streamed = append(streamed, content)
+				return nil
+			})
+		if err != nil {
+			t.Fatalf("流式发送消息失败: %v", err)
+		}
+		if len(streamed) != 3 || streamed[1]+streamed[2] != "最终回复" {
+			t.Errorf("推送的片段不正确: %q", streamed)
+		}
+		if len(resp.ToolMessages) != 2 || resp.AIMessage.Content != "最终回复" {
+			t.Errorf("流式工具调用结果不正确: tools=%d content=%q", len(resp.ToolMessages), resp.AIMessage.Content)
+		}
+	})
+
+	t.Run("模型不支持工具调用", func(t *testing.T) {
+		aiService := newTestAIService()
+		service, _ := newService("plain-model", aiService)
+
+		if _, err := service.SendMessage(ctx, &SendMessageRequest{SessionID: sessionID, Message: "问题", UserID: userID}); err != nil {
+			t.Fatalf("发送消息失败: %v", err)
+		}
+		if len(aiService.lastRequest.Tools) != 0 {
+			t.Errorf("期望不向不支持工具调用的模型提供工具, 得到 %+v", aiService.lastRequest.Tools)
+		}
+	})
+}
+
+// TestSendMessage_SchemaName 测试按名称使用会话保存的 JSON Schema
+func TestSendMessage_SchemaName(t *testing.T) {
+	ctx := context.Background()
+	userID := "user-123"
+	sessionID := "session-123"
+
+	schema := map[string]interface{}{"type": "object"}
+	sessionRepo := newMockSessionRepository()
+	sessionRepo.sessions[sessionID] = &model.ChatSession{
+		ID: sessionID, UserID: userID, ModelName: "test-model",
+		Schemas: datatypes.JSONMap{"city": schema},
+	}
+	aiService := newTestAIService()
+	aiService.response.Parsed = map[string]interface{}{"city": "杭州"}
+	messageRepo := newTestMessageRepository()
+	service := NewMessageService(newTestDB(t), sessionRepo, messageRepo, nil, nil, nil, nil, aiService, nil, nil, nil, nil)
+
+	resp, err := service.SendMessage(ctx, &SendMessageRequest{
+		SessionID: sessionID, Message: "提取城市", UserID: userID,
+		Options: &model.ChatOptions{SchemaName: "city"},
+	})
+	if err != nil {
+		t.Fatalf("发送消息失败: %v", err)
+	}
+	if !reflect.DeepEqual(aiService.lastRequest.Options.JSONSchema, schema) {
+		t.Errorf("期望请求使用会话保存的 Schema, 得到 %+v", aiService.lastRequest.Options)
+	}
+	if resp.Parsed == nil {
+		t.Error("期望响应包含解析后的回复")
+	}
+
+	_, err = service.SendMessage(ctx, &SendMessageRequest{
+		SessionID: sessionID, Message: "提取城市", UserID: userID,
+		Options: &model.ChatOptions{SchemaName: "unknown"},
+	})
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.CodeBadRequest {
+		t.Errorf("期望 Schema 不存在时返回请求错误, 得到 %v", err)
+	}
+	if len(messageRepo.messages) != 2 {
+		t.Errorf("Schema 不存在时不应保存消息, 得到 %d 条消息", len(messageRepo.messages))
+	}
+}
+
+// testKnowledgeRetriever 测试用知识库检索
+type testKnowledgeRetriever struct {
+	citations []model.Citation
+	err       error
+	queries   []string
+}
+
+func (r *testKnowledgeRetriever) GetKnowledgeBase(ctx context.Context, knowledgeBaseID, userID string) (*model.KnowledgeBase, error) {
+	return &model.KnowledgeBase{ID: knowledgeBaseID, UserID: userID}, nil
+}
+
+func (r *testKnowledgeRetriever) Search(ctx context.Context, userID string, knowledgeBaseIDs []string, query string, topK int) ([]model.Citation, error) {
+	r.queries = append(r.queries, query)
+	return r.citations, r.err
+}
+
+func TestSendMessage_KnowledgeBase(t *testing.T) {
+	ctx := context.Background()
+	userID := "user-123"
+	sessionID := "session-123"
+
+	newService := func(retriever *testKnowledgeRetriever, knowledgeBases []string) (MessageService, *testMessageRepository, *testAIService) {
+		sessionRepo := newMockSessionRepository()
+		sessionRepo.sessions[sessionID] = &model.ChatSession{
+			ID: sessionID, UserID: userID, ModelName: "test-model", SystemPrompt: "你是运维助手",
+			KnowledgeBases: datatypes.NewJSONSlice(knowledgeBases),
+		}
+		messageRepo := newTestMessageRepository()
+		aiService := newTestAIService()
+		return NewMessageService(newTestDB(t), sessionRepo, messageRepo, nil, nil, nil, retriever, aiService, nil, nil, nil, nil), messageRepo, aiService
+	}
+
+	t.Run("检索结果注入提示词并记录引用", func(t *testing.T) {
+		retriever := &testKnowledgeRetriever{citations: []model.Citation{
+			{KnowledgeBaseID: "kb-1", DocumentID: "doc-1", DocumentName: "部署.md", ChunkID: "chunk-1", Heading: "部署 > 数据库", Content: "设置 DATABASE_HOST。", Score: 0.9},
+		}}
+		service, messageRepo, aiService := newService(retriever, []string{"kb-1"})
+
+		resp, err := service.SendMessage(ctx, &SendMessageRequest{SessionID: sessionID, Message: "如何配置数据库", UserID: userID})
+		if err != nil {
+			t.Fatalf("发送消息失败: %v", err)
+		}
+
+		if len(retriever.queries) != 1 || retriever.queries[0] != "如何配置数据库" {
+			t.Errorf("期望以消息内容检索, 得到 %v", retriever.queries)
+		}
+		prompt := aiService.lastRequest.SystemPrompt
+		if !strings.HasPrefix(prompt, "你是运维助手") || !strings.Contains(prompt, "[1] 部署.md > 部署 > 数据库\n设置 DATABASE_HOST。") {
+			t.Errorf("系统提示词未包含参考资料: %q", prompt)
+		}
+		if resp.Meta == nil || len(resp.Meta.Citations) != 1 {
+			t.Fatalf("期望响应包含引用, 得到 %+v", resp.Meta)
+		}
+
+		detail, err := service.GetMessageByID(ctx, resp.MessageID, userID)
+		if err != nil {
+			t.Fatalf("获取消息失败: %v", err)
+		}
+		citations, ok := detail.Meta["citations"].([]interface{})
+		if !ok || len(citations) != 1 {
+			t.Fatalf("期望 AI 回复的 Meta 记录引用, 得到 %+v", detail.Meta)
+		}
+		if citations[0].(map[string]interface{})["chunkId"] != "chunk-1" {
+			t.Errorf("引用内容不正确: %+v", citations[0])
+		}
+		if len(messageRepo.messages[resp.MessageID].Meta) == 0 {
+			t.Error("期望保存 AI 回复的 Meta")
+		}
+	})
+
+	t.Run("未挂载知识库时不检索", func(t *testing.T) {
+		retriever := &testKnowledgeRetriever{}
+		service, _, aiService := newService(retriever, nil)
+
+		if _, err := service.SendMessage(ctx, &SendMessageRequest{SessionID: sessionID, Message: "你好", UserID: userID}); err != nil {
+			t.Fatalf("发送消息失败: %v", err)
+		}
+		if len(retriever.queries) != 0 || aiService.lastRequest.SystemPrompt != "你是运维助手" {
+			t.Errorf("不应检索知识库: %v, %q", retriever.queries, aiService.lastRequest.SystemPrompt)
+		}
+	})
+
+	t.Run("检索失败时仍发送消息", func(t *testing.T) {
+		retriever := &testKnowledgeRetriever{err: fmt.Errorf("向量服务不可用")}
+		service, _, aiService := newService(retriever, []string{"kb-1"})
+
+		resp, err := service.SendMessage(ctx, &SendMessageRequest{SessionID: sessionID, Message: "你好", UserID: userID})
+		if err != nil {
+			t.Fatalf("发送消息失败: %v", err)
+		}
+		if resp.Meta != nil && len(resp.Meta.Citations) != 0 || aiService.lastRequest.SystemPrompt != "你是运维助手" {
+			t.Errorf("检索失败时不应注入参考资料: %+v", resp.Meta)
+		}
+	})
+}
+
+// testAttachmentRepository 测试用附件仓库
+type testAttachmentRepository struct {
+	attachments []*model.ChatAttachment
+}
+
+func (r *testAttachmentRepository) Create(ctx context.Context, attachments []*model.ChatAttachment) error {
+	for _, attachment := range attachments {
+		attachment.ID = fmt.Sprintf("attachment-%d", len(r.attachments)+1)
+		r.attachments = append(r.attachments, attachment)
+	}
+	return nil
+}
+
+func (r *testAttachmentRepository) GetByMessageIDs(ctx context.Context, messageIDs []string) ([]*model.ChatAttachment, error) {
+	var result []*model.ChatAttachment
+	for _, attachment := range r.attachments {
+		for _, id := range messageIDs {
+			if attachment.MessageID == id {
+				result = append(result, attachment)
+			}
+		}
+	}
+	return result, nil
+}
+
+// TestSendMessage_Attachments 测试发送带媒体片段的消息
+func TestSendMessage_Attachments(t *testing.T) {
+	ctx := context.Background()
+	userID := "user-123"
+	sessionID := "session-123"
+
+	catalog := &testModelCatalog{models: map[string]*model.Model{
+		"vision-model": {Model: "vision-model", Features: []string{"vision"}},
+		"plain-model":  {Model: "plain-model"},
+	}}
+	newService := func(modelName string) (MessageService, *testMessageRepository, *testAttachmentRepository, *testAIService) {
+		sessionRepo := newMockSessionRepository()
+		sessionRepo.sessions[sessionID] = &model.ChatSession{ID: sessionID, UserID: userID, ModelName: modelName}
+		messageRepo := newTestMessageRepository()
+		attachmentRepo := &testAttachmentRepository{}
+		aiService := newTestAIService()
+		service := NewMessageService(newTestDB(t), sessionRepo, messageRepo, nil, attachmentRepo, nil, nil, aiService, catalog, nil, nil, nil)
+		return service, messageRepo, attachmentRepo, aiService
+	}
+	image := model.MessagePart{Type: model.PartTypeMedia, MimeType: "image/png", Data: "aGVsbG8="}
+
+	t.Run("保存附件并随历史发送", func(t *testing.T) {
+		service, messageRepo, attachmentRepo, aiService := newService("vision-model")
+
+		resp, err := service.SendMessage(ctx, &SendMessageRequest{
+			SessionID: sessionID, UserID: userID, Message: "这是什么",
+			Parts: []model.MessagePart{{Type: model.PartTypeText, Text: "请详细说明"}, image},
+		})
+		if err != nil {
+			t.Fatalf("发送消息失败: %v", err)
+		}
+		if resp.UserMessage.Content != "这是什么\n\n请详细说明" {
+			t.Errorf("文本片段应并入消息内容, 得到 %q", resp.UserMessage.Content)
+		}
+		if len(aiService.lastRequest.Parts) != 1 || aiService.lastRequest.Parts[0].Data != image.Data {
+			t.Errorf("期望请求携带媒体片段, 得到 %+v", aiService.lastRequest.Parts)
+		}
+		if len(attachmentRepo.attachments) != 1 || string(attachmentRepo.attachments[0].Data) != "hello" {
+			t.Fatalf("期望保存解码后的附件, 得到 %+v", attachmentRepo.attachments)
+		}
+		if attachmentRepo.attachments[0].MessageID != resp.UserMessage.ID {
+			t.Errorf("附件应属于用户消息")
+		}
+
+		if _, err := service.SendMessage(ctx, &SendMessageRequest{SessionID: sessionID, UserID: userID, Message: "继续"}); err != nil {
+			t.Fatalf("发送消息失败: %v", err)
+		}
+		history := aiService.lastRequest.History
+		if len(history) != 2 || len(history[0].Parts) != 1 || history[0].Parts[0].MimeType != "image/png" {
+			t.Errorf("期望历史中的用户消息携带附件, 得到 %+v", history)
+		}
+
+		// 重新生成时复用原用户消息的附件，不重复保存
+		if _, err := service.RegenerateMessage(ctx, &RegenerateMessageRequest{MessageID: resp.AIMessage.ID, UserID: userID}, nil); err != nil {
+			t.Fatalf("重新生成失败: %v", err)
+		}
+		if len(aiService.lastRequest.Parts) != 1 {
+			t.Errorf("期望重新生成时携带原消息的附件, 得到 %+v", aiService.lastRequest.Parts)
+		}
+		if len(attachmentRepo.attachments) != 1 || len(messageRepo.messages) != 5 {
+			t.Errorf("重新生成不应保存新附件: attachments=%d messages=%d", len(attachmentRepo.attachments), len(messageRepo.messages))
+		}
+	})
+
+	t.Run("模型不支持的附件", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			modelName string
+			part      model.MessagePart
+		}{
+			{name: "不支持图片", modelName: "plain-model", part: image},
+			{name: "不支持文档", modelName: "vision-model", part: model.MessagePart{Type: model.PartTypeMedia, MimeType: "application/pdf", URL: "https://example.com/a.pdf"}},
+			{name: "缺少数据", modelName: "vision-model", part: model.MessagePart{Type: model.PartTypeMedia, MimeType: "image/png"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				service, messageRepo, _, _ := newService(tt.modelName)
+
+				_, err := service.SendMessage(ctx, &SendMessageRequest{
+					SessionID: sessionID, UserID: userID, Message: "这是什么", Parts: []model.MessagePart{tt.part},
+				})
+				if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.CodeBadRequest {
+					t.Errorf("期望请求错误, 得到 %v", err)
+				}
+				if len(messageRepo.messages) != 0 {
+					t.Errorf("附件无效时不应保存消息, 得到 %d 条消息", len(messageRepo.messages))
+				}
+			})
+		}
+	})
+}
+
+// testFileReader 测试用上传文件读取
+type testFileReader struct {
+	files    map[string]*model.File
+	contents map[string][]byte
+}
+
+func (r *testFileReader) ReadFile(ctx context.Context, fileID string) (*model.File, []byte, error) {
+	file, ok := r.files[fileID]
+	if !ok {
+		return nil, nil, errors.NewFileNotFoundError(fileID)
+	}
+	return file, r.contents[fileID], nil
+}
+
+// TestSendMessage_FileReference 测试在消息片段中引用上传文件
+func TestSendMessage_FileReference(t *testing.T) {
+	ctx := context.Background()
+	userID := "user-123"
+	sessionID := "session-123"
+	fileID := "550e8400-e29b-41d4-a716-446655440000"
+	otherFileID := "550e8400-e29b-41d4-a716-446655440001"
+
+	catalog := &testModelCatalog{models: map[string]*model.Model{
+		"vision-model": {Model: "vision-model", Features: []string{"vision"}},
+	}}
+	newService := func(files FileReader) (MessageService, *testAttachmentRepository, *testAIService) {
+		sessionRepo := newMockSessionRepository()
+		sessionRepo.sessions[sessionID] = &model.ChatSession{ID: sessionID, UserID: userID, ModelName: "vision-model"}
+		attachmentRepo := &testAttachmentRepository{}
+		aiService := newTestAIService()
+		service := NewMessageService(newTestDB(t), sessionRepo, newTestMessageRepository(), nil, attachmentRepo, files, nil, aiService, catalog, nil, nil, nil)
+		return service, attachmentRepo, aiService
+	}
+	newFiles := func() *testFileReader {
+		return &testFileReader{
+			files: map[string]*model.File{
+				fileID:      {ID: fileID, UserID: userID, MimeType: "image/png", Size: 5},
+				otherFileID: {ID: otherFileID, UserID: "user-456", MimeType: "image/png", Size: 5},
+			},
+			contents: map[string][]byte{fileID: []byte("hello"), otherFileID: []byte("other")},
+		}
+	}
+
+	t.Run("引用文件并只保存文件ID", func(t *testing.T) {
+		files := newFiles()
+		service, attachmentRepo, aiService := newService(files)
+
+		if _, err := service.SendMessage(ctx, &SendMessageRequest{
+			SessionID: sessionID, UserID: userID, Message: "这是什么",
+			Parts: []model.MessagePart{{Type: model.PartTypeMedia, FileID: fileID}},
+		}); err != nil {
+			t.Fatalf("发送消息失败: %v", err)
+		}
+
+		parts := aiService.lastRequest.Parts
+		if len(parts) != 1 || parts[0].MimeType != "image/png" || parts[0].Data != "aGVsbG8=" {
+			t.Errorf("期望片段填充文件的类型和内容, 得到 %+v", parts)
+		}
+		if len(attachmentRepo.attachments) != 1 {
+			t.Fatalf("期望保存 1 个附件, 得到 %d", len(attachmentRepo.attachments))
+		}
+		attachment := attachmentRepo.attachments[0]
+		if attachment.FileID == nil || *attachment.FileID != fileID || len(attachment.Data) != 0 || attachment.Size != 5 {
+			t.Errorf("期望附件只保存文件ID和大小, 得到 %+v", attachment)
+		}
+
+		// 历史消息的附件从文件存储读取内容
+		if _, err := service.SendMessage(ctx, &SendMessageRequest{SessionID: sessionID, UserID: userID, Message: "继续"}); err != nil {
+			t.Fatalf("发送消息失败: %v", err)
+		}
+		history := aiService.lastRequest.History
+		if len(history) != 2 || len(history[0].Parts) != 1 || history[0].Parts[0].Data != "aGVsbG8=" {
+			t.Errorf("期望历史中的附件携带文件内容, 得到 %+v", history)
+		}
+
+		// 文件删除后历史中不再携带该附件
+		delete(files.files, fileID)
+		if _, err := service.SendMessage(ctx, &SendMessageRequest{SessionID: sessionID, UserID: userID, Message: "还在吗"}); err != nil {
+			t.Fatalf("发送消息失败: %v", err)
+		}
+		history = aiService.lastRequest.History
+		if len(history) == 0 || len(history[0].Parts) != 0 {
+			t.Errorf("期望跳过已删除文件的附件, 得到 %+v", history)
+		}
+	})
+
+	t.Run("无效的文件引用", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			files    FileReader
+			fileID   string
+			wantCode int
+		}{
+			{name: "文件不存在", files: newFiles(), fileID: "550e8400-e29b-41d4-a716-446655440002", wantCode: errors.CodeFileNotFound},
+			{name: "其他用户的文件", files: newFiles(), fileID: otherFileID, wantCode: errors.CodeFileAccessDenied},
+			{name: "不支持引用文件", files: nil, fileID: fileID, wantCode: errors.CodeBadRequest},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				service, attachmentRepo, _ := newService(tt.files)
+
+				_, err := service.SendMessage(ctx, &SendMessageRequest{
+					SessionID: sessionID, UserID: userID, Message: "这是什么",
+					Parts: []model.MessagePart{{Type: model.PartTypeMedia, FileID: tt.fileID}},
+				})
+				if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != tt.wantCode {
+					t.Errorf("期望错误码 %d, 得到 %v", tt.wantCode, err)
+				}
+				if len(attachmentRepo.attachments) != 0 {
+					t.Errorf("文件引用无效时不应保存附件")
+				}
+			})
+		}
+	})
+}
@@ -7,7 +7,11 @@ import (
 
 	"genkit-ai-service/internal/model"
 	"genkit-ai-service/internal/repository"
+	"genkit-ai-service/internal/service/tool"
 	"genkit-ai-service/pkg/errors"
+	"genkit-ai-service/pkg/jsonschema"
+
+	"gorm.io/datatypes"
 )
 
 // SessionService 会话业务逻辑接口
@@ -39,20 +43,36 @@ type SessionService interface {
 
 // sessionService 会话业务逻辑实现
 type sessionService struct {
-	sessionRepo repository.SessionRepository
-	messageRepo repository.MessageRepository
+	sessionRepo  repository.SessionRepository
+	messageRepo  repository.MessageRepository
+	toolRegistry tool.Registry
+	knowledge    KnowledgeRetriever
 }
 
 // NewSessionService 创建会话业务逻辑实例
-func NewSessionService(sessionRepo repository.SessionRepository, messageRepo repository.MessageRepository) SessionService {
+// toolRegistry 用于校验会话启用的工具，为 nil 时不校验；
+// knowledge 用于校验会话挂载的知识库，为 nil 时不允许挂载知识库
+func NewSessionService(sessionRepo repository.SessionRepository, messageRepo repository.MessageRepository, toolRegistry tool.Registry, knowledge KnowledgeRetriever) SessionService {
 	return &sessionService{
-		sessionRepo: sessionRepo,
-		messageRepo: messageRepo,
+		sessionRepo:  sessionRepo,
+		messageRepo:  messageRepo,
+		toolRegistry: toolRegistry,
+		knowledge:    knowledge,
 	}
 }
 
 // CreateSession 创建新会话
 func (s *sessionService) CreateSession(ctx context.Context, userID string, req *model.CreateSessionRequest) (*model.SessionResponse, error) {
+	if err := s.validateTools(req.Tools); err != nil {
+		return nil, err
+	}
+	if err := validateSchemas(req.Schemas); err != nil {
+		return nil, err
+	}
+	if err := s.validateKnowledgeBases(ctx, userID, req.KnowledgeBases); err != nil {
+		return nil, err
+	}
+
 	// 创建会话实体
 	session := &model.ChatSession{
 		UserID:       userID,
@@ -69,6 +89,15 @@ func (s *sessionService) CreateSession(ctx context.Context, userID string, req *
 		IsArchived:   false,
 		IsDeleted:    false,
 	}
+	if len(req.Tools) > 0 {
+		session.Tools = datatypes.NewJSONSlice(req.Tools)
+	}
+	if len(req.Schemas) > 0 {
+		session.Schemas = datatypes.JSONMap(req.Schemas)
+	}
+	if len(req.KnowledgeBases) > 0 {
+		session.KnowledgeBases = datatypes.NewJSONSlice(req.KnowledgeBases)
+	}
 
 	// 处理元数据
 	if req.Meta != nil {
@@ -176,6 +205,24 @@ func (s *sessionService) UpdateSession(ctx context.Context, sessionID, userID st
 	if req.ModelName != nil {
 		fields["model_name"] = *req.ModelName
 	}
+	if req.Tools != nil {
+		if err := s.validateTools(*req.Tools); err != nil {
+			return nil, err
+		}
+		fields["tools"] = datatypes.NewJSONSlice(*req.Tools)
+	}
+	if req.Schemas != nil {
+		if err := validateSchemas(req.Schemas); err != nil {
+			return nil, err
+		}
+		fields["schemas"] = datatypes.JSONMap(req.Schemas)
+	}
+	if req.KnowledgeBases != nil {
+		if err := s.validateKnowledgeBases(ctx, userID, *req.KnowledgeBases); err != nil {
+			return nil, err
+		}
+		fields["knowledge_bases"] = datatypes.NewJSONSlice(*req.KnowledgeBases)
+	}
 
 	// 更新时间戳
 	fields["updated_at"] = time.Now()
@@ -304,21 +351,67 @@ func (s *sessionService) ArchiveSession(ctx context.Context, sessionID, userID s
 	return nil
 }
 
+// validateTools 校验会话启用的工具均已注册
+func (s *sessionService) validateTools(names []string) error {
+	if s.toolRegistry == nil {
+		return nil
+	}
+	for _, name := range names {
+		if _, exists := s.toolRegistry.Get(name); !exists {
+			return errors.NewBadRequestError(fmt.Sprintf("工具 '%s' 不存在", name))
+		}
+	}
+	return nil
+}
+
+// validateKnowledgeBases 校验会话挂载的知识库均存在且属于该用户
+func (s *sessionService) validateKnowledgeBases(ctx context.Context, userID string, knowledgeBaseIDs []string) error {
+	if len(knowledgeBaseIDs) == 0 {
+		return nil
+	}
+	if s.knowledge == nil {
+		return errors.NewServiceUnavailableError("知识库服务未启用")
+	}
+	for _, knowledgeBaseID := range knowledgeBaseIDs {
+		if _, err := s.knowledge.GetKnowledgeBase(ctx, knowledgeBaseID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSchemas 校验会话保存的命名 JSON Schema 均为有效的 Schema 对象
+func validateSchemas(schemas map[string]interface{}) error {
+	for name, value := range schemas {
+		schema, ok := value.(map[string]interface{})
+		if !ok {
+			return errors.NewBadRequestError(fmt.Sprintf("Schema '%s' 必须为对象", name))
+		}
+		if err := jsonschema.Check(schema); err != nil {
+			return errors.NewBadRequestError(fmt.Sprintf("Schema '%s' 无效: %v", name, err))
+		}
+	}
+	return nil
+}
+
 // toSessionResponse 将会话实体转换为响应格式
 func (s *sessionService) toSessionResponse(session *model.ChatSession, lastMessage *model.ChatMessage) *model.SessionResponse {
 	response := &model.SessionResponse{
-		ID:           session.ID,
-		UserID:       session.UserID,
-		Title:        session.Title,
-		ModelName:    session.ModelName,
-		SystemPrompt: session.SystemPrompt,
-		Temperature:  session.Temperature,
-		TopP:         session.TopP,
-		CreatedAt:    session.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:    session.UpdatedAt.Format(time.RFC3339),
-		MessageCount: session.MessageCount,
-		IsPinned:     session.IsPinned,
-		IsArchived:   session.IsArchived,
+		ID:             session.ID,
+		UserID:         session.UserID,
+		Title:          session.Title,
+		ModelName:      session.ModelName,
+		SystemPrompt:   session.SystemPrompt,
+		Temperature:    session.Temperature,
+		TopP:           session.TopP,
+		CreatedAt:      session.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      session.UpdatedAt.Format(time.RFC3339),
+		MessageCount:   session.MessageCount,
+		IsPinned:       session.IsPinned,
+		IsArchived:     session.IsArchived,
+		Tools:          session.Tools,
+		Schemas:        session.Schemas,
+		KnowledgeBases: session.KnowledgeBases,
 	}
 
 	// 处理最后一条消息
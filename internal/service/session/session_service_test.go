@@ -2,10 +2,15 @@ package session
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"genkit-ai-service/internal/model"
 	"genkit-ai-service/internal/repository"
+	"genkit-ai-service/internal/service/tool"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 // mockSessionRepository 模拟会话仓库
@@ -30,7 +35,8 @@ func (m *mockSessionRepository) Create(ctx context.Context, session *model.ChatS
 func (m *mockSessionRepository) GetByID(ctx context.Context, sessionID string) (*model.ChatSession, error) {
 	session, exists := m.sessions[sessionID]
 	if !exists {
-		return nil, repository.ErrNotFound
+		// 与会话仓库一致，不存在时返回包装的 gorm.ErrRecordNotFound
+		return nil, fmt.Errorf("会话不存在: %w", gorm.ErrRecordNotFound)
 	}
 	return session, nil
 }
@@ -59,6 +65,12 @@ func (m *mockSessionRepository) UpdateFields(ctx context.Context, sessionID stri
 	if title, ok := fields["title"].(string); ok {
 		session.Title = title
 	}
+	if tools, ok := fields["tools"].(datatypes.JSONSlice[string]); ok {
+		session.Tools = tools
+	}
+	if schemas, ok := fields["schemas"].(datatypes.JSONMap); ok {
+		session.Schemas = schemas
+	}
 	return nil
 }
 
@@ -80,6 +92,9 @@ func (m *mockSessionRepository) IncrementMessageCount(ctx context.Context, sessi
 }
 
 func (m *mockSessionRepository) UpdateLastMessage(ctx context.Context, sessionID, messageID string) error {
+	if session, exists := m.sessions[sessionID]; exists {
+		session.LastMessageID = &messageID
+	}
 	return nil
 }
 
@@ -88,6 +103,7 @@ type mockMessageRepository struct {
 	getBySessionIDFunc   func(ctx context.Context, sessionID string, page, pageSize int) ([]*model.ChatMessage, int, error)
 	countBySessionIDFunc func(ctx context.Context, sessionID string) (int, error)
 	getMessagesAfterFunc func(ctx context.Context, sessionID, afterMessageID string) ([]*model.ChatMessage, error)
+	getBranchFunc        func(ctx context.Context, sessionID, leafMessageID string) ([]*model.ChatMessage, error)
 }
 
 func newMockMessageRepository() *mockMessageRepository {
@@ -102,13 +118,28 @@ func (m *mockMessageRepository) GetByID(ctx context.Context, messageID string) (
 	return nil, repository.ErrNotFound
 }
 
-func (m *mockMessageRepository) GetBySessionID(ctx context.Context, sessionID string, page, pageSize int) ([]*model.ChatMessage, int, error) {
+func (m *mockMessageRepository) GetBySessionID(ctx context.Context, sessionID string, page, pageSize int, filters *repository.MessageFilters) ([]*model.ChatMessage, int, error) {
 	if m.getBySessionIDFunc != nil {
 		return m.getBySessionIDFunc(ctx, sessionID, page, pageSize)
 	}
 	return []*model.ChatMessage{}, 0, nil
 }
 
+func (m *mockMessageRepository) UpdateFields(ctx context.Context, messageID string, fields map[string]interface{}) error {
+	return nil
+}
+
+func (m *mockMessageRepository) GetBranch(ctx context.Context, sessionID, leafMessageID string) ([]*model.ChatMessage, error) {
+	if m.getBranchFunc != nil {
+		return m.getBranchFunc(ctx, sessionID, leafMessageID)
+	}
+	return []*model.ChatMessage{}, nil
+}
+
+func (m *mockMessageRepository) GetChildren(ctx context.Context, sessionID string, parentID *string) ([]*model.ChatMessage, error) {
+	return []*model.ChatMessage{}, nil
+}
+
 func (m *mockMessageRepository) GetLatestMessages(ctx context.Context, sessionID string, limit int) ([]*model.ChatMessage, error) {
 	return []*model.ChatMessage{}, nil
 }
@@ -135,7 +166,7 @@ func (m *mockMessageRepository) GetMessagesAfter(ctx context.Context, sessionID
 func TestCreateSession(t *testing.T) {
 	sessionRepo := newMockSessionRepository()
 	messageRepo := newMockMessageRepository()
-	service := NewSessionService(sessionRepo, messageRepo)
+	service := NewSessionService(sessionRepo, messageRepo, nil, nil)
 
 	ctx := context.Background()
 	userID := "test-user-id"
@@ -172,7 +203,7 @@ func TestCreateSession(t *testing.T) {
 func TestGetSession(t *testing.T) {
 	sessionRepo := newMockSessionRepository()
 	messageRepo := newMockMessageRepository()
-	service := NewSessionService(sessionRepo, messageRepo)
+	service := NewSessionService(sessionRepo, messageRepo, nil, nil)
 
 	ctx := context.Background()
 	userID := "test-user-id"
@@ -205,7 +236,7 @@ func TestGetSession(t *testing.T) {
 func TestUpdateSession(t *testing.T) {
 	sessionRepo := newMockSessionRepository()
 	messageRepo := newMockMessageRepository()
-	service := NewSessionService(sessionRepo, messageRepo)
+	service := NewSessionService(sessionRepo, messageRepo, nil, nil)
 
 	ctx := context.Background()
 	userID := "test-user-id"
@@ -239,11 +270,85 @@ func TestUpdateSession(t *testing.T) {
 	}
 }
 
+// TestSessionTools 测试会话启用工具时校验工具已注册
+func TestSessionTools(t *testing.T) {
+	registry := tool.NewRegistry()
+	if err := registry.Register(tool.NewCurrentTimeTool()); err != nil {
+		t.Fatalf("注册工具失败: %v", err)
+	}
+	service := NewSessionService(newMockSessionRepository(), newMockMessageRepository(), registry, nil)
+	ctx := context.Background()
+	userID := "test-user-id"
+
+	if _, err := service.CreateSession(ctx, userID, &model.CreateSessionRequest{
+		Title: "测试会话", ModelName: "gpt-4", Tools: []string{"unknown_tool"},
+	}); err == nil {
+		t.Error("期望启用未注册的工具时返回错误")
+	}
+
+	created, err := service.CreateSession(ctx, userID, &model.CreateSessionRequest{
+		Title: "测试会话", ModelName: "gpt-4", Tools: []string{tool.CurrentTimeToolName},
+	})
+	if err != nil {
+		t.Fatalf("创建会话失败: %v", err)
+	}
+	if len(created.Tools) != 1 || created.Tools[0] != tool.CurrentTimeToolName {
+		t.Errorf("期望会话启用 %s, 得到 %v", tool.CurrentTimeToolName, created.Tools)
+	}
+
+	// 传空数组时清空会话的工具
+	updated, err := service.UpdateSession(ctx, created.ID, userID, &model.UpdateSessionRequest{Tools: &[]string{}})
+	if err != nil {
+		t.Fatalf("更新会话失败: %v", err)
+	}
+	if len(updated.Tools) != 0 {
+		t.Errorf("期望清空会话的工具, 得到 %v", updated.Tools)
+	}
+}
+
+// TestSessionSchemas 测试会话保存的命名 JSON Schema
+func TestSessionSchemas(t *testing.T) {
+	service := NewSessionService(newMockSessionRepository(), newMockMessageRepository(), nil, nil)
+	ctx := context.Background()
+	userID := "test-user-id"
+
+	for name, schemas := range map[string]map[string]interface{}{
+		"不是对象":  {"city": "string"},
+		"无效的类型": {"city": map[string]interface{}{"type": "date"}},
+	} {
+		if _, err := service.CreateSession(ctx, userID, &model.CreateSessionRequest{
+			Title: "测试会话", ModelName: "gpt-4", Schemas: schemas,
+		}); err == nil {
+			t.Errorf("%s: 期望 Schema 无效时返回错误", name)
+		}
+	}
+
+	created, err := service.CreateSession(ctx, userID, &model.CreateSessionRequest{
+		Title: "测试会话", ModelName: "gpt-4",
+		Schemas: map[string]interface{}{"city": map[string]interface{}{"type": "object"}},
+	})
+	if err != nil {
+		t.Fatalf("创建会话失败: %v", err)
+	}
+	if _, ok := created.Schemas["city"]; !ok {
+		t.Errorf("期望会话保存 Schema 'city', 得到 %v", created.Schemas)
+	}
+
+	// 传空对象时清空会话的 Schema
+	updated, err := service.UpdateSession(ctx, created.ID, userID, &model.UpdateSessionRequest{Schemas: map[string]interface{}{}})
+	if err != nil {
+		t.Fatalf("更新会话失败: %v", err)
+	}
+	if len(updated.Schemas) != 0 {
+		t.Errorf("期望清空会话的 Schema, 得到 %v", updated.Schemas)
+	}
+}
+
 // TestDeleteSession 测试删除会话
 func TestDeleteSession(t *testing.T) {
 	sessionRepo := newMockSessionRepository()
 	messageRepo := newMockMessageRepository()
-	service := NewSessionService(sessionRepo, messageRepo)
+	service := NewSessionService(sessionRepo, messageRepo, nil, nil)
 
 	ctx := context.Background()
 	userID := "test-user-id"
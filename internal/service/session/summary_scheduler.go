@@ -0,0 +1,205 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"genkit-ai-service/internal/logger"
+)
+
+// summaryJobTimeout 单次后台摘要生成的超时时间
+const summaryJobTimeout = 2 * time.Minute
+
+// SummaryScheduler 后台摘要调度器接口
+type SummaryScheduler interface {
+	// Schedule 提交会话的摘要检查任务
+	// 同一会话已有排队或执行中的任务、队列已满或调度器已停止时返回 false
+	Schedule(sessionID string) bool
+
+	// LastFailure 获取会话最近一次后台摘要失败记录，没有失败或失败后已成功生成时返回 nil
+	LastFailure(sessionID string) *SummaryFailure
+
+	// Start 启动工作协程
+	Start()
+
+	// Stop 停止接收任务并等待执行中的任务完成
+	Stop()
+}
+
+// SummaryFailure 后台摘要失败记录
+type SummaryFailure struct {
+	// 错误信息
+	Error string `json:"error"`
+	// 失败时间
+	FailedAt time.Time `json:"failedAt"`
+}
+
+// summaryScheduler 后台摘要调度器实现
+// 使用固定数量的工作协程和有界队列，同一会话同时最多只有一个任务
+type summaryScheduler struct {
+	summaryService SummaryService
+	workers        int
+	queue          chan string
+	logger         logger.Logger
+
+	mu       sync.Mutex
+	pending  map[string]bool
+	failures map[string]*SummaryFailure
+	stopped  bool
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewSummaryScheduler 创建后台摘要调度器
+func NewSummaryScheduler(summaryService SummaryService, workers, queueSize int, log logger.Logger) SummaryScheduler {
+	return &summaryScheduler{
+		summaryService: summaryService,
+		workers:        workers,
+		queue:          make(chan string, queueSize),
+		logger:         log,
+		pending:        make(map[string]bool),
+		failures:       make(map[string]*SummaryFailure),
+		stopChan:       make(chan struct{}),
+	}
+}
+
+// Schedule 提交会话的摘要检查任务
+func (s *summaryScheduler) Schedule(sessionID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped || s.pending[sessionID] {
+		return false
+	}
+
+	select {
+	case s.queue <- sessionID:
+		s.pending[sessionID] = true
+		return true
+	default:
+		s.logWarn("摘要任务队列已满，跳过本次摘要检查", logger.Fields{
+			"sessionId": sessionID,
+		})
+		return false
+	}
+}
+
+// LastFailure 获取会话最近一次后台摘要失败记录
+func (s *summaryScheduler) LastFailure(sessionID string) *SummaryFailure {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.failures[sessionID]
+}
+
+// Start 启动工作协程
+func (s *summaryScheduler) Start() {
+	for i := 0; i < s.workers; i++ {
+		s.wg.Add(1)
+		go s.worker()
+	}
+}
+
+// Stop 停止接收任务并等待执行中的任务完成，队列中未开始的任务将被丢弃
+func (s *summaryScheduler) Stop() {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.stopped = true
+	s.mu.Unlock()
+
+	close(s.stopChan)
+	s.wg.Wait()
+}
+
+// worker 工作协程，依次处理队列中的会话
+func (s *summaryScheduler) worker() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case sessionID := <-s.queue:
+			s.process(sessionID)
+		}
+	}
+}
+
+// process 检查会话是否需要摘要，需要时生成摘要并记录结果
+func (s *summaryScheduler) process(sessionID string) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, sessionID)
+		s.mu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), summaryJobTimeout)
+	defer cancel()
+
+	shouldGenerate, err := s.summaryService.ShouldGenerateSummary(ctx, sessionID)
+	if err != nil {
+		s.recordFailure(sessionID, err)
+		return
+	}
+	if !shouldGenerate {
+		return
+	}
+
+	summary, err := s.summaryService.GenerateSummary(ctx, sessionID)
+	if err != nil {
+		s.recordFailure(sessionID, err)
+		return
+	}
+
+	s.mu.Lock()
+	delete(s.failures, sessionID)
+	s.mu.Unlock()
+
+	if summary != nil {
+		s.logInfo("后台摘要生成完成", logger.Fields{
+			"sessionId": sessionID,
+			"summaryId": summary.ID,
+		})
+	}
+}
+
+// recordFailure 记录后台摘要失败
+func (s *summaryScheduler) recordFailure(sessionID string, err error) {
+	s.mu.Lock()
+	s.failures[sessionID] = &SummaryFailure{
+		Error:    err.Error(),
+		FailedAt: time.Now(),
+	}
+	s.mu.Unlock()
+
+	s.logError("后台摘要生成失败", logger.Fields{
+		"sessionId": sessionID,
+		"error":     err.Error(),
+	})
+}
+
+// logInfo 安全地记录信息日志
+func (s *summaryScheduler) logInfo(msg string, fields logger.Fields) {
+	if s.logger != nil {
+		s.logger.Info(msg, fields)
+	}
+}
+
+// logWarn 安全地记录警告日志
+func (s *summaryScheduler) logWarn(msg string, fields logger.Fields) {
+	if s.logger != nil {
+		s.logger.Warn(msg, fields)
+	}
+}
+
+// logError 安全地记录错误日志
+func (s *summaryScheduler) logError(msg string, fields logger.Fields) {
+	if s.logger != nil {
+		s.logger.Error(msg, fields)
+	}
+}
@@ -0,0 +1,173 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"genkit-ai-service/internal/model"
+)
+
+// stubSummaryService 测试用摘要服务，GenerateSummary 阻塞直到 release 关闭
+type stubSummaryService struct {
+	mu          sync.Mutex
+	calls       map[string]int
+	generateErr error
+	release     chan struct{}
+	started     chan string
+}
+
+func newStubSummaryService() *stubSummaryService {
+	return &stubSummaryService{
+		calls:   make(map[string]int),
+		release: make(chan struct{}),
+		started: make(chan string, 10),
+	}
+}
+
+func (s *stubSummaryService) GenerateSummary(ctx context.Context, sessionID string) (*model.ChatSummary, error) {
+	s.started <- sessionID
+	<-s.release
+
+	s.mu.Lock()
+	s.calls[sessionID]++
+	err := s.generateErr
+	s.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	return &model.ChatSummary{ID: "summary-" + sessionID, SessionID: sessionID}, nil
+}
+
+func (s *stubSummaryService) GetSummary(ctx context.Context, sessionID string) (*model.ChatSummary, error) {
+	return nil, nil
+}
+
+func (s *stubSummaryService) ShouldGenerateSummary(ctx context.Context, sessionID string) (bool, error) {
+	return true, nil
+}
+
+func (s *stubSummaryService) ListSummaries(ctx context.Context, sessionID, userID string) ([]*model.ChatSummary, error) {
+	return nil, nil
+}
+
+func (s *stubSummaryService) TriggerSummary(ctx context.Context, sessionID, userID string) (*model.ChatSummary, error) {
+	return nil, nil
+}
+
+func (s *stubSummaryService) callCount(sessionID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[sessionID]
+}
+
+// waitStarted 等待指定会话的任务开始执行
+func waitStarted(t *testing.T, stub *stubSummaryService, sessionID string) {
+	t.Helper()
+	select {
+	case got := <-stub.started:
+		if got != sessionID {
+			t.Fatalf("期望会话 %s 的任务开始执行，实际 %s", sessionID, got)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("等待会话 %s 的任务超时", sessionID)
+	}
+}
+
+func TestSummaryScheduler_DeduplicatesPerSession(t *testing.T) {
+	stub := newStubSummaryService()
+	scheduler := NewSummaryScheduler(stub, 1, 10, nil)
+	scheduler.Start()
+
+	if !scheduler.Schedule("session-1") {
+		t.Fatal("第一次提交应成功")
+	}
+	waitStarted(t, stub, "session-1")
+
+	// 执行中的会话不会重复提交
+	if scheduler.Schedule("session-1") {
+		t.Error("同一会话的任务执行中时不应重复提交")
+	}
+	// 其他会话可以排队
+	if !scheduler.Schedule("session-2") {
+		t.Error("其他会话应能提交")
+	}
+
+	close(stub.release)
+	scheduler.Stop()
+
+	if got := stub.callCount("session-1"); got != 1 {
+		t.Errorf("session-1 期望生成 1 次，实际 %d", got)
+	}
+}
+
+func TestSummaryScheduler_QueueFull(t *testing.T) {
+	stub := newStubSummaryService()
+	scheduler := NewSummaryScheduler(stub, 1, 1, nil)
+	scheduler.Start()
+
+	scheduler.Schedule("session-1")
+	waitStarted(t, stub, "session-1")
+
+	if !scheduler.Schedule("session-2") {
+		t.Fatal("队列未满时应能提交")
+	}
+	if scheduler.Schedule("session-3") {
+		t.Error("队列已满时不应提交成功")
+	}
+
+	close(stub.release)
+	scheduler.Stop()
+
+	if scheduler.Schedule("session-4") {
+		t.Error("调度器停止后不应提交成功")
+	}
+}
+
+func TestSummaryScheduler_RecordsFailure(t *testing.T) {
+	stub := newStubSummaryService()
+	stub.generateErr = errors.New("AI 服务不可用")
+	close(stub.release)
+
+	scheduler := NewSummaryScheduler(stub, 1, 10, nil)
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	scheduler.Schedule("session-1")
+	waitStarted(t, stub, "session-1")
+
+	// 等待失败记录写入
+	deadline := time.Now().Add(time.Second)
+	for scheduler.LastFailure("session-1") == nil && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	failure := scheduler.LastFailure("session-1")
+	if failure == nil {
+		t.Fatal("期望记录失败信息")
+	}
+	if failure.Error != "AI 服务不可用" {
+		t.Errorf("失败信息不正确: %s", failure.Error)
+	}
+
+	// 失败后再次成功时清除失败记录
+	stub.mu.Lock()
+	stub.generateErr = nil
+	stub.mu.Unlock()
+
+	deadline = time.Now().Add(time.Second)
+	for !scheduler.Schedule("session-1") && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	waitStarted(t, stub, "session-1")
+
+	for scheduler.LastFailure("session-1") != nil && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if scheduler.LastFailure("session-1") != nil {
+		t.Error("成功生成摘要后应清除失败记录")
+	}
+}
@@ -2,14 +2,19 @@ package session
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"strings"
+	"sync"
 
 	"genkit-ai-service/internal/config"
 	"genkit-ai-service/internal/logger"
 	"genkit-ai-service/internal/model"
 	"genkit-ai-service/internal/repository"
 	"genkit-ai-service/internal/service/ai"
+	"genkit-ai-service/pkg/errors"
+
+	"gorm.io/gorm"
 )
 
 // SummaryService 摘要业务逻辑接口
@@ -22,6 +27,20 @@ type SummaryService interface {
 
 	// ShouldGenerateSummary 判断是否需要生成摘要
 	ShouldGenerateSummary(ctx context.Context, sessionID string) (bool, error)
+
+	// ListSummaries 获取用户会话的所有摘要
+	ListSummaries(ctx context.Context, sessionID, userID string) ([]*model.ChatSummary, error)
+
+	// TriggerSummary 立即为用户会话生成摘要，不检查消息数量阈值
+	TriggerSummary(ctx context.Context, sessionID, userID string) (*model.ChatSummary, error)
+}
+
+// SummaryListResponse 会话摘要列表响应
+type SummaryListResponse struct {
+	// 摘要列表，最新的摘要在前
+	Summaries []*model.ChatSummary `json:"summaries"`
+	// 最近一次后台摘要失败记录
+	LastFailure *SummaryFailure `json:"lastFailure,omitempty"`
 }
 
 // summaryService 摘要业务逻辑实现
@@ -32,6 +51,10 @@ type summaryService struct {
 	aiService    ai.AIService
 	config       *config.Config
 	logger       logger.Logger
+
+	// 会话摘要锁，key: 会话ID，后台任务和手动触发共用，同一会话同时只生成一个摘要
+	locksMu sync.Mutex
+	locks   map[string]chan struct{}
 }
 
 // NewSummaryService 创建摘要服务实例
@@ -50,11 +73,19 @@ func NewSummaryService(
 		aiService:   aiService,
 		config:      cfg,
 		logger:      log,
+		locks:       make(map[string]chan struct{}),
 	}
 }
 
 // GenerateSummary 生成会话摘要
+// 同一会话同时只生成一个摘要，已有生成在进行时等待其完成后再基于最新摘要生成
 func (s *summaryService) GenerateSummary(ctx context.Context, sessionID string) (*model.ChatSummary, error) {
+	unlock, err := s.lockSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	s.logger.Info("开始生成会话摘要", map[string]interface{}{
 		"sessionId": sessionID,
 	})
@@ -75,42 +106,13 @@ func (s *summaryService) GenerateSummary(ctx context.Context, sessionID string)
 		return nil, fmt.Errorf("会话不存在")
 	}
 
-	// 2. 获取最新的摘要（如果存在）
-	latestSummary, err := s.summaryRepo.GetLatestBySessionID(ctx, sessionID)
+	// 2. 获取当前分支上最新的摘要和摘要之后的消息
+	messages, latestSummary, err := s.loadBranch(ctx, session)
 	if err != nil {
-		s.logger.Error("查询最新摘要失败", map[string]interface{}{
-			"sessionId": sessionID,
-			"error":     err.Error(),
-		})
-		return nil, fmt.Errorf("查询最新摘要失败: %w", err)
-	}
-
-	// 3. 获取需要摘要的消息列表
-	var messages []*model.ChatMessage
-	if latestSummary != nil {
-		// 如果已有摘要，只获取摘要之后的消息
-		messages, err = s.messageRepo.GetMessagesAfter(ctx, sessionID, latestSummary.LastMessageID)
-		if err != nil {
-			s.logger.Error("获取摘要后的消息失败", map[string]interface{}{
-				"sessionId":     sessionID,
-				"lastMessageId": latestSummary.LastMessageID,
-				"error":         err.Error(),
-			})
-			return nil, fmt.Errorf("获取消息失败: %w", err)
-		}
-	} else {
-		// 如果没有摘要，获取所有消息
-		messages, _, err = s.messageRepo.GetBySessionID(ctx, sessionID, 1, 10000)
-		if err != nil {
-			s.logger.Error("获取会话消息失败", map[string]interface{}{
-				"sessionId": sessionID,
-				"error":     err.Error(),
-			})
-			return nil, fmt.Errorf("获取消息失败: %w", err)
-		}
+		return nil, err
 	}
 
-	// 4. 检查是否有足够的消息生成摘要
+	// 3. 检查是否有足够的消息生成摘要
 	if len(messages) == 0 {
 		s.logger.Warn("没有新消息需要生成摘要", map[string]interface{}{
 			"sessionId": sessionID,
@@ -118,14 +120,15 @@ func (s *summaryService) GenerateSummary(ctx context.Context, sessionID string)
 		return latestSummary, nil
 	}
 
-	// 5. 构建摘要提示词
+	// 4. 构建摘要提示词
 	summaryPrompt := s.buildSummaryPrompt(messages, latestSummary)
 
-	// 6. 调用AI服务生成摘要
+	// 5. 调用AI服务生成摘要
 	temperature := 0.3 // 使用较低的温度以获得更稳定的摘要
 	maxTokens := 1000
 	chatReq := &model.ChatRequest{
 		Message: summaryPrompt,
+		Model:   session.ModelName,
 		Options: &model.ChatOptions{
 			Temperature: &temperature,
 			MaxTokens:   &maxTokens,
@@ -141,13 +144,15 @@ func (s *summaryService) GenerateSummary(ctx context.Context, sessionID string)
 		return nil, fmt.Errorf("AI生成摘要失败: %w", err)
 	}
 
-	// 7. 创建摘要记录
+	// 6. 创建摘要记录
 	lastMessageID := messages[len(messages)-1].ID
 	summary := &model.ChatSummary{
 		SessionID:     sessionID,
 		Summary:       chatResp.Message,
 		LastMessageID: lastMessageID,
-		TokenCount:    chatResp.Usage.TotalTokens,
+	}
+	if chatResp.Usage != nil {
+		summary.TokenCount = chatResp.Usage.TotalTokens
 	}
 
 	if err := s.summaryRepo.Create(ctx, summary); err != nil {
@@ -192,69 +197,177 @@ func (s *summaryService) ShouldGenerateSummary(ctx context.Context, sessionID st
 		"sessionId": sessionID,
 	})
 
-	// 1. 获取会话的消息总数
-	messageCount, err := s.messageRepo.CountBySessionID(ctx, sessionID)
+	// 1. 获取当前分支上最新的摘要之后的消息
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
 	if err != nil {
-		s.logger.Error("统计消息数量失败", map[string]interface{}{
+		s.logger.Error("查询会话失败", map[string]interface{}{
 			"sessionId": sessionID,
 			"error":     err.Error(),
 		})
-		return false, fmt.Errorf("统计消息数量失败: %w", err)
+		return false, fmt.Errorf("查询会话失败: %w", err)
+	}
+	if session == nil {
+		return false, fmt.Errorf("会话不存在")
+	}
+	messagesAfterSummary, latestSummary, err := s.loadBranch(ctx, session)
+	if err != nil {
+		return false, err
 	}
 
-	// 2. 检查消息数量是否达到阈值
+	// 2. 检查摘要之后的消息数量是否达到阈值
 	threshold := s.config.Session.SummaryThreshold
-	if messageCount < threshold {
-		s.logger.Debug("消息数量未达到摘要阈值", map[string]interface{}{
-			"sessionId":    sessionID,
-			"messageCount": messageCount,
-			"threshold":    threshold,
+	newMessageCount := len(messagesAfterSummary)
+	shouldGenerate := newMessageCount >= threshold
+
+	s.logger.Debug("检查摘要生成条件", map[string]interface{}{
+		"sessionId":       sessionID,
+		"hasSummary":      latestSummary != nil,
+		"newMessageCount": newMessageCount,
+		"threshold":       threshold,
+		"shouldGenerate":  shouldGenerate,
+	})
+
+	return shouldGenerate, nil
+}
+
+// ListSummaries 获取用户会话的所有摘要
+func (s *summaryService) ListSummaries(ctx context.Context, sessionID, userID string) ([]*model.ChatSummary, error) {
+	if err := s.checkOwnership(ctx, sessionID, userID); err != nil {
+		return nil, err
+	}
+
+	summaries, err := s.summaryRepo.GetBySessionID(ctx, sessionID)
+	if err != nil {
+		s.logger.Error("查询会话摘要列表失败", map[string]interface{}{
+			"sessionId": sessionID,
+			"error":     err.Error(),
 		})
-		return false, nil
+		return nil, errors.NewInternalError(err)
+	}
+
+	if summaries == nil {
+		summaries = []*model.ChatSummary{}
+	}
+
+	return summaries, nil
+}
+
+// TriggerSummary 立即为用户会话生成摘要
+// 与后台摘要任务共用会话摘要锁，后台任务执行中时等待其完成；
+// 摘要之后没有新消息时返回最新的已有摘要，会话没有任何消息时返回 nil
+func (s *summaryService) TriggerSummary(ctx context.Context, sessionID, userID string) (*model.ChatSummary, error) {
+	if err := s.checkOwnership(ctx, sessionID, userID); err != nil {
+		return nil, err
 	}
 
-	// 3. 获取最新的摘要
-	latestSummary, err := s.summaryRepo.GetLatestBySessionID(ctx, sessionID)
+	summary, err := s.GenerateSummary(ctx, sessionID)
+	if err != nil {
+		return nil, errors.NewSummaryGenerationFailedError(err)
+	}
+
+	return summary, nil
+}
+
+// checkOwnership 验证会话存在且属于指定用户
+func (s *summaryService) checkOwnership(ctx context.Context, sessionID, userID string) error {
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
 	if err != nil {
-		s.logger.Error("查询最新摘要失败", map[string]interface{}{
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.NewSessionNotFoundError(sessionID)
+		}
+		s.logger.Error("查询会话失败", map[string]interface{}{
 			"sessionId": sessionID,
 			"error":     err.Error(),
 		})
-		return false, fmt.Errorf("查询最新摘要失败: %w", err)
+		return errors.NewInternalError(err)
 	}
 
-	// 4. 如果没有摘要，需要生成
-	if latestSummary == nil {
-		s.logger.Info("会话无摘要且消息数达到阈值，需要生成摘要", map[string]interface{}{
+	if session.UserID != userID {
+		s.logger.Warn("用户尝试访问其他用户的会话摘要", map[string]interface{}{
 			"sessionId":    sessionID,
-			"messageCount": messageCount,
-			"threshold":    threshold,
+			"userId":       userID,
+			"sessionOwner": session.UserID,
 		})
-		return true, nil
+		return errors.NewSessionAccessDeniedError()
+	}
+
+	return nil
+}
+
+// lockSession 获取会话摘要锁，返回释放函数；等待期间上下文结束时返回错误
+func (s *summaryService) lockSession(ctx context.Context, sessionID string) (func(), error) {
+	for {
+		s.locksMu.Lock()
+		lock, held := s.locks[sessionID]
+		if !held {
+			lock = make(chan struct{})
+			s.locks[sessionID] = lock
+			s.locksMu.Unlock()
+
+			return func() {
+				s.locksMu.Lock()
+				delete(s.locks, sessionID)
+				s.locksMu.Unlock()
+				close(lock)
+			}, nil
+		}
+		s.locksMu.Unlock()
+
+		select {
+		case <-lock:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("等待会话摘要生成完成失败: %w", ctx.Err())
+		}
+	}
+}
+
+// loadBranch 获取会话当前分支上最新的摘要和摘要之后的消息
+// 只使用当前分支（以会话最后一条消息为末端）上的消息，重新生成、编辑或切换分支后被放弃的分支不计入摘要；
+// 没有摘要时返回整个分支，会话没有消息时返回空列表
+func (s *summaryService) loadBranch(ctx context.Context, session *model.ChatSession) ([]*model.ChatMessage, *model.ChatSummary, error) {
+	if session.LastMessageID == nil {
+		return nil, nil, nil
 	}
 
-	// 5. 计算摘要后的新消息数量
-	messagesAfterSummary, err := s.messageRepo.GetMessagesAfter(ctx, sessionID, latestSummary.LastMessageID)
+	branch, err := s.messageRepo.GetBranch(ctx, session.ID, *session.LastMessageID)
 	if err != nil {
-		s.logger.Error("获取摘要后的消息失败", map[string]interface{}{
-			"sessionId":     sessionID,
-			"lastMessageId": latestSummary.LastMessageID,
+		s.logger.Error("获取会话分支失败", map[string]interface{}{
+			"sessionId":     session.ID,
+			"lastMessageId": *session.LastMessageID,
 			"error":         err.Error(),
 		})
-		return false, fmt.Errorf("获取摘要后的消息失败: %w", err)
+		return nil, nil, fmt.Errorf("获取消息失败: %w", err)
 	}
 
-	newMessageCount := len(messagesAfterSummary)
-	shouldGenerate := newMessageCount >= threshold
+	summaries, err := s.summaryRepo.GetBySessionID(ctx, session.ID)
+	if err != nil {
+		s.logger.Error("查询会话摘要失败", map[string]interface{}{
+			"sessionId": session.ID,
+			"error":     err.Error(),
+		})
+		return nil, nil, fmt.Errorf("查询最新摘要失败: %w", err)
+	}
 
-	s.logger.Debug("检查摘要生成条件", map[string]interface{}{
-		"sessionId":       sessionID,
-		"newMessageCount": newMessageCount,
-		"threshold":       threshold,
-		"shouldGenerate":  shouldGenerate,
-	})
+	summary, position := branchSummary(summaries, branch)
+	return branch[position+1:], summary, nil
+}
 
-	return shouldGenerate, nil
+// branchSummary 返回分支上覆盖消息最多的摘要及其最后一条消息在分支中的位置
+// 最后一条消息不在分支上的摘要是基于其他分支生成的，不使用；没有可用的摘要时返回 nil 和 -1
+func branchSummary(summaries []*model.ChatSummary, branch []*model.ChatMessage) (*model.ChatSummary, int) {
+	positions := make(map[string]int, len(branch))
+	for i, msg := range branch {
+		positions[msg.ID] = i
+	}
+
+	var latest *model.ChatSummary
+	latestPosition := -1
+	for _, summary := range summaries {
+		if position, ok := positions[summary.LastMessageID]; ok && position > latestPosition {
+			latest, latestPosition = summary, position
+		}
+	}
+	return latest, latestPosition
 }
 
 // buildSummaryPrompt 构建摘要提示词
@@ -275,6 +388,10 @@ func (s *summaryService) buildSummaryPrompt(messages []*model.ChatMessage, previ
 
 	// 添加消息内容
 	for _, msg := range messages {
+		// 跳过生成失败和尚未生成完成的回复
+		if msg.Content == "" || msg.Status == model.MessageStatusFailed {
+			continue
+		}
 		builder.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
 	}
 
@@ -3,12 +3,16 @@ package session
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"genkit-ai-service/internal/config"
 	"genkit-ai-service/internal/logger"
 	"genkit-ai-service/internal/model"
+	apperrors "genkit-ai-service/pkg/errors"
 )
 
 // mockSummaryRepository 模拟摘要仓库
@@ -58,7 +62,7 @@ func (m *mockSummaryRepository) GetBySessionID(ctx context.Context, sessionID st
 type mockAIService struct {
 	chatFunc       func(ctx context.Context, req *model.ChatRequest) (*model.ChatResponse, error)
 	chatStreamFunc func(ctx context.Context, req *model.ChatRequest) (<-chan model.StreamChunk, error)
-	abortChatFunc  func(ctx context.Context, sessionID string) error
+	abortChatFunc  func(ctx context.Context, sessionID string) (bool, error)
 }
 
 func (m *mockAIService) Chat(ctx context.Context, req *model.ChatRequest) (*model.ChatResponse, error) {
@@ -80,11 +84,11 @@ func (m *mockAIService) ChatStream(ctx context.Context, req *model.ChatRequest)
 	return nil, nil
 }
 
-func (m *mockAIService) AbortChat(ctx context.Context, sessionID string) error {
+func (m *mockAIService) AbortChat(ctx context.Context, sessionID string) (bool, error) {
 	if m.abortChatFunc != nil {
 		return m.abortChatFunc(ctx, sessionID)
 	}
-	return nil
+	return false, nil
 }
 
 // setupSummaryServiceTest 设置测试环境
@@ -113,11 +117,30 @@ func setupSummaryServiceTest() (*summaryService, *mockSummaryRepository, *mockMe
 		aiService:   aiService,
 		config:      cfg,
 		logger:      log,
+		locks:       make(map[string]chan struct{}),
 	}
 
 	return service, summaryRepo, messageRepo, sessionRepo, aiService
 }
 
+// newBranch 创建 count 条消息组成的分支（msg-1 到 msg-count），并设置为会话的当前分支
+func newBranch(session *model.ChatSession, count int) []*model.ChatMessage {
+	messages := make([]*model.ChatMessage, count)
+	for i := range messages {
+		messages[i] = &model.ChatMessage{
+			ID:        fmt.Sprintf("msg-%d", i+1),
+			SessionID: session.ID,
+			Role:      "user",
+			Content:   "测试消息",
+			Sequence:  i + 1,
+		}
+	}
+	if count > 0 {
+		session.LastMessageID = &messages[count-1].ID
+	}
+	return messages
+}
+
 // TestGenerateSummary_Success 测试成功生成摘要
 func TestGenerateSummary_Success(t *testing.T) {
 	service, summaryRepo, messageRepo, sessionRepo, aiService := setupSummaryServiceTest()
@@ -152,9 +175,10 @@ func TestGenerateSummary_Success(t *testing.T) {
 			Sequence:  2,
 		},
 	}
-	
-	messageRepo.getBySessionIDFunc = func(ctx context.Context, sid string, page, pageSize int) ([]*model.ChatMessage, int, error) {
-		return messages, len(messages), nil
+	session.LastMessageID = &messages[1].ID
+
+	messageRepo.getBranchFunc = func(ctx context.Context, sid, leafID string) ([]*model.ChatMessage, error) {
+		return messages, nil
 	}
 
 	// 模拟AI生成摘要
@@ -241,9 +265,10 @@ func TestGenerateSummary_NoMessages(t *testing.T) {
 	}
 	sessionRepo.sessions[sessionID] = session
 
-	// 模拟没有消息
-	messageRepo.getBySessionIDFunc = func(ctx context.Context, sid string, page, pageSize int) ([]*model.ChatMessage, int, error) {
-		return []*model.ChatMessage{}, 0, nil
+	// 模拟没有消息（会话没有最后一条消息）
+	messageRepo.getBranchFunc = func(ctx context.Context, sid, leafID string) ([]*model.ChatMessage, error) {
+		t.Error("会话没有消息时不应查询分支")
+		return nil, nil
 	}
 
 	// 执行测试
@@ -303,14 +328,17 @@ func TestGetSummary_Success(t *testing.T) {
 
 // TestShouldGenerateSummary_BelowThreshold 测试消息数量未达到阈值
 func TestShouldGenerateSummary_BelowThreshold(t *testing.T) {
-	service, _, messageRepo, _, _ := setupSummaryServiceTest()
+	service, _, messageRepo, sessionRepo, _ := setupSummaryServiceTest()
 	ctx := context.Background()
 
 	sessionID := "test-session-id"
+	session := &model.ChatSession{ID: sessionID}
+	sessionRepo.sessions[sessionID] = session
 
-	// 模拟消息数量为30（低于阈值50）
-	messageRepo.countBySessionIDFunc = func(ctx context.Context, sid string) (int, error) {
-		return 30, nil
+	// 模拟当前分支的消息数量为30（低于阈值50）
+	branch := newBranch(session, 30)
+	messageRepo.getBranchFunc = func(ctx context.Context, sid, leafID string) ([]*model.ChatMessage, error) {
+		return branch, nil
 	}
 
 	// 执行测试
@@ -328,18 +356,21 @@ func TestShouldGenerateSummary_BelowThreshold(t *testing.T) {
 
 // TestShouldGenerateSummary_AboveThreshold_NoSummary 测试消息数量达到阈值且无摘要
 func TestShouldGenerateSummary_AboveThreshold_NoSummary(t *testing.T) {
-	service, summaryRepo, messageRepo, _, _ := setupSummaryServiceTest()
+	service, summaryRepo, messageRepo, sessionRepo, _ := setupSummaryServiceTest()
 	ctx := context.Background()
 
 	sessionID := "test-session-id"
+	session := &model.ChatSession{ID: sessionID}
+	sessionRepo.sessions[sessionID] = session
 
-	// 模拟消息数量为60（高于阈值50）
-	messageRepo.countBySessionIDFunc = func(ctx context.Context, sid string) (int, error) {
-		return 60, nil
+	// 模拟当前分支的消息数量为60（高于阈值50）
+	branch := newBranch(session, 60)
+	messageRepo.getBranchFunc = func(ctx context.Context, sid, leafID string) ([]*model.ChatMessage, error) {
+		return branch, nil
 	}
 
 	// 模拟没有摘要
-	summaryRepo.getLatestBySessionIDFunc = func(ctx context.Context, sid string) (*model.ChatSummary, error) {
+	summaryRepo.getBySessionIDFunc = func(ctx context.Context, sid string) ([]*model.ChatSummary, error) {
 		return nil, nil
 	}
 
@@ -358,38 +389,25 @@ func TestShouldGenerateSummary_AboveThreshold_NoSummary(t *testing.T) {
 
 // TestShouldGenerateSummary_WithExistingSummary 测试已有摘要的情况
 func TestShouldGenerateSummary_WithExistingSummary(t *testing.T) {
-	service, summaryRepo, messageRepo, _, _ := setupSummaryServiceTest()
+	service, summaryRepo, messageRepo, sessionRepo, _ := setupSummaryServiceTest()
 	ctx := context.Background()
 
 	sessionID := "test-session-id"
+	session := &model.ChatSession{ID: sessionID}
+	sessionRepo.sessions[sessionID] = session
 
-	// 模拟消息数量为100
-	messageRepo.countBySessionIDFunc = func(ctx context.Context, sid string) (int, error) {
-		return 100, nil
-	}
-
-	// 模拟已有摘要
-	summaryRepo.getLatestBySessionIDFunc = func(ctx context.Context, sid string) (*model.ChatSummary, error) {
-		return &model.ChatSummary{
-			ID:            "summary-1",
-			SessionID:     sessionID,
-			LastMessageID: "msg-50",
-		}, nil
+	// 模拟当前分支有110条消息
+	branch := newBranch(session, 110)
+	messageRepo.getBranchFunc = func(ctx context.Context, sid, leafID string) ([]*model.ChatMessage, error) {
+		return branch, nil
 	}
 
-	// 模拟摘要后有60条新消息（超过阈值50）
-	messageRepo.getMessagesAfterFunc = func(ctx context.Context, sid, afterMsgID string) ([]*model.ChatMessage, error) {
-		messages := make([]*model.ChatMessage, 60)
-		for i := 0; i < 60; i++ {
-			messages[i] = &model.ChatMessage{
-				ID:        "msg-" + string(rune(51+i)),
-				SessionID: sessionID,
-				Role:      "user",
-				Content:   "测试消息",
-			}
-		}
-		return messages, nil
-	}
+	// 模拟已有摘要，摘要后有60条新消息（超过阈值50）
+	summaryRepo.summaries[sessionID] = []*model.ChatSummary{{
+		ID:            "summary-1",
+		SessionID:     sessionID,
+		LastMessageID: "msg-50",
+	}}
 
 	// 执行测试
 	shouldGenerate, err := service.ShouldGenerateSummary(ctx, sessionID)
@@ -404,16 +422,19 @@ func TestShouldGenerateSummary_WithExistingSummary(t *testing.T) {
 	}
 }
 
-// TestShouldGenerateSummary_CountError 测试统计消息数量失败
-func TestShouldGenerateSummary_CountError(t *testing.T) {
-	service, _, messageRepo, _, _ := setupSummaryServiceTest()
+// TestShouldGenerateSummary_BranchError 测试获取当前分支失败
+func TestShouldGenerateSummary_BranchError(t *testing.T) {
+	service, _, messageRepo, sessionRepo, _ := setupSummaryServiceTest()
 	ctx := context.Background()
 
 	sessionID := "test-session-id"
+	session := &model.ChatSession{ID: sessionID}
+	sessionRepo.sessions[sessionID] = session
+	newBranch(session, 1)
 
-	// 模拟统计失败
-	messageRepo.countBySessionIDFunc = func(ctx context.Context, sid string) (int, error) {
-		return 0, errors.New("数据库错误")
+	// 模拟获取分支失败
+	messageRepo.getBranchFunc = func(ctx context.Context, sid, leafID string) ([]*model.ChatMessage, error) {
+		return nil, errors.New("数据库错误")
 	}
 
 	// 执行测试
@@ -424,3 +445,230 @@ func TestShouldGenerateSummary_CountError(t *testing.T) {
 		t.Error("ShouldGenerateSummary() 应该返回错误")
 	}
 }
+
+// TestGenerateSummary_InactiveBranch 测试只基于当前分支生成摘要
+func TestGenerateSummary_InactiveBranch(t *testing.T) {
+	service, summaryRepo, messageRepo, sessionRepo, aiService := setupSummaryServiceTest()
+	ctx := context.Background()
+
+	sessionID := "test-session-id"
+	session := &model.ChatSession{ID: sessionID, ModelName: "qwen-plus"}
+	sessionRepo.sessions[sessionID] = session
+	branch := newBranch(session, 4)
+	branch[3].Content = "当前分支的回答"
+
+	var requestedLeaf string
+	messageRepo.getBranchFunc = func(ctx context.Context, sid, leafID string) ([]*model.ChatMessage, error) {
+		requestedLeaf = leafID
+		return branch, nil
+	}
+
+	// 较新的摘要基于重新生成前被放弃的分支，较早的摘要位于当前分支上
+	summaryRepo.summaries[sessionID] = []*model.ChatSummary{
+		{ID: "summary-1", SessionID: sessionID, Summary: "当前分支的摘要", LastMessageID: "msg-2"},
+		{ID: "summary-2", SessionID: sessionID, Summary: "旧分支的摘要", LastMessageID: "abandoned-msg"},
+	}
+
+	var prompt string
+	aiService.chatFunc = func(ctx context.Context, req *model.ChatRequest) (*model.ChatResponse, error) {
+		prompt = req.Message
+		return &model.ChatResponse{Message: "新摘要"}, nil
+	}
+
+	summary, err := service.GenerateSummary(ctx, sessionID)
+	if err != nil {
+		t.Fatalf("GenerateSummary() 返回错误: %v", err)
+	}
+
+	if requestedLeaf != "msg-4" {
+		t.Errorf("期望查询以 msg-4 为末端的分支, 实际 %s", requestedLeaf)
+	}
+	if summary.LastMessageID != "msg-4" {
+		t.Errorf("最后消息ID不正确，期望 msg-4，实际 %s", summary.LastMessageID)
+	}
+	if !strings.Contains(prompt, "当前分支的摘要") || strings.Contains(prompt, "旧分支的摘要") {
+		t.Errorf("摘要提示词应基于当前分支的摘要: %s", prompt)
+	}
+	if strings.Count(prompt, "测试消息") != 1 || !strings.Contains(prompt, "当前分支的回答") {
+		t.Errorf("摘要提示词应只包含当前分支摘要之后的消息: %s", prompt)
+	}
+}
+
+// TestGenerateSummary_NilUsage 测试 AI 响应不包含 token 使用情况
+func TestGenerateSummary_NilUsage(t *testing.T) {
+	service, summaryRepo, messageRepo, sessionRepo, aiService := setupSummaryServiceTest()
+	ctx := context.Background()
+
+	sessionID := "test-session-id"
+	session := &model.ChatSession{ID: sessionID, UserID: "test-user-id", ModelName: "qwen-plus"}
+	sessionRepo.sessions[sessionID] = session
+	branch := newBranch(session, 1)
+	messageRepo.getBranchFunc = func(ctx context.Context, sid, leafID string) ([]*model.ChatMessage, error) {
+		return branch, nil
+	}
+
+	var requestedModel string
+	aiService.chatFunc = func(ctx context.Context, req *model.ChatRequest) (*model.ChatResponse, error) {
+		requestedModel = req.Model
+		return &model.ChatResponse{Message: "摘要"}, nil
+	}
+
+	summary, err := service.GenerateSummary(ctx, sessionID)
+	if err != nil {
+		t.Fatalf("GenerateSummary() 返回错误: %v", err)
+	}
+	if summary.TokenCount != 0 {
+		t.Errorf("Token数量应为 0，实际 %d", summary.TokenCount)
+	}
+	if requestedModel != "qwen-plus" {
+		t.Errorf("摘要应使用会话模型 qwen-plus，实际 %s", requestedModel)
+	}
+	if len(summaryRepo.summaries[sessionID]) != 1 {
+		t.Errorf("期望保存 1 条摘要，实际 %d", len(summaryRepo.summaries[sessionID]))
+	}
+}
+
+// TestListSummaries 测试获取会话摘要列表
+func TestListSummaries(t *testing.T) {
+	service, summaryRepo, _, sessionRepo, _ := setupSummaryServiceTest()
+	ctx := context.Background()
+
+	sessionID := "test-session-id"
+	sessionRepo.sessions[sessionID] = &model.ChatSession{ID: sessionID, UserID: "test-user-id"}
+	summaryRepo.summaries[sessionID] = []*model.ChatSummary{
+		{ID: "summary-1", SessionID: sessionID, Summary: "摘要1"},
+		{ID: "summary-2", SessionID: sessionID, Summary: "摘要2"},
+	}
+
+	t.Run("成功获取", func(t *testing.T) {
+		summaries, err := service.ListSummaries(ctx, sessionID, "test-user-id")
+		if err != nil {
+			t.Fatalf("ListSummaries() 返回错误: %v", err)
+		}
+		if len(summaries) != 2 {
+			t.Errorf("期望 2 条摘要，实际 %d", len(summaries))
+		}
+	})
+
+	t.Run("无权访问", func(t *testing.T) {
+		_, err := service.ListSummaries(ctx, sessionID, "other-user")
+		appErr, ok := err.(*apperrors.AppError)
+		if !ok || appErr.Code != apperrors.CodeSessionAccessDenied {
+			t.Errorf("期望无权访问错误，实际 %v", err)
+		}
+	})
+
+	t.Run("会话不存在", func(t *testing.T) {
+		_, err := service.ListSummaries(ctx, "missing", "test-user-id")
+		appErr, ok := err.(*apperrors.AppError)
+		if !ok || appErr.Code != apperrors.CodeSessionNotFound {
+			t.Errorf("期望会话不存在错误，实际 %v", err)
+		}
+	})
+}
+
+// TestTriggerSummary_Failure 测试立即生成摘要失败
+func TestTriggerSummary_Failure(t *testing.T) {
+	service, _, messageRepo, sessionRepo, aiService := setupSummaryServiceTest()
+	ctx := context.Background()
+
+	sessionID := "test-session-id"
+	session := &model.ChatSession{ID: sessionID, UserID: "test-user-id"}
+	sessionRepo.sessions[sessionID] = session
+	branch := newBranch(session, 1)
+	messageRepo.getBranchFunc = func(ctx context.Context, sid, leafID string) ([]*model.ChatMessage, error) {
+		return branch, nil
+	}
+	aiService.chatFunc = func(ctx context.Context, req *model.ChatRequest) (*model.ChatResponse, error) {
+		return nil, errors.New("AI 服务不可用")
+	}
+
+	_, err := service.TriggerSummary(ctx, sessionID, "test-user-id")
+	appErr, ok := err.(*apperrors.AppError)
+	if !ok || appErr.Code != apperrors.CodeSummaryGenerationFailed {
+		t.Errorf("期望摘要生成失败错误，实际 %v", err)
+	}
+}
+
+// failingSessionRepository 查询会话返回指定错误的会话仓库
+type failingSessionRepository struct {
+	*mockSessionRepository
+	err error
+}
+
+func (r *failingSessionRepository) GetByID(ctx context.Context, sessionID string) (*model.ChatSession, error) {
+	return nil, r.err
+}
+
+// TestTriggerSummary_SessionQueryError 测试查询会话失败时返回内部错误而不是会话不存在
+func TestTriggerSummary_SessionQueryError(t *testing.T) {
+	service, _, _, sessionRepo, _ := setupSummaryServiceTest()
+	service.sessionRepo = &failingSessionRepository{mockSessionRepository: sessionRepo, err: errors.New("数据库连接失败")}
+
+	_, err := service.TriggerSummary(context.Background(), "test-session-id", "test-user-id")
+	appErr, ok := err.(*apperrors.AppError)
+	if !ok || appErr.Code != apperrors.CodeInternalError {
+		t.Errorf("期望内部错误，实际 %v", err)
+	}
+}
+
+// TestTriggerSummary_WaitsForRunningSummary 测试同一会话的摘要正在生成时，立即生成等待其完成且不重复生成
+func TestTriggerSummary_WaitsForRunningSummary(t *testing.T) {
+	service, _, messageRepo, sessionRepo, aiService := setupSummaryServiceTest()
+	ctx := context.Background()
+
+	sessionID := "test-session-id"
+	session := &model.ChatSession{ID: sessionID, UserID: "test-user-id"}
+	sessionRepo.sessions[sessionID] = session
+	branch := newBranch(session, 4)
+	messageRepo.getBranchFunc = func(ctx context.Context, sid, leafID string) ([]*model.ChatMessage, error) {
+		return branch, nil
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var calls int32
+	aiService.chatFunc = func(ctx context.Context, req *model.ChatRequest) (*model.ChatResponse, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			<-release
+		}
+		return &model.ChatResponse{Message: "测试摘要"}, nil
+	}
+
+	// 模拟后台任务正在生成摘要
+	done := make(chan error, 1)
+	go func() {
+		_, err := service.GenerateSummary(ctx, sessionID)
+		done <- err
+	}()
+	<-started
+
+	triggered := make(chan *model.ChatSummary, 1)
+	go func() {
+		summary, err := service.TriggerSummary(ctx, sessionID, "test-user-id")
+		if err != nil {
+			t.Errorf("立即生成摘要失败: %v", err)
+		}
+		triggered <- summary
+	}()
+
+	select {
+	case <-triggered:
+		t.Fatal("摘要生成中时立即生成不应提前返回")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("后台生成摘要失败: %v", err)
+	}
+
+	summary := <-triggered
+	if summary == nil || summary.LastMessageID != "msg-4" {
+		t.Errorf("期望返回后台任务生成的摘要，实际 %+v", summary)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("期望只生成一次摘要，实际调用 AI 服务 %d 次", n)
+	}
+}
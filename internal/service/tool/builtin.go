@@ -0,0 +1,62 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CurrentTimeToolName 获取当前时间工具的名称
+const CurrentTimeToolName = "get_current_time"
+
+// NewCurrentTimeTool 创建获取当前时间的内置工具
+// 模型本身不知道当前时间，回答与日期、时间相关的问题时可调用该工具
+func NewCurrentTimeTool() Tool {
+	return NewFunc(
+		CurrentTimeToolName,
+		"获取当前的日期和时间。回答与今天日期、当前时间或星期几相关的问题时使用",
+		map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"timezone": map[string]interface{}{
+					"type":        "string",
+					"description": "IANA 时区名称，例如 Asia/Shanghai，默认为 UTC",
+				},
+			},
+		},
+		currentTime,
+	)
+}
+
+// currentTime 返回指定时区的当前时间
+func currentTime(ctx context.Context, arguments string) (string, error) {
+	var args struct {
+		Timezone string `json:"timezone"`
+	}
+	if arguments != "" {
+		if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+			return "", fmt.Errorf("解析参数失败: %w", err)
+		}
+	}
+	if args.Timezone == "" {
+		args.Timezone = "UTC"
+	}
+
+	location, err := time.LoadLocation(args.Timezone)
+	if err != nil {
+		return "", fmt.Errorf("无效的时区 '%s'", args.Timezone)
+	}
+
+	now := time.Now().In(location)
+	result, err := json.Marshal(map[string]string{
+		"time":     now.Format(time.RFC3339),
+		"weekday":  now.Weekday().String(),
+		"timezone": args.Timezone,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(result), nil
+}
@@ -0,0 +1,130 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"genkit-ai-service/internal/model"
+)
+
+// namePattern 工具名称格式，与主流模型接口对函数名称的要求一致
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// Tool 可供模型调用的工具
+type Tool interface {
+	// Definition 返回提供给模型的工具定义（名称、描述和参数 JSON Schema）
+	Definition() model.ToolDefinition
+
+	// Call 执行工具调用
+	// arguments 为模型生成的 JSON 格式参数，返回的内容作为工具结果回传给模型
+	Call(ctx context.Context, arguments string) (string, error)
+}
+
+// Registry 工具注册表
+type Registry interface {
+	// Register 注册工具，工具名称不能重复
+	Register(tool Tool) error
+
+	// Get 根据名称获取工具
+	Get(name string) (Tool, bool)
+
+	// List 返回所有工具的定义，按名称排序
+	List() []model.ToolDefinition
+}
+
+// registry 工具注册表实现
+type registry struct {
+	mu    sync.RWMutex
+	tools map[string]Tool // key: 工具名称
+}
+
+// NewRegistry 创建工具注册表
+func NewRegistry() Registry {
+	return &registry{
+		tools: make(map[string]Tool),
+	}
+}
+
+// Register 注册工具
+func (r *registry) Register(tool Tool) error {
+	if tool == nil {
+		return fmt.Errorf("工具不能为空")
+	}
+
+	name := tool.Definition().Name
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("工具名称 '%s' 无效，只能包含字母、数字、下划线和连字符，长度不超过 64", name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.tools[name]; exists {
+		return fmt.Errorf("工具 '%s' 已注册", name)
+	}
+	r.tools[name] = tool
+
+	return nil
+}
+
+// Get 根据名称获取工具
+func (r *registry) Get(name string) (Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tool, exists := r.tools[name]
+	return tool, exists
+}
+
+// List 返回所有工具的定义
+func (r *registry) List() []model.ToolDefinition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	definitions := make([]model.ToolDefinition, 0, len(r.tools))
+	for _, tool := range r.tools {
+		definitions = append(definitions, tool.Definition())
+	}
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Name < definitions[j].Name
+	})
+
+	return definitions
+}
+
+// funcTool 由函数实现的工具
+type funcTool struct {
+	definition model.ToolDefinition
+	fn         func(ctx context.Context, arguments string) (string, error)
+}
+
+// NewFunc 创建由函数实现的工具
+// 参数:
+//
+//	name: 工具名称
+//	description: 工具描述，模型据此决定是否调用
+//	parameters: 参数的 JSON Schema，没有参数时可为 nil
+//	fn: 工具实现，arguments 为 JSON 格式的调用参数
+func NewFunc(name, description string, parameters map[string]interface{}, fn func(ctx context.Context, arguments string) (string, error)) Tool {
+	return &funcTool{
+		definition: model.ToolDefinition{
+			Name:        name,
+			Description: description,
+			Parameters:  parameters,
+		},
+		fn: fn,
+	}
+}
+
+// Definition 返回工具定义
+func (t *funcTool) Definition() model.ToolDefinition {
+	return t.definition
+}
+
+// Call 执行工具调用
+func (t *funcTool) Call(ctx context.Context, arguments string) (string, error) {
+	return t.fn(ctx, arguments)
+}
@@ -0,0 +1,60 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestRegistry_Register(t *testing.T) {
+	registry := NewRegistry()
+	echo := NewFunc("echo", "原样返回参数", nil, func(ctx context.Context, arguments string) (string, error) {
+		return arguments, nil
+	})
+
+	if err := registry.Register(echo); err != nil {
+		t.Fatalf("注册工具失败: %v", err)
+	}
+	if err := registry.Register(echo); err == nil {
+		t.Error("期望重复注册返回错误")
+	}
+	if err := registry.Register(NewFunc("非法 名称", "", nil, nil)); err == nil {
+		t.Error("期望无效名称返回错误")
+	}
+	if err := registry.Register(NewCurrentTimeTool()); err != nil {
+		t.Fatalf("注册内置工具失败: %v", err)
+	}
+
+	tool, ok := registry.Get("echo")
+	if !ok {
+		t.Fatal("期望找到已注册的工具")
+	}
+	if result, err := tool.Call(context.Background(), `{"a":1}`); err != nil || result != `{"a":1}` {
+		t.Errorf("Call() = %s, %v", result, err)
+	}
+
+	definitions := registry.List()
+	if len(definitions) != 2 || definitions[0].Name != "echo" || definitions[1].Name != CurrentTimeToolName {
+		t.Errorf("List() = %+v", definitions)
+	}
+}
+
+func TestCurrentTimeTool(t *testing.T) {
+	tool := NewCurrentTimeTool()
+
+	result, err := tool.Call(context.Background(), `{"timezone":"Asia/Shanghai"}`)
+	if err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	var output map[string]string
+	if err := json.Unmarshal([]byte(result), &output); err != nil {
+		t.Fatalf("结果不是 JSON: %s", result)
+	}
+	if output["timezone"] != "Asia/Shanghai" || output["time"] == "" {
+		t.Errorf("结果不正确: %+v", output)
+	}
+
+	if _, err := tool.Call(context.Background(), `{"timezone":"Mars/Base"}`); err == nil {
+		t.Error("期望无效时区返回错误")
+	}
+}
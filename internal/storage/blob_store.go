@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrBlobNotFound 表示 Blob 不存在
+var ErrBlobNotFound = stderrors.New("blob 不存在")
+
+// BlobStore 二进制内容存储接口
+// key 由调用方生成（如内容的 SHA-256），只能包含字母、数字、'-' 和 '_'
+type BlobStore interface {
+	// Put 保存内容，key 已存在时覆盖
+	Put(ctx context.Context, key string, r io.Reader) error
+
+	// Get 读取内容，调用方负责关闭返回的 ReadCloser；不存在时返回 ErrBlobNotFound
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Exists 判断内容是否存在
+	Exists(ctx context.Context, key string) (bool, error)
+
+	// Delete 删除内容，不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+}
+
+// LocalBlobStore 本地文件系统的 Blob 存储实现
+// 内容保存在 dir/<key 前两位>/<key>，先写入临时文件再重命名，读取时不会看到写了一半的内容
+type LocalBlobStore struct {
+	dir string
+}
+
+// NewLocalBlobStore 创建本地文件系统的 Blob 存储，目录不存在时自动创建
+func NewLocalBlobStore(dir string) (*LocalBlobStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("存储目录不能为空")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建存储目录失败: %w", err)
+	}
+	return &LocalBlobStore{dir: dir}, nil
+}
+
+// Put 保存内容，key 已存在时覆盖
+func (s *LocalBlobStore) Put(ctx context.Context, key string, r io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("创建存储目录失败: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, &contextReader{ctx: ctx, r: r}); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入 blob 失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("写入 blob 失败: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("保存 blob 失败: %w", err)
+	}
+	return nil
+}
+
+// Get 读取内容，调用方负责关闭返回的 ReadCloser；不存在时返回 ErrBlobNotFound
+func (s *LocalBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrBlobNotFound
+		}
+		return nil, fmt.Errorf("读取 blob 失败: %w", err)
+	}
+	return f, nil
+}
+
+// Exists 判断内容是否存在
+func (s *LocalBlobStore) Exists(ctx context.Context, key string) (bool, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("读取 blob 失败: %w", err)
+	}
+	return true, nil
+}
+
+// Delete 删除内容，不存在时不返回错误
+func (s *LocalBlobStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除 blob 失败: %w", err)
+	}
+	return nil
+}
+
+// path 返回 key 对应的文件路径，拒绝可能逃出存储目录的 key
+func (s *LocalBlobStore) path(key string) (string, error) {
+	if len(key) < 3 || strings.IndexFunc(key, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_')
+	}) >= 0 {
+		return "", fmt.Errorf("无效的 blob key '%s'", key)
+	}
+	return filepath.Join(s.dir, key[:2], key), nil
+}
+
+// contextReader 在上下文取消后停止读取
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// Read 实现 io.Reader 接口
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
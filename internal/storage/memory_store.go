@@ -26,6 +26,19 @@ type Store interface {
 	// GetModel 获取指定模型
 	GetModel(providerID, modelID string) (*model.Model, error)
 
+	// AddModel 向提供商添加一个模型，同名模型已存在时返回错误
+	AddModel(providerID string, m model.Model) error
+
+	// RemoveModel 从提供商移除指定模型
+	RemoveModel(providerID, modelID string) error
+
+	// Snapshot 获取当前模型目录的快照
+	Snapshot() *Snapshot
+
+	// ReplaceAll 以快照原子地替换全部提供商和模型
+	// 通过 AddModel 添加的自定义模型（fetch_from 为 customizable-model）在其提供商仍存在时保留
+	ReplaceAll(snapshot *Snapshot)
+
 	// GetProvidersCount 获取提供商数量
 	GetProvidersCount() int
 
@@ -152,6 +165,114 @@ func (s *MemoryStore) GetModel(providerID, modelID string) (*model.Model, error)
 	return nil, errors.NewModelNotFoundError(modelID)
 }
 
+// AddModel 向提供商添加一个模型，同名模型已存在时返回错误
+func (s *MemoryStore) AddModel(providerID string, m model.Model) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.providers[providerID]; !exists {
+		return errors.NewProviderNotFoundError(providerID)
+	}
+
+	for _, existing := range s.models[providerID] {
+		if existing.Model == m.Model {
+			return errors.NewModelAlreadyExistsError(m.Model)
+		}
+	}
+
+	// 复制后追加，避免与已返回的切片共享底层数组
+	models := make([]model.Model, len(s.models[providerID]), len(s.models[providerID])+1)
+	copy(models, s.models[providerID])
+	s.models[providerID] = append(models, m)
+	return nil
+}
+
+// RemoveModel 从提供商移除指定模型
+func (s *MemoryStore) RemoveModel(providerID, modelID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.providers[providerID]; !exists {
+		return errors.NewProviderNotFoundError(providerID)
+	}
+
+	models := s.models[providerID]
+	for i := range models {
+		if models[i].Model == modelID {
+			remaining := make([]model.Model, 0, len(models)-1)
+			remaining = append(remaining, models[:i]...)
+			remaining = append(remaining, models[i+1:]...)
+			s.models[providerID] = remaining
+			return nil
+		}
+	}
+
+	return errors.NewModelNotFoundError(modelID)
+}
+
+// Snapshot 获取当前模型目录的快照
+func (s *MemoryStore) Snapshot() *Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := NewSnapshot()
+	for _, provider := range s.providers {
+		snapshot.Providers = append(snapshot.Providers, *provider)
+	}
+	for providerID, models := range s.models {
+		modelsCopy := make([]model.Model, len(models))
+		copy(modelsCopy, models)
+		snapshot.Models[providerID] = modelsCopy
+	}
+
+	return snapshot
+}
+
+// ReplaceAll 以快照原子地替换全部提供商和模型
+func (s *MemoryStore) ReplaceAll(snapshot *Snapshot) {
+	providers := make(map[string]*model.Provider, len(snapshot.Providers))
+	for i := range snapshot.Providers {
+		provider := snapshot.Providers[i]
+		providers[provider.ID] = &provider
+	}
+
+	models := make(map[string][]model.Model, len(snapshot.Models))
+	for providerID, list := range snapshot.Models {
+		modelsCopy := make([]model.Model, len(list))
+		copy(modelsCopy, list)
+		models[providerID] = modelsCopy
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 保留自定义模型，新快照中存在同名模型时以新快照为准
+	for providerID, list := range s.models {
+		if _, exists := providers[providerID]; !exists {
+			continue
+		}
+		for _, m := range list {
+			if m.FetchFrom != model.FetchFromCustomizableModel || containsModel(models[providerID], m.Model) {
+				continue
+			}
+			models[providerID] = append(models[providerID], m)
+		}
+	}
+
+	s.providers = providers
+	s.models = models
+}
+
+// containsModel 检查模型列表中是否存在指定模型
+func containsModel(models []model.Model, modelID string) bool {
+	for i := range models {
+		if models[i].Model == modelID {
+			return true
+		}
+	}
+	return false
+}
+
 // GetProvidersCount 获取提供商数量
 func (s *MemoryStore) GetProvidersCount() int {
 	s.mu.RLock()
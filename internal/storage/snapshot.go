@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"reflect"
+	"sort"
+
+	"genkit-ai-service/internal/model"
+)
+
+// Snapshot 模型目录快照，包含全部提供商及其模型
+type Snapshot struct {
+	// Providers 提供商列表
+	Providers []model.Provider
+	// Models 各提供商的模型列表，key: provider_id
+	Models map[string][]model.Model
+}
+
+// NewSnapshot 创建空的模型目录快照
+func NewSnapshot() *Snapshot {
+	return &Snapshot{
+		Models: make(map[string][]model.Model),
+	}
+}
+
+// Diff 比较 previous 与当前快照，返回新增、移除和变更的提供商和模型
+// 模型以 provider/model 形式表示，结果按名称排序
+func (s *Snapshot) Diff(previous *Snapshot) *model.CatalogChanges {
+	changes := &model.CatalogChanges{
+		Providers: diffEntries(previous.providerIndex(), s.providerIndex()),
+		Models:    diffEntries(previous.modelIndex(), s.modelIndex()),
+	}
+	return changes
+}
+
+// providerIndex 按提供商ID索引提供商
+func (s *Snapshot) providerIndex() map[string]interface{} {
+	index := make(map[string]interface{}, len(s.Providers))
+	for _, provider := range s.Providers {
+		index[provider.ID] = provider
+	}
+	return index
+}
+
+// modelIndex 按 provider/model 索引模型
+func (s *Snapshot) modelIndex() map[string]interface{} {
+	index := make(map[string]interface{})
+	for providerID, models := range s.Models {
+		for _, m := range models {
+			index[providerID+"/"+m.Model] = m
+		}
+	}
+	return index
+}
+
+// diffEntries 比较两个索引
+func diffEntries(previous, current map[string]interface{}) model.CatalogChangeSet {
+	changeSet := model.CatalogChangeSet{
+		Added:   []string{},
+		Removed: []string{},
+		Changed: []string{},
+	}
+
+	for key, entry := range current {
+		old, exists := previous[key]
+		switch {
+		case !exists:
+			changeSet.Added = append(changeSet.Added, key)
+		case !reflect.DeepEqual(old, entry):
+			changeSet.Changed = append(changeSet.Changed, key)
+		}
+	}
+	for key := range previous {
+		if _, exists := current[key]; !exists {
+			changeSet.Removed = append(changeSet.Removed, key)
+		}
+	}
+
+	sort.Strings(changeSet.Added)
+	sort.Strings(changeSet.Removed)
+	sort.Strings(changeSet.Changed)
+	return changeSet
+}
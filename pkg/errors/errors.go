@@ -19,11 +19,13 @@ const (
 	CodeServiceUnavailable = 503 // 服务不可用
 	CodeAIServiceError     = 550 // AI 服务错误
 	CodeContextCancelled   = 551 // 上下文已取消
+	CodeStructuredOutput   = 552 // 结构化输出不符合 JSON Schema
 	
 	// 模型提供商相关错误 560-569
-	CodeProviderNotFound = 560 // 提供商不存在
-	CodeModelNotFound    = 561 // 模型不存在
-	CodeLoadDataError    = 562 // 数据加载错误
+	CodeProviderNotFound   = 560 // 提供商不存在
+	CodeModelNotFound      = 561 // 模型不存在
+	CodeLoadDataError      = 562 // 数据加载错误
+	CodeModelAlreadyExists = 563 // 模型已存在
 
 	// 会话相关错误 570-579
 	CodeSessionNotFound      = 570 // 会话不存在
@@ -37,6 +39,21 @@ const (
 
 	// 摘要相关错误 590-599
 	CodeSummaryGenerationFailed = 590 // 摘要生成失败
+
+	// 文件相关错误 600-609
+	CodeFileNotFound     = 600 // 文件不存在
+	CodeFileAccessDenied = 601 // 无权访问文件
+	CodeFileTooLarge     = 602 // 文件过大
+
+	// 知识库相关错误 610-619
+	CodeKnowledgeBaseNotFound     = 610 // 知识库不存在
+	CodeKnowledgeBaseAccessDenied = 611 // 无权访问知识库
+	CodeDocumentNotFound          = 612 // 知识库文档不存在
+
+	// 凭证相关错误 620-629
+	CodeCredentialNotFound      = 620 // 凭证不存在
+	CodeCredentialAccessDenied  = 621 // 无权访问凭证
+	CodeCredentialAlreadyExists = 622 // 凭证已存在
 )
 
 // 错误消息常量
@@ -51,15 +68,26 @@ const (
 	MsgServiceUnavailable  = "服务不可用"
 	MsgAIServiceError      = "AI 服务错误"
 	MsgContextCancelled    = "请求已取消"
+	MsgStructuredOutput    = "模型回复不符合 JSON Schema"
 	MsgProviderNotFound    = "提供商不存在"
 	MsgModelNotFound       = "模型不存在"
 	MsgLoadDataError       = "数据加载失败"
+	MsgModelAlreadyExists  = "模型已存在"
 	MsgSessionNotFound          = "会话不存在"
 	MsgSessionAccessDenied      = "无权访问会话"
 	MsgMessageNotFound          = "消息不存在"
 	MsgMessageAccessDenied      = "无权访问消息"
 	MsgMessageSendFailed        = "消息发送失败"
 	MsgSummaryGenerationFailed  = "摘要生成失败"
+	MsgFileNotFound             = "文件不存在"
+	MsgFileAccessDenied         = "无权访问文件"
+	MsgFileTooLarge             = "文件过大"
+	MsgKnowledgeBaseNotFound     = "知识库不存在"
+	MsgKnowledgeBaseAccessDenied = "无权访问知识库"
+	MsgDocumentNotFound          = "知识库文档不存在"
+	MsgCredentialNotFound        = "凭证不存在"
+	MsgCredentialAccessDenied    = "无权访问凭证"
+	MsgCredentialAlreadyExists   = "凭证已存在"
 )
 
 // AppError 自定义应用错误类型
@@ -140,6 +168,11 @@ func NewContextCancelledError() *AppError {
 	return New(CodeContextCancelled, MsgContextCancelled)
 }
 
+// NewStructuredOutputError 创建结构化输出错误
+func NewStructuredOutputError(err error) *AppError {
+	return Wrap(CodeStructuredOutput, MsgStructuredOutput, err)
+}
+
 // NewServiceUnavailableError 创建服务不可用错误
 func NewServiceUnavailableError(message string) *AppError {
 	if message == "" {
@@ -166,6 +199,15 @@ func NewModelNotFoundError(modelID string) *AppError {
 	return New(CodeModelNotFound, message)
 }
 
+// NewModelAlreadyExistsError 创建模型已存在错误
+func NewModelAlreadyExistsError(modelID string) *AppError {
+	message := MsgModelAlreadyExists
+	if modelID != "" {
+		message = fmt.Sprintf("模型 '%s' 已存在", modelID)
+	}
+	return New(CodeModelAlreadyExists, message)
+}
+
 // NewLoadDataError 创建数据加载错误
 func NewLoadDataError(err error) *AppError {
 	return Wrap(CodeLoadDataError, MsgLoadDataError, err)
@@ -208,3 +250,68 @@ func NewMessageSendFailedError(err error) *AppError {
 func NewSummaryGenerationFailedError(err error) *AppError {
 	return Wrap(CodeSummaryGenerationFailed, MsgSummaryGenerationFailed, err)
 }
+
+// NewFileNotFoundError 创建文件不存在错误
+func NewFileNotFoundError(fileID string) *AppError {
+	message := MsgFileNotFound
+	if fileID != "" {
+		message = fmt.Sprintf("文件 '%s' 不存在", fileID)
+	}
+	return New(CodeFileNotFound, message)
+}
+
+// NewFileAccessDeniedError 创建文件访问拒绝错误
+func NewFileAccessDeniedError() *AppError {
+	return New(CodeFileAccessDenied, MsgFileAccessDenied)
+}
+
+// NewFileTooLargeError 创建文件过大错误，maxSize 为允许的最大字节数
+func NewFileTooLargeError(maxSize int64) *AppError {
+	message := MsgFileTooLarge
+	if maxSize > 0 {
+		message = fmt.Sprintf("文件大小不能超过 %d MB", maxSize>>20)
+	}
+	return New(CodeFileTooLarge, message)
+}
+
+// NewKnowledgeBaseNotFoundError 创建知识库不存在错误
+func NewKnowledgeBaseNotFoundError(knowledgeBaseID string) *AppError {
+	message := MsgKnowledgeBaseNotFound
+	if knowledgeBaseID != "" {
+		message = fmt.Sprintf("知识库 '%s' 不存在", knowledgeBaseID)
+	}
+	return New(CodeKnowledgeBaseNotFound, message)
+}
+
+// NewKnowledgeBaseAccessDeniedError 创建知识库访问拒绝错误
+func NewKnowledgeBaseAccessDeniedError() *AppError {
+	return New(CodeKnowledgeBaseAccessDenied, MsgKnowledgeBaseAccessDenied)
+}
+
+// NewDocumentNotFoundError 创建知识库文档不存在错误
+func NewDocumentNotFoundError(documentID string) *AppError {
+	message := MsgDocumentNotFound
+	if documentID != "" {
+		message = fmt.Sprintf("文档 '%s' 不存在", documentID)
+	}
+	return New(CodeDocumentNotFound, message)
+}
+
+// NewCredentialNotFoundError 创建凭证不存在错误
+func NewCredentialNotFoundError(credentialID string) *AppError {
+	message := MsgCredentialNotFound
+	if credentialID != "" {
+		message = fmt.Sprintf("凭证 '%s' 不存在", credentialID)
+	}
+	return New(CodeCredentialNotFound, message)
+}
+
+// NewCredentialAccessDeniedError 创建凭证访问拒绝错误
+func NewCredentialAccessDeniedError() *AppError {
+	return New(CodeCredentialAccessDenied, MsgCredentialAccessDenied)
+}
+
+// NewCredentialAlreadyExistsError 创建凭证已存在错误
+func NewCredentialAlreadyExistsError() *AppError {
+	return New(CodeCredentialAlreadyExists, MsgCredentialAlreadyExists)
+}
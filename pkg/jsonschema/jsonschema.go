@@ -0,0 +1,396 @@
+// Package jsonschema 提供结构化输出所需的 JSON Schema 校验
+//
+// 只实现常用关键字：type、enum、const、properties、required、additionalProperties、
+// items、minItems、maxItems、minLength、maxLength、pattern、minimum、maximum、
+// exclusiveMinimum、exclusiveMaximum、allOf、anyOf、oneOf。不支持 $ref 等引用关键字，
+// 未识别的关键字（如 description、title）会被忽略。
+package jsonschema
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// schemaTypes JSON Schema 支持的类型名称
+var schemaTypes = map[string]bool{
+	"object":  true,
+	"array":   true,
+	"string":  true,
+	"number":  true,
+	"integer": true,
+	"boolean": true,
+	"null":    true,
+}
+
+// ValidationError 值不符合 Schema 时返回的错误，包含所有不符合的位置
+type ValidationError struct {
+	Problems []string
+}
+
+// Error 实现 error 接口
+func (e *ValidationError) Error() string {
+	return strings.Join(e.Problems, "; ")
+}
+
+// Check 检查 Schema 本身是否有效
+func Check(schema map[string]interface{}) error {
+	return checkSchema(schema, "$")
+}
+
+// Validate 校验 JSON 值是否符合 Schema
+// value 应为 encoding/json 解码得到的值（map[string]interface{}、[]interface{}、float64 等）
+func Validate(schema map[string]interface{}, value interface{}) error {
+	var problems []string
+	validate(schema, value, "$", &problems)
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// checkSchema 递归检查 Schema 及其子 Schema
+func checkSchema(schema map[string]interface{}, path string) error {
+	if schema == nil {
+		return fmt.Errorf("%s: Schema 不能为空", path)
+	}
+
+	if t, ok := schema["type"]; ok {
+		types, ok := typeNames(t)
+		if !ok || len(types) == 0 {
+			return fmt.Errorf("%s.type: 必须为类型名称或类型名称数组", path)
+		}
+		for _, name := range types {
+			if !schemaTypes[name] {
+				return fmt.Errorf("%s.type: 不支持的类型 '%s'", path, name)
+			}
+		}
+	}
+
+	if properties, ok := schema["properties"]; ok {
+		props, ok := properties.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s.properties: 必须为对象", path)
+		}
+		for name, prop := range props {
+			sub, ok := prop.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s.properties.%s: 必须为 Schema 对象", path, name)
+			}
+			if err := checkSchema(sub, path+".properties."+name); err != nil {
+				return err
+			}
+		}
+	}
+
+	if required, ok := schema["required"]; ok {
+		if _, ok := stringList(required); !ok {
+			return fmt.Errorf("%s.required: 必须为字符串数组", path)
+		}
+	}
+
+	if items, ok := schema["items"]; ok {
+		sub, ok := items.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s.items: 必须为 Schema 对象", path)
+		}
+		if err := checkSchema(sub, path+".items"); err != nil {
+			return err
+		}
+	}
+
+	if additional, ok := schema["additionalProperties"]; ok {
+		switch v := additional.(type) {
+		case bool:
+		case map[string]interface{}:
+			if err := checkSchema(v, path+".additionalProperties"); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s.additionalProperties: 必须为布尔值或 Schema 对象", path)
+		}
+	}
+
+	if pattern, ok := schema["pattern"]; ok {
+		s, ok := pattern.(string)
+		if !ok {
+			return fmt.Errorf("%s.pattern: 必须为字符串", path)
+		}
+		if _, err := regexp.Compile(s); err != nil {
+			return fmt.Errorf("%s.pattern: 无效的正则表达式: %v", path, err)
+		}
+	}
+
+	for _, keyword := range []string{"allOf", "anyOf", "oneOf"} {
+		list, ok := schema[keyword]
+		if !ok {
+			continue
+		}
+		subs, ok := list.([]interface{})
+		if !ok || len(subs) == 0 {
+			return fmt.Errorf("%s.%s: 必须为非空的 Schema 数组", path, keyword)
+		}
+		for i, item := range subs {
+			sub, ok := item.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s.%s[%d]: 必须为 Schema 对象", path, keyword, i)
+			}
+			if err := checkSchema(sub, fmt.Sprintf("%s.%s[%d]", path, keyword, i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validate 递归校验值，不符合的位置追加到 problems
+func validate(schema map[string]interface{}, value interface{}, path string, problems *[]string) {
+	if t, ok := schema["type"]; ok {
+		types, _ := typeNames(t)
+		matched := false
+		for _, name := range types {
+			if matchType(name, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			*problems = append(*problems, fmt.Sprintf("%s: 期望类型 %s, 实际为 %s", path, strings.Join(types, "|"), typeOf(value)))
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, candidate := range enum {
+			if reflect.DeepEqual(candidate, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			*problems = append(*problems, fmt.Sprintf("%s: 值不在允许的枚举中", path))
+		}
+	}
+
+	if constant, ok := schema["const"]; ok && !reflect.DeepEqual(constant, value) {
+		*problems = append(*problems, fmt.Sprintf("%s: 值必须为 %v", path, constant))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		validateObject(schema, v, path, problems)
+	case []interface{}:
+		validateArray(schema, v, path, problems)
+	case string:
+		validateString(schema, v, path, problems)
+	case float64:
+		validateNumber(schema, v, path, problems)
+	}
+
+	if subs, ok := schema["allOf"].([]interface{}); ok {
+		for _, item := range subs {
+			if sub, ok := item.(map[string]interface{}); ok {
+				validate(sub, value, path, problems)
+			}
+		}
+	}
+
+	if subs, ok := schema["anyOf"].([]interface{}); ok {
+		if countMatches(subs, value, path) == 0 {
+			*problems = append(*problems, fmt.Sprintf("%s: 不符合 anyOf 中的任何 Schema", path))
+		}
+	}
+
+	if subs, ok := schema["oneOf"].([]interface{}); ok {
+		if n := countMatches(subs, value, path); n != 1 {
+			*problems = append(*problems, fmt.Sprintf("%s: 必须恰好符合 oneOf 中的一个 Schema, 实际符合 %d 个", path, n))
+		}
+	}
+}
+
+// validateObject 校验对象的属性
+func validateObject(schema map[string]interface{}, value map[string]interface{}, path string, problems *[]string) {
+	required, _ := stringList(schema["required"])
+	for _, name := range required {
+		if _, exists := value[name]; !exists {
+			*problems = append(*problems, fmt.Sprintf("%s: 缺少必需的属性 '%s'", path, name))
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	for name, item := range value {
+		if prop, ok := properties[name].(map[string]interface{}); ok {
+			validate(prop, item, path+"."+name, problems)
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				*problems = append(*problems, fmt.Sprintf("%s: 不允许的属性 '%s'", path, name))
+			}
+		case map[string]interface{}:
+			validate(additional, item, path+"."+name, problems)
+		}
+	}
+}
+
+// validateArray 校验数组的元素和长度
+func validateArray(schema map[string]interface{}, value []interface{}, path string, problems *[]string) {
+	if min, ok := toFloat(schema["minItems"]); ok && float64(len(value)) < min {
+		*problems = append(*problems, fmt.Sprintf("%s: 元素数量不能少于 %v", path, min))
+	}
+	if max, ok := toFloat(schema["maxItems"]); ok && float64(len(value)) > max {
+		*problems = append(*problems, fmt.Sprintf("%s: 元素数量不能多于 %v", path, max))
+	}
+
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		for i, item := range value {
+			validate(items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	}
+}
+
+// validateString 校验字符串的长度和格式
+func validateString(schema map[string]interface{}, value string, path string, problems *[]string) {
+	length := float64(utf8.RuneCountInString(value))
+	if min, ok := toFloat(schema["minLength"]); ok && length < min {
+		*problems = append(*problems, fmt.Sprintf("%s: 长度不能小于 %v", path, min))
+	}
+	if max, ok := toFloat(schema["maxLength"]); ok && length > max {
+		*problems = append(*problems, fmt.Sprintf("%s: 长度不能大于 %v", path, max))
+	}
+
+	if pattern, ok := schema["pattern"].(string); ok {
+		if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(value) {
+			*problems = append(*problems, fmt.Sprintf("%s: 不符合格式 %s", path, pattern))
+		}
+	}
+}
+
+// validateNumber 校验数值范围
+func validateNumber(schema map[string]interface{}, value float64, path string, problems *[]string) {
+	if min, ok := toFloat(schema["minimum"]); ok && value < min {
+		*problems = append(*problems, fmt.Sprintf("%s: 必须大于或等于 %v", path, min))
+	}
+	if max, ok := toFloat(schema["maximum"]); ok && value > max {
+		*problems = append(*problems, fmt.Sprintf("%s: 必须小于或等于 %v", path, max))
+	}
+	if min, ok := toFloat(schema["exclusiveMinimum"]); ok && value <= min {
+		*problems = append(*problems, fmt.Sprintf("%s: 必须大于 %v", path, min))
+	}
+	if max, ok := toFloat(schema["exclusiveMaximum"]); ok && value >= max {
+		*problems = append(*problems, fmt.Sprintf("%s: 必须小于 %v", path, max))
+	}
+}
+
+// countMatches 返回值符合的子 Schema 数量
+func countMatches(subs []interface{}, value interface{}, path string) int {
+	matches := 0
+	for _, item := range subs {
+		sub, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var subProblems []string
+		validate(sub, value, path, &subProblems)
+		if len(subProblems) == 0 {
+			matches++
+		}
+	}
+	return matches
+}
+
+// matchType 判断值是否为指定的 JSON 类型
+func matchType(name string, value interface{}) bool {
+	switch name {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == float64(int64(f))
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	default:
+		return false
+	}
+}
+
+// typeOf 返回值的 JSON 类型名称
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// typeNames 解析 type 关键字，支持单个类型名称或类型名称数组
+func typeNames(value interface{}) ([]string, bool) {
+	if name, ok := value.(string); ok {
+		return []string{name}, true
+	}
+	return stringList(value)
+}
+
+// stringList 将 JSON 数组转换为字符串切片
+func stringList(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case []string:
+		return v, true
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			result = append(result, s)
+		}
+		return result, true
+	default:
+		return nil, false
+	}
+}
+
+// toFloat 将 Schema 中的数值关键字转换为 float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,118 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func mustDecode(t *testing.T, text string) map[string]interface{} {
+	t.Helper()
+	var v map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &v); err != nil {
+		t.Fatalf("解析 JSON 失败: %v", err)
+	}
+	return v
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		wantErr string
+	}{
+		{name: "有效", schema: `{"type":"object","properties":{"name":{"type":"string"}},"required":["name"]}`},
+		{name: "联合类型", schema: `{"type":["string","null"]}`},
+		{name: "未知类型", schema: `{"type":"date"}`, wantErr: "$.type"},
+		{name: "属性不是对象", schema: `{"properties":{"name":"string"}}`, wantErr: "$.properties.name"},
+		{name: "required 不是字符串数组", schema: `{"required":"name"}`, wantErr: "$.required"},
+		{name: "无效的正则", schema: `{"type":"string","pattern":"("}`, wantErr: "$.pattern"},
+		{name: "嵌套的无效 Schema", schema: `{"type":"array","items":{"type":"unknown"}}`, wantErr: "$.items.type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(mustDecode(t, tt.schema))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("期望 Schema 有效, 得到 %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("期望错误包含 %q, 得到 %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	schema := mustDecode(t, `{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "minLength": 1},
+			"age": {"type": "integer", "minimum": 0},
+			"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+			"level": {"enum": ["low", "high"]}
+		},
+		"required": ["name", "age"],
+		"additionalProperties": false
+	}`)
+
+	tests := []struct {
+		name     string
+		value    string
+		problems []string
+	}{
+		{name: "符合", value: `{"name":"张三","age":18,"tags":["a"],"level":"low"}`},
+		{name: "缺少必需属性", value: `{"name":"张三"}`, problems: []string{"缺少必需的属性 'age'"}},
+		{name: "类型错误", value: `{"name":"张三","age":1.5}`, problems: []string{"$.age: 期望类型 integer"}},
+		{name: "数组元素", value: `{"name":"张三","age":1,"tags":["a",1,"c"]}`, problems: []string{"$.tags[1]", "元素数量不能多于 2"}},
+		{name: "枚举和多余属性", value: `{"name":"张三","age":1,"level":"mid","extra":true}`, problems: []string{"$.level", "不允许的属性 'extra'"}},
+		{name: "根类型", value: `["张三"]`, problems: []string{"$: 期望类型 object, 实际为 array"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value interface{}
+			if err := json.Unmarshal([]byte(tt.value), &value); err != nil {
+				t.Fatalf("解析 JSON 失败: %v", err)
+			}
+
+			err := Validate(schema, value)
+			if len(tt.problems) == 0 {
+				if err != nil {
+					t.Errorf("期望校验通过, 得到 %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("期望校验失败")
+			}
+			for _, problem := range tt.problems {
+				if !strings.Contains(err.Error(), problem) {
+					t.Errorf("期望错误包含 %q, 得到 %v", problem, err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate_Combinators(t *testing.T) {
+	schema := mustDecode(t, `{"oneOf":[{"type":"string"},{"type":"number","minimum":10}]}`)
+
+	if err := Validate(schema, "ok"); err != nil {
+		t.Errorf("期望字符串符合 oneOf, 得到 %v", err)
+	}
+	if err := Validate(schema, float64(5)); err == nil {
+		t.Error("期望小于 10 的数值不符合 oneOf")
+	}
+
+	anyOf := mustDecode(t, `{"anyOf":[{"type":"null"},{"type":"boolean"}]}`)
+	if err := Validate(anyOf, nil); err != nil {
+		t.Errorf("期望 null 符合 anyOf, 得到 %v", err)
+	}
+	if err := Validate(anyOf, "x"); err == nil {
+		t.Error("期望字符串不符合 anyOf")
+	}
+}
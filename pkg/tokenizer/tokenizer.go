@@ -0,0 +1,43 @@
+// Package tokenizer 提供与具体模型无关的 token 数量估算
+//
+// 各提供商的分词器不同，这里按字符类别近似估算，结果略大于实际值，
+// 用于在调用模型前判断上下文是否超出窗口大小。
+package tokenizer
+
+import "unicode"
+
+const (
+	// MessageOverhead 每条对话消息的固定开销（角色标记、分隔符等）
+	MessageOverhead = 4
+
+	// asciiCharsPerToken 英文等 ASCII 文本平均每个 token 的字符数
+	asciiCharsPerToken = 4
+)
+
+// Estimate 估算文本的 token 数量
+// 中日韩等宽字符按每字 1 个 token 计算，ASCII 字符按每 4 个字符 1 个 token 计算
+func Estimate(text string) int {
+	if text == "" {
+		return 0
+	}
+
+	wide := 0
+	ascii := 0
+	for _, r := range text {
+		switch {
+		case r <= unicode.MaxASCII:
+			ascii++
+		case unicode.IsSpace(r):
+			ascii++
+		default:
+			wide++
+		}
+	}
+
+	return wide + (ascii+asciiCharsPerToken-1)/asciiCharsPerToken
+}
+
+// EstimateMessage 估算一条对话消息的 token 数量，包含消息固定开销
+func EstimateMessage(content string) int {
+	return Estimate(content) + MessageOverhead
+}
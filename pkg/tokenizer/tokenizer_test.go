@@ -0,0 +1,31 @@
+package tokenizer
+
+import "testing"
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{name: "空文本", text: "", want: 0},
+		{name: "英文", text: "hello world!", want: 3},
+		{name: "英文不足一个token", text: "hi", want: 1},
+		{name: "中文", text: "你好世界", want: 4},
+		{name: "中英混合", text: "你好 world", want: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Estimate(tt.text); got != tt.want {
+				t.Errorf("Estimate(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimateMessage(t *testing.T) {
+	if got := EstimateMessage("你好"); got != 2+MessageOverhead {
+		t.Errorf("EstimateMessage() = %d, want %d", got, 2+MessageOverhead)
+	}
+}
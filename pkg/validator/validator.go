@@ -20,6 +20,19 @@ type ValidationError struct {
 	Message string `json:"message"`
 }
 
+// FieldErrors 字段级验证错误列表
+// 实现 error 接口，供服务层返回无法通过结构体标签表达的校验结果（如模型参数规则）
+type FieldErrors []ValidationError
+
+// Error 实现 error 接口
+func (e FieldErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fieldError := range e {
+		messages = append(messages, fieldError.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
 // New 创建新的验证器实例
 func New() *Validator {
 	v := validator.New()